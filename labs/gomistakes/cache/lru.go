@@ -0,0 +1,234 @@
+// Package cache holds a bounded cache, the fix for a map used as a cache
+// that grows for as long as the program runs. An LRU holds at most its
+// capacity of entries, dropping the least recently used to make room, and
+// expires entries after a TTL without a goroutine to stop.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Reason is why an entry left the cache
+type Reason int
+
+const (
+	// Evicted entries made room for newer ones
+	Evicted Reason = iota
+	// Expired entries outlived the TTL
+	Expired
+	// Removed entries were deleted or purged
+	Removed
+)
+
+func (r Reason) String() string {
+	switch r {
+	case Evicted:
+		return "evicted"
+	case Expired:
+		return "expired"
+	case Removed:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// Stats counts what happened to lookups and entries since the cache was
+// made
+type Stats struct {
+	Hits, Misses uint64
+	Evictions    uint64
+	Expirations  uint64
+}
+
+// HitRate is the fraction of lookups that hit, 0 before any
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// LRU is a cache of at most capacity entries that evicts the least
+// recently used. It is safe for concurrent use.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	onEvict  func(K, V, Reason)
+	// order has the most recently used entry at the front
+	order *list.List
+	items map[K]*list.Element
+	stats Stats
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// New creates an LRU holding at most capacity entries, each expiring ttl
+// after it was set (0 for never). It panics if capacity is not positive.
+func New[K comparable, V any](capacity int, ttl time.Duration) *LRU[K, V] {
+	if capacity <= 0 {
+		panic("cache: capacity must be positive")
+	}
+	return &LRU[K, V]{capacity: capacity, ttl: ttl, order: list.New(), items: make(map[K]*list.Element, capacity)}
+}
+
+// OnEvict sets f to be called with every entry that leaves the cache other
+// than by being replaced, and why. f is called without the cache locked,
+// so it may use the cache.
+func (c *LRU[K, V]) OnEvict(f func(key K, value V, reason Reason)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEvict = f
+}
+
+// gone is an entry that left the cache, for the callback
+type gone[K comparable, V any] struct {
+	entry  *entry[K, V]
+	reason Reason
+}
+
+// notify calls f with the entries that left, once the cache is unlocked
+func notify[K comparable, V any](f func(K, V, Reason), left []gone[K, V]) {
+	if f == nil {
+		return
+	}
+	for _, g := range left {
+		f(g.entry.key, g.entry.value, g.reason)
+	}
+}
+
+// remove takes e out of c, counting why
+func (c *LRU[K, V]) remove(e *list.Element, reason Reason) gone[K, V] {
+	ent := c.order.Remove(e).(*entry[K, V])
+	delete(c.items, ent.key)
+	switch reason {
+	case Evicted:
+		c.stats.Evictions++
+	case Expired:
+		c.stats.Expirations++
+	}
+	return gone[K, V]{ent, reason}
+}
+
+func (c *LRU[K, V]) expired(ent *entry[K, V], now time.Time) bool {
+	return c.ttl > 0 && now.After(ent.expires)
+}
+
+// Set stores value under key as the most recently used entry, evicting
+// the least recently used if the cache is full
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = time.Now().Add(c.ttl)
+	}
+	var left []gone[K, V]
+	if e, ok := c.items[key]; ok {
+		ent := e.Value.(*entry[K, V])
+		ent.value, ent.expires = value, expires
+		c.order.MoveToFront(e)
+	} else {
+		if c.order.Len() >= c.capacity {
+			left = append(left, c.remove(c.order.Back(), Evicted))
+		}
+		c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+	}
+	f := c.onEvict
+	c.mu.Unlock()
+	notify(f, left)
+}
+
+// Get returns the value stored under key, marking it the most recently
+// used. An expired entry is removed and missed.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	var left []gone[K, V]
+	e, ok := c.items[key]
+	if ok && c.expired(e.Value.(*entry[K, V]), time.Now()) {
+		left = append(left, c.remove(e, Expired))
+		ok = false
+	}
+	var value V
+	if ok {
+		c.stats.Hits++
+		c.order.MoveToFront(e)
+		value = e.Value.(*entry[K, V]).value
+	} else {
+		c.stats.Misses++
+	}
+	f := c.onEvict
+	c.mu.Unlock()
+	notify(f, left)
+	return value, ok
+}
+
+// Delete removes the entry stored under key and reports whether there was
+// one
+func (c *LRU[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	e, ok := c.items[key]
+	var left []gone[K, V]
+	if ok {
+		left = append(left, c.remove(e, Removed))
+	}
+	f := c.onEvict
+	c.mu.Unlock()
+	notify(f, left)
+	return ok
+}
+
+// RemoveExpired removes every expired entry and returns how many there
+// were. Expired entries are otherwise removed only when looked up or
+// evicted.
+func (c *LRU[K, V]) RemoveExpired() int {
+	c.mu.Lock()
+	var left []gone[K, V]
+	now := time.Now()
+	for e := c.order.Back(); e != nil; {
+		prev := e.Prev()
+		if c.expired(e.Value.(*entry[K, V]), now) {
+			left = append(left, c.remove(e, Expired))
+		}
+		e = prev
+	}
+	f := c.onEvict
+	c.mu.Unlock()
+	notify(f, left)
+	return len(left)
+}
+
+// Purge removes every entry
+func (c *LRU[K, V]) Purge() {
+	c.mu.Lock()
+	left := make([]gone[K, V], 0, c.order.Len())
+	for e := c.order.Back(); e != nil; e = c.order.Back() {
+		left = append(left, c.remove(e, Removed))
+	}
+	f := c.onEvict
+	c.mu.Unlock()
+	notify(f, left)
+}
+
+// Len is how many entries the cache holds, expired ones not yet removed
+// included
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Cap is the most entries the cache holds
+func (c *LRU[K, V]) Cap() int { return c.capacity }
+
+// Stats returns the counts so far
+func (c *LRU[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
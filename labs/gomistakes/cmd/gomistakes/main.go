@@ -0,0 +1,553 @@
+// Command gomistakes lists and runs the lessons, each run bounded by a
+// timeout so lessons that block forever, such as the goroutine leak, end.
+// After running, it reports the goroutines each run leaked and the heap it
+// left behind, and with -profile writes pprof profiles of every run or
+// with -pprof serves them live. With -trace it writes the execution trace
+// of every run and prints its garbage collections, goroutines and heap as a
+// timeline. bench and report run the lesson benchmarks with go test, in the
+// lesson package's source.
+//
+//	gomistakes list
+//	gomistakes describe goroutine
+//	gomistakes -timeout 5s run goroutine --bad
+//	gomistakes -leaks run timer
+//	gomistakes -profile heap,goroutine run-all --bad
+//	gomistakes -pprof localhost:6060 -timeout 1m run goroutine
+//	gomistakes -trace run lru
+//	gomistakes bench -count 10 -benchstat slice > slice.txt
+//	gomistakes run-all
+//	gomistakes report -o workshop.html
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"gomistakes/leakcheck"
+	"gomistakes/lesson"
+	"gomistakes/memcheck"
+	"gomistakes/profile"
+	"gomistakes/report"
+	"gomistakes/timeline"
+)
+
+// errUsage is returned for malformed command lines, after the usage has
+// been printed
+var errUsage = errors.New("usage")
+
+func main() {
+	var opts options
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "time allowed for each run of a lesson (0 for none)")
+	flag.BoolVar(&opts.leaks, "leaks", false, "print the stack of every goroutine a run leaked")
+	flag.Func("profile", "write `kinds` of pprof profile of every run, comma separated: heap, allocs, goroutine, cpu", func(s string) error {
+		kinds, err := profile.ParseKinds(s)
+		opts.profiler.Kinds = kinds
+		return err
+	})
+	flag.StringVar(&opts.profiler.Dir, "profile-dir", "profiles", "`dir`ectory to write profiles and traces to")
+	flag.BoolVar(&opts.trace, "trace", false, "write the execution trace of every run and print a timeline of it")
+	flag.StringVar(&opts.pprof, "pprof", "", "serve net/http/pprof on `addr` while running and, after, until interrupted")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: gomistakes [flags] list | describe | run | run-all | bench | report [args]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if opts.pprof != "" {
+		addr, err := profile.Serve(opts.pprof)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "gomistakes:", err)
+			os.Exit(1)
+		}
+		opts.pprof = fmt.Sprintf("http://%s/debug/pprof/", addr)
+		fmt.Println("serving pprof on", opts.pprof)
+	}
+
+	err := run(ctx, opts, flag.Args())
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gomistakes:", err)
+		os.Exit(1)
+	}
+}
+
+// options are the global flags
+type options struct {
+	timeout  time.Duration
+	leaks    bool
+	profiler profile.Profiler
+	// pprof is where profiles are served, empty when they are not
+	pprof string
+	trace bool
+}
+
+// command parses its arguments and carries itself out
+type command struct {
+	usage string
+	run   func(ctx context.Context, opts options, fs *flag.FlagSet, args []string) error
+}
+
+var commands = map[string]command{
+	"list":     {"", list},
+	"describe": {"[LESSON]", describe},
+	"run":      {"LESSON [--bad | --good]", runLesson},
+	"run-all":  {"[--bad | --good]", runAll},
+	"bench":    {"[-count N] [-benchstat] [LESSON]", bench},
+	"report":   {"[-o FILE] [-title TITLE] [-bench=false] [--bad | --good]", writeReport},
+}
+
+func run(ctx context.Context, opts options, args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		flag.Usage()
+		return errUsage
+	}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: gomistakes [flags] %s %s\n", args[0], cmd.usage)
+		fs.PrintDefaults()
+	}
+	return cmd.run(ctx, opts, fs, args[1:])
+}
+
+// parse parses the flags of a command, before and after its positional
+// arguments, and checks it was given at most max of them
+func parse(fs *flag.FlagSet, args []string, max int) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		pos = append(pos, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(pos) > max {
+		fs.Usage()
+		return nil, errUsage
+	}
+	return pos, nil
+}
+
+// variantFlags adds --bad and --good to fs and returns the variants they
+// select, both when neither is given
+func variantFlags(fs *flag.FlagSet) func() []lesson.Variant {
+	bad := fs.Bool("bad", false, "run only the mistake")
+	good := fs.Bool("good", false, "run only the fix")
+	return func() []lesson.Variant {
+		switch {
+		case *bad && !*good:
+			return []lesson.Variant{lesson.Bad}
+		case *good && !*bad:
+			return []lesson.Variant{lesson.Good}
+		}
+		return lesson.Variants
+	}
+}
+
+func find(name string) (lesson.Lesson, error) {
+	l, ok := lesson.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown lesson %q; see gomistakes list", name)
+	}
+	return l, nil
+}
+
+func list(_ context.Context, _ options, fs *flag.FlagSet, args []string) error {
+	if _, err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	for _, l := range lesson.All() {
+		fmt.Println(l.Name())
+	}
+	return nil
+}
+
+func describe(_ context.Context, _ options, fs *flag.FlagSet, args []string) error {
+	pos, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	lessons := lesson.All()
+	if len(pos) == 1 {
+		l, err := find(pos[0])
+		if err != nil {
+			return err
+		}
+		lessons = []lesson.Lesson{l}
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, l := range lessons {
+		fmt.Fprintf(tw, "%s\t%s\n", l.Name(), l.Description())
+	}
+	return tw.Flush()
+}
+
+func runLesson(ctx context.Context, opts options, fs *flag.FlagSet, args []string) error {
+	variants := variantFlags(fs)
+	pos, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	l, err := find(pos[0])
+	if err != nil {
+		return err
+	}
+	return runs(ctx, opts, []lesson.Lesson{l}, variants())
+}
+
+func runAll(ctx context.Context, opts options, fs *flag.FlagSet, args []string) error {
+	variants := variantFlags(fs)
+	if _, err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	return runs(ctx, opts, lesson.All(), variants())
+}
+
+// outcome is how a run went, the goroutines it leaked, what it left on the
+// heap and, when traced, its timeline
+type outcome struct {
+	lesson.Result
+	leaks    []leakcheck.Goroutine
+	memory   *memcheck.Delta
+	timeline *timeline.Timeline
+}
+
+// traced runs f, recording its timeline and writing its execution trace to
+// the file named name in dir
+func traced(dir, name string, f func()) (*timeline.Timeline, string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, "", err
+	}
+	path := filepath.Join(dir, name+".trace")
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+	t, err := timeline.Record(file, f)
+	if err != nil {
+		return nil, path, err
+	}
+	return t, path, file.Close()
+}
+
+// results is what running variants of lessons produced
+type results struct {
+	outcomes    []outcome
+	comparisons []memcheck.Comparison
+	// files are the profiles and traces written
+	files []string
+}
+
+// failed names the runs that failed
+func (r results) failed() []string {
+	var failed []string
+	for _, o := range r.outcomes {
+		if o.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s)", o.Lesson, o.Variant))
+		}
+	}
+	return failed
+}
+
+// execute runs variants of lessons one after the other, recording how
+// each went, the goroutines it leaked and what it left on the heap
+func execute(ctx context.Context, opts options, lessons []lesson.Lesson, variants []lesson.Variant) (results, error) {
+	var r results
+	for _, l := range lessons {
+		c := memcheck.Comparison{Lesson: l.Name()}
+		for _, v := range variants {
+			fmt.Printf("=== %s (%s)\n", l.Name(), v)
+			var o outcome
+			check := leakcheck.Check()
+			var d memcheck.Delta
+			measure := func() {
+				d = memcheck.Measure(func() { o.Result = lesson.Run(ctx, l, v, opts.timeout) })
+			}
+			name := fmt.Sprintf("%s-%s", l.Name(), v)
+			var tracePath string
+			var traceErr error
+			written, err := opts.profiler.Capture(name, func() {
+				if !opts.trace {
+					measure()
+					return
+				}
+				o.timeline, tracePath, traceErr = traced(opts.profiler.Dir, name, measure)
+			})
+			r.files = append(r.files, written...)
+			if tracePath != "" {
+				r.files = append(r.files, tracePath)
+			}
+			if err = errors.Join(err, traceErr); err != nil {
+				return r, fmt.Errorf("profiling %s (%s): %w", l.Name(), v, err)
+			}
+			o.leaks = check.Leaks(leakcheck.Wait)
+			o.memory = &d
+			r.outcomes = append(r.outcomes, o)
+			if v == lesson.Bad {
+				c.Bad = &d
+			} else {
+				c.Good = &d
+			}
+		}
+		r.comparisons = append(r.comparisons, c)
+	}
+	return r, nil
+}
+
+// runs runs variants of lessons and reports how each went, the goroutines
+// it leaked and what it left on the heap, failing if any of them failed
+func runs(ctx context.Context, opts options, lessons []lesson.Lesson, variants []lesson.Variant) error {
+	r, err := execute(ctx, opts, lessons, variants)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LESSON\tVARIANT\tELAPSED\tLEAKED\tRESULT")
+	for _, o := range r.outcomes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", o.Lesson, o.Variant, o.Elapsed.Round(time.Millisecond), len(o.leaks), o.Status())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+	if err := memcheck.WriteTable(os.Stdout, r.comparisons); err != nil {
+		return err
+	}
+	if opts.leaks {
+		for _, o := range r.outcomes {
+			for _, g := range o.leaks {
+				fmt.Printf("\n=== %s (%s) leaked %s\n%s\n", o.Lesson, o.Variant, g, g.Stack)
+			}
+		}
+	}
+	for _, o := range r.outcomes {
+		if o.timeline != nil {
+			fmt.Printf("\n=== %s (%s) timeline\n", o.Lesson, o.Variant)
+			if err := o.timeline.WriteText(os.Stdout); err != nil {
+				return err
+			}
+		}
+	}
+	if len(r.files) > 0 {
+		fmt.Printf("\nfiles written to %s:\n", opts.profiler.Dir)
+		for _, f := range r.files {
+			fmt.Println(" ", filepath.Base(f))
+		}
+	}
+	if opts.pprof != "" && ctx.Err() == nil {
+		fmt.Printf("\nserving pprof on %s until interrupted\n", opts.pprof)
+		<-ctx.Done()
+	}
+	return failure(r)
+}
+
+// failure is the error for the runs of r that failed, if any
+func failure(r results) error {
+	if failed := r.failed(); len(failed) > 0 {
+		return fmt.Errorf("%d run(s) failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func bench(ctx context.Context, _ options, fs *flag.FlagSet, args []string) error {
+	count := fs.Int("count", 1, "run each benchmark `n` times")
+	benchstat := fs.Bool("benchstat", false, "write results in the go test format benchstat reads")
+	pos, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	benchmarks := lesson.Benchmarks()
+	if len(pos) == 1 {
+		if _, err := find(pos[0]); err != nil {
+			return err
+		}
+		var of []lesson.Benchmark
+		for _, b := range benchmarks {
+			if b.Lesson == pos[0] {
+				of = append(of, b)
+			}
+		}
+		benchmarks = of
+	}
+
+	if *benchstat {
+		// go test writes the format itself
+		_, err := goBench(ctx, benchmarks, *count, os.Stdout)
+		return err
+	}
+	results, err := goBench(ctx, benchmarks, *count, nil)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LESSON\tVARIANT\tBENCHMARK\tNS/OP\tB/OP\tALLOCS/OP")
+	for _, b := range benchmarks {
+		for _, r := range results[b.Name] {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\n", b.Lesson, b.Variant, b.Name, r.NsPerOp(), r.AllocedBytesPerOp(), r.AllocsPerOp())
+		}
+	}
+	return tw.Flush()
+}
+
+// goBench runs benchmarks count times each with go test -bench in the
+// lesson package, copying its output to w unless w is nil, and returns
+// the results of each by name
+func goBench(ctx context.Context, benchmarks []lesson.Benchmark, count int, w io.Writer) (map[string][]testing.BenchmarkResult, error) {
+	if len(benchmarks) == 0 {
+		return nil, nil
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		return nil, fmt.Errorf("benchmarks run with go test: %w", err)
+	}
+	names := make([]string, len(benchmarks))
+	for i, b := range benchmarks {
+		names[i] = b.Name
+	}
+	cmd := exec.CommandContext(ctx, "go", "test", "-run", "^$",
+		"-bench", "^("+strings.Join(names, "|")+")$", "-benchmem",
+		"-count", strconv.Itoa(count), "-timeout", "0", ".")
+	cmd.Dir = lesson.Dir()
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if w != nil {
+		cmd.Stdout, cmd.Stderr = io.MultiWriter(&out, w), io.MultiWriter(&out, w)
+	}
+	if err := cmd.Run(); err != nil {
+		if w != nil {
+			return nil, fmt.Errorf("go test: %w", err)
+		}
+		return nil, fmt.Errorf("go test: %w\n%s", err, out.Bytes())
+	}
+
+	results := map[string][]testing.BenchmarkResult{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		if name, r, ok := parseBenchLine(line); ok {
+			results[name] = append(results[name], r)
+		}
+	}
+	return results, nil
+}
+
+// parseBenchLine reads a result line of go test -bench -benchmem, such as
+// "BenchmarkSliceCopy-8  100  11595 ns/op  81944 B/op  2 allocs/op",
+// into the benchmark's name and result
+func parseBenchLine(line string) (string, testing.BenchmarkResult, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") || len(fields)%2 != 0 {
+		return "", testing.BenchmarkResult{}, false
+	}
+	// go test adds a -GOMAXPROCS suffix unless it is 1
+	name := fields[0]
+	if i := strings.LastIndexByte(name, '-'); i > 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			name = name[:i]
+		}
+	}
+	n, err := strconv.Atoi(fields[1])
+	if err != nil || n <= 0 {
+		return "", testing.BenchmarkResult{}, false
+	}
+	r := testing.BenchmarkResult{N: n}
+	for i := 2; i+1 < len(fields); i += 2 {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return "", testing.BenchmarkResult{}, false
+		}
+		total := v * float64(n)
+		switch fields[i+1] {
+		case "ns/op":
+			r.T = time.Duration(total)
+		case "B/op":
+			r.MemBytes = uint64(total)
+		case "allocs/op":
+			r.MemAllocs = uint64(total)
+		}
+	}
+	return name, r, true
+}
+
+// writeReport runs every lesson and, unless told not to, every benchmark
+// once and writes the results as an HTML page
+func writeReport(ctx context.Context, opts options, fs *flag.FlagSet, args []string) error {
+	variants := variantFlags(fs)
+	out := fs.String("o", "report.html", "write the report to `file`")
+	title := fs.String("title", "Go memory mistakes", "`title` of the report")
+	withBench := fs.Bool("bench", true, "run every benchmark once and include the results")
+	if _, err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	selected := variants()
+	r, err := execute(ctx, opts, lesson.All(), selected)
+	if err != nil {
+		return err
+	}
+
+	rep := report.Report{Title: *title, Generated: time.Now()}
+	for _, o := range r.outcomes {
+		rep.Runs = append(rep.Runs, report.Run{Result: o.Result, Leaked: len(o.leaks), Memory: o.memory})
+	}
+	if *withBench {
+		var benchmarks []lesson.Benchmark
+		for _, b := range lesson.Benchmarks() {
+			if slices.Contains(selected, b.Variant) {
+				benchmarks = append(benchmarks, b)
+			}
+		}
+		fmt.Printf("=== running %d benchmarks with go test\n", len(benchmarks))
+		results, err := goBench(ctx, benchmarks, 1, nil)
+		if err != nil {
+			fmt.Println("benchmarks left out:", err)
+		}
+		for _, b := range benchmarks {
+			for _, res := range results[b.Name] {
+				rep.Benchmarks = append(rep.Benchmarks, report.Benchmark{Benchmark: b, BenchmarkResult: res})
+			}
+		}
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := rep.WriteHTML(f); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Println("\nreport written to", *out)
+	return failure(r)
+}
@@ -0,0 +1,22 @@
+//go:build !linux && !darwin
+
+package fdcheck
+
+import (
+	"errors"
+	"os"
+)
+
+func openDir() (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func count(*os.File) (int, error) {
+	return 0, errors.ErrUnsupported
+}
+
+// Limit lowers the soft limit on open descriptors to n until restore is
+// called. Opening more than that fails with "too many open files".
+func Limit(n int) (restore func() error, err error) {
+	return nil, errors.ErrUnsupported
+}
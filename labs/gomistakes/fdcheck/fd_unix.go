@@ -0,0 +1,51 @@
+//go:build linux || darwin
+
+package fdcheck
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// fdDir lists a process's own descriptors
+var fdDir = "/dev/fd"
+
+func init() {
+	if runtime.GOOS == "linux" {
+		fdDir = "/proc/self/fd"
+	}
+}
+
+// openDir opens the directory listing the process's descriptors
+func openDir() (*os.File, error) {
+	return os.Open(fdDir)
+}
+
+// count lists the descriptors in d from the start
+func count(d *os.File) (int, error) {
+	if _, err := d.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return 0, err
+	}
+	return len(names) - 1, nil // Less the one listing them
+}
+
+// Limit lowers the soft limit on open descriptors to n until restore is
+// called. Opening more than that fails with "too many open files".
+func Limit(n int) (restore func() error, err error) {
+	var old syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &old); err != nil {
+		return nil, err
+	}
+	lowered := old
+	lowered.Cur = min(uint64(n), old.Cur)
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &lowered); err != nil {
+		return nil, err
+	}
+	return func() error { return syscall.Setrlimit(syscall.RLIMIT_NOFILE, &old) }, nil
+}
@@ -0,0 +1,93 @@
+// Package fdcheck counts the file descriptors the process has open: files,
+// sockets, pipes and the rest. A Monitor samples the count while code runs
+// and reports the peak, and Limit lowers how many may be open so a leak
+// runs out of them quickly.
+//
+// Counting needs a descriptor to list them with. A Monitor keeps its own
+// open, so it can count even once the rest have run out.
+package fdcheck
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Count is how many descriptors the process has open
+func Count() (int, error) {
+	d, err := openDir()
+	if err != nil {
+		return 0, err
+	}
+	defer d.Close()
+	return count(d)
+}
+
+// Usage is the descriptors open as a Monitor started, at most while it ran
+// and as it stopped
+type Usage struct {
+	Start, Peak, End int
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("%d open at start, %d at peak, %d at end", u.Start, u.Peak, u.End)
+}
+
+// Monitor samples the open descriptors until stopped
+type Monitor struct {
+	dir  *os.File
+	stop chan struct{}
+	done chan Usage
+}
+
+// Watch counts the open descriptors, less its own, now and then every
+// interval until Stop, calling report from its own goroutine with every
+// count that differs from the last
+func Watch(every time.Duration, report func(open int)) (*Monitor, error) {
+	d, err := openDir()
+	if err != nil {
+		return nil, err
+	}
+	n, err := count(d)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	m := &Monitor{dir: d, stop: make(chan struct{}), done: make(chan Usage)}
+	go m.sample(every, Usage{Start: n, Peak: n, End: n}, report)
+	return m, nil
+}
+
+func (m *Monitor) sample(every time.Duration, u Usage, report func(int)) {
+	take := func() {
+		n, err := count(m.dir)
+		if err != nil {
+			return
+		}
+		if n != u.End && report != nil {
+			report(n)
+		}
+		u.Peak, u.End = max(u.Peak, n), n
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			take()
+		case <-m.stop:
+			take()
+			m.done <- u
+			return
+		}
+	}
+}
+
+// Stop takes a last sample and returns what the monitor saw. report is no
+// longer called once Stop returns.
+func (m *Monitor) Stop() Usage {
+	close(m.stop)
+	u := <-m.done
+	m.dir.Close()
+	return u
+}
@@ -0,0 +1,152 @@
+// Package leakcheck finds goroutines a piece of code started and left
+// running. It records the goroutines alive before, and once the code is
+// done waits for those it started to exit, reporting the ones that do not
+// with where they were created.
+//
+// From a test:
+//
+//	defer leakcheck.Check().Verify(t)
+//
+// The runtime records only the call that started a goroutine; run with
+// GODEBUG=tracebackancestors=N to add the stacks of its N ancestors.
+package leakcheck
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Wait is how long Leaks waits by default for goroutines to exit
+var Wait = time.Second
+
+// poll is how often the goroutines are listed while waiting
+const poll = 10 * time.Millisecond
+
+// Goroutine is one goroutine as the runtime prints it
+type Goroutine struct {
+	ID int
+	// State is what the goroutine is doing, e.g. "select" or "chan receive"
+	State string
+	// CreatedBy is the function that started the goroutine and where
+	CreatedBy string
+	// Stack is the goroutine's full trace
+	Stack string
+}
+
+func (g Goroutine) String() string {
+	return fmt.Sprintf("goroutine %d [%s] created by %s", g.ID, g.State, g.CreatedBy)
+}
+
+// Goroutines lists every running goroutine ordered by ID
+func Goroutines() []Goroutine {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	var gs []Goroutine
+	for _, block := range bytes.Split(buf, []byte("\n\n")) {
+		if g, ok := parse(string(block)); ok {
+			gs = append(gs, g)
+		}
+	}
+	sort.Slice(gs, func(i, j int) bool { return gs[i].ID < gs[j].ID })
+	return gs
+}
+
+// parse reads a goroutine from its trace:
+//
+//	goroutine 7 [select, 2 minutes]:
+//	main.work(...)
+//		/src/main.go:12 +0x1d
+//	created by main.main in goroutine 1
+//		/src/main.go:8 +0x25
+func parse(trace string) (Goroutine, bool) {
+	header, rest, _ := strings.Cut(strings.TrimSpace(trace), "\n")
+	id, state, ok := strings.Cut(strings.TrimPrefix(header, "goroutine "), " [")
+	if !ok || !strings.HasPrefix(header, "goroutine ") {
+		return Goroutine{}, false
+	}
+	g := Goroutine{Stack: trace}
+	var err error
+	if g.ID, err = strconv.Atoi(id); err != nil {
+		return Goroutine{}, false
+	}
+	g.State, _, _ = strings.Cut(strings.TrimSuffix(state, "]:"), ",")
+
+	lines := strings.Split(rest, "\n")
+	for i, line := range lines {
+		fn, ok := strings.CutPrefix(line, "created by ")
+		if !ok {
+			continue
+		}
+		g.CreatedBy = fn
+		if i+1 < len(lines) {
+			at, _, _ := strings.Cut(strings.TrimSpace(lines[i+1]), " +0x")
+			g.CreatedBy += " at " + at
+		}
+	}
+	return g, true
+}
+
+// Checker remembers the goroutines running when it was made
+type Checker struct {
+	before map[int]bool
+}
+
+// Check records the goroutines running now
+func Check() *Checker {
+	c := &Checker{before: map[int]bool{}}
+	for _, g := range Goroutines() {
+		c.before[g.ID] = true
+	}
+	return c
+}
+
+// started lists the goroutines running now that were not when c was made
+func (c *Checker) started() []Goroutine {
+	var gs []Goroutine
+	for _, g := range Goroutines() {
+		if !c.before[g.ID] {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
+
+// Leaks waits up to wait for the goroutines started since Check to exit
+// and returns those still running
+func (c *Checker) Leaks(wait time.Duration) []Goroutine {
+	deadline := time.Now().Add(wait)
+	for {
+		gs := c.started()
+		if len(gs) == 0 || !time.Now().Before(deadline) {
+			return gs
+		}
+		time.Sleep(poll)
+	}
+}
+
+// TB is the part of testing.TB Verify reports to
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Verify fails t for every goroutine started since Check still running
+// after Wait
+func (c *Checker) Verify(t TB) {
+	t.Helper()
+	for _, g := range c.Leaks(Wait) {
+		t.Errorf("leaked %s\n%s", g, g.Stack)
+	}
+}
@@ -0,0 +1,103 @@
+package lesson
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// 11. time.After in a Select Loop
+//
+// Every iteration allocates a timer and its channel. Before Go 1.23 a
+// timer was not collected until it fired either, so each stayed on the
+// heap for the whole idle timeout; now they are garbage, but garbage made
+// per event.
+const (
+	// afterEvents is how many events each variant handles and afterIdle
+	// how long either waits for the next before giving up
+	afterEvents = 100_000
+	afterIdle   = time.Minute
+)
+
+type afterLesson struct{}
+
+func init() { Register(afterLesson{}) }
+
+func (afterLesson) Name() string { return "after" }
+
+func (afterLesson) Description() string {
+	return "time.After in a select loop makes a new timer every iteration, each alive until it fires"
+}
+
+// produce sends n events and closes the channel, or stops once ctx is
+// done
+func produce(ctx context.Context, n int) <-chan int {
+	events := make(chan int)
+	go func() {
+		defer close(events)
+		for i := 0; i < n; i++ {
+			select {
+			case events <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// Bad: A new timer per event, none of them stopped
+func (afterLesson) RunBad(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, handled := produce(ctx, afterEvents), 0
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				fmt.Println(handled, "events handled")
+				return nil
+			}
+			handled++
+		case <-time.After(afterIdle):
+			fmt.Println("idle")
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Good: One timer, reset for every event
+func (afterLesson) RunGood(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	idle := time.NewTimer(afterIdle)
+	defer idle.Stop()
+	events, handled := produce(ctx, afterEvents), 0
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				fmt.Println(handled, "events handled")
+				return nil
+			}
+			handled++
+			if !idle.Stop() {
+				// Drained so Reset cannot deliver a stale expiry
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(afterIdle)
+		case <-idle.C:
+			fmt.Println("idle")
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
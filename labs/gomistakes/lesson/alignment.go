@@ -0,0 +1,109 @@
+package lesson
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"text/tabwriter"
+	"unsafe"
+
+	"gomistakes/memcheck"
+)
+
+// 24. Struct Field Alignment
+//
+// Every field starts at a multiple of its alignment and a struct's size
+// is a multiple of its largest, so small fields between large ones leave
+// padding. Ordering fields from the largest alignment down packs them;
+// the fieldalignment analyzer in golang.org/x/tools finds structs that
+// would shrink.
+const alignEvents = 2_000_000
+
+// paddedEvent has its fields in the order they came to mind
+type paddedEvent struct {
+	Active  bool
+	ID      int64
+	Retries uint8
+	Code    int32
+	Urgent  bool
+	Time    float64
+	Sampled bool
+	Port    uint16
+}
+
+// packedEvent has the same fields, largest alignment first
+type packedEvent struct {
+	ID      int64
+	Time    float64
+	Code    int32
+	Port    uint16
+	Retries uint8
+	Active  bool
+	Urgent  bool
+	Sampled bool
+}
+
+// printLayout prints the size and alignment of T and where each of its
+// fields sits, with the padding after it
+func printLayout[T any]() {
+	var v T
+	t := reflect.TypeOf(v)
+	fmt.Printf("%s: unsafe.Sizeof %d, unsafe.Alignof %d\n", t.Name(), unsafe.Sizeof(v), unsafe.Alignof(v))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  FIELD\tTYPE\tOFFSET\tSIZE\tALIGN\tPADDING")
+	padding := uintptr(0)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		end := t.Size()
+		if i+1 < t.NumField() {
+			end = t.Field(i + 1).Offset
+		}
+		pad := end - f.Offset - f.Type.Size()
+		padding += pad
+		fmt.Fprintf(tw, "  %s\t%s\t%d\t%d\t%d\t%d\n", f.Name, f.Type, f.Offset, f.Type.Size(), f.Type.Align(), pad)
+	}
+	tw.Flush()
+	fmt.Printf("  %d of %d bytes are padding\n", padding, t.Size())
+}
+
+// allocEvents makes alignEvents Ts, setting each with set, and prints the
+// heap they took
+func allocEvents[T any](set func(*T, int)) {
+	var events []T
+	d := memcheck.Measure(func() {
+		events = make([]T, alignEvents)
+		for i := range events {
+			set(&events[i], i)
+		}
+	})
+	fmt.Printf("%d events: %s allocated, %s held\n", len(events), memcheck.Bytes(d.Allocated()), memcheck.Bytes(d.Heap()))
+}
+
+type alignmentLesson struct{}
+
+func init() { Register(alignmentLesson{}) }
+
+func (alignmentLesson) Name() string { return "alignment" }
+
+func (alignmentLesson) Description() string {
+	return "Struct fields out of alignment order pad every instance with unused bytes"
+}
+
+// Bad: Small fields between large ones, each padded out
+func (alignmentLesson) RunBad(context.Context) error {
+	printLayout[paddedEvent]()
+	allocEvents(func(e *paddedEvent, i int) {
+		e.ID, e.Code, e.Active = int64(i), int32(i), true
+	})
+	return nil
+}
+
+// Good: Fields ordered by alignment, only the tail padded
+func (alignmentLesson) RunGood(context.Context) error {
+	printLayout[packedEvent]()
+	allocEvents(func(e *packedEvent, i int) {
+		e.ID, e.Code, e.Active = int64(i), int32(i), true
+	})
+	return nil
+}
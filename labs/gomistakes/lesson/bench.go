@@ -0,0 +1,96 @@
+package lesson
+
+import (
+	"path/filepath"
+	"runtime"
+)
+
+// Benchmark names a benchmark of bench_test.go, which measures the mistake
+// or the fix of a lesson per operation
+type Benchmark struct {
+	// Name follows the Go convention, e.g. BenchmarkSliceReslice
+	Name    string
+	Lesson  string
+	Variant Variant
+}
+
+// Benchmarks lists a pair of benchmarks, mistake then fix, for every
+// lesson whose mistake costs time or memory, in lesson order. Lessons
+// whose mistake cannot be measured, such as one crashing the program,
+// compare their fixes instead.
+func Benchmarks() []Benchmark {
+	return []Benchmark{
+		{"BenchmarkAfterInSelect", "after", Bad},
+		{"BenchmarkAfterResetTimer", "after", Good},
+		{"BenchmarkAlignmentPadded", "alignment", Bad},
+		{"BenchmarkAlignmentPacked", "alignment", Good},
+		{"BenchmarkChannelBlocked", "channel", Bad},
+		{"BenchmarkChannelCancelled", "channel", Good},
+		{"BenchmarkClosureCaptureObject", "closure", Bad},
+		{"BenchmarkClosureCaptureSize", "closure", Good},
+		{"BenchmarkConcurrentMapRWMutexReads90", "concurrentmap", Good},
+		{"BenchmarkConcurrentMapSyncMapReads90", "concurrentmap", Good},
+		{"BenchmarkConcurrentMapShardedReads90", "concurrentmap", Good},
+		{"BenchmarkConcurrentMapRWMutexReads50", "concurrentmap", Good},
+		{"BenchmarkConcurrentMapSyncMapReads50", "concurrentmap", Good},
+		{"BenchmarkConcurrentMapShardedReads50", "concurrentmap", Good},
+		{"BenchmarkConcurrentMapRWMutexReads10", "concurrentmap", Good},
+		{"BenchmarkConcurrentMapSyncMapReads10", "concurrentmap", Good},
+		{"BenchmarkConcurrentMapShardedReads10", "concurrentmap", Good},
+		{"BenchmarkDeferInLoop", "defer", Bad},
+		{"BenchmarkDeferPerIteration", "defer", Good},
+		{"BenchmarkDeferArgsInLoop", "deferargs", Bad},
+		{"BenchmarkDeferArgsInPlace", "deferargs", Good},
+		{"BenchmarkDeferArgsOpenCoded", "deferargs", Good},
+		{"BenchmarkDeferArgsExplicit", "deferargs", Good},
+		{"BenchmarkEscapePointer", "escape", Bad},
+		{"BenchmarkEscapeValue", "escape", Good},
+		{"BenchmarkEscapeInterface", "escape", Bad},
+		{"BenchmarkEscapeAppendInt", "escape", Good},
+		{"BenchmarkEscapeClosureKept", "escape", Bad},
+		{"BenchmarkEscapeClosureCalled", "escape", Good},
+		{"BenchmarkGlobalMap", "global", Bad},
+		{"BenchmarkGlobalSyncMap", "global", Good},
+		{"BenchmarkGoroutineStringConcat", "goroutine", Bad},
+		{"BenchmarkGoroutineStringBuilder", "goroutine", Good},
+		{"BenchmarkHTTPBodyOpen", "http", Bad},
+		{"BenchmarkHTTPBodyClosed", "http", Good},
+		{"BenchmarkHTTPClientPerRequest", "httpclient", Bad},
+		{"BenchmarkHTTPClientShared", "httpclient", Good},
+		{"BenchmarkLoopVarShared", "loopvar", Bad},
+		{"BenchmarkLoopVarParam", "loopvar", Good},
+		{"BenchmarkLRUMapGrow", "lru", Bad},
+		{"BenchmarkLRUBounded", "lru", Good},
+		{"BenchmarkMapCacheSetGet", "map", Bad},
+		{"BenchmarkMapBetterCacheSetGet", "map", Good},
+		{"BenchmarkMapGrowUnsized", "mapgrow", Bad},
+		{"BenchmarkMapGrowSized", "mapgrow", Good},
+		{"BenchmarkPoolBufferPerRequest", "pool", Bad},
+		{"BenchmarkPoolCapped", "pool", Good},
+		{"BenchmarkPreallocAppend", "prealloc", Bad},
+		{"BenchmarkPreallocMake", "prealloc", Good},
+		{"BenchmarkSliceReslice", "slice", Bad},
+		{"BenchmarkSliceCopy", "slice", Good},
+		{"BenchmarkTimerUnstopped", "timer", Bad},
+		{"BenchmarkTimerStopped", "timer", Good},
+		{"BenchmarkWorkerPoolGoroutinePerTask", "workerpool", Bad},
+		{"BenchmarkWorkerPoolWorkers", "workerpool", Good},
+	}
+}
+
+// Dir is the directory of the package's source, where go test -bench
+// runs the benchmarks
+func Dir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}
+
+// sinks keep results alive so the compiler cannot optimize the work away
+var (
+	sinkInts    []int
+	sinkFunc    func() int
+	sinkString  string
+	sinkObjects *LargeObject
+	sinkEvents  any
+	sinkInt     int
+)
@@ -0,0 +1,633 @@
+package lesson
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gomistakes/cache"
+)
+
+// leftovers holds what the operations of a leaking benchmark leave
+// behind, releasing it off the clock every batch operations so long runs
+// do not exhaust memory or descriptors
+type leftovers[T any] struct {
+	b       *testing.B
+	items   []T
+	release func(T)
+}
+
+const batch = 1024
+
+func newLeftovers[T any](b *testing.B, release func(T)) *leftovers[T] {
+	l := &leftovers[T]{b: b, items: make([]T, 0, batch), release: release}
+	b.Cleanup(l.flush)
+	return l
+}
+
+func (l *leftovers[T]) add(v T) {
+	l.items = append(l.items, v)
+	if len(l.items) == cap(l.items) {
+		l.b.StopTimer()
+		l.flush()
+		l.b.StartTimer()
+	}
+}
+
+func (l *leftovers[T]) flush() {
+	for _, v := range l.items {
+		l.release(v)
+	}
+	l.items = l.items[:0]
+}
+
+func BenchmarkAfterInSelect(b *testing.B) {
+	events := make(chan int, 1)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		events <- i
+		select {
+		case <-events:
+		case <-time.After(afterIdle):
+		}
+	}
+}
+
+func BenchmarkAfterResetTimer(b *testing.B) {
+	events := make(chan int, 1)
+	idle := time.NewTimer(afterIdle)
+	defer idle.Stop()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		events <- i
+		select {
+		case <-events:
+			idle.Reset(afterIdle)
+		case <-idle.C:
+		}
+	}
+}
+
+// benchEventCount is how many events the alignment benchmarks allocate per
+// operation
+const benchEventCount = 1 << 16
+
+func benchEvents[T any](b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sinkEvents = make([]T, benchEventCount)
+	}
+}
+
+func BenchmarkAlignmentPadded(b *testing.B) { benchEvents[paddedEvent](b) }
+func BenchmarkAlignmentPacked(b *testing.B) { benchEvents[packedEvent](b) }
+
+func BenchmarkChannelBlocked(b *testing.B) {
+	b.ReportAllocs()
+	pending := newLeftovers(b, func(ch chan int) { close(ch) })
+	for i := 0; i < b.N; i++ {
+		ch := make(chan int)
+		go func() {
+			<-ch // Blocked until the benchmark releases it
+		}()
+		pending.add(ch)
+	}
+}
+
+func BenchmarkChannelCancelled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := make(chan int)
+		go func() {
+			select {
+			case <-ch:
+			case <-ctx.Done():
+			}
+		}()
+		cancel()
+	}
+}
+
+func BenchmarkClosureCaptureObject(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		obj := &LargeObject{data: make([]byte, 64<<10)}
+		sinkFunc = func() int { return len(obj.data) }
+	}
+}
+
+func BenchmarkClosureCaptureSize(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		obj := &LargeObject{data: make([]byte, 64<<10)}
+		size := len(obj.data)
+		sinkFunc = func() int { return size }
+	}
+}
+
+// benchStore has parallel goroutines do operations on a store, reads
+// percent of them reads
+func benchStore[S mapStore](newStore func() S, reads int) func(b *testing.B) {
+	return func(b *testing.B) {
+		s := newStore()
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				if i%100 < reads {
+					s.Load(i % mapKeys)
+				} else {
+					s.Store(i%mapKeys, i)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkConcurrentMapRWMutexReads90(b *testing.B) { benchStore(newMutexMap, 90)(b) }
+func BenchmarkConcurrentMapSyncMapReads90(b *testing.B) { benchStore(newSyncMap, 90)(b) }
+func BenchmarkConcurrentMapShardedReads90(b *testing.B) { benchStore(newShardedMap, 90)(b) }
+func BenchmarkConcurrentMapRWMutexReads50(b *testing.B) { benchStore(newMutexMap, 50)(b) }
+func BenchmarkConcurrentMapSyncMapReads50(b *testing.B) { benchStore(newSyncMap, 50)(b) }
+func BenchmarkConcurrentMapShardedReads50(b *testing.B) { benchStore(newShardedMap, 50)(b) }
+func BenchmarkConcurrentMapRWMutexReads10(b *testing.B) { benchStore(newMutexMap, 10)(b) }
+func BenchmarkConcurrentMapSyncMapReads10(b *testing.B) { benchStore(newSyncMap, 10)(b) }
+func BenchmarkConcurrentMapShardedReads10(b *testing.B) { benchStore(newShardedMap, 10)(b) }
+
+// deferFiles is how many files each defer benchmark operation opens
+const deferFiles = 100
+
+func deferFile(b *testing.B) string {
+	path := filepath.Join(b.TempDir(), "output.txt")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkDeferInLoop(b *testing.B) {
+	path := deferFile(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		func() {
+			for j := 0; j < deferFiles; j++ {
+				file, err := os.Open(path)
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer file.Close() // Won't be called until function returns
+			}
+		}()
+	}
+}
+
+func BenchmarkDeferPerIteration(b *testing.B) {
+	path := deferFile(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < deferFiles; j++ {
+			func() {
+				file, err := os.Open(path)
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer file.Close() // Called when anonymous function returns
+			}()
+		}
+	}
+}
+
+// benchVisits visits deferVisits elements per operation with loop
+func benchVisits(loop func([]int, func(int))) func(b *testing.B) {
+	return func(b *testing.B) {
+		xs := make([]int, deferVisits)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			loop(xs, func(x int) { sinkInt += x })
+		}
+	}
+}
+
+func BenchmarkDeferArgsInLoop(b *testing.B)  { benchVisits(visitDeferred)(b) }
+func BenchmarkDeferArgsInPlace(b *testing.B) { benchVisits(visitInPlace)(b) }
+
+var benchMu sync.Mutex
+
+// lockedDeferred increments sinkInt under benchMu, unlocking with a defer
+// the compiler open codes
+func lockedDeferred() {
+	benchMu.Lock()
+	defer benchMu.Unlock()
+	sinkInt++
+}
+
+// lockedExplicit increments sinkInt under benchMu, unlocking by hand
+func lockedExplicit() {
+	benchMu.Lock()
+	sinkInt++
+	benchMu.Unlock()
+}
+
+func benchLocked(locked func()) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			locked()
+		}
+	}
+}
+
+func BenchmarkDeferArgsOpenCoded(b *testing.B) { benchLocked(lockedDeferred)(b) }
+func BenchmarkDeferArgsExplicit(b *testing.B)  { benchLocked(lockedExplicit)(b) }
+
+// benchCall benchmarks a call of one of escapeCases
+func benchCall(call func(i int) int) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sinkInt += call(i)
+		}
+	}
+}
+
+func BenchmarkEscapePointer(b *testing.B)       { benchCall(escapeCases[0].bad.call)(b) }
+func BenchmarkEscapeValue(b *testing.B)         { benchCall(escapeCases[0].good.call)(b) }
+func BenchmarkEscapeInterface(b *testing.B)     { benchCall(escapeCases[1].bad.call)(b) }
+func BenchmarkEscapeAppendInt(b *testing.B)     { benchCall(escapeCases[1].good.call)(b) }
+func BenchmarkEscapeClosureKept(b *testing.B)   { benchCall(escapeCases[2].bad.call)(b) }
+func BenchmarkEscapeClosureCalled(b *testing.B) { benchCall(escapeCases[2].good.call)(b) }
+
+func BenchmarkGlobalMap(b *testing.B) {
+	b.ReportAllocs()
+	stored := newLeftovers(b, func(key string) { delete(globalCache, key) })
+	for i := 0; i < b.N; i++ {
+		key := strconv.Itoa(i)
+		globalCache[key] = &LargeObject{data: make([]byte, 64)}
+		sinkObjects = globalCache[key]
+		stored.add(key)
+	}
+}
+
+func BenchmarkGlobalSyncMap(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		key := strconv.Itoa(i)
+		SetGlobalCache(key, &LargeObject{data: make([]byte, 64)})
+		sinkObjects = GetGlobalCache(key)
+		betterCache.Delete(key)
+	}
+}
+
+// benchLine is what the string building benchmarks append per line
+const benchLine = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+
+// benchLines is how many lines each string building operation appends
+const benchLines = 100
+
+func BenchmarkGoroutineStringConcat(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := ""
+		for j := 0; j < benchLines; j++ {
+			s += benchLine
+		}
+		sinkString = s
+	}
+}
+
+func BenchmarkGoroutineStringBuilder(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var sb strings.Builder
+		for j := 0; j < benchLines; j++ {
+			sb.WriteString(benchLine)
+		}
+		sinkString = sb.String()
+	}
+}
+
+func benchServer(b *testing.B) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	b.Cleanup(srv.Close)
+	return srv
+}
+
+func BenchmarkHTTPBodyOpen(b *testing.B) {
+	srv := benchServer(b)
+	open := newLeftovers(b, func(body io.ReadCloser) { body.Close() })
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := get(context.Background(), srv.URL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.ReadAll(resp.Body); err != nil {
+			b.Fatal(err)
+		}
+		open.add(resp.Body)
+	}
+}
+
+func BenchmarkHTTPBodyClosed(b *testing.B) {
+	srv := benchServer(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := get(context.Background(), srv.URL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.ReadAll(resp.Body); err != nil {
+			b.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+}
+
+// fetchOnce makes a request to url with client and reads the response
+func fetchOnce(b *testing.B, client *http.Client, url string) {
+	resp, err := client.Get(url)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkHTTPClientPerRequest(b *testing.B) {
+	srv := benchServer(b)
+	idle := newLeftovers(b, func(t *http.Transport) { t.CloseIdleConnections() })
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		transport := &http.Transport{}
+		fetchOnce(b, &http.Client{Transport: transport}, srv.URL)
+		idle.add(transport)
+	}
+}
+
+func BenchmarkHTTPClientShared(b *testing.B) {
+	srv := benchServer(b)
+	transport := &http.Transport{MaxIdleConnsPerHost: clientWorkers}
+	b.Cleanup(transport.CloseIdleConnections)
+	client := &http.Client{Transport: transport}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fetchOnce(b, client, srv.URL)
+	}
+}
+
+// benchKeys is how many keys the cache benchmarks cycle through
+const benchKeys = 1024
+
+func BenchmarkMapCacheSetGet(b *testing.B) {
+	cache := &Cache{items: make(map[string][]byte)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		key := strconv.Itoa(i % benchKeys)
+		cache.Set(key, []byte("value"))
+		cache.RLock()
+		_ = cache.items[key]
+		cache.RUnlock()
+	}
+}
+
+func BenchmarkMapBetterCacheSetGet(b *testing.B) {
+	cache := NewBetterCache(time.Minute, benchKeys)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		key := strconv.Itoa(i % benchKeys)
+		cache.Set(key, []byte("value"))
+		cache.Get(key)
+	}
+}
+
+func BenchmarkLoopVarShared(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sinkInts = captureShared()
+	}
+}
+
+func BenchmarkLoopVarParam(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sinkInts = captureParam()
+	}
+}
+
+func BenchmarkLRUMapGrow(b *testing.B) {
+	cache := &Cache{items: make(map[string][]byte)}
+	b.ReportAllocs()
+	stored := newLeftovers(b, func(key string) { delete(cache.items, key) })
+	for i := 0; i < b.N; i++ {
+		key := strconv.Itoa(i)
+		cache.Set(key, []byte("value"))
+		stored.add(key)
+	}
+}
+
+func BenchmarkLRUBounded(b *testing.B) {
+	lru := cache.New[string, []byte](benchKeys, time.Minute)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lru.Set(strconv.Itoa(i), []byte("value"))
+	}
+}
+
+// benchRequest is the request the pool benchmarks serve, never a large one
+const benchRequest = 0
+
+// benchMapKeys is how many keys the mapgrow benchmarks insert per
+// operation
+const benchMapKeys = 1 << 16
+
+// benchMapGrow inserts benchMapKeys keys into a map made with hint
+func benchMapGrow(hint int) func(b *testing.B) {
+	return func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m := make(map[int]int, hint)
+			for j := 0; j < benchMapKeys; j++ {
+				m[j] = j
+			}
+		}
+	}
+}
+
+func BenchmarkMapGrowUnsized(b *testing.B) { benchMapGrow(0)(b) }
+func BenchmarkMapGrowSized(b *testing.B)   { benchMapGrow(benchMapKeys)(b) }
+
+func BenchmarkPoolBufferPerRequest(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := new(bytes.Buffer)
+		respond(buf, benchRequest)
+	}
+}
+
+func BenchmarkPoolCapped(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := goodPool.Get().(*bytes.Buffer)
+		respond(buf, benchRequest)
+		if buf.Cap() <= poolMaxCap {
+			buf.Reset()
+			goodPool.Put(buf)
+		}
+	}
+}
+
+// benchAppends is how many ints the prealloc benchmarks append per
+// operation
+const benchAppends = 1 << 16
+
+func BenchmarkPreallocAppend(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var s []int
+		for j := 0; j < benchAppends; j++ {
+			s = append(s, j)
+		}
+		sinkInts = s
+	}
+}
+
+func BenchmarkPreallocMake(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := make([]int, 0, benchAppends)
+		for j := 0; j < benchAppends; j++ {
+			s = append(s, j)
+		}
+		sinkInts = s
+	}
+}
+
+func BenchmarkSliceReslice(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data := make([]int, 10_000)
+		sinkInts = data[len(data)-3:]
+	}
+}
+
+func BenchmarkSliceCopy(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data := make([]int, 10_000)
+		small := make([]int, 3)
+		copy(small, data[len(data)-3:])
+		sinkInts = small
+	}
+}
+
+func BenchmarkTimerUnstopped(b *testing.B) {
+	b.ReportAllocs()
+	pending := newLeftovers(b, func(t *time.Timer) { t.Stop() })
+	for i := 0; i < b.N; i++ {
+		pending.add(time.NewTimer(time.Hour)) // Never stopped by the operation
+	}
+}
+
+func BenchmarkTimerStopped(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		t := time.NewTimer(time.Hour)
+		t.Stop()
+	}
+}
+
+// benchTasks is how many tasks each worker pool benchmark operation runs
+const benchTasks = 1000
+
+func BenchmarkWorkerPoolGoroutinePerTask(b *testing.B) {
+	var sum atomic.Int64
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		for t := 0; t < benchTasks; t++ {
+			wg.Add(1)
+			go func(t int) {
+				defer wg.Done()
+				tinyTask(t, &sum)
+			}(t)
+		}
+		wg.Wait()
+	}
+}
+
+func BenchmarkWorkerPoolWorkers(b *testing.B) {
+	var sum atomic.Int64
+	tasks := make(chan int, 256)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				tinyTask(t, &sum)
+			}
+		}()
+	}
+	defer func() {
+		close(tasks)
+		wg.Wait()
+	}()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for t := 0; t < benchTasks; t++ {
+			tasks <- t
+		}
+	}
+}
+
+// TestBenchmarksListed checks Benchmarks names every benchmark of this
+// file, and only those, since the runner finds them through it
+func TestBenchmarksListed(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "bench_test.go", nil, parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defined := map[string]bool{}
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok && strings.HasPrefix(fd.Name.Name, "Benchmark") {
+			defined[fd.Name.Name] = true
+		}
+	}
+	for _, b := range Benchmarks() {
+		if !defined[b.Name] {
+			t.Errorf("%s is listed but not defined", b.Name)
+		}
+		delete(defined, b.Name)
+	}
+	for name := range defined {
+		t.Errorf("%s is defined but not listed", name)
+	}
+}
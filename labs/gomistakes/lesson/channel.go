@@ -7,19 +7,32 @@ import (
 )
 
 // 5. Channel Leak
-func ChannelLeak() {
-	// Bad: Channel and goroutine never cleanup
+type channelLesson struct{}
+
+func init() { Register(channelLesson{}) }
+
+func (channelLesson) Name() string { return "channel" }
+
+func (channelLesson) Description() string {
+	return "A goroutine receiving from a channel nobody sends on blocks forever"
+}
+
+// Bad: Channel and goroutine never cleanup
+func (channelLesson) RunBad(context.Context) error {
 	ch := make(chan int)
 	go func() {
 		val := <-ch // Blocked forever if nothing sends
 		fmt.Println(val)
 	}()
+	return nil
+}
 
-	// Good: Use context for cancellation
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+// Good: Use context for cancellation
+func (channelLesson) RunGood(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
 	defer cancel()
 
-	ch = make(chan int)
+	ch := make(chan int)
 	go func() {
 		select {
 		case val := <-ch:
@@ -28,5 +41,5 @@ func ChannelLeak() {
 			return
 		}
 	}()
-
+	return nil
 }
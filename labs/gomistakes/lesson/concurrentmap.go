@@ -0,0 +1,184 @@
+package lesson
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// 15. Concurrent Map Access
+const (
+	// mapWorkers goroutines each do mapOps operations on mapKeys keys
+	mapWorkers = 8
+	mapOps     = 100_000
+	mapKeys    = 1024
+	// mapShards is how many maps a shardedMap splits its keys over
+	mapShards = 32
+)
+
+// mapStore is a map safe for concurrent use
+type mapStore interface {
+	Load(key int) (int, bool)
+	Store(key, value int)
+}
+
+// Good: A map behind a read-write mutex, simple and fast while writes are
+// rare
+type mutexMap struct {
+	mu sync.RWMutex
+	m  map[int]int
+}
+
+func newMutexMap() *mutexMap { return &mutexMap{m: make(map[int]int)} }
+
+func (m *mutexMap) Load(key int) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.m[key]
+	return v, ok
+}
+
+func (m *mutexMap) Store(key, value int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[key] = value
+}
+
+// Good: sync.Map, built for keys written once and read many times, or by
+// disjoint goroutines; it boxes every value
+type syncMap struct {
+	m sync.Map
+}
+
+func newSyncMap() *syncMap { return &syncMap{} }
+
+func (m *syncMap) Load(key int) (int, bool) {
+	v, ok := m.m.Load(key)
+	if !ok {
+		return 0, false
+	}
+	return v.(int), true
+}
+
+func (m *syncMap) Store(key, value int) { m.m.Store(key, value) }
+
+// Good: Keys spread over maps each behind its own mutex, so writers to
+// different shards do not wait for each other
+type shardedMap struct {
+	shards [mapShards]mutexMap
+}
+
+func newShardedMap() *shardedMap {
+	m := &shardedMap{}
+	for i := range m.shards {
+		m.shards[i].m = make(map[int]int)
+	}
+	return m
+}
+
+func (m *shardedMap) shard(key int) *mutexMap { return &m.shards[uint(key)%mapShards] }
+
+func (m *shardedMap) Load(key int) (int, bool) { return m.shard(key).Load(key) }
+
+func (m *shardedMap) Store(key, value int) { m.shard(key).Store(key, value) }
+
+// mapStores are the stores compared and how to make each
+var mapStores = []struct {
+	name string
+	new  func() mapStore
+}{
+	{"RWMutex map", func() mapStore { return newMutexMap() }},
+	{"sync.Map", func() mapStore { return newSyncMap() }},
+	{"sharded map", func() mapStore { return newShardedMap() }},
+}
+
+// mapMixes are the percentages of reads the stores are compared at
+var mapMixes = []int{90, 50, 10}
+
+// hammer has mapWorkers goroutines do mapOps operations each on s,
+// reads percent of them reads
+func hammer(ctx context.Context, s mapStore, reads int) error {
+	var wg sync.WaitGroup
+	for w := 0; w < mapWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < mapOps && ctx.Err() == nil; i++ {
+				key := (w*mapOps + i) % mapKeys
+				if i%100 < reads {
+					s.Load(key)
+				} else {
+					s.Store(key, i)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// Bad: Goroutines write a plain map. The runtime detects it and crashes
+// the program, so it runs in a copy of it.
+func concurrentMapWrites() {
+	// Goroutines on one processor are not preempted inside a map write,
+	// so the race needs threads the operating system interleaves
+	runtime.GOMAXPROCS(mapWorkers)
+	m := make(map[int]int)
+	var wg sync.WaitGroup
+	for w := 0; w < mapWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				m[(w*mapOps+i)%mapKeys] = i
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+type concurrentmapLesson struct{}
+
+func init() { Register(concurrentmapLesson{}) }
+
+func (concurrentmapLesson) Name() string { return "concurrentmap" }
+
+func (concurrentmapLesson) Description() string {
+	return "Writing a map from several goroutines crashes the program; a mutex, sync.Map or shards make it safe"
+}
+
+func (concurrentmapLesson) RunBad(ctx context.Context) error {
+	out, err := subprocess(ctx, "concurrentmap")
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || !bytes.Contains(out, []byte("concurrent map")) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("expected the subprocess to crash on concurrent map writes, got %v", err)
+	}
+	line, _, _ := bytes.Cut(out, []byte("\n"))
+	fmt.Printf("subprocess crashed (%s): %s\n", exit, line)
+	return nil
+}
+
+func (concurrentmapLesson) RunGood(ctx context.Context) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STORE\tREADS\tELAPSED")
+	for _, reads := range mapMixes {
+		for _, s := range mapStores {
+			start := time.Now()
+			if err := hammer(ctx, s.new(), reads); err != nil {
+				return err
+			}
+			fmt.Fprintf(tw, "%s\t%d%%\t%s\n", s.name, reads, time.Since(start).Round(time.Microsecond))
+		}
+	}
+	return tw.Flush()
+}
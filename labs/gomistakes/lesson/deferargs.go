@@ -0,0 +1,120 @@
+package lesson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 29. Deferred Arguments Evaluated Early
+//
+// A defer statement evaluates the call's arguments when it runs, not when
+// the call does: a duration or an error passed to it is whatever it was
+// at that point. A deferred closure reads them at return instead. Deferred
+// calls in a loop also all wait for the function to return, and cannot be
+// open coded by the compiler, so each costs a record at run time.
+const (
+	// deferWork is how long the measured operation takes
+	deferWork = 20 * time.Millisecond
+	// deferVisits elements are visited deferRounds times to time the loops
+	deferVisits = 64
+	deferRounds = 100_000
+)
+
+var errDeferFailed = errors.New("operation failed")
+
+// traced prints how long op took
+func traced(op string, took time.Duration) {
+	fmt.Printf("  %s took %s\n", op, took.Round(time.Millisecond))
+}
+
+// reported prints how op ended
+func reported(op string, err error) {
+	fmt.Printf("  %s ended with error: %v\n", op, err)
+}
+
+// elapsed is time.Since(start). go vet reports time.Since deferred as an
+// argument, but not a helper wrapping it.
+func elapsed(start time.Time) time.Duration { return time.Since(start) }
+
+// Bad: The duration and err are evaluated as the defers are reached
+func operationBad() (err error) {
+	start := time.Now()
+	defer traced("operationBad", elapsed(start))
+	defer reported("operationBad", err)
+	time.Sleep(deferWork)
+	return errDeferFailed
+}
+
+// Good: The closures read the duration and the named result at return
+func operationGood() (err error) {
+	start := time.Now()
+	defer func() { traced("operationGood", time.Since(start)) }()
+	defer func() { reported("operationGood", err) }()
+	time.Sleep(deferWork)
+	return errDeferFailed
+}
+
+// Bad: A deferred call per element, recorded at run time and run in
+// reverse once the loop is long done
+func visitDeferred(xs []int, visit func(int)) {
+	for _, x := range xs {
+		defer visit(x)
+	}
+}
+
+// Good: Each element visited in place
+func visitInPlace(xs []int, visit func(int)) {
+	for _, x := range xs {
+		visit(x)
+	}
+}
+
+// timeVisits prints how long visiting an element with loop takes, and
+// the order of the first few visits
+func timeVisits(how string, loop func([]int, func(int))) {
+	xs := make([]int, deferVisits)
+	for i := range xs {
+		xs[i] = i
+	}
+	var order []int
+	loop(xs[:4], func(x int) { order = append(order, x) })
+
+	sum := 0
+	start := time.Now()
+	for r := 0; r < deferRounds; r++ {
+		loop(xs, func(x int) { sum += x })
+	}
+	per := time.Since(start) / (deferRounds * deferVisits)
+	fmt.Printf("%s: %s per element, visiting %v\n", how, per, order)
+	sinkInt = sum
+}
+
+type deferargsLesson struct{}
+
+func init() { Register(deferargsLesson{}) }
+
+func (deferargsLesson) Name() string { return "deferargs" }
+
+func (deferargsLesson) Description() string {
+	return "Deferred call arguments are evaluated at the defer, so timers measure zero and errors read stale"
+}
+
+func (deferargsLesson) RunBad(context.Context) error {
+	fmt.Println("arguments evaluated at the defer:")
+	if err := operationBad(); !errors.Is(err, errDeferFailed) {
+		return fmt.Errorf("operationBad: got %v", err)
+	}
+	timeVisits("deferred in a loop", visitDeferred)
+	return nil
+}
+
+func (deferargsLesson) RunGood(context.Context) error {
+	fmt.Println("closures evaluated at return:")
+	if err := operationGood(); !errors.Is(err, errDeferFailed) {
+		return fmt.Errorf("operationGood: got %v", err)
+	}
+	timeVisits("in place", visitInPlace)
+	return nil
+}
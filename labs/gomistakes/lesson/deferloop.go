@@ -1,18 +1,44 @@
 package lesson
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"syscall"
 )
 
 // 9. Defer in Loop Leak
-func DeferInLoopLeak() {
-	// Bad: Defers accumulate until function returns
-	/* for i := 0; i < 100_000; i++ {
-		file, err := os.OpenFile("output.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+type deferLesson struct{}
+
+func init() { Register(deferLesson{}) }
+
+func (deferLesson) Name() string { return "defer" }
+
+func (deferLesson) Description() string {
+	return "Defers in a loop only run when the function returns, keeping every file open until then"
+}
+
+const deferLoopFiles = 100_000
+
+// Bad: Defers accumulate until function returns
+func (deferLesson) RunBad(ctx context.Context) error {
+	dir, err := os.MkdirTemp("", "gomistakes-defer")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < deferLoopFiles && ctx.Err() == nil; i++ {
+		file, err := os.OpenFile(filepath.Join(dir, "output.txt"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if errors.Is(err, syscall.EMFILE) {
+			// The outcome shown: every descriptor is held by a pending defer
+			fmt.Printf("opening file %d: %v\n", i, err)
+			return nil
+		}
 		if err != nil {
-			fmt.Printf("Error opening file: %v\n", err)
-			continue
+			return fmt.Errorf("opening file %d: %w", i, err)
 		}
 		defer file.Close() // Won't be called until function returns
 
@@ -20,12 +46,21 @@ func DeferInLoopLeak() {
 		if _, err := file.WriteString(fmt.Sprintf("Line %d\n", i)); err != nil {
 			fmt.Printf("Error writing to file: %v\n", err)
 		}
-	}*/
+	}
+	return nil
+}
+
+// Good: Close in the same loop iteration
+func (deferLesson) RunGood(ctx context.Context) error {
+	dir, err := os.MkdirTemp("", "gomistakes-defer")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
 
-	// Good: Close in the same loop iteration
-	for i := 0; i < 100_000; i++ {
+	for i := 0; i < deferLoopFiles && ctx.Err() == nil; i++ {
 		func() {
-			file, err := os.OpenFile("output.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			file, err := os.OpenFile(filepath.Join(dir, "output.txt"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 			if err != nil {
 				fmt.Printf("Error opening file: %v\n", err)
 				return
@@ -38,4 +73,5 @@ func DeferInLoopLeak() {
 			}
 		}()
 	}
+	return nil
 }
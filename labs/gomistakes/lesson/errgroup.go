@@ -0,0 +1,103 @@
+package lesson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// 17. Fan-out Losing Errors
+const (
+	// fanTasks tasks each take fanTaskTime, fanLimit at a time when
+	// limited, but task fanFails fails halfway through
+	fanTasks    = 20
+	fanTaskTime = 50 * time.Millisecond
+	fanLimit    = 4
+	fanFails    = 5
+)
+
+var errTaskFailed = errors.New("task failed")
+
+// fanout counts what the tasks of a fan-out did
+type fanout struct {
+	running, peak        atomic.Int32
+	completed, cancelled atomic.Int32
+}
+
+// task runs task i, working for fanTaskTime unless ctx is done first,
+// and failing halfway if it is fanFails
+func (f *fanout) task(ctx context.Context, i int) error {
+	defer f.running.Add(-1)
+	raise(&f.peak, f.running.Add(1))
+	d := fanTaskTime
+	if i == fanFails {
+		d /= 2
+	}
+	work := time.NewTimer(d)
+	defer work.Stop()
+	select {
+	case <-work.C:
+		if i == fanFails {
+			return fmt.Errorf("task %d: %w", i, errTaskFailed)
+		}
+		f.completed.Add(1)
+		return nil
+	case <-ctx.Done():
+		f.cancelled.Add(1)
+		return ctx.Err()
+	}
+}
+
+func (f *fanout) String() string {
+	return fmt.Sprintf("%d completed, %d cancelled, at most %d at once", f.completed.Load(), f.cancelled.Load(), f.peak.Load())
+}
+
+type errgroupLesson struct{}
+
+func init() { Register(errgroupLesson{}) }
+
+func (errgroupLesson) Name() string { return "errgroup" }
+
+func (errgroupLesson) Description() string {
+	return "Goroutines fanned out with a WaitGroup drop their errors, run unbounded and outlive a failed sibling"
+}
+
+// Bad: Every task at once, errors dropped, the rest running on after one
+// has failed
+func (errgroupLesson) RunBad(ctx context.Context) error {
+	var f fanout
+	var wg sync.WaitGroup
+	for i := 0; i < fanTasks; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			f.task(ctx, i) // The error goes nowhere
+		}(i)
+	}
+	wg.Wait()
+	fmt.Println("no error seen;", &f)
+	return nil
+}
+
+// Good: An errgroup bounded by SetLimit, cancelling the others on the
+// first error and returning it
+func (errgroupLesson) RunGood(ctx context.Context) error {
+	var f fanout
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(fanLimit)
+	for i := 0; i < fanTasks && ctx.Err() == nil; i++ {
+		i := i // Go 1.21 loop semantics, see the loopvar lesson
+		g.Go(func() error { return f.task(ctx, i) })
+	}
+	err := g.Wait()
+	if !errors.Is(err, errTaskFailed) {
+		return fmt.Errorf("expected the failed task's error, got %v", err)
+	}
+	fmt.Printf("error %q; %s\n", err, &f)
+	return nil
+}
@@ -0,0 +1,247 @@
+package lesson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"gomistakes/memcheck"
+)
+
+// 25. Values Escaping to the Heap
+//
+// The compiler keeps a value on the stack only if it can prove nothing
+// refers to it once its function returns. Returning its address, storing
+// it in an interface or capturing it in a closure that is kept moves it
+// to the heap, an allocation per call. go build -gcflags=-m prints the
+// compiler's decisions, which the lesson reads back for this file.
+const escapeRuns = 1000
+
+type point struct{ X, Y, Z float64 }
+
+// Bad: The point outlives the call through the returned pointer
+//
+//go:noinline
+func newPointBad(x float64) *point {
+	p := point{X: x, Y: x, Z: x}
+	return &p
+}
+
+// Good: The point is copied out
+//
+//go:noinline
+func newPointGood(x float64) point {
+	return point{X: x, Y: x, Z: x}
+}
+
+// Bad: fmt takes its arguments as interfaces, boxing id on the heap
+//
+//go:noinline
+func formatBad(id int) int {
+	return len(fmt.Sprint(id))
+}
+
+// Good: Formatted into a buffer on the stack
+//
+//go:noinline
+func formatGood(id int) int {
+	var buf [20]byte
+	return len(strconv.AppendInt(buf[:0], int64(id), 10))
+}
+
+// lastVisitor is the visitor eachKept was last called with
+var lastVisitor func(int)
+
+// eachKept calls f with each of xs and keeps f
+//
+//go:noinline
+func eachKept(xs []int, f func(int)) {
+	lastVisitor = f
+	for _, x := range xs {
+		f(x)
+	}
+}
+
+// each calls f with each of xs
+//
+//go:noinline
+func each(xs []int, f func(int)) {
+	for _, x := range xs {
+		f(x)
+	}
+}
+
+// Bad: The closure is kept, so it and the total it captures go to the heap
+func sumBad(xs []int) int {
+	total := 0
+	eachKept(xs, func(x int) { total += x })
+	return total
+}
+
+// Good: The closure is only called, so both stay on the stack
+func sumGood(xs []int) int {
+	total := 0
+	each(xs, func(x int) { total += x })
+	return total
+}
+
+// escapeCase is a function, as declared in this file, and a call to it
+type escapeCase struct {
+	fn   string
+	call func(i int) int
+}
+
+// escapeCases pairs what moves each value to the heap with the bad and good
+// functions
+var escapeCases = []struct {
+	what      string
+	bad, good escapeCase
+}{
+	{"returning a pointer",
+		escapeCase{"newPointBad", func(i int) int { return int(newPointBad(float64(i)).X) }},
+		escapeCase{"newPointGood", func(i int) int { return int(newPointGood(float64(i)).X) }}},
+	{"interface conversion",
+		escapeCase{"formatBad", func(i int) int { return formatBad(1000 + i) }},
+		escapeCase{"formatGood", func(i int) int { return formatGood(1000 + i) }}},
+	{"closure capture",
+		escapeCase{"sumBad", func(i int) int { return sumBad([]int{i, i}) }},
+		escapeCase{"sumGood", func(i int) int { return sumGood([]int{i, i}) }}},
+}
+
+// escapeSource is this file, which the lesson compiles to read what the
+// compiler decided
+func escapeSource() string {
+	_, file, _, _ := runtime.Caller(0)
+	return file
+}
+
+// allocsPerRun is the average number of allocations a call of f makes
+// over runs calls, after a first call to warm up, as the benchmarks report
+// it
+func allocsPerRun(runs int, f func()) float64 {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	f()
+	d := memcheck.Delta{Before: memcheck.Take()}
+	for i := 0; i < runs; i++ {
+		f()
+	}
+	d.After = memcheck.Take()
+	return float64(d.Allocs() / int64(runs))
+}
+
+// escapes compiles the package of file with -gcflags=-m and returns what
+// the compiler moved to the heap in each function of file
+func escapes(ctx context.Context, file string) (map[string][]string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	type span struct {
+		name       string
+		start, end int
+	}
+	var funcs []span
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok {
+			funcs = append(funcs, span{fd.Name.Name, fset.Position(fd.Pos()).Line, fset.Position(fd.End()).Line})
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, "go", "build", "-gcflags=-m", ".")
+	cmd.Dir = filepath.Dir(file)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("go build -gcflags=-m: %w\n%s", err, out)
+	}
+	// Lines look like: ./escape.go:37:2: moved to heap: p
+	found := map[string][]string{}
+	prefix := "./" + filepath.Base(file) + ":"
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		rest, ok := strings.CutPrefix(s.Text(), prefix)
+		if !ok {
+			continue
+		}
+		fields := strings.SplitN(rest, ":", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		line, err := strconv.Atoi(fields[0])
+		msg := strings.TrimSpace(fields[2])
+		if err != nil || !strings.Contains(msg, "escapes to heap") && !strings.HasPrefix(msg, "moved to heap") {
+			continue
+		}
+		for _, fn := range funcs {
+			if fn.start <= line && line <= fn.end {
+				found[fn.name] = append(found[fn.name], msg)
+			}
+		}
+	}
+	return found, s.Err()
+}
+
+// printEscapes prints for each case what the compiler moved to the heap
+// and how many allocations a call makes
+func printEscapes(ctx context.Context, pick func(bad, good escapeCase) escapeCase) {
+	file := escapeSource()
+	var found map[string][]string
+	var err error
+	if _, statErr := os.Stat(file); statErr != nil {
+		err = fmt.Errorf("source not found: %w", statErr)
+	} else if _, lookErr := exec.LookPath("go"); lookErr != nil {
+		err = lookErr
+	} else {
+		found, err = escapes(ctx, file)
+	}
+	if err != nil {
+		fmt.Println("compiler output unavailable:", err)
+	}
+	for _, c := range escapeCases {
+		e := pick(c.bad, c.good)
+		i := 0
+		allocs := allocsPerRun(escapeRuns, func() {
+			i += e.call(i)
+		})
+		fmt.Printf("%s (%s): %.0f allocations per call\n", e.fn, c.what, allocs)
+		if err != nil {
+			continue
+		}
+		if len(found[e.fn]) == 0 {
+			fmt.Println("  nothing escapes")
+		}
+		for _, msg := range found[e.fn] {
+			fmt.Println(" ", msg)
+		}
+	}
+}
+
+type escapeLesson struct{}
+
+func init() { Register(escapeLesson{}) }
+
+func (escapeLesson) Name() string { return "escape" }
+
+func (escapeLesson) Description() string {
+	return "Returning a pointer, converting to an interface or keeping a closure moves values to the heap"
+}
+
+func (escapeLesson) RunBad(ctx context.Context) error {
+	printEscapes(ctx, func(bad, _ escapeCase) escapeCase { return bad })
+	return nil
+}
+
+func (escapeLesson) RunGood(ctx context.Context) error {
+	printEscapes(ctx, func(_, good escapeCase) escapeCase { return good })
+	return nil
+}
@@ -0,0 +1,203 @@
+package lesson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// 31. Exiting Past Deferred Cleanup
+//
+// os.Exit, and log.Fatal which calls it, end the process on the spot:
+// deferred calls do not run, so buffered writes are lost and connections
+// are dropped rather than closed. Exit only from main, once a run
+// function has returned and its defers have run, and on a signal cancel
+// a context, stop taking work and let the workers drain first.
+const (
+	// exitRecords records are written before the bad variant's fatal error
+	exitRecords = 100
+	// exitWorkers workers take exitJobTime per job until the good variant
+	// signals itself after exitRunFor, then get exitDrain to finish
+	exitWorkers = 4
+	exitJobTime = time.Millisecond
+	exitRunFor  = 50 * time.Millisecond
+	exitDrain   = time.Second
+)
+
+// exitFileEnv names the file the bad variant's copy of the program writes
+const exitFileEnv = "GOMISTAKES_EXIT_FILE"
+
+// Bad: Buffered records, then log.Fatal, so neither the flush nor the
+// close deferred before it runs. It runs in a copy of the program.
+func fatalExit() {
+	f, err := os.Create(os.Getenv(exitFileEnv))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+	for i := 0; i < exitRecords; i++ {
+		fmt.Fprintf(w, "record %d\n", i)
+	}
+	log.Fatal("config reload failed")
+}
+
+// recordFile writes records through a buffer, from any goroutine
+type recordFile struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *bufio.Writer
+}
+
+func createRecordFile(path string) (*recordFile, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &recordFile{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (r *recordFile) write(i int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.w, "record %d\n", i)
+}
+
+// Close flushes the buffer and closes the file
+func (r *recordFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return errors.Join(r.w.Flush(), r.f.Close())
+}
+
+// countRecords counts the records written to path
+func countRecords(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	return bytes.Count(data, []byte("\n")), err
+}
+
+// terminate sends the process SIGTERM, as a service manager stopping it
+// would
+func terminate() error {
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+	return p.Signal(syscall.SIGTERM)
+}
+
+type exitLesson struct{}
+
+func init() { Register(exitLesson{}) }
+
+func (exitLesson) Name() string { return "exit" }
+
+func (exitLesson) Description() string {
+	return "log.Fatal and os.Exit skip deferred cleanup, losing buffered writes"
+}
+
+func (exitLesson) RunBad(ctx context.Context) error {
+	dir, err := os.MkdirTemp("", "gomistakes-exit")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "records.txt")
+
+	out, err := subprocess(ctx, "exit", exitFileEnv+"="+path)
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("expected the subprocess to exit with an error, got %v", err)
+	}
+	n, err := countRecords(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("subprocess exited (%s): %s", exit, out)
+	fmt.Printf("%d of %d records reached the file\n", n, exitRecords)
+	return nil
+}
+
+// Good: Workers stop taking jobs on SIGTERM and finish the ones they
+// have, and the file is flushed and closed before returning
+func (exitLesson) RunGood(ctx context.Context) error {
+	dir, err := os.MkdirTemp("", "gomistakes-exit")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "records.txt")
+	records, err := createRecordFile(path)
+	if err != nil {
+		return err
+	}
+	defer records.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM)
+	defer stop()
+	signaller := time.AfterFunc(exitRunFor, func() {
+		if err := terminate(); err != nil {
+			cancel() // Signals cannot be sent here, so stop as one would
+		}
+	})
+	defer signaller.Stop()
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < exitWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				time.Sleep(exitJobTime)
+				records.write(j)
+			}
+		}()
+	}
+	accepted := 0
+	for ctx.Err() == nil {
+		select {
+		case jobs <- accepted:
+			accepted++
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	fmt.Printf("stopping (%v): draining %d workers\n", context.Cause(ctx), exitWorkers)
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(exitDrain):
+		return fmt.Errorf("workers still running after %s", exitDrain)
+	}
+	if err := records.Close(); err != nil {
+		return err
+	}
+	n, err := countRecords(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d of %d accepted records reached the file\n", n, accepted)
+	return nil
+}
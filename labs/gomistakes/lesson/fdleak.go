@@ -0,0 +1,215 @@
+package lesson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"gomistakes/fdcheck"
+)
+
+// 21. File Descriptor Leaks
+//
+// A file or socket that is never closed holds its descriptor until the
+// collector happens to run its finalizer. A busy program opens more before
+// that and runs out, every open and dial failing with "too many open
+// files". The lesson lowers the limit to fdHeadroom above what is open so
+// it runs out quickly.
+const (
+	// fdOps is how many files each variant reads and datagrams it sends
+	fdOps      = 1000
+	fdHeadroom = 256
+	// fdWatch is how often the monitor counts descriptors, and fdStep how
+	// far the count must move before it is printed again
+	fdWatch = time.Millisecond
+	fdStep  = 64
+	// fdSettle is how long the finalizers get to close what leaked
+	fdSettle = time.Second
+)
+
+// readConfigBad reads path, never closing it
+func readConfigBad(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 64)
+	n, err := f.Read(buf)
+	return buf[:n], err
+}
+
+// readConfigGood reads path and closes it
+func readConfigGood(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	buf := make([]byte, 64)
+	n, err := f.Read(buf)
+	return buf[:n], err
+}
+
+// sendMetricBad dials addr for every datagram, never closing the socket
+func sendMetricBad(addr string, i int) error {
+	c, err := net.Dial("udp", addr)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c, "requests:%d|c", i)
+	return err
+}
+
+// sendMetric sends a datagram on a socket shared by every call
+func sendMetric(c net.Conn, i int) error {
+	_, err := fmt.Fprintf(c, "requests:%d|c", i)
+	return err
+}
+
+// fdRun runs fdOps operations with the descriptors limited and watched,
+// printing the count as it moves, and how many operations succeeded
+func fdRun(ctx context.Context, what string, op func(i int) error) error {
+	open, err := fdcheck.Count()
+	if err != nil {
+		return err
+	}
+	restore, err := fdcheck.Limit(open + fdHeadroom)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	last := open
+	m, err := fdcheck.Watch(fdWatch, func(n int) {
+		if n/fdStep != last/fdStep {
+			fmt.Printf("  %d descriptors open\n", n)
+		}
+		last = n
+	})
+	if err != nil {
+		return err
+	}
+	done := 0
+	for ; done < fdOps && ctx.Err() == nil; done++ {
+		if err = op(done); err != nil {
+			break
+		}
+	}
+	u := m.Stop()
+	fmt.Printf("%s: %d of %d done; descriptors %s\n", what, done, fdOps, u)
+	if err != nil {
+		fmt.Printf("%s: %v\n", what, err)
+	}
+	return nil
+}
+
+// collected runs the collector until the descriptors open are back to
+// open, or fdSettle passes, and prints how many are open then
+func collected(open int) {
+	deadline := time.Now().Add(fdSettle)
+	n, _ := fdcheck.Count()
+	for n > open && time.Now().Before(deadline) {
+		runtime.GC() // Queues the finalizers that close what leaked
+		time.Sleep(10 * time.Millisecond)
+		n, _ = fdcheck.Count()
+	}
+	fmt.Printf("after collecting: %d descriptors open\n", n)
+}
+
+// fdFixture is a file to read and a socket to send datagrams to, removed
+// by the returned func
+func fdFixture() (path string, addr string, cleanup func(), err error) {
+	dir, err := os.MkdirTemp("", "gomistakes-fd")
+	if err != nil {
+		return "", "", nil, err
+	}
+	path = filepath.Join(dir, "config.txt")
+	if err := os.WriteFile(path, []byte("workers=4\n"), 0o644); err != nil {
+		os.RemoveAll(dir)
+		return "", "", nil, err
+	}
+	sink, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", nil, err
+	}
+	return path, sink.LocalAddr().String(), func() {
+		sink.Close()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+type fdleakLesson struct{}
+
+func init() { Register(fdleakLesson{}) }
+
+func (fdleakLesson) Name() string { return "fdleak" }
+
+func (fdleakLesson) Description() string {
+	return "Files and sockets left open run the process out of descriptors until opens fail with too many open files"
+}
+
+// Bad: Files read and sockets dialed per call, none of them closed
+func (fdleakLesson) RunBad(ctx context.Context) error {
+	path, addr, cleanup, err := fdFixture()
+	if errors.Is(err, errors.ErrUnsupported) {
+		fmt.Println("descriptors cannot be counted or limited on", runtime.GOOS)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	open, err := fdcheck.Count()
+	if err != nil {
+		return err
+	}
+
+	if err := fdRun(ctx, "files", func(int) error {
+		_, err := readConfigBad(path)
+		return err
+	}); err != nil {
+		return err
+	}
+	collected(open)
+	if err := fdRun(ctx, "sockets", func(i int) error {
+		return sendMetricBad(addr, i)
+	}); err != nil {
+		return err
+	}
+	collected(open)
+	return nil
+}
+
+// Good: Every file closed before the read returns, and datagrams sent on
+// one pooled socket
+func (fdleakLesson) RunGood(ctx context.Context) error {
+	path, addr, cleanup, err := fdFixture()
+	if errors.Is(err, errors.ErrUnsupported) {
+		fmt.Println("descriptors cannot be counted or limited on", runtime.GOOS)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if err := fdRun(ctx, "files", func(int) error {
+		_, err := readConfigGood(path)
+		return err
+	}); err != nil {
+		return err
+	}
+	c, err := net.Dial("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return fdRun(ctx, "sockets", func(i int) error {
+		return sendMetric(c, i)
+	})
+}
@@ -1,8 +1,8 @@
 package lesson
 
 import (
+	"context"
 	"fmt"
-	"runtime"
 	"sync"
 	"time"
 )
@@ -34,11 +34,25 @@ func GetGlobalCache(key string) *LargeObject {
 	return value.(*LargeObject)
 }
 
-func GlobalVariableLeak() {
+type globalLesson struct{}
+
+func init() { Register(globalLesson{}) }
+
+func (globalLesson) Name() string { return "global" }
+
+func (globalLesson) Description() string {
+	return "Values stored in a package-level map live as long as the program"
+}
+
+func (globalLesson) RunBad(context.Context) error {
+	globalCache["key"] = &LargeObject{data: make([]byte, 1024*1024)}
+	fmt.Println(len(globalCache["key"].data))
+	return nil
+}
+
+func (globalLesson) RunGood(context.Context) error {
 	SetGlobalCache("key", &LargeObject{data: make([]byte, 1024*1024)})
 	fmt.Println(len(GetGlobalCache("key").data))
-
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	fmt.Println(m.Alloc)
+	betterCache.Delete("key")
+	return nil
 }
@@ -17,8 +17,18 @@ type Leak struct {
 var leakSlice = []Leak{}
 
 // 1. Goroutine Leak
-func GoroutineLeak() {
-	// Bad: Goroutine never exits
+type goroutineLesson struct{}
+
+func init() { Register(goroutineLesson{}) }
+
+func (goroutineLesson) Name() string { return "goroutine" }
+
+func (goroutineLesson) Description() string {
+	return "A goroutine with no way to stop keeps running, and growing, after its caller is gone"
+}
+
+// Bad: Goroutine never exits
+func (goroutineLesson) RunBad(ctx context.Context) error {
 	go func() {
 		ticker := time.NewTicker(time.Second)
 		largeString := ""
@@ -31,32 +41,37 @@ func GoroutineLeak() {
 					birthday: time.Now(),
 				}
 				leakSlice = append(leakSlice, newLeak)
-				largeString += "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+				largeString += "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
 				log.Printf("\nWorking... %d", len(leakSlice))
-				ClosureLeak()
+				closureLesson{}.RunBad(ctx)
 			}
 		}
 	}()
-	select {}
+	<-ctx.Done()
+	return nil
 }
 
 // Good: Proper cancellation
-func GoroutineLeakWithContext() {
-	ctx, cancel := context.WithCancel(context.Background())
+func (goroutineLesson) RunGood(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
 		ticker := time.NewTicker(time.Second)
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
 				log.Println("Working...")
-				
+
 			case <-ctx.Done():
 				return
 			}
 		}
 	}()
-	select {}
+	<-ctx.Done()
+	<-done
+	return nil
 }
@@ -1,15 +1,51 @@
 package lesson
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 )
 
 // 8. HTTP Response Body Leak
-func HTTPBodyLeak() error {
-	// Bad: Response body not closed
-	resp, err := http.Get("https://example.com")
+const (
+	// httpPageSize is the size of the page the local server serves
+	httpPageSize = 4 << 10
+)
+
+type httpLesson struct{}
+
+func init() { Register(httpLesson{}) }
+
+func (httpLesson) Name() string { return "http" }
+
+func (httpLesson) Description() string {
+	return "An HTTP response body left open holds its connection and buffers"
+}
+
+// httpPage serves the page fetched, so the lesson needs no network
+func httpPage() *httptest.Server {
+	page := strings.Repeat("x", httpPageSize)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, page)
+	}))
+}
+
+func get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// Bad: Response body not closed
+func (httpLesson) RunBad(ctx context.Context) error {
+	srv := httpPage()
+	defer srv.Close()
+	resp, err := get(ctx, srv.URL)
 	if err != nil {
 		return err
 	}
@@ -19,14 +55,19 @@ func HTTPBodyLeak() error {
 		return err
 	}
 	fmt.Println(len(body))
+	return nil
+}
 
-	// Good: Always close response body
-	resp, err = http.Get("https://example.com")
+// Good: Always close response body
+func (httpLesson) RunGood(ctx context.Context) error {
+	srv := httpPage()
+	defer srv.Close()
+	resp, err := get(ctx, srv.URL)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-	body, err = io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -0,0 +1,131 @@
+package lesson
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 22. http.Client per Request
+//
+// A Transport pools connections, so one made per request dials a new one
+// every time and, closed after, leaves it in TIME_WAIT on the client. A
+// shared Transport keeps only MaxIdleConnsPerHost idle connections per
+// host, 2 by default, and closes what concurrent requests opened beyond
+// that, churning the same way.
+const (
+	// clientRequests requests are made, clientWorkers at a time
+	clientRequests = 1000
+	clientWorkers  = 16
+)
+
+// tcpTimeWait is the state of a socket in /proc/net/tcp once it has closed
+// first and waits out late packets
+const tcpTimeWait = "06"
+
+// timeWait counts the sockets to or from port in TIME_WAIT, which only
+// Linux lists
+func timeWait(port int) (int, bool) {
+	f, err := os.Open("/proc/net/tcp")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	// Ports are hexadecimal, after the address: 0100007F:1F90
+	suffix := fmt.Sprintf(":%04X", port)
+	n := 0
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 4 || fields[3] != tcpTimeWait {
+			continue
+		}
+		if strings.HasSuffix(fields[1], suffix) || strings.HasSuffix(fields[2], suffix) {
+			n++
+		}
+	}
+	return n, s.Err() == nil
+}
+
+// fetch makes clientRequests requests to a fresh backend, clientWorkers at
+// a time, each through the client client returns and done is then called
+// with, and prints the connections they took
+func fetch(ctx context.Context, how string, client func() *http.Client, done func(*http.Client)) {
+	b := newBackend()
+	defer b.Close()
+
+	var c calls
+	var wg sync.WaitGroup
+	requests := make(chan struct{})
+	for w := 0; w < clientWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range requests {
+				cl := client()
+				c.call(ctx, cl, b)
+				done(cl)
+			}
+		}()
+	}
+	for i := 0; i < clientRequests && ctx.Err() == nil; i++ {
+		requests <- struct{}{}
+	}
+	close(requests)
+	wg.Wait()
+
+	waiting := "-"
+	u, _ := url.Parse(b.URL)
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		if n, ok := timeWait(port); ok {
+			waiting = strconv.Itoa(n)
+		}
+	}
+	fmt.Printf("%-24s %d ok, %d failed; %d connections opened, at most %d at once, %s in TIME_WAIT\n", how+":",
+		c.ok.Load(), c.rejected.Load()+c.failed.Load(), b.opened.Load(), b.peakConns.Load(), waiting)
+}
+
+type httpclientLesson struct{}
+
+func init() { Register(httpclientLesson{}) }
+
+func (httpclientLesson) Name() string { return "httpclient" }
+
+func (httpclientLesson) Description() string {
+	return "A client per request, or a shared one keeping too few idle connections, dials again and again"
+}
+
+// Bad: A client and transport per request, closed after it, and a shared
+// client keeping the default 2 idle connections for 16 workers
+func (httpclientLesson) RunBad(ctx context.Context) error {
+	fetch(ctx, "client per request", func() *http.Client {
+		return &http.Client{Transport: &http.Transport{}}
+	}, func(cl *http.Client) {
+		cl.CloseIdleConnections() // Else its connection stays open for good
+	})
+
+	transport := &http.Transport{}
+	defer transport.CloseIdleConnections()
+	shared := &http.Client{Transport: transport}
+	fetch(ctx, "shared, 2 idle per host", func() *http.Client { return shared }, func(*http.Client) {})
+	return nil
+}
+
+// Good: One client whose transport keeps an idle connection for every
+// worker
+func (httpclientLesson) RunGood(ctx context.Context) error {
+	transport := &http.Transport{
+		MaxIdleConns:        clientWorkers,
+		MaxIdleConnsPerHost: clientWorkers,
+	}
+	defer transport.CloseIdleConnections()
+	shared := &http.Client{Transport: transport}
+	fetch(ctx, "shared, tuned", func() *http.Client { return shared }, func(*http.Client) {})
+	return nil
+}
@@ -1,28 +1,52 @@
 package lesson
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // 2. Closure Capturing Large Objects
 type LargeObject struct {
 	data []byte
 }
 
-func ClosureLeak() {
+type closureLesson struct{}
+
+// badHandler and goodHandler outlive the lessons, like callbacks
+// registered for later
+var badHandler, goodHandler func()
+
+func init() { Register(closureLesson{}) }
+
+func (closureLesson) Name() string { return "closure" }
+
+func (closureLesson) Description() string {
+	return "A closure capturing a large object keeps all of it alive for as long as the closure lives"
+}
+
+// Bad: Captures entire obj
+func (closureLesson) RunBad(context.Context) error {
 	obj := &LargeObject{
 		data: make([]byte, 1024*1024), // 1MB
 	}
 
-	// Bad: Captures entire obj
-	handler := func() {
+	badHandler = func() {
 		fmt.Println(len(obj.data))
 	}
-	handler()
-
-	/*
-	   // Good: Capture only what's needed
-	   size := len(obj.data)
-	   handler = func() {
-	       fmt.Println(size)
-	   }
-	*/
+	badHandler()
+	return nil
+}
+
+// Good: Capture only what's needed
+func (closureLesson) RunGood(context.Context) error {
+	obj := &LargeObject{
+		data: make([]byte, 1024*1024), // 1MB
+	}
+
+	size := len(obj.data)
+	goodHandler = func() {
+		fmt.Println(size)
+	}
+	goodHandler()
+	return nil
 }
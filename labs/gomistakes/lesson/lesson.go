@@ -0,0 +1,45 @@
+package lesson
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Lesson is a common Go mistake paired with its fix
+type Lesson interface {
+	// Name is the short name the lesson is looked up by
+	Name() string
+	Description() string
+	// RunBad runs the mistake and RunGood the fix. Lessons that would
+	// block forever return once ctx is done instead.
+	RunBad(ctx context.Context) error
+	RunGood(ctx context.Context) error
+}
+
+var registry = map[string]Lesson{}
+
+// Register adds l to the registry. It panics if a lesson with the same
+// name is already registered.
+func Register(l Lesson) {
+	if _, ok := registry[l.Name()]; ok {
+		panic(fmt.Sprintf("lesson: %q registered twice", l.Name()))
+	}
+	registry[l.Name()] = l
+}
+
+// All returns every registered lesson sorted by name
+func All() []Lesson {
+	lessons := make([]Lesson, 0, len(registry))
+	for _, l := range registry {
+		lessons = append(lessons, l)
+	}
+	sort.Slice(lessons, func(i, j int) bool { return lessons[i].Name() < lessons[j].Name() })
+	return lessons
+}
+
+// Get returns the lesson registered under name
+func Get(name string) (Lesson, bool) {
+	l, ok := registry[name]
+	return l, ok
+}
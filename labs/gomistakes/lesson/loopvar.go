@@ -0,0 +1,124 @@
+package lesson
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// 13. Loop Variable Capture
+//
+// Until Go 1.22 a for loop declares its variable once and every iteration
+// updates it, so goroutines started in the loop share it. From Go 1.22
+// each iteration has its own, but only in modules and files whose go
+// version is 1.22 or later; this module declares 1.21.
+const loopGoroutines = 5
+
+// recorder collects what the goroutines of a loop saw, holding them until
+// the loop is done so that which value they see does not depend on
+// scheduling
+type recorder struct {
+	wg    sync.WaitGroup
+	start chan struct{}
+	mu    sync.Mutex
+	seen  []int
+}
+
+func newRecorder() *recorder { return &recorder{start: make(chan struct{})} }
+
+// record is called by a goroutine of the loop, once the loop is done, with
+// the value it sees
+func (r *recorder) record(see func() int) {
+	defer r.wg.Done()
+	<-r.start
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen = append(r.seen, see())
+}
+
+// finish lets the goroutines run, waits for them and returns what they
+// saw, sorted
+func (r *recorder) finish() []int {
+	close(r.start)
+	r.wg.Wait()
+	slices.Sort(r.seen)
+	return r.seen
+}
+
+// checkSeen fails unless the goroutines saw every index exactly once
+func checkSeen(seen []int) error {
+	want := make([]int, loopGoroutines)
+	for i := range want {
+		want[i] = i
+	}
+	if !slices.Equal(seen, want) {
+		return fmt.Errorf("goroutines saw %v, want %v", seen, want)
+	}
+	return nil
+}
+
+// captureShared starts a goroutine per iteration using the loop variable,
+// compiled with this module's Go 1.21 semantics
+func captureShared() []int {
+	r := newRecorder()
+	for i := 0; i < loopGoroutines; i++ {
+		r.wg.Add(1)
+		go r.record(func() int { return i }) // Every goroutine reads the same i
+	}
+	return r.finish()
+}
+
+// captureParam passes the loop variable to each goroutine
+func captureParam() []int {
+	r := newRecorder()
+	for i := 0; i < loopGoroutines; i++ {
+		r.wg.Add(1)
+		go func(i int) {
+			r.record(func() int { return i })
+		}(i) // Copied as the goroutine starts
+	}
+	return r.finish()
+}
+
+type loopvarLesson struct{}
+
+func init() { Register(loopvarLesson{}) }
+
+func (loopvarLesson) Name() string { return "loopvar" }
+
+func (loopvarLesson) Description() string {
+	return "Goroutines started in a loop share its variable before Go 1.22 and all see its last value"
+}
+
+// Bad: Goroutines capture the one loop variable
+func (loopvarLesson) RunBad(context.Context) error {
+	seen := captureShared()
+	if err := checkSeen(seen); err != nil {
+		fmt.Println("go 1.21 loop, captured:", err)
+		return nil
+	}
+	fmt.Println("go 1.21 loop, captured: ok", seen)
+	return nil
+}
+
+// Good: Each goroutine gets its own copy, as a parameter or from Go 1.22
+// loop semantics
+func (loopvarLesson) RunGood(context.Context) error {
+	seen := captureParam()
+	if err := checkSeen(seen); err != nil {
+		return fmt.Errorf("go 1.21 loop, parameter: %w", err)
+	}
+	fmt.Println("go 1.21 loop, parameter: ok", seen)
+
+	seen, ok := captureIteration()
+	if !ok {
+		fmt.Println("go 1.22 loop, captured: needs a Go 1.22 toolchain")
+		return nil
+	}
+	if err := checkSeen(seen); err != nil {
+		return fmt.Errorf("go 1.22 loop, captured: %w", err)
+	}
+	fmt.Println("go 1.22 loop, captured: ok", seen)
+	return nil
+}
@@ -0,0 +1,6 @@
+//go:build !go1.22
+
+package lesson
+
+// captureIteration needs a toolchain with Go 1.22 loop semantics
+func captureIteration() ([]int, bool) { return nil, false }
@@ -0,0 +1,14 @@
+//go:build go1.22
+
+package lesson
+
+// captureIteration is captureShared compiled with Go 1.22 semantics, which
+// the build constraint of this file selects: every iteration has its own i
+func captureIteration() ([]int, bool) {
+	r := newRecorder()
+	for i := 0; i < loopGoroutines; i++ {
+		r.wg.Add(1)
+		go r.record(func() int { return i })
+	}
+	return r.finish(), true
+}
@@ -0,0 +1,87 @@
+package lesson
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gomistakes/cache"
+	"gomistakes/memcheck"
+)
+
+// 10. Unbounded Cache Under Load
+const (
+	// lruLoad is how many requests each variant serves, half for one of
+	// lruHot keys and half for a key never seen before
+	lruLoad = 100_000
+	lruHot  = 500
+	// lruValue is the size of each value and lruReport how often the heap
+	// is printed
+	lruValue  = 1024
+	lruReport = 20_000
+	// lruCapacity bounds the fixed cache
+	lruCapacity = 1000
+)
+
+// lruMap and lruCache outlive the lessons, like a cache serving requests
+var (
+	lruMap   *Cache
+	lruCache *cache.LRU[string, []byte]
+)
+
+type lruLesson struct{}
+
+func init() { Register(lruLesson{}) }
+
+func (lruLesson) Name() string { return "lru" }
+
+func (lruLesson) Description() string {
+	return "Under a stream of distinct keys a map cache keeps growing while an LRU stays at its capacity"
+}
+
+// lruStore is what load needs of a cache
+type lruStore interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Len() int
+}
+
+// load serves lruLoad requests through c, storing the value on a miss,
+// and prints the entries held and the live heap every lruReport of them
+func load(ctx context.Context, c lruStore) error {
+	base := memcheck.Take()
+	for i := 1; i <= lruLoad; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key := fmt.Sprintf("key%d", i)
+		if i%2 == 0 {
+			key = fmt.Sprintf("hot%d", i/2%lruHot)
+		}
+		if _, ok := c.Get(key); !ok {
+			c.Set(key, make([]byte, lruValue))
+		}
+		if i%lruReport == 0 {
+			heap := int64(memcheck.Take().HeapAlloc) - int64(base.HeapAlloc)
+			fmt.Printf("%6d requests: %6d held, heap %s\n", i, c.Len(), memcheck.Bytes(heap))
+		}
+	}
+	return nil
+}
+
+// Bad: Every key ever requested stays in the map
+func (lruLesson) RunBad(ctx context.Context) error {
+	lruMap = &Cache{items: make(map[string][]byte)}
+	return load(ctx, lruMap)
+}
+
+// Good: The least recently used keys make room for new ones
+func (lruLesson) RunGood(ctx context.Context) error {
+	lruCache = cache.New[string, []byte](lruCapacity, time.Minute)
+	if err := load(ctx, lruCache); err != nil {
+		return err
+	}
+	stats := lruCache.Stats()
+	fmt.Printf("evictions %d, hit rate %.2f\n", stats.Evictions, stats.HitRate())
+	return nil
+}
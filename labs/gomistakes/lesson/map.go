@@ -1,8 +1,8 @@
 package lesson
 
 import (
+	"context"
 	"fmt"
-	"runtime"
 	"sync"
 	"time"
 )
@@ -20,7 +20,20 @@ func (c *Cache) Set(key string, value []byte) {
 	c.items[key] = value
 }
 
-// Good: With TTL and cleanup
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	value, ok := c.items[key]
+	return value, ok
+}
+
+func (c *Cache) Len() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.items)
+}
+
+// Good: With TTL, a size limit and cleanup that stops
 type CacheItem struct {
 	value     []byte
 	timestamp time.Time
@@ -30,40 +43,142 @@ type BetterCache struct {
 	sync.RWMutex
 	items map[string]CacheItem
 	ttl   time.Duration
+	// maxEntries bounds items, evicting the oldest; 0 for no bound
+	maxEntries int
+
+	stop context.CancelFunc
+	done chan struct{}
 }
 
+// NewBetterCache creates a cache whose items expire after ttl (0 or less
+// for never), holding at most maxEntries of them (0 for no limit)
+func NewBetterCache(ttl time.Duration, maxEntries int) *BetterCache {
+	return &BetterCache{items: make(map[string]CacheItem), ttl: ttl, maxEntries: maxEntries}
+}
+
+// Set stores value under key, evicting the oldest item when the cache is
+// full
 func (c *BetterCache) Set(key string, value []byte) {
 	c.Lock()
 	defer c.Unlock()
+	if _, ok := c.items[key]; !ok && c.maxEntries > 0 && len(c.items) >= c.maxEntries {
+		c.evictOldest()
+	}
 	c.items[key] = CacheItem{value: value, timestamp: time.Now()}
 }
 
-func (c *BetterCache) Cleanup() {
-	ticker := time.NewTicker(time.Minute)
+func (c *BetterCache) evictOldest() {
+	var oldest string
+	var at time.Time
+	for k, v := range c.items {
+		if at.IsZero() || v.timestamp.Before(at) {
+			oldest, at = k, v.timestamp
+		}
+	}
+	delete(c.items, oldest)
+}
+
+// Get returns the value stored under key unless it has expired
+func (c *BetterCache) Get(key string) ([]byte, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	item, ok := c.items[key]
+	if !ok || c.expired(item, time.Now()) {
+		return nil, false
+	}
+	return item.value, true
+}
+
+// Len is how many items the cache holds, expired ones not yet cleaned up
+// included
+func (c *BetterCache) Len() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.items)
+}
+
+// expired reports whether item is older than the ttl, if there is one
+func (c *BetterCache) expired(item CacheItem, now time.Time) bool {
+	return c.ttl > 0 && now.Sub(item.timestamp) > c.ttl
+}
+
+// Cleanup removes expired items every ttl until ctx is done or the cache
+// is closed. Only the first call starts cleaning up, and none does when
+// items never expire.
+func (c *BetterCache) Cleanup(ctx context.Context) {
+	c.Lock()
+	defer c.Unlock()
+	if c.done != nil || c.ttl <= 0 {
+		return
+	}
+	ctx, c.stop = context.WithCancel(ctx)
+	c.done = make(chan struct{})
 	go func() {
-		for range ticker.C {
-			c.Lock()
-			now := time.Now()
-			for k, v := range c.items {
-				if now.Sub(v.timestamp) > c.ttl {
-					delete(c.items, k)
-				}
+		defer close(c.done)
+		ticker := time.NewTicker(c.ttl)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.removeExpired()
+			case <-ctx.Done():
+				return
 			}
-			c.Unlock()
 		}
 	}()
 }
 
-func MapLeak() {
-	var m runtime.MemStats
+func (c *BetterCache) removeExpired() {
+	c.Lock()
+	defer c.Unlock()
+	now := time.Now()
+	for k, v := range c.items {
+		if c.expired(v, now) {
+			delete(c.items, k)
+		}
+	}
+}
+
+// Close stops the cleanup and waits for it to finish
+func (c *BetterCache) Close() {
+	c.Lock()
+	stop, done := c.stop, c.done
+	c.Unlock()
+	if stop == nil {
+		return
+	}
+	stop()
+	<-done
+}
+
+type mapLesson struct{}
+
+func init() { Register(mapLesson{}) }
+
+func (mapLesson) Name() string { return "map" }
+
+func (mapLesson) Description() string {
+	return "A map used as a cache grows without bound unless entries expire"
+}
 
+func (mapLesson) RunBad(context.Context) error {
 	cache := &Cache{items: make(map[string][]byte)}
-	cache.Set("key", []byte("value"))
-	runtime.ReadMemStats(&m)
-	fmt.Println(m.Alloc)
-
-	betterCache := &BetterCache{items: make(map[string]CacheItem), ttl: time.Minute}
-	betterCache.Cleanup()
-	runtime.ReadMemStats(&m)
-	fmt.Println(m.Alloc)
+	for i := 0; i < 1000; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+	fmt.Println(len(cache.items))
+	return nil
+}
+
+func (mapLesson) RunGood(ctx context.Context) error {
+	betterCache := NewBetterCache(time.Minute, 100)
+	betterCache.Cleanup(ctx)
+	defer betterCache.Close()
+
+	for i := 0; i < 1000; i++ {
+		betterCache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+	value, ok := betterCache.Get("key999")
+	fmt.Println(betterCache.Len(), string(value), ok)
+	return nil
 }
@@ -0,0 +1,90 @@
+package lesson
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"gomistakes/memcheck"
+)
+
+// 27. Map Growth and Shrinking
+//
+// A map made without a size hint grows as keys arrive, each time
+// allocating a larger table and moving every key over. Deleting keys
+// frees none of it: the table stays as large as the map ever was until
+// the map itself is dropped.
+const (
+	// growKeys keys are inserted, then all but growKept deleted
+	growKeys = 1_000_000
+	growKept = 1000
+)
+
+// insert puts growKeys keys in m, printing how long it took and what it
+// allocated
+func insert(how string, m map[int]int) {
+	var took time.Duration
+	d := memcheck.Measure(func() {
+		start := time.Now()
+		for i := 0; i < growKeys; i++ {
+			m[i] = i
+		}
+		took = time.Since(start)
+	})
+	fmt.Printf("%-24s %d keys in %s, %s allocated in %d allocations\n", how+":", len(m),
+		took.Round(time.Millisecond), size(d.Allocated()), d.Allocs())
+}
+
+// prune deletes all but growKept of m's keys
+func prune(m map[int]int) {
+	for k := range m {
+		if k >= growKept {
+			delete(m, k)
+		}
+	}
+}
+
+// held is the live heap over base
+func held(base memcheck.Snapshot) string {
+	return size(int64(memcheck.Take().HeapAlloc) - int64(base.HeapAlloc))
+}
+
+type mapgrowLesson struct{}
+
+func init() { Register(mapgrowLesson{}) }
+
+func (mapgrowLesson) Name() string { return "mapgrow" }
+
+func (mapgrowLesson) Description() string {
+	return "A map made without a size grows by rehashing, and never gives back memory when keys are deleted"
+}
+
+// Bad: Grown one table at a time, then kept after the deletes
+func (mapgrowLesson) RunBad(context.Context) error {
+	base := memcheck.Take()
+	m := make(map[int]int)
+	insert("unsized", m)
+	fmt.Printf("%-24s %d keys hold %s\n", "full:", len(m), held(base))
+	prune(m)
+	fmt.Printf("%-24s %d keys hold %s\n", "after deletes:", len(m), held(base))
+	runtime.KeepAlive(m)
+	return nil
+}
+
+// Good: Sized for every key up front, then rebuilt for the keys left
+func (mapgrowLesson) RunGood(context.Context) error {
+	base := memcheck.Take()
+	m := make(map[int]int, growKeys)
+	insert("sized", m)
+	fmt.Printf("%-24s %d keys hold %s\n", "full:", len(m), held(base))
+	prune(m)
+	rebuilt := make(map[int]int, len(m))
+	for k, v := range m {
+		rebuilt[k] = v
+	}
+	m = rebuilt
+	fmt.Printf("%-24s %d keys hold %s\n", "rebuilt after deletes:", len(m), held(base))
+	runtime.KeepAlive(m)
+	return nil
+}
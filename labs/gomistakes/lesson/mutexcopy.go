@@ -0,0 +1,161 @@
+package lesson
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// 28. Copied Mutexes
+//
+// A mutex guards only the value it is in. A struct holding one that is
+// passed or received by value locks a fresh copy each time while the
+// slices, maps and pointers it holds are still shared, so nothing is
+// guarded at all. go vet's copylocks check reports it, and the race
+// detector catches the writes; testdata/mutexcopy is the program both are
+// run on.
+const (
+	// mutexWorkers workers each record mutexRecords hits over mutexRoutes
+	// routes
+	mutexWorkers = 8
+	mutexRecords = 1000
+	mutexRoutes  = 4
+)
+
+//go:embed testdata/mutexcopy/main.go
+var mutexCopySource []byte
+
+// hitCounter counts hits per route
+type hitCounter struct {
+	sync.Mutex
+	hits []int // Shared by every copy
+}
+
+func (c *hitCounter) record(route int) {
+	c.Lock()
+	defer c.Unlock()
+	n := c.hits[route]
+	runtime.Gosched() // Another worker gets to run, as on a busy machine
+	c.hits[route] = n + 1
+}
+
+func (c *hitCounter) total() int {
+	c.Lock()
+	defer c.Unlock()
+	total := 0
+	for _, n := range c.hits {
+		total += n
+	}
+	return total
+}
+
+// countHits has mutexWorkers workers record hits on c through counter and
+// returns how many c counted
+func countHits(c *hitCounter, counter func(*hitCounter) *hitCounter) int {
+	var wg sync.WaitGroup
+	for w := 0; w < mutexWorkers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < mutexRecords; i++ {
+				counter(c).record((w + i) % mutexRoutes)
+			}
+		}(w)
+	}
+	wg.Wait()
+	return c.total()
+}
+
+// goTool runs the go command in a temporary module holding the mutexcopy
+// program
+func goTool(ctx context.Context, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("go"); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "gomistakes-mutexcopy")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module mutexcopy\n\ngo 1.21\n"), 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), mutexCopySource, 0o644); err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "GOTOOLCHAIN=local")
+	return cmd.CombinedOutput()
+}
+
+// detect runs go vet and the race detector on the mutexcopy program and
+// prints what they report
+func detect(ctx context.Context) {
+	out, err := goTool(ctx, "vet", ".")
+	if err == nil {
+		fmt.Println("go vet: nothing reported")
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.Contains(line, "lock") {
+			fmt.Println("go vet:", strings.TrimSpace(line))
+		}
+	}
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		fmt.Println("go vet unavailable:", err)
+		return
+	}
+
+	out, err = goTool(ctx, "run", "-race", ".")
+	races := bytes.Count(out, []byte("WARNING: DATA RACE"))
+	if races == 0 {
+		fmt.Printf("race detector: nothing reported (%v)\n", err)
+		return
+	}
+	_, at, _ := bytes.Cut(out, []byte("main.hitCounter.record()\n"))
+	at, _, _ = bytes.Cut(bytes.TrimSpace(at), []byte(" "))
+	fmt.Printf("race detector: %d reports, the first in record at %s\n", races, filepath.Base(string(at)))
+}
+
+type mutexcopyLesson struct{}
+
+func init() { Register(mutexcopyLesson{}) }
+
+func (mutexcopyLesson) Name() string { return "mutexcopy" }
+
+func (mutexcopyLesson) Description() string {
+	return "A struct holding a mutex copied by value locks the copy and guards nothing"
+}
+
+// Bad: Every record goes through a copy of the counter, as a value
+// receiver does. go vet reports that form, not this one.
+func (mutexcopyLesson) RunBad(ctx context.Context) error {
+	c := &hitCounter{hits: make([]int, mutexRoutes)}
+	got := countHits(c, func(c *hitCounter) *hitCounter {
+		copied := byValue(c)
+		return &copied
+	})
+	fmt.Printf("copied: %d of %d hits counted\n", got, mutexWorkers*mutexRecords)
+	detect(ctx)
+	return nil
+}
+
+// Good: Pointer receivers, so every record locks the one mutex
+func (mutexcopyLesson) RunGood(context.Context) error {
+	c := &hitCounter{hits: make([]int, mutexRoutes)}
+	got := countHits(c, func(c *hitCounter) *hitCounter { return c })
+	if want := mutexWorkers * mutexRecords; got != want {
+		return fmt.Errorf("shared: %d of %d hits counted", got, want)
+	}
+	fmt.Printf("shared: %d of %d hits counted\n", got, mutexWorkers*mutexRecords)
+	return nil
+}
@@ -0,0 +1,97 @@
+package lesson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// 14. Nil Interface vs Typed Nil
+//
+// An interface value is nil only when both its type and its value are. A
+// nil *ValidationError returned as error has a type, so it is not nil.
+type ValidationError struct {
+	Field string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid " + e.Field
+}
+
+// Bad: Returns the nil pointer through the error interface
+func validateBad(name string) error {
+	var err *ValidationError
+	if name == "" {
+		err = &ValidationError{Field: "name"}
+	}
+	return err // Non-nil even when err is nil
+}
+
+// Good: Returns a literal nil when there is no error
+func validateGood(name string) error {
+	if name == "" {
+		return &ValidationError{Field: "name"}
+	}
+	return nil
+}
+
+// Good: Keeps the concrete type until it is known to be set
+func validateConcrete(name string) *ValidationError {
+	if name == "" {
+		return &ValidationError{Field: "name"}
+	}
+	return nil
+}
+
+// isTypedNil reports whether err holds a nil pointer, which err != nil
+// does not catch
+func isTypedNil(err error) bool {
+	if err == nil {
+		return false
+	}
+	v := reflect.ValueOf(err)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+type nilerrorLesson struct{}
+
+func init() { Register(nilerrorLesson{}) }
+
+func (nilerrorLesson) Name() string { return "nilerror" }
+
+func (nilerrorLesson) Description() string {
+	return "A nil pointer returned as an error is a non-nil error"
+}
+
+func (nilerrorLesson) RunBad(context.Context) error {
+	err := validateBad("gopher")
+	if err == nil {
+		return errors.New("a typed nil error compared equal to nil")
+	}
+	fmt.Printf("err != nil: %t, holding %#v, typed nil: %t\n", err != nil, err, isTypedNil(err))
+
+	var ve *ValidationError
+	fmt.Printf("errors.As finds a *ValidationError: %t, and it is nil: %t\n", errors.As(err, &ve), ve == nil)
+	return nil
+}
+
+func (nilerrorLesson) RunGood(context.Context) error {
+	if err := validateGood("gopher"); err != nil {
+		return fmt.Errorf("validateGood: unexpected error %v", err)
+	}
+	fmt.Println("literal nil: err == nil")
+
+	if ve := validateConcrete("gopher"); ve != nil {
+		return fmt.Errorf("validateConcrete: unexpected error %v", ve)
+	}
+	fmt.Println("concrete type: ve == nil")
+
+	err := error(validateConcrete(""))
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Field != "name" {
+		return fmt.Errorf("validateConcrete: got %v, want a *ValidationError for name", err)
+	}
+	fmt.Println("concrete type converted once set:", err)
+	return nil
+}
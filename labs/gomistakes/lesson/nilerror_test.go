@@ -0,0 +1,22 @@
+package lesson
+
+import "testing"
+
+func TestValidateBadReturnsTypedNil(t *testing.T) {
+	err := validateBad("x")
+	if err == nil {
+		t.Fatal("validateBad(\"x\") = nil, want the non-nil typed nil")
+	}
+	if !isTypedNil(err) {
+		t.Errorf("isTypedNil(%#v) = false, want true", err)
+	}
+}
+
+func TestValidateGoodReturnsNil(t *testing.T) {
+	if err := validateGood("x"); err != nil {
+		t.Fatalf("validateGood(\"x\") = %#v, want nil", err)
+	}
+	if isTypedNil(nil) {
+		t.Error("isTypedNil(nil) = true, want false")
+	}
+}
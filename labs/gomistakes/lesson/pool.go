@@ -0,0 +1,99 @@
+package lesson
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"gomistakes/memcheck"
+)
+
+// 12. sync.Pool Usage and Misuse
+const (
+	// poolRequests is how many requests each phase serves, most writing a
+	// poolSmall response but every poolLargeEvery a poolLarge one
+	poolRequests   = 10_000
+	poolSmall      = 4 << 10
+	poolLarge      = 4 << 20
+	poolLargeEvery = 1000
+	// poolMaxCap is the largest buffer the fixed pool takes back
+	poolMaxCap = 64 << 10
+)
+
+// poolChunk is what responses are written from
+var poolChunk = make([]byte, poolSmall)
+
+// respond writes the response to request i to buf
+func respond(buf *bytes.Buffer, i int) {
+	n := poolSmall
+	if i%poolLargeEvery == poolLargeEvery-1 {
+		n = poolLarge
+	}
+	buf.Grow(n)
+	for buf.Len() < n {
+		buf.Write(poolChunk)
+	}
+}
+
+// badPool and goodPool outlive the lessons, like a server's pools
+var (
+	badPool  = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+	goodPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+)
+
+type poolLesson struct{}
+
+func init() { Register(poolLesson{}) }
+
+func (poolLesson) Name() string { return "pool" }
+
+func (poolLesson) Description() string {
+	return "Per-request buffers churn the heap, and a sync.Pool taking back buffers of any size pins the largest"
+}
+
+// serve runs the requests through phase, printing what it allocated and
+// what was still alive after it
+func serve(ctx context.Context, phase string, handle func(i int)) error {
+	var err error
+	d := memcheck.Measure(func() {
+		for i := 0; i < poolRequests; i++ {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			handle(i)
+		}
+	})
+	fmt.Printf("%-24s allocated %s, still held %s\n", phase+":", memcheck.Bytes(d.Allocated()), memcheck.Bytes(d.Heap()))
+	return err
+}
+
+// Bad: A buffer per request, then a pool that takes back any buffer,
+// pinning the large ones and handing them out for small responses
+func (poolLesson) RunBad(ctx context.Context) error {
+	if err := serve(ctx, "buffer per request", func(i int) {
+		buf := new(bytes.Buffer)
+		respond(buf, i)
+	}); err != nil {
+		return err
+	}
+	return serve(ctx, "pooled, any size", func(i int) {
+		buf := badPool.Get().(*bytes.Buffer)
+		respond(buf, i)
+		buf.Reset()
+		badPool.Put(buf)
+	})
+}
+
+// Good: Buffers reused through a pool that drops the oversized ones
+func (poolLesson) RunGood(ctx context.Context) error {
+	return serve(ctx, "pooled, capped", func(i int) {
+		buf := goodPool.Get().(*bytes.Buffer)
+		respond(buf, i)
+		if buf.Cap() > poolMaxCap {
+			return // Left to the collector rather than pinned by the pool
+		}
+		buf.Reset()
+		goodPool.Put(buf)
+	})
+}
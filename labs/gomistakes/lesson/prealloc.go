@@ -0,0 +1,78 @@
+package lesson
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"unsafe"
+
+	"gomistakes/memcheck"
+)
+
+// 26. Appending Without Preallocating
+//
+// append grows a full slice by allocating a larger array and copying
+// everything over, doubling small slices and growing large ones by about
+// a quarter. Over a few elements that is noise; over millions it is
+// dozens of allocations and several times the final size copied. When
+// the length is known, or can be bounded, make the slice with that
+// capacity; when it cannot, slices.Grow with an estimate still saves most
+// of the copies.
+var preallocSizes = []int{8, 1000, 100_000, 4_000_000}
+
+// growth appends n ints to s, counting the arrays it reallocated and the
+// bytes copied into them
+func growth(s []int, n int) (reallocs int, copied int64) {
+	for i := 0; i < n; i++ {
+		if len(s) == cap(s) && cap(s) > 0 {
+			reallocs++
+			copied += int64(len(s)) * int64(unsafe.Sizeof(i))
+		}
+		s = append(s, i)
+	}
+	sinkInts = s
+	return reallocs, copied
+}
+
+// size formats a size with a binary unit
+func size(n int64) string {
+	return strings.TrimPrefix(memcheck.Bytes(n), "+")
+}
+
+// printGrowth prints for each of preallocSizes the reallocations and bytes
+// copied appending to the slice start returns
+func printGrowth(start func(n int) []int) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "N\tREALLOCATIONS\tCOPIED\tFINAL")
+	for _, n := range preallocSizes {
+		reallocs, copied := growth(start(n), n)
+		final := int64(cap(sinkInts)) * int64(unsafe.Sizeof(0))
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\n", n, reallocs, size(copied), size(final))
+	}
+	tw.Flush()
+	sinkInts = nil
+}
+
+type preallocLesson struct{}
+
+func init() { Register(preallocLesson{}) }
+
+func (preallocLesson) Name() string { return "prealloc" }
+
+func (preallocLesson) Description() string {
+	return "Appending to an empty slice reallocates and copies it again and again as it grows"
+}
+
+// Bad: Starts empty and grows as it goes
+func (preallocLesson) RunBad(context.Context) error {
+	printGrowth(func(int) []int { return nil })
+	return nil
+}
+
+// Good: Made with room for all of it up front
+func (preallocLesson) RunGood(context.Context) error {
+	printGrowth(func(n int) []int { return make([]int, 0, n) })
+	return nil
+}
@@ -0,0 +1,150 @@
+package lesson
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// 30. Panics in Goroutines
+//
+// recover only stops a panic in the goroutine it runs in. A worker that
+// panics takes the whole process down, whatever the goroutine that
+// started it deferred. Recover at the top of each goroutine you start and
+// hand the panic on as an error, but only there: a recover deeper down
+// hides bugs behind a half-finished operation, and it cannot catch fatal
+// errors such as concurrent map writes anyway.
+var recoverRecords = []string{
+	"alice,30,admin",
+	"bob,25,user",
+	"carol,41", // Missing its role
+	"dave,35,user",
+}
+
+// parseRecord returns the role of a record, panicking on one with too few
+// fields
+func parseRecord(record string) string {
+	fields := strings.Split(record, ",")
+	return fields[2]
+}
+
+// panicError is a panic recovered in a task, and where it happened
+type panicError struct {
+	task  int
+	value any
+	at    string
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("task %d panicked at %s: %v", e.task, e.at, e.value)
+}
+
+// panicSite is the innermost call outside the runtime of the panic being
+// recovered, called from the deferred function recovering it
+func panicSite() string {
+	pcs := make([]uintptr, 16)
+	// Skips runtime.Callers, panicSite and the deferred function
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "runtime.") {
+			return fmt.Sprintf("%s:%d", filepath.Base(f.File), f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// Bad: A worker panics, and the recover deferred by the goroutine that
+// started it does not help. It runs in a copy of the program.
+func workerPanic() {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("recovered:", r) // Never reached
+		}
+	}()
+	var wg sync.WaitGroup
+	for _, record := range recoverRecords {
+		wg.Add(1)
+		go func(record string) {
+			defer wg.Done()
+			parseRecord(record)
+		}(record)
+	}
+	wg.Wait()
+}
+
+// Good: Runs task i in a goroutine that recovers from its panic and
+// reports it on errs, as it does any error
+func safeGo(wg *sync.WaitGroup, errs chan<- error, i int, task func() error) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				errs <- &panicError{task: i, value: r, at: panicSite()}
+			}
+		}()
+		if err := task(); err != nil {
+			errs <- fmt.Errorf("task %d: %w", i, err)
+		}
+	}()
+}
+
+type recoverLesson struct{}
+
+func init() { Register(recoverLesson{}) }
+
+func (recoverLesson) Name() string { return "recover" }
+
+func (recoverLesson) Description() string {
+	return "A panic in a goroutine crashes the process, past any recover in the goroutine that started it"
+}
+
+func (recoverLesson) RunBad(ctx context.Context) error {
+	out, err := subprocess(ctx, "recover")
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || !bytes.HasPrefix(out, []byte("panic: ")) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("expected the subprocess to crash on the worker's panic, got %v", err)
+	}
+	line, _, _ := bytes.Cut(out, []byte("\n"))
+	fmt.Printf("subprocess crashed (%s) without recovering: %s\n", exit, line)
+	return nil
+}
+
+func (recoverLesson) RunGood(context.Context) error {
+	errs := make(chan error, len(recoverRecords))
+	roles := make([]string, len(recoverRecords))
+	var wg sync.WaitGroup
+	for i, record := range recoverRecords {
+		i, record := i, record
+		safeGo(&wg, errs, i, func() error {
+			roles[i] = parseRecord(record)
+			return nil
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	var failed []error
+	for err := range errs {
+		failed = append(failed, err)
+	}
+	var pe *panicError
+	if len(failed) != 1 || !errors.As(failed[0], &pe) {
+		return fmt.Errorf("expected one recovered panic, got %v", failed)
+	}
+	fmt.Printf("roles: %q\n", roles)
+	fmt.Println("reported:", pe)
+	return nil
+}
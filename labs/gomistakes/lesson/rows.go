@@ -0,0 +1,188 @@
+package lesson
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	_ "modernc.org/sqlite"
+)
+
+// 20. sql.Rows Left Open
+//
+// Rows hold their connection until they are closed or read to the end. A
+// lookup that returns from inside its rows.Next loop keeps it, and once
+// the pool is out of connections every query waits for one that never
+// comes back.
+const (
+	// rowsScores rows are looked up rowsLookups times through a pool of
+	// rowsConns connections, rowsWorkers lookups at a time when concurrent
+	rowsScores  = 1000
+	rowsLookups = 100
+	rowsConns   = 4
+	rowsWorkers = 8
+	// rowsWait is how long a lookup may wait before it is taken as hung
+	rowsWait = 500 * time.Millisecond
+	// rowsLifetime and rowsIdleTime bound how long the tuned pool keeps a
+	// connection at all and unused
+	rowsLifetime = time.Minute
+	rowsIdleTime = 10 * time.Second
+)
+
+var errLookupHung = errors.New("lookup hung waiting for a connection")
+
+// openScores opens a SQLite database of rowsScores scores in a temporary
+// directory, removed by the returned func along with the database
+func openScores(ctx context.Context) (*sql.DB, func(), error) {
+	dir, err := os.MkdirTemp("", "gomistakes-rows")
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := sql.Open("sqlite", filepath.Join(dir, "scores.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	cleanup := func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+	if err := fillScores(ctx, db); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return db, cleanup, nil
+}
+
+// fillScores creates the scores table and fills it in one statement
+func fillScores(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, "CREATE TABLE scores (id INTEGER PRIMARY KEY, score INTEGER NOT NULL)"); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `WITH RECURSIVE ids(id) AS (SELECT 1 UNION ALL SELECT id + 1 FROM ids WHERE id < ?)
+		INSERT INTO scores (id, score) SELECT id, id * 37 % 100 FROM ids`, rowsScores)
+	return err
+}
+
+// printPool prints how a pool was doing
+func printPool(when string, s sql.DBStats) {
+	fmt.Printf("%-10s open %d/%d, in use %d, idle %d; waited %d times for %s, %d closed at max lifetime\n",
+		when+":", s.OpenConnections, s.MaxOpenConnections, s.InUse, s.Idle,
+		s.WaitCount, s.WaitDuration.Round(time.Millisecond), s.MaxLifetimeClosed)
+}
+
+// Bad: Returns from inside the loop, leaving the rows and their
+// connection open
+func firstOverBad(ctx context.Context, db *sql.DB, min int) (int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id FROM scores WHERE score >= ? ORDER BY id", min)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		return id, err
+	}
+	return 0, rows.Err()
+}
+
+// Good: Closes the rows however it returns
+func firstOverGood(ctx context.Context, db *sql.DB, min int) (int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id FROM scores WHERE score >= ? ORDER BY id", min)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		return id, err
+	}
+	return 0, rows.Err()
+}
+
+type rowsLesson struct{}
+
+func init() { Register(rowsLesson{}) }
+
+func (rowsLesson) Name() string { return "rows" }
+
+func (rowsLesson) Description() string {
+	return "sql.Rows left open keep their connections until the pool runs dry and queries hang"
+}
+
+// Bad: Lookups leaking a connection each, until one waits forever
+func (rowsLesson) RunBad(ctx context.Context) error {
+	db, cleanup, err := openScores(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	db.SetMaxOpenConns(rowsConns)
+
+	// Cancelling the queries' context is the only thing that closes the
+	// rows they left open
+	queries, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var stuck sql.DBStats
+	for i := 0; i < rowsLookups; i++ {
+		watchdog := time.AfterFunc(rowsWait, func() {
+			stuck = db.Stats()
+			cancel()
+		})
+		_, err := firstOverBad(queries, db, i%100)
+		if !watchdog.Stop() {
+			fmt.Printf("lookup %d: %v after %s\n", i, errLookupHung, rowsWait)
+			printPool("hung", stuck)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("lookup %d: %w", i, err)
+		}
+	}
+	printPool("done", db.Stats())
+	return nil
+}
+
+// Good: Rows closed by every lookup, through a pool bounded in size and
+// in how long it keeps connections, looked up concurrently
+func (rowsLesson) RunGood(ctx context.Context) error {
+	db, cleanup, err := openScores(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	db.SetMaxOpenConns(rowsConns)
+	db.SetMaxIdleConns(rowsConns)
+	db.SetConnMaxLifetime(rowsLifetime)
+	db.SetConnMaxIdleTime(rowsIdleTime)
+
+	for i := 0; i < rowsLookups; i++ {
+		if _, err := firstOverGood(ctx, db, i%100); err != nil {
+			return fmt.Errorf("lookup %d: %w", i, err)
+		}
+	}
+	printPool("sequential", db.Stats())
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(rowsWorkers)
+	for i := 0; i < rowsLookups; i++ {
+		i := i
+		g.Go(func() error {
+			if _, err := firstOverGood(gctx, db, i%100); err != nil {
+				return fmt.Errorf("lookup %d: %w", i, err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	printPool("concurrent", db.Stats())
+	return nil
+}
@@ -0,0 +1,88 @@
+package lesson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Variant selects the mistake or the fix of a lesson
+type Variant string
+
+const (
+	Bad  Variant = "bad"
+	Good Variant = "good"
+)
+
+// Variants lists both variants, the mistake first
+var Variants = []Variant{Bad, Good}
+
+// ErrAbandoned is the error of a run that did not return within
+// AbandonGrace of its timeout. Its goroutine is left running.
+var ErrAbandoned = errors.New("lesson did not stop at its timeout")
+
+// AbandonGrace is how long a run may take to return after its timeout
+var AbandonGrace = time.Second
+
+// Result is the outcome of running one variant of a lesson
+type Result struct {
+	Lesson  string
+	Variant Variant
+	Elapsed time.Duration
+	// TimedOut is set when the run was stopped by its timeout
+	TimedOut bool
+	Err      error
+}
+
+// Status sums the result up as ok, timeout or the error
+func (r Result) Status() string {
+	switch {
+	case r.Err != nil:
+		return "error: " + r.Err.Error()
+	case r.TimedOut:
+		return "timeout"
+	}
+	return "ok"
+}
+
+// Run runs variant v of l, stopping it after timeout, or never when
+// timeout is 0
+func Run(ctx context.Context, l Lesson, v Variant, timeout time.Duration) Result {
+	run := l.RunGood
+	if v == Bad {
+		run = l.RunBad
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	res := Result{Lesson: l.Name(), Variant: v}
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		done <- run(ctx)
+	}()
+	select {
+	case res.Err = <-done:
+	case <-ctx.Done():
+		select {
+		case res.Err = <-done:
+		case <-time.After(AbandonGrace):
+			res.Err = ErrAbandoned
+		}
+	}
+	res.Elapsed = time.Since(start)
+	res.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+	if res.TimedOut && errors.Is(res.Err, context.DeadlineExceeded) {
+		res.Err = nil
+	}
+	return res
+}
@@ -0,0 +1,155 @@
+package lesson
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// 19. Unlimited Outbound Calls
+const (
+	// semCalls calls are made to a backend serving semCapacity at once,
+	// semLimit at a time when limited, each taking semCallTime
+	semCalls    = 500
+	semCapacity = 32
+	semLimit    = 16
+	semCallTime = 5 * time.Millisecond
+)
+
+// raise sets peak to v if v is larger
+func raise(peak *atomic.Int32, v int32) {
+	for p := peak.Load(); v > p && !peak.CompareAndSwap(p, v); p = peak.Load() {
+	}
+}
+
+// backend is a local server that serves at most semCapacity requests at
+// once and rejects the rest, as an overloaded service does
+type backend struct {
+	*httptest.Server
+	running, peakRunning atomic.Int32
+	conns, peakConns     atomic.Int32
+	// opened is every connection accepted, closed since or not
+	opened atomic.Int32
+}
+
+func newBackend() *backend {
+	b := &backend{}
+	b.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		running := b.running.Add(1)
+		defer b.running.Add(-1)
+		raise(&b.peakRunning, running)
+		if running > semCapacity {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		time.Sleep(semCallTime)
+	}))
+	b.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			b.opened.Add(1)
+			raise(&b.peakConns, b.conns.Add(1))
+		case http.StateClosed, http.StateHijacked:
+			b.conns.Add(-1)
+		}
+	}
+	b.Start()
+	return b
+}
+
+// calls counts how the calls to a backend went
+type calls struct {
+	ok, rejected, failed atomic.Int32
+}
+
+// call makes a call to b with client, counting how it went
+func (c *calls) call(ctx context.Context, client *http.Client, b *backend) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.URL, nil)
+	if err != nil {
+		c.failed.Add(1)
+		return
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		c.failed.Add(1)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode == http.StatusOK {
+		c.ok.Add(1)
+	} else {
+		c.rejected.Add(1)
+	}
+}
+
+// limited makes semCalls calls to a fresh backend, each once acquire lets
+// it, and prints how they went
+func limited(ctx context.Context, how string, acquire func(context.Context) error, release func()) {
+	b := newBackend()
+	defer b.Close()
+	transport := &http.Transport{MaxIdleConnsPerHost: semLimit}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport}
+
+	var c calls
+	var wg sync.WaitGroup
+	for i := 0; i < semCalls; i++ {
+		if err := acquire(ctx); err != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer release()
+			c.call(ctx, client, b)
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("%s: %d ok, %d rejected, %d failed; at most %d requests and %d connections at once\n",
+		how, c.ok.Load(), c.rejected.Load(), c.failed.Load(), b.peakRunning.Load(), b.peakConns.Load())
+}
+
+type semaphoreLesson struct{}
+
+func init() { Register(semaphoreLesson{}) }
+
+func (semaphoreLesson) Name() string { return "semaphore" }
+
+func (semaphoreLesson) Description() string {
+	return "Calls made all at once exhaust connections and overload the service they call"
+}
+
+// Bad: Every call at once
+func (semaphoreLesson) RunBad(ctx context.Context) error {
+	limited(ctx, "unlimited", func(context.Context) error { return nil }, func() {})
+	return nil
+}
+
+// Good: At most semLimit calls at once, through a buffered channel or a
+// weighted semaphore
+func (semaphoreLesson) RunGood(ctx context.Context) error {
+	tokens := make(chan struct{}, semLimit)
+	limited(ctx, "channel semaphore", func(ctx context.Context) error {
+		select {
+		case tokens <- struct{}{}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, func() { <-tokens })
+
+	weighted := semaphore.NewWeighted(semLimit)
+	limited(ctx, "x/sync/semaphore", func(ctx context.Context) error {
+		return weighted.Acquire(ctx, 1)
+	}, func() { weighted.Release(1) })
+	return nil
+}
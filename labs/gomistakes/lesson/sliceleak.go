@@ -1,15 +1,40 @@
 package lesson
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // 3. Slice Leak
-func SliceLeak() {
-	// Bad: Original array stays in memory
+type sliceLesson struct{}
+
+// badTail and goodTail outlive the lessons, like results handed to a
+// long-lived caller
+var badTail, goodTail []int
+
+func init() { Register(sliceLesson{}) }
+
+func (sliceLesson) Name() string { return "slice" }
+
+func (sliceLesson) Description() string {
+	return "A small slice of a large array keeps the whole backing array in memory"
+}
+
+// Bad: Original array stays in memory
+func (sliceLesson) RunBad(context.Context) error {
 	data := make([]int, 1000000)
 	small := data[len(data)-3:]
+	badTail = small
+	fmt.Println(len(small), cap(small))
+	return nil
+}
 
-	// Good: Copy only what's needed
-	small = make([]int, 3)
+// Good: Copy only what's needed
+func (sliceLesson) RunGood(context.Context) error {
+	data := make([]int, 1000000)
+	small := make([]int, 3)
 	copy(small, data[len(data)-3:])
-	fmt.Println(len(small))
+	goodTail = small
+	fmt.Println(len(small), cap(small))
+	return nil
 }
@@ -0,0 +1,48 @@
+package lesson
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// subprocessEnv names, in the environment of a copy of the program a
+// lesson started, the function the copy runs instead of the program
+const subprocessEnv = "GOMISTAKES_SUBPROCESS"
+
+// subprocesses are what lessons run in a copy of the program because they
+// crash or exit it
+var subprocesses = map[string]func(){
+	"concurrentmap": concurrentMapWrites,
+	"exit":          fatalExit,
+	"recover":       workerPanic,
+	"waitgroup":     waitGroupReuse,
+}
+
+func init() {
+	name, ok := os.LookupEnv(subprocessEnv)
+	if !ok {
+		return
+	}
+	f, ok := subprocesses[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "lesson: unknown subprocess %q\n", name)
+		os.Exit(2)
+	}
+	f()
+	os.Exit(0)
+}
+
+// subprocess runs the function registered as name in a copy of the
+// program, with env added to its environment, and returns what it wrote
+// and how it exited
+func subprocess(ctx context.Context, name string, env ...string) ([]byte, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, exe)
+	cmd.Env = append(append(os.Environ(), subprocessEnv+"="+name), env...)
+	return cmd.CombinedOutput()
+}
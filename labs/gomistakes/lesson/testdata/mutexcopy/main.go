@@ -0,0 +1,37 @@
+// Command mutexcopy records hits through a method with a value receiver,
+// which locks a copy of the mutex rather than the counter's own. go vet
+// reports it and the race detector catches the unguarded writes.
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+type hitCounter struct {
+	sync.Mutex
+	hits []int // Per route, shared by every copy
+}
+
+// Bad: c is a copy, its mutex a new one each call
+func (c hitCounter) record(route int) {
+	c.Lock()
+	defer c.Unlock()
+	c.hits[route]++
+}
+
+func main() {
+	c := hitCounter{hits: make([]int, 4)}
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				c.record((w + i) % len(c.hits))
+			}
+		}(w)
+	}
+	wg.Wait()
+	fmt.Println(c.hits)
+}
@@ -7,19 +7,34 @@ import (
 )
 
 // 6. Timer/Ticker Leak
-func TimerLeak() {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+type timerLesson struct{}
+
+func init() { Register(timerLesson{}) }
+
+func (timerLesson) Name() string { return "timer" }
+
+func (timerLesson) Description() string {
+	return "A timer nobody stops holds its goroutine, and everything it references, until it fires"
+}
 
-	// Bad: Timer never stopped
+// Bad: Timer never stopped
+func (timerLesson) RunBad(ctx context.Context) error {
 	timer := time.NewTimer(time.Hour)
 	go func() {
 		<-timer.C
 		fmt.Println("Done!")
 	}()
 
-	// Good: Properly stop timer
-	timer = time.NewTimer(time.Second)
+	<-ctx.Done()
+	return nil
+}
+
+// Good: Properly stop timer
+func (timerLesson) RunGood(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	timer := time.NewTimer(time.Second)
 	defer timer.Stop()
 	go func() {
 		for {
@@ -32,5 +47,6 @@ func TimerLeak() {
 		}
 	}()
 
-	select {}
+	<-ctx.Done()
+	return nil
 }
@@ -0,0 +1,169 @@
+package lesson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// 23. Missing HTTP Timeouts
+//
+// Neither http.Client nor http.Server times out by default. A dependency
+// that stops answering holds every caller, and a client that stops
+// sending holds a server connection and goroutine, for as long as they
+// last.
+const (
+	// timeoutBudget is how long a request may take where there are
+	// timeouts
+	timeoutBudget = 100 * time.Millisecond
+	// timeoutStuck is how long a call may wait before it is taken as hung
+	timeoutStuck = 500 * time.Millisecond
+)
+
+// hungServer is a dependency whose handler answers nothing until it is
+// released or its request is cancelled
+type hungServer struct {
+	*httptest.Server
+	release chan struct{}
+	once    sync.Once
+}
+
+// newHungServer starts a hung server, wrap applied to its handler and
+// configure to its http.Server before it starts
+func newHungServer(wrap func(http.Handler) http.Handler, configure func(*http.Server)) *hungServer {
+	h := &hungServer{release: make(chan struct{})}
+	h.Server = httptest.NewUnstartedServer(wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-h.release:
+		case <-r.Context().Done():
+		}
+	})))
+	configure(h.Config)
+	h.Start()
+	return h
+}
+
+// Release lets the handlers still running and any started later return
+func (h *hungServer) Release() {
+	h.once.Do(func() { close(h.release) })
+}
+
+// Close releases the handlers and stops the server
+func (h *hungServer) Close() {
+	h.Release()
+	h.Server.Close()
+}
+
+// timed calls h with client, giving up waiting after timeoutStuck, and
+// prints how the call ended
+func timed(ctx context.Context, how string, client *http.Client, h *hungServer) {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL, nil)
+		if err != nil {
+			done <- err
+			return
+		}
+		resp, err := client.Do(req)
+		if err == nil {
+			defer resp.Body.Close()
+			io.Copy(io.Discard, resp.Body)
+			err = fmt.Errorf("%s", resp.Status)
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		fmt.Printf("%-22s ended after %s: %v\n", how+":", time.Since(start).Round(10*time.Millisecond), err)
+	case <-time.After(timeoutStuck):
+		fmt.Printf("%-22s still waiting after %s\n", how+":", timeoutStuck)
+		h.Release() // So the call can return
+		<-done
+	}
+}
+
+// slowHeaders connects to h and sends a request line and one header but
+// never the blank line that ends them, then prints whether the server gave
+// up on the connection within timeoutStuck
+func slowHeaders(how string, h *hungServer) error {
+	conn, err := net.Dial("tcp", h.Listener.Addr().String())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	start := time.Now()
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: gomistakes\r\n"); err != nil {
+		return err
+	}
+	conn.SetReadDeadline(start.Add(timeoutStuck))
+	_, err = io.ReadAll(conn)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		fmt.Printf("%-22s server still holding the connection after %s\n", how+":", timeoutStuck)
+		return nil
+	}
+	fmt.Printf("%-22s server closed the connection after %s\n", how+":", time.Since(start).Round(10*time.Millisecond))
+	return nil
+}
+
+type timeoutsLesson struct{}
+
+func init() { Register(timeoutsLesson{}) }
+
+func (timeoutsLesson) Name() string { return "timeouts" }
+
+func (timeoutsLesson) Description() string {
+	return "HTTP clients and servers without timeouts wait forever on a hung peer"
+}
+
+// unwrapped leaves a handler as it is
+func unwrapped(h http.Handler) http.Handler { return h }
+
+// Bad: A client without a timeout calling a hung dependency, and a server
+// without timeouts waiting on a client that stopped sending
+func (timeoutsLesson) RunBad(ctx context.Context) error {
+	h := newHungServer(unwrapped, func(*http.Server) {})
+	defer h.Close()
+	if err := slowHeaders("slow client", h); err != nil {
+		return err
+	}
+	timed(ctx, "client", &http.Client{}, h) // No Timeout
+	return nil
+}
+
+// Good: A deadline on every request and a client timeout behind it, and a
+// server bounding how long it reads, writes, keeps idle connections and
+// lets handlers run
+func (timeoutsLesson) RunGood(ctx context.Context) error {
+	h := newHungServer(func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, timeoutBudget, "timed out")
+	}, func(s *http.Server) {
+		s.ReadHeaderTimeout = timeoutBudget
+		s.ReadTimeout = timeoutBudget
+		s.WriteTimeout = 2 * timeoutBudget // Longer than the handler may run
+		s.IdleTimeout = time.Minute
+	})
+	defer h.Close()
+	if err := slowHeaders("slow client", h); err != nil {
+		return err
+	}
+
+	// Without the TimeoutHandler the dependency hangs as it does in the bad
+	// variant, which the client deadlines must stop
+	dep := newHungServer(unwrapped, func(*http.Server) {})
+	defer dep.Close()
+	reqCtx, cancel := context.WithTimeout(ctx, timeoutBudget)
+	defer cancel()
+	timed(reqCtx, "request deadline", &http.Client{}, dep)
+	timed(ctx, "client timeout", &http.Client{Timeout: timeoutBudget}, dep)
+
+	timed(ctx, "server timeout", &http.Client{Timeout: timeoutStuck}, h)
+	return nil
+}
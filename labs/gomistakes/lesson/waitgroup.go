@@ -0,0 +1,189 @@
+package lesson
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 16. WaitGroup Misuse
+//
+// The fixes run clean under the race detector. The mistakes are ordering
+// bugs rather than data races, which it does not report.
+const (
+	// wgTasks is how many tasks each part starts and wgRounds how many
+	// rounds the reuse part runs
+	wgTasks  = 5
+	wgRounds = 3
+	// wgStuck is how long Wait may block before it is taken to be stuck
+	wgStuck = 100 * time.Millisecond
+)
+
+// byValue copies the value p points to, as passing it by value or calling
+// a value method on it does. The lessons copy lock-bearing values with it,
+// a sync.WaitGroup or a struct holding a sync.Mutex: go vet reports those
+// forms, not this one.
+func byValue[T any](p *T) T { return *p }
+
+// Bad: Add inside the goroutine. Wait can run before any Add and return
+// with every task still to do.
+func addInside() int {
+	var wg sync.WaitGroup
+	var done atomic.Int32
+	start := make(chan struct{})
+	var all sync.WaitGroup // Only so the lesson does not leak the tasks
+	for i := 0; i < wgTasks; i++ {
+		all.Add(1)
+		go func() {
+			defer all.Done()
+			<-start // Scheduled late, as a busy program may
+			wg.Add(1)
+			defer wg.Done()
+			done.Add(1)
+		}()
+	}
+	wg.Wait()
+	finished := int(done.Load())
+	close(start)
+	all.Wait()
+	return finished
+}
+
+// Bad: Each task calls Done on its own copy, so the counter of the
+// WaitGroup waited on never reaches zero
+func doneOnCopy() bool {
+	var wg sync.WaitGroup
+	for i := 0; i < wgTasks; i++ {
+		wg.Add(1)
+		copied := byValue(&wg)
+		go func() {
+			defer copied.Done()
+		}()
+	}
+	waited := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		return false
+	case <-time.After(wgStuck):
+		wg.Add(-wgTasks) // Released so the waiting goroutine does not leak
+		<-waited
+		return true
+	}
+}
+
+// Bad: A task of one round Adds the next to the WaitGroup before the Wait
+// of its own round has returned, which panics. It runs in a copy of the
+// program.
+func waitGroupReuse() {
+	// On one processor the waiter woken by Done runs only once the task
+	// that called it yields, after its Add, so the panic is certain
+	runtime.GOMAXPROCS(1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		wg.Done()
+		wg.Add(1) // The next round
+		go func() {
+			time.Sleep(wgStuck)
+			wg.Done()
+		}()
+	}()
+	wg.Wait()
+}
+
+// Good: Add before starting each goroutine
+func addBefore() int {
+	var wg sync.WaitGroup
+	var done atomic.Int32
+	for i := 0; i < wgTasks; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done.Add(1)
+		}()
+	}
+	wg.Wait()
+	return int(done.Load())
+}
+
+// Good: Pass the WaitGroup by pointer
+func doneOnPointer(wg *sync.WaitGroup) {
+	defer wg.Done()
+}
+
+// Good: Start a round only once the Wait of the last one has returned
+func rounds() int {
+	var wg sync.WaitGroup
+	var done atomic.Int32
+	for r := 0; r < wgRounds; r++ {
+		for i := 0; i < wgTasks; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				done.Add(1)
+			}()
+		}
+		wg.Wait()
+	}
+	return int(done.Load())
+}
+
+type waitgroupLesson struct{}
+
+func init() { Register(waitgroupLesson{}) }
+
+func (waitgroupLesson) Name() string { return "waitgroup" }
+
+func (waitgroupLesson) Description() string {
+	return "A WaitGroup added to inside its goroutines, reused before Wait returns or copied waits wrongly"
+}
+
+func (waitgroupLesson) RunBad(ctx context.Context) error {
+	fmt.Printf("add inside: Wait returned after %d of %d tasks\n", addInside(), wgTasks)
+	if doneOnCopy() {
+		fmt.Printf("done on copy: Wait still blocked after %s\n", wgStuck)
+	}
+
+	out, err := subprocess(ctx, "waitgroup")
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || !bytes.Contains(out, []byte("WaitGroup")) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("expected the subprocess to panic on WaitGroup reuse, got %v", err)
+	}
+	line, _, _ := bytes.Cut(out, []byte("\n"))
+	fmt.Printf("reuse: subprocess crashed (%s): %s\n", exit, line)
+	return nil
+}
+
+func (waitgroupLesson) RunGood(context.Context) error {
+	if n := addBefore(); n != wgTasks {
+		return fmt.Errorf("add before: Wait returned after %d of %d tasks", n, wgTasks)
+	}
+	fmt.Printf("add before: Wait returned after %d of %d tasks\n", wgTasks, wgTasks)
+
+	var wg sync.WaitGroup
+	for i := 0; i < wgTasks; i++ {
+		wg.Add(1)
+		go doneOnPointer(&wg)
+	}
+	wg.Wait()
+	fmt.Println("done on pointer: Wait returned")
+
+	if n := rounds(); n != wgRounds*wgTasks {
+		return fmt.Errorf("rounds: %d of %d tasks done", n, wgRounds*wgTasks)
+	}
+	fmt.Printf("rounds: %d rounds of %d tasks\n", wgRounds, wgTasks)
+	return nil
+}
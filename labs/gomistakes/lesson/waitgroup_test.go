@@ -0,0 +1,52 @@
+package lesson
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// These run under go test -race too: the fixes must be clean under the
+// race detector, while the mistakes, ordering bugs, are caught here.
+
+func TestAddBefore(t *testing.T) {
+	if n := addBefore(); n != wgTasks {
+		t.Errorf("addBefore() = %d, want %d", n, wgTasks)
+	}
+}
+
+func TestDoneOnPointer(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < wgTasks; i++ {
+		wg.Add(1)
+		go doneOnPointer(&wg)
+	}
+	waited := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait still blocked after every task called doneOnPointer")
+	}
+}
+
+func TestRounds(t *testing.T) {
+	if n := rounds(); n != wgRounds*wgTasks {
+		t.Errorf("rounds() = %d, want %d", n, wgRounds*wgTasks)
+	}
+}
+
+func TestAddInsideReturnsEarly(t *testing.T) {
+	if n := addInside(); n >= wgTasks {
+		t.Errorf("addInside() = %d, want Wait to return before all %d tasks", n, wgTasks)
+	}
+}
+
+func TestDoneOnCopyBlocks(t *testing.T) {
+	if !doneOnCopy() {
+		t.Error("doneOnCopy() = false, want Wait to stay blocked")
+	}
+}
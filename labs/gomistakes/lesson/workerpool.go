@@ -0,0 +1,74 @@
+package lesson
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// 18. Goroutine per Task
+const poolTasks = 100_000
+
+// tinyTask is a few hundred nanoseconds of work. It yields once, as a
+// task making a call would, so spawned tasks pile up rather than finish
+// before the next is started.
+func tinyTask(i int, sum *atomic.Int64) {
+	runtime.Gosched()
+	h := uint64(i)
+	for j := 0; j < 64; j++ {
+		h = h*6364136223846793005 + 1442695040888963407
+	}
+	sum.Add(int64(h >> 60))
+}
+
+type workerpoolLesson struct{}
+
+func init() { Register(workerpoolLesson{}) }
+
+func (workerpoolLesson) Name() string { return "workerpool" }
+
+func (workerpoolLesson) Description() string {
+	return "A goroutine per tiny task piles up goroutines and their stacks where a few workers would do"
+}
+
+// Bad: A goroutine for every task
+func (workerpoolLesson) RunBad(ctx context.Context) error {
+	var sum atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < poolTasks && ctx.Err() == nil; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tinyTask(i, &sum)
+		}(i)
+	}
+	wg.Wait()
+	fmt.Println(poolTasks, "tasks on as many goroutines, sum", sum.Load())
+	return nil
+}
+
+// Good: A worker per processor taking tasks from a channel
+func (workerpoolLesson) RunGood(ctx context.Context) error {
+	var sum atomic.Int64
+	var wg sync.WaitGroup
+	tasks := make(chan int, 256)
+	workers := runtime.GOMAXPROCS(0)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range tasks {
+				tinyTask(i, &sum)
+			}
+		}()
+	}
+	for i := 0; i < poolTasks && ctx.Err() == nil; i++ {
+		tasks <- i
+	}
+	close(tasks)
+	wg.Wait()
+	fmt.Println(poolTasks, "tasks on", workers, "workers, sum", sum.Load())
+	return nil
+}
@@ -1,18 +1,30 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
 	"gomistakes/lesson"
 )
 
+// main runs the fixed version of the lesson named by its argument, the
+// defer lesson by default
 func main() {
-	// lesson.GoroutineLeak()
-	// lesson.GoroutineLeakWithContext()
-	// lesson.SliceLeak()
-	// lesson.ClosureLeak()
-	// lesson.MapLeak()
-	// lesson.ChannelLeak()
-	// lesson.TimerLeak()
-	// lesson.GlobalVariableLeak()
-	// lesson.HTTPBodyLeak()
-	lesson.DeferInLoopLeak()
+	name := "defer"
+	if len(os.Args) > 1 {
+		name = os.Args[1]
+	}
+	l, ok := lesson.Get(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown lesson %q; lessons are:\n", name)
+		for _, l := range lesson.All() {
+			fmt.Fprintf(os.Stderr, "  %-10s %s\n", l.Name(), l.Description())
+		}
+		os.Exit(2)
+	}
+	if err := l.RunGood(context.Background()); err != nil {
+		log.Fatal(err)
+	}
 }
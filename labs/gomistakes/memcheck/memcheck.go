@@ -0,0 +1,171 @@
+// Package memcheck measures what a lesson leaves behind: the heap and
+// goroutines still alive after a garbage collection, before and after it
+// runs, and what it used on the way: the heap it allocated and the most
+// heap and goroutines it had at once.
+package memcheck
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/metrics"
+	"text/tabwriter"
+	"time"
+)
+
+// Settle is how long Measure waits after f returns for goroutines that
+// are stopping to exit
+var Settle = 100 * time.Millisecond
+
+// Interval is how often Measure samples the heap and goroutines while f
+// runs
+var Interval = time.Millisecond
+
+// Snapshot is the live heap and goroutine count at one point, and the
+// heap allocated so far
+type Snapshot struct {
+	HeapAlloc   uint64
+	HeapObjects uint64
+	Goroutines  int
+	TotalAlloc  uint64
+	Mallocs     uint64
+}
+
+// Take forces a garbage collection and snapshots what survived it
+func Take() Snapshot {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return Snapshot{HeapAlloc: m.HeapAlloc, HeapObjects: m.HeapObjects, Goroutines: runtime.NumGoroutine(),
+		TotalAlloc: m.TotalAlloc, Mallocs: m.Mallocs}
+}
+
+// Delta is the change from Before to After. Peak holds the largest heap,
+// garbage included, and the most goroutines sampled in between.
+type Delta struct {
+	Before, After Snapshot
+	Peak          Snapshot
+}
+
+// Heap is the change in live heap bytes
+func (d Delta) Heap() int64 { return int64(d.After.HeapAlloc) - int64(d.Before.HeapAlloc) }
+
+// Objects is the change in live heap objects
+func (d Delta) Objects() int64 { return int64(d.After.HeapObjects) - int64(d.Before.HeapObjects) }
+
+// Goroutines is the change in running goroutines
+func (d Delta) Goroutines() int { return d.After.Goroutines - d.Before.Goroutines }
+
+// Allocated is the heap bytes allocated in between, freed since or not
+func (d Delta) Allocated() int64 { return int64(d.After.TotalAlloc - d.Before.TotalAlloc) }
+
+// Allocs is the heap objects allocated in between
+func (d Delta) Allocs() int64 { return int64(d.After.Mallocs - d.Before.Mallocs) }
+
+// PeakHeap is the most the heap grew at any point
+func (d Delta) PeakHeap() int64 { return int64(d.Peak.HeapAlloc) - int64(d.Before.HeapAlloc) }
+
+// PeakGoroutines is the most goroutines added at any point
+func (d Delta) PeakGoroutines() int { return d.Peak.Goroutines - d.Before.Goroutines }
+
+// Measure snapshots before f and, after Settle, once f has returned,
+// sampling the peaks every Interval while f runs
+func Measure(f func()) Delta {
+	before := Take()
+	stop, peak := make(chan struct{}), make(chan Snapshot)
+	go sample(before, stop, peak)
+	f()
+	close(stop)
+	d := Delta{Before: before, Peak: <-peak}
+	time.Sleep(Settle)
+	d.After = Take()
+	return d
+}
+
+// sample tracks the peak heap and goroutines, not counting its own, from
+// start until stop is closed and sends it on peak
+func sample(start Snapshot, stop <-chan struct{}, peak chan<- Snapshot) {
+	heap := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	p := Snapshot{HeapAlloc: start.HeapAlloc, Goroutines: start.Goroutines}
+	take := func() {
+		metrics.Read(heap)
+		p.HeapAlloc = max(p.HeapAlloc, heap[0].Value.Uint64())
+		p.Goroutines = max(p.Goroutines, runtime.NumGoroutine()-1)
+	}
+	ticker := time.NewTicker(Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			take()
+		case <-stop:
+			take()
+			peak <- p
+			return
+		}
+	}
+}
+
+// Comparison is what the bad and good variants of a lesson left behind and
+// used; either is nil when it was not run
+type Comparison struct {
+	Lesson    string
+	Bad, Good *Delta
+}
+
+// WriteTable writes comparisons as two tables, what the runs left behind
+// and what they used, the bad variant's delta beside the good one's for
+// each measure
+func WriteTable(w io.Writer, cs []Comparison) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LESSON\tHEAP BAD\tHEAP GOOD\tOBJECTS BAD\tOBJECTS GOOD\tGOROUTINES BAD\tGOROUTINES GOOD")
+	for _, c := range cs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Lesson,
+			column(c.Bad, func(d Delta) string { return Bytes(d.Heap()) }),
+			column(c.Good, func(d Delta) string { return Bytes(d.Heap()) }),
+			column(c.Bad, func(d Delta) string { return signed(d.Objects()) }),
+			column(c.Good, func(d Delta) string { return signed(d.Objects()) }),
+			column(c.Bad, func(d Delta) string { return signed(int64(d.Goroutines())) }),
+			column(c.Good, func(d Delta) string { return signed(int64(d.Goroutines())) }))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "LESSON\tALLOCATED BAD\tALLOCATED GOOD\tPEAK HEAP BAD\tPEAK HEAP GOOD\tPEAK GOROUTINES BAD\tPEAK GOROUTINES GOOD")
+	for _, c := range cs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Lesson,
+			column(c.Bad, func(d Delta) string { return Bytes(d.Allocated()) }),
+			column(c.Good, func(d Delta) string { return Bytes(d.Allocated()) }),
+			column(c.Bad, func(d Delta) string { return Bytes(d.PeakHeap()) }),
+			column(c.Good, func(d Delta) string { return Bytes(d.PeakHeap()) }),
+			column(c.Bad, func(d Delta) string { return signed(int64(d.PeakGoroutines())) }),
+			column(c.Good, func(d Delta) string { return signed(int64(d.PeakGoroutines())) }))
+	}
+	return tw.Flush()
+}
+
+func column(d *Delta, f func(Delta) string) string {
+	if d == nil {
+		return "-"
+	}
+	return f(*d)
+}
+
+func signed(n int64) string {
+	return fmt.Sprintf("%+d", n)
+}
+
+// Bytes formats a change in bytes with a sign and a binary unit
+func Bytes(n int64) string {
+	sign := "+"
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%s%.1f GiB", sign, float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%s%.1f MiB", sign, float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%s%.1f KiB", sign, float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%s%d B", sign, n)
+}
@@ -0,0 +1,166 @@
+// Package profile writes pprof profiles of a lesson run and serves them
+// live. Heap, allocs and goroutine profiles are written before and after
+// the run, to compare with
+//
+//	go tool pprof -base map-bad-heap-before.pb.gz map-bad-heap-after.pb.gz
+//
+// while a CPU profile covers the run itself.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	httppprof "net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+)
+
+// ErrUnknownKind is returned for a profile that cannot be written
+var ErrUnknownKind = errors.New("unknown profile")
+
+// Kind is a profile that can be written of a run
+type Kind string
+
+const (
+	Heap      Kind = "heap"
+	Allocs    Kind = "allocs"
+	Goroutine Kind = "goroutine"
+	CPU       Kind = "cpu"
+)
+
+// Kinds lists every kind
+var Kinds = []Kind{Heap, Allocs, Goroutine, CPU}
+
+// ParseKinds parses a comma separated list of kinds
+func ParseKinds(s string) ([]Kind, error) {
+	var kinds []Kind
+	for _, name := range strings.Split(s, ",") {
+		k := Kind(strings.TrimSpace(name))
+		known := false
+		for _, kind := range Kinds {
+			known = known || k == kind
+		}
+		if !known {
+			return nil, fmt.Errorf("%w %q; want %s", ErrUnknownKind, k, list(Kinds))
+		}
+		kinds = append(kinds, k)
+	}
+	return kinds, nil
+}
+
+func list(kinds []Kind) string {
+	names := make([]string, len(kinds))
+	for i, k := range kinds {
+		names[i] = string(k)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Profiler writes the profiles of Kinds into Dir
+type Profiler struct {
+	Dir   string
+	Kinds []Kind
+}
+
+// Capture runs f, writing the profiles of the run named name around it,
+// and returns the files written
+func (p *Profiler) Capture(name string, f func()) ([]string, error) {
+	if len(p.Kinds) == 0 {
+		f()
+		return nil, nil
+	}
+	if err := os.MkdirAll(p.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	files, err := p.snapshots(name, "before")
+	if err != nil {
+		return files, err
+	}
+	var cpu *os.File
+	if p.has(CPU) {
+		path := filepath.Join(p.Dir, fmt.Sprintf("%s-cpu.pb.gz", name))
+		if cpu, err = os.Create(path); err != nil {
+			return files, err
+		}
+		defer cpu.Close()
+		if err := pprof.StartCPUProfile(cpu); err != nil {
+			return files, err
+		}
+		files = append(files, path)
+	}
+
+	f()
+
+	if cpu != nil {
+		pprof.StopCPUProfile()
+		if err := cpu.Close(); err != nil {
+			return files, err
+		}
+	}
+	after, err := p.snapshots(name, "after")
+	return append(files, after...), err
+}
+
+func (p *Profiler) has(k Kind) bool {
+	for _, kind := range p.Kinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshots writes every profile but the CPU one of the run named name,
+// suffixed with when
+func (p *Profiler) snapshots(name, when string) ([]string, error) {
+	var files []string
+	for _, k := range p.Kinds {
+		if k == CPU {
+			continue
+		}
+		if k == Heap {
+			// The heap profile is as of the last garbage collection
+			runtime.GC()
+		}
+		path := filepath.Join(p.Dir, fmt.Sprintf("%s-%s-%s.pb.gz", name, k, when))
+		if err := write(path, k); err != nil {
+			return files, err
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+func write(path string, k Kind) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pprof.Lookup(string(k)).WriteTo(f, 0); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Serve serves the net/http/pprof handlers on addr until the program
+// exits and returns the address listened on
+func Serve(addr string) (net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", httppprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", httppprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", httppprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", httppprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
+	go http.Serve(ln, mux)
+	return ln.Addr(), nil
+}
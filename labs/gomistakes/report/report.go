@@ -0,0 +1,169 @@
+// Package report renders the results of lesson runs and benchmarks as a
+// single HTML page, styles and charts inline, to share after a workshop.
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"gomistakes/lesson"
+	"gomistakes/memcheck"
+)
+
+//go:embed report.html
+var page string
+
+var tmpl = template.Must(template.New("report").Funcs(template.FuncMap{"bytes": memcheck.Bytes}).Parse(page))
+
+// Run is how a variant of a lesson went
+type Run struct {
+	lesson.Result
+	// Leaked is how many goroutines the run left running
+	Leaked int
+	// Memory is what the run left behind, nil if it was not measured
+	Memory *memcheck.Delta
+}
+
+// Benchmark is the result of a lesson benchmark
+type Benchmark struct {
+	lesson.Benchmark
+	testing.BenchmarkResult
+}
+
+// Report is what a workshop ran
+type Report struct {
+	Title      string
+	Generated  time.Time
+	Runs       []Run
+	Benchmarks []Benchmark
+}
+
+// view is the report as the page shows it, a section per lesson
+type view struct {
+	Title        string
+	Generated    string
+	Lessons      []section
+	Runs, Failed int
+	Leaked       int
+}
+
+type section struct {
+	Name, Description string
+	Runs              []Run
+	Benchmarks        []Benchmark
+	Charts            []chart
+}
+
+// chart compares a measure of the variants of a lesson
+type chart struct {
+	Title string
+	Bars  []bar
+}
+
+type bar struct {
+	Variant lesson.Variant
+	Label   string
+	// Width is the percentage of the chart the bar spans
+	Width float64
+}
+
+// WriteHTML writes r as a self-contained HTML page
+func (r *Report) WriteHTML(w io.Writer) error {
+	return tmpl.Execute(w, r.view())
+}
+
+func (r *Report) view() view {
+	v := view{Title: r.Title, Generated: r.Generated.Format(time.RFC1123)}
+	sections := map[string]*section{}
+	var order []string
+	of := func(name string) *section {
+		s, ok := sections[name]
+		if !ok {
+			s = &section{Name: name}
+			if l, ok := lesson.Get(name); ok {
+				s.Description = l.Description()
+			}
+			sections[name] = s
+			order = append(order, name)
+		}
+		return s
+	}
+	for _, run := range r.Runs {
+		s := of(run.Lesson)
+		s.Runs = append(s.Runs, run)
+		v.Runs++
+		v.Leaked += run.Leaked
+		if run.Err != nil {
+			v.Failed++
+		}
+	}
+	for _, b := range r.Benchmarks {
+		s := of(b.Lesson)
+		s.Benchmarks = append(s.Benchmarks, b)
+	}
+
+	for _, name := range order {
+		s := sections[name]
+		var heap, allocated, peakHeap, peakGoroutines, leaked, ns, bytes []point
+		for _, run := range s.Runs {
+			if m := run.Memory; m != nil {
+				heap = append(heap, point{run.Variant, float64(m.Heap()), memcheck.Bytes(m.Heap())})
+				allocated = append(allocated, point{run.Variant, float64(m.Allocated()), memcheck.Bytes(m.Allocated())})
+				peakHeap = append(peakHeap, point{run.Variant, float64(m.PeakHeap()), memcheck.Bytes(m.PeakHeap())})
+				peakGoroutines = append(peakGoroutines, point{run.Variant, float64(m.PeakGoroutines()), fmt.Sprintf("%+d", m.PeakGoroutines())})
+			}
+			leaked = append(leaked, point{run.Variant, float64(run.Leaked), fmt.Sprint(run.Leaked)})
+		}
+		for _, b := range s.Benchmarks {
+			ns = append(ns, point{b.Variant, float64(b.NsPerOp()), fmt.Sprintf("%d ns/op", b.NsPerOp())})
+			bytes = append(bytes, point{b.Variant, float64(b.AllocedBytesPerOp()), fmt.Sprintf("%d B/op", b.AllocedBytesPerOp())})
+		}
+		for _, c := range []struct {
+			title  string
+			points []point
+		}{
+			{"Heap left behind", heap},
+			{"Allocated during the run", allocated},
+			{"Peak heap", peakHeap},
+			{"Peak goroutines", peakGoroutines},
+			{"Goroutines leaked", leaked},
+			{"Time per operation", ns},
+			{"Allocated per operation", bytes},
+		} {
+			if len(c.points) > 0 {
+				s.Charts = append(s.Charts, plot(c.title, c.points))
+			}
+		}
+		v.Lessons = append(v.Lessons, *s)
+	}
+	return v
+}
+
+// point is a measure of a variant and how it is labelled
+type point struct {
+	variant lesson.Variant
+	value   float64
+	label   string
+}
+
+// plot scales the bars of points to the largest of them
+func plot(title string, points []point) chart {
+	var most float64
+	for _, p := range points {
+		most = math.Max(most, math.Abs(p.value))
+	}
+	c := chart{Title: title}
+	for _, p := range points {
+		width := 0.0
+		if most > 0 {
+			width = 100 * math.Abs(p.value) / most
+		}
+		c.Bars = append(c.Bars, bar{Variant: p.variant, Label: p.label, Width: width})
+	}
+	return c
+}
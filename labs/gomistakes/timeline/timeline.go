@@ -0,0 +1,181 @@
+// Package timeline records how a run uses the runtime over time: the
+// goroutines, the live heap and the garbage collections, sampled while it
+// runs, alongside an execution trace for go tool trace. It prints them as
+// a text timeline.
+package timeline
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/trace"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gomistakes/memcheck"
+)
+
+// Interval is how often Record samples the runtime
+var Interval = 10 * time.Millisecond
+
+// Rows is the most rows WriteText prints, samples in between skipped
+var Rows = 20
+
+// Sample is the runtime at one point of a run
+type Sample struct {
+	// At is how long into the run it was taken
+	At         time.Duration
+	Goroutines int
+	HeapAlloc  uint64
+	NumGC      uint32
+	PauseTotal time.Duration
+}
+
+// Timeline is the samples of a run, the first taken as it started and the
+// last as it ended
+type Timeline struct {
+	Samples []Sample
+	// MaxPause is the longest garbage collection pause during the run
+	MaxPause time.Duration
+}
+
+// sampler takes samples and follows the pauses between them
+type sampler struct {
+	start  time.Time
+	lastGC uint32
+	t      Timeline
+}
+
+func (s *sampler) take() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	if len(s.t.Samples) == 0 {
+		s.lastGC = m.NumGC
+	}
+	// PauseNs holds the pauses of the last 256 collections
+	for n := s.lastGC + 1; n <= m.NumGC && m.NumGC-n < 256; n++ {
+		if p := time.Duration(m.PauseNs[(n+255)%256]); p > s.t.MaxPause {
+			s.t.MaxPause = p
+		}
+	}
+	s.lastGC = m.NumGC
+	s.t.Samples = append(s.t.Samples, Sample{
+		At:         time.Since(s.start),
+		Goroutines: runtime.NumGoroutine(),
+		HeapAlloc:  m.HeapAlloc,
+		NumGC:      m.NumGC,
+		PauseTotal: time.Duration(m.PauseTotalNs),
+	})
+}
+
+// Record runs f, sampling the runtime every Interval, and writes its
+// execution trace to w unless w is nil. The sampling goroutine has exited
+// when Record returns.
+func Record(w io.Writer, f func()) (*Timeline, error) {
+	if w != nil {
+		if err := trace.Start(w); err != nil {
+			return nil, err
+		}
+	}
+	s := &sampler{start: time.Now()}
+	s.take()
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.take()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	f()
+
+	close(stop)
+	<-done
+	s.take()
+	if w != nil {
+		trace.Stop()
+	}
+	return &s.t, nil
+}
+
+func (t *Timeline) first() Sample { return t.Samples[0] }
+func (t *Timeline) last() Sample  { return t.Samples[len(t.Samples)-1] }
+
+// GCs is how many garbage collections ran
+func (t *Timeline) GCs() int { return int(t.last().NumGC - t.first().NumGC) }
+
+// Pauses is how long garbage collections stopped the program in total
+func (t *Timeline) Pauses() time.Duration { return t.last().PauseTotal - t.first().PauseTotal }
+
+// HeapGrowth is the change in the heap from the start to the end
+func (t *Timeline) HeapGrowth() int64 {
+	return int64(t.last().HeapAlloc) - int64(t.first().HeapAlloc)
+}
+
+// PeakHeap is the largest heap sampled
+func (t *Timeline) PeakHeap() uint64 {
+	var peak uint64
+	for _, s := range t.Samples {
+		peak = max(peak, s.HeapAlloc)
+	}
+	return peak
+}
+
+// PeakGoroutines is the most goroutines sampled
+func (t *Timeline) PeakGoroutines() int {
+	var peak int
+	for _, s := range t.Samples {
+		peak = max(peak, s.Goroutines)
+	}
+	return peak
+}
+
+// barWidth is the width of the heap bar of the fullest row
+const barWidth = 30
+
+// WriteText writes a summary of t and, a row per sample up to Rows, the
+// goroutines, heap and collections over time
+func (t *Timeline) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "gc: %d collections, %s paused, longest %s\n", t.GCs(), t.Pauses(), t.MaxPause)
+	fmt.Fprintf(w, "goroutines: %d at start, %d at peak, %d at end\n", t.first().Goroutines, t.PeakGoroutines(), t.last().Goroutines)
+	fmt.Fprintf(w, "heap: %s at peak, %s at end\n", bytes(t.PeakHeap()), memcheck.Bytes(t.HeapGrowth()))
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tGOROUTINES\tHEAP\tGC\t")
+	peak := t.PeakHeap()
+	for _, s := range t.rows() {
+		bar := 0
+		if peak > 0 {
+			bar = int(s.HeapAlloc * barWidth / peak)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\n", s.At.Round(time.Millisecond), s.Goroutines, bytes(s.HeapAlloc),
+			s.NumGC-t.first().NumGC, strings.Repeat("#", bar))
+	}
+	return tw.Flush()
+}
+
+// rows picks at most Rows samples evenly, the first and last included
+func (t *Timeline) rows() []Sample {
+	n := len(t.Samples)
+	if n <= Rows || Rows < 2 {
+		return t.Samples
+	}
+	rows := make([]Sample, Rows)
+	for i := range rows {
+		rows[i] = t.Samples[i*(n-1)/(Rows-1)]
+	}
+	return rows
+}
+
+// bytes formats a size with a binary unit
+func bytes(n uint64) string {
+	return strings.TrimPrefix(memcheck.Bytes(int64(n)), "+")
+}
@@ -0,0 +1,137 @@
+package loan
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"loan/retry"
+	"loan/tracing"
+)
+
+// AffordabilityMonths is how many months of bank history affordability
+// checks look at
+const AffordabilityMonths = 6
+
+// Bank transaction categories. The affordability analyzer ignores
+// transfers and counts debt repayments separately; any other money in is
+// income and any other money out is spending.
+const (
+	CategorySalary   = "salary"
+	CategoryTransfer = "transfer"
+	CategoryDebt     = "debt_repayment"
+)
+
+// BankTransaction is a booked entry on a customer's bank account
+type BankTransaction struct {
+	ID       string    `json:"id"`
+	Account  string    `json:"account"`
+	BookedAt time.Time `json:"bookedAt"`
+	// Amount is positive for money in and negative for money out
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description,omitempty"`
+	Category    string  `json:"category,omitempty"`
+}
+
+// AccountDataProvider pulls the transactions of the bank accounts a
+// customer has consented to share
+type AccountDataProvider interface {
+	Transactions(ctx context.Context, customerID string, since time.Time) ([]BankTransaction, error)
+}
+
+// Affordability is what a customer's bank history says about their
+// capacity to repay. Amounts are monthly averages over Months.
+type Affordability struct {
+	Months        int     `json:"months"`
+	MonthlyIncome float64 `json:"monthlyIncome"`
+	// IncomeStability is 1 for the same income every month, falling
+	// towards 0 as it varies or months go without income
+	IncomeStability float64 `json:"incomeStability"`
+	MonthlyExpenses float64 `json:"monthlyExpenses"`
+	// MonthlyDebtPayments are the expenses repaying other lenders
+	MonthlyDebtPayments float64 `json:"monthlyDebtPayments"`
+	// ExpenseRatio is expenses over income, 0 without income
+	ExpenseRatio float64 `json:"expenseRatio"`
+	// DisposableIncome is income left after expenses
+	DisposableIncome float64 `json:"disposableIncome"`
+}
+
+// AnalyzeAffordability computes the affordability figures of the
+// AffordabilityMonths full calendar months before asOf. Transfers between
+// the customer's own accounts are ignored.
+func AnalyzeAffordability(txs []BankTransaction, asOf time.Time) Affordability {
+	start, end := affordabilityWindow(asOf)
+	income := make([]float64, AffordabilityMonths)
+	var expenses, debt float64
+	for _, tx := range txs {
+		at := tx.BookedAt.UTC()
+		if at.Before(start) || !at.Before(end) || tx.Category == CategoryTransfer {
+			continue
+		}
+		switch {
+		case tx.Amount > 0:
+			m := (at.Year()-start.Year())*12 + int(at.Month()-start.Month())
+			income[m] += tx.Amount
+		case tx.Category == CategoryDebt:
+			debt -= tx.Amount
+			expenses -= tx.Amount
+		default:
+			expenses -= tx.Amount
+		}
+	}
+
+	months := float64(AffordabilityMonths)
+	var total float64
+	for _, v := range income {
+		total += v
+	}
+	a := Affordability{
+		Months:              AffordabilityMonths,
+		MonthlyIncome:       round2(total / months),
+		MonthlyExpenses:     round2(expenses / months),
+		MonthlyDebtPayments: round2(debt / months),
+	}
+	a.DisposableIncome = round2(a.MonthlyIncome - a.MonthlyExpenses)
+	if total > 0 {
+		mean := total / months
+		var variance float64
+		for _, v := range income {
+			variance += (v - mean) * (v - mean)
+		}
+		cv := math.Sqrt(variance/months) / mean
+		a.IncomeStability = math.Round(math.Max(0, 1-cv)*100) / 100
+		a.ExpenseRatio = math.Round(expenses/total*100) / 100
+	}
+	return a
+}
+
+// WithAccountData makes the risk engine see the applicant's affordability
+// computed from the bank transactions p provides
+func WithAccountData(p AccountDataProvider) Option {
+	return func(s *LoanService) {
+		s.accountData = p
+	}
+}
+
+func (s *LoanService) affordability(ctx context.Context, customerID string, asOf time.Time) (_ *Affordability, err error) {
+	ctx, span := tracing.Start(ctx, "AccountDataProvider.Transactions")
+	defer tracing.End(span, &err)
+	since, _ := affordabilityWindow(asOf)
+	txs, err := retry.Value(ctx, s.retry, func(ctx context.Context) ([]BankTransaction, error) {
+		return s.accountData.Transactions(ctx, customerID, since)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("account data: %w", err)
+	}
+	a := AnalyzeAffordability(txs, asOf)
+	return &a, nil
+}
+
+// affordabilityWindow returns the start of the first and the end of the
+// last month analyzed as of asOf
+func affordabilityWindow(asOf time.Time) (start, end time.Time) {
+	asOf = asOf.UTC()
+	end = time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return end.AddDate(0, -AffordabilityMonths, 0), end
+}
@@ -0,0 +1,186 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"loan/tracing"
+)
+
+// AgingRecord is one loan of a month-end aging snapshot, the read model
+// the aging report is computed from
+type AgingRecord struct {
+	LoanID      string  `json:"loanId"`
+	Product     string  `json:"product"`
+	Bucket      Bucket  `json:"bucket"`
+	DaysPastDue int     `json:"daysPastDue"`
+	Outstanding float64 `json:"outstanding"`
+}
+
+// ErrNoAging is returned for months no aging snapshot was taken of
+var ErrNoAging = errors.New("no aging snapshot")
+
+// AgingStore keeps month-end aging snapshots keyed by the month they close
+type AgingStore interface {
+	// SaveAging replaces the snapshot of the month of month with records
+	SaveAging(ctx context.Context, month time.Time, records []AgingRecord) error
+	// Aging returns the snapshot of the month of month, ordered by loan ID.
+	// The second result is false when no snapshot was taken.
+	Aging(ctx context.Context, month time.Time) ([]AgingRecord, bool, error)
+}
+
+// SnapshotAging records the delinquency bucket and balance of every loan
+// of the book, ordered by loan ID
+func SnapshotAging(loans []*Loan) []AgingRecord {
+	out := make([]AgingRecord, 0, len(loans))
+	for _, l := range loans {
+		if l.Status != StatusApproved && l.Status != StatusDefault {
+			continue
+		}
+		out = append(out, AgingRecord{
+			LoanID: l.ID, Product: l.Product, Bucket: BucketFor(l.DaysPastDue),
+			DaysPastDue: l.DaysPastDue, Outstanding: round2(l.Balance),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LoanID < out[j].LoanID })
+	return out
+}
+
+// AgingBuckets are the delinquency buckets from current to worst, the row
+// order of the aging report
+var AgingBuckets = []Bucket{BucketCurrent, Bucket1To30, Bucket31To60, Bucket61To90, Bucket90Plus}
+
+// AgingCell is the balance of one bucket and product at the end of the
+// month and of the month before
+type AgingCell struct {
+	Bucket              Bucket  `json:"bucket"`
+	Product             string  `json:"product"`
+	Count               int     `json:"count"`
+	Outstanding         float64 `json:"outstanding"`
+	PreviousCount       int     `json:"previousCount"`
+	PreviousOutstanding float64 `json:"previousOutstanding"`
+	// Change is Outstanding less PreviousOutstanding
+	Change float64 `json:"change"`
+}
+
+// AgingReport is the outstanding balance of the book by delinquency bucket
+// and product at the end of Month, compared with the month before
+type AgingReport struct {
+	// Month and Previous are the first days of the months compared
+	Month    time.Time `json:"month"`
+	Previous time.Time `json:"previous"`
+	// HasPrevious is false when no snapshot of the previous month was
+	// taken, leaving the previous figures zero
+	HasPrevious bool `json:"hasPrevious"`
+	// Rows are ordered by bucket as in AgingBuckets, then by product
+	Rows []AgingCell `json:"rows"`
+	// ByBucket totals every product of each bucket; Product is empty
+	ByBucket []AgingCell `json:"byBucket"`
+	Total    AgingCell   `json:"total"`
+}
+
+// WithAgingStore reads the aging report from the snapshots in store
+func WithAgingStore(store AgingStore) ReportingOption {
+	return func(s *ReportingService) { s.aging = store }
+}
+
+// Aging compares the aging snapshot of the month of month with the one of
+// the month before. The current month, not closed yet, is read from the
+// live book when no snapshot was taken.
+func (s *ReportingService) Aging(ctx context.Context, month time.Time) (_ AgingReport, err error) {
+	month = MonthStart(month)
+	ctx, span := tracing.Start(ctx, "ReportingService.Aging", attribute.String("report.month", month.Format("2006-01")))
+	defer tracing.End(span, &err)
+	if s.aging == nil {
+		return AgingReport{}, fmt.Errorf("%w: no aging store configured", ErrNoAging)
+	}
+	current, ok, err := s.aging.Aging(ctx, month)
+	if err != nil {
+		return AgingReport{}, err
+	}
+	if !ok {
+		if !month.Equal(MonthStart(s.now())) {
+			return AgingReport{}, fmt.Errorf("%w for %s", ErrNoAging, month.Format("2006-01"))
+		}
+		loans, err := s.repo.List(ctx, Filter{Statuses: bookStatuses})
+		if err != nil {
+			return AgingReport{}, err
+		}
+		current = SnapshotAging(loans)
+	}
+	r := AgingReport{Month: month, Previous: month.AddDate(0, -1, 0)}
+	previous, ok, err := s.aging.Aging(ctx, r.Previous)
+	if err != nil {
+		return AgingReport{}, err
+	}
+	r.HasPrevious = ok
+	r.Rows, r.ByBucket, r.Total = agingCells(current, previous)
+	return r, nil
+}
+
+// agingCells totals the records of both months per bucket and product,
+// per bucket and in total
+func agingCells(current, previous []AgingRecord) (rows, byBucket []AgingCell, total AgingCell) {
+	type key struct {
+		bucket  Bucket
+		product string
+	}
+	cells, buckets := map[key]*AgingCell{}, map[Bucket]*AgingCell{}
+	for _, b := range AgingBuckets {
+		buckets[b] = &AgingCell{Bucket: b}
+	}
+	add := func(r AgingRecord, prev bool) {
+		k := key{r.Bucket, r.Product}
+		c := cells[k]
+		if c == nil {
+			c = &AgingCell{Bucket: r.Bucket, Product: r.Product}
+			cells[k] = c
+		}
+		b := buckets[r.Bucket]
+		if b == nil {
+			b = &AgingCell{Bucket: r.Bucket}
+			buckets[r.Bucket] = b
+		}
+		for _, c := range []*AgingCell{c, b, &total} {
+			if prev {
+				c.PreviousCount++
+				c.PreviousOutstanding += r.Outstanding
+			} else {
+				c.Count++
+				c.Outstanding += r.Outstanding
+			}
+		}
+	}
+	for _, r := range current {
+		add(r, false)
+	}
+	for _, r := range previous {
+		add(r, true)
+	}
+	finish := func(c *AgingCell) AgingCell {
+		c.Outstanding, c.PreviousOutstanding = round2(c.Outstanding), round2(c.PreviousOutstanding)
+		c.Change = round2(c.Outstanding - c.PreviousOutstanding)
+		return *c
+	}
+	rows = make([]AgingCell, 0, len(cells))
+	for _, c := range cells {
+		rows = append(rows, finish(c))
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Bucket != rows[j].Bucket {
+			return rows[i].Bucket.rank() < rows[j].Bucket.rank()
+		}
+		return rows[i].Product < rows[j].Product
+	})
+	byBucket = make([]AgingCell, 0, len(buckets))
+	for _, c := range buckets {
+		byBucket = append(byBucket, finish(c))
+	}
+	sort.Slice(byBucket, func(i, j int) bool { return byBucket[i].Bucket.rank() < byBucket[j].Bucket.rank() })
+	return rows, byBucket, finish(&total)
+}
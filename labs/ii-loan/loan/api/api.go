@@ -0,0 +1,225 @@
+// Package api exposes the loan service over JSON/HTTP.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"loan"
+	"loan/api/openapi"
+	"loan/i18n"
+	"loan/investor"
+	"loan/pool"
+	"loan/tracing"
+	"loan/webhook"
+)
+
+// maxBodyBytes bounds request bodies
+const maxBodyBytes = 1 << 20
+
+// Handler serves the loan REST API
+type Handler struct {
+	svc     *loan.LoanService
+	mux     *http.ServeMux
+	sunset  time.Time
+	idem    IdempotencyStore
+	idemTTL time.Duration
+	cursors cursorSigner
+
+	principals PrincipalResolver
+	roles      RoleResolver
+	visibility Visibility
+	investors  *investor.Book
+	pools      *pool.Service
+	products   loan.ProductRepository
+	customers  *loan.CustomerService
+	webhooks   *webhook.WebhookService
+	catalog    *i18n.Catalog
+	events     *loan.EventBus
+}
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithV1Sunset announces the date v1 and the unversioned paths will be
+// retired, sent in the Sunset header of their responses
+func WithV1Sunset(t time.Time) Option {
+	return func(h *Handler) { h.sunset = t }
+}
+
+// NewHandler creates the API handler for svc. Every version is mounted
+// under its own prefix; the unversioned paths that predate versioning keep
+// serving v1, and both are marked deprecated in favour of v2.
+func NewHandler(svc *loan.LoanService, opts ...Option) *Handler {
+	h := &Handler{svc: svc, mux: http.NewServeMux(), idem: NewMemoryIdempotencyStore(), idemTTL: DefaultIdempotencyTTL, cursors: newCursorSigner()}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	old, latest := v1(), v2()
+	old.deprecated = true
+	successor := func(p string) string { return latest.path(strings.TrimPrefix(p, old.path(""))) }
+	var current *openapi.Document
+	for _, v := range []*version{old, latest} {
+		spec := openapi.NewBuilder("Loan API", v.name)
+		spec.Server(v.path(""))
+		for _, rt := range h.routes(v) {
+			rt.Deprecated = v.deprecated
+			var handler http.Handler = rt.handler
+			if rt.Method == http.MethodPost {
+				handler = h.idempotent(v, handler)
+				rt.Headers = append(rt.Headers, openapi.Parameter{
+					Name: IdempotencyHeader, In: "header", Schema: &openapi.Schema{Type: "string"},
+					Description: "makes retries of this request return the first response",
+				})
+				rt.Responses[http.StatusConflict] = ErrorBody{}
+			}
+			if !rt.streams() {
+				// streams shape each event themselves
+				handler = h.localize(h.redact(handler))
+			}
+			if h.catalog != nil {
+				rt.Headers = append(rt.Headers, openapi.Parameter{
+					Name: "Accept-Language", In: "header", Schema: &openapi.Schema{Type: "string"},
+					Description: "locale of the statusText and rejectionReasonText fields, one of " + strings.Join(h.catalog.Locales(), ", "),
+				})
+			}
+			if v.deprecated {
+				handler = deprecate(handler, successor, h.sunset)
+				h.handle(rt.Method+" "+rt.Path, handler)
+			}
+			h.handle(rt.Method+" "+v.path(rt.Path), handler)
+			spec.Add(rt.Operation)
+		}
+		doc := spec.Document()
+		h.mux.HandleFunc("GET "+v.path("/openapi.json"), serveSpec(doc))
+		h.mux.HandleFunc("GET "+v.path("/docs"), serveDocs)
+		current = doc
+	}
+	h.mux.HandleFunc("GET /openapi.json", serveSpec(current))
+	h.mux.HandleFunc("GET /docs", serveDocs)
+	return h
+}
+
+// handle registers a route traced under its pattern
+func (h *Handler) handle(pattern string, handler http.Handler) {
+	h.mux.Handle(pattern, tracing.Handler(pattern, handler))
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// ErrorBody is the JSON error envelope returned by every endpoint
+type ErrorBody struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes a failed request
+type ErrorDetail struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// requestError is an error with a ready HTTP status and body
+type requestError struct {
+	status int
+	detail ErrorDetail
+}
+
+func (e *requestError) Error() string { return e.detail.Message }
+
+func badRequest(code, format string, args ...any) error {
+	return &requestError{status: http.StatusBadRequest, detail: ErrorDetail{Code: code, Message: fmt.Sprintf(format, args...)}}
+}
+
+func invalidFields(fields map[string]string) error {
+	return &requestError{status: http.StatusUnprocessableEntity, detail: ErrorDetail{
+		Code: "validation_failed", Message: "request validation failed", Fields: fields,
+	}}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError maps service and request errors onto HTTP responses
+func writeError(w http.ResponseWriter, err error) {
+	status, detail := errorResponse(err)
+	writeJSON(w, status, ErrorBody{Error: detail})
+}
+
+// errorResponse returns the HTTP status and error detail for err
+func errorResponse(err error) (int, ErrorDetail) {
+	var reqErr *requestError
+	var valErr *loan.ValidationError
+	switch {
+	case errors.As(err, &reqErr):
+		return reqErr.status, reqErr.detail
+	case errors.As(err, &valErr):
+		return http.StatusUnprocessableEntity, ErrorDetail{
+			Code: "validation_failed", Message: valErr.Message, Fields: map[string]string{valErr.Field: valErr.Message},
+		}
+	case errors.Is(err, loan.ErrLoanNotFound), errors.Is(err, loan.ErrMandateNotFound), errors.Is(err, loan.ErrNoDecision),
+		errors.Is(err, loan.ErrTransferNotFound), errors.Is(err, loan.ErrProductNotFound), errors.Is(err, loan.ErrNoEscrow),
+		errors.Is(err, loan.ErrNotInsured), errors.Is(err, loan.ErrCustomerNotFound):
+		return http.StatusNotFound, ErrorDetail{Code: "not_found", Message: err.Error()}
+	case errors.Is(err, loan.ErrInvalidTransition):
+		return http.StatusConflict, ErrorDetail{Code: "invalid_state", Message: err.Error()}
+	case errors.Is(err, loan.ErrConflict):
+		return http.StatusConflict, ErrorDetail{Code: "conflict", Message: err.Error()}
+	case errors.Is(err, loan.ErrActiveLoans):
+		return http.StatusConflict, ErrorDetail{Code: "active_loans", Message: err.Error()}
+	case errors.Is(err, loan.ErrScreeningHold):
+		return http.StatusConflict, ErrorDetail{Code: "screening_hold", Message: err.Error()}
+	case errors.Is(err, loan.ErrNotScreened):
+		return http.StatusConflict, ErrorDetail{Code: "no_matches", Message: err.Error()}
+	case errors.Is(err, loan.ErrSameApprover):
+		return http.StatusForbidden, ErrorDetail{Code: "same_approver", Message: err.Error()}
+	case errors.Is(err, loan.ErrPaymentDeclined):
+		return http.StatusUnprocessableEntity, ErrorDetail{Code: "payment_declined", Message: err.Error()}
+	case errors.Is(err, loan.ErrAccountNotVerified):
+		return http.StatusUnprocessableEntity, ErrorDetail{Code: "account_not_verified", Message: err.Error()}
+	case errors.Is(err, loan.ErrNoPaymentProvider), errors.Is(err, loan.ErrDirectDebitDisabled),
+		errors.Is(err, loan.ErrTransfersDisabled):
+		return http.StatusNotImplemented, ErrorDetail{Code: "not_configured", Message: err.Error()}
+	}
+	return http.StatusInternalServerError, ErrorDetail{Code: "internal", Message: "internal server error"}
+}
+
+// decodeJSON strictly decodes a single JSON object from the request body
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || mediaType != "application/json" {
+			return &requestError{status: http.StatusUnsupportedMediaType, detail: ErrorDetail{
+				Code: "unsupported_media_type", Message: "Content-Type must be application/json",
+			}}
+		}
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return &requestError{status: http.StatusRequestEntityTooLarge, detail: ErrorDetail{
+				Code: "body_too_large", Message: fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit),
+			}}
+		}
+		return badRequest("malformed_json", "malformed JSON body: %v", err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return badRequest("malformed_json", "request body must contain a single JSON object")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// MaxBatchApprove is the most loans one batch approval may name
+const MaxBatchApprove = 100
+
+// batchParallelism bounds how many approvals of a batch run at once, so a
+// large batch cannot monopolise the repository
+const batchParallelism = 8
+
+// BatchApproveRequest is the body of POST /loans:batchApprove
+type BatchApproveRequest struct {
+	LoanIDs []string `json:"loanIds"`
+}
+
+// Batch outcomes
+const (
+	BatchApproved = "approved"
+	BatchFailed   = "failed"
+	BatchPartial  = "partial"
+)
+
+// BatchItem is the outcome of approving one loan of a batch
+type BatchItem struct {
+	LoanID string       `json:"loanId"`
+	Status string       `json:"status"`
+	Error  *ErrorDetail `json:"error,omitempty"`
+}
+
+// BatchApproveResponse is returned by POST /loans:batchApprove. Status is
+// approved when every loan was approved, failed when none was and partial
+// otherwise.
+type BatchApproveResponse struct {
+	Status   string      `json:"status"`
+	Approved int         `json:"approved"`
+	Failed   int         `json:"failed"`
+	Results  []BatchItem `json:"results"`
+}
+
+func (req BatchApproveRequest) validate() map[string]string {
+	switch {
+	case len(req.LoanIDs) == 0:
+		return map[string]string{"loanIds": "is required"}
+	case len(req.LoanIDs) > MaxBatchApprove:
+		return map[string]string{"loanIds": fmt.Sprintf("must name at most %d loans", MaxBatchApprove)}
+	}
+	seen := make(map[string]bool, len(req.LoanIDs))
+	for i, id := range req.LoanIDs {
+		field := fmt.Sprintf("loanIds[%d]", i)
+		switch id = strings.TrimSpace(id); {
+		case id == "":
+			return map[string]string{field: "must not be empty"}
+		case seen[id]:
+			return map[string]string{field: fmt.Sprintf("duplicates loan %s", id)}
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// batchApprove approves every named loan and reports each outcome; one
+// failing loan does not stop the others. It is the same in every API
+// version since it only carries loan IDs.
+func (h *Handler) batchApprove(w http.ResponseWriter, r *http.Request) {
+	var req BatchApproveRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if fields := req.validate(); fields != nil {
+		writeError(w, invalidFields(fields))
+		return
+	}
+	writeJSON(w, http.StatusOK, h.approveAll(r.Context(), req.LoanIDs))
+}
+
+func (h *Handler) approveAll(ctx context.Context, ids []string) BatchApproveResponse {
+	results := make([]BatchItem, len(ids))
+	sem := make(chan struct{}, batchParallelism)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		id = strings.TrimSpace(id)
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = BatchItem{LoanID: id, Status: BatchApproved}
+			if _, err := h.svc.ApproveLoan(ctx, id); err != nil {
+				_, detail := errorResponse(err)
+				results[i].Status, results[i].Error = BatchFailed, &detail
+			}
+		}(i, id)
+	}
+	wg.Wait()
+
+	resp := BatchApproveResponse{Results: results}
+	for _, it := range results {
+		if it.Status == BatchApproved {
+			resp.Approved++
+		} else {
+			resp.Failed++
+		}
+	}
+	switch {
+	case resp.Failed == 0:
+		resp.Status = BatchApproved
+	case resp.Approved == 0:
+		resp.Status = BatchFailed
+	default:
+		resp.Status = BatchPartial
+	}
+	return resp
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"loan"
+)
+
+// Page sizes of GET /loans
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// WithCursorKey signs pagination cursors with key. Instances behind one
+// load balancer must share it; without it each handler signs with a
+// random key, and cursors do not survive a restart.
+func WithCursorKey(key []byte) Option {
+	return func(h *Handler) { h.cursors = cursorSigner{key: key} }
+}
+
+// cursorSigner issues the opaque cursors of paginated listings. A cursor
+// holds the position of the last loan of a page, so the next page starts
+// after it however many loans were stored meanwhile, and the filter it was
+// issued for, so it cannot page through another listing. It is signed so
+// clients cannot forge positions.
+type cursorSigner struct {
+	key []byte
+}
+
+func newCursorSigner() cursorSigner {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return cursorSigner{key: key}
+}
+
+// cursorBody is the signed content of a cursor
+type cursorBody struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"i"`
+	Filter    string    `json:"f"`
+}
+
+var errInvalidCursor = badRequest("invalid_cursor", "the cursor is malformed or from another listing")
+
+// filterDigest identifies the listing f selects, whatever page it is on
+func filterDigest(f loan.Filter) string {
+	f.After, f.Limit = loan.Position{}, 0
+	b, _ := json.Marshal(f)
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
+func (s cursorSigner) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// encode returns the cursor of the page after pos in the listing of f
+func (s cursorSigner) encode(pos loan.Position, f loan.Filter) string {
+	b, _ := json.Marshal(cursorBody{CreatedAt: pos.CreatedAt, ID: pos.ID, Filter: filterDigest(f)})
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + s.sign(payload)
+}
+
+// decode checks a cursor issued for the listing of f and returns its
+// position
+func (s cursorSigner) decode(cursor string, f loan.Filter) (loan.Position, error) {
+	payload, sig, ok := strings.Cut(cursor, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(payload))) {
+		return loan.Position{}, errInvalidCursor
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return loan.Position{}, errInvalidCursor
+	}
+	var body cursorBody
+	if err := json.Unmarshal(b, &body); err != nil || body.Filter != filterDigest(f) {
+		return loan.Position{}, errInvalidCursor
+	}
+	return loan.Position{CreatedAt: body.CreatedAt, ID: body.ID}, nil
+}
+
+// pageSize parses the limit parameter
+func pageSize(s string) (int, error) {
+	if s == "" {
+		return DefaultPageSize, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > MaxPageSize {
+		return 0, badRequest("invalid_query", "limit must be between 1 and %d", MaxPageSize)
+	}
+	return n, nil
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"loan"
+)
+
+// WithCustomers serves the customer endpoints from svc. Without it they
+// answer 501.
+func WithCustomers(svc *loan.CustomerService) Option {
+	return func(h *Handler) { h.customers = svc }
+}
+
+var errNoCustomers = &requestError{status: http.StatusNotImplemented, detail: ErrorDetail{
+	Code: "not_configured", Message: "customer management is not configured",
+}}
+
+func (h *Handler) anonymizeCustomer(w http.ResponseWriter, r *http.Request) {
+	if h.customers == nil {
+		writeError(w, errNoCustomers)
+		return
+	}
+	a, err := h.customers.Anonymize(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
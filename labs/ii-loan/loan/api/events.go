@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"loan"
+	"loan/cloudevents"
+)
+
+// eventBuffer is how many events a stream may fall behind before it is
+// ended; clients reconnect and read the loan to catch up
+const eventBuffer = 64
+
+// keepAlive is how often an idle stream is written to, so proxies and
+// clients do not take it for dead
+const keepAlive = 15 * time.Second
+
+// WithEventStream serves GET /loans/{id}/events, streaming the loan's
+// domain events from bus as they are published, as server-sent events or
+// over a WebSocket. bus must also be the service's event publisher.
+func WithEventStream(bus *loan.EventBus) Option {
+	return func(h *Handler) { h.events = bus }
+}
+
+// loanEvents streams the events of a loan, each a CloudEvents envelope
+// with the data in the version's representation. Streams start with the
+// events published after they open; nothing is replayed.
+func (h *Handler) loanEvents(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.events == nil {
+			writeError(w, &requestError{status: http.StatusNotImplemented, detail: ErrorDetail{
+				Code: "not_configured", Message: "event streaming is not configured",
+			}})
+			return
+		}
+		id := r.PathValue("id")
+		// subscribe before reading the loan so no event falls in between
+		sub := h.events.Subscribe(id, eventBuffer)
+		defer sub.Close()
+		if _, err := h.svc.GetLoan(r.Context(), id); err != nil {
+			writeError(w, err)
+			return
+		}
+		encode := h.eventEncoder(v, r)
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			streamWebSocket(w, r, sub, encode)
+			return
+		}
+		streamSSE(w, r, sub, encode)
+	}
+}
+
+// eventEncoder returns the encoding of events for the caller: redacted by
+// its role and localized like the other responses
+func (h *Handler) eventEncoder(v *version, r *http.Request) func(loan.Event) ([]byte, error) {
+	var hidden map[string]bool
+	if h.roles != nil {
+		hidden = h.visibility.hidden(h.roles(r))
+	}
+	var locale string
+	if h.catalog != nil {
+		locale = h.catalog.Negotiate(r.Header.Get("Accept-Language"))
+	}
+	return func(e loan.Event) ([]byte, error) {
+		switch data := e.Data.(type) {
+		case *loan.Loan:
+			e.Data = v.loan(data)
+		case loan.Payment:
+			e.Data = v.payment(data)
+		}
+		env, err := cloudevents.New(v.path("/loans/"+e.LoanID), e)
+		if err != nil {
+			return nil, err
+		}
+		body, err := json.Marshal(env)
+		if err != nil || (len(hidden) == 0 && h.catalog == nil) {
+			return body, err
+		}
+		if len(hidden) > 0 {
+			if body, err = stripFields(body, hidden); err != nil {
+				return nil, err
+			}
+		}
+		if h.catalog != nil {
+			if body, err = h.annotate(body, locale); err != nil {
+				return nil, err
+			}
+		}
+		return bytes.TrimSpace(body), nil
+	}
+}
+
+// streamSSE writes the events as a text/event-stream until the client
+// goes away or the subscription ends
+func streamSSE(w http.ResponseWriter, r *http.Request, sub *loan.Subscription, encode func(loan.Event) ([]byte, error)) {
+	rc := http.NewResponseController(w)
+	// the server's timeouts are for requests, not for streams
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	tick := time.NewTicker(keepAlive)
+	defer tick.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-tick.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case e, ok := <-sub.Events():
+			if !ok {
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", sub.Err())
+				rc.Flush()
+				return
+			}
+			body, err := encode(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, body)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// streamWebSocket upgrades the request and sends each event as a text
+// message until either side closes the connection
+func streamWebSocket(w http.ResponseWriter, r *http.Request, sub *loan.Subscription, encode func(loan.Event) ([]byte, error)) {
+	conn := acceptWebSocket(w, r)
+	if conn == nil {
+		return
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		// reading answers pings and notices the client closing
+		conn.readUntilClose()
+		cancel()
+	}()
+	tick := time.NewTicker(keepAlive)
+	defer tick.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			conn.close(wsCloseNormal, "")
+			return
+		case <-tick.C:
+			err = conn.write(wsPing, nil)
+		case e, ok := <-sub.Events():
+			if !ok {
+				conn.close(wsCloseTryAgain, sub.Err().Error())
+				return
+			}
+			body, encErr := encode(e)
+			if encErr != nil {
+				continue
+			}
+			err = conn.write(wsText, body)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
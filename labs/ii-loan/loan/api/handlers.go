@@ -0,0 +1,554 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"loan"
+	"loan/bulkimport"
+	"loan/filterexpr"
+	"loan/pdf"
+)
+
+// ApplicationRequest is the body of POST /applications
+type ApplicationRequest struct {
+	CustomerID   string  `json:"customerId"`
+	Amount       float64 `json:"amount"`
+	InterestRate float64 `json:"interestRate"`
+	TermMonths   int     `json:"termMonths"`
+	Product      string  `json:"product,omitempty"`
+	// Purpose is one of loan.Purposes; products may require one
+	Purpose string `json:"purpose,omitempty"`
+	// Frequency is one of loan.Frequencies, monthly when empty
+	Frequency string `json:"frequency,omitempty"`
+}
+
+// Validate returns per-field problems, or nil when the request is valid
+func (a ApplicationRequest) Validate() map[string]string {
+	fields := map[string]string{}
+	if strings.TrimSpace(a.CustomerID) == "" {
+		fields["customerId"] = "is required"
+	}
+	if a.Amount <= 0 || math.IsInf(a.Amount, 0) {
+		fields["amount"] = "must be a positive number"
+	}
+	if a.InterestRate < 0 || a.InterestRate > 1 {
+		fields["interestRate"] = "must be an annual rate between 0 and 1"
+	}
+	if a.TermMonths < 1 || a.TermMonths > 360 {
+		fields["termMonths"] = "must be between 1 and 360"
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// RejectRequest is the body of POST /loans/{id}/reject
+type RejectRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ClearScreeningRequest is the body of POST /loans/{id}/screening/clear
+type ClearScreeningRequest struct {
+	ClearedBy string `json:"clearedBy"`
+	// Note says why the matches are false positives
+	Note string `json:"note"`
+}
+
+// TermsRequest is the body of POST /loans/{id}/terms
+type TermsRequest struct {
+	// InterestRate is the new annual rate, 0 to keep the current one
+	InterestRate float64 `json:"interestRate,omitempty"`
+	// Installments is the new number of installments left, 0 to keep it
+	Installments int    `json:"installments,omitempty"`
+	Reason       string `json:"reason"`
+	ChangedBy    string `json:"changedBy"`
+}
+
+// EscrowRequest is the body of POST /loans/{id}/escrow
+type EscrowRequest struct {
+	// Items are the bills paid from escrow, such as the insurance premium
+	// and property tax of the collateral
+	Items []loan.EscrowItem `json:"items"`
+}
+
+// InsuranceRequest is the body of POST /loans/{id}/insurance
+type InsuranceRequest struct {
+	// Plan is the code of an insurance plan the loan's product offers
+	Plan string `json:"plan"`
+}
+
+// ClaimRequest is the body of POST /loans/{id}/insurance/claims
+type ClaimRequest struct {
+	// Event is one of loan.InsuredEvents covered by the loan's plan
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+// DisburseRequest is the body of POST /loans/{id}/disburse
+type DisburseRequest struct {
+	Account string `json:"account"`
+	// AccountName is the customer's name as the bank should hold it
+	AccountName string `json:"accountName"`
+}
+
+// MandateRequest is the body of POST /loans/{id}/mandate
+type MandateRequest struct {
+	Account string `json:"account"`
+	// RetryDays default to loan.DefaultRetryDays when omitted
+	RetryDays []int `json:"retryDays,omitempty"`
+}
+
+// PaymentRequest is the body of POST /loans/{id}/payments
+type PaymentRequest struct {
+	Amount float64 `json:"amount"`
+}
+
+// ScheduleResponse is returned by GET /loans/{id}/schedule
+type ScheduleResponse struct {
+	LoanID string `json:"loanId"`
+	// Amortization is the loan's method, one of loan.Amortizations
+	Amortization string `json:"amortization"`
+	// TotalInterest is the interest charged over the installments
+	TotalInterest float64            `json:"totalInterest"`
+	Installments  []loan.Installment `json:"installments"`
+}
+
+// PaymentsResponse is returned by GET /loans/{id}/payments
+type PaymentsResponse struct {
+	LoanID   string         `json:"loanId"`
+	Payments []loan.Payment `json:"payments"`
+}
+
+// ListResponse is returned by GET /loans
+type ListResponse struct {
+	Loans []*loan.Loan `json:"loans"`
+	// NextCursor fetches the next page as the cursor parameter; it is
+	// absent on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+func (h *Handler) submitApplication(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := v.decodeApplication(w, r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if fields := req.Validate(); fields != nil {
+			writeError(w, invalidFields(fields))
+			return
+		}
+		l := &loan.Loan{
+			CustomerID:   strings.TrimSpace(req.CustomerID),
+			Amount:       req.Amount,
+			InterestRate: req.InterestRate,
+			TermMonths:   req.TermMonths,
+			Product:      strings.TrimSpace(req.Product),
+			Purpose:      strings.TrimSpace(req.Purpose),
+			Frequency:    strings.TrimSpace(req.Frequency),
+		}
+		if err := h.svc.ProcessLoanApplication(r.Context(), l); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Location", v.path("/applications/"+l.ID))
+		writeJSON(w, http.StatusCreated, v.loan(l))
+	}
+}
+
+// maxImportBytes bounds the size of a bulk application file
+const maxImportBytes = 10 << 20
+
+// importApplications submits every row of a CSV file and answers with the
+// per-row report, even when some rows failed. It is the same in every API
+// version since the file format is not versioned.
+func (h *Handler) importApplications(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "text/csv" {
+		writeError(w, &requestError{status: http.StatusUnsupportedMediaType, detail: ErrorDetail{
+			Code: "unsupported_media_type", Message: "Content-Type must be text/csv",
+		}})
+		return
+	}
+	report, err := bulkimport.New(h.svc).Import(r.Context(), http.MaxBytesReader(w, r.Body, maxImportBytes))
+	var headerErr *bulkimport.HeaderError
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &headerErr):
+		writeError(w, badRequest("invalid_file", "%s", headerErr.Message))
+	case errors.As(err, &maxErr):
+		writeError(w, &requestError{status: http.StatusRequestEntityTooLarge, detail: ErrorDetail{
+			Code: "body_too_large", Message: fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit),
+		}})
+	case err != nil:
+		writeError(w, err)
+	default:
+		writeJSON(w, http.StatusOK, report)
+	}
+}
+
+func (h *Handler) getLoan(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l, err := h.svc.GetLoan(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v.loan(l))
+	}
+}
+
+func (h *Handler) getDecision(w http.ResponseWriter, r *http.Request) {
+	d, err := h.svc.GetDecision(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (h *Handler) customerExposure(w http.ResponseWriter, r *http.Request) {
+	e, err := h.svc.CustomerExposure(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (h *Handler) listLoans(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		filter := loan.Filter{CustomerID: q.Get("customerId")}
+		for _, s := range q["status"] {
+			switch s {
+			case loan.StatusPending, loan.StatusApproved, loan.StatusRejected, loan.StatusDefault:
+				filter.Statuses = append(filter.Statuses, s)
+			default:
+				writeError(w, badRequest("invalid_query", "unknown status %q", s))
+				return
+			}
+		}
+		conds, err := filterexpr.Parse(q.Get("filter"))
+		if err != nil {
+			writeError(w, badRequest("invalid_filter", "%s", err))
+			return
+		}
+		filter.Conditions = conds
+		limit, err := pageSize(q.Get("limit"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if c := q.Get("cursor"); c != "" {
+			if filter.After, err = h.cursors.decode(c, filter); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+		// one more than the page tells whether there is a next one
+		filter.Limit = limit + 1
+		loans, err := h.svc.ListLoans(r.Context(), filter)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var next string
+		if len(loans) > limit {
+			loans = loans[:limit]
+			next = h.cursors.encode(loan.PositionOf(loans[limit-1]), filter)
+		}
+		writeJSON(w, http.StatusOK, v.list(loans, next))
+	}
+}
+
+func (h *Handler) approveLoan(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l, err := h.svc.ApproveLoan(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v.loan(l))
+	}
+}
+
+func (h *Handler) rejectLoan(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RejectRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			writeError(w, err)
+			return
+		}
+		if strings.TrimSpace(req.Reason) == "" {
+			writeError(w, invalidFields(map[string]string{"reason": "is required"}))
+			return
+		}
+		l, err := h.svc.RejectLoan(r.Context(), r.PathValue("id"), req.Reason)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v.loan(l))
+	}
+}
+
+func (h *Handler) clearScreening(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ClearScreeningRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			writeError(w, err)
+			return
+		}
+		l, err := h.svc.ClearScreening(r.Context(), r.PathValue("id"), req.ClearedBy, req.Note)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v.loan(l))
+	}
+}
+
+func (h *Handler) changeTerms(w http.ResponseWriter, r *http.Request) {
+	var req TermsRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	change, err := h.svc.ChangeTerms(r.Context(), r.PathValue("id"), loan.TermsChangeRequest{
+		InterestRate: req.InterestRate, Installments: req.Installments, Reason: req.Reason, ChangedBy: req.ChangedBy,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, change)
+}
+
+func (h *Handler) setUpEscrow(w http.ResponseWriter, r *http.Request) {
+	var req EscrowRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	e, err := h.svc.SetUpEscrow(r.Context(), r.PathValue("id"), req.Items)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+func (h *Handler) getEscrow(w http.ResponseWriter, r *http.Request) {
+	e, err := h.svc.GetEscrow(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (h *Handler) analyzeEscrow(w http.ResponseWriter, r *http.Request) {
+	a, err := h.svc.AnalyzeEscrow(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+func (h *Handler) attachInsurance(w http.ResponseWriter, r *http.Request) {
+	var req InsuranceRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	ins, err := h.svc.AttachInsurance(r.Context(), r.PathValue("id"), req.Plan)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ins)
+}
+
+func (h *Handler) claimInsurance(w http.ResponseWriter, r *http.Request) {
+	var req ClaimRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	claim, err := h.svc.ClaimInsurance(r.Context(), r.PathValue("id"), req.Event, req.OccurredAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, claim)
+}
+
+func (h *Handler) disburseLoan(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req DisburseRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			writeError(w, err)
+			return
+		}
+		fields := map[string]string{}
+		if strings.TrimSpace(req.Account) == "" {
+			fields["account"] = "is required"
+		}
+		if strings.TrimSpace(req.AccountName) == "" {
+			fields["accountName"] = "is required"
+		}
+		if len(fields) > 0 {
+			writeError(w, invalidFields(fields))
+			return
+		}
+		payee := loan.Payee{Account: strings.TrimSpace(req.Account), Name: strings.TrimSpace(req.AccountName)}
+		l, err := h.svc.Disburse(r.Context(), r.PathValue("id"), payee)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v.loan(l))
+	}
+}
+
+// createMandate, getMandate and cancelMandate are the same in every API
+// version; mandates carry no amounts
+func (h *Handler) createMandate(w http.ResponseWriter, r *http.Request) {
+	var req MandateRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	m, err := h.svc.CreateMandate(r.Context(), r.PathValue("id"), strings.TrimSpace(req.Account), req.RetryDays)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, m)
+}
+
+func (h *Handler) getMandate(w http.ResponseWriter, r *http.Request) {
+	m, err := h.svc.GetMandate(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (h *Handler) cancelMandate(w http.ResponseWriter, r *http.Request) {
+	m, err := h.svc.CancelMandate(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (h *Handler) getSchedule(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l, err := h.svc.GetLoan(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v.schedule(l))
+	}
+}
+
+// getStatement renders the statement for ?month=YYYY-MM, by default the
+// current month. It is the same document in every API version.
+func (h *Handler) getStatement(w http.ResponseWriter, r *http.Request) {
+	month := time.Now()
+	if m := r.URL.Query().Get("month"); m != "" {
+		var err error
+		if month, err = time.Parse("2006-01", m); err != nil {
+			writeError(w, badRequest("invalid_query", "month must be formatted as YYYY-MM"))
+			return
+		}
+	}
+	st, err := h.svc.Statement(r.Context(), r.PathValue("id"), month)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var body bytes.Buffer
+	if err := pdf.Statement(&body, st); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"statement-%s-%s.pdf\"", st.LoanID, st.Period.Format("2006-01")))
+	w.Write(body.Bytes())
+}
+
+func (h *Handler) listPayments(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l, err := h.svc.GetLoan(r.Context(), r.PathValue("id"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v.payments(l))
+	}
+}
+
+func (h *Handler) getPayoffQuote(w http.ResponseWriter, r *http.Request) {
+	q, err := h.svc.PayoffQuote(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, q)
+}
+
+func (h *Handler) settleLoan(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := v.decodePayment(w, r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if req.Amount <= 0 {
+			writeError(w, invalidFields(map[string]string{"amount": "must be a positive number"}))
+			return
+		}
+		p, err := h.svc.SettleLoan(r.Context(), r.PathValue("id"), req.Amount)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Location", v.path("/loans/"+p.LoanID+"/payments"))
+		writeJSON(w, http.StatusCreated, v.payment(p))
+	}
+}
+
+func (h *Handler) recordPayment(v *version) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := v.decodePayment(w, r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if req.Amount <= 0 {
+			writeError(w, invalidFields(map[string]string{"amount": "must be a positive number"}))
+			return
+		}
+		p, err := h.svc.RecordPayment(r.Context(), r.PathValue("id"), req.Amount)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Location", v.path("/loans/"+p.LoanID+"/payments"))
+		writeJSON(w, http.StatusCreated, v.payment(p))
+	}
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"loan/logging"
+)
+
+// IdempotencyHeader carries the client-chosen key of a write request
+const IdempotencyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyTTL is how long a stored response is replayed
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKey bounds the key length
+const maxIdempotencyKey = 255
+
+// idempotencySweepEvery is how often MemoryIdempotencyStore drops the
+// expired keys that were never used again
+const idempotencySweepEvery = time.Minute
+
+// ErrRequestInFlight is returned by an IdempotencyStore when the key is
+// reserved by a request that has not completed yet
+var ErrRequestInFlight = errors.New("request with this idempotency key is in progress")
+
+// StoredResponse is a response recorded for replay
+type StoredResponse struct {
+	Status      int
+	Header      http.Header
+	Body        []byte
+	Fingerprint string // hash of the request body that produced it
+}
+
+// IdempotencyStore keeps the first response for each idempotency key.
+// Implementations shared by several API instances make retries safe across
+// the whole fleet; MemoryIdempotencyStore only covers one process.
+type IdempotencyStore interface {
+	// Reserve claims key for a new request. When the key already completed
+	// it returns the stored response instead; when another request holds
+	// it, ErrRequestInFlight.
+	Reserve(ctx context.Context, key string, ttl time.Duration) (*StoredResponse, error)
+	// Complete stores the response for a reserved key
+	Complete(ctx context.Context, key string, resp StoredResponse) error
+	// Release drops a reservation so the request can be retried
+	Release(ctx context.Context, key string) error
+}
+
+type idempotencyEntry struct {
+	resp    *StoredResponse // nil while in flight
+	expires time.Time
+}
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore. A key expires
+// when it is next used, and the keys never used again are swept at most
+// once every idempotencySweepEvery.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	now       func() time.Time
+	nextSweep time.Time
+}
+
+// NewMemoryIdempotencyStore creates an empty store
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: map[string]*idempotencyEntry{}, now: time.Now}
+}
+
+// Reserve implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Reserve(_ context.Context, key string, ttl time.Duration) (*StoredResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.After(s.nextSweep) {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(idempotencySweepEvery)
+	}
+	if e, ok := s.entries[key]; ok && !now.After(e.expires) {
+		if e.resp == nil {
+			return nil, ErrRequestInFlight
+		}
+		return e.resp, nil
+	}
+	s.entries[key] = &idempotencyEntry{expires: now.Add(ttl)}
+	return nil, nil
+}
+
+// Complete implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Complete(_ context.Context, key string, resp StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		e.resp = &resp
+	}
+	return nil
+}
+
+// Release implements IdempotencyStore
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// WithIdempotencyStore replaces the in-process store, e.g. with one shared
+// between instances
+func WithIdempotencyStore(s IdempotencyStore) Option {
+	return func(h *Handler) { h.idem = s }
+}
+
+// WithIdempotencyTTL sets how long responses are replayed
+func WithIdempotencyTTL(d time.Duration) Option {
+	return func(h *Handler) { h.idemTTL = d }
+}
+
+// PrincipalResolver returns who made an authenticated request
+type PrincipalResolver func(r *http.Request) string
+
+// PrincipalFromHeader resolves principals from a header set by the
+// authenticating gateway in front of the API. The header must not be
+// reachable by clients directly.
+func PrincipalFromHeader(name string) PrincipalResolver {
+	return func(r *http.Request) string { return r.Header.Get(name) }
+}
+
+// WithPrincipals scopes idempotency keys to the caller, so one client's
+// key never replays another's response. Keys are always scoped to the
+// tenant.
+func WithPrincipals(resolve PrincipalResolver) Option {
+	return func(h *Handler) { h.principals = resolve }
+}
+
+// idempotencyScope is what key is unique within: the tenant and principal
+// making the request and the operation it is for
+func (h *Handler) idempotencyScope(v *version, r *http.Request, key string) string {
+	var principal string
+	if h.principals != nil {
+		principal = h.principals(r)
+	}
+	// v1 is served both with and without its prefix; both share keys
+	path := strings.TrimPrefix(r.URL.Path, v.path(""))
+	return strings.Join([]string{strconv.Quote(logging.Tenant(r.Context())), strconv.Quote(principal), v.name, r.Method, path, key}, " ")
+}
+
+// recorder passes a response through while keeping a copy of it
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// idempotent makes a write endpoint safe to retry. Requests without an
+// Idempotency-Key run as before. The first request with a key in its scope
+// runs and its response is stored; retries with the same key and body
+// within the TTL get that response replayed without touching the service
+// again. Server errors are not stored so the client can retry them.
+func (h *Handler) idempotent(v *version, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			writeError(w, badRequest("invalid_idempotency_key", "%s must be at most %d characters", IdempotencyHeader, maxIdempotencyKey))
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+		if err != nil {
+			writeError(w, badRequest("malformed_body", "reading request body: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		sum := sha256.Sum256(body)
+		fingerprint := hex.EncodeToString(sum[:])
+
+		scope := h.idempotencyScope(v, r, key)
+		ctx := r.Context()
+		stored, err := h.idem.Reserve(ctx, scope, h.idemTTL)
+		switch {
+		case errors.Is(err, ErrRequestInFlight):
+			writeJSON(w, http.StatusConflict, ErrorBody{Error: ErrorDetail{Code: "request_in_progress", Message: err.Error()}})
+			return
+		case err != nil:
+			writeError(w, err)
+			return
+		case stored != nil:
+			if stored.Fingerprint != fingerprint {
+				writeError(w, invalidFields(map[string]string{
+					IdempotencyHeader: "was already used with a different request body",
+				}))
+				return
+			}
+			for k, vs := range stored.Header {
+				w.Header()[k] = vs
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w}
+		defer func() {
+			// a panicking or failed request must not leave the key stuck
+			if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+				h.idem.Release(context.WithoutCancel(ctx), scope)
+				return
+			}
+			h.idem.Complete(context.WithoutCancel(ctx), scope, StoredResponse{
+				Status: rec.status, Header: w.Header().Clone(), Body: rec.body.Bytes(), Fingerprint: fingerprint,
+			})
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"loan/investor"
+)
+
+// WithInvestors serves peer-to-peer funding from book. Without it the
+// investor endpoints answer 501.
+func WithInvestors(book *investor.Book) Option {
+	return func(h *Handler) { h.investors = book }
+}
+
+// FundRequest is the body of POST /loans/{id}/investors
+type FundRequest struct {
+	InvestorID string  `json:"investorId"`
+	Amount     float64 `json:"amount"`
+}
+
+// SharesResponse is returned by GET /loans/{id}/investors
+type SharesResponse struct {
+	Shares []investor.Share `json:"shares"`
+}
+
+var errNoInvestors = &requestError{status: http.StatusNotImplemented, detail: ErrorDetail{
+	Code: "not_configured", Message: "investor funding is not configured",
+}}
+
+// investorError maps the investor package's errors onto the API's
+func investorError(err error) error {
+	switch {
+	case errors.Is(err, investor.ErrOverfunded):
+		return &requestError{status: http.StatusConflict, detail: ErrorDetail{Code: "overfunded", Message: err.Error()}}
+	case errors.Is(err, investor.ErrNotFundable):
+		return &requestError{status: http.StatusConflict, detail: ErrorDetail{Code: "invalid_state", Message: err.Error()}}
+	}
+	return err
+}
+
+func (h *Handler) fundLoan(w http.ResponseWriter, r *http.Request) {
+	if h.investors == nil {
+		writeError(w, errNoInvestors)
+		return
+	}
+	var req FundRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	fields := map[string]string{}
+	if strings.TrimSpace(req.InvestorID) == "" {
+		fields["investorId"] = "is required"
+	}
+	if req.Amount <= 0 || math.IsNaN(req.Amount) {
+		fields["amount"] = "must be positive"
+	}
+	if len(fields) > 0 {
+		writeError(w, invalidFields(fields))
+		return
+	}
+	s, err := h.investors.Fund(r.Context(), r.PathValue("id"), strings.TrimSpace(req.InvestorID), req.Amount)
+	if err != nil {
+		writeError(w, investorError(err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, s)
+}
+
+func (h *Handler) listShares(w http.ResponseWriter, r *http.Request) {
+	if h.investors == nil {
+		writeError(w, errNoInvestors)
+		return
+	}
+	if _, err := h.svc.GetLoan(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	shares, err := h.investors.Shares(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if shares == nil {
+		shares = []investor.Share{}
+	}
+	writeJSON(w, http.StatusOK, SharesResponse{Shares: shares})
+}
+
+func (h *Handler) investorStatement(w http.ResponseWriter, r *http.Request) {
+	if h.investors == nil {
+		writeError(w, errNoInvestors)
+		return
+	}
+	month := time.Now()
+	if m := r.URL.Query().Get("month"); m != "" {
+		var err error
+		if month, err = time.Parse("2006-01", m); err != nil {
+			writeError(w, badRequest("invalid_query", "month must be formatted as YYYY-MM"))
+			return
+		}
+	}
+	st, err := h.investors.Statement(r.Context(), r.PathValue("id"), month)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"loan/i18n"
+)
+
+// WithLocalization negotiates the locale of each request from its
+// Accept-Language header and adds text in that locale beside the codes of
+// JSON responses: statusText beside statuses and rejectionReasonText
+// beside rejection reasons. Without this option responses carry the codes
+// only.
+func WithLocalization(cat *i18n.Catalog) Option {
+	return func(h *Handler) { h.catalog = cat }
+}
+
+// localize annotates successful JSON responses in the negotiated locale.
+// It wraps redaction, so no text is added for a field the caller may not
+// see, and the negotiated locale is on the request context for handlers
+// rendering documents.
+func (h *Handler) localize(next http.Handler) http.Handler {
+	if h.catalog == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := h.catalog.Negotiate(r.Header.Get("Accept-Language"))
+		r = r.WithContext(i18n.WithLocale(r.Context(), locale))
+		buf := &bufferedWriter{header: http.Header{}}
+		next.ServeHTTP(buf, r)
+		for k, vs := range buf.header {
+			w.Header()[k] = vs
+		}
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+		body := buf.body.Bytes()
+		mediaType, _, _ := mime.ParseMediaType(buf.header.Get("Content-Type"))
+		if mediaType == "application/json" && buf.status < http.StatusBadRequest {
+			if shaped, err := h.annotate(body, locale); err == nil {
+				body = shaped
+				w.Header().Del("Content-Length")
+			}
+		}
+		w.WriteHeader(buf.status)
+		w.Write(body)
+	})
+}
+
+// annotate re-encodes the JSON document body with localized text fields
+func (h *Handler) annotate(body []byte, locale string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(h.translate(doc, locale)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// translate adds statusText for statuses the catalog names and
+// rejectionReasonText for every rejection reason, at any depth
+func (h *Handler) translate(v any, locale string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = h.translate(child, locale)
+		}
+		if s, ok := v["status"].(string); ok {
+			if text, ok := h.catalog.Message(locale, "status."+s); ok {
+				v["statusText"] = text
+			}
+		}
+		if s, ok := v["rejectionReason"].(string); ok && s != "" {
+			v["rejectionReasonText"] = h.catalog.Reason(locale, s)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = h.translate(child, locale)
+		}
+	}
+	return v
+}
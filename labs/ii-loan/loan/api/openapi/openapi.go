@@ -0,0 +1,310 @@
+// Package openapi builds OpenAPI 3 documents from Go types using reflection,
+// so the published schema follows the request and response structs the
+// handlers actually use.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI version produced
+const Version = "3.0.3"
+
+// Document is the root of an OpenAPI document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Server is a base URL the paths are relative to
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Components holds reusable schemas
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// PathItem groups the operations on one path
+type PathItem struct {
+	Get    *Op `json:"get,omitempty"`
+	Post   *Op `json:"post,omitempty"`
+	Put    *Op `json:"put,omitempty"`
+	Patch  *Op `json:"patch,omitempty"`
+	Delete *Op `json:"delete,omitempty"`
+}
+
+// Op is a serialized operation
+type Op struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes a JSON request body
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes one response status
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType wraps a schema for a content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema used by OpenAPI 3.0
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// Operation is what callers register for each handler
+type Operation struct {
+	Method     string
+	Path       string // ServeMux style, e.g. /loans/{id}
+	ID         string
+	Summary    string
+	Tags       []string
+	Deprecated bool
+	Query      []Parameter
+	Headers    []Parameter
+	// Request is a zero value of the request body type, a Binary for a
+	// non-JSON body, or nil
+	Request any
+	// Responses maps status codes to a zero value of the body type (nil for
+	// no body, Binary for a non-JSON body)
+	Responses map[int]any
+}
+
+// Binary is a request or response body that is a file, such as a CSV
+// upload or a PDF, rather than JSON
+type Binary struct {
+	ContentType string
+}
+
+func (b Binary) content() map[string]MediaType {
+	return map[string]MediaType{b.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+}
+
+// Builder accumulates operations into a Document
+type Builder struct {
+	doc   Document
+	names map[reflect.Type]string
+}
+
+// NewBuilder creates a builder for an API
+func NewBuilder(title, version string) *Builder {
+	return &Builder{
+		doc: Document{
+			OpenAPI:    Version,
+			Info:       Info{Title: title, Version: version},
+			Paths:      map[string]*PathItem{},
+			Components: Components{Schemas: map[string]*Schema{}},
+		},
+		names: map[reflect.Type]string{},
+	}
+}
+
+var pathParam = regexp.MustCompile(`\{([^}.]+)(\.\.\.)?\}`)
+
+// Server adds a base URL to the document
+func (b *Builder) Server(url string) {
+	b.doc.Servers = append(b.doc.Servers, Server{URL: url})
+}
+
+// Add registers an operation. Path parameters are derived from the path.
+func (b *Builder) Add(op Operation) {
+	path := pathParam.ReplaceAllString(op.Path, "{$1}")
+	item := b.doc.Paths[path]
+	if item == nil {
+		item = &PathItem{}
+		b.doc.Paths[path] = item
+	}
+
+	out := &Op{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Tags:        op.Tags,
+		Deprecated:  op.Deprecated,
+		Responses:   map[string]*Response{},
+	}
+	for _, m := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+		out.Parameters = append(out.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	out.Parameters = append(out.Parameters, op.Query...)
+	out.Parameters = append(out.Parameters, op.Headers...)
+	switch req := op.Request.(type) {
+	case nil:
+	case Binary:
+		out.RequestBody = &RequestBody{Required: true, Content: req.content()}
+	default:
+		out.RequestBody = &RequestBody{Required: true, Content: jsonContent(b.SchemaOf(req))}
+	}
+	for code, body := range op.Responses {
+		resp := &Response{Description: http.StatusText(code)}
+		switch body := body.(type) {
+		case nil:
+		case Binary:
+			resp.Content = body.content()
+		default:
+			resp.Content = jsonContent(b.SchemaOf(body))
+		}
+		out.Responses[strconv.Itoa(code)] = resp
+	}
+
+	switch op.Method {
+	case http.MethodGet:
+		item.Get = out
+	case http.MethodPost:
+		item.Post = out
+	case http.MethodPut:
+		item.Put = out
+	case http.MethodPatch:
+		item.Patch = out
+	case http.MethodDelete:
+		item.Delete = out
+	}
+}
+
+// Document returns the built document
+func (b *Builder) Document() *Document {
+	return &b.doc
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf returns the schema of v's type, registering named structs as
+// reusable components
+func (b *Builder) SchemaOf(v any) *Schema {
+	return b.schema(reflect.TypeOf(v))
+}
+
+func (b *Builder) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = b.componentName(t)
+			b.names[t] = name
+			b.doc.Components.Schemas[name] = &Schema{Type: "object"} // placeholder for recursion
+			b.doc.Components.Schemas[name] = b.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+func (b *Builder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded := b.structSchema(f.Type)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := b.schema(f.Type)
+		if strings.Contains(opts, "string") {
+			prop = &Schema{Type: "string"}
+		}
+		s.Properties[name] = prop
+	}
+	return s
+}
+
+// componentName uses the bare type name unless another package already
+// registered it, in which case the package name is prepended
+func (b *Builder) componentName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := b.doc.Components.Schemas[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+}
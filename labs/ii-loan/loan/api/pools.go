@@ -0,0 +1,184 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"loan"
+	"loan/pool"
+)
+
+// WithPools serves loan pools from pools. Without it the pool endpoints
+// answer 501.
+func WithPools(pools *pool.Service) Option {
+	return func(h *Handler) { h.pools = pools }
+}
+
+// CreatePoolRequest is the body of POST /pools
+type CreatePoolRequest struct {
+	Name     string        `json:"name"`
+	Criteria pool.Criteria `json:"criteria"`
+}
+
+// AddPoolLoansRequest is the body of POST /pools/{id}/loans. Fill adds
+// every eligible loan not yet pooled instead of the loans listed.
+type AddPoolLoansRequest struct {
+	LoanIDs []string `json:"loanIds,omitempty"`
+	Fill    bool     `json:"fill,omitempty"`
+}
+
+// PoolsResponse is returned by GET /pools
+type PoolsResponse struct {
+	Pools []*pool.Pool `json:"pools"`
+}
+
+var errNoPools = &requestError{status: http.StatusNotImplemented, detail: ErrorDetail{
+	Code: "not_configured", Message: "loan pools are not configured",
+}}
+
+// poolError maps the pool package's errors onto the API's
+func poolError(err error) error {
+	var ineligible *pool.IneligibleError
+	switch {
+	case errors.Is(err, pool.ErrNotFound):
+		return &requestError{status: http.StatusNotFound, detail: ErrorDetail{Code: "not_found", Message: err.Error()}}
+	case errors.Is(err, pool.ErrFrozen):
+		return &requestError{status: http.StatusConflict, detail: ErrorDetail{Code: "pool_frozen", Message: err.Error()}}
+	case errors.Is(err, pool.ErrAlreadyPooled):
+		return &requestError{status: http.StatusConflict, detail: ErrorDetail{Code: "already_pooled", Message: err.Error()}}
+	case errors.Is(err, pool.ErrEmpty):
+		return &requestError{status: http.StatusConflict, detail: ErrorDetail{Code: "pool_empty", Message: err.Error()}}
+	case errors.As(err, &ineligible):
+		return invalidFields(ineligible.Reasons)
+	}
+	return err
+}
+
+func (h *Handler) createPool(w http.ResponseWriter, r *http.Request) {
+	if h.pools == nil {
+		writeError(w, errNoPools)
+		return
+	}
+	var req CreatePoolRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	fields := map[string]string{}
+	if strings.TrimSpace(req.Name) == "" {
+		fields["name"] = "is required"
+	}
+	c := req.Criteria
+	if c.MaxLTV < 0 || c.MinSeasoningMonths < 0 || c.MaxDaysPastDue < 0 {
+		fields["criteria"] = "cannot be negative"
+	}
+	if len(fields) > 0 {
+		writeError(w, invalidFields(fields))
+		return
+	}
+	p, err := h.pools.Create(r.Context(), req.Name, c)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+func (h *Handler) listPools(w http.ResponseWriter, r *http.Request) {
+	if h.pools == nil {
+		writeError(w, errNoPools)
+		return
+	}
+	pools, err := h.pools.List(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, PoolsResponse{Pools: pools})
+}
+
+func (h *Handler) getPool(w http.ResponseWriter, r *http.Request) {
+	if h.pools == nil {
+		writeError(w, errNoPools)
+		return
+	}
+	p, err := h.pools.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, poolError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Handler) addPoolLoans(w http.ResponseWriter, r *http.Request) {
+	if h.pools == nil {
+		writeError(w, errNoPools)
+		return
+	}
+	var req AddPoolLoansRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Fill == (len(req.LoanIDs) > 0) {
+		writeError(w, invalidFields(map[string]string{"loanIds": "list loans or set fill, not both"}))
+		return
+	}
+	var (
+		p   *pool.Pool
+		err error
+	)
+	if req.Fill {
+		p, err = h.pools.Fill(r.Context(), r.PathValue("id"))
+	} else {
+		p, err = h.pools.Add(r.Context(), r.PathValue("id"), req.LoanIDs)
+	}
+	if err != nil {
+		writeError(w, poolError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Handler) freezePool(w http.ResponseWriter, r *http.Request) {
+	if h.pools == nil {
+		writeError(w, errNoPools)
+		return
+	}
+	p, err := h.pools.Freeze(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, poolError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Handler) poolCashflows(w http.ResponseWriter, r *http.Request) {
+	if h.pools == nil {
+		writeError(w, errNoPools)
+		return
+	}
+	now := time.Now()
+	from, to := now.AddDate(0, -11, 0), now.AddDate(0, 12, 0)
+	for name, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := r.URL.Query().Get(name); v != "" {
+			var err error
+			if *t, err = time.Parse("2006-01", v); err != nil {
+				writeError(w, badRequest("invalid_query", "%s must be formatted as YYYY-MM", name))
+				return
+			}
+		}
+	}
+	if loan.MonthStart(to).Before(loan.MonthStart(from)) {
+		writeError(w, badRequest("invalid_query", "to must not be before from"))
+		return
+	}
+	report, err := h.pools.Cashflows(r.Context(), r.PathValue("id"), from, to)
+	if err != nil {
+		writeError(w, poolError(err))
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"loan"
+)
+
+// WithProducts serves the product catalog from repo for administrators to
+// maintain. Without it the product endpoints answer 501.
+func WithProducts(repo loan.ProductRepository) Option {
+	return func(h *Handler) { h.products = repo }
+}
+
+// ProductRequest is the body of PUT /products/{code}
+type ProductRequest struct {
+	Name      string  `json:"name"`
+	MinAmount float64 `json:"minAmount,omitempty"`
+	MaxAmount float64 `json:"maxAmount,omitempty"`
+	Rate      float64 `json:"rate,omitempty"`
+	Terms     []int   `json:"terms,omitempty"`
+	// Purposes restricts the product to these loan.Purposes
+	Purposes []string `json:"purposes,omitempty"`
+	// PurposeRates replace Rate for the purposes they name
+	PurposeRates map[string]float64 `json:"purposeRates,omitempty"`
+	// Amortization is one of loan.Amortizations, an annuity when empty
+	Amortization string `json:"amortization,omitempty"`
+	// Prepayment is the penalty for settling the product's loans early
+	Prepayment *loan.PrepaymentPenalty `json:"prepayment,omitempty"`
+	// Insurance lists the credit insurance plans offered with the product
+	Insurance []loan.InsurancePlan `json:"insurance,omitempty"`
+}
+
+// ProductsResponse is returned by GET /products
+type ProductsResponse struct {
+	Products []loan.Product `json:"products"`
+}
+
+var errNoProducts = &requestError{status: http.StatusNotImplemented, detail: ErrorDetail{
+	Code: "not_configured", Message: "the product catalog is not configured",
+}}
+
+func (h *Handler) listProducts(w http.ResponseWriter, r *http.Request) {
+	if h.products == nil {
+		writeError(w, errNoProducts)
+		return
+	}
+	products, err := h.products.Products(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if products == nil {
+		products = []loan.Product{}
+	}
+	writeJSON(w, http.StatusOK, ProductsResponse{Products: products})
+}
+
+func (h *Handler) getProduct(w http.ResponseWriter, r *http.Request) {
+	if h.products == nil {
+		writeError(w, errNoProducts)
+		return
+	}
+	p, err := h.products.Product(r.Context(), r.PathValue("code"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Handler) putProduct(w http.ResponseWriter, r *http.Request) {
+	if h.products == nil {
+		writeError(w, errNoProducts)
+		return
+	}
+	var req ProductRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	fields := map[string]string{}
+	if strings.TrimSpace(req.Name) == "" {
+		fields["name"] = "is required"
+	}
+	if req.MinAmount < 0 || req.MaxAmount < 0 {
+		fields["amount"] = "cannot be negative"
+	} else if req.MaxAmount > 0 && req.MinAmount > req.MaxAmount {
+		fields["minAmount"] = "cannot be above maxAmount"
+	}
+	if req.Rate < 0 || req.Rate >= 1 {
+		fields["rate"] = "must be a fraction between 0 and 1"
+	}
+	for _, t := range req.Terms {
+		if t <= 0 {
+			fields["terms"] = "must be positive months"
+		}
+	}
+	for _, purpose := range req.Purposes {
+		if !loan.ValidPurpose(purpose) {
+			fields["purposes"] = "must be among " + strings.Join(loan.Purposes, ", ")
+		}
+	}
+	for purpose, rate := range req.PurposeRates {
+		switch {
+		case !loan.ValidPurpose(purpose):
+			fields["purposeRates"] = "must be keyed by " + strings.Join(loan.Purposes, ", ")
+		case len(req.Purposes) > 0 && !slices.Contains(req.Purposes, purpose):
+			fields["purposeRates"] = "can only price the product's purposes"
+		case rate < 0 || rate >= 1:
+			fields["purposeRates"] = "must be fractions between 0 and 1"
+		}
+	}
+	if req.Amortization != "" && !loan.ValidAmortization(req.Amortization) {
+		fields["amortization"] = "must be one of " + strings.Join(loan.Amortizations, ", ")
+	}
+	if req.Prepayment != nil {
+		var verr *loan.ValidationError
+		if err := req.Prepayment.Validate(); errors.As(err, &verr) {
+			fields[verr.Field] = verr.Message
+		}
+	}
+	plans := map[string]bool{}
+	for _, plan := range req.Insurance {
+		var verr *loan.ValidationError
+		if err := plan.Validate(); errors.As(err, &verr) {
+			fields[verr.Field] = verr.Message
+		}
+		if plans[plan.Code] {
+			fields["insurance.code"] = "plan codes must be unique"
+		}
+		plans[plan.Code] = true
+	}
+	if len(fields) > 0 {
+		writeError(w, invalidFields(fields))
+		return
+	}
+	p := loan.Product{
+		Code: r.PathValue("code"), Name: req.Name,
+		MinAmount: req.MinAmount, MaxAmount: req.MaxAmount, Rate: req.Rate, Terms: req.Terms,
+		Purposes: req.Purposes, PurposeRates: req.PurposeRates, Amortization: req.Amortization,
+		Prepayment: req.Prepayment, Insurance: req.Insurance,
+	}
+	if err := h.products.SaveProduct(r.Context(), p); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *Handler) deleteProduct(w http.ResponseWriter, r *http.Request) {
+	if h.products == nil {
+		writeError(w, errNoProducts)
+		return
+	}
+	if err := h.products.DeleteProduct(r.Context(), r.PathValue("code")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
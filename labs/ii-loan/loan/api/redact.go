@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// Role is the caller's role, which decides which response fields it may
+// see
+type Role string
+
+// Roles known to the redaction policy
+const (
+	RoleAdmin       Role = "admin"
+	RoleUnderwriter Role = "underwriter"
+	RoleCollector   Role = "collector"
+	RoleSupport     Role = "support"
+)
+
+// RoleResolver returns the role of an authenticated request
+type RoleResolver func(r *http.Request) Role
+
+// RoleFromHeader resolves roles from a header set by the authenticating
+// gateway in front of the API. The header must not be reachable by clients
+// directly.
+func RoleFromHeader(name string) RoleResolver {
+	return func(r *http.Request) Role { return Role(r.Header.Get(name)) }
+}
+
+// Visibility lists, per role, the JSON fields removed from its responses.
+// Fields are matched by name at any depth, so one entry covers every API
+// version and every resource embedding the field.
+type Visibility map[Role][]string
+
+// DefaultVisibility lets underwriters see everything, hides credit
+// report data from collectors and additionally the customers, both sides
+// of a transfer included, from support
+var DefaultVisibility = Visibility{
+	RoleAdmin:       nil,
+	RoleUnderwriter: nil,
+	RoleCollector:   {"creditScore", "rejectionReason", "decision", "explanation", "probabilityOfDefault"},
+	RoleSupport: {"creditScore", "rejectionReason", "decision", "explanation", "probabilityOfDefault",
+		"customerId", "fromCustomerId", "toCustomerId"},
+}
+
+// hidden returns the fields hidden from role. Roles missing from the
+// policy see no more than the most restricted role.
+func (v Visibility) hidden(role Role) map[string]bool {
+	fields, ok := v[role]
+	if !ok {
+		for _, f := range v {
+			fields = append(fields, f...)
+		}
+	}
+	set := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		set[f] = true
+	}
+	return set
+}
+
+// WithRoles shapes JSON responses by the caller's role, removing the
+// fields vis hides from it. Without this option responses are not
+// redacted.
+func WithRoles(resolve RoleResolver, vis Visibility) Option {
+	return func(h *Handler) { h.roles, h.visibility = resolve, vis }
+}
+
+// redact removes the fields hidden from the caller's role from successful
+// JSON responses. It wraps the whole route, idempotent replays included,
+// so handlers stay unaware of roles.
+func (h *Handler) redact(next http.Handler) http.Handler {
+	if h.roles == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hidden := h.visibility.hidden(h.roles(r))
+		if len(hidden) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		buf := &bufferedWriter{header: http.Header{}}
+		next.ServeHTTP(buf, r)
+		for k, vs := range buf.header {
+			w.Header()[k] = vs
+		}
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+		body := buf.body.Bytes()
+		mediaType, _, _ := mime.ParseMediaType(buf.header.Get("Content-Type"))
+		if mediaType == "application/json" && buf.status < http.StatusBadRequest {
+			if shaped, err := stripFields(body, hidden); err == nil {
+				body = shaped
+				w.Header().Del("Content-Length")
+			}
+		}
+		w.WriteHeader(buf.status)
+		w.Write(body)
+	})
+}
+
+// bufferedWriter holds a response until it has been shaped
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedWriter) Header() http.Header { return b.header }
+
+func (b *bufferedWriter) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+// stripFields re-encodes the JSON document body without the hidden fields
+func stripFields(body []byte, hidden map[string]bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.NewEncoder(&out).Encode(strip(doc, hidden)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func strip(v any, hidden map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if hidden[k] {
+				delete(v, k)
+				continue
+			}
+			v[k] = strip(child, hidden)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = strip(child, hidden)
+		}
+	}
+	return v
+}
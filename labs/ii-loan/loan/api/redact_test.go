@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"loan"
+)
+
+func TestSupportCannotSeeTransferCustomers(t *testing.T) {
+	transfer := &loan.Transfer{
+		ID:             "tr-1",
+		LoanID:         "loan-1",
+		FromCustomerID: "cust-1",
+		ToCustomerID:   "cust-2",
+		Status:         loan.TransferPending,
+		KYC:            loan.KYCResult{CustomerID: "cust-2", Passed: true},
+	}
+	h := &Handler{roles: RoleFromHeader("X-Role"), visibility: DefaultVisibility}
+	redacted := h.redact(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, transfer)
+	}))
+	get := func(role Role) map[string]any {
+		r := httptest.NewRequest(http.MethodGet, "/transfers/tr-1", nil)
+		r.Header.Set("X-Role", string(role))
+		w := httptest.NewRecorder()
+		redacted.ServeHTTP(w, r)
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	support := get(RoleSupport)
+	for _, field := range []string{"fromCustomerId", "toCustomerId"} {
+		if v, ok := support[field]; ok {
+			t.Errorf("support sees %s %v", field, v)
+		}
+	}
+	if v, ok := support["kyc"].(map[string]any)["customerId"]; ok {
+		t.Errorf("support sees kyc.customerId %v", v)
+	}
+	if support["loanId"] != "loan-1" {
+		t.Errorf("support sees loanId %v, want loan-1", support["loanId"])
+	}
+
+	underwriter := get(RoleUnderwriter)
+	if underwriter["fromCustomerId"] != "cust-1" || underwriter["toCustomerId"] != "cust-2" {
+		t.Errorf("underwriter sees customers %v and %v, want cust-1 and cust-2", underwriter["fromCustomerId"], underwriter["toCustomerId"])
+	}
+}
@@ -0,0 +1,346 @@
+package api
+
+import (
+	"net/http"
+
+	"loan"
+	"loan/api/openapi"
+	"loan/bulkimport"
+	"loan/investor"
+	"loan/pool"
+	"loan/webhook"
+)
+
+// route binds a handler to the OpenAPI operation describing it. Routes are
+// registered on the mux and in the spec from the same table, so the
+// published document cannot drift from what is served.
+type route struct {
+	openapi.Operation
+	handler http.HandlerFunc
+}
+
+// eventStream is the response of routes writing events as they happen
+var eventStream = openapi.Binary{ContentType: "text/event-stream"}
+
+// streams reports whether rt answers with an event stream, which must not
+// be buffered
+func (rt route) streams() bool {
+	return rt.Responses[http.StatusOK] == eventStream
+}
+
+// errorResponses are the error statuses shared by most operations
+func errorResponses(codes ...int) map[int]any {
+	m := map[int]any{http.StatusInternalServerError: ErrorBody{}}
+	for _, c := range codes {
+		m[c] = ErrorBody{}
+	}
+	return m
+}
+
+func responses(ok int, body any, errs ...int) map[int]any {
+	m := errorResponses(errs...)
+	m[ok] = body
+	return m
+}
+
+func (h *Handler) routes(v *version) []route {
+	return []route{
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/applications", ID: "submitApplication",
+			Summary: "Submit a loan application", Tags: []string{"applications"},
+			Request:   v.types.application,
+			Responses: responses(http.StatusCreated, v.types.loan, http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity),
+		}, h.submitApplication(v)},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/applications/import", ID: "importApplications",
+			Summary: "Submit applications in bulk from a CSV file", Tags: []string{"applications"},
+			Request:   openapi.Binary{ContentType: "text/csv"},
+			Responses: responses(http.StatusOK, bulkimport.Report{}, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType),
+		}, h.importApplications},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/applications/{id}", ID: "getApplication",
+			Summary: "Retrieve an application", Tags: []string{"applications"},
+			Responses: responses(http.StatusOK, v.types.loan, http.StatusNotFound),
+		}, h.getLoan(v)},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/applications/{id}/decision", ID: "getDecision",
+			Summary: "Retrieve the risk engine's decision on an application and why it was made", Tags: []string{"applications"},
+			Responses: responses(http.StatusOK, loan.Decision{}, http.StatusNotFound),
+		}, h.getDecision},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/loans", ID: "listLoans",
+			Summary: "List loans", Tags: []string{"loans"},
+			Query: []openapi.Parameter{
+				{Name: "status", In: "query", Description: "repeatable status filter", Schema: &openapi.Schema{
+					Type: "string", Enum: []string{loan.StatusPending, loan.StatusApproved, loan.StatusRejected, loan.StatusDefault},
+				}},
+				{Name: "customerId", In: "query", Schema: &openapi.Schema{Type: "string"}},
+				{Name: "filter", In: "query", Description: "conditions on status, customerId, product, amount, termMonths and createdAt joined by AND, " +
+					`e.g. status in (approved, default) AND amount > 5000 AND createdAt >= 2024-01-01`, Schema: &openapi.Schema{Type: "string"}},
+				{Name: "limit", In: "query", Description: "page size, default 100, at most 1000", Schema: &openapi.Schema{Type: "integer"}},
+				{Name: "cursor", In: "query", Description: "nextCursor of the previous page, with the same filters", Schema: &openapi.Schema{Type: "string"}},
+			},
+			Responses: responses(http.StatusOK, v.types.list, http.StatusBadRequest),
+		}, h.listLoans(v)},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/loans/{id}", ID: "getLoan",
+			Summary: "Retrieve a loan", Tags: []string{"loans"},
+			Responses: responses(http.StatusOK, v.types.loan, http.StatusNotFound),
+		}, h.getLoan(v)},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/approve", ID: "approveLoan",
+			Summary: "Approve a pending loan", Tags: []string{"loans"},
+			Responses: responses(http.StatusOK, v.types.loan, http.StatusNotFound, http.StatusConflict),
+		}, h.approveLoan(v)},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans:batchApprove", ID: "batchApproveLoans",
+			Summary: "Approve several pending loans at once", Tags: []string{"loans"},
+			Request:   BatchApproveRequest{},
+			Responses: responses(http.StatusOK, BatchApproveResponse{}, http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity),
+		}, h.batchApprove},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/reject", ID: "rejectLoan",
+			Summary: "Reject a pending loan", Tags: []string{"loans"},
+			Request:   RejectRequest{},
+			Responses: responses(http.StatusOK, v.types.loan, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity),
+		}, h.rejectLoan(v)},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/screening/clear", ID: "clearScreening",
+			Summary: "Clear the watchlist matches of a pending application as false positives", Tags: []string{"loans"},
+			Request:   ClearScreeningRequest{},
+			Responses: responses(http.StatusOK, v.types.loan, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity),
+		}, h.clearScreening(v)},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/terms", ID: "changeTerms",
+			Summary: "Reprice or restructure an approved loan, regenerating the installments not yet due", Tags: []string{"loans"},
+			Request:   TermsRequest{},
+			Responses: responses(http.StatusOK, loan.TermsChange{}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity),
+		}, h.changeTerms},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/escrow", ID: "setUpEscrow",
+			Summary: "Open escrow on a secured loan for the insurance and taxes paid on the borrower's behalf", Tags: []string{"escrow"},
+			Request:   EscrowRequest{},
+			Responses: responses(http.StatusCreated, loan.Escrow{}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity),
+		}, h.setUpEscrow},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/loans/{id}/escrow", ID: "getEscrow",
+			Summary: "Escrow balance, items, transactions and analyses of a loan", Tags: []string{"escrow"},
+			Responses: responses(http.StatusOK, loan.Escrow{}, http.StatusNotFound),
+		}, h.getEscrow},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/escrow/analysis", ID: "analyzeEscrow",
+			Summary: "Analyze escrow now, resetting the escrow payment for the coming year", Tags: []string{"escrow"},
+			Responses: responses(http.StatusOK, loan.EscrowAnalysis{}, http.StatusNotFound, http.StatusConflict),
+		}, h.analyzeEscrow},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/insurance", ID: "attachInsurance",
+			Summary: "Insure a loan under a credit insurance plan of its product, adding the premium to its installments", Tags: []string{"insurance"},
+			Request:   InsuranceRequest{},
+			Responses: responses(http.StatusCreated, loan.Insurance{}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity),
+		}, h.attachInsurance},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/insurance/claims", ID: "claimInsurance",
+			Summary: "Claim a loan's insurance for a covered event, paying off its balance", Tags: []string{"insurance"},
+			Request:   ClaimRequest{},
+			Responses: responses(http.StatusCreated, loan.InsuranceClaim{}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity),
+		}, h.claimInsurance},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/disburse", ID: "disburseLoan",
+			Summary: "Pay an approved loan out to the customer", Tags: []string{"loans"},
+			Request:   DisburseRequest{},
+			Responses: responses(http.StatusOK, v.types.loan, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusNotImplemented),
+		}, h.disburseLoan(v)},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/mandate", ID: "createMandate",
+			Summary: "Set up direct debit of a loan's installments", Tags: []string{"payments"},
+			Request:   MandateRequest{},
+			Responses: responses(http.StatusCreated, loan.Mandate{}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusNotImplemented),
+		}, h.createMandate},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/loans/{id}/mandate", ID: "getMandate",
+			Summary: "Direct debit mandate of a loan with its attempts", Tags: []string{"payments"},
+			Responses: responses(http.StatusOK, loan.Mandate{}, http.StatusNotFound, http.StatusNotImplemented),
+		}, h.getMandate},
+		{openapi.Operation{
+			Method: http.MethodDelete, Path: "/loans/{id}/mandate", ID: "cancelMandate",
+			Summary: "Stop direct debit of a loan", Tags: []string{"payments"},
+			Responses: responses(http.StatusOK, loan.Mandate{}, http.StatusNotFound, http.StatusNotImplemented),
+		}, h.cancelMandate},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/transfers", ID: "requestTransfer",
+			Summary: "Request the transfer of a loan to another customer", Tags: []string{"transfers"},
+			Request:   TransferRequest{},
+			Responses: responses(http.StatusCreated, loan.Transfer{}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusNotImplemented),
+		}, h.requestTransfer},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/loans/{id}/transfers", ID: "listTransfers",
+			Summary: "Transfers of a loan and its prior obligors", Tags: []string{"transfers"},
+			Responses: responses(http.StatusOK, TransfersResponse{}, http.StatusNotFound, http.StatusNotImplemented),
+		}, h.listTransfers},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/transfers/{id}", ID: "getTransfer",
+			Summary: "Retrieve a loan transfer", Tags: []string{"transfers"},
+			Responses: responses(http.StatusOK, loan.Transfer{}, http.StatusNotFound, http.StatusNotImplemented),
+		}, h.getTransfer},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/transfers/{id}/approve", ID: "approveTransfer",
+			Summary: "Approve a pending transfer, moving the loan to the new customer", Tags: []string{"transfers"},
+			Request:   TransferDecisionRequest{},
+			Responses: responses(http.StatusOK, loan.Transfer{}, http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusNotImplemented),
+		}, h.approveTransfer},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/transfers/{id}/reject", ID: "rejectTransfer",
+			Summary: "Reject a pending transfer", Tags: []string{"transfers"},
+			Request:   TransferDecisionRequest{},
+			Responses: responses(http.StatusOK, loan.Transfer{}, http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusNotImplemented),
+		}, h.rejectTransfer},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/loans/{id}/schedule", ID: "getSchedule",
+			Summary: "Repayment schedule of a loan", Tags: []string{"loans"},
+			Responses: responses(http.StatusOK, v.types.schedule, http.StatusNotFound),
+		}, h.getSchedule(v)},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/loans/{id}/statement", ID: "getStatement",
+			Summary: "Monthly statement of a loan as PDF", Tags: []string{"loans"},
+			Query: []openapi.Parameter{
+				{Name: "month", In: "query", Description: "statement month as YYYY-MM, default the current month", Schema: &openapi.Schema{Type: "string"}},
+			},
+			Responses: responses(http.StatusOK, openapi.Binary{ContentType: "application/pdf"}, http.StatusBadRequest, http.StatusNotFound),
+		}, h.getStatement},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/loans/{id}/payments", ID: "listPayments",
+			Summary: "Payments received on a loan", Tags: []string{"payments"},
+			Responses: responses(http.StatusOK, v.types.payments, http.StatusNotFound),
+		}, h.listPayments(v)},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/loans/{id}/events", ID: "streamLoanEvents",
+			Summary: "Stream a loan's events as they happen, as server-sent events or over a WebSocket upgrade", Tags: []string{"loans"},
+			Responses: responses(http.StatusOK, eventStream, http.StatusBadRequest, http.StatusNotFound, http.StatusNotImplemented),
+		}, h.loanEvents(v)},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/payments", ID: "recordPayment",
+			Summary: "Record a repayment", Tags: []string{"payments"},
+			Request:   v.types.paymentRequest,
+			Responses: responses(http.StatusCreated, v.types.payment, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity),
+		}, h.recordPayment(v)},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/loans/{id}/payoff", ID: "getPayoffQuote",
+			Summary: "Quote the amount settling a loan today, prepayment penalty included", Tags: []string{"payments"},
+			Responses: responses(http.StatusOK, loan.PayoffQuote{}, http.StatusNotFound, http.StatusConflict),
+		}, h.getPayoffQuote},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/settle", ID: "settleLoan",
+			Summary: "Settle a loan early for the amount of today's payoff quote", Tags: []string{"payments"},
+			Request:   v.types.paymentRequest,
+			Responses: responses(http.StatusCreated, v.types.payment, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity),
+		}, h.settleLoan(v)},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/loans/{id}/investors", ID: "fundLoan",
+			Summary: "Commit part of a loan's principal to an investor", Tags: []string{"investors"},
+			Request:   FundRequest{},
+			Responses: responses(http.StatusCreated, investor.Share{}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusNotImplemented),
+		}, h.fundLoan},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/loans/{id}/investors", ID: "listShares",
+			Summary: "Investors funding a loan and their shares", Tags: []string{"investors"},
+			Responses: responses(http.StatusOK, SharesResponse{}, http.StatusNotFound, http.StatusNotImplemented),
+		}, h.listShares},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/investors/{id}/statement", ID: "getInvestorStatement",
+			Summary: "Holdings and repayments received by an investor in a month", Tags: []string{"investors"},
+			Query: []openapi.Parameter{
+				{Name: "month", In: "query", Description: "statement month as YYYY-MM, default the current month", Schema: &openapi.Schema{Type: "string"}},
+			},
+			Responses: responses(http.StatusOK, investor.Statement{}, http.StatusBadRequest, http.StatusNotImplemented),
+		}, h.investorStatement},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/pools", ID: "createPool",
+			Summary: "Open a loan pool with eligibility criteria", Tags: []string{"pools"},
+			Request:   CreatePoolRequest{},
+			Responses: responses(http.StatusCreated, pool.Pool{}, http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusNotImplemented),
+		}, h.createPool},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/pools", ID: "listPools",
+			Summary: "Loan pools", Tags: []string{"pools"},
+			Responses: responses(http.StatusOK, PoolsResponse{}, http.StatusNotImplemented),
+		}, h.listPools},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/pools/{id}", ID: "getPool",
+			Summary: "A loan pool and its members", Tags: []string{"pools"},
+			Responses: responses(http.StatusOK, pool.Pool{}, http.StatusNotFound, http.StatusNotImplemented),
+		}, h.getPool},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/pools/{id}/loans", ID: "addPoolLoans",
+			Summary: "Add eligible loans to an open pool", Tags: []string{"pools"},
+			Request:   AddPoolLoansRequest{},
+			Responses: responses(http.StatusOK, pool.Pool{}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusNotImplemented),
+		}, h.addPoolLoans},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/pools/{id}/freeze", ID: "freezePool",
+			Summary: "Freeze the membership of a pool at its cut-off balance", Tags: []string{"pools"},
+			Responses: responses(http.StatusOK, pool.Pool{}, http.StatusNotFound, http.StatusConflict, http.StatusNotImplemented),
+		}, h.freezePool},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/pools/{id}/cashflows", ID: "getPoolCashflows",
+			Summary: "Scheduled and collected cashflows of a pool by month", Tags: []string{"pools"},
+			Query: []openapi.Parameter{
+				{Name: "from", In: "query", Description: "first month as YYYY-MM, default eleven months ago", Schema: &openapi.Schema{Type: "string"}},
+				{Name: "to", In: "query", Description: "last month as YYYY-MM, default a year ahead", Schema: &openapi.Schema{Type: "string"}},
+			},
+			Responses: responses(http.StatusOK, pool.CashflowReport{}, http.StatusBadRequest, http.StatusNotFound, http.StatusNotImplemented),
+		}, h.poolCashflows},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/customers/{id}/exposure", ID: "getCustomerExposure",
+			Summary: "What a customer owes and has applied for, against the exposure limit", Tags: []string{"customers"},
+			Responses: responses(http.StatusOK, loan.Exposure{}),
+		}, h.customerExposure},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/customers/{id}/anonymize", ID: "anonymizeCustomer",
+			Summary: "Erase a customer's personal data, keeping their settled loans under a pseudonym", Tags: []string{"customers"},
+			Responses: responses(http.StatusOK, loan.Anonymization{}, http.StatusNotFound, http.StatusConflict, http.StatusNotImplemented),
+		}, h.anonymizeCustomer},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/products", ID: "listProducts",
+			Summary: "The loan product catalog", Tags: []string{"products"},
+			Responses: responses(http.StatusOK, ProductsResponse{}, http.StatusNotImplemented),
+		}, h.listProducts},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/products/{code}", ID: "getProduct",
+			Summary: "A loan product", Tags: []string{"products"},
+			Responses: responses(http.StatusOK, loan.Product{}, http.StatusNotFound, http.StatusNotImplemented),
+		}, h.getProduct},
+		{openapi.Operation{
+			Method: http.MethodPut, Path: "/products/{code}", ID: "putProduct",
+			Summary: "Add or replace a loan product", Tags: []string{"products"},
+			Request:   ProductRequest{},
+			Responses: responses(http.StatusOK, loan.Product{}, http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusNotImplemented),
+		}, h.putProduct},
+		{openapi.Operation{
+			Method: http.MethodDelete, Path: "/products/{code}", ID: "deleteProduct",
+			Summary: "Withdraw a loan product", Tags: []string{"products"},
+			Responses: responses(http.StatusNoContent, nil, http.StatusNotFound, http.StatusNotImplemented),
+		}, h.deleteProduct},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/webhooks", ID: "registerWebhook",
+			Summary: "Subscribe an endpoint to loan events, delivered signed with its secret", Tags: []string{"webhooks"},
+			Request:   WebhookRequest{},
+			Responses: responses(http.StatusCreated, webhook.Subscription{}, http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusNotImplemented),
+		}, h.registerWebhook},
+		{openapi.Operation{
+			Method: http.MethodGet, Path: "/webhooks", ID: "listWebhooks",
+			Summary: "Endpoints subscribed to loan events", Tags: []string{"webhooks"},
+			Responses: responses(http.StatusOK, WebhooksResponse{}, http.StatusNotImplemented),
+		}, h.listWebhooks},
+		{openapi.Operation{
+			Method: http.MethodDelete, Path: "/webhooks/{id}", ID: "deleteWebhook",
+			Summary: "Stop delivering events to an endpoint", Tags: []string{"webhooks"},
+			Responses: responses(http.StatusNoContent, nil, http.StatusNotFound, http.StatusNotImplemented),
+		}, h.deleteWebhook},
+		{openapi.Operation{
+			Method: http.MethodPost, Path: "/stress-tests", ID: "runStressTest",
+			Summary: "Run what-if scenarios on the loan book", Tags: []string{"risk"},
+			Request:   StressTestRequest{},
+			Responses: responses(http.StatusOK, StressTestResponse{}, http.StatusBadRequest, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity),
+		}, h.stressTest},
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"loan"
+	"loan/stress"
+)
+
+// StressTestRequest is the body of POST /stress-tests. Script holds one or
+// more scenarios in the stress package's language.
+type StressTestRequest struct {
+	Script string `json:"script"`
+}
+
+// StressTestResponse is returned by POST /stress-tests, one result per
+// scenario in script order
+type StressTestResponse struct {
+	Results []stress.Result `json:"results"`
+}
+
+// stressTest runs the scenarios of the request on the current book with
+// the default provisioning model. It changes nothing; it is a POST only
+// because the script does not fit a query string.
+func (h *Handler) stressTest(w http.ResponseWriter, r *http.Request) {
+	var req StressTestRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	scenarios, err := stress.Parse(strings.NewReader(req.Script))
+	if err != nil {
+		var perr *stress.ParseError
+		if errors.As(err, &perr) {
+			writeError(w, invalidFields(map[string]string{"script": err.Error()}))
+			return
+		}
+		writeError(w, err)
+		return
+	}
+	loans, err := h.svc.ListLoans(r.Context(), loan.Filter{Statuses: []string{loan.StatusApproved, loan.StatusDefault}})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, StressTestResponse{Results: stress.Run(loans, loan.DefaultProvisioningModel, scenarios)})
+}
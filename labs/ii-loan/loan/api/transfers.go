@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+
+	"loan"
+)
+
+// TransferRequest is the body of POST /loans/{id}/transfers
+type TransferRequest struct {
+	ToCustomerID string `json:"toCustomerId"`
+	Reason       string `json:"reason"`
+	RequestedBy  string `json:"requestedBy"`
+}
+
+// TransferDecisionRequest is the body of POST /transfers/{id}/approve and
+// /transfers/{id}/reject. Reason is required to reject.
+type TransferDecisionRequest struct {
+	DecidedBy string `json:"decidedBy"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// TransfersResponse is returned by GET /loans/{id}/transfers
+type TransfersResponse struct {
+	LoanID    string           `json:"loanId"`
+	Transfers []*loan.Transfer `json:"transfers"`
+}
+
+func (h *Handler) requestTransfer(w http.ResponseWriter, r *http.Request) {
+	var req TransferRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	t, err := h.svc.RequestTransfer(r.Context(), loan.TransferRequest{
+		LoanID: r.PathValue("id"), ToCustomerID: req.ToCustomerID, Reason: req.Reason, RequestedBy: req.RequestedBy,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *Handler) listTransfers(w http.ResponseWriter, r *http.Request) {
+	transfers, err := h.svc.Transfers(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if transfers == nil {
+		transfers = []*loan.Transfer{}
+	}
+	writeJSON(w, http.StatusOK, TransfersResponse{LoanID: r.PathValue("id"), Transfers: transfers})
+}
+
+func (h *Handler) getTransfer(w http.ResponseWriter, r *http.Request) {
+	t, err := h.svc.GetTransfer(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Handler) approveTransfer(w http.ResponseWriter, r *http.Request) {
+	var req TransferDecisionRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	t, err := h.svc.ApproveTransfer(r.Context(), r.PathValue("id"), req.DecidedBy)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Handler) rejectTransfer(w http.ResponseWriter, r *http.Request) {
+	var req TransferDecisionRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	t, err := h.svc.RejectTransfer(r.Context(), r.PathValue("id"), req.DecidedBy, req.Reason)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
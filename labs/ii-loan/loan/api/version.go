@@ -0,0 +1,343 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"loan"
+)
+
+// DefaultCurrency is the ISO 4217 code reported by v2 Money values
+const DefaultCurrency = loan.DefaultCurrency
+
+// version describes how one API version represents resources. Handlers are
+// written once against the internal request types and domain model; each
+// version only supplies the translation to and from its wire format, which
+// is the compatibility layer that lets the API evolve without breaking
+// older clients.
+type version struct {
+	name       string
+	deprecated bool
+
+	loan     func(*loan.Loan) any
+	schedule func(*loan.Loan) any
+	payment  func(loan.Payment) any
+	payments func(*loan.Loan) any
+	// list represents a page of loans and the cursor of the next, if any
+	list func(loans []*loan.Loan, next string) any
+
+	decodeApplication func(w http.ResponseWriter, r *http.Request) (ApplicationRequest, error)
+	decodePayment     func(w http.ResponseWriter, r *http.Request) (PaymentRequest, error)
+
+	// zero values of the wire types, used to build the OpenAPI document
+	types struct {
+		application, paymentRequest             any
+		loan, schedule, payment, payments, list any
+	}
+}
+
+func v1() *version {
+	v := &version{
+		name: "v1",
+		loan: func(l *loan.Loan) any { return l },
+		schedule: func(l *loan.Loan) any {
+			return ScheduleResponse{
+				LoanID: l.ID, Amortization: l.AmortizationMethod(),
+				TotalInterest: loan.TotalInterest(l.Schedule), Installments: orEmpty(l.Schedule),
+			}
+		},
+		payment: func(p loan.Payment) any { return p },
+		payments: func(l *loan.Loan) any {
+			return PaymentsResponse{LoanID: l.ID, Payments: orEmpty(l.Payments)}
+		},
+		list: func(loans []*loan.Loan, next string) any {
+			return ListResponse{Loans: orEmpty(loans), NextCursor: next}
+		},
+		decodeApplication: func(w http.ResponseWriter, r *http.Request) (ApplicationRequest, error) {
+			var req ApplicationRequest
+			return req, decodeJSON(w, r, &req)
+		},
+		decodePayment: func(w http.ResponseWriter, r *http.Request) (PaymentRequest, error) {
+			var req PaymentRequest
+			return req, decodeJSON(w, r, &req)
+		},
+	}
+	v.types.application, v.types.paymentRequest = ApplicationRequest{}, PaymentRequest{}
+	v.types.loan, v.types.schedule, v.types.payment = &loan.Loan{}, ScheduleResponse{}, loan.Payment{}
+	v.types.payments, v.types.list = PaymentsResponse{}, ListResponse{}
+	return v
+}
+
+// path prefixes an API path with the version
+func (v *version) path(p string) string {
+	return "/" + v.name + p
+}
+
+// Money is an exact decimal amount with its currency, introduced in v2 to
+// replace the floating point amounts of v1
+type Money struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func money(v float64) Money {
+	return Money{Amount: strconv.FormatFloat(v, 'f', 2, 64), Currency: DefaultCurrency}
+}
+
+// value parses m and checks the currency is one the service books in
+func (m Money) value(field string) (float64, error) {
+	if m.Currency != DefaultCurrency {
+		return 0, invalidFields(map[string]string{field + ".currency": fmt.Sprintf("must be %s", DefaultCurrency)})
+	}
+	v, err := strconv.ParseFloat(m.Amount, 64)
+	if err != nil {
+		return 0, invalidFields(map[string]string{field + ".amount": "must be a decimal string such as \"1200.00\""})
+	}
+	return v, nil
+}
+
+// LoanV2 is the v2 representation of a loan
+type LoanV2 struct {
+	ID              string                  `json:"id"`
+	CustomerID      string                  `json:"customerId"`
+	Status          string                  `json:"status"`
+	Principal       Money                   `json:"principal"`
+	InterestRate    float64                 `json:"interestRate"`
+	TermMonths      int                     `json:"termMonths"`
+	Product         string                  `json:"product,omitempty"`
+	Purpose         string                  `json:"purpose,omitempty"`
+	Amortization    string                  `json:"amortization,omitempty"`
+	Frequency       string                  `json:"frequency,omitempty"`
+	Prepayment      *loan.PrepaymentPenalty `json:"prepayment,omitempty"`
+	Escrow          *loan.Escrow            `json:"escrow,omitempty"`
+	Insurance       *loan.Insurance         `json:"insurance,omitempty"`
+	CreatedAt       time.Time               `json:"createdAt"`
+	ApprovedAt      *time.Time              `json:"approvedAt,omitempty"`
+	DisbursedAt     *time.Time              `json:"disbursedAt,omitempty"`
+	Balance         Money                   `json:"balance"`
+	AccruedInterest Money                   `json:"accruedInterest"`
+	DaysPastDue     int                     `json:"daysPastDue"`
+	Delinquency     string                  `json:"delinquency,omitempty"`
+	RejectionReason string                  `json:"rejectionReason,omitempty"`
+	Decision        *loan.Decision          `json:"decision,omitempty"`
+	Screening       *loan.Screening         `json:"screening,omitempty"`
+	TermsChanges    []loan.TermsChange      `json:"termsChanges,omitempty"`
+	Links           map[string]Link         `json:"_links"`
+}
+
+// Link is a hypermedia reference
+type Link struct {
+	Href string `json:"href"`
+}
+
+// InstallmentV2 is the v2 representation of an installment
+type InstallmentV2 struct {
+	Number    int       `json:"number"`
+	DueDate   time.Time `json:"dueDate"`
+	Principal Money     `json:"principal"`
+	Interest  Money     `json:"interest"`
+	Amount    Money     `json:"amount"`
+	Paid      Money     `json:"paid"`
+	Escrow    *Money    `json:"escrow,omitempty"`
+	Premium   *Money    `json:"premium,omitempty"`
+}
+
+// PaymentV2 is the v2 representation of a payment
+type PaymentV2 struct {
+	ID        string    `json:"id"`
+	LoanID    string    `json:"loanId"`
+	Amount    Money     `json:"amount"`
+	Interest  Money     `json:"interest"`
+	Principal Money     `json:"principal"`
+	Penalty   *Money    `json:"penalty,omitempty"`
+	Escrow    *Money    `json:"escrow,omitempty"`
+	Premium   *Money    `json:"premium,omitempty"`
+	PaidAt    time.Time `json:"paidAt"`
+}
+
+// ApplicationRequestV2 is the v2 body of POST /v2/applications
+type ApplicationRequestV2 struct {
+	CustomerID   string  `json:"customerId"`
+	Amount       Money   `json:"amount"`
+	InterestRate float64 `json:"interestRate"`
+	TermMonths   int     `json:"termMonths"`
+	Product      string  `json:"product,omitempty"`
+	Purpose      string  `json:"purpose,omitempty"`
+	Frequency    string  `json:"frequency,omitempty"`
+}
+
+// PaymentRequestV2 is the v2 body of POST /v2/loans/{id}/payments
+type PaymentRequestV2 struct {
+	Amount Money `json:"amount"`
+}
+
+// ScheduleResponseV2 is returned by GET /v2/loans/{id}/schedule
+type ScheduleResponseV2 struct {
+	LoanID        string          `json:"loanId"`
+	Amortization  string          `json:"amortization"`
+	TotalInterest Money           `json:"totalInterest"`
+	Installments  []InstallmentV2 `json:"installments"`
+}
+
+// PaymentsResponseV2 is returned by GET /v2/loans/{id}/payments
+type PaymentsResponseV2 struct {
+	LoanID   string      `json:"loanId"`
+	Payments []PaymentV2 `json:"payments"`
+}
+
+// ListResponseV2 is returned by GET /v2/loans
+type ListResponseV2 struct {
+	Loans      []LoanV2 `json:"loans"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+func loanV2(l *loan.Loan) LoanV2 {
+	out := LoanV2{
+		ID:              l.ID,
+		CustomerID:      l.CustomerID,
+		Status:          l.Status,
+		Principal:       money(l.Amount),
+		InterestRate:    l.AnnualRate(),
+		TermMonths:      l.TermMonths,
+		Product:         l.Product,
+		Purpose:         l.Purpose,
+		Amortization:    l.Amortization,
+		Frequency:       l.Frequency,
+		Prepayment:      l.Prepayment,
+		Escrow:          l.Escrow,
+		Insurance:       l.Insurance,
+		CreatedAt:       l.CreatedAt,
+		Balance:         money(l.Balance),
+		AccruedInterest: money(l.AccruedInterest),
+		DaysPastDue:     l.DaysPastDue,
+		Delinquency:     string(l.Delinquency),
+		RejectionReason: l.RejectionReason,
+		Decision:        l.Decision,
+		Screening:       l.Screening,
+		TermsChanges:    l.TermsChanges,
+		Links: map[string]Link{
+			"self":     {Href: "/v2/loans/" + l.ID},
+			"schedule": {Href: "/v2/loans/" + l.ID + "/schedule"},
+			"payments": {Href: "/v2/loans/" + l.ID + "/payments"},
+		},
+	}
+	if !l.ApprovedAt.IsZero() {
+		t := l.ApprovedAt
+		out.ApprovedAt = &t
+	}
+	if !l.DisbursedAt.IsZero() {
+		t := l.DisbursedAt
+		out.DisbursedAt = &t
+	}
+	return out
+}
+
+func paymentV2(p loan.Payment) PaymentV2 {
+	out := PaymentV2{
+		ID: p.ID, LoanID: p.LoanID, PaidAt: p.PaidAt,
+		Amount: money(p.Amount), Interest: money(p.Interest), Principal: money(p.Principal),
+	}
+	if p.Penalty > 0 {
+		penalty := money(p.Penalty)
+		out.Penalty = &penalty
+	}
+	if p.Escrow > 0 {
+		escrow := money(p.Escrow)
+		out.Escrow = &escrow
+	}
+	if p.Premium > 0 {
+		premium := money(p.Premium)
+		out.Premium = &premium
+	}
+	return out
+}
+
+func v2() *version {
+	v := &version{
+		name: "v2",
+		loan: func(l *loan.Loan) any { return loanV2(l) },
+		schedule: func(l *loan.Loan) any {
+			out := ScheduleResponseV2{
+				LoanID: l.ID, Amortization: l.AmortizationMethod(),
+				TotalInterest: money(loan.TotalInterest(l.Schedule)), Installments: make([]InstallmentV2, 0, len(l.Schedule)),
+			}
+			for _, i := range l.Schedule {
+				inst := InstallmentV2{
+					Number: i.Number, DueDate: i.DueDate, Principal: money(i.Principal),
+					Interest: money(i.Interest), Amount: money(i.Amount), Paid: money(i.Paid),
+				}
+				if i.Escrow > 0 {
+					escrow := money(i.Escrow)
+					inst.Escrow = &escrow
+				}
+				if i.Premium > 0 {
+					premium := money(i.Premium)
+					inst.Premium = &premium
+				}
+				out.Installments = append(out.Installments, inst)
+			}
+			return out
+		},
+		payment: func(p loan.Payment) any { return paymentV2(p) },
+		payments: func(l *loan.Loan) any {
+			out := PaymentsResponseV2{LoanID: l.ID, Payments: make([]PaymentV2, 0, len(l.Payments))}
+			for _, p := range l.Payments {
+				out.Payments = append(out.Payments, paymentV2(p))
+			}
+			return out
+		},
+		list: func(loans []*loan.Loan, next string) any {
+			out := ListResponseV2{Loans: make([]LoanV2, 0, len(loans)), NextCursor: next}
+			for _, l := range loans {
+				out.Loans = append(out.Loans, loanV2(l))
+			}
+			return out
+		},
+		decodeApplication: func(w http.ResponseWriter, r *http.Request) (ApplicationRequest, error) {
+			var req ApplicationRequestV2
+			if err := decodeJSON(w, r, &req); err != nil {
+				return ApplicationRequest{}, err
+			}
+			amount, err := req.Amount.value("amount")
+			return ApplicationRequest{
+				CustomerID: req.CustomerID, Amount: amount,
+				InterestRate: req.InterestRate, TermMonths: req.TermMonths, Product: req.Product,
+				Purpose: req.Purpose, Frequency: req.Frequency,
+			}, err
+		},
+		decodePayment: func(w http.ResponseWriter, r *http.Request) (PaymentRequest, error) {
+			var req PaymentRequestV2
+			if err := decodeJSON(w, r, &req); err != nil {
+				return PaymentRequest{}, err
+			}
+			amount, err := req.Amount.value("amount")
+			return PaymentRequest{Amount: amount}, err
+		},
+	}
+	v.types.application, v.types.paymentRequest = ApplicationRequestV2{}, PaymentRequestV2{}
+	v.types.loan, v.types.schedule, v.types.payment = LoanV2{}, ScheduleResponseV2{}, PaymentV2{}
+	v.types.payments, v.types.list = PaymentsResponseV2{}, ListResponseV2{}
+	return v
+}
+
+// deprecate marks responses from a superseded version. RFC 8594 Sunset is
+// only sent when a retirement date has been announced.
+func deprecate(next http.Handler, successor func(path string) string, sunset time.Time) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		if !sunset.IsZero() {
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor(r.URL.Path)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func orEmpty[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
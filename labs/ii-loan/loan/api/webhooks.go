@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"loan"
+	"loan/webhook"
+)
+
+// WithWebhooks lets clients subscribe endpoints to the events delivered by
+// hooks. Without it the webhook endpoints answer 501.
+func WithWebhooks(hooks *webhook.WebhookService) Option {
+	return func(h *Handler) { h.webhooks = hooks }
+}
+
+// WebhookRequest is the body of POST /webhooks
+type WebhookRequest struct {
+	URL string `json:"url"`
+	// Secret signs every delivery; it is not returned
+	Secret string `json:"secret"`
+	// EventTypes limits the deliveries to these events, all when empty
+	EventTypes []loan.EventType `json:"eventTypes,omitempty"`
+}
+
+// WebhooksResponse is returned by GET /webhooks
+type WebhooksResponse struct {
+	Webhooks []webhook.Subscription `json:"webhooks"`
+}
+
+var errNoWebhooks = &requestError{status: http.StatusNotImplemented, detail: ErrorDetail{
+	Code: "not_configured", Message: "webhooks are not configured",
+}}
+
+// webhookError maps the webhook package's errors onto the API's
+func webhookError(err error) error {
+	switch {
+	case errors.Is(err, webhook.ErrInvalidEndpoint):
+		return invalidFields(map[string]string{"url": "must be an absolute http or https URL"})
+	case errors.Is(err, webhook.ErrSecretRequired):
+		return invalidFields(map[string]string{"secret": "is required"})
+	case errors.Is(err, webhook.ErrSubscriptionNotFound):
+		return &requestError{status: http.StatusNotFound, detail: ErrorDetail{Code: "not_found", Message: err.Error()}}
+	}
+	return err
+}
+
+func (h *Handler) registerWebhook(w http.ResponseWriter, r *http.Request) {
+	if h.webhooks == nil {
+		writeError(w, errNoWebhooks)
+		return
+	}
+	var req WebhookRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	sub, err := h.webhooks.Register(strings.TrimSpace(req.URL), req.Secret, req.EventTypes...)
+	if err != nil {
+		writeError(w, webhookError(err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+func (h *Handler) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	if h.webhooks == nil {
+		writeError(w, errNoWebhooks)
+		return
+	}
+	subs := h.webhooks.Subscriptions()
+	slices.SortFunc(subs, func(a, b webhook.Subscription) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	writeJSON(w, http.StatusOK, WebhooksResponse{Webhooks: subs})
+}
+
+func (h *Handler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if h.webhooks == nil {
+		writeError(w, errNoWebhooks)
+		return
+	}
+	if err := h.webhooks.Unregister(r.PathValue("id")); err != nil {
+		writeError(w, webhookError(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The server side of RFC 6455 the event streams need: text messages out,
+// pings answered and closes honoured in. Fragmented and binary messages
+// from clients are read and discarded.
+
+// wsGUID is appended to the client's key to derive the accept key
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xa
+)
+
+// WebSocket close codes
+const (
+	wsCloseNormal = 1000
+	// wsCloseTryAgain tells the client to reconnect later
+	wsCloseTryAgain = 1013
+)
+
+// wsMaxFrame bounds the frames read from clients, which only send
+// control frames
+const wsMaxFrame = 1 << 16
+
+// errBadFrame is returned for frames a client may not send
+var errBadFrame = errors.New("websocket: unmasked or oversized frame")
+
+// wsWriteTimeout bounds each frame written
+const wsWriteTimeout = 10 * time.Second
+
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	// mu serializes writes, which come from the stream and from the
+	// reader answering pings
+	mu sync.Mutex
+	// closeSent is set once a close frame went out; nothing may follow it
+	closeSent bool
+}
+
+// acceptWebSocket completes the opening handshake of r, or answers it with
+// an error and returns nil
+func acceptWebSocket(w http.ResponseWriter, r *http.Request) *wsConn {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerHas(r.Header, "Connection", "upgrade") || r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeError(w, badRequest("invalid_upgrade", "a WebSocket upgrade needs Connection: Upgrade, Sec-WebSocket-Version: 13 and a Sec-WebSocket-Key"))
+		return nil
+	}
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		writeError(w, err)
+		return nil
+	}
+	conn.SetDeadline(time.Time{})
+	sum := sha1.Sum([]byte(key + wsGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil
+	}
+	return &wsConn{conn: conn, rw: rw}
+}
+
+// headerHas reports whether the comma-separated header name lists token
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// write sends one unfragmented frame
+func (c *wsConn) write(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	c.closeSent = op == wsClose
+	header := []byte{0x80 | op, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	c.rw.Write(header)
+	c.rw.Write(payload)
+	return c.rw.Flush()
+}
+
+// close sends a close frame; the connection is closed by Close
+func (c *wsConn) close(code uint16, reason string) {
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	c.write(wsClose, append(payload, reason...))
+}
+
+func (c *wsConn) Close() error { return c.conn.Close() }
+
+// readUntilClose reads frames, answering pings, until the client closes
+// the connection or a read fails
+func (c *wsConn) readUntilClose() {
+	for {
+		op, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch op {
+		case wsPing:
+			if c.write(wsPong, payload) != nil {
+				return
+			}
+		case wsClose:
+			if len(payload) >= 2 {
+				c.write(wsClose, payload[:2])
+			} else {
+				c.write(wsClose, nil)
+			}
+			return
+		}
+	}
+}
+
+// readFrame reads one masked client frame and unmasks its payload
+func (c *wsConn) readFrame() (op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.rw, head[:]); err != nil {
+		return 0, nil, err
+	}
+	op = head[0] & 0x0f
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if head[1]&0x80 == 0 || n > wsMaxFrame {
+		return 0, nil, errBadFrame
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
@@ -0,0 +1,129 @@
+// Package bankverify supports loan.BankVerification: a cache that keeps
+// each account's result so repeated payouts do not repeat the penny drop,
+// and a fake bank for labs and development.
+package bankverify
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"loan"
+)
+
+// DefaultTTL is how long a verification result is reused
+const DefaultTTL = 24 * time.Hour
+
+// Cache reuses verification results per account and payee name. Results
+// are cached whether or not the account verified, since both are answers
+// from the bank; errors are not.
+type Cache struct {
+	next loan.BankVerification
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	results map[string]cached
+}
+
+type cached struct {
+	result  loan.AccountVerification
+	expires time.Time
+}
+
+// CacheOption configures a Cache
+type CacheOption func(*Cache)
+
+// WithTTL sets how long results are reused instead of DefaultTTL
+func WithTTL(d time.Duration) CacheOption {
+	return func(c *Cache) { c.ttl = d }
+}
+
+// NewCache wraps next with a result cache
+func NewCache(next loan.BankVerification, opts ...CacheOption) *Cache {
+	c := &Cache{next: next, ttl: DefaultTTL, now: time.Now, results: make(map[string]cached)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// VerifyAccount implements loan.BankVerification
+func (c *Cache) VerifyAccount(ctx context.Context, payee loan.Payee) (loan.AccountVerification, error) {
+	key := payee.Account + "\x00" + NormalizeName(payee.Name)
+	now := c.now()
+	c.mu.Lock()
+	hit, ok := c.results[key]
+	c.mu.Unlock()
+	if ok && now.Before(hit.expires) {
+		return hit.result, nil
+	}
+
+	v, err := c.next.VerifyAccount(ctx, payee)
+	if err != nil {
+		return loan.AccountVerification{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.results {
+		if !now.Before(e.expires) {
+			delete(c.results, k)
+		}
+	}
+	c.results[key] = cached{result: v, expires: now.Add(c.ttl)}
+	return v, nil
+}
+
+// Forget drops the cached results of account, for example after the
+// customer reports it closed
+func (c *Cache) Forget(account string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.results {
+		if strings.HasPrefix(k, account+"\x00") {
+			delete(c.results, k)
+		}
+	}
+}
+
+// honorifics are ignored when comparing names
+var honorifics = map[string]bool{
+	"MR": true, "MRS": true, "MS": true, "MISS": true, "DR": true,
+	"NAI": true, "NANG": true, "NANGSAO": true,
+}
+
+// NormalizeName upper-cases name, drops punctuation and honorifics and
+// collapses whitespace
+func NormalizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '.' || r == ',' || r == '-' || r == '\'' {
+			return ' '
+		}
+		return r
+	}, strings.ToUpper(name))
+	words := strings.Fields(name)
+	out := words[:0]
+	for _, w := range words {
+		if !honorifics[w] {
+			out = append(out, w)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// MatchName grades a payee name against the bank's account holder name:
+// exact when the normalized names are equal, partial when they share the
+// first and last word (a missing middle name, say) and none otherwise
+func MatchName(holder, payee string) loan.NameMatch {
+	h, p := strings.Fields(NormalizeName(holder)), strings.Fields(NormalizeName(payee))
+	switch {
+	case len(h) == 0 || len(p) == 0:
+		return loan.NameNone
+	case strings.Join(h, " ") == strings.Join(p, " "):
+		return loan.NameExact
+	case h[0] == p[0] && h[len(h)-1] == p[len(p)-1]:
+		return loan.NamePartial
+	}
+	return loan.NameNone
+}
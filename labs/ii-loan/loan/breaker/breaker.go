@@ -0,0 +1,265 @@
+// Package breaker isolates the loan service from failing external
+// providers. A Breaker counts consecutive failures of the calls made
+// through it; past a threshold it opens and fails calls immediately with
+// ErrOpen instead of piling more load onto a struggling dependency. After a
+// cool-down it lets a few trial calls through (half-open) and closes again
+// once they succeed.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ErrOpen is returned without calling the provider while the breaker is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the position of a breaker
+type State int
+
+// Breaker states
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Metrics is a snapshot of a breaker's counters
+type Metrics struct {
+	State       State
+	Successes   uint64
+	Failures    uint64
+	Rejected    uint64 // calls refused while open
+	Transitions uint64
+	OpenedAt    time.Time // zero unless open
+}
+
+// Breaker guards calls to one provider. It is safe for concurrent use.
+type Breaker struct {
+	name          string
+	threshold     int
+	openTimeout   time.Duration
+	halfOpenCalls int
+	isFailure     func(error) bool
+	onChange      func(name string, from, to State)
+	now           func() time.Time
+
+	calls       metric.Int64Counter
+	transitions metric.Int64Counter
+
+	mu          sync.Mutex
+	state       State
+	consecutive int    // failures while closed, successes while half-open
+	inFlight    int    // trial calls admitted while half-open
+	generation  uint64 // bumped on every transition
+	metrics     Metrics
+}
+
+// Option configures a Breaker
+type Option func(*Breaker)
+
+// WithThreshold sets how many consecutive failures open the breaker (default 5)
+func WithThreshold(n int) Option {
+	return func(b *Breaker) { b.threshold = n }
+}
+
+// WithOpenTimeout sets how long the breaker stays open before trying again
+// (default 30s)
+func WithOpenTimeout(d time.Duration) Option {
+	return func(b *Breaker) { b.openTimeout = d }
+}
+
+// WithHalfOpenCalls sets how many trial calls must succeed to close the
+// breaker again; only that many run concurrently while half-open (default 1)
+func WithHalfOpenCalls(n int) Option {
+	return func(b *Breaker) { b.halfOpenCalls = n }
+}
+
+// WithFailurePredicate decides which errors count against the provider.
+// By default every error does except cancellation by the caller.
+func WithFailurePredicate(f func(error) bool) Option {
+	return func(b *Breaker) { b.isFailure = f }
+}
+
+// WithStateChange registers a callback run on every transition, e.g. to
+// log it. It is called with the breaker's lock held and must not call back
+// into the breaker.
+func WithStateChange(f func(name string, from, to State)) Option {
+	return func(b *Breaker) { b.onChange = f }
+}
+
+func defaultIsFailure(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
+
+// New creates a closed breaker. name identifies the provider in metrics.
+// Counters are recorded through the global OpenTelemetry meter provider as
+// breaker.calls and breaker.transitions.
+func New(name string, opts ...Option) *Breaker {
+	b := &Breaker{
+		name:          name,
+		threshold:     5,
+		openTimeout:   30 * time.Second,
+		halfOpenCalls: 1,
+		isFailure:     defaultIsFailure,
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	meter := otel.Meter("loan/breaker")
+	b.calls, _ = meter.Int64Counter("breaker.calls", metric.WithDescription("calls through a circuit breaker by result"))
+	b.transitions, _ = meter.Int64Counter("breaker.transitions", metric.WithDescription("circuit breaker state changes"))
+	return b
+}
+
+// Name returns the provider name
+func (b *Breaker) Name() string { return b.name }
+
+// State returns the current state, moving an expired open breaker to half-open
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire()
+	return b.state
+}
+
+// Metrics returns a snapshot of the counters
+func (b *Breaker) Metrics() Metrics {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire()
+	m := b.metrics
+	m.State = b.state
+	return m
+}
+
+// Do runs fn unless the breaker is open and records its outcome
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	gen, err := b.admit(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn(ctx)
+	b.record(ctx, err, gen)
+	return err
+}
+
+// Call is Do for functions returning a value
+func Call[T any](ctx context.Context, b *Breaker, fn func(ctx context.Context) (T, error)) (T, error) {
+	var out T
+	err := b.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = fn(ctx)
+		return err
+	})
+	return out, err
+}
+
+// admit decides whether a call may run and returns the generation it was
+// admitted in
+func (b *Breaker) admit(ctx context.Context) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire()
+	switch {
+	case b.state == Open, b.state == HalfOpen && b.inFlight >= b.halfOpenCalls:
+		b.metrics.Rejected++
+		b.count(ctx, "rejected")
+		return 0, fmt.Errorf("%s: %w", b.name, ErrOpen)
+	case b.state == HalfOpen:
+		b.inFlight++
+	}
+	return b.generation, nil
+}
+
+// record applies a call's outcome. Results of calls admitted before the
+// last transition only update the counters, so a slow call started while
+// closed cannot decide a half-open trial.
+func (b *Breaker) record(ctx context.Context, err error, gen uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	failed := err != nil && b.isFailure(err)
+	if failed {
+		b.metrics.Failures++
+		b.count(ctx, "failure")
+	} else {
+		b.metrics.Successes++
+		b.count(ctx, "success")
+	}
+
+	if gen != b.generation {
+		return
+	}
+	switch b.state {
+	case HalfOpen:
+		b.inFlight--
+		if failed {
+			b.transition(ctx, Open)
+			return
+		}
+		b.consecutive++
+		if b.consecutive >= b.halfOpenCalls {
+			b.transition(ctx, Closed)
+		}
+	case Closed:
+		if !failed {
+			b.consecutive = 0
+			return
+		}
+		b.consecutive++
+		if b.consecutive >= b.threshold {
+			b.transition(ctx, Open)
+		}
+	}
+}
+
+// expire moves an open breaker whose timeout elapsed to half-open
+func (b *Breaker) expire() {
+	if b.state == Open && !b.now().Before(b.metrics.OpenedAt.Add(b.openTimeout)) {
+		b.transition(context.Background(), HalfOpen)
+	}
+}
+
+func (b *Breaker) transition(ctx context.Context, to State) {
+	from := b.state
+	b.state = to
+	b.consecutive = 0
+	b.generation++
+	b.metrics.Transitions++
+	if to == Open {
+		b.metrics.OpenedAt = b.now()
+	} else {
+		b.metrics.OpenedAt = time.Time{}
+	}
+	b.inFlight = 0
+	b.transitions.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("breaker", b.name), attribute.String("from", from.String()), attribute.String("to", to.String()),
+	))
+	if b.onChange != nil {
+		b.onChange(b.name, from, to)
+	}
+}
+
+func (b *Breaker) count(ctx context.Context, result string) {
+	b.calls.Add(ctx, 1, metric.WithAttributes(attribute.String("breaker", b.name), attribute.String("result", result)))
+}
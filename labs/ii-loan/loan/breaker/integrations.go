@@ -0,0 +1,67 @@
+package breaker
+
+import (
+	"context"
+
+	"loan"
+	"loan/cloudevents"
+	"loan/notification/email"
+	"loan/sms"
+)
+
+// The wrappers below put a breaker in front of each outbound integration
+// interface. Construct one Breaker per provider and keep it for the life
+// of the process; wrapping the same provider twice splits its counters.
+
+// CreditBureau guards a credit bureau
+func CreditBureau(b *Breaker, next loan.CreditBureau) loan.CreditBureau {
+	return loan.CreditBureauFunc(func(ctx context.Context, customerID string) (loan.CreditReport, error) {
+		return Call(ctx, b, func(ctx context.Context) (loan.CreditReport, error) {
+			return next.CreditReport(ctx, customerID)
+		})
+	})
+}
+
+type smsSender struct {
+	b    *Breaker
+	next sms.SMSSender
+}
+
+func (s smsSender) SendSMS(ctx context.Context, to, body string) (string, error) {
+	return Call(ctx, s.b, func(ctx context.Context) (string, error) {
+		return s.next.SendSMS(ctx, to, body)
+	})
+}
+
+// SMSSender guards an SMS gateway
+func SMSSender(b *Breaker, next sms.SMSSender) sms.SMSSender {
+	return smsSender{b, next}
+}
+
+type emailSender struct {
+	b    *Breaker
+	next email.Sender
+}
+
+func (s emailSender) Send(ctx context.Context, msg email.Message) error {
+	return s.b.Do(ctx, func(ctx context.Context) error { return s.next.Send(ctx, msg) })
+}
+
+// EmailSender guards a mail server
+func EmailSender(b *Breaker, next email.Sender) email.Sender {
+	return emailSender{b, next}
+}
+
+type eventSender struct {
+	b    *Breaker
+	next cloudevents.Sender
+}
+
+func (s eventSender) Send(ctx context.Context, msg cloudevents.Message) error {
+	return s.b.Do(ctx, func(ctx context.Context) error { return s.next.Send(ctx, msg) })
+}
+
+// EventSender guards the transport CloudEvents are published over
+func EventSender(b *Breaker, next cloudevents.Sender) cloudevents.Sender {
+	return eventSender{b, next}
+}
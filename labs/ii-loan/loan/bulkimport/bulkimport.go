@@ -0,0 +1,265 @@
+// Package bulkimport loads loan applications from CSV files. Every row is
+// validated on its own and valid rows go through the normal application
+// pipeline, so one bad row does not hold up the rest of the file; the
+// report says what happened to each.
+//
+// The file starts with a header naming its columns in any order:
+//
+//	customer_id,amount,term_months,interest_rate,product,purpose,frequency
+//	C-1001,25000,24,0.12,PL,home_improvement,biweekly
+//
+// customer_id, amount and term_months are required; interest_rate,
+// product, purpose and frequency may be left out.
+package bulkimport
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"loan"
+)
+
+// Row outcomes
+const (
+	Created = "created"
+	Invalid = "invalid"
+	Failed  = "failed"
+)
+
+// DefaultMaxRows bounds the applications in one file
+const DefaultMaxRows = 10000
+
+// Processor submits one application; *loan.LoanService implements it
+type Processor interface {
+	ProcessLoanApplication(ctx context.Context, l *loan.Loan) error
+}
+
+// RowResult is the outcome of one data row
+type RowResult struct {
+	// Line is the line of the file the row starts on, the header being 1
+	Line   int    `json:"line"`
+	Status string `json:"status"`
+	LoanID string `json:"loanId,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Fields maps columns to their problem for invalid rows
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Summary counts the rows by outcome
+type Summary struct {
+	Total   int `json:"total"`
+	Created int `json:"created"`
+	Invalid int `json:"invalid"`
+	Failed  int `json:"failed"`
+}
+
+// Report is the result of an import
+type Report struct {
+	Summary Summary     `json:"summary"`
+	Rows    []RowResult `json:"rows"`
+	// Truncated is set when the file held more rows than the importer
+	// accepts; the rows past the limit were not read
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+func (r *Report) add(res RowResult) {
+	r.Rows = append(r.Rows, res)
+	r.Summary.Total++
+	switch res.Status {
+	case Created:
+		r.Summary.Created++
+	case Invalid:
+		r.Summary.Invalid++
+	case Failed:
+		r.Summary.Failed++
+	}
+}
+
+// Importer reads application files
+type Importer struct {
+	svc     Processor
+	maxRows int
+}
+
+// Option configures an Importer
+type Option func(*Importer)
+
+// WithMaxRows changes how many data rows a file may hold (default
+// DefaultMaxRows)
+func WithMaxRows(n int) Option {
+	return func(im *Importer) { im.maxRows = n }
+}
+
+// New creates an importer submitting applications to svc
+func New(svc Processor, opts ...Option) *Importer {
+	im := &Importer{svc: svc, maxRows: DefaultMaxRows}
+	for _, opt := range opts {
+		opt(im)
+	}
+	return im
+}
+
+// Columns of an application file
+const (
+	ColCustomerID   = "customer_id"
+	ColAmount       = "amount"
+	ColTermMonths   = "term_months"
+	ColInterestRate = "interest_rate"
+	ColProduct      = "product"
+	ColPurpose      = "purpose"
+	ColFrequency    = "frequency"
+)
+
+var required = []string{ColCustomerID, ColAmount, ColTermMonths}
+
+// canonical maps header spellings, compared without case or underscores,
+// to their column
+var canonical = map[string]string{
+	"customerid":   ColCustomerID,
+	"amount":       ColAmount,
+	"termmonths":   ColTermMonths,
+	"interestrate": ColInterestRate,
+	"product":      ColProduct,
+	"purpose":      ColPurpose,
+	"frequency":    ColFrequency,
+}
+
+// HeaderError reports a file whose header cannot be used; no row of it was
+// imported
+type HeaderError struct {
+	Message string
+}
+
+func (e *HeaderError) Error() string { return "bulkimport: " + e.Message }
+
+func readHeader(cr *csv.Reader) (map[string]int, error) {
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &HeaderError{Message: "file is empty"}
+	}
+	if err != nil {
+		return nil, &HeaderError{Message: err.Error()}
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))), "_", "")
+		col, ok := canonical[key]
+		if !ok {
+			return nil, &HeaderError{Message: fmt.Sprintf("unknown column %q", name)}
+		}
+		if _, dup := cols[col]; dup {
+			return nil, &HeaderError{Message: fmt.Sprintf("duplicate column %q", name)}
+		}
+		cols[col] = i
+	}
+	for _, col := range required {
+		if _, ok := cols[col]; !ok {
+			return nil, &HeaderError{Message: fmt.Sprintf("missing column %q", col)}
+		}
+	}
+	return cols, nil
+}
+
+// Import reads the file and submits every valid row. The error is non-nil
+// only when the file as a whole could not be processed: a *HeaderError, a
+// read failure or a cancelled context. The report then
+// covers the rows handled before it happened.
+func (im *Importer) Import(ctx context.Context, r io.Reader) (Report, error) {
+	report := Report{Rows: []RowResult{}}
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cols, err := readHeader(cr)
+	if err != nil {
+		return report, err
+	}
+	cr.FieldsPerRecord = len(cols)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if report.Summary.Total == im.maxRows {
+			report.Truncated = true
+			return report, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			report.add(RowResult{Line: parseErr.StartLine, Status: Invalid, Error: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		line, _ := cr.FieldPos(0)
+		report.add(im.row(ctx, line, cols, record))
+	}
+}
+
+// row validates and submits one record
+func (im *Importer) row(ctx context.Context, line int, cols map[string]int, record []string) RowResult {
+	l, fields := parse(cols, record)
+	if fields != nil {
+		return RowResult{Line: line, Status: Invalid, Error: "row validation failed", Fields: fields}
+	}
+	if err := im.svc.ProcessLoanApplication(ctx, l); err != nil {
+		var valErr *loan.ValidationError
+		if errors.As(err, &valErr) {
+			return RowResult{Line: line, Status: Invalid, Error: valErr.Message, Fields: map[string]string{valErr.Field: valErr.Message}}
+		}
+		return RowResult{Line: line, Status: Failed, Error: err.Error()}
+	}
+	return RowResult{Line: line, Status: Created, LoanID: l.ID}
+}
+
+// parse turns a record into an application, applying the same rules as
+// the REST API, or returns the problem of every invalid column
+func parse(cols map[string]int, record []string) (*loan.Loan, map[string]string) {
+	get := func(col string) string {
+		if i, ok := cols[col]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	fields := map[string]string{}
+	l := &loan.Loan{CustomerID: get(ColCustomerID), Product: get(ColProduct), Purpose: get(ColPurpose), Frequency: get(ColFrequency)}
+	if l.CustomerID == "" {
+		fields[ColCustomerID] = "is required"
+	}
+	if l.Purpose != "" && !loan.ValidPurpose(l.Purpose) {
+		fields[ColPurpose] = "must be one of " + strings.Join(loan.Purposes, ", ")
+	}
+	if l.Frequency != "" && !loan.ValidFrequency(l.Frequency) {
+		fields[ColFrequency] = "must be one of " + strings.Join(loan.Frequencies, ", ")
+	}
+	if v, err := strconv.ParseFloat(get(ColAmount), 64); err != nil || v <= 0 || math.IsInf(v, 0) {
+		fields[ColAmount] = "must be a positive number"
+	} else {
+		l.Amount = v
+	}
+	if s := get(ColInterestRate); s != "" {
+		if v, err := strconv.ParseFloat(s, 64); err != nil || v < 0 || v > 1 {
+			fields[ColInterestRate] = "must be an annual rate between 0 and 1"
+		} else {
+			l.InterestRate = v
+		}
+	}
+	if v, err := strconv.Atoi(get(ColTermMonths)); err != nil || v < 1 || v > 360 {
+		fields[ColTermMonths] = "must be between 1 and 360"
+	} else {
+		l.TermMonths = v
+	}
+	if len(fields) > 0 {
+		return nil, fields
+	}
+	return l, nil
+}
@@ -0,0 +1,115 @@
+package loan
+
+import (
+	"context"
+	"time"
+)
+
+// CreditReport is what a credit bureau returns for a customer. Bureaus
+// that only return a score leave the file details empty.
+type CreditReport struct {
+	CustomerID    string        `json:"customerId"`
+	Bureau        string        `json:"bureau"`
+	Score         int           `json:"score"`
+	RetrievedAt   time.Time     `json:"retrievedAt"`
+	TradeLines    []TradeLine   `json:"tradeLines,omitempty"`
+	Delinquencies []Delinquency `json:"delinquencies,omitempty"`
+	Inquiries     []Inquiry     `json:"inquiries,omitempty"`
+}
+
+// TradeLine is a credit account the customer holds with any lender
+type TradeLine struct {
+	Account string `json:"account"`
+	// Type is the bureau's account type, such as "card" or "personal"
+	Type           string    `json:"type"`
+	Opened         time.Time `json:"opened"`
+	Closed         time.Time `json:"closed"`
+	Limit          float64   `json:"limit"`
+	Balance        float64   `json:"balance"`
+	MonthlyPayment float64   `json:"monthlyPayment"`
+}
+
+// Open reports whether the account has not been closed
+func (t TradeLine) Open() bool {
+	return t.Closed.IsZero()
+}
+
+// Delinquency is a late payment reported on a trade line
+type Delinquency struct {
+	Account     string    `json:"account"`
+	ReportedAt  time.Time `json:"reportedAt"`
+	DaysPastDue int       `json:"daysPastDue"`
+	Amount      float64   `json:"amount"`
+}
+
+// Inquiry is a lender looking at the customer's file
+type Inquiry struct {
+	At      time.Time `json:"at"`
+	Member  string    `json:"member"`
+	Purpose string    `json:"purpose"`
+}
+
+// Look-back windows of BureauSummary
+const (
+	DelinquencyWindowMonths = 24
+	InquiryWindowMonths     = 6
+)
+
+// BureauSummary condenses a credit file into the figures the risk engine
+// uses
+type BureauSummary struct {
+	OpenTradeLines     int     `json:"openTradeLines"`
+	TotalBalance       float64 `json:"totalBalance"`
+	TotalLimit         float64 `json:"totalLimit"`
+	MonthlyObligations float64 `json:"monthlyObligations"`
+	// Delinquencies counts late payments of 30 days or more in the last
+	// DelinquencyWindowMonths
+	Delinquencies    int `json:"delinquencies"`
+	WorstDaysPastDue int `json:"worstDaysPastDue"`
+	// RecentInquiries counts inquiries in the last InquiryWindowMonths
+	RecentInquiries int `json:"recentInquiries"`
+}
+
+// Summary condenses the report as of asOf
+func (r CreditReport) Summary(asOf time.Time) BureauSummary {
+	var s BureauSummary
+	for _, t := range r.TradeLines {
+		if !t.Open() {
+			continue
+		}
+		s.OpenTradeLines++
+		s.TotalBalance += t.Balance
+		s.TotalLimit += t.Limit
+		s.MonthlyObligations += t.MonthlyPayment
+	}
+	since := asOf.AddDate(0, -DelinquencyWindowMonths, 0)
+	for _, d := range r.Delinquencies {
+		if d.DaysPastDue < 30 || d.ReportedAt.Before(since) {
+			continue
+		}
+		s.Delinquencies++
+		s.WorstDaysPastDue = max(s.WorstDaysPastDue, d.DaysPastDue)
+	}
+	since = asOf.AddDate(0, -InquiryWindowMonths, 0)
+	for _, q := range r.Inquiries {
+		if !q.At.Before(since) {
+			s.RecentInquiries++
+		}
+	}
+	s.TotalBalance, s.TotalLimit, s.MonthlyObligations = round2(s.TotalBalance), round2(s.TotalLimit), round2(s.MonthlyObligations)
+	return s
+}
+
+// CreditBureau fetches credit reports. Implementations call external
+// services and must honour ctx cancellation.
+type CreditBureau interface {
+	CreditReport(ctx context.Context, customerID string) (CreditReport, error)
+}
+
+// CreditBureauFunc adapts a function to CreditBureau
+type CreditBureauFunc func(ctx context.Context, customerID string) (CreditReport, error)
+
+// CreditReport implements CreditBureau
+func (f CreditBureauFunc) CreditReport(ctx context.Context, customerID string) (CreditReport, error) {
+	return f(ctx, customerID)
+}
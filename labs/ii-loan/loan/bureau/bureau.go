@@ -0,0 +1,111 @@
+// Package bureau implements loan.CreditBureau against a credit bureau's
+// HTTP API.
+package bureau
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"loan"
+	"loan/retry"
+	"loan/tracing"
+)
+
+// ErrNoFile is returned when the bureau holds no file for the customer
+var ErrNoFile = errors.New("bureau: no credit file for customer")
+
+// Client calls a bureau exposing GET {base}/v1/reports/{customerID}. The
+// bureau answers with a JSON score or with the full file in the report
+// format read by ParseReport.
+type Client struct {
+	base   string
+	name   string
+	apiKey string
+	client *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient overrides the HTTP client. Keep tracing.Transport in its
+// transport chain to propagate the trace to the bureau.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) { cl.client = c }
+}
+
+// WithName sets the bureau name recorded on reports
+func WithName(name string) Option {
+	return func(cl *Client) { cl.name = name }
+}
+
+// WithAPIKey authenticates requests with key as a bearer token
+func WithAPIKey(key string) Option {
+	return func(cl *Client) { cl.apiKey = key }
+}
+
+// NewClient creates a client for the bureau at baseURL
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		base:   strings.TrimRight(baseURL, "/"),
+		name:   "bureau",
+		client: &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type reportResponse struct {
+	Score int `json:"score"`
+}
+
+// CreditReport implements loan.CreditBureau
+func (c *Client) CreditReport(ctx context.Context, customerID string) (loan.CreditReport, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/v1/reports/"+url.PathEscape(customerID), nil)
+	if err != nil {
+		return loan.CreditReport{}, err
+	}
+	req.Header.Set("Accept", "application/json, "+ReportContentType)
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return loan.CreditReport{}, fmt.Errorf("bureau: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return loan.CreditReport{}, ErrNoFile
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= http.StatusInternalServerError:
+		return loan.CreditReport{}, fmt.Errorf("bureau: status %s: %w", resp.Status, retry.ErrTransient)
+	case resp.StatusCode != http.StatusOK:
+		return loan.CreditReport{}, fmt.Errorf("bureau: unexpected status %s", resp.Status)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == ReportContentType {
+		rep, err := ParseReport(resp.Body)
+		if err != nil {
+			return loan.CreditReport{}, err
+		}
+		rep.CustomerID, rep.Bureau, rep.RetrievedAt = customerID, c.name, time.Now().UTC()
+		return rep, nil
+	}
+	var body reportResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return loan.CreditReport{}, fmt.Errorf("bureau: decoding report: %w", err)
+	}
+	return loan.CreditReport{
+		CustomerID:  customerID,
+		Bureau:      c.name,
+		Score:       body.Score,
+		RetrievedAt: time.Now().UTC(),
+	}, nil
+}
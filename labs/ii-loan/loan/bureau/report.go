@@ -0,0 +1,166 @@
+package bureau
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"loan"
+)
+
+// ReportContentType is the media type of the bureau's file format
+const ReportContentType = "text/x-bureau-report"
+
+// The report format is line based with pipe-separated fields. Blank lines
+// and lines starting with # are ignored.
+//
+//	HDR|LBR1|<bureau>|<customer>|<report date>|<score>
+//	TL|<account>|<type>|<opened>|<closed>|<limit>|<balance>|<monthly payment>
+//	DQ|<account>|<reported>|<days past due>|<amount>
+//	IQ|<date>|<member>|<purpose>
+//	TRL|<number of TL, DQ and IQ records>
+//
+// Dates are YYYY-MM-DD; an empty closed date means the account is open.
+// Delinquencies must refer to a trade line of the file.
+const formatVersion = "LBR1"
+
+// ParseError reports a malformed report line
+type ParseError struct {
+	Line    int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("bureau: report line %d: %s", e.Line, e.Message)
+}
+
+// recordFields is the number of fields of each record type
+var recordFields = map[string]int{"HDR": 6, "TL": 8, "DQ": 5, "IQ": 4, "TRL": 2}
+
+// ParseReport reads a credit file in the bureau's report format
+func ParseReport(r io.Reader) (loan.CreditReport, error) {
+	var (
+		rep      loan.CreditReport
+		p        parser
+		header   bool
+		trailer  bool
+		records  int
+		accounts = map[string]bool{}
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		p.line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		f := strings.Split(text, "|")
+		want, known := recordFields[f[0]]
+		switch {
+		case !known:
+			return loan.CreditReport{}, p.fail("unknown record type %q", f[0])
+		case len(f) != want:
+			return loan.CreditReport{}, p.fail("%s record has %d fields, want %d", f[0], len(f), want)
+		case trailer:
+			return loan.CreditReport{}, p.fail("record after trailer")
+		case !header && f[0] != "HDR":
+			return loan.CreditReport{}, p.fail("file must start with a HDR record")
+		case header && f[0] == "HDR":
+			return loan.CreditReport{}, p.fail("duplicate HDR record")
+		}
+
+		switch f[0] {
+		case "HDR":
+			header = true
+			if f[1] != formatVersion {
+				return loan.CreditReport{}, p.fail("unsupported format %q, want %s", f[1], formatVersion)
+			}
+			rep.Bureau, rep.CustomerID = f[2], f[3]
+			rep.RetrievedAt = p.date(f[4], "report date")
+			rep.Score = p.int(f[5], "score")
+		case "TL":
+			records++
+			t := loan.TradeLine{
+				Account: f[1], Type: f[2], Opened: p.date(f[3], "opened"),
+				Limit: p.amount(f[5], "limit"), Balance: p.amount(f[6], "balance"), MonthlyPayment: p.amount(f[7], "monthly payment"),
+			}
+			if f[4] != "" {
+				t.Closed = p.date(f[4], "closed")
+			}
+			if accounts[t.Account] {
+				return loan.CreditReport{}, p.fail("duplicate trade line %q", t.Account)
+			}
+			accounts[t.Account] = true
+			rep.TradeLines = append(rep.TradeLines, t)
+		case "DQ":
+			records++
+			if !accounts[f[1]] {
+				return loan.CreditReport{}, p.fail("delinquency on unknown trade line %q", f[1])
+			}
+			rep.Delinquencies = append(rep.Delinquencies, loan.Delinquency{
+				Account: f[1], ReportedAt: p.date(f[2], "reported"), DaysPastDue: p.int(f[3], "days past due"), Amount: p.amount(f[4], "amount"),
+			})
+		case "IQ":
+			records++
+			rep.Inquiries = append(rep.Inquiries, loan.Inquiry{At: p.date(f[1], "date"), Member: f[2], Purpose: f[3]})
+		case "TRL":
+			trailer = true
+			if n := p.int(f[1], "record count"); p.err == nil && n != records {
+				return loan.CreditReport{}, p.fail("trailer counts %d records, file has %d", n, records)
+			}
+		}
+		if p.err != nil {
+			return loan.CreditReport{}, p.err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return loan.CreditReport{}, fmt.Errorf("bureau: reading report: %w", err)
+	}
+	if !trailer {
+		return loan.CreditReport{}, p.fail("missing TRL record")
+	}
+	return rep, nil
+}
+
+// parser converts fields, keeping the first error with its line
+type parser struct {
+	line int
+	err  error
+}
+
+func (p *parser) fail(format string, args ...any) error {
+	return &ParseError{Line: p.line, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) set(format string, args ...any) {
+	if p.err == nil {
+		p.err = p.fail(format, args...)
+	}
+}
+
+func (p *parser) date(s, field string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		p.set("%s %q is not a YYYY-MM-DD date", field, s)
+	}
+	return t
+}
+
+func (p *parser) int(s, field string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		p.set("%s %q is not a non-negative integer", field, s)
+	}
+	return n
+}
+
+func (p *parser) amount(s, field string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		p.set("%s %q is not a non-negative amount", field, s)
+	}
+	return v
+}
@@ -0,0 +1,108 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrSubscriberLagging ends a subscription that fell further behind the
+// published events than its buffer holds
+var ErrSubscriberLagging = errors.New("event subscriber fell behind")
+
+// ErrEventBusClosed ends the subscriptions of a closed bus
+var ErrEventBusClosed = errors.New("event bus closed")
+
+// EventBus is an EventPublisher fanning events out to subscribers in the
+// same process, such as the API's live event streams. Publishing never
+// waits on a subscriber: one that is not keeping up is dropped instead.
+// Subscribers must not modify the events' Data, which they share.
+type EventBus struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewEventBus creates a bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events of one loan, or of every loan
+type Subscription struct {
+	bus    *EventBus
+	loanID string
+	events chan Event
+	err    error
+}
+
+// Subscribe returns a subscription to the events of loanID, or of every
+// loan when loanID is empty, holding up to buffer events not yet received
+func (b *EventBus) Subscribe(loanID string, buffer int) *Subscription {
+	s := &Subscription{bus: b, loanID: loanID, events: make(chan Event, buffer)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[s] = struct{}{}
+	if b.closed {
+		s.err = ErrEventBusClosed
+		b.drop(s)
+	}
+	return s
+}
+
+// Close ends every subscription, and those made later at once, so
+// long-lived streams finish when a server shuts down
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		s.err = ErrEventBusClosed
+		b.drop(s)
+	}
+}
+
+// Publish implements EventPublisher
+func (b *EventBus) Publish(_ context.Context, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if s.loanID != "" && s.loanID != event.LoanID {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			s.err = ErrSubscriberLagging
+			b.drop(s)
+		}
+	}
+	return nil
+}
+
+// drop ends s; b.mu must be held
+func (b *EventBus) drop(s *Subscription) {
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.events)
+	}
+}
+
+// Events is closed when the subscription ends, by Close, because the
+// subscriber lagged or because the bus was closed
+func (s *Subscription) Events() <-chan Event { return s.events }
+
+// Err returns why the bus ended the subscription: ErrSubscriberLagging or
+// ErrEventBusClosed, and nil otherwise
+func (s *Subscription) Err() error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.drop(s)
+}
@@ -0,0 +1,128 @@
+// Package calendar tells business days from weekends and public holidays
+// and moves dates that fall on a day off by a roll convention. Holidays
+// come from pluggable sets, such as the regional ones in Regions, which
+// may be combined.
+//
+//	cal := calendar.New(calendar.Regions["TH"], calendar.Dates("Makha Bucha", makhaBucha2027))
+//	due := cal.Adjust(date, calendar.ModifiedFollowing)
+package calendar
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Convention says where a date that is not a business day moves to
+type Convention string
+
+// Roll conventions
+const (
+	// Unadjusted keeps the date
+	Unadjusted Convention = "unadjusted"
+	// Following moves to the next business day
+	Following Convention = "following"
+	// ModifiedFollowing moves to the next business day unless that is in
+	// the next month, in which case it moves to the previous one
+	ModifiedFollowing Convention = "modified-following"
+	// Preceding moves to the previous business day
+	Preceding Convention = "preceding"
+)
+
+// ParseConvention returns the convention named s
+func ParseConvention(s string) (Convention, error) {
+	switch c := Convention(s); c {
+	case Unadjusted, Following, ModifiedFollowing, Preceding:
+		return c, nil
+	}
+	return "", fmt.Errorf("calendar: unknown roll convention %q", s)
+}
+
+// Calendar is a business-day calendar: every day but Saturdays, Sundays
+// and the holidays of its sets. It is safe for concurrent use.
+type Calendar struct {
+	sets []HolidaySet
+	mu   sync.Mutex
+	// years caches the holidays of each year looked up, by day
+	years map[int]map[day]string
+}
+
+// day is a date without time or location
+type day struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func dayOf(t time.Time) day {
+	y, m, d := t.Date()
+	return day{y, m, d}
+}
+
+// New creates a calendar observing the holidays of sets
+func New(sets ...HolidaySet) *Calendar {
+	return &Calendar{sets: sets, years: make(map[int]map[day]string)}
+}
+
+// Holiday returns the name of the holiday on the date of t
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	d := dayOf(t)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	year, ok := c.years[d.year]
+	if !ok {
+		year = make(map[day]string)
+		// observed dates can cross into the neighbouring years
+		for y := d.year - 1; y <= d.year+1; y++ {
+			for _, s := range c.sets {
+				for _, h := range s.Holidays(y) {
+					if hd := dayOf(h.Date); hd.year == d.year {
+						year[hd] = h.Name
+					}
+				}
+			}
+		}
+		c.years[d.year] = year
+	}
+	name, ok := year[d]
+	return name, ok
+}
+
+// IsBusinessDay reports whether the date of t is neither a weekend day
+// nor a holiday
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	if isWeekend(t) {
+		return false
+	}
+	_, holiday := c.Holiday(t)
+	return !holiday
+}
+
+// Adjust moves t by conv when its date is not a business day, keeping its
+// time of day
+func (c *Calendar) Adjust(t time.Time, conv Convention) time.Time {
+	switch conv {
+	case Following:
+		return c.roll(t, 1)
+	case Preceding:
+		return c.roll(t, -1)
+	case ModifiedFollowing:
+		if next := c.roll(t, 1); next.Month() == t.Month() {
+			return next
+		}
+		return c.roll(t, -1)
+	}
+	return t
+}
+
+// roll steps t by step days until it is a business day
+func (c *Calendar) roll(t time.Time, step int) time.Time {
+	for !c.IsBusinessDay(t) {
+		t = t.AddDate(0, 0, step)
+	}
+	return t
+}
+
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
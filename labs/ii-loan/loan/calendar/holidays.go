@@ -0,0 +1,196 @@
+package calendar
+
+import (
+	"slices"
+	"time"
+)
+
+// Holiday is a day off
+type Holiday struct {
+	Date time.Time `json:"date"`
+	Name string    `json:"name"`
+}
+
+// HolidaySet lists the holidays of a region. Holidays returns those of
+// year, observed dates included; an observed date may fall in the year
+// before or after.
+type HolidaySet interface {
+	Holidays(year int) []Holiday
+}
+
+// Observance says which day off is given for a holiday on a weekend
+type Observance int
+
+// Observances
+const (
+	// Actual gives no day in lieu
+	Actual Observance = iota
+	// Nearest observes Saturdays on the Friday before and Sundays on the
+	// Monday after
+	Nearest
+	// Substitute observes the holiday on the next weekday that is not
+	// already a holiday
+	Substitute
+)
+
+// Rule computes one holiday in a year
+type Rule struct {
+	Name    string
+	Observe Observance
+	date    func(year int) time.Time
+}
+
+// Fixed is a holiday on the same date every year
+func Fixed(month time.Month, d int, name string, observe Observance) Rule {
+	return Rule{Name: name, Observe: observe, date: func(y int) time.Time {
+		return time.Date(y, month, d, 0, 0, 0, 0, time.UTC)
+	}}
+}
+
+// NthWeekday is a holiday on the nth weekday of a month, counting from the
+// end for negative n: NthWeekday(time.May, time.Monday, -1, ...) is the
+// last Monday of May
+func NthWeekday(month time.Month, wd time.Weekday, n int, name string) Rule {
+	return Rule{Name: name, date: func(y int) time.Time {
+		if n < 0 {
+			last := time.Date(y, month+1, 0, 0, 0, 0, 0, time.UTC)
+			back := (int(last.Weekday()) - int(wd) + 7) % 7
+			return last.AddDate(0, 0, -back+7*(n+1))
+		}
+		first := time.Date(y, month, 1, 0, 0, 0, 0, time.UTC)
+		ahead := (int(wd) - int(first.Weekday()) + 7) % 7
+		return first.AddDate(0, 0, ahead+7*(n-1))
+	}}
+}
+
+// EasterOffset is a holiday days after Western Easter Sunday, before it
+// for negative days
+func EasterOffset(days int, name string) Rule {
+	return Rule{Name: name, date: func(y int) time.Time {
+		return Easter(y).AddDate(0, 0, days)
+	}}
+}
+
+// Easter returns Western Easter Sunday of year, by the anonymous
+// Gregorian algorithm
+func Easter(year int) time.Time {
+	a, b, c := year%19, year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// Rules is a HolidaySet computed from rules
+type Rules []Rule
+
+// Holidays implements HolidaySet. Every holiday is placed on its date
+// first; days in lieu are then given in rule order, so a substitute never
+// lands on another holiday.
+func (rs Rules) Holidays(year int) []Holiday {
+	var out []Holiday
+	taken := map[day]bool{}
+	actual := make([]time.Time, len(rs))
+	for i, r := range rs {
+		actual[i] = r.date(year)
+		taken[dayOf(actual[i])] = true
+	}
+	for i, r := range rs {
+		d := actual[i]
+		out = append(out, Holiday{Date: d, Name: r.Name})
+		if !isWeekend(d) || r.Observe == Actual {
+			continue
+		}
+		if r.Observe == Nearest {
+			if d.Weekday() == time.Saturday {
+				d = d.AddDate(0, 0, -1)
+			} else {
+				d = d.AddDate(0, 0, 1)
+			}
+		} else {
+			for isWeekend(d) || taken[dayOf(d)] {
+				d = d.AddDate(0, 0, 1)
+			}
+		}
+		taken[dayOf(d)] = true
+		out = append(out, Holiday{Date: d, Name: r.Name + " (observed)"})
+	}
+	slices.SortStableFunc(out, func(a, b Holiday) int { return a.Date.Compare(b.Date) })
+	return out
+}
+
+// Dates is a HolidaySet of one-off dates, for holidays no rule computes,
+// such as those set by a lunar calendar or announced by decree
+func Dates(name string, dates ...time.Time) HolidaySet {
+	return dateSet{name, dates}
+}
+
+type dateSet struct {
+	name  string
+	dates []time.Time
+}
+
+func (s dateSet) Holidays(year int) []Holiday {
+	var out []Holiday
+	for _, d := range s.dates {
+		if d.Year() == year {
+			out = append(out, Holiday{Date: d, Name: s.name})
+		}
+	}
+	return out
+}
+
+// Regions are the public holiday sets of the regions loans are booked in,
+// keyed by ISO 3166 code. Holidays following the lunar calendar change
+// date every year and are not included; add them with Dates.
+var Regions = map[string]HolidaySet{
+	// TH is Thailand's fixed-date public holidays
+	"TH": Rules{
+		Fixed(time.January, 1, "New Year's Day", Substitute),
+		Fixed(time.April, 6, "Chakri Memorial Day", Substitute),
+		Fixed(time.April, 13, "Songkran", Substitute),
+		Fixed(time.April, 14, "Songkran", Substitute),
+		Fixed(time.April, 15, "Songkran", Substitute),
+		Fixed(time.May, 1, "National Labour Day", Substitute),
+		Fixed(time.May, 4, "Coronation Day", Substitute),
+		Fixed(time.June, 3, "Queen Suthida's Birthday", Substitute),
+		Fixed(time.July, 28, "King Vajiralongkorn's Birthday", Substitute),
+		Fixed(time.August, 12, "Mother's Day", Substitute),
+		Fixed(time.October, 13, "King Bhumibol Memorial Day", Substitute),
+		Fixed(time.October, 23, "Chulalongkorn Day", Substitute),
+		Fixed(time.December, 5, "Father's Day", Substitute),
+		Fixed(time.December, 10, "Constitution Day", Substitute),
+		Fixed(time.December, 31, "New Year's Eve", Substitute),
+	},
+	// US is the United States' federal holidays
+	"US": Rules{
+		Fixed(time.January, 1, "New Year's Day", Nearest),
+		NthWeekday(time.January, time.Monday, 3, "Martin Luther King Jr. Day"),
+		NthWeekday(time.February, time.Monday, 3, "Washington's Birthday"),
+		NthWeekday(time.May, time.Monday, -1, "Memorial Day"),
+		Fixed(time.June, 19, "Juneteenth", Nearest),
+		Fixed(time.July, 4, "Independence Day", Nearest),
+		NthWeekday(time.September, time.Monday, 1, "Labor Day"),
+		NthWeekday(time.October, time.Monday, 2, "Columbus Day"),
+		Fixed(time.November, 11, "Veterans Day", Nearest),
+		NthWeekday(time.November, time.Thursday, 4, "Thanksgiving Day"),
+		Fixed(time.December, 25, "Christmas Day", Nearest),
+	},
+	// GB is the bank holidays of England and Wales
+	"GB": Rules{
+		Fixed(time.January, 1, "New Year's Day", Substitute),
+		EasterOffset(-2, "Good Friday"),
+		EasterOffset(1, "Easter Monday"),
+		NthWeekday(time.May, time.Monday, 1, "Early May Bank Holiday"),
+		NthWeekday(time.May, time.Monday, -1, "Spring Bank Holiday"),
+		NthWeekday(time.August, time.Monday, -1, "Summer Bank Holiday"),
+		Fixed(time.December, 25, "Christmas Day", Substitute),
+		Fixed(time.December, 26, "Boxing Day", Substitute),
+	},
+}
@@ -0,0 +1,171 @@
+// Package catalog caches the product catalog in front of its repository.
+// Every application looks its product up, so a Cache reads each product
+// through once and keeps it, unknown codes included, until its TTL runs
+// out or an administrator changes it. Changes made through the Cache
+// invalidate their entry at once; changes made elsewhere, by another
+// instance, show within the TTL unless Invalidate is called.
+package catalog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"loan"
+)
+
+// DefaultTTL is how long a product is served from the cache
+const DefaultTTL = 5 * time.Minute
+
+// Metrics counts the cache's lookups since it was created
+type Metrics struct {
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Invalidations int64 `json:"invalidations"`
+	// Entries is how many codes are cached, expired ones included
+	Entries int `json:"entries"`
+}
+
+// Cache is a read-through loan.ProductRepository over another. It is safe
+// for concurrent use.
+type Cache struct {
+	repo loan.ProductRepository
+	ttl  time.Duration
+	now  func() time.Time
+
+	lookups       metric.Int64Counter
+	invalidations metric.Int64Counter
+
+	mu      sync.Mutex
+	entries map[string]entry
+	metrics Metrics
+	// generation is bumped by every invalidation, so a read that started
+	// before one does not cache what it read
+	generation uint64
+}
+
+// entry is a cached lookup: the product, or ErrProductNotFound
+type entry struct {
+	product loan.Product
+	err     error
+	expires time.Time
+}
+
+// Option configures a Cache
+type Option func(*Cache)
+
+// WithTTL sets how long products are cached instead of DefaultTTL
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) { c.ttl = ttl }
+}
+
+// New creates an empty cache over repo. Counters are recorded through the
+// global OpenTelemetry meter provider as catalog.cache.lookups, by hit or
+// miss, and catalog.cache.invalidations.
+func New(repo loan.ProductRepository, opts ...Option) *Cache {
+	c := &Cache{repo: repo, ttl: DefaultTTL, now: time.Now, entries: make(map[string]entry)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	meter := otel.Meter("loan/catalog")
+	c.lookups, _ = meter.Int64Counter("catalog.cache.lookups", metric.WithDescription("product catalog lookups by cache hit or miss"))
+	c.invalidations, _ = meter.Int64Counter("catalog.cache.invalidations", metric.WithDescription("product catalog cache entries dropped by updates"))
+	return c
+}
+
+// Product implements loan.ProductCatalog, reading through to the
+// repository on a miss
+func (c *Cache) Product(ctx context.Context, code string) (loan.Product, error) {
+	c.mu.Lock()
+	e, ok := c.entries[code]
+	hit := ok && c.now().Before(e.expires)
+	if hit {
+		c.metrics.Hits++
+	} else {
+		c.metrics.Misses++
+	}
+	gen := c.generation
+	c.mu.Unlock()
+	c.record(ctx, hit)
+	if hit {
+		return e.product.Clone(), e.err
+	}
+
+	p, err := c.repo.Product(ctx, code)
+	if err != nil && !errors.Is(err, loan.ErrProductNotFound) {
+		return loan.Product{}, err
+	}
+	c.mu.Lock()
+	if gen == c.generation {
+		c.entries[code] = entry{product: p, err: err, expires: c.now().Add(c.ttl)}
+	}
+	c.mu.Unlock()
+	return p.Clone(), err
+}
+
+func (c *Cache) record(ctx context.Context, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	c.lookups.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+}
+
+// Products lists the repository's catalog; listings are not cached
+func (c *Cache) Products(ctx context.Context) ([]loan.Product, error) {
+	return c.repo.Products(ctx)
+}
+
+// SaveProduct saves p to the repository and invalidates its entry
+func (c *Cache) SaveProduct(ctx context.Context, p loan.Product) error {
+	defer c.Invalidate(ctx, p.Code)
+	return c.repo.SaveProduct(ctx, p)
+}
+
+// DeleteProduct deletes the product from the repository and invalidates
+// its entry
+func (c *Cache) DeleteProduct(ctx context.Context, code string) error {
+	defer c.Invalidate(ctx, code)
+	return c.repo.DeleteProduct(ctx, code)
+}
+
+// Invalidate drops the cached entry of code, for products changed without
+// going through the cache
+func (c *Cache) Invalidate(ctx context.Context, code string) {
+	c.mu.Lock()
+	_, ok := c.entries[code]
+	delete(c.entries, code)
+	c.generation++
+	if ok {
+		c.metrics.Invalidations++
+	}
+	c.mu.Unlock()
+	if ok {
+		c.invalidations.Add(ctx, 1)
+	}
+}
+
+// Purge drops every cached entry
+func (c *Cache) Purge(ctx context.Context) {
+	c.mu.Lock()
+	n := len(c.entries)
+	clear(c.entries)
+	c.generation++
+	c.metrics.Invalidations += int64(n)
+	c.mu.Unlock()
+	c.invalidations.Add(ctx, int64(n))
+}
+
+// Metrics returns a snapshot of the counters
+func (c *Cache) Metrics() Metrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := c.metrics
+	m.Entries = len(c.entries)
+	return m
+}
@@ -0,0 +1,230 @@
+// Package cloudevents wraps loan domain events in CloudEvents 1.0 envelopes
+// so external systems can consume them in a standard way.
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"loan"
+)
+
+// SpecVersion is the CloudEvents specification version produced by this package
+const SpecVersion = "1.0"
+
+// Content types used by the CloudEvents JSON format
+const (
+	ContentTypeStructured = "application/cloudevents+json"
+	ContentTypeJSON       = "application/json"
+)
+
+// Mode selects how an envelope is mapped onto a transport message
+type Mode int
+
+const (
+	// ModeStructured puts the whole envelope in the message body
+	ModeStructured Mode = iota
+	// ModeBinary puts attributes in ce-* headers and only data in the body
+	ModeBinary
+)
+
+// Envelope is a CloudEvents 1.0 event with JSON data
+type Envelope struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// New wraps a domain event in an envelope originating from source
+func New(source string, event loan.Event) (Envelope, error) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("marshal event data: %w", err)
+	}
+	env := Envelope{
+		SpecVersion:     SpecVersion,
+		ID:              event.ID,
+		Source:          source,
+		Type:            string(event.Type),
+		Subject:         event.LoanID,
+		Time:            event.OccurredAt,
+		DataContentType: ContentTypeJSON,
+		Data:            data,
+	}
+	return env, env.Validate()
+}
+
+// Validate checks that the required context attributes are present
+func (e Envelope) Validate() error {
+	var missing []string
+	if e.SpecVersion == "" {
+		missing = append(missing, "specversion")
+	}
+	if e.ID == "" {
+		missing = append(missing, "id")
+	}
+	if e.Source == "" {
+		missing = append(missing, "source")
+	}
+	if e.Type == "" {
+		missing = append(missing, "type")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("cloudevents: missing required attributes: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Message is an encoded envelope ready to be handed to a transport
+type Message struct {
+	ContentType string
+	Header      map[string]string
+	Body        []byte
+}
+
+// Encode maps an envelope onto a message using the given mode
+func Encode(env Envelope, mode Mode) (Message, error) {
+	if err := env.Validate(); err != nil {
+		return Message{}, err
+	}
+	switch mode {
+	case ModeStructured:
+		body, err := json.Marshal(env)
+		if err != nil {
+			return Message{}, err
+		}
+		return Message{ContentType: ContentTypeStructured, Body: body}, nil
+	case ModeBinary:
+		header := map[string]string{
+			"ce-specversion": env.SpecVersion,
+			"ce-id":          env.ID,
+			"ce-source":      env.Source,
+			"ce-type":        env.Type,
+		}
+		if env.Subject != "" {
+			header["ce-subject"] = env.Subject
+		}
+		if !env.Time.IsZero() {
+			header["ce-time"] = env.Time.Format(time.RFC3339Nano)
+		}
+		contentType := env.DataContentType
+		if contentType == "" {
+			contentType = ContentTypeJSON
+		}
+		return Message{ContentType: contentType, Header: header, Body: env.Data}, nil
+	default:
+		return Message{}, fmt.Errorf("cloudevents: unknown mode %d", mode)
+	}
+}
+
+// Decode reads an envelope back from a structured or binary message
+func Decode(msg Message) (Envelope, error) {
+	mediaType, _, err := mime.ParseMediaType(msg.ContentType)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("cloudevents: content type: %w", err)
+	}
+	if mediaType == ContentTypeStructured {
+		var env Envelope
+		if err := json.Unmarshal(msg.Body, &env); err != nil {
+			return Envelope{}, err
+		}
+		return env, env.Validate()
+	}
+
+	env := Envelope{
+		SpecVersion:     msg.Header["ce-specversion"],
+		ID:              msg.Header["ce-id"],
+		Source:          msg.Header["ce-source"],
+		Type:            msg.Header["ce-type"],
+		Subject:         msg.Header["ce-subject"],
+		DataContentType: msg.ContentType,
+		Data:            msg.Body,
+	}
+	if t := msg.Header["ce-time"]; t != "" {
+		if env.Time, err = time.Parse(time.RFC3339Nano, t); err != nil {
+			return Envelope{}, fmt.Errorf("cloudevents: ce-time: %w", err)
+		}
+	}
+	return env, env.Validate()
+}
+
+// ErrNotAcceptable is returned by Negotiate when no supported format is acceptable
+var ErrNotAcceptable = errors.New("cloudevents: no acceptable content type")
+
+// Negotiate picks an encoding mode from an HTTP-style Accept header.
+// application/cloudevents+json selects structured mode, application/json
+// selects binary mode; an empty header or */* defaults to structured.
+func Negotiate(accept string) (Mode, error) {
+	if strings.TrimSpace(accept) == "" {
+		return ModeStructured, nil
+	}
+	best, bestQ := ModeStructured, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if _, err := fmt.Sscanf(v, "%g", &q); err != nil {
+				continue
+			}
+		}
+		var mode Mode
+		switch mediaType {
+		case ContentTypeStructured, "*/*", "application/*":
+			mode = ModeStructured
+		case ContentTypeJSON:
+			mode = ModeBinary
+		default:
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = mode, q
+		}
+	}
+	if bestQ <= 0 {
+		return 0, ErrNotAcceptable
+	}
+	return best, nil
+}
+
+// Sender delivers encoded messages over a transport
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Publisher is a loan.EventPublisher that emits CloudEvents over a Sender
+type Publisher struct {
+	source string
+	mode   Mode
+	sender Sender
+}
+
+// NewPublisher creates a publisher stamping events with source
+func NewPublisher(source string, mode Mode, sender Sender) *Publisher {
+	return &Publisher{source: source, mode: mode, sender: sender}
+}
+
+// Publish wraps the event in an envelope and sends it
+func (p *Publisher) Publish(ctx context.Context, event loan.Event) error {
+	env, err := New(p.source, event)
+	if err != nil {
+		return err
+	}
+	msg, err := Encode(env, p.mode)
+	if err != nil {
+		return err
+	}
+	return p.sender.Send(ctx, msg)
+}
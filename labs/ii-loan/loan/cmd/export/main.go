@@ -0,0 +1,111 @@
+// Command export writes the loans of a database to stdout as CSV,
+// newline-delimited JSON or an XLSX workbook, streaming rows as they are read.
+//
+//	export -dsn file:loan.db -status approved -status default > book.csv
+//	export -format ndjson -customer c-42
+//	export -format xlsx > book.xlsx
+//	export -report aging -month 2024-05 > aging.csv
+//	export -report rollrate -month 2024-05 > rollrate.csv
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"loan"
+	"loan/export"
+	"loan/sqlstore"
+	_ "loan/sqlstore/drivers"
+)
+
+type statuses []string
+
+func (s *statuses) String() string     { return strings.Join(*s, ",") }
+func (s *statuses) Set(v string) error { *s = append(*s, v); return nil }
+
+func main() {
+	driver := flag.String("driver", "sqlite", "database/sql driver: sqlite or pgx")
+	dsn := flag.String("dsn", "file:loan.db", "data source name")
+	format := flag.String("format", "csv", "output format: csv, ndjson or xlsx")
+	customer := flag.String("customer", "", "only export the loans of this customer")
+	timeout := flag.Duration("timeout", 30*time.Minute, "time allowed for the export")
+	report := flag.String("report", "", "write a report instead of the loans: aging or rollrate")
+	month := flag.String("month", "", "month of the report, YYYY-MM (default: this month)")
+	var filter loan.Filter
+	flag.Var((*statuses)(&filter.Statuses), "status", "only export loans in this status (repeatable)")
+	flag.Parse()
+	filter.CustomerID = *customer
+
+	var err error
+	switch *report {
+	case "":
+		err = run(*driver, *dsn, *format, *timeout, filter)
+	case "aging", "rollrate":
+		err = runReport(*driver, *dsn, *report, *month, *timeout)
+	default:
+		err = fmt.Errorf("unknown report %q", *report)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "export:", err)
+		os.Exit(1)
+	}
+}
+
+func run(driver, dsn, format string, timeout time.Duration, filter loan.Filter) error {
+	f, err := export.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	db, err := sqlstore.Open(ctx, driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	n, err := export.Write(ctx, os.Stdout, sqlstore.NewLoanRepository(db), filter, f)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d loans\n", n)
+	return nil
+}
+
+// runReport writes the delinquency aging or roll-rate report of month as
+// CSV
+func runReport(driver, dsn, report, month string, timeout time.Duration) error {
+	at := time.Now()
+	if month != "" {
+		var err error
+		if at, err = time.Parse("2006-01", month); err != nil {
+			return fmt.Errorf("month: %w", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	db, err := sqlstore.Open(ctx, driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	reports := loan.NewReportingService(sqlstore.NewLoanRepository(db),
+		loan.WithAgingStore(sqlstore.NewAgingStore(db)), loan.WithTransitionStore(sqlstore.NewTransitionStore(db)))
+	if report == "rollrate" {
+		r, err := reports.RollRates(ctx, at)
+		if err != nil {
+			return err
+		}
+		return export.WriteRollRatesCSV(os.Stdout, r)
+	}
+	r, err := reports.Aging(ctx, at)
+	if err != nil {
+		return err
+	}
+	return export.WriteAgingCSV(os.Stdout, r)
+}
@@ -0,0 +1,717 @@
+// Command loan-api serves the loan service over JSON/HTTP and gRPC and runs
+// the scheduled lifecycle jobs.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"loan"
+	"loan/api"
+	"loan/breaker"
+	"loan/bureau"
+	"loan/calendar"
+	"loan/catalog"
+	loanconfig "loan/config"
+	"loan/featureflag"
+	"loan/fieldcrypt"
+	"loan/fixtures"
+	"loan/gateway"
+	"loan/grpcapi"
+	loanhealth "loan/health"
+	"loan/i18n"
+	"loan/investor"
+	"loan/jobs"
+	"loan/ledger"
+	"loan/logging"
+	"loan/memory"
+	"loan/notification"
+	"loan/notification/email"
+	"loan/openbanking"
+	"loan/pool"
+	"loan/risk"
+	"loan/scheduler"
+	"loan/secrets"
+	"loan/sms"
+	"loan/sqlstore"
+	_ "loan/sqlstore/drivers"
+	"loan/tracing"
+	"loan/watchlist"
+	"loan/webhook"
+)
+
+type config struct {
+	addr            string
+	grpcAddr        string
+	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+	jobs            bool
+	dbDriver        string
+	dsn             string
+	dev             bool
+	seedLoans       int
+	investors       bool
+	pools           bool
+	products        bool
+	webhooks        bool
+	productTTL      time.Duration
+	collateral      string
+	watchlist       string
+	svcOpts         []loan.Option
+	apiOpts         []api.Option
+	flags           *featureflag.File
+	pricing         *loanconfig.PricingFile
+	// cipher encrypts customers' and mandates' sensitive fields at rest
+	cipher *fieldcrypt.Cipher
+	// email and sms notify customers of their loans, when configured
+	email, sms notification.Notifier
+}
+
+func main() {
+	var cfg config
+	flag.StringVar(&cfg.addr, "addr", ":8080", "HTTP listen address")
+	flag.StringVar(&cfg.grpcAddr, "grpc-addr", ":9090", "gRPC listen address (empty disables gRPC)")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 15*time.Second, "time allowed for in-flight requests and jobs to finish")
+	flag.DurationVar(&cfg.drainDelay, "drain-delay", 0, "time /readyz reports unready before the servers stop accepting requests")
+	flag.BoolVar(&cfg.jobs, "jobs", true, "run the scheduled lifecycle jobs")
+	flag.StringVar(&cfg.dbDriver, "db-driver", "", "database/sql driver, sqlite or pgx (empty keeps loans in memory)")
+	flag.StringVar(&cfg.dsn, "dsn", "file:loan.db", "data source name for -db-driver")
+	flag.BoolVar(&cfg.dev, "dev", false, "development mode: apply pending migrations at startup")
+	flag.IntVar(&cfg.seedLoans, "seed-loans", 0, "store this many generated demo loans at startup")
+	flag.BoolVar(&cfg.investors, "investors", false, "fund loans peer to peer, keeping investor shares in memory")
+	flag.BoolVar(&cfg.pools, "pools", false, "group loans into securitization pools, kept in memory")
+	flag.BoolVar(&cfg.products, "product-catalog", false, "serve the product catalog to administrators and check applications naming a product against it")
+	flag.BoolVar(&cfg.webhooks, "webhooks", false, "deliver events to endpoints registered through the API, keeping subscriptions in memory")
+	flag.DurationVar(&cfg.productTTL, "product-cache-ttl", catalog.DefaultTTL, "how long products are cached between catalog reads")
+	flag.StringVar(&cfg.collateral, "pool-collateral", "", "JSON file of collateral values by loan ID, for the pools' LTV criterion")
+	flag.StringVar(&cfg.watchlist, "watchlist", "", "JSON file of sanctions and blacklist entries applicants are screened against; matches hold approval until cleared")
+	trace := flag.Bool("trace", false, "log OpenTelemetry spans")
+	bureauURL := flag.String("bureau-url", "", "credit bureau base URL (empty skips credit checks)")
+	bureauProfiles := flag.Bool("bureau-profiles", false, "answer credit checks from the sample borrower profiles instead of -bureau-url")
+	sampleAccounts := flag.Bool("sample-account-data", false, "check affordability against generated open banking histories")
+	riskRules := flag.Bool("risk-rules", false, "record the underwriting rules' decision on new applications")
+	riskModels := flag.String("risk-models", "", "JSON file selecting the scoring model of each product (overrides -risk-rules)")
+	flagsFile := flag.String("feature-flags", "", "JSON feature flag file, reloaded when it changes; gates -risk-models per tenant with the \""+risk.FlagScoringModels+"\" flag")
+	riskExperiment := flag.Float64("risk-experiment", 0, "share of customers decided by -risk-models in the \"scoring\" experiment, the others by the rules")
+	v1Sunset := flag.String("v1-sunset", "", "retirement date of API v1 (YYYY-MM-DD), announced in the Sunset header")
+	paymentSandbox := flag.Bool("payment-sandbox", false, "disburse and collect through the in-memory sandbox payment gateway")
+	roleHeader := flag.String("role-header", "", "header carrying the caller's role, set by the authenticating gateway (empty disables response redaction)")
+	principalHeader := flag.String("principal-header", "", "header carrying the authenticated caller, set by the gateway; idempotency keys are scoped to it and the tenant")
+	holidays := flag.String("holidays", "", "comma-separated regions whose public holidays installments may not fall due on, e.g. TH (empty keeps due dates unadjusted)")
+	dueDateRoll := flag.String("due-date-roll", string(calendar.ModifiedFollowing), "how due dates on weekends and -holidays move: following, modified-following or preceding")
+	localize := flag.Bool("localize", false, "add statusText and rejectionReasonText in the caller's Accept-Language to API responses")
+	logUnmasked := flag.Bool("log-unmasked", false, "log customer IDs, account numbers and large amounts in clear (local debugging only)")
+	secretsFrom := flag.String("secrets", "env", "where secrets, such as ${secret:db/password} in -dsn, bureau/api-key and fieldcrypt/keys, are read: env (LOAN_SECRET_ variables), dir:PATH or vault:ADDR (token in VAULT_TOKEN)")
+	notifyEmail := flag.String("notify-email", "", "email customers about their loans: sandbox:DIR writes .eml files, smtp:HOST:PORT relays them (credentials in smtp/username and smtp/password)")
+	emailFrom := flag.String("notify-email-from", "loans@example.com", "sender address of -notify-email")
+	notifySMS := flag.String("notify-sms", "", "text customers payment reminders: twilio, or twilio:URL for a compatible provider (credentials in twilio/account-sid and twilio/auth-token)")
+	smsFrom := flag.String("notify-sms-from", "", "sender number of -notify-sms")
+	configFile := flag.String("config", "", "YAML configuration file; it and the LOAN_ environment variables set what no flag does")
+	flag.Parse()
+
+	settings, err := loanconfig.Load(*configFile, os.Environ())
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	applyConfig(settings, &cfg, localize, paymentSandbox)
+	if *configFile != "" {
+		// rates and products follow the file without a restart
+		pricing, err := loanconfig.NewPricingFile(*configFile, os.Environ(),
+			loanconfig.WithErrorHandler(func(err error) { slog.Error("reloading pricing", "error", err) }),
+			loanconfig.WithReloadHandler(func(p *loan.Pricing) {
+				slog.Info("pricing reloaded", "file", *configFile, "rate_tiers", len(p.Rates), "products", len(p.Products))
+			}))
+		if err != nil {
+			fatal("loading pricing", err)
+		}
+		cfg.pricing = pricing
+		cfg.svcOpts = append(cfg.svcOpts, loan.WithPricing(pricing))
+	} else if p := settings.Pricing(); p != nil {
+		cfg.svcOpts = append(cfg.svcOpts, loan.WithPricing(loan.StaticPricing{P: p}))
+	}
+
+	secretStore, err := openSecrets(*secretsFrom)
+	if err != nil {
+		fatal("invalid -secrets", err)
+	}
+	if cfg.dsn, err = secrets.Expand(context.Background(), secretStore, cfg.dsn); err != nil {
+		fatal("reading database secrets", err)
+	}
+	// instances share the key so their pagination cursors work on each other
+	cursorKey, err := secrets.Optional(context.Background(), secretStore, "api/cursor-key")
+	if err != nil {
+		fatal("reading API secrets", err)
+	}
+	if cursorKey != "" {
+		cfg.apiOpts = append(cfg.apiOpts, api.WithCursorKey([]byte(cursorKey)))
+	}
+	if cfg.email, cfg.sms, err = openNotifiers(context.Background(), secretStore, *notifyEmail, *emailFrom, *notifySMS, *smsFrom); err != nil {
+		fatal("configuring notifications", err)
+	}
+	if cfg.cipher, err = fieldCipher(context.Background(), secretStore, cfg.dbDriver != ""); err != nil {
+		fatal("reading field encryption keys", err)
+	}
+
+	if *v1Sunset != "" {
+		t, err := time.Parse(time.DateOnly, *v1Sunset)
+		if err != nil {
+			fatal("invalid -v1-sunset", err)
+		}
+		cfg.apiOpts = append(cfg.apiOpts, api.WithV1Sunset(t))
+	}
+
+	if *paymentSandbox {
+		cfg.svcOpts = append(cfg.svcOpts, loan.WithPaymentProvider(gateway.NewSandbox()))
+	}
+	if *holidays != "" {
+		conv, err := calendar.ParseConvention(*dueDateRoll)
+		if err != nil {
+			fatal("invalid -due-date-roll", err)
+		}
+		var sets []calendar.HolidaySet
+		for _, region := range strings.Split(*holidays, ",") {
+			set, ok := calendar.Regions[strings.ToUpper(strings.TrimSpace(region))]
+			if !ok {
+				fatal("invalid -holidays", fmt.Errorf("no holidays known for region %q", region))
+			}
+			sets = append(sets, set)
+		}
+		cfg.svcOpts = append(cfg.svcOpts, loan.WithBusinessCalendar(calendar.New(sets...), conv))
+	}
+	if *roleHeader != "" {
+		cfg.apiOpts = append(cfg.apiOpts, api.WithRoles(api.RoleFromHeader(*roleHeader), api.DefaultVisibility))
+	}
+	if *principalHeader != "" {
+		cfg.apiOpts = append(cfg.apiOpts, api.WithPrincipals(api.PrincipalFromHeader(*principalHeader)))
+	}
+	if *localize {
+		cfg.apiOpts = append(cfg.apiOpts, api.WithLocalization(i18n.Default()))
+	}
+
+	var logOpts []logging.Option
+	if *logUnmasked {
+		logOpts = append(logOpts, logging.Unmasked())
+	}
+	logger := logging.New(os.Stderr, slog.LevelInfo, logOpts...)
+	slog.SetDefault(logger)
+
+	if *trace {
+		shutdown := tracing.Setup("loan-api", tracing.LogExporter{Logger: logger})
+		defer shutdown(context.Background())
+	}
+	if *bureauProfiles {
+		cfg.svcOpts = append(cfg.svcOpts, loan.WithCreditBureau(bureau.NewProfileBureau()))
+	} else if *bureauURL != "" {
+		cb := breaker.New("credit-bureau", breaker.WithStateChange(func(name string, from, to breaker.State) {
+			logger.Warn("circuit breaker state changed", "breaker", name, "from", from.String(), "to", to.String())
+		}))
+		key, err := secrets.Optional(context.Background(), secretStore, "bureau/api-key")
+		if err != nil {
+			fatal("reading bureau secrets", err)
+		}
+		cfg.svcOpts = append(cfg.svcOpts, loan.WithCreditBureau(breaker.CreditBureau(cb, bureau.NewClient(*bureauURL, bureau.WithAPIKey(key)))))
+	}
+	if *sampleAccounts {
+		cfg.svcOpts = append(cfg.svcOpts, loan.WithAccountData(openbanking.NewFake()))
+	}
+	if *riskModels != "" {
+		engine, err := loadRiskEngine(*riskModels)
+		if err != nil {
+			fatal("loading risk models", err)
+		}
+		var e loan.RiskEngine = engine
+		if *riskExperiment > 0 {
+			e = risk.NewExperiment("scoring", risk.NewRules(), engine, risk.WithTreatmentShare(*riskExperiment), risk.WithShadow())
+		}
+		if *flagsFile != "" {
+			flags, err := featureflag.NewFile(*flagsFile,
+				featureflag.WithErrorHandler(func(err error) { logger.Error("reloading feature flags", "error", err) }),
+				featureflag.WithReloadHandler(func(featureflag.Set) { logger.Info("feature flags reloaded", "file", *flagsFile) }))
+			if err != nil {
+				fatal("loading feature flags", err)
+			}
+			cfg.flags = flags
+			e = risk.NewToggle(flags, risk.FlagScoringModels, e, risk.NewRules())
+		}
+		cfg.svcOpts = append(cfg.svcOpts, loan.WithRiskEngine(e))
+	} else if *riskRules {
+		cfg.svcOpts = append(cfg.svcOpts, loan.WithRiskEngine(risk.NewRules()))
+	}
+
+	if err := run(logger, cfg); err != nil {
+		fatal("loan-api stopped", err)
+	}
+}
+
+// applyConfig copies the settings of c whose flags were not given into
+// cfg and the flag values
+func applyConfig(c loanconfig.Config, cfg *config, localize, paymentSandbox *bool) {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	set := func(name string, apply func()) {
+		if !given[name] {
+			apply()
+		}
+	}
+	set("addr", func() { cfg.addr = c.Server.Addr })
+	set("grpc-addr", func() { cfg.grpcAddr = c.Server.GRPCAddr })
+	set("shutdown-timeout", func() { cfg.shutdownTimeout = c.Server.ShutdownTimeout })
+	set("drain-delay", func() { cfg.drainDelay = c.Server.DrainDelay })
+	set("db-driver", func() { cfg.dbDriver = c.Database.Driver })
+	set("dsn", func() { cfg.dsn = c.Database.DSN })
+	set("jobs", func() { cfg.jobs = c.Features.Jobs })
+	set("investors", func() { cfg.investors = c.Features.Investors })
+	set("pools", func() { cfg.pools = c.Features.Pools })
+	set("product-catalog", func() { cfg.products = c.Features.ProductCatalog })
+	set("webhooks", func() { cfg.webhooks = c.Features.Webhooks })
+	set("localize", func() { *localize = c.Features.Localize })
+	set("payment-sandbox", func() { *paymentSandbox = c.Features.PaymentSandbox })
+	cfg.svcOpts = append(cfg.svcOpts, loan.WithLimits(c.LoanLimits()))
+}
+
+// openSecrets returns the secrets provider named by spec, the -secrets flag
+func openSecrets(spec string) (secrets.Provider, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch {
+	case kind == "env" && arg == "":
+		return secrets.NewEnv(), nil
+	case kind == "dir" && arg != "":
+		return secrets.Dir(arg), nil
+	case kind == "vault" && arg != "":
+		// the token is the one secret that has to come from the environment
+		return secrets.NewVault(arg, os.Getenv("VAULT_TOKEN")), nil
+	}
+	return nil, fmt.Errorf("%q is not env, dir:PATH or vault:ADDR", spec)
+}
+
+// openNotifiers returns the email and SMS channels of the -notify-email
+// and -notify-sms specs, nil for those not given
+func openNotifiers(ctx context.Context, store secrets.Provider, emailSpec, emailFrom, smsSpec, smsFrom string) (emailer, texter notification.Notifier, err error) {
+	if emailSpec != "" {
+		var sender email.Sender
+		kind, arg, _ := strings.Cut(emailSpec, ":")
+		switch {
+		case kind == "sandbox" && arg != "":
+			sender = &email.SandboxSender{Dir: arg}
+		case kind == "smtp" && arg != "":
+			smtpSender := email.SMTPSender{Addr: arg}
+			user, err := secrets.Optional(ctx, store, "smtp/username")
+			if err != nil {
+				return nil, nil, err
+			}
+			if user != "" {
+				password, err := store.Secret(ctx, "smtp/password")
+				if err != nil {
+					return nil, nil, err
+				}
+				host, _, _ := strings.Cut(arg, ":")
+				smtpSender.Auth = smtp.PlainAuth("", user, password, host)
+			}
+			sender = smtpSender
+		default:
+			return nil, nil, fmt.Errorf("-notify-email %q is not sandbox:DIR or smtp:HOST:PORT", emailSpec)
+		}
+		n, err := email.NewNotifier(emailFrom, sender)
+		if err != nil {
+			return nil, nil, err
+		}
+		emailer = n
+	}
+	if smsSpec != "" {
+		kind, arg, _ := strings.Cut(smsSpec, ":")
+		if kind != "twilio" {
+			return nil, nil, fmt.Errorf("-notify-sms %q is not twilio or twilio:URL", smsSpec)
+		}
+		sid, err := store.Secret(ctx, "twilio/account-sid")
+		if err != nil {
+			return nil, nil, err
+		}
+		token, err := store.Secret(ctx, "twilio/auth-token")
+		if err != nil {
+			return nil, nil, err
+		}
+		texter = sms.NewNotifier(&sms.TwilioSender{AccountSID: sid, AuthToken: token, From: smsFrom, BaseURL: arg})
+	}
+	return emailer, texter, nil
+}
+
+// fieldCipher returns the cipher for the keys in the fieldcrypt/keys
+// secret, written as fieldcrypt.ParseKeys reads them. A database is not
+// opened without them; in-memory stores, which end with the process, are
+// encrypted under a random key instead.
+func fieldCipher(ctx context.Context, store secrets.Provider, persistent bool) (*fieldcrypt.Cipher, error) {
+	spec, err := secrets.Optional(ctx, store, "fieldcrypt/keys")
+	if err != nil {
+		return nil, err
+	}
+	if spec != "" {
+		keys, err := fieldcrypt.ParseKeys(spec)
+		if err != nil {
+			return nil, err
+		}
+		return fieldcrypt.New(keys), nil
+	}
+	if persistent {
+		return nil, errors.New("the fieldcrypt/keys secret is required with -db-driver")
+	}
+	key := make([]byte, fieldcrypt.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	keys, err := fieldcrypt.NewStaticKeys("memory", map[string][]byte{"memory": key})
+	if err != nil {
+		return nil, err
+	}
+	return fieldcrypt.New(keys), nil
+}
+
+// loadRiskEngine builds the scoring engine configured in path
+func loadRiskEngine(path string) (*risk.Engine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := risk.LoadConfig(f)
+	if err != nil {
+		return nil, err
+	}
+	return risk.NewEngineFromConfig(c)
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+func run(logger *slog.Logger, cfg config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	st, err := openStore(ctx, logger, cfg)
+	if err != nil {
+		return err
+	}
+	defer st.close()
+	repo := st.loans
+	if cfg.seedLoans > 0 {
+		ds := fixtures.New().Generate(cfg.seedLoans/4+1, cfg.seedLoans)
+		// customers are stored too, so they can be notified and anonymized
+		for _, c := range ds.Customers {
+			_, err := st.customers.FindByID(ctx, c.ID)
+			if err == nil {
+				continue // seeded before, or generated twice
+			}
+			if !errors.Is(err, loan.ErrCustomerNotFound) {
+				return err
+			}
+			if err := st.customers.Save(ctx, &loan.Customer{ID: c.ID, Name: c.Name, Email: c.Email, Phone: c.Phone, CreatedAt: time.Now().UTC()}); err != nil {
+				return err
+			}
+		}
+		for _, l := range ds.Loans {
+			if err := repo.Save(ctx, l); err != nil {
+				return err
+			}
+		}
+		logger.Info("demo loans seeded", "loans", len(ds.Loans), "customers", len(ds.Customers))
+	}
+	var publisher loan.EventPublisher = loan.EventPublisherFunc(func(ctx context.Context, e loan.Event) error {
+		logger.InfoContext(ctx, "event published", "event_type", e.Type, logging.Loan(e.LoanID))
+		return nil
+	})
+	// the bus feeds the API's live event streams
+	bus := loan.NewEventBus()
+	publisher = loan.MultiPublisher(publisher, bus)
+	apiOpts := append(cfg.apiOpts, api.WithEventStream(bus))
+	if cfg.investors {
+		book := investor.NewBook(investor.NewMemory(), repo)
+		publisher = loan.MultiPublisher(publisher, book)
+		apiOpts = append(apiOpts, api.WithInvestors(book))
+	}
+	if cfg.email != nil || cfg.sms != nil {
+		notifier := notification.NewDispatcher(notification.CustomerContacts(st.customers))
+		if cfg.email != nil {
+			notifier.Register(cfg.email)
+		}
+		if cfg.sms != nil {
+			notifier.Register(cfg.sms, notification.KindPaymentDue, notification.KindPaymentOverdue)
+		}
+		// a customer who cannot be reached must not fail the change they
+		// are told about
+		publisher = loan.MultiPublisher(publisher, loan.EventPublisherFunc(func(ctx context.Context, e loan.Event) error {
+			if err := notifier.Publish(ctx, e); err != nil {
+				logger.WarnContext(ctx, "notifying customer", "event_type", e.Type, logging.Loan(e.LoanID), "error", err)
+			}
+			return nil
+		}))
+	}
+	var hooks *webhook.WebhookService
+	if cfg.webhooks {
+		hooks = webhook.NewWebhookService("loan-api")
+		publisher = loan.MultiPublisher(publisher, hooks)
+		apiOpts = append(apiOpts, api.WithWebhooks(hooks))
+	}
+	if cfg.products {
+		products := catalog.New(st.products, catalog.WithTTL(cfg.productTTL))
+		cfg.svcOpts = append(cfg.svcOpts, loan.WithProductCatalog(products))
+		apiOpts = append(apiOpts, api.WithProducts(products))
+	}
+	if cfg.watchlist != "" {
+		entries, err := watchlist.LoadFile(cfg.watchlist)
+		if err != nil {
+			return fmt.Errorf("loading watchlist: %w", err)
+		}
+		cfg.svcOpts = append(cfg.svcOpts, loan.WithWatchlist(watchlist.New(entries), st.customers))
+	}
+	if cfg.pools {
+		var collateral pool.StaticCollateral
+		if cfg.collateral != "" {
+			f, err := os.Open(cfg.collateral)
+			if err != nil {
+				return fmt.Errorf("loading pool collateral: %w", err)
+			}
+			collateral, err = pool.LoadCollateral(f)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("loading pool collateral: %w", err)
+			}
+		}
+		apiOpts = append(apiOpts, api.WithPools(pool.NewService(pool.NewMemory(), repo, pool.WithCollateral(collateral))))
+	}
+	customers := loan.NewCustomerService(st.customers, repo,
+		loan.WithCustomerLogger(logger),
+		loan.WithCustomerEraser(st.statements),
+		loan.WithCustomerEraser(loan.MandateEraser(st.mandates, repo)),
+		loan.WithCustomerEraser(st.transfers),
+	)
+	apiOpts = append(apiOpts, api.WithCustomers(customers))
+	svc := loan.NewLoanService(repo, append([]loan.Option{
+		loan.WithLogger(logger),
+		loan.WithEventPublisher(publisher),
+		loan.WithMandates(st.mandates),
+		loan.WithTransfers(st.transfers, loan.CustomerKYC(st.customers)),
+	}, cfg.svcOpts...)...)
+
+	if cfg.flags != nil {
+		go cfg.flags.Watch(ctx)
+	}
+	if cfg.pricing != nil {
+		go cfg.pricing.Watch(ctx)
+	}
+
+	probes := loanhealth.New()
+	// events only reach in-process subscribers and webhooks, so there is no
+	// broker to check
+	probes.Add("repository", loanhealth.Ping(repo))
+
+	mux := http.NewServeMux()
+	probes.Register(mux)
+	mux.Handle("/", logging.Middleware(logger, api.NewHandler(svc, apiOpts...)))
+	srv := &http.Server{
+		Addr:              cfg.addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+	// streams would otherwise hold Shutdown until its timeout
+	srv.RegisterOnShutdown(bus.Close)
+
+	errc := make(chan error, 2)
+	go func() {
+		logger.Info("loan-api listening", "addr", cfg.addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	gs := grpcapi.NewServer(svc, grpcapi.WithLogger(logger)).Register()
+	grpcHealth := health.NewServer()
+	healthpb.RegisterHealthServer(gs, grpcHealth)
+	if cfg.grpcAddr != "" {
+		lis, err := net.Listen("tcp", cfg.grpcAddr)
+		if err != nil {
+			return err
+		}
+		go func() {
+			logger.Info("loan-api gRPC listening", "addr", cfg.grpcAddr)
+			errc <- gs.Serve(lis)
+		}()
+	}
+
+	// Jobs get their own context so they are cancelled after the servers
+	// have drained rather than as soon as the signal arrives.
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+	var jobsDone sync.WaitGroup
+	if hooks != nil {
+		// deliveries queued by the last requests still go out while draining
+		jobsDone.Add(1)
+		go func() {
+			defer jobsDone.Done()
+			hooks.Run(jobsCtx)
+		}()
+	}
+	if cfg.jobs {
+		sched := scheduler.New(st.locker, scheduler.WithLogger(logger))
+		if err := jobs.Register(sched, jobs.Deps{
+			Loans:       repo,
+			Publisher:   publisher,
+			Statements:  st.statements,
+			Ledger:      ledger.NewMemoryLedger(),
+			Mandates:    st.mandates,
+			Collector:   svc,
+			Aging:       st.aging,
+			Transitions: st.transitions,
+		}); err != nil {
+			return err
+		}
+		jobsDone.Add(1)
+		go func() {
+			defer jobsDone.Done()
+			sched.Run(jobsCtx)
+		}()
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Report unready first so load balancers stop routing new requests,
+	// then stop accepting and drain in-flight ones, then cancel the jobs.
+	logger.Info("shutting down", "drain_delay", cfg.drainDelay.String())
+	probes.Drain()
+	grpcHealth.Shutdown()
+	time.Sleep(cfg.drainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+	go func() {
+		<-shutdownCtx.Done()
+		gs.Stop()
+	}()
+	gs.GracefulStop()
+	shutdownErr := srv.Shutdown(shutdownCtx)
+
+	cancelJobs()
+	finished := make(chan struct{})
+	go func() {
+		jobsDone.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-shutdownCtx.Done():
+		logger.Warn("jobs still running at shutdown timeout")
+	}
+
+	if shutdownErr != nil {
+		return shutdownErr
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.Info("shutdown complete")
+	return nil
+}
+
+type repository interface {
+	loan.LoanRepository
+	loanhealth.Pinger
+}
+
+type statementStore interface {
+	jobs.StatementStore
+	loan.CustomerEraser
+}
+
+type transferStore interface {
+	loan.TransferRepository
+	loan.CustomerEraser
+}
+
+// stores are the persistence the server runs on
+type stores struct {
+	loans       repository
+	statements  statementStore
+	mandates    loan.MandateRepository
+	aging       loan.AgingStore
+	transitions loan.TransitionStore
+	customers   loan.CustomerRepository
+	transfers   transferStore
+	products    loan.ProductRepository
+	// locker keeps instances sharing the database from running a job
+	// occurrence twice
+	locker scheduler.Locker
+	close  func() error
+}
+
+// openStore returns the in-memory stores unless a database is configured.
+// In dev mode pending migrations are applied first; otherwise they are left
+// to the migrate command.
+func openStore(ctx context.Context, logger *slog.Logger, cfg config) (stores, error) {
+	if cfg.dbDriver == "" {
+		return stores{
+			loans:       memory.NewLoanRepository(),
+			statements:  memory.NewStatementStore(),
+			mandates:    fieldcrypt.NewMandateRepository(memory.NewMandateRepository(), cfg.cipher),
+			aging:       memory.NewAgingStore(),
+			transitions: memory.NewTransitionStore(),
+			customers:   fieldcrypt.NewCustomerRepository(memory.NewCustomerRepository(), cfg.cipher),
+			transfers:   memory.NewTransferRepository(),
+			products:    memory.NewProductRepository(),
+			locker:      scheduler.NewMemoryLocker(),
+			close:       func() error { return nil },
+		}, nil
+	}
+	db, err := sqlstore.Open(ctx, cfg.dbDriver, cfg.dsn)
+	if err != nil {
+		return stores{}, err
+	}
+	if cfg.dev {
+		m, err := sqlstore.NewMigrator(db)
+		if err != nil {
+			db.Close()
+			return stores{}, err
+		}
+		ran, err := m.Up(ctx)
+		for _, mig := range ran {
+			logger.Info("migration applied", "version", mig.Version, "name", mig.Name)
+		}
+		if err != nil {
+			db.Close()
+			return stores{}, err
+		}
+	}
+	return stores{
+		loans:       sqlstore.NewLoanRepository(db),
+		statements:  sqlstore.NewStatementStore(db),
+		mandates:    fieldcrypt.NewMandateRepository(sqlstore.NewMandateRepository(db), cfg.cipher),
+		aging:       sqlstore.NewAgingStore(db),
+		transitions: sqlstore.NewTransitionStore(db),
+		customers:   fieldcrypt.NewCustomerRepository(sqlstore.NewCustomerRepository(db), cfg.cipher),
+		transfers:   sqlstore.NewTransferRepository(db),
+		products:    sqlstore.NewProductRepository(db),
+		locker:      sqlstore.NewLeaseLocker(db),
+		close:       db.Close,
+	}, nil
+}
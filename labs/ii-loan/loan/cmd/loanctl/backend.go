@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"loan"
+)
+
+// backend carries out loanctl's commands, against the REST API of a
+// running loan-api or directly against its database
+type backend interface {
+	Apply(ctx context.Context, l *loan.Loan) (*loan.Loan, error)
+	Approve(ctx context.Context, id string) (*loan.Loan, error)
+	Reject(ctx context.Context, id, reason string) (*loan.Loan, error)
+	Pay(ctx context.Context, id string, amount float64) (loan.Payment, error)
+	List(ctx context.Context, filter loan.Filter) ([]*loan.Loan, error)
+	// Show returns the loan with its schedule and payments
+	Show(ctx context.Context, id string) (*loan.Loan, error)
+	// Reports returns the reporting service over the backend's loans
+	Reports() *loan.ReportingService
+}
+
+// direct runs the loan service in process on the database. Nothing is
+// published: webhooks and notifications only follow changes made through
+// loan-api.
+type direct struct {
+	repo loan.LoanRepository
+	svc  *loan.LoanService
+}
+
+func newDirect(repo loan.LoanRepository) *direct {
+	return &direct{repo: repo, svc: loan.NewLoanService(repo)}
+}
+
+func (d *direct) Apply(ctx context.Context, l *loan.Loan) (*loan.Loan, error) {
+	return l, d.svc.ProcessLoanApplication(ctx, l)
+}
+
+func (d *direct) Approve(ctx context.Context, id string) (*loan.Loan, error) {
+	return d.svc.ApproveLoan(ctx, id)
+}
+
+func (d *direct) Reject(ctx context.Context, id, reason string) (*loan.Loan, error) {
+	return d.svc.RejectLoan(ctx, id, reason)
+}
+
+func (d *direct) Pay(ctx context.Context, id string, amount float64) (loan.Payment, error) {
+	return d.svc.RecordPayment(ctx, id, amount)
+}
+
+func (d *direct) List(ctx context.Context, filter loan.Filter) ([]*loan.Loan, error) {
+	return d.svc.ListLoans(ctx, filter)
+}
+
+func (d *direct) Show(ctx context.Context, id string) (*loan.Loan, error) {
+	return d.svc.GetLoan(ctx, id)
+}
+
+func (d *direct) Reports() *loan.ReportingService {
+	return loan.NewReportingService(d.repo)
+}
+
+// errReadOnly is returned by readOnly's writes
+var errReadOnly = errors.New("loanctl: reports cannot change loans")
+
+// readOnly adapts a backend to the loan.LoanRepository reports read from
+type readOnly struct {
+	backend
+}
+
+func (r readOnly) Save(context.Context, *loan.Loan) error   { return errReadOnly }
+func (r readOnly) Update(context.Context, *loan.Loan) error { return errReadOnly }
+
+func (r readOnly) FindByID(ctx context.Context, id string) (*loan.Loan, error) {
+	return r.Show(ctx, id)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"loan"
+	"loan/api"
+	"loan/filterexpr"
+)
+
+// client is the backend of a running loan-api, spoken to in API v2
+type client struct {
+	base string
+	http *http.Client
+	// header is added to every request, e.g. the caller's role
+	header http.Header
+}
+
+func newClient(base string, header http.Header) *client {
+	return &client{
+		base:   strings.TrimSuffix(base, "/") + "/v2",
+		http:   &http.Client{Timeout: 30 * time.Second},
+		header: header,
+	}
+}
+
+// do sends a request with body encoded as JSON, when not nil, and decodes
+// the response into out. Error responses are returned as errors carrying
+// the API's code and message.
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, r)
+	if err != nil {
+		return err
+	}
+	for k, vs := range c.header {
+		req.Header[k] = vs
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		var e api.ErrorBody
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error.Code == "" {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return apiError(e.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// apiError formats an error response, field problems in name order
+func apiError(d api.ErrorDetail) error {
+	msg := d.Code + ": " + d.Message
+	keys := make([]string, 0, len(d.Fields))
+	for k := range d.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		msg += fmt.Sprintf("; %s %s", k, d.Fields[k])
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+func (c *client) Apply(ctx context.Context, l *loan.Loan) (*loan.Loan, error) {
+	var out api.LoanV2
+	err := c.do(ctx, http.MethodPost, "/applications", api.ApplicationRequestV2{
+		CustomerID: l.CustomerID, Amount: money(l.Amount),
+		InterestRate: l.InterestRate, TermMonths: l.TermMonths, Product: l.Product, Purpose: l.Purpose,
+		Frequency: l.Frequency,
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return fromV2(out)
+}
+
+func (c *client) Approve(ctx context.Context, id string) (*loan.Loan, error) {
+	var out api.LoanV2
+	if err := c.do(ctx, http.MethodPost, "/loans/"+url.PathEscape(id)+"/approve", nil, &out); err != nil {
+		return nil, err
+	}
+	return fromV2(out)
+}
+
+func (c *client) Reject(ctx context.Context, id, reason string) (*loan.Loan, error) {
+	var out api.LoanV2
+	if err := c.do(ctx, http.MethodPost, "/loans/"+url.PathEscape(id)+"/reject", api.RejectRequest{Reason: reason}, &out); err != nil {
+		return nil, err
+	}
+	return fromV2(out)
+}
+
+func (c *client) Pay(ctx context.Context, id string, amount float64) (loan.Payment, error) {
+	var out api.PaymentV2
+	if err := c.do(ctx, http.MethodPost, "/loans/"+url.PathEscape(id)+"/payments", api.PaymentRequestV2{Amount: money(amount)}, &out); err != nil {
+		return loan.Payment{}, err
+	}
+	return paymentFromV2(out)
+}
+
+func (c *client) List(ctx context.Context, filter loan.Filter) ([]*loan.Loan, error) {
+	q := url.Values{"status": filter.Statuses, "limit": {strconv.Itoa(api.MaxPageSize)}}
+	if filter.CustomerID != "" {
+		q.Set("customerId", filter.CustomerID)
+	}
+	if len(filter.Conditions) > 0 {
+		q.Set("filter", filterexpr.Format(filter.Conditions))
+	}
+	loans := []*loan.Loan{}
+	for {
+		var out api.ListResponseV2
+		if err := c.do(ctx, http.MethodGet, "/loans?"+q.Encode(), nil, &out); err != nil {
+			return nil, err
+		}
+		for _, l := range out.Loans {
+			converted, err := fromV2(l)
+			if err != nil {
+				return nil, err
+			}
+			loans = append(loans, converted)
+		}
+		if out.NextCursor == "" {
+			return loans, nil
+		}
+		q.Set("cursor", out.NextCursor)
+	}
+}
+
+func (c *client) Show(ctx context.Context, id string) (*loan.Loan, error) {
+	path := "/loans/" + url.PathEscape(id)
+	var l api.LoanV2
+	if err := c.do(ctx, http.MethodGet, path, nil, &l); err != nil {
+		return nil, err
+	}
+	out, err := fromV2(l)
+	if err != nil {
+		return nil, err
+	}
+	var schedule api.ScheduleResponseV2
+	if err := c.do(ctx, http.MethodGet, path+"/schedule", nil, &schedule); err != nil {
+		return nil, err
+	}
+	for _, i := range schedule.Installments {
+		var a amounts
+		inst := loan.Installment{
+			Number: i.Number, DueDate: i.DueDate, Principal: a.parse(i.Principal),
+			Interest: a.parse(i.Interest), Amount: a.parse(i.Amount), Paid: a.parse(i.Paid),
+		}
+		if i.Escrow != nil {
+			inst.Escrow = a.parse(*i.Escrow)
+		}
+		if i.Premium != nil {
+			inst.Premium = a.parse(*i.Premium)
+		}
+		out.Schedule = append(out.Schedule, inst)
+		if a.err != nil {
+			return nil, a.err
+		}
+	}
+	var payments api.PaymentsResponseV2
+	if err := c.do(ctx, http.MethodGet, path+"/payments", nil, &payments); err != nil {
+		return nil, err
+	}
+	for _, p := range payments.Payments {
+		payment, err := paymentFromV2(p)
+		if err != nil {
+			return nil, err
+		}
+		out.Payments = append(out.Payments, payment)
+	}
+	return out, nil
+}
+
+// Reports reads the loans through the API. API v2 does not expose credit
+// scores, so every loan reports under the "unrated" risk grade.
+func (c *client) Reports() *loan.ReportingService {
+	return loan.NewReportingService(readOnly{c})
+}
+
+func money(v float64) api.Money {
+	return api.Money{Amount: strconv.FormatFloat(v, 'f', 2, 64), Currency: api.DefaultCurrency}
+}
+
+// amounts parses Money values, keeping the first error
+type amounts struct{ err error }
+
+func (a *amounts) parse(m api.Money) float64 {
+	v, err := strconv.ParseFloat(m.Amount, 64)
+	if err != nil && a.err == nil {
+		a.err = fmt.Errorf("loanctl: amount %q: %w", m.Amount, err)
+	}
+	return v
+}
+
+func fromV2(l api.LoanV2) (*loan.Loan, error) {
+	var a amounts
+	out := &loan.Loan{
+		ID: l.ID, CustomerID: l.CustomerID, Status: l.Status, Amount: a.parse(l.Principal),
+		InterestRate: l.InterestRate, TermMonths: l.TermMonths, Product: l.Product, Purpose: l.Purpose,
+		Amortization: l.Amortization, Frequency: l.Frequency, Prepayment: l.Prepayment, Escrow: l.Escrow, Insurance: l.Insurance, Balance: a.parse(l.Balance), AccruedInterest: a.parse(l.AccruedInterest),
+		CreatedAt: l.CreatedAt, DaysPastDue: l.DaysPastDue, Delinquency: loan.Bucket(l.Delinquency),
+		RejectionReason: l.RejectionReason, Decision: l.Decision, Screening: l.Screening, TermsChanges: l.TermsChanges, Currency: l.Principal.Currency,
+	}
+	if l.ApprovedAt != nil {
+		out.ApprovedAt = *l.ApprovedAt
+	}
+	if l.DisbursedAt != nil {
+		out.DisbursedAt = *l.DisbursedAt
+	}
+	return out, a.err
+}
+
+func paymentFromV2(p api.PaymentV2) (loan.Payment, error) {
+	var a amounts
+	out := loan.Payment{
+		ID: p.ID, LoanID: p.LoanID, PaidAt: p.PaidAt,
+		Amount: a.parse(p.Amount), Interest: a.parse(p.Interest), Principal: a.parse(p.Principal),
+	}
+	if p.Penalty != nil {
+		out.Penalty = a.parse(*p.Penalty)
+	}
+	if p.Escrow != nil {
+		out.Escrow = a.parse(*p.Escrow)
+	}
+	if p.Premium != nil {
+		out.Premium = a.parse(*p.Premium)
+	}
+	return out, a.err
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"loan"
+	"loan/tui"
+)
+
+// dashboard runs the full-screen view of the loan book: portfolio figures
+// refreshed live, the loans filtered by status and search text, and a
+// loan's schedule and history on enter
+func dashboard(ctx context.Context, b backend, out *output, fs *flag.FlagSet, args []string) error {
+	every := fs.Duration("refresh", 5*time.Second, "how often the figures and loans are reloaded")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *every <= 0 {
+		return fmt.Errorf("dashboard: -refresh must be positive")
+	}
+	_, err := tui.NewProgram(&board{ctx: ctx, b: b, every: *every}).Run()
+	return err
+}
+
+// statusFilters are cycled through with the s key; empty shows every loan
+var statusFilters = []string{"", loan.StatusPending, loan.StatusApproved, loan.StatusDefault, loan.StatusRejected}
+
+// board is the dashboard's tui.Model
+type board struct {
+	ctx   context.Context
+	b     backend
+	every time.Duration
+
+	width, height int
+
+	report  loan.PortfolioReport
+	loans   []*loan.Loan
+	updated time.Time
+	err     error
+
+	// shown are the loans passing the status filter and search
+	shown  []*loan.Loan
+	status int
+	search string
+	typing bool
+	cursor int
+	offset int
+
+	// detail is the loan opened, nil on the list
+	detail       *loan.Loan
+	detailOffset int
+}
+
+// bookMsg carries a reload of the figures and loans
+type bookMsg struct {
+	report loan.PortfolioReport
+	loans  []*loan.Loan
+	err    error
+	at     time.Time
+}
+
+// loanMsg carries the loan opened
+type loanMsg struct {
+	loan *loan.Loan
+	err  error
+}
+
+type refreshMsg struct{}
+
+func (m *board) Init() tui.Cmd { return m.load }
+
+// load reads the report and the loans, newest first
+func (m *board) load() tui.Msg {
+	report, err := m.b.Reports().Portfolio(m.ctx)
+	if err != nil {
+		return bookMsg{err: err, at: time.Now()}
+	}
+	loans, err := m.b.List(m.ctx, loan.Filter{})
+	sort.SliceStable(loans, func(i, j int) bool { return loans[i].CreatedAt.After(loans[j].CreatedAt) })
+	return bookMsg{report: report, loans: loans, err: err, at: time.Now()}
+}
+
+func (m *board) open(id string) tui.Cmd {
+	return func() tui.Msg {
+		l, err := m.b.Show(m.ctx, id)
+		return loanMsg{loan: l, err: err}
+	}
+}
+
+func (m *board) Update(msg tui.Msg) (tui.Model, tui.Cmd) {
+	switch msg := msg.(type) {
+	case tui.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.clamp()
+	case bookMsg:
+		m.updated, m.err = msg.at, msg.err
+		if msg.err == nil {
+			m.report, m.loans = msg.report, msg.loans
+			m.filter()
+		}
+		return m, tui.Tick(m.every, func(time.Time) tui.Msg { return refreshMsg{} })
+	case refreshMsg:
+		return m, m.load
+	case loanMsg:
+		m.err = msg.err
+		if msg.err == nil {
+			m.detail, m.detailOffset = msg.loan, 0
+		}
+	case tui.KeyMsg:
+		if m.detail != nil {
+			return m.detailKey(msg.Key)
+		}
+		if m.typing {
+			m.searchKey(msg.Key)
+			return m, nil
+		}
+		return m.listKey(msg.Key)
+	}
+	return m, nil
+}
+
+func (m *board) listKey(key string) (tui.Model, tui.Cmd) {
+	switch key {
+	case "q":
+		return m, tui.Quit
+	case "r":
+		return m, m.load
+	case "/":
+		m.typing = true
+	case "s":
+		m.status = (m.status + 1) % len(statusFilters)
+		m.filter()
+	case "up", "k":
+		m.cursor--
+	case "down", "j":
+		m.cursor++
+	case "pgup":
+		m.cursor -= m.rows()
+	case "pgdown":
+		m.cursor += m.rows()
+	case "home":
+		m.cursor = 0
+	case "end":
+		m.cursor = len(m.shown) - 1
+	case "enter":
+		if m.cursor < len(m.shown) {
+			return m, m.open(m.shown[m.cursor].ID)
+		}
+	}
+	m.clamp()
+	return m, nil
+}
+
+func (m *board) searchKey(key string) {
+	switch key {
+	case "enter":
+		m.typing = false
+	case "esc":
+		m.typing, m.search = false, ""
+	case "backspace":
+		if r := []rune(m.search); len(r) > 0 {
+			m.search = string(r[:len(r)-1])
+		}
+	default:
+		if len([]rune(key)) == 1 {
+			m.search += key
+		}
+	}
+	m.filter()
+}
+
+func (m *board) detailKey(key string) (tui.Model, tui.Cmd) {
+	switch key {
+	case "q":
+		return m, tui.Quit
+	case "esc", "backspace", "left":
+		m.detail = nil
+	case "r":
+		return m, m.open(m.detail.ID)
+	case "up", "k":
+		m.detailOffset = max(m.detailOffset-1, 0)
+	case "down", "j":
+		m.detailOffset++
+	case "pgup":
+		m.detailOffset = max(m.detailOffset-m.height/2, 0)
+	case "pgdown":
+		m.detailOffset += m.height / 2
+	}
+	return m, nil
+}
+
+// filter recomputes the loans shown, keeping the cursor on the same loan
+// when it is still shown
+func (m *board) filter() {
+	var selected string
+	if m.cursor < len(m.shown) {
+		selected = m.shown[m.cursor].ID
+	}
+	status, search := statusFilters[m.status], strings.ToLower(m.search)
+	m.shown = m.shown[:0]
+	for _, l := range m.loans {
+		if status != "" && l.Status != status {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(l.ID+" "+l.CustomerID+" "+l.Product), search) {
+			continue
+		}
+		if l.ID == selected {
+			m.cursor = len(m.shown)
+		}
+		m.shown = append(m.shown, l)
+	}
+	m.clamp()
+}
+
+// headerLines and footerLines are the lines around the loan table
+const headerLines, footerLines = 6, 2
+
+// rows is how many loans fit on the screen
+func (m *board) rows() int {
+	return max(m.height-headerLines-footerLines, 1)
+}
+
+// clamp keeps the cursor on a loan and scrolls the table to it
+func (m *board) clamp() {
+	m.cursor = max(min(m.cursor, len(m.shown)-1), 0)
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.rows() {
+		m.offset = m.cursor - m.rows() + 1
+	}
+}
+
+func (m *board) View() string {
+	if m.detail != nil {
+		return m.detailView()
+	}
+	var b strings.Builder
+	r := m.report
+	updated := "loading..."
+	if !m.updated.IsZero() {
+		updated = "updated " + m.updated.Format(time.TimeOnly)
+	}
+	fmt.Fprintf(&b, "%s  %s\n", tui.Bold("Loan book"), tui.Faint(fmt.Sprintf("%s, every %s", updated, m.every)))
+	fmt.Fprintf(&b, "Loans %d   Exposure %s %s   Avg ticket %s   NPL %s   Loss allowance %s\n",
+		len(m.loans), amount(r.Exposure.Total), r.Exposure.Base, amount(r.AverageTicketSize),
+		nplText(r.NPLRatio), amount(r.LossAllowance))
+	var groups []string
+	for _, g := range r.OutstandingByStatus {
+		groups = append(groups, fmt.Sprintf("%s %d (%s)", statusText(g.Key), g.Count, amount(g.Outstanding)))
+	}
+	fmt.Fprintf(&b, "%s\n", strings.Join(groups, "   "))
+	if m.err != nil {
+		fmt.Fprintf(&b, "%s\n", tui.Red("error: "+m.err.Error()))
+	} else {
+		b.WriteString("\n")
+	}
+	status := statusFilters[m.status]
+	if status == "" {
+		status = "all"
+	}
+	search := m.search
+	if m.typing {
+		search += "_"
+	}
+	fmt.Fprintf(&b, "status %s   search %s   %d shown\n", tui.Bold(status), tui.Bold(search), len(m.shown))
+	b.WriteString(tui.Bold(loanRow("ID", "CUSTOMER", "STATUS", "AMOUNT", "BALANCE", "DPD", "CREATED")) + "\n")
+	end := min(m.offset+m.rows(), len(m.shown))
+	for i := m.offset; i < end; i++ {
+		l := m.shown[i]
+		row := loanRow(l.ID, l.CustomerID, l.Status, amount(l.Amount), amount(l.Balance), fmt.Sprint(l.DaysPastDue), date(l.CreatedAt))
+		if i == m.cursor {
+			row = tui.Reverse(tui.Pad(row, m.width))
+		}
+		b.WriteString(row + "\n")
+	}
+	for i := end - m.offset; i < m.rows(); i++ {
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	if m.typing {
+		b.WriteString(tui.Faint("type to search id, customer or product · enter keep · esc clear"))
+	} else {
+		b.WriteString(tui.Faint("↑/↓ move · enter open · / search · s status · r refresh · q quit"))
+	}
+	return b.String()
+}
+
+func loanRow(id, customer, status, amount, balance, dpd, created string) string {
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return fmt.Sprintf("%-12s  %-12s  %-9s  %12s  %12s  %4s  %-10s", id, customer, status, amount, balance, dpd, created)
+}
+
+// statusText colours a loan status by how the loan is doing
+func statusText(s string) string {
+	switch s {
+	case loan.StatusDefault:
+		return tui.Red(s)
+	case loan.StatusPending:
+		return tui.Yellow(s)
+	case loan.StatusApproved:
+		return tui.Green(s)
+	}
+	return s
+}
+
+func nplText(ratio float64) string {
+	s := fmt.Sprintf("%.2f%%", ratio*100)
+	if ratio > 0.05 {
+		return tui.Red(s)
+	}
+	return s
+}
+
+// event is a line of a loan's history
+type event struct {
+	at   time.Time
+	text string
+}
+
+// history lists what happened to l, oldest first
+func history(l *loan.Loan) []event {
+	events := []event{{l.CreatedAt, "applied for " + amount(l.Amount)}}
+	switch {
+	case !l.ApprovedAt.IsZero():
+		events = append(events, event{l.ApprovedAt, "approved"})
+	case l.Status == loan.StatusRejected:
+		events = append(events, event{l.CreatedAt, "rejected: " + l.RejectionReason})
+	}
+	if !l.DisbursedAt.IsZero() {
+		events = append(events, event{l.DisbursedAt, "disbursed"})
+	}
+	for _, p := range l.Payments {
+		events = append(events, event{p.PaidAt, fmt.Sprintf("paid %s (principal %s, interest %s)",
+			amount(p.Amount), amount(p.Principal), amount(p.Interest))})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+	return events
+}
+
+func (m *board) detailView() string {
+	l := m.detail
+	lines := []string{
+		fmt.Sprintf("%s %s", tui.Bold("Loan"), l.ID),
+		fmt.Sprintf("customer %s   status %s   product %s", l.CustomerID, statusText(l.Status), orDash(l.Product)),
+		fmt.Sprintf("amount %s   rate %.2f%%   term %d months   balance %s   accrued %s",
+			amount(l.Amount), l.AnnualRate()*100, l.TermMonths, amount(l.Balance), amount(l.AccruedInterest)),
+		fmt.Sprintf("days past due %d   delinquency %s", l.DaysPastDue, orDash(string(l.Delinquency))),
+		"",
+		tui.Bold("Schedule"),
+		tui.Bold(fmt.Sprintf("%3s  %-10s  %12s  %12s  %12s  %12s", "#", "DUE", "AMOUNT", "PRINCIPAL", "INTEREST", "PAID")),
+	}
+	now := time.Now()
+	for _, i := range l.Schedule {
+		line := fmt.Sprintf("%3d  %-10s  %12s  %12s  %12s  %12s", i.Number, date(i.DueDate),
+			amount(i.Amount), amount(i.Principal), amount(i.Interest), amount(i.Paid))
+		switch {
+		case i.Outstanding() == 0:
+			line = tui.Faint(line)
+		case i.DueDate.Before(now):
+			line = tui.Red(line)
+		}
+		lines = append(lines, line)
+	}
+	if len(l.Schedule) == 0 {
+		lines = append(lines, tui.Faint("no schedule until approved"))
+	}
+	lines = append(lines, "", tui.Bold("History"))
+	for _, e := range history(l) {
+		lines = append(lines, fmt.Sprintf("%-10s  %s", date(e.at), e.text))
+	}
+
+	body := max(m.height-footerLines, 1)
+	m.detailOffset = max(min(m.detailOffset, len(lines)-body), 0)
+	end := min(m.detailOffset+body, len(lines))
+	var b strings.Builder
+	for _, line := range lines[m.detailOffset:end] {
+		b.WriteString(line + "\n")
+	}
+	for i := end - m.detailOffset; i < body; i++ {
+		b.WriteString("\n")
+	}
+	if m.err != nil {
+		b.WriteString(tui.Red("error: "+m.err.Error()) + "\n")
+	} else {
+		b.WriteString("\n")
+	}
+	b.WriteString(tui.Faint("↑/↓ scroll · esc back · r reload · q quit"))
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
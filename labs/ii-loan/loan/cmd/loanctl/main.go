@@ -0,0 +1,388 @@
+// Command loanctl administers loans from the shell, through the REST API
+// of a running loan-api or directly on its database.
+//
+//	loanctl -api http://localhost:8080 apply -customer c-42 -amount 50000 -rate 0.12 -term 24
+//	loanctl -api http://localhost:8080 approve 3f2a...
+//	loanctl -dsn file:loan.db reject -reason "insufficient income" 3f2a...
+//	loanctl -dsn file:loan.db pay -amount 2500 3f2a...
+//	loanctl list -status approved -customer c-42
+//	loanctl list -filter 'product = PL AND amount > 5000'
+//	loanctl -json show 3f2a...
+//	loanctl report
+//	loanctl -api http://localhost:8080 dashboard -refresh 10s
+//
+// Changes made on the database directly publish no events, so they send no
+// webhooks or notifications; use -api where those matter.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"loan"
+	"loan/filterexpr"
+	"loan/sqlstore"
+	_ "loan/sqlstore/drivers"
+)
+
+type strs []string
+
+func (s *strs) String() string     { return strings.Join(*s, ",") }
+func (s *strs) Set(v string) error { *s = append(*s, v); return nil }
+
+// errUsage is returned for malformed command lines, after the usage has
+// been printed
+var errUsage = errors.New("usage")
+
+func main() {
+	apiURL := flag.String("api", "", "base URL of loan-api, e.g. http://localhost:8080 (empty works on the database)")
+	driver := flag.String("driver", "sqlite", "database/sql driver without -api: sqlite or pgx")
+	dsn := flag.String("dsn", "file:loan.db", "data source name without -api")
+	asJSON := flag.Bool("json", false, "write results as JSON instead of text")
+	timeout := flag.Duration("timeout", time.Minute, "time allowed for the command")
+	var headers strs
+	flag.Var(&headers, "header", "\"Name: value\" header sent with -api requests, e.g. the role header (repeatable)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: loanctl [flags] apply | approve | reject | pay | list | show | report | dashboard [args]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	err := func() error {
+		var b backend
+		if *apiURL != "" {
+			header := http.Header{}
+			for _, h := range headers {
+				name, value, ok := strings.Cut(h, ":")
+				if !ok {
+					return fmt.Errorf("-header %q: want \"Name: value\"", h)
+				}
+				header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+			}
+			b = newClient(*apiURL, header)
+		} else {
+			db, err := sqlstore.Open(ctx, *driver, *dsn)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			b = newDirect(sqlstore.NewLoanRepository(db))
+		}
+		out := &output{w: os.Stdout, json: *asJSON}
+		return run(ctx, b, out, flag.Args())
+	}()
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loanctl:", err)
+		os.Exit(1)
+	}
+}
+
+// command parses its arguments and carries itself out on a backend
+type command struct {
+	usage string
+	run   func(ctx context.Context, b backend, out *output, fs *flag.FlagSet, args []string) error
+}
+
+var commands = map[string]command{
+	"apply":     {"-customer ID -amount N -rate R -term MONTHS [-product CODE] [-purpose P] [-frequency F]", apply},
+	"approve":   {"LOAN", approve},
+	"reject":    {"-reason TEXT LOAN", reject},
+	"pay":       {"-amount N LOAN", pay},
+	"list":      {"[-status S]... [-customer ID] [-filter EXPR]", list},
+	"show":      {"LOAN", show},
+	"report":    {"", report},
+	"dashboard": {"[-refresh INTERVAL]", dashboard},
+}
+
+func run(ctx context.Context, b backend, out *output, args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		flag.Usage()
+		return errUsage
+	}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: loanctl [flags] %s %s\n", args[0], cmd.usage)
+		fs.PrintDefaults()
+	}
+	return cmd.run(ctx, b, out, fs, args[1:])
+}
+
+// parse parses the flags of a command and checks it was given n
+// positional arguments
+func parse(fs *flag.FlagSet, args []string, n int) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() != n {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// loanArg parses a command taking only a loan ID
+func loanArg(fs *flag.FlagSet, args []string) (string, error) {
+	if err := parse(fs, args, 1); err != nil {
+		return "", err
+	}
+	return fs.Arg(0), nil
+}
+
+func apply(ctx context.Context, b backend, out *output, fs *flag.FlagSet, args []string) error {
+	l := &loan.Loan{}
+	fs.StringVar(&l.CustomerID, "customer", "", "customer ID")
+	fs.Float64Var(&l.Amount, "amount", 0, "amount applied for")
+	fs.Float64Var(&l.InterestRate, "rate", 0, "annual interest rate, e.g. 0.12 (0 for the tiered default)")
+	fs.IntVar(&l.TermMonths, "term", 12, "term in months")
+	fs.StringVar(&l.Product, "product", "", "loan product code")
+	fs.StringVar(&l.Purpose, "purpose", "", "what the loan is for: "+strings.Join(loan.Purposes, ", "))
+	fs.StringVar(&l.Frequency, "frequency", "", "how often installments fall due: "+strings.Join(loan.Frequencies, ", "))
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	l, err := b.Apply(ctx, l)
+	if err != nil {
+		return err
+	}
+	return out.loan(l)
+}
+
+func approve(ctx context.Context, b backend, out *output, fs *flag.FlagSet, args []string) error {
+	id, err := loanArg(fs, args)
+	if err != nil {
+		return err
+	}
+	l, err := b.Approve(ctx, id)
+	if err != nil {
+		return err
+	}
+	return out.loan(l)
+}
+
+func reject(ctx context.Context, b backend, out *output, fs *flag.FlagSet, args []string) error {
+	reason := fs.String("reason", "", "reason given to the customer")
+	id, err := loanArg(fs, args)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(*reason) == "" {
+		return errors.New("reject: -reason is required")
+	}
+	l, err := b.Reject(ctx, id, *reason)
+	if err != nil {
+		return err
+	}
+	return out.loan(l)
+}
+
+func pay(ctx context.Context, b backend, out *output, fs *flag.FlagSet, args []string) error {
+	amount := fs.Float64("amount", 0, "amount repaid")
+	id, err := loanArg(fs, args)
+	if err != nil {
+		return err
+	}
+	p, err := b.Pay(ctx, id, *amount)
+	if err != nil {
+		return err
+	}
+	return out.payment(p)
+}
+
+func list(ctx context.Context, b backend, out *output, fs *flag.FlagSet, args []string) error {
+	var filter loan.Filter
+	fs.Var((*strs)(&filter.Statuses), "status", "only list loans in this status (repeatable)")
+	fs.StringVar(&filter.CustomerID, "customer", "", "only list the loans of this customer")
+	expr := fs.String("filter", "", "only list loans matching this expression, e.g. 'amount > 5000 AND createdAt >= 2024-01-01'")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	conds, err := filterexpr.Parse(*expr)
+	if err != nil {
+		return err
+	}
+	filter.Conditions = conds
+	loans, err := b.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return out.loans(loans)
+}
+
+func show(ctx context.Context, b backend, out *output, fs *flag.FlagSet, args []string) error {
+	id, err := loanArg(fs, args)
+	if err != nil {
+		return err
+	}
+	l, err := b.Show(ctx, id)
+	if err != nil {
+		return err
+	}
+	return out.loan(l)
+}
+
+// report prints the portfolio report of the book
+func report(ctx context.Context, b backend, out *output, fs *flag.FlagSet, args []string) error {
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	r, err := b.Reports().Portfolio(ctx)
+	if err != nil {
+		return err
+	}
+	return out.report(r)
+}
+
+// output writes results as aligned text or as JSON
+type output struct {
+	w    io.Writer
+	json bool
+}
+
+func (o *output) encode(v any) error {
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (o *output) loan(l *loan.Loan) error {
+	if o.json {
+		return o.encode(l)
+	}
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(tw, "%s\t%s\n", name, value)
+		}
+	}
+	field("id", l.ID)
+	field("customer", l.CustomerID)
+	field("status", l.Status)
+	field("product", l.Product)
+	field("purpose", l.Purpose)
+	field("amount", amount(l.Amount))
+	field("rate", strconv.FormatFloat(l.AnnualRate(), 'f', -1, 64))
+	field("term", fmt.Sprintf("%d months", l.TermMonths))
+	field("frequency", l.Frequency)
+	if len(l.Schedule) > 0 {
+		field("amortization", l.AmortizationMethod())
+		field("total interest", amount(loan.TotalInterest(l.Schedule)))
+	}
+	field("balance", amount(l.Balance))
+	field("created", date(l.CreatedAt))
+	field("approved", date(l.ApprovedAt))
+	field("disbursed", date(l.DisbursedAt))
+	if l.DaysPastDue > 0 {
+		field("days past due", fmt.Sprintf("%d (%s)", l.DaysPastDue, l.Delinquency))
+	}
+	field("rejection reason", l.RejectionReason)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(l.Schedule) > 0 {
+		fmt.Fprintln(o.w)
+		fmt.Fprintln(tw, "#\tDUE\tAMOUNT\tPRINCIPAL\tINTEREST\tPAID")
+		for _, i := range l.Schedule {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", i.Number, date(i.DueDate),
+				amount(i.Amount), amount(i.Principal), amount(i.Interest), amount(i.Paid))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(l.Payments) > 0 {
+		fmt.Fprintln(o.w)
+		return o.paymentRows(l.Payments)
+	}
+	return nil
+}
+
+func (o *output) payment(p loan.Payment) error {
+	if o.json {
+		return o.encode(p)
+	}
+	return o.paymentRows([]loan.Payment{p})
+}
+
+func (o *output) paymentRows(payments []loan.Payment) error {
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PAYMENT\tPAID\tAMOUNT\tPRINCIPAL\tINTEREST")
+	for _, p := range payments {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.ID, date(p.PaidAt), amount(p.Amount), amount(p.Principal), amount(p.Interest))
+	}
+	return tw.Flush()
+}
+
+func (o *output) loans(loans []*loan.Loan) error {
+	if o.json {
+		if loans == nil {
+			loans = []*loan.Loan{}
+		}
+		return o.encode(loans)
+	}
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCUSTOMER\tSTATUS\tAMOUNT\tBALANCE\tDPD\tCREATED")
+	for _, l := range loans {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", l.ID, l.CustomerID, l.Status,
+			amount(l.Amount), amount(l.Balance), l.DaysPastDue, date(l.CreatedAt))
+	}
+	return tw.Flush()
+}
+
+func (o *output) report(r loan.PortfolioReport) error {
+	if o.json {
+		return o.encode(r)
+	}
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "average ticket\t%s\n", amount(r.AverageTicketSize))
+	fmt.Fprintf(tw, "NPL ratio\t%.2f%%\n", r.NPLRatio*100)
+	fmt.Fprintf(tw, "exposure\t%s %s\n", amount(r.Exposure.Total), r.Exposure.Base)
+	fmt.Fprintf(tw, "loss allowance\t%s\n", amount(r.LossAllowance))
+	for _, section := range []struct {
+		title  string
+		groups []loan.Group
+	}{
+		{"STATUS", r.OutstandingByStatus},
+		{"PRODUCT", r.OutstandingByProduct},
+		{"GRADE", r.OutstandingByGrade},
+		{"ORIGINATED", r.Originations},
+	} {
+		fmt.Fprintf(tw, "\n%s\tLOANS\tPRINCIPAL\tOUTSTANDING\n", section.title)
+		for _, g := range section.groups {
+			key := g.Key
+			if key == "" {
+				key = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", key, g.Count, amount(g.Principal), amount(g.Outstanding))
+		}
+	}
+	return tw.Flush()
+}
+
+func amount(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+
+func date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
@@ -0,0 +1,95 @@
+// Command migrate applies, reverts and lists the embedded schema
+// migrations of the SQL repositories.
+//
+//	migrate -driver sqlite -dsn file:loan.db up
+//	migrate -driver pgx -dsn postgres://localhost/loan down 1
+//	migrate status
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"loan/sqlstore"
+	_ "loan/sqlstore/drivers"
+)
+
+func main() {
+	driver := flag.String("driver", "sqlite", "database/sql driver: sqlite or pgx")
+	dsn := flag.String("dsn", "file:loan.db", "data source name")
+	timeout := flag.Duration("timeout", time.Minute, "time allowed for the command")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: migrate [flags] up | down [steps] | status\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*driver, *dsn, *timeout, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(driver, dsn string, timeout time.Duration, args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	db, err := sqlstore.Open(ctx, driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	m, err := sqlstore.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := m.Up(ctx)
+		for _, mig := range ran {
+			fmt.Printf("applied %04d %s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(ran) == 0 {
+			fmt.Println("already up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("down: steps must be a positive number, got %q", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, mig := range reverted {
+			fmt.Printf("reverted %04d %s\n", mig.Version, mig.Name)
+		}
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied() {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
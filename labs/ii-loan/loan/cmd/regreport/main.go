@@ -0,0 +1,74 @@
+// Command regreport writes the monthly regulatory lending return of a
+// database to stdout. Nothing is written unless every record passes
+// validation against the layout.
+//
+//	regreport -dsn file:loan.db -institution BANK01 -period 2026-09 > return.txt
+//	regreport -layout layout.json -period 2026-09
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"loan/regreport"
+	"loan/sqlstore"
+	_ "loan/sqlstore/drivers"
+)
+
+func main() {
+	driver := flag.String("driver", "sqlite", "database/sql driver: sqlite or pgx")
+	dsn := flag.String("dsn", "file:loan.db", "data source name")
+	layoutFile := flag.String("layout", "", "JSON record layout (empty uses the default layout)")
+	institution := flag.String("institution", "LOANLAB", "reporting institution code for the default layout")
+	period := flag.String("period", time.Now().AddDate(0, -1, 0).Format("2006-01"), "reporting month, YYYY-MM")
+	flag.Parse()
+
+	if err := run(*driver, *dsn, *layoutFile, *institution, *period); err != nil {
+		var verr *regreport.ValidationError
+		if errors.As(err, &verr) {
+			fmt.Fprintln(os.Stderr, "regreport: validation failed, nothing written:")
+			for _, p := range verr.Problems {
+				fmt.Fprintln(os.Stderr, "  "+p.String())
+			}
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "regreport:", err)
+		os.Exit(1)
+	}
+}
+
+func run(driver, dsn, layoutFile, institution, period string) error {
+	month, err := time.Parse("2006-01", period)
+	if err != nil {
+		return fmt.Errorf("invalid -period %q, want YYYY-MM", period)
+	}
+	layout := regreport.DefaultLayout(institution)
+	if layoutFile != "" {
+		f, err := os.Open(layoutFile)
+		if err != nil {
+			return err
+		}
+		layout, err = regreport.LoadLayout(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	db, err := sqlstore.Open(ctx, driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	report, err := regreport.Build(ctx, sqlstore.NewLoanRepository(db), month)
+	if err != nil {
+		return err
+	}
+	return layout.Write(os.Stdout, report)
+}
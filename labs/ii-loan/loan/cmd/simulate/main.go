@@ -0,0 +1,75 @@
+// Command simulate runs a Monte Carlo simulation of credit losses on the
+// loan book of a database and writes the loss distribution as JSON.
+//
+//	simulate -dsn file:loan.db -scenarios 50000 -seed 42
+//	simulate -assumptions stress.json -workers 4
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"loan"
+	"loan/simulation"
+	"loan/sqlstore"
+	_ "loan/sqlstore/drivers"
+)
+
+func main() {
+	driver := flag.String("driver", "sqlite", "database/sql driver: sqlite or pgx")
+	dsn := flag.String("dsn", "file:loan.db", "data source name")
+	assumptions := flag.String("assumptions", "", "JSON assumptions (empty uses the defaults)")
+	scenarios := flag.Int("scenarios", simulation.DefaultScenarios, "number of scenarios")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "scenarios run in parallel")
+	seed := flag.Uint64("seed", 0, "random seed; 0 draws one")
+	timeout := flag.Duration("timeout", 10*time.Minute, "time allowed for the simulation")
+	flag.Parse()
+
+	opts := []simulation.Option{simulation.WithScenarios(*scenarios), simulation.WithWorkers(*workers)}
+	if *seed != 0 {
+		opts = append(opts, simulation.WithSeed(*seed))
+	}
+	if err := run(*driver, *dsn, *assumptions, *timeout, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "simulate:", err)
+		os.Exit(1)
+	}
+}
+
+func run(driver, dsn, assumptionsFile string, timeout time.Duration, opts []simulation.Option) error {
+	a := simulation.DefaultAssumptions
+	if assumptionsFile != "" {
+		f, err := os.Open(assumptionsFile)
+		if err != nil {
+			return err
+		}
+		a, err = simulation.LoadAssumptions(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	db, err := sqlstore.Open(ctx, driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	loans, err := sqlstore.NewLoanRepository(db).List(ctx, loan.Filter{Statuses: []string{loan.StatusApproved, loan.StatusDefault}})
+	if err != nil {
+		return err
+	}
+	res, err := simulation.Run(ctx, loans, a, opts...)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
@@ -0,0 +1,99 @@
+// Command stresstest runs the what-if scenarios of a script on the loan
+// book of a database and prints each scenario's figures before and after.
+//
+//	stresstest -dsn file:loan.db -scenarios recession.txt
+//	echo 'scenario "Rate shock"
+//	rate +2%' | stresstest -json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"loan"
+	"loan/sqlstore"
+	_ "loan/sqlstore/drivers"
+	"loan/stress"
+)
+
+func main() {
+	driver := flag.String("driver", "sqlite", "database/sql driver: sqlite or pgx")
+	dsn := flag.String("dsn", "file:loan.db", "data source name")
+	script := flag.String("scenarios", "-", "scenario script, - for stdin")
+	asJSON := flag.Bool("json", false, "write the results as JSON instead of a table")
+	flag.Parse()
+
+	if err := run(*driver, *dsn, *script, *asJSON); err != nil {
+		fmt.Fprintln(os.Stderr, "stresstest:", err)
+		os.Exit(1)
+	}
+}
+
+func run(driver, dsn, script string, asJSON bool) error {
+	in := io.Reader(os.Stdin)
+	if script != "-" {
+		f, err := os.Open(script)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	scenarios, err := stress.Parse(in)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	db, err := sqlstore.Open(ctx, driver, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	loans, err := sqlstore.NewLoanRepository(db).List(ctx, loan.Filter{Statuses: []string{loan.StatusApproved, loan.StatusDefault}})
+	if err != nil {
+		return err
+	}
+	results := stress.Run(loans, loan.DefaultProvisioningModel, scenarios)
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	return printTable(os.Stdout, results)
+}
+
+func printTable(w io.Writer, results []stress.Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "scenario\tmetric\tbefore\tafter\tchange\t")
+	for _, r := range results {
+		rows := []struct {
+			name                 string
+			before, after, delta float64
+			// decimals shown, more for ratios
+			prec int
+		}{
+			{"exposure", r.Before.Exposure, r.After.Exposure, r.Change.Exposure, 2},
+			{"loss allowance", r.Before.LossAllowance, r.After.LossAllowance, r.Change.LossAllowance, 2},
+			{"coverage", r.Before.Coverage, r.After.Coverage, r.Change.Coverage, 4},
+			{"interest income", r.Before.InterestIncome, r.After.InterestIncome, r.Change.InterestIncome, 2},
+			{"monthly payments", r.Before.MonthlyPayments, r.After.MonthlyPayments, r.Change.MonthlyPayments, 2},
+		}
+		for i, row := range rows {
+			name := ""
+			if i == 0 {
+				name = r.Scenario.Name
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%.*f\t%.*f\t%+.*f\t\n", name, row.name,
+				row.prec, row.before, row.prec, row.after, row.prec, row.delta)
+		}
+	}
+	return tw.Flush()
+}
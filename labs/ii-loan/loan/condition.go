@@ -0,0 +1,142 @@
+package loan
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Op is the comparison of a Condition
+type Op string
+
+// Comparison operators
+const (
+	OpEq Op = "="
+	OpNe Op = "!="
+	OpLt Op = "<"
+	OpLe Op = "<="
+	OpGt Op = ">"
+	OpGe Op = ">="
+	// OpIn holds when the field equals one of a []string Value
+	OpIn Op = "in"
+)
+
+// Loan fields Conditions can compare
+const (
+	FieldStatus     = "status"
+	FieldCustomerID = "customerId"
+	FieldProduct    = "product"
+	FieldPurpose    = "purpose"
+	FieldAmount     = "amount"
+	FieldTermMonths = "termMonths"
+	FieldCreatedAt  = "createdAt"
+)
+
+// stringFields are the fields compared with strings
+var stringFields = []string{FieldStatus, FieldCustomerID, FieldProduct, FieldPurpose}
+
+// Condition compares a loan field with a value: status, customerId,
+// product and purpose with a string, or a []string for OpIn, amount with a
+// float64, termMonths with an int and createdAt with a time.Time. Strings
+// are only compared for equality.
+type Condition struct {
+	Field string `json:"field"`
+	Op    Op     `json:"op"`
+	Value any    `json:"value"`
+}
+
+// Validate checks that the field is known and that the operator and the
+// value's type suit it
+func (c Condition) Validate() error {
+	var ok bool
+	switch c.Field {
+	case FieldStatus, FieldCustomerID, FieldProduct, FieldPurpose:
+		switch c.Op {
+		case OpEq, OpNe:
+			_, ok = c.Value.(string)
+		case OpIn:
+			_, ok = c.Value.([]string)
+		default:
+			return fmt.Errorf("%s can only be compared with =, != or in", c.Field)
+		}
+	case FieldAmount:
+		_, ok = c.Value.(float64)
+	case FieldTermMonths:
+		_, ok = c.Value.(int)
+	case FieldCreatedAt:
+		_, ok = c.Value.(time.Time)
+	default:
+		return fmt.Errorf("unknown field %q", c.Field)
+	}
+	if c.Op == OpIn && !slices.Contains(stringFields, c.Field) {
+		return fmt.Errorf("%s cannot be compared with in", c.Field)
+	}
+	if !ok {
+		return fmt.Errorf("%s cannot be compared with a %T", c.Field, c.Value)
+	}
+	return nil
+}
+
+// Match reports whether l satisfies the condition; invalid conditions
+// match nothing
+func (c Condition) Match(l *Loan) bool {
+	switch c.Field {
+	case FieldStatus:
+		return matchString(c, l.Status)
+	case FieldCustomerID:
+		return matchString(c, l.CustomerID)
+	case FieldProduct:
+		return matchString(c, l.Product)
+	case FieldPurpose:
+		return matchString(c, l.Purpose)
+	case FieldAmount:
+		v, ok := c.Value.(float64)
+		return ok && compare(c.Op, cmpFloat(l.Amount, v))
+	case FieldTermMonths:
+		v, ok := c.Value.(int)
+		return ok && compare(c.Op, cmpFloat(float64(l.TermMonths), float64(v)))
+	case FieldCreatedAt:
+		v, ok := c.Value.(time.Time)
+		return ok && compare(c.Op, l.CreatedAt.Compare(v))
+	}
+	return false
+}
+
+func matchString(c Condition, s string) bool {
+	switch v := c.Value.(type) {
+	case string:
+		return c.Op == OpEq && s == v || c.Op == OpNe && s != v
+	case []string:
+		return c.Op == OpIn && slices.Contains(v, s)
+	}
+	return false
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compare applies op to the result of comparing a field with a value
+func compare(op Op, cmp int) bool {
+	switch op {
+	case OpEq:
+		return cmp == 0
+	case OpNe:
+		return cmp != 0
+	case OpLt:
+		return cmp < 0
+	case OpLe:
+		return cmp <= 0
+	case OpGt:
+		return cmp > 0
+	case OpGe:
+		return cmp >= 0
+	}
+	return false
+}
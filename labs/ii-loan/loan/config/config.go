@@ -0,0 +1,478 @@
+// Package config loads the settings of the loan services: listen
+// addresses, the database, default rates, application limits and
+// features. Settings start from Default, are overridden by a YAML file and
+// then by environment variables, and are validated before use, so a
+// service refuses to start on a bad setting instead of failing later:
+//
+//	server:
+//	  addr: ":8080"
+//	  shutdownTimeout: 30s
+//	database:
+//	  driver: pgx
+//	  dsn: postgres://loan@db/loan
+//	rates:
+//	  - {above: 0, rate: 0.11}
+//	  - {above: 25000, rate: 0.14}
+//	products:
+//	  - {code: PL, name: Personal loan, maxAmount: 300000, rate: 0.18, terms: [12, 24, 36],
+//	     prepayment: {type: declining, rates: [0.03, 0.02, 0.01]},
+//	     insurance: [{code: LIFE, name: Credit life, rate: 0.004, covers: [death, disability]}]}
+//	  - {code: AUTO, name: Car loan, rate: 0.09, purposes: [auto], amortization: straight_line}
+//	limits:
+//	  maxAmount: 500000
+//	  maxTermMonths: 84
+//	  maxExposure: 1000000
+//	features:
+//	  localize: true
+//
+// Every setting has a variable named LOAN_<SECTION>_<KEY>, the key in
+// upper snake case: LOAN_SERVER_SHUTDOWN_TIMEOUT=30s,
+// LOAN_LIMITS_MAX_AMOUNT=500000. LOAN_RATES takes the tiers as
+// above:rate pairs, LOAN_RATES=0:0.11,25000:0.14. Products are only read
+// from the file.
+//
+// Keys the file or the variables of a section do not know are errors, as
+// are invalid values; Load reports all of them at once.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+
+	"loan"
+)
+
+// EnvPrefix starts the names of the variables Load reads
+const EnvPrefix = "LOAN_"
+
+// Config holds every setting
+type Config struct {
+	Server   Server   `yaml:"server"`
+	Database Database `yaml:"database"`
+	// Rates fix the rate of applications that do not ask for one; without
+	// them such loans keep following loan.DefaultRateTable
+	Rates Rates `yaml:"rates"`
+	// Products, when there are any, are the only ones applications may
+	// name
+	Products []Product `yaml:"products" env:"-"`
+	Limits   Limits    `yaml:"limits"`
+	Features Features  `yaml:"features"`
+}
+
+// Server holds the listeners and their shutdown
+type Server struct {
+	Addr string `yaml:"addr"`
+	// GRPCAddr is empty to disable gRPC
+	GRPCAddr        string        `yaml:"grpcAddr"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	DrainDelay      time.Duration `yaml:"drainDelay"`
+}
+
+// Database selects where loans are kept
+type Database struct {
+	// Driver is sqlite or pgx, or empty to keep loans in memory
+	Driver string `yaml:"driver"`
+	DSN    string `yaml:"dsn"`
+}
+
+// Limits bound what applications may ask for; zero does not limit
+type Limits struct {
+	MinAmount     float64 `yaml:"minAmount"`
+	MaxAmount     float64 `yaml:"maxAmount"`
+	MinTermMonths int     `yaml:"minTermMonths"`
+	MaxTermMonths int     `yaml:"maxTermMonths"`
+	MaxRate       float64 `yaml:"maxRate"`
+	MaxExposure   float64 `yaml:"maxExposure"`
+}
+
+// Product is a loan product; see loan.Product
+type Product struct {
+	Code      string  `yaml:"code"`
+	Name      string  `yaml:"name"`
+	MinAmount float64 `yaml:"minAmount"`
+	MaxAmount float64 `yaml:"maxAmount"`
+	Rate      float64 `yaml:"rate"`
+	Terms     []int   `yaml:"terms"`
+	// Purposes and PurposeRates restrict and re-price loans by purpose
+	Purposes     []string           `yaml:"purposes"`
+	PurposeRates map[string]float64 `yaml:"purposeRates"`
+	// Amortization is annuity, the default, or straight_line
+	Amortization string `yaml:"amortization"`
+	// Prepayment is the penalty for settling early, as {type: percent,
+	// rate: 0.02} or {type: declining, rates: [0.03, 0.02, 0.01]}
+	Prepayment *loan.PrepaymentPenalty `yaml:"prepayment"`
+	// Insurance lists the credit insurance plans offered, as {code: LIFE,
+	// name: Credit life, rate: 0.004, covers: [death, disability]}
+	Insurance []loan.InsurancePlan `yaml:"insurance"`
+}
+
+// Features switches optional behaviour on
+type Features struct {
+	Jobs           bool `yaml:"jobs"`
+	Investors      bool `yaml:"investors"`
+	Pools          bool `yaml:"pools"`
+	Localize       bool `yaml:"localize"`
+	PaymentSandbox bool `yaml:"paymentSandbox"`
+	ProductCatalog bool `yaml:"productCatalog"`
+	Webhooks       bool `yaml:"webhooks"`
+}
+
+// Rates is a rate table, a YAML list of tiers or, as text, comma-separated
+// above:rate pairs
+type Rates []loan.RateTier
+
+// UnmarshalText parses above:rate pairs
+func (r *Rates) UnmarshalText(text []byte) error {
+	var tiers Rates
+	for _, pair := range strings.Split(string(text), ",") {
+		above, rate, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return fmt.Errorf("rate tier %q is not above:rate", pair)
+		}
+		a, err := strconv.ParseFloat(above, 64)
+		if err != nil {
+			return fmt.Errorf("rate tier %q: %w", pair, err)
+		}
+		v, err := strconv.ParseFloat(rate, 64)
+		if err != nil {
+			return fmt.Errorf("rate tier %q: %w", pair, err)
+		}
+		tiers = append(tiers, loan.RateTier{Above: a, Rate: v})
+	}
+	*r = tiers
+	return nil
+}
+
+// Error lists every problem found loading or validating a configuration
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	if len(e.Problems) == 1 {
+		return "config: " + e.Problems[0]
+	}
+	return fmt.Sprintf("config: %d problems:\n  %s", len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+// Default returns the settings used where neither the file nor the
+// environment says otherwise
+func Default() Config {
+	return Config{
+		Server:   Server{Addr: ":8080", GRPCAddr: ":9090", ShutdownTimeout: 15 * time.Second},
+		Database: Database{DSN: "file:loan.db"},
+		Features: Features{Jobs: true},
+	}
+}
+
+// Load reads the YAML file at path, if path is not empty, over Default,
+// then the LOAN_ variables of env, formatted like os.Environ, and
+// validates the result. Problems are returned together as an *Error.
+func Load(path string, env []string) (Config, error) {
+	c := Default()
+	var problems []string
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: %w", err)
+		}
+		problems = append(problems, decodeYAML(data, &c)...)
+	}
+	problems = append(problems, c.applyEnv(env)...)
+	if err := c.Validate(); err != nil {
+		var cerr *Error
+		if !errors.As(err, &cerr) {
+			return Config{}, err
+		}
+		problems = append(problems, cerr.Problems...)
+	}
+	if len(problems) > 0 {
+		return Config{}, &Error{Problems: problems}
+	}
+	return c, nil
+}
+
+// decodeYAML decodes data over c, rejecting unknown keys
+func decodeYAML(data []byte, c *Config) []string {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(c)
+	var terr *yaml.TypeError
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+		return nil
+	case errors.As(err, &terr):
+		// the decoder keeps going past type errors and unknown keys
+		problems := make([]string, len(terr.Errors))
+		for i, e := range terr.Errors {
+			problems[i] = "yaml: " + strings.TrimPrefix(e, "yaml: ")
+		}
+		return problems
+	}
+	return []string{err.Error()}
+}
+
+// applyEnv sets the settings named by the LOAN_ variables of env
+func (c *Config) applyEnv(env []string) []string {
+	vars := make(map[string]reflect.Value)
+	sections := make(map[string]bool)
+	cv := reflect.ValueOf(c).Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		if cv.Type().Field(i).Tag.Get("env") == "-" {
+			continue
+		}
+		section := EnvPrefix + envName(yamlName(cv.Type().Field(i)))
+		sections[section] = true
+		field := cv.Field(i)
+		if field.Kind() != reflect.Struct {
+			vars[section] = field
+			continue
+		}
+		for j := 0; j < field.NumField(); j++ {
+			vars[section+"_"+envName(yamlName(field.Type().Field(j)))] = field.Field(j)
+		}
+	}
+	var problems []string
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		field, ok := vars[name]
+		if !ok {
+			// variables outside the config's sections belong to others,
+			// such as LOAN_FIELD_KEYS
+			if section, _, _ := strings.Cut(strings.TrimPrefix(name, EnvPrefix), "_"); strings.HasPrefix(name, EnvPrefix) && sections[EnvPrefix+section] {
+				problems = append(problems, fmt.Sprintf("env: unknown variable %s", name))
+			}
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			problems = append(problems, fmt.Sprintf("env: %s: %v", name, err))
+		}
+	}
+	return problems
+}
+
+// setField parses value into field
+func setField(field reflect.Value, value string) error {
+	if u, ok := field.Addr().Interface().(interface{ UnmarshalText([]byte) error }); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
+
+// yamlName is the key of f in the YAML file
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	return name
+}
+
+// envName turns a camelCase key into upper snake case
+func envName(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		if unicode.IsUpper(r) && i > 0 && !unicode.IsUpper(rune(key[i-1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// Validate reports every invalid setting of c as an *Error
+func (c Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Server.Addr == "" {
+		add("server.addr is required")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		add("server.shutdownTimeout must be positive")
+	}
+	if c.Server.DrainDelay < 0 {
+		add("server.drainDelay must not be negative")
+	}
+
+	switch c.Database.Driver {
+	case "", "sqlite", "pgx":
+	default:
+		add("database.driver %q is not sqlite or pgx", c.Database.Driver)
+	}
+	if c.Database.Driver != "" && c.Database.DSN == "" {
+		add("database.dsn is required with database.driver %s", c.Database.Driver)
+	}
+
+	seen := make(map[float64]bool, len(c.Rates))
+	for i, t := range c.Rates {
+		if t.Above < 0 {
+			add("rates[%d].above must not be negative", i)
+		}
+		if t.Rate <= 0 || t.Rate >= 1 {
+			add("rates[%d].rate %g is not between 0 and 1", i, t.Rate)
+		}
+		if seen[t.Above] {
+			add("rates[%d] repeats the tier above %g", i, t.Above)
+		}
+		seen[t.Above] = true
+	}
+
+	codes := make(map[string]bool, len(c.Products))
+	for i, p := range c.Products {
+		if p.Code == "" {
+			add("products[%d].code is required", i)
+		} else if codes[p.Code] {
+			add("products[%d] repeats the code %s", i, p.Code)
+		}
+		codes[p.Code] = true
+		if p.MinAmount < 0 || p.MaxAmount < 0 || p.Rate < 0 {
+			add("products[%d] amounts and rate must not be negative", i)
+		}
+		if p.MaxAmount > 0 && p.MinAmount > p.MaxAmount {
+			add("products[%d].minAmount %g is above its maxAmount %g", i, p.MinAmount, p.MaxAmount)
+		}
+		if p.Rate >= 1 {
+			add("products[%d].rate %g is not below 1", i, p.Rate)
+		}
+		for _, t := range p.Terms {
+			if t <= 0 {
+				add("products[%d].terms must be positive", i)
+				break
+			}
+		}
+		for _, purpose := range p.Purposes {
+			if !loan.ValidPurpose(purpose) {
+				add("products[%d].purposes has the unknown purpose %q", i, purpose)
+			}
+		}
+		priced := make([]string, 0, len(p.PurposeRates))
+		for purpose := range p.PurposeRates {
+			priced = append(priced, purpose)
+		}
+		slices.Sort(priced)
+		for _, purpose := range priced {
+			switch r := p.PurposeRates[purpose]; {
+			case !loan.ValidPurpose(purpose):
+				add("products[%d].purposeRates has the unknown purpose %q", i, purpose)
+			case len(p.Purposes) > 0 && !slices.Contains(p.Purposes, purpose):
+				add("products[%d].purposeRates prices %s, which is not in its purposes", i, purpose)
+			case r < 0 || r >= 1:
+				add("products[%d].purposeRates.%s %g is not a fraction between 0 and 1", i, purpose, r)
+			}
+		}
+		if p.Amortization != "" && !loan.ValidAmortization(p.Amortization) {
+			add("products[%d].amortization %q is not one of %s", i, p.Amortization, strings.Join(loan.Amortizations, ", "))
+		}
+		if p.Prepayment != nil {
+			if err := p.Prepayment.Validate(); err != nil {
+				add("products[%d].prepayment: %v", i, err)
+			}
+		}
+		plans := map[string]bool{}
+		for _, plan := range p.Insurance {
+			if err := plan.Validate(); err != nil {
+				add("products[%d].insurance: %v", i, err)
+			}
+			if plans[plan.Code] {
+				add("products[%d].insurance offers plan %q twice", i, plan.Code)
+			}
+			plans[plan.Code] = true
+		}
+	}
+
+	l := c.Limits
+	if l.MinAmount < 0 || l.MaxAmount < 0 || l.MinTermMonths < 0 || l.MaxTermMonths < 0 || l.MaxRate < 0 || l.MaxExposure < 0 {
+		add("limits must not be negative")
+	}
+	if l.MaxAmount > 0 && l.MinAmount > l.MaxAmount {
+		add("limits.minAmount %g is above limits.maxAmount %g", l.MinAmount, l.MaxAmount)
+	}
+	if l.MaxTermMonths > 0 && l.MinTermMonths > l.MaxTermMonths {
+		add("limits.minTermMonths %d is above limits.maxTermMonths %d", l.MinTermMonths, l.MaxTermMonths)
+	}
+	if l.MaxExposure > 0 && l.MaxExposure < l.MaxAmount {
+		add("limits.maxExposure %g is below limits.maxAmount %g", l.MaxExposure, l.MaxAmount)
+	}
+	if l.MaxRate >= 1 {
+		add("limits.maxRate %g is not below 1", l.MaxRate)
+	}
+	for i, t := range c.Rates {
+		if l.MaxRate > 0 && t.Rate > l.MaxRate {
+			add("rates[%d].rate %g is above limits.maxRate %g", i, t.Rate, l.MaxRate)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
+	return nil
+}
+
+// RateTable returns the configured rates, nil when there are none
+func (c Config) RateTable() loan.RateTable {
+	if len(c.Rates) == 0 {
+		return nil
+	}
+	return loan.RateTable(c.Rates)
+}
+
+// Pricing returns a snapshot of the configured rates and products, nil
+// when neither is configured
+func (c Config) Pricing() *loan.Pricing {
+	if len(c.Rates) == 0 && len(c.Products) == 0 {
+		return nil
+	}
+	products := make([]loan.Product, len(c.Products))
+	for i, p := range c.Products {
+		products[i] = loan.Product(p)
+	}
+	return loan.NewPricing(c.RateTable(), products)
+}
+
+// LoanLimits returns the configured application limits
+func (c Config) LoanLimits() loan.Limits {
+	return loan.Limits(c.Limits)
+}
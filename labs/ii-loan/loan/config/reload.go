@@ -0,0 +1,132 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"loan"
+)
+
+// DefaultPollInterval is how often a PricingFile checks for changes
+const DefaultPollInterval = 10 * time.Second
+
+// PricingFile is a loan.PricingSource serving the rates and products of a
+// configuration file, reloaded when the file changes or the process gets
+// SIGHUP. Each reload is loaded and validated like at startup and swapped
+// in whole; one that fails leaves the last good pricing in force. The
+// file's other settings take effect on restart only.
+type PricingFile struct {
+	path     string
+	env      []string
+	interval time.Duration
+	onError  func(error)
+	onReload func(*loan.Pricing)
+	current  atomic.Pointer[loan.Pricing]
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// PricingOption configures a PricingFile
+type PricingOption func(*PricingFile)
+
+// WithPollInterval sets how often the file is checked instead of
+// DefaultPollInterval
+func WithPollInterval(d time.Duration) PricingOption {
+	return func(f *PricingFile) { f.interval = d }
+}
+
+// WithErrorHandler is called with reload errors, which are otherwise
+// dropped
+func WithErrorHandler(fn func(error)) PricingOption {
+	return func(f *PricingFile) { f.onError = fn }
+}
+
+// WithReloadHandler is called with the new pricing after each reload
+func WithReloadHandler(fn func(*loan.Pricing)) PricingOption {
+	return func(f *PricingFile) { f.onReload = fn }
+}
+
+// NewPricingFile loads the pricing of the file at path, with env applied
+// as by Load. It fails if the first load does.
+func NewPricingFile(path string, env []string, opts ...PricingOption) (*PricingFile, error) {
+	f := &PricingFile{path: path, env: env, interval: DefaultPollInterval}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if _, err := f.reload(true); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Pricing implements loan.PricingSource. Without rates or products it is
+// an empty snapshot, leaving rates to loan.DefaultRateTable.
+func (f *PricingFile) Pricing() *loan.Pricing {
+	return f.current.Load()
+}
+
+// Reload reads the file whether or not it changed
+func (f *PricingFile) Reload() error {
+	_, err := f.reload(true)
+	return err
+}
+
+// reload reads the file if force is set or it changed since the last
+// load, reporting whether it did
+func (f *PricingFile) reload(force bool) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, fmt.Errorf("config: %w", err)
+	}
+	if !force && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return false, nil
+	}
+	c, err := Load(f.path, f.env)
+	if err != nil {
+		return false, fmt.Errorf("%w (in %s)", err, f.path)
+	}
+	p := c.Pricing()
+	if p == nil {
+		p = loan.NewPricing(nil, nil)
+	}
+	f.current.Store(p)
+	f.modTime, f.size = info.ModTime(), info.Size()
+	if f.onReload != nil {
+		f.onReload(p)
+	}
+	return true, nil
+}
+
+// Watch reloads the file every poll interval if it changed, and on
+// SIGHUP, until ctx is done
+func (f *PricingFile) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	t := time.NewTicker(f.interval)
+	defer t.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			_, err = f.reload(false)
+		case <-hup:
+			err = f.Reload()
+		}
+		if err != nil && f.onError != nil {
+			f.onError(err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"loan/tracing"
+)
+
+// ErrCustomerNotFound is returned by repositories when no customer has the
+// given ID
+var ErrCustomerNotFound = errors.New("customer not found")
+
+// ErrActiveLoans is returned when a customer cannot be anonymized because
+// a loan of theirs is still pending or owes money
+var ErrActiveLoans = errors.New("customer has active loans")
+
+// erased replaces free text that may hold personal data
+const erased = "[erased]"
+
+// Customer is a borrower and the personal data held about them
+type Customer struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Email      string `json:"email,omitempty"`
+	Phone      string `json:"phone,omitempty"`
+	NationalID string `json:"nationalId,omitempty"`
+	// BankAccount is the account loans are disbursed to and collected from
+	BankAccount string    `json:"bankAccount,omitempty"`
+	Address     string    `json:"address,omitempty"`
+	DateOfBirth time.Time `json:"dateOfBirth,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	// AnonymizedAt is set once the personal data has been erased
+	AnonymizedAt time.Time `json:"anonymizedAt,omitempty"`
+	// Pseudonym is the ID the customer's loans are moved to while they are
+	// anonymized. It is cleared with the rest once erasure completes.
+	Pseudonym string `json:"-"`
+}
+
+// IsAnonymized reports whether the customer's personal data was erased
+func (c *Customer) IsAnonymized() bool {
+	return !c.AnonymizedAt.IsZero()
+}
+
+// Validate checks the customer can be stored
+func (c *Customer) Validate() error {
+	if c.ID == "" {
+		return invalid("id", "customer ID is required")
+	}
+	if c.Name == "" {
+		return invalid("name", "name is required")
+	}
+	return nil
+}
+
+// anonymize clears every personal field, keeping only the ID as a
+// tombstone so the erasure is not repeated
+func (c *Customer) anonymize(at time.Time) {
+	*c = Customer{ID: c.ID, CreatedAt: c.CreatedAt, AnonymizedAt: at}
+}
+
+// pseudonymize moves l to pseudonym, erasing its free text, who changed
+// its terms and the watchlist names its customer was matched against
+func (l *Loan) pseudonymize(pseudonym string) {
+	l.CustomerID = pseudonym
+	texts := []*string{&l.RejectionReason}
+	for i := range l.TermsChanges {
+		texts = append(texts, &l.TermsChanges[i].Reason, &l.TermsChanges[i].ChangedBy)
+	}
+	if s := l.Screening; s != nil {
+		texts = append(texts, &s.Note, &s.ClearedBy)
+		for i := range s.Matches {
+			texts = append(texts, &s.Matches[i].Name)
+		}
+	}
+	for _, text := range texts {
+		if *text != "" {
+			*text = erased
+		}
+	}
+}
+
+// CustomerRepository persists customers
+type CustomerRepository interface {
+	Save(ctx context.Context, c *Customer) error
+	FindByID(ctx context.Context, id string) (*Customer, error)
+	Update(ctx context.Context, c *Customer) error
+}
+
+// CustomerEraser is implemented by stores keeping copies of customer data
+// outside the loan and customer repositories, such as generated statements
+// or documents. EraseCustomer replaces every reference to customerID with
+// pseudonym and removes personal data; it must be safe to repeat.
+type CustomerEraser interface {
+	EraseCustomer(ctx context.Context, customerID, pseudonym string) error
+}
+
+// Anonymization is the outcome of CustomerService.Anonymize
+type Anonymization struct {
+	// Pseudonym is the customer ID the anonymized loans now carry. It is
+	// random, so it cannot be traced back to the customer.
+	Pseudonym    string    `json:"pseudonym"`
+	Loans        int       `json:"loans"`
+	AnonymizedAt time.Time `json:"anonymizedAt"`
+}
+
+// CustomerService manages customers and their right to erasure
+type CustomerService struct {
+	customers CustomerRepository
+	loans     LoanRepository
+	erasers   []CustomerEraser
+	logger    *slog.Logger
+}
+
+// CustomerOption configures optional CustomerService dependencies
+type CustomerOption func(*CustomerService)
+
+// WithCustomerEraser adds a store to erase customer data from
+func WithCustomerEraser(e CustomerEraser) CustomerOption {
+	return func(s *CustomerService) {
+		s.erasers = append(s.erasers, e)
+	}
+}
+
+// WithCustomerLogger sets the logger
+func WithCustomerLogger(l *slog.Logger) CustomerOption {
+	return func(s *CustomerService) {
+		s.logger = l
+	}
+}
+
+// NewCustomerService creates a customer service over the customer and loan
+// repositories
+func NewCustomerService(customers CustomerRepository, loans LoanRepository, opts ...CustomerOption) *CustomerService {
+	s := &CustomerService{customers: customers, loans: tracedRepository{loans}, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// RegisterCustomer stores a new customer
+func (s *CustomerService) RegisterCustomer(ctx context.Context, c *Customer) (err error) {
+	ctx, span := tracing.Start(ctx, "CustomerService.RegisterCustomer")
+	defer tracing.End(span, &err)
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now().UTC()
+	}
+	return s.customers.Save(ctx, c)
+}
+
+// GetCustomer returns a stored customer
+func (s *CustomerService) GetCustomer(ctx context.Context, id string) (_ *Customer, err error) {
+	ctx, span := tracing.Start(ctx, "CustomerService.GetCustomer")
+	defer tracing.End(span, &err)
+	return s.customers.FindByID(ctx, id)
+}
+
+// Anonymize irreversibly erases a customer's personal data. Their loans
+// are kept with amounts, balances, schedules and payments intact, so
+// portfolio figures do not change, but are moved to a random pseudonym
+// and stripped of free text; the registered erasers do the same for the
+// other stores. Customers with a pending loan or money still owed are
+// refused with ErrActiveLoans. Anonymizing an anonymized customer does
+// nothing.
+func (s *CustomerService) Anonymize(ctx context.Context, customerID string) (_ Anonymization, err error) {
+	ctx, span := tracing.Start(ctx, "CustomerService.Anonymize")
+	defer tracing.End(span, &err)
+
+	c, err := s.customers.FindByID(ctx, customerID)
+	if err != nil {
+		return Anonymization{}, err
+	}
+	if c.IsAnonymized() {
+		return Anonymization{AnonymizedAt: c.AnonymizedAt}, nil
+	}
+	loans, err := s.loans.List(ctx, Filter{CustomerID: customerID})
+	if err != nil {
+		return Anonymization{}, err
+	}
+	for _, l := range loans {
+		if l.Status == StatusPending || l.Balance > 0 {
+			return Anonymization{}, fmt.Errorf("%w: loan %s is %s with balance %.2f", ErrActiveLoans, l.ID, l.Status, l.Balance)
+		}
+	}
+
+	// The pseudonym is stored before anything is rewritten under it, so a
+	// run retried after a partial failure carries on with the same one.
+	// The customer record is anonymized last.
+	if c.Pseudonym == "" {
+		c.Pseudonym = "anon-" + NewID()
+		if err := s.customers.Update(ctx, c); err != nil {
+			return Anonymization{}, err
+		}
+	}
+	moved, err := s.loans.List(ctx, Filter{CustomerID: c.Pseudonym})
+	if err != nil {
+		return Anonymization{}, err
+	}
+	result := Anonymization{Pseudonym: c.Pseudonym, Loans: len(loans) + len(moved), AnonymizedAt: time.Now().UTC()}
+	for _, e := range s.erasers {
+		if err := e.EraseCustomer(ctx, customerID, result.Pseudonym); err != nil {
+			return Anonymization{}, fmt.Errorf("erasing customer data: %w", err)
+		}
+	}
+	for _, l := range loans {
+		l.pseudonymize(result.Pseudonym)
+		if err := s.loans.Update(ctx, l); err != nil {
+			return Anonymization{}, err
+		}
+	}
+	c.anonymize(result.AnonymizedAt)
+	if err := s.customers.Update(ctx, c); err != nil {
+		return Anonymization{}, err
+	}
+	// The customer ID is not logged: it would outlive the erasure.
+	s.logger.InfoContext(ctx, "customer anonymized", "loans", result.Loans)
+	return result, nil
+}
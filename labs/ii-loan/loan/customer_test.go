@@ -0,0 +1,71 @@
+package loan_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"loan"
+	"loan/memory"
+)
+
+func TestAnonymizeErasesLoanFreeText(t *testing.T) {
+	ctx := context.Background()
+	customers, loans := memory.NewCustomerRepository(), memory.NewLoanRepository()
+	svc := loan.NewCustomerService(customers, loans)
+	if err := svc.RegisterCustomer(ctx, &loan.Customer{ID: "cust-1", Name: "Somchai Jaidee"}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	if err := loans.Save(ctx, &loan.Loan{
+		ID:              "loan-1",
+		CustomerID:      "cust-1",
+		Amount:          10_000,
+		Status:          loan.StatusRejected,
+		RejectionReason: "true hit on the sanctions list",
+		TermsChanges: []loan.TermsChange{{
+			ID:        "change-1",
+			Reason:    "hardship reported by Somchai Jaidee",
+			ChangedBy: "collections@example.com",
+			ChangedAt: now,
+		}},
+		Screening: &loan.Screening{
+			Status:     "cleared",
+			Matches:    []loan.WatchlistMatch{{EntryID: "sdn-1", List: "OFAC-SDN", Name: "Somchai Jaidee", Score: 1}},
+			ScreenedAt: now,
+			ClearedBy:  "analyst@example.com",
+			ClearedAt:  now,
+			Note:       "same date of birth as Somchai Jaidee",
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := svc.Anonymize(ctx, "cust-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := loans.FindByID(ctx, "loan-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.CustomerID != result.Pseudonym {
+		t.Errorf("customer ID %q, want the pseudonym %q", l.CustomerID, result.Pseudonym)
+	}
+	s := l.Screening
+	for field, text := range map[string]string{
+		"rejection reason": l.RejectionReason,
+		"screening note":   s.Note,
+		"cleared by":       s.ClearedBy,
+		"matched name":     s.Matches[0].Name,
+		"terms reason":     l.TermsChanges[0].Reason,
+		"terms changed by": l.TermsChanges[0].ChangedBy,
+	} {
+		if text != "[erased]" {
+			t.Errorf("%s %q, want it erased", field, text)
+		}
+	}
+	if m := s.Matches[0]; m.EntryID != "sdn-1" || m.List != "OFAC-SDN" {
+		t.Errorf("match %+v, want its entry and list kept", m)
+	}
+}
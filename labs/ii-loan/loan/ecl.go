@@ -0,0 +1,188 @@
+package loan
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"loan/tracing"
+)
+
+// Impairment stages, as in IFRS 9
+const (
+	// Stage1 loans are performing and provisioned for 12 months of losses
+	Stage1 = 1
+	// Stage2 loans have deteriorated, more than 30 days past due, and are
+	// provisioned for lifetime losses
+	Stage2 = 2
+	// Stage3 loans are credit-impaired: defaulted or non-performing
+	Stage3 = 3
+)
+
+// ProvisioningModel holds the parameters of the expected credit loss
+// calculation
+type ProvisioningModel struct {
+	// PD is the 12-month probability of default per risk grade
+	PD map[string]float64 `json:"pd"`
+	// BucketPD is the 12-month probability of default per delinquency
+	// bucket; a loan takes the higher of its grade and bucket PDs
+	BucketPD map[Bucket]float64 `json:"bucketPd"`
+	// LGD is the share of the exposure lost on default, per product with
+	// DefaultLGD for products not listed
+	LGD        map[string]float64 `json:"lgd,omitempty"`
+	DefaultLGD float64            `json:"defaultLgd"`
+}
+
+// DefaultProvisioningModel is a simple unsecured retail calibration
+var DefaultProvisioningModel = ProvisioningModel{
+	PD: map[string]float64{"A": 0.005, "B": 0.01, "C": 0.025, "D": 0.05, "E": 0.12, "unrated": 0.04},
+	BucketPD: map[Bucket]float64{
+		BucketCurrent: 0, Bucket1To30: 0.08, Bucket31To60: 0.25, Bucket61To90: 0.5, Bucket90Plus: 1,
+	},
+	LGD:        map[string]float64{"HOME": 0.2, "AUTO": 0.35},
+	DefaultLGD: 0.45,
+}
+
+// LoanECL is the expected credit loss of one loan: PD × LGD × EAD
+type LoanECL struct {
+	LoanID  string `json:"loanId"`
+	Product string `json:"product,omitempty"`
+	Grade   string `json:"grade"`
+	Bucket  Bucket `json:"bucket"`
+	Stage   int    `json:"stage"`
+	// PD is the probability of default over the stage's horizon
+	PD  float64 `json:"pd"`
+	LGD float64 `json:"lgd"`
+	// EAD is the exposure at default, the balance owed
+	EAD float64 `json:"ead"`
+	ECL float64 `json:"ecl"`
+}
+
+// ProvisionGroup totals the loans of one stage or grade
+type ProvisionGroup struct {
+	Key   string  `json:"key"`
+	Count int     `json:"count"`
+	EAD   float64 `json:"ead"`
+	ECL   float64 `json:"ecl"`
+	// Coverage is the provision over the exposure
+	Coverage float64 `json:"coverage"`
+}
+
+// ProvisionReport is the loss allowance of the book
+type ProvisionReport struct {
+	AsOf time.Time `json:"asOf"`
+	// ByStage is keyed by the stage number
+	ByStage  []ProvisionGroup `json:"byStage"`
+	ByGrade  []ProvisionGroup `json:"byGrade"`
+	TotalEAD float64          `json:"totalEad"`
+	TotalECL float64          `json:"totalEcl"`
+	Coverage float64          `json:"coverage"`
+	Loans    []LoanECL        `json:"loans"`
+}
+
+// Stage returns the impairment stage of the loan
+func (l *Loan) Stage() int {
+	switch {
+	case l.Status == StatusDefault || l.IsNonPerforming():
+		return Stage3
+	case l.DaysPastDue > 30:
+		return Stage2
+	}
+	return Stage1
+}
+
+// ECL computes the expected credit loss of l. Stage 2 loans
+// compound the 12-month PD over their remaining term; stage 3 loans have
+// defaulted, so their PD is 1.
+func (m ProvisioningModel) ECL(l *Loan) LoanECL {
+	e := LoanECL{
+		LoanID: l.ID, Product: l.Product, Grade: RiskGrade(l.CreditScore),
+		Bucket: BucketFor(l.DaysPastDue), Stage: l.Stage(), EAD: round2(math.Max(l.Balance, 0)),
+	}
+	pd := math.Max(m.PD[e.Grade], m.BucketPD[e.Bucket])
+	switch e.Stage {
+	case Stage2:
+		years := math.Max(float64(l.RemainingMonths())/12, 1)
+		pd = 1 - math.Pow(1-pd, years)
+	case Stage3:
+		pd = 1
+	}
+	e.PD = math.Round(math.Min(pd, 1)*10000) / 10000
+	e.LGD = m.DefaultLGD
+	if lgd, ok := m.LGD[l.Product]; ok {
+		e.LGD = lgd
+	}
+	e.ECL = round2(e.PD * e.LGD * e.EAD)
+	return e
+}
+
+// RemainingInstallments counts the installments still to be paid, all of
+// them for loans without a schedule
+func (l *Loan) RemainingInstallments() int {
+	if len(l.Schedule) == 0 {
+		return l.Installments()
+	}
+	n := 0
+	for _, inst := range l.Schedule {
+		if !inst.IsPaid() {
+			n++
+		}
+	}
+	return n
+}
+
+// WithProvisioningModel sets the parameters of Provisioning instead of
+// DefaultProvisioningModel
+func WithProvisioningModel(m ProvisioningModel) ReportingOption {
+	return func(s *ReportingService) { s.provisioning = m }
+}
+
+// Provisioning computes the expected credit loss of every loan of the
+// book and the loss allowance per stage, per grade and in total
+func (s *ReportingService) Provisioning(ctx context.Context) (_ ProvisionReport, err error) {
+	ctx, span := tracing.Start(ctx, "ReportingService.Provisioning")
+	defer tracing.End(span, &err)
+	loans, err := s.repo.List(ctx, Filter{Statuses: bookStatuses})
+	if err != nil {
+		return ProvisionReport{}, err
+	}
+	r := ProvisionReport{AsOf: s.now().UTC(), Loans: make([]LoanECL, 0, len(loans))}
+	stages, grades := map[string]*ProvisionGroup{}, map[string]*ProvisionGroup{}
+	add := func(groups map[string]*ProvisionGroup, key string, e LoanECL) {
+		g := groups[key]
+		if g == nil {
+			g = &ProvisionGroup{Key: key}
+			groups[key] = g
+		}
+		g.Count++
+		g.EAD += e.EAD
+		g.ECL += e.ECL
+	}
+	for _, l := range loans {
+		e := s.provisioning.ECL(l)
+		r.Loans = append(r.Loans, e)
+		add(stages, strconv.Itoa(e.Stage), e)
+		add(grades, e.Grade, e)
+		r.TotalEAD += e.EAD
+		r.TotalECL += e.ECL
+	}
+	r.ByStage, r.ByGrade = provisionGroups(stages), provisionGroups(grades)
+	sort.Slice(r.Loans, func(i, j int) bool { return r.Loans[i].LoanID < r.Loans[j].LoanID })
+	r.TotalEAD, r.TotalECL = round2(r.TotalEAD), round2(r.TotalECL)
+	r.Coverage = ratio(r.TotalECL, r.TotalEAD)
+	return r, nil
+}
+
+// provisionGroups rounds the groups and orders them by key
+func provisionGroups(groups map[string]*ProvisionGroup) []ProvisionGroup {
+	out := make([]ProvisionGroup, 0, len(groups))
+	for _, g := range groups {
+		g.EAD, g.ECL = round2(g.EAD), round2(g.ECL)
+		g.Coverage = ratio(g.ECL, g.EAD)
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
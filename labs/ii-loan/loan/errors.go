@@ -0,0 +1,21 @@
+package loan
+
+import "errors"
+
+// ErrInvalidTransition is returned when an operation is not allowed in the
+// loan's current status
+var ErrInvalidTransition = errors.New("invalid loan status transition")
+
+// ValidationError reports invalid loan data for a single field
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalid(field, message string) error {
+	return &ValidationError{Field: field, Message: message}
+}
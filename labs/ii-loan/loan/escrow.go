@@ -0,0 +1,349 @@
+package loan
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"loan/tracing"
+)
+
+// ErrNoEscrow is returned for escrow operations on a loan without escrow
+var ErrNoEscrow = errors.New("loan has no escrow account")
+
+// Escrow item kinds
+const (
+	EscrowInsurance = "insurance"
+	EscrowTax       = "tax"
+)
+
+// Escrow transaction types
+const (
+	EscrowDeposit      = "deposit"
+	EscrowDisbursement = "disbursement"
+)
+
+// EscrowCushionMonths is the part of a year's disbursements, in months,
+// kept in escrow as a cushion against rises in premiums and taxes
+const EscrowCushionMonths = 2
+
+// EscrowItem is a bill paid from escrow on the borrower's behalf, such as
+// the premium of the insurance on the collateral or its property tax
+type EscrowItem struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Payee string `json:"payee"`
+	// Amount is paid every IntervalMonths, first on NextDue
+	Amount         float64   `json:"amount"`
+	IntervalMonths int       `json:"intervalMonths"`
+	NextDue        time.Time `json:"nextDue"`
+}
+
+// Annual is what the item costs a year
+func (i EscrowItem) Annual() float64 {
+	return i.Amount * 12 / float64(i.IntervalMonths)
+}
+
+// EscrowTransaction is money into or out of escrow
+type EscrowTransaction struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// ItemID is the item a disbursement paid, PaymentID the payment a
+	// deposit came with
+	ItemID    string  `json:"itemId,omitempty"`
+	PaymentID string  `json:"paymentId,omitempty"`
+	Amount    float64 `json:"amount"`
+	// Balance is the escrow balance after the transaction
+	Balance float64   `json:"balance"`
+	At      time.Time `json:"at"`
+}
+
+// EscrowAnalysis projects a year of escrow deposits and disbursements and
+// sets the escrow payment for it: enough for the year's bills, plus a
+// twelfth of any shortage below the cushion
+type EscrowAnalysis struct {
+	AnalyzedAt time.Time `json:"analyzedAt"`
+	// Disbursements are the bills projected over the year
+	Disbursements float64 `json:"disbursements"`
+	Cushion       float64 `json:"cushion"`
+	// LowPoint is the lowest balance projected at the base payment
+	LowPoint float64 `json:"lowPoint"`
+	// Shortage is how far LowPoint falls below Cushion, Surplus how far it
+	// rises above it
+	Shortage        float64 `json:"shortage"`
+	Surplus         float64 `json:"surplus"`
+	PreviousPayment float64 `json:"previousPayment"`
+	Payment         float64 `json:"payment"`
+}
+
+// EscrowShortfall is an escrow disbursement the balance could not cover;
+// the lender advanced the difference, which the next analysis recovers
+type EscrowShortfall struct {
+	LoanID     string            `json:"loanId"`
+	CustomerID string            `json:"customerId"`
+	Item       EscrowItem        `json:"item"`
+	Shortfall  float64           `json:"shortfall"`
+	Payment    EscrowTransaction `json:"payment"`
+}
+
+// Customer returns the customer whose escrow fell short
+func (s EscrowShortfall) Customer() string { return s.CustomerID }
+
+// Escrow is the sub-account of a secured loan collecting for the bills
+// paid on the borrower's behalf. Its share of each installment is held
+// apart from the loan's balance and paid out as bills fall due.
+type Escrow struct {
+	Items []EscrowItem `json:"items"`
+	// Payment is collected with each installment
+	Payment float64 `json:"payment"`
+	// Balance is negative while the lender is advancing a shortfall
+	Balance      float64             `json:"balance"`
+	Transactions []EscrowTransaction `json:"transactions,omitempty"`
+	Analyses     []EscrowAnalysis    `json:"analyses,omitempty"`
+}
+
+// Clone returns a deep copy of the escrow
+func (e *Escrow) Clone() *Escrow {
+	c := *e
+	c.Items = slices.Clone(e.Items)
+	c.Transactions = slices.Clone(e.Transactions)
+	c.Analyses = slices.Clone(e.Analyses)
+	return &c
+}
+
+// Shortfall is the amount the lender has advanced to escrow, 0 while the
+// balance covers the bills paid
+func (e *Escrow) Shortfall() float64 {
+	return round2(math.Max(-e.Balance, 0))
+}
+
+// LastAnalyzed is when escrow was last analyzed, at set up at the latest
+func (e *Escrow) LastAnalyzed() time.Time {
+	if len(e.Analyses) == 0 {
+		return time.Time{}
+	}
+	return e.Analyses[len(e.Analyses)-1].AnalyzedAt
+}
+
+// record adds t to the transactions, moving the balance by its amount
+func (e *Escrow) record(t EscrowTransaction) EscrowTransaction {
+	t.ID, t.Amount, t.At = NewID(), round2(t.Amount), t.At.UTC()
+	if t.Type == EscrowDisbursement {
+		e.Balance = round2(e.Balance - t.Amount)
+	} else {
+		e.Balance = round2(e.Balance + t.Amount)
+	}
+	t.Balance = e.Balance
+	e.Transactions = append(e.Transactions, t)
+	return t
+}
+
+func validEscrowItems(items []EscrowItem) error {
+	if len(items) == 0 {
+		return invalid("items", "escrow needs at least one item")
+	}
+	for _, it := range items {
+		switch {
+		case it.Kind != EscrowInsurance && it.Kind != EscrowTax:
+			return invalid("items.kind", fmt.Sprintf("unknown escrow item kind %q; kinds are insurance, tax", it.Kind))
+		case strings.TrimSpace(it.Payee) == "":
+			return invalid("items.payee", "who the item is paid to is required")
+		case it.Amount <= 0:
+			return invalid("items.amount", "item amount must be positive")
+		case it.IntervalMonths <= 0 || 12%it.IntervalMonths != 0:
+			return invalid("items.intervalMonths", "items are paid every 1, 2, 3, 4, 6 or 12 months")
+		case it.NextDue.IsZero():
+			return invalid("items.nextDue", "when the item is next due is required")
+		}
+	}
+	return nil
+}
+
+// SetUpEscrow opens escrow on a pending or approved loan for items and
+// analyzes it at the time at, spreading the escrow payment over the
+// installments falling due after at. Items without an ID are given one.
+func (l *Loan) SetUpEscrow(items []EscrowItem, at time.Time) (*Escrow, error) {
+	if l.Status != StatusPending && !l.IsActive() {
+		return nil, fmt.Errorf("%w: cannot set up escrow on loan in status %q", ErrInvalidTransition, l.Status)
+	}
+	if l.Escrow != nil {
+		return nil, fmt.Errorf("%w: loan already has escrow", ErrInvalidTransition)
+	}
+	if err := validEscrowItems(items); err != nil {
+		return nil, err
+	}
+	e := &Escrow{Items: slices.Clone(items)}
+	for i := range e.Items {
+		if e.Items[i].ID == "" {
+			e.Items[i].ID = NewID()
+		}
+		e.Items[i].NextDue = e.Items[i].NextDue.UTC()
+	}
+	l.Escrow = e
+	l.AnalyzeEscrow(at)
+	return e, nil
+}
+
+// AnalyzeEscrow projects the year after at from the escrow balance: the
+// installments falling due deposit the base payment, a year's bills
+// divided over the year's installments, and the items are paid out when
+// due. A balance projected below the cushion is a shortage, recovered over
+// the year by raising the payment. The new payment is spread over the
+// installments falling due after at.
+func (l *Loan) AnalyzeEscrow(at time.Time) EscrowAnalysis {
+	e := l.Escrow
+	periods := PeriodsPerYear(l.Frequency)
+	end := at.AddDate(1, 0, 0)
+
+	var annual float64
+	for _, it := range e.Items {
+		annual += it.Annual()
+	}
+	base := round2(annual / float64(periods))
+
+	// a year of events, deposits before bills on the same day
+	type flow struct {
+		at     time.Time
+		amount float64
+	}
+	var flows []flow
+	for n := 1; n <= periods; n++ {
+		flows = append(flows, flow{dueAfter(at, l.Frequency, n), base})
+	}
+	var bills float64
+	for _, it := range e.Items {
+		for due := it.NextDue; due.Before(end); due = due.AddDate(0, it.IntervalMonths, 0) {
+			if due.After(at) {
+				flows = append(flows, flow{due, -it.Amount})
+				bills += it.Amount
+			}
+		}
+	}
+	slices.SortStableFunc(flows, func(a, b flow) int {
+		if c := a.at.Compare(b.at); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.amount, a.amount)
+	})
+	balance, low := e.Balance, e.Balance
+	for _, f := range flows {
+		balance += f.amount
+		low = math.Min(low, balance)
+	}
+
+	a := EscrowAnalysis{
+		AnalyzedAt:      at.UTC(),
+		Disbursements:   round2(bills),
+		Cushion:         round2(annual * EscrowCushionMonths / 12),
+		LowPoint:        round2(low),
+		PreviousPayment: e.Payment,
+	}
+	a.Shortage = round2(math.Max(a.Cushion-a.LowPoint, 0))
+	a.Surplus = round2(math.Max(a.LowPoint-a.Cushion, 0))
+	a.Payment = round2(base + a.Shortage/float64(periods))
+	e.Payment = a.Payment
+	e.Analyses = append(e.Analyses, a)
+	l.spreadAddOns(at)
+	return a
+}
+
+// DisburseEscrow pays the escrow items due by at from the escrow balance,
+// every one of their payments missed included, and returns the payments
+// and those that overdrew the balance
+func (l *Loan) DisburseEscrow(at time.Time) ([]EscrowTransaction, []EscrowShortfall) {
+	if l.Escrow == nil {
+		return nil, nil
+	}
+	e := l.Escrow
+	var paid []EscrowTransaction
+	var short []EscrowShortfall
+	for i := range e.Items {
+		it := &e.Items[i]
+		for !it.NextDue.After(at) {
+			before := e.Balance
+			t := e.record(EscrowTransaction{Type: EscrowDisbursement, ItemID: it.ID, Amount: it.Amount, At: it.NextDue})
+			paid = append(paid, t)
+			if t.Balance < 0 {
+				short = append(short, EscrowShortfall{
+					LoanID: l.ID, CustomerID: l.CustomerID, Item: *it, Payment: t,
+					Shortfall: round2(-t.Balance - math.Max(-before, 0)),
+				})
+			}
+			it.NextDue = it.NextDue.AddDate(0, it.IntervalMonths, 0)
+		}
+	}
+	return paid, short
+}
+
+// EscrowAnalysisDue reports whether a year has passed since escrow was
+// last analyzed
+func (l *Loan) EscrowAnalysisDue(at time.Time) bool {
+	return l.Escrow != nil && !l.Escrow.LastAnalyzed().AddDate(1, 0, 0).After(at)
+}
+
+// SetUpEscrow opens escrow on a loan for items, spreading the escrow
+// payment over its installments not yet due
+func (s *LoanService) SetUpEscrow(ctx context.Context, id string, items []EscrowItem) (_ *Escrow, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.SetUpEscrow", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	l, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	e, err := l.SetUpEscrow(items, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, l); err != nil {
+		s.log(l).ErrorContext(ctx, "updating loan with escrow", "error", err)
+		return nil, err
+	}
+	s.log(l).InfoContext(ctx, "escrow set up", "items", len(e.Items), "payment", e.Payment)
+	return e, nil
+}
+
+// GetEscrow returns the escrow of a loan, or ErrNoEscrow
+func (s *LoanService) GetEscrow(ctx context.Context, id string) (_ *Escrow, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.GetEscrow", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	l, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if l.Escrow == nil {
+		return nil, ErrNoEscrow
+	}
+	return l.Escrow, nil
+}
+
+// AnalyzeEscrow analyzes the escrow of a loan now, ahead of its yearly
+// analysis, and publishes EventEscrowAnalyzed
+func (s *LoanService) AnalyzeEscrow(ctx context.Context, id string) (_ EscrowAnalysis, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.AnalyzeEscrow", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	l, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return EscrowAnalysis{}, err
+	}
+	if l.Escrow == nil {
+		return EscrowAnalysis{}, ErrNoEscrow
+	}
+	if !l.IsActive() {
+		return EscrowAnalysis{}, fmt.Errorf("%w: cannot analyze escrow of loan in status %q", ErrInvalidTransition, l.Status)
+	}
+	a := l.AnalyzeEscrow(time.Now())
+	if err := s.repo.Update(ctx, l); err != nil {
+		s.log(l).ErrorContext(ctx, "updating analyzed escrow", "error", err)
+		return EscrowAnalysis{}, err
+	}
+	s.log(l).InfoContext(ctx, "escrow analyzed", "shortage", a.Shortage, "surplus", a.Surplus, "payment", a.Payment)
+	return a, s.publish(ctx, l, NewEvent(EventEscrowAnalyzed, l.ID, a))
+}
@@ -0,0 +1,140 @@
+// Package esign confirms loan agreement signatures with a one-time
+// password sent by SMS.
+package esign
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"loan"
+	"loan/sms"
+)
+
+// Errors returned when confirming a signature
+var (
+	ErrChallengeNotFound = errors.New("esign: challenge not found")
+	ErrChallengeExpired  = errors.New("esign: one-time password expired")
+	ErrInvalidCode       = errors.New("esign: invalid one-time password")
+	ErrTooManyAttempts   = errors.New("esign: too many attempts")
+)
+
+// Signature is the evidence that a customer accepted a loan agreement
+type Signature struct {
+	LoanID      string    `json:"loanId"`
+	ChallengeID string    `json:"challengeId"`
+	Method      string    `json:"method"`
+	Phone       string    `json:"phone"`
+	SignedAt    time.Time `json:"signedAt"`
+}
+
+type challenge struct {
+	loanID    string
+	phone     string
+	codeHash  [32]byte
+	expiresAt time.Time
+	attempts  int
+}
+
+// Service issues and verifies signing challenges
+type Service struct {
+	sender      sms.SMSSender
+	ttl         time.Duration
+	maxAttempts int
+	now         func() time.Time
+
+	mu         sync.Mutex
+	challenges map[string]*challenge
+}
+
+// NewService creates a signing service sending codes through sender.
+// Codes are valid for five minutes and allow three attempts.
+func NewService(sender sms.SMSSender) *Service {
+	return &Service{
+		sender:      sender,
+		ttl:         5 * time.Minute,
+		maxAttempts: 3,
+		now:         time.Now,
+		challenges:  make(map[string]*challenge),
+	}
+}
+
+// RequestSignature sends a six-digit code to phone and returns the challenge ID
+func (s *Service) RequestSignature(ctx context.Context, loanID, phone string) (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	id := loan.NewID()
+	body := fmt.Sprintf("Your code to sign loan agreement %s is %s. It expires in %d minutes.",
+		loanID, code, int(s.ttl.Minutes()))
+	if _, err := s.sender.SendSMS(ctx, phone, body); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.challenges[id] = &challenge{
+		loanID:    loanID,
+		phone:     phone,
+		codeHash:  sha256.Sum256([]byte(code)),
+		expiresAt: s.now().Add(s.ttl),
+	}
+	return id, nil
+}
+
+// Confirm verifies code for the challenge and returns the signature.
+// A challenge can be confirmed only once.
+func (s *Service) Confirm(ctx context.Context, challengeID, code string) (Signature, error) {
+	if err := ctx.Err(); err != nil {
+		return Signature{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.challenges[challengeID]
+	if !ok {
+		return Signature{}, ErrChallengeNotFound
+	}
+	now := s.now()
+	if now.After(c.expiresAt) {
+		delete(s.challenges, challengeID)
+		return Signature{}, ErrChallengeExpired
+	}
+	hash := sha256.Sum256([]byte(code))
+	if subtle.ConstantTimeCompare(hash[:], c.codeHash[:]) != 1 {
+		c.attempts++
+		if c.attempts >= s.maxAttempts {
+			delete(s.challenges, challengeID)
+			return Signature{}, ErrTooManyAttempts
+		}
+		return Signature{}, ErrInvalidCode
+	}
+	delete(s.challenges, challengeID)
+	return Signature{
+		LoanID:      c.loanID,
+		ChallengeID: challengeID,
+		Method:      "sms-otp",
+		Phone:       maskPhone(c.phone),
+		SignedAt:    now.UTC(),
+	}, nil
+}
+
+func maskPhone(p string) string {
+	if len(p) <= 4 {
+		return p
+	}
+	masked := []byte(p)
+	for i := 0; i < len(masked)-4; i++ {
+		if masked[i] >= '0' && masked[i] <= '9' {
+			masked[i] = '*'
+		}
+	}
+	return string(masked)
+}
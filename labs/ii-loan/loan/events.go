@@ -0,0 +1,92 @@
+package loan
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// EventType identifies the kind of domain event raised by the loan service
+type EventType string
+
+// Domain event types published by the loan service
+const (
+	EventApplicationSubmitted EventType = "loan.application.submitted"
+	EventLoanApproved         EventType = "loan.approved"
+	EventLoanRejected         EventType = "loan.rejected"
+	EventLoanDisbursed        EventType = "loan.disbursed"
+	EventPaymentReceived      EventType = "loan.payment.received"
+	EventInstallmentDue       EventType = "loan.installment.due"
+	EventPaymentOverdue       EventType = "loan.payment.overdue"
+	EventDirectDebitFailed    EventType = "loan.debit.failed"
+	EventStatementGenerated   EventType = "loan.statement.generated"
+	EventLoanTransferred      EventType = "loan.transferred"
+	EventTermsChanged         EventType = "loan.terms.changed"
+	EventLoanSettled          EventType = "loan.settled"
+	EventEscrowDisbursed      EventType = "loan.escrow.disbursed"
+	EventEscrowShortfall      EventType = "loan.escrow.shortfall"
+	EventEscrowAnalyzed       EventType = "loan.escrow.analyzed"
+	EventInsuranceAttached    EventType = "loan.insurance.attached"
+	EventInsuranceClaimed     EventType = "loan.insurance.claimed"
+)
+
+// Event is a domain event describing a change to a loan
+type Event struct {
+	ID         string
+	Type       EventType
+	LoanID     string
+	OccurredAt time.Time
+	Data       any
+}
+
+// NewEvent creates an event of the given type with a fresh ID
+func NewEvent(eventType EventType, loanID string, data any) Event {
+	return Event{
+		ID:         NewID(),
+		Type:       eventType,
+		LoanID:     loanID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// EventPublisher delivers domain events to interested parties
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// EventPublisherFunc adapts a function to the EventPublisher interface
+type EventPublisherFunc func(ctx context.Context, event Event) error
+
+// Publish calls f(ctx, event)
+func (f EventPublisherFunc) Publish(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// MultiPublisher publishes every event to each publisher in turn and
+// returns the joined errors
+func MultiPublisher(publishers ...EventPublisher) EventPublisher {
+	return EventPublisherFunc(func(ctx context.Context, event Event) error {
+		var errs []error
+		for _, p := range publishers {
+			errs = append(errs, p.Publish(ctx, event))
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// nopPublisher is used when no publisher is configured
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(context.Context, Event) error { return nil }
+
+// NewID returns a random 128-bit identifier encoded as hex
+func NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
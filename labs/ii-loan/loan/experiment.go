@@ -0,0 +1,102 @@
+package loan
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"loan/tracing"
+)
+
+// ExperimentAssignment records which arm of an experiment decided an
+// application
+type ExperimentAssignment struct {
+	Name string `json:"name"`
+	Arm  string `json:"arm"`
+	// ShadowOutcome is what the other arm would have decided, when the
+	// experiment runs both
+	ShadowOutcome string `json:"shadowOutcome,omitempty"`
+}
+
+// ArmResult is how the applications of one experiment arm were decided
+// and how the loans they became have performed
+type ArmResult struct {
+	Arm          string         `json:"arm"`
+	Applications int            `json:"applications"`
+	Outcomes     map[string]int `json:"outcomes"`
+	// ShadowOutcomes counts what the other arm would have decided
+	ShadowOutcomes map[string]int `json:"shadowOutcomes,omitempty"`
+	AveragePD      float64        `json:"averagePd"`
+	// Booked counts the loans lent out, whatever the engine advised
+	Booked        int `json:"booked"`
+	NonPerforming int `json:"nonPerforming"`
+	// ApprovalRate is the approve outcomes over the applications
+	ApprovalRate float64 `json:"approvalRate"`
+	// NPLRate is the non-performing loans over the booked ones
+	NPLRate float64 `json:"nplRate"`
+}
+
+// ExperimentReport compares the arms of an experiment
+type ExperimentReport struct {
+	Name string      `json:"name"`
+	Arms []ArmResult `json:"arms"`
+}
+
+// Experiment compares the arms of the named experiment over every
+// application it decided. Applications keep their arm, so the report can
+// be rerun as the loans age to follow their performance.
+func (s *ReportingService) Experiment(ctx context.Context, name string) (_ ExperimentReport, err error) {
+	ctx, span := tracing.Start(ctx, "ReportingService.Experiment", attribute.String("experiment.name", name))
+	defer tracing.End(span, &err)
+	loans, err := s.repo.List(ctx, Filter{})
+	if err != nil {
+		return ExperimentReport{}, err
+	}
+	arms := map[string]*ArmResult{}
+	pd := map[string]float64{}
+	for _, l := range loans {
+		d := l.Decision
+		if d == nil || d.Experiment == nil || d.Experiment.Name != name {
+			continue
+		}
+		a := arms[d.Experiment.Arm]
+		if a == nil {
+			a = &ArmResult{Arm: d.Experiment.Arm, Outcomes: map[string]int{}}
+			arms[d.Experiment.Arm] = a
+		}
+		a.Applications++
+		a.Outcomes[d.Outcome]++
+		if o := d.Experiment.ShadowOutcome; o != "" {
+			if a.ShadowOutcomes == nil {
+				a.ShadowOutcomes = map[string]int{}
+			}
+			a.ShadowOutcomes[o]++
+		}
+		pd[a.Arm] += d.PD
+		if l.Status == StatusApproved || l.Status == StatusDefault {
+			a.Booked++
+			if l.IsNonPerforming() {
+				a.NonPerforming++
+			}
+		}
+	}
+	r := ExperimentReport{Name: name, Arms: []ArmResult{}}
+	for _, a := range arms {
+		a.AveragePD = ratio(pd[a.Arm], float64(a.Applications))
+		a.ApprovalRate = ratio(float64(a.Outcomes[OutcomeApprove]), float64(a.Applications))
+		a.NPLRate = ratio(float64(a.NonPerforming), float64(a.Booked))
+		r.Arms = append(r.Arms, *a)
+	}
+	sort.Slice(r.Arms, func(i, j int) bool { return r.Arms[i].Arm < r.Arms[j].Arm })
+	return r, nil
+}
+
+// ratio returns n/d to four places, 0 when d is 0
+func ratio(n, d float64) float64 {
+	if d == 0 {
+		return 0
+	}
+	return math.Round(n/d*10000) / 10000
+}
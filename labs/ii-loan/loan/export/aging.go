@@ -0,0 +1,46 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"loan"
+)
+
+// AgingColumns is the header of WriteAgingCSV
+var AgingColumns = []string{
+	"month", "bucket", "product", "count", "outstanding", "previous_count", "previous_outstanding", "change",
+}
+
+// WriteAgingCSV writes the aging report as one row per bucket and
+// product, followed by a row per bucket with an empty product and a
+// grand total row with neither
+func WriteAgingCSV(w io.Writer, r loan.AgingReport) error {
+	cw := csv.NewWriter(w)
+	month := r.Month.Format("2006-01")
+	row := func(c loan.AgingCell) []string {
+		return []string{
+			month, string(c.Bucket), c.Product, strconv.Itoa(c.Count), amount(c.Outstanding),
+			strconv.Itoa(c.PreviousCount), amount(c.PreviousOutstanding), amount(c.Change),
+		}
+	}
+	if err := cw.Write(AgingColumns); err != nil {
+		return err
+	}
+	for _, c := range r.Rows {
+		if err := cw.Write(row(c)); err != nil {
+			return err
+		}
+	}
+	for _, c := range r.ByBucket {
+		if err := cw.Write(row(c)); err != nil {
+			return err
+		}
+	}
+	if err := cw.Write(row(r.Total)); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,193 @@
+// Package export writes the loan book as CSV, newline-delimited JSON or a
+// formatted XLSX workbook for finance teams to load into spreadsheets.
+// Loans are streamed from the repository one at a time, so exports of any
+// size run in constant memory on repositories that implement loan.Streamer.
+//
+//	n, err := export.Write(ctx, w, repo, loan.Filter{Statuses: []string{loan.StatusApproved}}, export.CSV)
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"loan"
+)
+
+// Format is an export file format
+type Format string
+
+// Supported formats
+const (
+	CSV    Format = "csv"
+	NDJSON Format = "ndjson"
+	XLSX   Format = "xlsx"
+)
+
+// ParseFormat returns the format named s
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case CSV, NDJSON, XLSX:
+		return f, nil
+	}
+	return "", fmt.Errorf("export: unknown format %q", s)
+}
+
+// ContentType returns the media type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case NDJSON:
+		return "application/x-ndjson"
+	case XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Record is one exported loan. Schedules and payments are summarised
+// rather than nested so every format has the same flat columns.
+type Record struct {
+	ID              string     `json:"id"`
+	CustomerID      string     `json:"customerId"`
+	Product         string     `json:"product"`
+	Status          string     `json:"status"`
+	Amount          float64    `json:"amount"`
+	InterestRate    float64    `json:"interestRate"`
+	TermMonths      int        `json:"termMonths"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	ApprovedAt      *time.Time `json:"approvedAt,omitempty"`
+	Balance         float64    `json:"balance"`
+	AccruedInterest float64    `json:"accruedInterest"`
+	TotalPaid       float64    `json:"totalPaid"`
+	DaysPastDue     int        `json:"daysPastDue"`
+	Delinquency     string     `json:"delinquency"`
+	RiskGrade       string     `json:"riskGrade"`
+	RejectionReason string     `json:"rejectionReason"`
+}
+
+// NewRecord flattens l
+func NewRecord(l *loan.Loan) Record {
+	r := Record{
+		ID:              l.ID,
+		CustomerID:      l.CustomerID,
+		Product:         l.Product,
+		Status:          l.Status,
+		Amount:          l.Amount,
+		InterestRate:    l.AnnualRate(),
+		TermMonths:      l.TermMonths,
+		CreatedAt:       l.CreatedAt.UTC(),
+		Balance:         l.Balance,
+		AccruedInterest: l.AccruedInterest,
+		DaysPastDue:     l.DaysPastDue,
+		Delinquency:     string(l.Delinquency),
+		RiskGrade:       loan.RiskGrade(l.CreditScore),
+		RejectionReason: l.RejectionReason,
+	}
+	if !l.ApprovedAt.IsZero() {
+		t := l.ApprovedAt.UTC()
+		r.ApprovedAt = &t
+	}
+	var paid float64
+	for _, p := range l.Payments {
+		paid += p.Amount
+	}
+	r.TotalPaid = math.Round(paid*100) / 100
+	return r
+}
+
+// Columns is the CSV header, in the order of Record.row
+var Columns = []string{
+	"id", "customer_id", "product", "status", "amount", "interest_rate", "term_months", "created_at", "approved_at",
+	"balance", "accrued_interest", "total_paid", "days_past_due", "delinquency", "risk_grade", "rejection_reason",
+}
+
+func (r Record) row() []string {
+	return []string{
+		r.ID, r.CustomerID, r.Product, r.Status, amount(r.Amount), strconv.FormatFloat(r.InterestRate, 'f', -1, 64),
+		strconv.Itoa(r.TermMonths), date(&r.CreatedAt), date(r.ApprovedAt),
+		amount(r.Balance), amount(r.AccruedInterest), amount(r.TotalPaid), strconv.Itoa(r.DaysPastDue),
+		r.Delinquency, r.RiskGrade, r.RejectionReason,
+	}
+}
+
+func amount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// date formats t as RFC 3339, or empty when unset
+func date(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Write exports the loans matching filter in format f and returns how
+// many were written
+func Write(ctx context.Context, w io.Writer, repo loan.LoanRepository, filter loan.Filter, f Format) (int, error) {
+	switch f {
+	case CSV:
+		return WriteCSV(ctx, w, repo, filter)
+	case NDJSON:
+		return WriteNDJSON(ctx, w, repo, filter)
+	case XLSX:
+		return WriteXLSX(ctx, w, repo, filter)
+	}
+	return 0, fmt.Errorf("export: unknown format %q", f)
+}
+
+// flushEvery is how many records are buffered before they are pushed to
+// the writer, so long exports reach the client as they are produced
+const flushEvery = 500
+
+// WriteCSV writes a header line and one row per loan matching filter
+func WriteCSV(ctx context.Context, w io.Writer, repo loan.LoanRepository, filter loan.Filter) (int, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Columns); err != nil {
+		return 0, err
+	}
+	n := 0
+	err := loan.EachLoan(ctx, repo, filter, func(l *loan.Loan) error {
+		if err := cw.Write(NewRecord(l).row()); err != nil {
+			return err
+		}
+		n++
+		if n%flushEvery == 0 {
+			cw.Flush()
+			return cw.Error()
+		}
+		return nil
+	})
+	cw.Flush()
+	if err != nil {
+		return n, err
+	}
+	return n, cw.Error()
+}
+
+// WriteNDJSON writes one JSON Record per line for each loan matching filter
+func WriteNDJSON(ctx context.Context, w io.Writer, repo loan.LoanRepository, filter loan.Filter) (int, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	n := 0
+	err := loan.EachLoan(ctx, repo, filter, func(l *loan.Loan) error {
+		if err := enc.Encode(NewRecord(l)); err != nil {
+			return err
+		}
+		n++
+		if n%flushEvery == 0 {
+			return bw.Flush()
+		}
+		return nil
+	})
+	if flushErr := bw.Flush(); err == nil {
+		err = flushErr
+	}
+	return n, err
+}
@@ -0,0 +1,35 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"loan"
+)
+
+// RollRateColumns is the header of WriteRollRatesCSV
+var RollRateColumns = []string{"month", "from", "to", "count", "outstanding", "rate"}
+
+// WriteRollRatesCSV writes the roll-rate matrix as one row per bucket and
+// destination
+func WriteRollRatesCSV(w io.Writer, r loan.RollRateReport) error {
+	cw := csv.NewWriter(w)
+	month := r.Month.Format("2006-01")
+	if err := cw.Write(RollRateColumns); err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		for _, c := range row.To {
+			rec := []string{
+				month, string(row.From), string(c.To), strconv.Itoa(c.Count), amount(c.Outstanding),
+				strconv.FormatFloat(c.Rate, 'f', -1, 64),
+			}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
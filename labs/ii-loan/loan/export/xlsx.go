@@ -0,0 +1,309 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"loan"
+)
+
+// The workbook is written as plain SpreadsheetML: a zip of XML parts with
+// one worksheet part per sheet. Sheets are written one after the other,
+// each from its own pass over the repository, so memory stays flat however
+// large the book is.
+
+// Cell styles, indexes into cellXfs of styles.xml
+const (
+	styleDefault = iota
+	styleMoney
+	styleDate
+	stylePercent
+	styleHeader
+)
+
+const stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="3"><numFmt numFmtId="164" formatCode="#,##0.00"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd"/><numFmt numFmtId="166" formatCode="0.00%"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="5">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+</cellXfs>
+</styleSheet>`
+
+// sheets are the worksheets of the workbook, in tab order
+var sheets = []struct {
+	name  string
+	write func(ctx context.Context, s *sheet, repo loan.LoanRepository, filter loan.Filter) error
+}{
+	{"Summary", summarySheet},
+	{"Loans", loansSheet},
+	{"Payments", paymentsSheet},
+	{"Delinquencies", delinquenciesSheet},
+}
+
+// WriteXLSX writes a workbook with a summary of the loans matching filter
+// and sheets listing the loans, their payments and the delinquent ones.
+// It returns how many loans the Loans sheet holds.
+func WriteXLSX(ctx context.Context, w io.Writer, repo loan.LoanRepository, filter loan.Filter) (int, error) {
+	zw := zip.NewWriter(w)
+	part := func(name, body string) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, body)
+		return err
+	}
+
+	var types, rels, entries strings.Builder
+	for i, sh := range sheets {
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+		fmt.Fprintf(&entries, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, sh.name, i+1, i+1)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+
+	err := part("[Content_Types].xml", xml.Header+`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`+
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`+
+		`<Default Extension="xml" ContentType="application/xml"/>`+
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`+
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`+
+		types.String()+`</Types>`)
+	if err == nil {
+		err = part("_rels/.rels", xml.Header+`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`)
+	}
+	if err == nil {
+		err = part("xl/workbook.xml", xml.Header+`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" `+
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`+entries.String()+`</sheets></workbook>`)
+	}
+	if err == nil {
+		err = part("xl/_rels/workbook.xml.rels", xml.Header+`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`+
+			rels.String()+`</Relationships>`)
+	}
+	if err == nil {
+		err = part("xl/styles.xml", stylesXML)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	loans := 0
+	for i, sh := range sheets {
+		f, err := zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return loans, err
+		}
+		s := &sheet{w: bufio.NewWriter(f)}
+		if err := sh.write(ctx, s, repo, filter); err != nil {
+			return loans, err
+		}
+		if err := s.close(); err != nil {
+			return loans, err
+		}
+		if sh.name == "Loans" {
+			loans = s.rows - 1
+		}
+	}
+	return loans, zw.Close()
+}
+
+// cell is one worksheet cell
+type cell struct {
+	text   string
+	number float64
+	isText bool
+	style  int
+}
+
+func text(s string) cell { return cell{text: s, isText: true} }
+
+func header(s string) cell { return cell{text: s, isText: true, style: styleHeader} }
+
+func number(v float64) cell { return cell{number: v} }
+
+func money(v float64) cell { return cell{number: v, style: styleMoney} }
+
+func percent(v float64) cell { return cell{number: v, style: stylePercent} }
+
+// excelEpoch is day zero of spreadsheet date serials
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// day is a date cell, empty for the zero time
+func day(t time.Time) cell {
+	if t.IsZero() {
+		return text("")
+	}
+	return cell{number: t.UTC().Sub(excelEpoch).Hours() / 24, style: styleDate}
+}
+
+// sheet streams the rows of one worksheet
+type sheet struct {
+	w      *bufio.Writer
+	rows   int
+	opened bool
+}
+
+// start writes the worksheet preamble with column widths in characters;
+// a header row, if any, stays frozen at the top
+func (s *sheet) start(widths ...float64) {
+	s.opened = true
+	s.w.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	s.w.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	if len(widths) > 0 {
+		s.w.WriteString("<cols>")
+		for i, wd := range widths {
+			fmt.Fprintf(s.w, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, wd)
+		}
+		s.w.WriteString("</cols>")
+	}
+	s.w.WriteString("<sheetData>")
+}
+
+// row appends a row of cells
+func (s *sheet) row(cells ...cell) {
+	if !s.opened {
+		s.start()
+	}
+	s.rows++
+	fmt.Fprintf(s.w, `<row r="%d">`, s.rows)
+	for i, c := range cells {
+		ref := column(i) + strconv.Itoa(s.rows)
+		switch {
+		case c.isText && c.text == "":
+		case c.isText:
+			fmt.Fprintf(s.w, `<c r="%s" t="inlineStr" s="%d"><is><t xml:space="preserve">`, ref, c.style)
+			xml.EscapeText(s.w, []byte(c.text))
+			s.w.WriteString("</t></is></c>")
+		default:
+			fmt.Fprintf(s.w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, c.style, strconv.FormatFloat(c.number, 'f', -1, 64))
+		}
+	}
+	s.w.WriteString("</row>")
+}
+
+func (s *sheet) close() error {
+	if !s.opened {
+		s.start()
+	}
+	s.w.WriteString("</sheetData></worksheet>")
+	return s.w.Flush()
+}
+
+// column returns the letters of the zero-based column i
+func column(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func summarySheet(ctx context.Context, s *sheet, repo loan.LoanRepository, filter loan.Filter) error {
+	reports := loan.NewReportingService(repo)
+	byStatus, err := reports.Group(ctx, filter, loan.ByStatus)
+	if err != nil {
+		return err
+	}
+	performance, err := reports.Group(ctx, filter, loan.ByPerformance)
+	if err != nil {
+		return err
+	}
+
+	s.start(24, 12, 18, 18)
+	s.row(header("Loan book"), text(""), text("Generated"), day(time.Now()))
+	s.row()
+	s.row(header("Status"), header("Loans"), header("Principal"), header("Outstanding"))
+	var total loan.Group
+	for _, g := range byStatus {
+		s.row(text(g.Key), number(float64(g.Count)), money(g.Principal), money(g.Outstanding))
+		total.Count += g.Count
+		total.Principal += g.Principal
+		total.Outstanding += g.Outstanding
+	}
+	s.row(header("Total"), number(float64(total.Count)), money(math.Round(total.Principal*100)/100), money(math.Round(total.Outstanding*100)/100))
+	s.row()
+	s.row(header("Performance"), header("Loans"), header("Principal"), header("Outstanding"))
+	var npl, outstanding float64
+	for _, g := range performance {
+		s.row(text(g.Key), number(float64(g.Count)), money(g.Principal), money(g.Outstanding))
+		outstanding += g.Outstanding
+		if g.Key == loan.NonPerformingKey {
+			npl += g.Outstanding
+		}
+	}
+	if outstanding > 0 {
+		s.row(header("NPL ratio"), text(""), text(""), percent(npl/outstanding))
+	}
+	return nil
+}
+
+func loansSheet(ctx context.Context, s *sheet, repo loan.LoanRepository, filter loan.Filter) error {
+	s.start(34, 16, 10, 10, 14, 10, 8, 12, 12, 14, 14, 14, 8, 12, 8, 30)
+	headers := make([]cell, len(Columns))
+	for i, c := range Columns {
+		headers[i] = header(c)
+	}
+	s.row(headers...)
+	return loan.EachLoan(ctx, repo, filter, func(l *loan.Loan) error {
+		r := NewRecord(l)
+		approved := time.Time{}
+		if r.ApprovedAt != nil {
+			approved = *r.ApprovedAt
+		}
+		s.row(text(r.ID), text(r.CustomerID), text(r.Product), text(r.Status), money(r.Amount), percent(r.InterestRate),
+			number(float64(r.TermMonths)), day(r.CreatedAt), day(approved),
+			money(r.Balance), money(r.AccruedInterest), money(r.TotalPaid), number(float64(r.DaysPastDue)),
+			text(r.Delinquency), text(r.RiskGrade), text(r.RejectionReason))
+		return nil
+	})
+}
+
+func paymentsSheet(ctx context.Context, s *sheet, repo loan.LoanRepository, filter loan.Filter) error {
+	s.start(34, 16, 34, 12, 14, 14, 14)
+	s.row(header("loan_id"), header("customer_id"), header("payment_id"), header("paid_at"),
+		header("amount"), header("interest"), header("principal"))
+	return loan.EachLoan(ctx, repo, filter, func(l *loan.Loan) error {
+		for _, p := range l.Payments {
+			s.row(text(l.ID), text(l.CustomerID), text(p.ID), day(p.PaidAt), money(p.Amount), money(p.Interest), money(p.Principal))
+		}
+		return nil
+	})
+}
+
+func delinquenciesSheet(ctx context.Context, s *sheet, repo loan.LoanRepository, filter loan.Filter) error {
+	now := time.Now()
+	s.start(34, 16, 10, 8, 10, 14, 14, 14)
+	s.row(header("loan_id"), header("customer_id"), header("product"), header("days_past_due"), header("bucket"),
+		header("oldest_unpaid_due"), header("overdue_amount"), header("balance"))
+	return loan.EachLoan(ctx, repo, filter, func(l *loan.Loan) error {
+		if l.DaysPastDue <= 0 {
+			return nil
+		}
+		var overdue float64
+		for _, inst := range l.Schedule {
+			if !inst.DueDate.After(now) {
+				overdue += inst.Outstanding()
+			}
+		}
+		oldest, _ := l.OldestUnpaid(now)
+		s.row(text(l.ID), text(l.CustomerID), text(l.Product), number(float64(l.DaysPastDue)), text(string(loan.BucketFor(l.DaysPastDue))),
+			day(oldest.DueDate), money(math.Round(overdue*100)/100), money(l.Balance))
+		return nil
+	})
+}
@@ -0,0 +1,54 @@
+package loan
+
+import (
+	"context"
+
+	"loan/tracing"
+)
+
+// Exposure is what a customer already owes the lender or has applied for.
+// Amounts of loans in different currencies are summed as booked.
+type Exposure struct {
+	CustomerID string `json:"customerId"`
+	// Outstanding is the balance of the customer's active loans
+	Outstanding float64 `json:"outstanding"`
+	// Pending is the amount of the customer's applications awaiting a
+	// decision
+	Pending float64 `json:"pending"`
+	// Loans and Applications count the loans behind each amount
+	Loans        int `json:"loans"`
+	Applications int `json:"applications"`
+	// Limit is the most the customer may owe with pending applications
+	// counted, 0 for no limit
+	Limit float64 `json:"limit,omitempty"`
+}
+
+// Total is the outstanding and pending amounts together
+func (e Exposure) Total() float64 {
+	return round2(e.Outstanding + e.Pending)
+}
+
+// CustomerExposure sums the balances of a customer's active loans and the
+// amounts of their pending applications, with the exposure limit in force
+func (s *LoanService) CustomerExposure(ctx context.Context, customerID string) (_ Exposure, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.CustomerExposure")
+	defer tracing.End(span, &err)
+
+	loans, err := s.repo.List(ctx, Filter{CustomerID: customerID, Statuses: []string{StatusPending, StatusApproved}})
+	if err != nil {
+		return Exposure{}, err
+	}
+	e := Exposure{CustomerID: customerID, Limit: s.limits.MaxExposure}
+	for _, l := range loans {
+		switch {
+		case l.Status == StatusPending:
+			e.Pending += l.Amount
+			e.Applications++
+		case l.IsActive():
+			e.Outstanding += l.Balance
+			e.Loans++
+		}
+	}
+	e.Outstanding, e.Pending = round2(e.Outstanding), round2(e.Pending)
+	return e, nil
+}
@@ -0,0 +1,123 @@
+// Package featureflag toggles behaviour per tenant without a redeploy.
+// Flags are read from an in-memory set or from a JSON file reloaded when
+// it changes:
+//
+//	{
+//	  "risk.scoring-models": {"default": false, "tenants": {"acme": true}},
+//	  "fees.v2": {"default": true, "tenants": {"legacy-bank": false}}
+//	}
+//
+// Flags missing from the set are off.
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"sync"
+
+	"loan/logging"
+)
+
+// Flags reports whether a flag is on for the tenant of ctx, as set by
+// logging.WithTenant
+type Flags interface {
+	Enabled(ctx context.Context, flag string) bool
+}
+
+// Rule is the state of one flag: Default for tenants not listed
+type Rule struct {
+	Default bool            `json:"default"`
+	Tenants map[string]bool `json:"tenants,omitempty"`
+}
+
+// Set maps flag names to their rules
+type Set map[string]Rule
+
+// EnabledFor reports whether flag is on for tenant
+func (s Set) EnabledFor(flag, tenant string) bool {
+	r, ok := s[flag]
+	if !ok {
+		return false
+	}
+	if on, ok := r.Tenants[tenant]; ok && tenant != "" {
+		return on
+	}
+	return r.Default
+}
+
+// Load decodes a JSON flag set
+func Load(r io.Reader) (Set, error) {
+	var s Set
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("featureflag: %w", err)
+	}
+	return s, nil
+}
+
+// clone copies s deeply, so callers cannot change a provider's flags
+func (s Set) clone() Set {
+	c := make(Set, len(s))
+	for name, r := range s {
+		c[name] = Rule{Default: r.Default, Tenants: maps.Clone(r.Tenants)}
+	}
+	return c
+}
+
+// Memory holds flags set in code, for tests, labs and admin tooling
+type Memory struct {
+	mu  sync.RWMutex
+	set Set
+}
+
+// NewMemory creates a provider starting with a copy of set
+func NewMemory(set Set) *Memory {
+	return &Memory{set: set.clone()}
+}
+
+// Enabled implements Flags
+func (m *Memory) Enabled(ctx context.Context, flag string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.set.EnabledFor(flag, logging.Tenant(ctx))
+}
+
+// SetDefault turns flag on or off for tenants without their own setting
+func (m *Memory) SetDefault(flag string, on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.set[flag]
+	r.Default = on
+	m.set[flag] = r
+}
+
+// SetTenant turns flag on or off for tenant only
+func (m *Memory) SetTenant(flag, tenant string, on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.set[flag]
+	r.Tenants = maps.Clone(r.Tenants)
+	if r.Tenants == nil {
+		r.Tenants = map[string]bool{}
+	}
+	r.Tenants[tenant] = on
+	m.set[flag] = r
+}
+
+// Replace swaps every flag for those of set
+func (m *Memory) Replace(set Set) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set = set.clone()
+}
+
+// Snapshot returns a copy of the current flags
+func (m *Memory) Snapshot() Set {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.set.clone()
+}
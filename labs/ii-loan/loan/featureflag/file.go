@@ -0,0 +1,115 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval is how often a File checks for changes
+const DefaultPollInterval = 10 * time.Second
+
+// File serves the flags of a JSON file, reloading it when its
+// modification time or size changes. A file that fails to load leaves the
+// last good flags in force.
+type File struct {
+	path     string
+	interval time.Duration
+	onError  func(error)
+	onReload func(Set)
+	flags    *Memory
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// FileOption configures a File
+type FileOption func(*File)
+
+// WithPollInterval sets how often the file is checked instead of
+// DefaultPollInterval
+func WithPollInterval(d time.Duration) FileOption {
+	return func(f *File) { f.interval = d }
+}
+
+// WithErrorHandler is called with reload errors, which are otherwise
+// dropped
+func WithErrorHandler(fn func(error)) FileOption {
+	return func(f *File) { f.onError = fn }
+}
+
+// WithReloadHandler is called with the new flags after each reload
+func WithReloadHandler(fn func(Set)) FileOption {
+	return func(f *File) { f.onReload = fn }
+}
+
+// NewFile loads the flags in path. It fails if the first load does, so a
+// typo is caught at startup rather than leaving every flag off.
+func NewFile(path string, opts ...FileOption) (*File, error) {
+	f := &File{path: path, interval: DefaultPollInterval, flags: NewMemory(nil)}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Enabled implements Flags
+func (f *File) Enabled(ctx context.Context, flag string) bool {
+	return f.flags.Enabled(ctx, flag)
+}
+
+// Snapshot returns a copy of the current flags
+func (f *File) Snapshot() Set {
+	return f.flags.Snapshot()
+}
+
+// Reload reads the file if it changed since the last load, reporting
+// whether it did
+func (f *File) Reload() (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, fmt.Errorf("featureflag: %w", err)
+	}
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return false, nil
+	}
+	r, err := os.Open(f.path)
+	if err != nil {
+		return false, fmt.Errorf("featureflag: %w", err)
+	}
+	defer r.Close()
+	set, err := Load(r)
+	if err != nil {
+		return false, fmt.Errorf("%w (in %s)", err, f.path)
+	}
+	f.flags.Replace(set)
+	f.modTime, f.size = info.ModTime(), info.Size()
+	if f.onReload != nil {
+		f.onReload(set.clone())
+	}
+	return true, nil
+}
+
+// Watch reloads the file every poll interval until ctx is done
+func (f *File) Watch(ctx context.Context) {
+	t := time.NewTicker(f.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := f.Reload(); err != nil && f.onError != nil {
+				f.onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package fieldcrypt
+
+import (
+	"context"
+
+	"loan"
+)
+
+// customerFields are the sensitive customer fields that are encrypted
+func customerFields(c *loan.Customer) map[string]*string {
+	return map[string]*string{
+		"nationalId":  &c.NationalID,
+		"bankAccount": &c.BankAccount,
+	}
+}
+
+// CustomerRepository encrypts the national ID and bank account of
+// customers on the way into the wrapped repository and decrypts them on
+// the way out
+type CustomerRepository struct {
+	next   loan.CustomerRepository
+	cipher *Cipher
+}
+
+var _ loan.CustomerRepository = (*CustomerRepository)(nil)
+
+// NewCustomerRepository wraps next
+func NewCustomerRepository(next loan.CustomerRepository, c *Cipher) *CustomerRepository {
+	return &CustomerRepository{next: next, cipher: c}
+}
+
+// aad binds a value to the field and record it was sealed for
+func aad(kind, id, field string) string {
+	return kind + "/" + id + "/" + field
+}
+
+// seal returns a copy of c with its sensitive fields encrypted
+func (r *CustomerRepository) seal(ctx context.Context, c *loan.Customer) (*loan.Customer, error) {
+	out := *c
+	for field, v := range customerFields(&out) {
+		enc, err := r.cipher.Encrypt(ctx, *v, aad("customer", out.ID, field))
+		if err != nil {
+			return nil, err
+		}
+		*v = enc
+	}
+	return &out, nil
+}
+
+func (r *CustomerRepository) open(ctx context.Context, c *loan.Customer) error {
+	for field, v := range customerFields(c) {
+		plain, err := r.cipher.Decrypt(ctx, *v, aad("customer", c.ID, field))
+		if err != nil {
+			return err
+		}
+		*v = plain
+	}
+	return nil
+}
+
+// Save stores a new customer with its sensitive fields encrypted
+func (r *CustomerRepository) Save(ctx context.Context, c *loan.Customer) error {
+	sealed, err := r.seal(ctx, c)
+	if err != nil {
+		return err
+	}
+	return r.next.Save(ctx, sealed)
+}
+
+// FindByID returns the customer with its sensitive fields decrypted
+func (r *CustomerRepository) FindByID(ctx context.Context, id string) (*loan.Customer, error) {
+	c, err := r.next.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.open(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update replaces a customer, encrypting its sensitive fields
+func (r *CustomerRepository) Update(ctx context.Context, c *loan.Customer) error {
+	sealed, err := r.seal(ctx, c)
+	if err != nil {
+		return err
+	}
+	return r.next.Update(ctx, sealed)
+}
+
+// Rotate re-encrypts a stored customer under the current key when any of
+// its fields is plaintext or sealed under an older key, and reports
+// whether it did. Run it over every customer before retiring a key.
+func (r *CustomerRepository) Rotate(ctx context.Context, id string) (bool, error) {
+	stored, err := r.next.FindByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	stale := false
+	for _, v := range customerFields(stored) {
+		s, err := r.cipher.Stale(ctx, *v)
+		if err != nil {
+			return false, err
+		}
+		stale = stale || s
+	}
+	if !stale {
+		return false, nil
+	}
+	if err := r.open(ctx, stored); err != nil {
+		return false, err
+	}
+	return true, r.Update(ctx, stored)
+}
@@ -0,0 +1,207 @@
+// Package fieldcrypt encrypts sensitive fields, such as national IDs and
+// bank account numbers, before they reach a repository. Values are sealed
+// with AES-256-GCM under the current key of a KeyProvider and carry the ID
+// of that key, so keys can be rotated without re-encrypting everything at
+// once: old values stay readable as long as their key is provided, and
+// Rotate moves a record onto the current key.
+//
+// Wrapping a repository makes the encryption transparent to the service:
+//
+//	spec, _ := provider.Secret(ctx, "field-keys") // a secrets.Provider
+//	keys, _ := fieldcrypt.ParseKeys(spec)
+//	customers := fieldcrypt.NewCustomerRepository(sqlstore.NewCustomerRepository(db), fieldcrypt.New(keys))
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix marks an encrypted value: enc:v1:<key ID>:<base64 nonce+ciphertext>
+const prefix = "enc:v1:"
+
+// ErrUnknownKey is returned when a value was encrypted with a key the
+// provider does not have
+var ErrUnknownKey = errors.New("fieldcrypt: unknown key")
+
+// KeySize is the length of AES-256 keys
+const KeySize = 32
+
+// Key is a named data encryption key
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// KeyProvider hands out encryption keys. Implementations may fetch them
+// from a KMS or secret store; they should cache, as every field
+// encryption and decryption asks for a key.
+type KeyProvider interface {
+	// Current returns the key new values are encrypted with
+	Current(ctx context.Context) (Key, error)
+	// Key returns the key with the given ID, or ErrUnknownKey
+	Key(ctx context.Context, id string) (Key, error)
+}
+
+// StaticKeys is a KeyProvider over a fixed set of keys
+type StaticKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewStaticKeys creates a provider encrypting with the key named current.
+// Every key must be KeySize bytes.
+func NewStaticKeys(current string, keys map[string][]byte) (*StaticKeys, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, current)
+	}
+	for id, k := range keys {
+		if len(k) != KeySize {
+			return nil, fmt.Errorf("fieldcrypt: key %q is %d bytes, want %d", id, len(k), KeySize)
+		}
+		if strings.Contains(id, ":") {
+			return nil, fmt.Errorf("fieldcrypt: key ID %q contains ':'", id)
+		}
+	}
+	return &StaticKeys{current: current, keys: keys}, nil
+}
+
+// ParseKeys reads keys written as "id:base64key,id:base64key"; the first is
+// the current one and the others are kept for decryption
+func ParseKeys(spec string) (*StaticKeys, error) {
+	keys := map[string][]byte{}
+	var current string
+	for _, part := range strings.Split(spec, ",") {
+		id, enc, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("fieldcrypt: malformed key %q, want id:base64key", part)
+		}
+		secret, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil {
+			return nil, fmt.Errorf("fieldcrypt: key %q: %w", id, err)
+		}
+		if current == "" {
+			current = id
+		}
+		keys[id] = secret
+	}
+	return NewStaticKeys(current, keys)
+}
+
+// Current implements KeyProvider
+func (s *StaticKeys) Current(ctx context.Context) (Key, error) {
+	return s.Key(ctx, s.current)
+}
+
+// Key implements KeyProvider
+func (s *StaticKeys) Key(_ context.Context, id string) (Key, error) {
+	secret, ok := s.keys[id]
+	if !ok {
+		return Key{}, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return Key{ID: id, Secret: secret}, nil
+}
+
+// Cipher encrypts and decrypts field values
+type Cipher struct {
+	keys KeyProvider
+}
+
+// New creates a cipher using keys
+func New(keys KeyProvider) *Cipher {
+	return &Cipher{keys: keys}
+}
+
+// IsEncrypted reports whether v is an encrypted value
+func IsEncrypted(v string) bool {
+	return strings.HasPrefix(v, prefix)
+}
+
+// KeyID returns the ID of the key v was encrypted with, or "" when v is
+// plaintext
+func KeyID(v string) string {
+	if !IsEncrypted(v) {
+		return ""
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(v, prefix), ":")
+	return id
+}
+
+func aead(k Key) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.Secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals plaintext under the current key. aad binds the value to
+// its context, such as the record ID and field name, so it cannot be moved
+// to another record. Empty values stay empty.
+func (c *Cipher) Encrypt(ctx context.Context, plaintext, aad string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	k, err := c.keys.Current(ctx)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := aead(k)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(aad))
+	return prefix + k.ID + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt with the same aad. Plaintext
+// values, written before encryption was enabled, are returned unchanged.
+func (c *Cipher) Decrypt(ctx context.Context, value, aad string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	id, enc, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return "", errors.New("fieldcrypt: malformed encrypted value")
+	}
+	k, err := c.keys.Key(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := aead(k)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(enc)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", errors.New("fieldcrypt: malformed encrypted value")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(aad))
+	if err != nil {
+		return "", fmt.Errorf("fieldcrypt: decrypting with key %q: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+// Stale reports whether value is plaintext or sealed under a key other
+// than the current one
+func (c *Cipher) Stale(ctx context.Context, value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	k, err := c.keys.Current(ctx)
+	if err != nil {
+		return false, err
+	}
+	return KeyID(value) != k.ID, nil
+}
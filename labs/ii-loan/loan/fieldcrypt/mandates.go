@@ -0,0 +1,85 @@
+package fieldcrypt
+
+import (
+	"context"
+
+	"loan"
+)
+
+// MandateRepository encrypts the bank account of direct debit mandates on
+// the way into the wrapped repository and decrypts it on the way out
+type MandateRepository struct {
+	next   loan.MandateRepository
+	cipher *Cipher
+}
+
+var _ loan.MandateRepository = (*MandateRepository)(nil)
+
+// NewMandateRepository wraps next
+func NewMandateRepository(next loan.MandateRepository, c *Cipher) *MandateRepository {
+	return &MandateRepository{next: next, cipher: c}
+}
+
+func (r *MandateRepository) open(ctx context.Context, m *loan.Mandate) error {
+	plain, err := r.cipher.Decrypt(ctx, m.Account, aad("mandate", m.ID, "account"))
+	if err != nil {
+		return err
+	}
+	m.Account = plain
+	return nil
+}
+
+// SaveMandate stores m with its account encrypted
+func (r *MandateRepository) SaveMandate(ctx context.Context, m *loan.Mandate) error {
+	enc, err := r.cipher.Encrypt(ctx, m.Account, aad("mandate", m.ID, "account"))
+	if err != nil {
+		return err
+	}
+	sealed := m.Clone()
+	sealed.Account = enc
+	return r.next.SaveMandate(ctx, sealed)
+}
+
+// FindMandate returns the loan's mandate with its account decrypted
+func (r *MandateRepository) FindMandate(ctx context.Context, loanID string) (*loan.Mandate, error) {
+	m, err := r.next.FindMandate(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.open(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ActiveMandates returns the active mandates with their accounts decrypted
+func (r *MandateRepository) ActiveMandates(ctx context.Context) ([]*loan.Mandate, error) {
+	ms, err := r.next.ActiveMandates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range ms {
+		if err := r.open(ctx, m); err != nil {
+			return nil, err
+		}
+	}
+	return ms, nil
+}
+
+// Rotate re-encrypts the loan's stored mandate under the current key when
+// its account is plaintext or sealed under an older key, and reports
+// whether it did
+func (r *MandateRepository) Rotate(ctx context.Context, loanID string) (bool, error) {
+	stored, err := r.next.FindMandate(ctx, loanID)
+	if err != nil {
+		return false, err
+	}
+	stale, err := r.cipher.Stale(ctx, stored.Account)
+	if err != nil || !stale {
+		return false, err
+	}
+	if err := r.open(ctx, stored); err != nil {
+		return false, err
+	}
+	return true, r.SaveMandate(ctx, stored)
+}
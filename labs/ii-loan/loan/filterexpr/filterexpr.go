@@ -0,0 +1,285 @@
+// Package filterexpr parses the filter expressions of loan listings into
+// loan.Conditions:
+//
+//	status=approved AND amount>5000 AND createdAt>=2024-01-01
+//	status in (approved, default) AND product != "PL" AND termMonths <= 24
+//
+// An expression is comparisons joined by AND, which, like IN, is matched
+// in any case. Fields are those of loan.Condition; values are bare words
+// or double-quoted strings. Amounts are numbers, terms whole months and
+// creation times dates (midnight UTC) or RFC 3339 times; statuses must be
+// known ones. Errors point at where the expression went wrong.
+package filterexpr
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"loan"
+)
+
+// SyntaxError is a problem in an expression, at the 1-based column Col
+type SyntaxError struct {
+	Col int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("filter: column %d: %s", e.Col, e.Msg)
+}
+
+// statuses are the values status may be compared with
+var statuses = []string{loan.StatusPending, loan.StatusApproved, loan.StatusRejected, loan.StatusDefault}
+
+// fields are the fields expressions may name
+var fields = []string{loan.FieldStatus, loan.FieldCustomerID, loan.FieldProduct, loan.FieldPurpose, loan.FieldAmount, loan.FieldTermMonths, loan.FieldCreatedAt}
+
+// Parse returns the conditions of expr, nil for a blank expression
+func Parse(expr string) ([]loan.Condition, error) {
+	toks, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return nil, nil
+	}
+	var conds []loan.Condition
+	for {
+		c, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, c)
+		t := p.next()
+		switch {
+		case t.kind == tokEOF:
+			return conds, nil
+		case t.kind == tokWord && strings.EqualFold(t.text, "and"):
+		case t.kind == tokWord && strings.EqualFold(t.text, "or"):
+			return nil, &SyntaxError{t.col, "OR is not supported; list alternatives with in (...)"}
+		default:
+			return nil, &SyntaxError{t.col, fmt.Sprintf("expected AND or the end of the filter, found %s", t)}
+		}
+	}
+}
+
+// Format writes conds as an expression Parse reads back
+func Format(conds []loan.Condition) string {
+	parts := make([]string, len(conds))
+	for i, c := range conds {
+		parts[i] = c.Field + " " + string(c.Op) + " " + formatValue(c.Value)
+	}
+	return strings.Join(parts, " AND ")
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []string:
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = strconv.Quote(s)
+		}
+		return "(" + strings.Join(quoted, ", ") + ")"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// condition parses field op value
+func (p *parser) condition() (loan.Condition, error) {
+	f := p.next()
+	if f.kind != tokWord {
+		return loan.Condition{}, &SyntaxError{f.col, fmt.Sprintf("expected a field, found %s", f)}
+	}
+	field, err := resolveField(f)
+	if err != nil {
+		return loan.Condition{}, err
+	}
+	o := p.next()
+	var op loan.Op
+	switch {
+	case o.kind == tokOp:
+		op = loan.Op(o.text)
+	case o.kind == tokWord && strings.EqualFold(o.text, "in"):
+		op = loan.OpIn
+	default:
+		return loan.Condition{}, &SyntaxError{o.col, fmt.Sprintf("expected a comparison after %s (=, !=, <, <=, >, >= or in), found %s", field, o)}
+	}
+	if op == loan.OpIn {
+		values, err := p.list()
+		if err != nil {
+			return loan.Condition{}, err
+		}
+		texts := make([]string, len(values))
+		for i, v := range values {
+			value, err := convert(field, v)
+			if err != nil {
+				return loan.Condition{}, err
+			}
+			s, ok := value.(string)
+			if !ok {
+				return loan.Condition{}, &SyntaxError{o.col, fmt.Sprintf("%s cannot be compared with in", field)}
+			}
+			texts[i] = s
+		}
+		return check(loan.Condition{Field: field, Op: op, Value: texts}, f)
+	}
+	v := p.next()
+	if v.kind != tokWord && v.kind != tokString {
+		return loan.Condition{}, &SyntaxError{v.col, fmt.Sprintf("expected a value after %s %s, found %s", field, op, v)}
+	}
+	value, err := convert(field, v)
+	if err != nil {
+		return loan.Condition{}, err
+	}
+	if field == loan.FieldCreatedAt && (op == loan.OpEq || op == loan.OpNe) {
+		return loan.Condition{}, &SyntaxError{o.col, "createdAt is a time; select a day with createdAt >= D AND createdAt < D+1"}
+	}
+	return check(loan.Condition{Field: field, Op: op, Value: value}, f)
+}
+
+// list parses ( value, ... )
+func (p *parser) list() ([]token, error) {
+	if t := p.next(); t.kind != tokLParen {
+		return nil, &SyntaxError{t.col, fmt.Sprintf("expected ( after in, found %s", t)}
+	}
+	var values []token
+	for {
+		v := p.next()
+		if v.kind != tokWord && v.kind != tokString {
+			return nil, &SyntaxError{v.col, fmt.Sprintf("expected a value in the list, found %s", v)}
+		}
+		values = append(values, v)
+		switch t := p.next(); t.kind {
+		case tokComma:
+		case tokRParen:
+			return values, nil
+		default:
+			return nil, &SyntaxError{t.col, fmt.Sprintf("expected , or ) in the list, found %s", t)}
+		}
+	}
+}
+
+// check validates c, reporting problems at the field
+func check(c loan.Condition, at token) (loan.Condition, error) {
+	if err := c.Validate(); err != nil {
+		return loan.Condition{}, &SyntaxError{at.col, err.Error()}
+	}
+	return c, nil
+}
+
+// resolveField matches a field name in any case, suggesting the closest
+// field for a misspelt one
+func resolveField(t token) (string, error) {
+	for _, f := range fields {
+		if strings.EqualFold(f, t.text) {
+			return f, nil
+		}
+	}
+	msg := fmt.Sprintf("unknown field %q", t.text)
+	if s := closest(t.text, fields); s != "" {
+		msg += fmt.Sprintf("; did you mean %s?", s)
+	} else {
+		msg += "; fields are " + strings.Join(fields, ", ")
+	}
+	return "", &SyntaxError{t.col, msg}
+}
+
+// convert parses the value of a comparison with field
+func convert(field string, t token) (any, error) {
+	bad := func(format string, args ...any) error {
+		return &SyntaxError{t.col, fmt.Sprintf(format, args...)}
+	}
+	switch field {
+	case loan.FieldStatus:
+		s := strings.ToLower(t.text)
+		if !slices.Contains(statuses, s) {
+			return nil, bad("unknown status %q; statuses are %s", t.text, strings.Join(statuses, ", "))
+		}
+		return s, nil
+	case loan.FieldPurpose:
+		s := strings.ToLower(t.text)
+		if !loan.ValidPurpose(s) {
+			return nil, bad("unknown purpose %q; purposes are %s", t.text, strings.Join(loan.Purposes, ", "))
+		}
+		return s, nil
+	case loan.FieldAmount:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, bad("amount %q is not a number", t.text)
+		}
+		return f, nil
+	case loan.FieldTermMonths:
+		n, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, bad("termMonths %q is not a whole number of months", t.text)
+		}
+		return n, nil
+	case loan.FieldCreatedAt:
+		if d, err := time.Parse(time.DateOnly, t.text); err == nil {
+			return d, nil
+		}
+		ts, err := time.Parse(time.RFC3339Nano, t.text)
+		if err != nil {
+			return nil, bad("createdAt %q is not a date (YYYY-MM-DD) or an RFC 3339 time", t.text)
+		}
+		return ts.UTC(), nil
+	}
+	return t.text, nil
+}
+
+// closest returns the candidate within two edits of s, if any
+func closest(s string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := distance(strings.ToLower(s), strings.ToLower(c)); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
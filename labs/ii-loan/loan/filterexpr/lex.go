@@ -0,0 +1,102 @@
+package filterexpr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// col is the 1-based column the token starts at
+	col int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "the end of the filter"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// isWord reports whether r may appear in a bare word: names, numbers,
+// dates and times
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-:+", r)
+}
+
+// lex splits expr into tokens ending with tokEOF
+func lex(expr string) ([]token, error) {
+	var toks []token
+	rs := []rune(expr)
+	for i := 0; i < len(rs); {
+		r, col := rs[i], i+1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokLParen, "(", col})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokRParen, ")", col})
+			i++
+		case r == ',':
+			toks = append(toks, token{tokComma, ",", col})
+			i++
+		case strings.ContainsRune("=!<>", r):
+			op := string(r)
+			if i+1 < len(rs) && rs[i+1] == '=' {
+				op += "="
+			}
+			i += len(op)
+			switch op {
+			case "!":
+				return nil, &SyntaxError{col, `expected "!="`}
+			case "==":
+				op = "="
+			}
+			toks = append(toks, token{tokOp, op, col})
+		case r == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(rs) && rs[j] != '"'; j++ {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+				}
+				b.WriteRune(rs[j])
+			}
+			if j == len(rs) {
+				return nil, &SyntaxError{col, "unterminated string"}
+			}
+			toks = append(toks, token{tokString, b.String(), col})
+			i = j + 1
+		case isWord(r):
+			j := i
+			for j < len(rs) && isWord(rs[j]) {
+				j++
+			}
+			toks = append(toks, token{tokWord, string(rs[i:j]), col})
+			i = j
+		default:
+			return nil, &SyntaxError{col, fmt.Sprintf("unexpected %q", r)}
+		}
+	}
+	return append(toks, token{kind: tokEOF, col: len(rs) + 1}), nil
+}
@@ -0,0 +1,247 @@
+// Package fixtures generates realistic, reproducible demo data: customers,
+// loan products and loans spread over the lifecycle states, with repayment
+// histories consistent with their schedules. The same seed always yields
+// the same dataset.
+package fixtures
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"loan"
+)
+
+// Customer is a generated borrower
+type Customer struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Email         string  `json:"email"`
+	Phone         string  `json:"phone"`
+	MonthlyIncome float64 `json:"monthlyIncome"`
+}
+
+// Product is a loan product loans are drawn from
+type Product struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	MinAmount float64 `json:"minAmount"`
+	MaxAmount float64 `json:"maxAmount"`
+	Rate      float64 `json:"rate"`
+	Terms     []int   `json:"terms"`
+}
+
+// Products is the demo product range
+var Products = []Product{
+	{Code: "PL", Name: "Personal loan", MinAmount: 5000, MaxAmount: 300000, Rate: 0.18, Terms: []int{12, 24, 36, 48}},
+	{Code: "AUTO", Name: "Auto loan", MinAmount: 100000, MaxAmount: 1500000, Rate: 0.065, Terms: []int{36, 48, 60, 72}},
+	{Code: "HOME", Name: "Home improvement", MinAmount: 50000, MaxAmount: 800000, Rate: 0.09, Terms: []int{24, 36, 60}},
+	{Code: "MICRO", Name: "Micro business", MinAmount: 10000, MaxAmount: 200000, Rate: 0.21, Terms: []int{6, 12, 18}},
+	{Code: "EDU", Name: "Education", MinAmount: 20000, MaxAmount: 400000, Rate: 0.05, Terms: []int{24, 48, 60}},
+}
+
+// State is the lifecycle position of a generated loan
+type State string
+
+// Generated loan states
+const (
+	Pending    State = "pending"
+	Rejected   State = "rejected"
+	Active     State = "active"
+	Delinquent State = "delinquent"
+	Defaulted  State = "defaulted"
+)
+
+// Mix weighs how often each state is generated
+type Mix map[State]int
+
+// DefaultMix resembles a healthy consumer book
+var DefaultMix = Mix{Pending: 10, Rejected: 8, Active: 65, Delinquent: 12, Defaulted: 5}
+
+// Dataset is one generated book
+type Dataset struct {
+	Customers []Customer   `json:"customers"`
+	Products  []Product    `json:"products"`
+	Loans     []*loan.Loan `json:"loans"`
+}
+
+// Generator produces fixtures from a seeded random source. It is not safe
+// for concurrent use.
+type Generator struct {
+	rng *rand.Rand
+	now time.Time
+	mix Mix
+}
+
+// Option configures a Generator
+type Option func(*Generator)
+
+// WithSeed sets the random seed (default 1)
+func WithSeed(seed uint64) Option {
+	return func(g *Generator) { g.rng = rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)) }
+}
+
+// WithNow sets the date histories are generated up to (default today)
+func WithNow(t time.Time) Option {
+	return func(g *Generator) { g.now = t.UTC() }
+}
+
+// WithMix sets the state weights (default DefaultMix)
+func WithMix(m Mix) Option {
+	return func(g *Generator) { g.mix = m }
+}
+
+// New creates a generator
+func New(opts ...Option) *Generator {
+	g := &Generator{now: time.Now().UTC(), mix: DefaultMix}
+	WithSeed(1)(g)
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+var (
+	firstNames = []string{"Somchai", "Suda", "Anan", "Malee", "Niran", "Pimchanok", "Kittisak", "Ratana", "Wichai", "Siriporn", "Thanawat", "Nattaya", "Prasert", "Kanya", "Chaiwat", "Orawan"}
+	lastNames  = []string{"Srisuk", "Wongsawat", "Chaiyaporn", "Boonmee", "Rattanakul", "Saetang", "Phromma", "Kongkaew", "Thongdee", "Jaidee", "Sukprasert", "Inthasorn"}
+)
+
+// Customers generates n customers
+func (g *Generator) Customers(n int) []Customer {
+	out := make([]Customer, n)
+	for i := range out {
+		first, last := pick(g.rng, firstNames), pick(g.rng, lastNames)
+		out[i] = Customer{
+			ID:            fmt.Sprintf("CUST-%06d", g.rng.IntN(1_000_000)),
+			Name:          first + " " + last,
+			Email:         strings.ToLower(first+"."+last) + fmt.Sprintf("%d@example.com", i),
+			Phone:         fmt.Sprintf("+668%d%07d", 1+g.rng.IntN(9), g.rng.IntN(10_000_000)),
+			MonthlyIncome: math.Round(15000 + g.rng.ExpFloat64()*35000),
+		}
+	}
+	return out
+}
+
+// Generate creates a dataset of the given number of customers and loans.
+// Loans are assigned to random customers and products with states drawn
+// from the generator's mix.
+func (g *Generator) Generate(customers, loans int) Dataset {
+	ds := Dataset{Customers: g.Customers(max(customers, 1)), Products: Products}
+	for range loans {
+		ds.Loans = append(ds.Loans, g.Loan(pick(g.rng, ds.Customers), pick(g.rng, Products), g.state()))
+	}
+	return ds
+}
+
+func (g *Generator) state() State {
+	total := 0
+	for _, w := range g.mix {
+		total += w
+	}
+	if total <= 0 {
+		return Active
+	}
+	n := g.rng.IntN(total)
+	// iterate in a fixed order so the seed alone decides the outcome
+	for _, s := range []State{Pending, Rejected, Active, Delinquent, Defaulted} {
+		if n -= g.mix[s]; n < 0 {
+			return s
+		}
+	}
+	return Active
+}
+
+var rejectionReasons = []string{"insufficient income", "high existing debt", "incomplete documents", "credit score below threshold"}
+
+// Loan generates one loan of c for product p in state s. Approved loans
+// have a schedule starting in the past and payments for the installments
+// the state says were met; delinquent and defaulted loans stop paying.
+func (g *Generator) Loan(c Customer, p Product, s State) *loan.Loan {
+	term := pick(g.rng, p.Terms)
+	amount := math.Round((p.MinAmount+g.rng.Float64()*(p.MaxAmount-p.MinAmount))/1000) * 1000
+	l := &loan.Loan{
+		ID:           g.id(),
+		CustomerID:   c.ID,
+		Amount:       amount,
+		InterestRate: p.Rate,
+		TermMonths:   term,
+		Status:       loan.StatusPending,
+		CreditScore:  min(max(int(680+g.rng.NormFloat64()*70), 300), 850),
+		Product:      p.Code,
+	}
+
+	switch s {
+	case Pending:
+		l.CreatedAt = g.now.Add(-time.Duration(g.rng.IntN(14*24)) * time.Hour)
+		return l
+	case Rejected:
+		l.CreatedAt = g.daysAgo(10, 400)
+		l.CreditScore = 300 + g.rng.IntN(250)
+		_ = l.Reject(pick(g.rng, rejectionReasons))
+		return l
+	}
+
+	// months of installments already due, and how many of the latest
+	// ones were missed
+	elapsed, missed := 1+g.rng.IntN(term), 0
+	switch s {
+	case Delinquent:
+		missed = 1 + g.rng.IntN(3)
+	case Defaulted:
+		missed = 4 + g.rng.IntN(4)
+	}
+	elapsed = max(elapsed, missed)
+	if elapsed >= term && s == Active {
+		elapsed = term - 1
+	}
+	// a few days into the current period so the next installment is not due today
+	approvedAt := g.now.AddDate(0, -elapsed, -1-g.rng.IntN(20))
+	l.CreatedAt = approvedAt.Add(-time.Duration(1+g.rng.IntN(72)) * time.Hour)
+	_ = l.Approve()
+	l.ApprovedAt = approvedAt
+	l.Schedule = loan.BuildSchedule(l.Amount, l.AnnualRate(), term, approvedAt)
+
+	for i, inst := range l.Schedule {
+		if inst.DueDate.After(g.now) {
+			break
+		}
+		// interest accrues over the period whether or not it is paid
+		l.AccruedInterest = round2(l.AccruedInterest + inst.Interest)
+		l.Balance = round2(l.Balance + inst.Interest)
+		l.AccruedThrough = inst.DueDate
+		if i >= elapsed-missed {
+			continue
+		}
+		_, _ = l.ApplyPayment(math.Min(inst.Amount, l.Balance), inst.DueDate.Add(-time.Duration(g.rng.IntN(72))*time.Hour))
+	}
+
+	for i := range l.Payments {
+		l.Payments[i].ID = g.id()
+	}
+
+	l.DaysPastDue = l.CalculateDaysPastDue(g.now)
+	l.Delinquency = loan.BucketFor(l.DaysPastDue)
+	if s == Defaulted {
+		l.Status = loan.StatusDefault
+	}
+	return l
+}
+
+func (g *Generator) daysAgo(lo, hi int) time.Time {
+	return g.now.AddDate(0, 0, -(lo + g.rng.IntN(hi-lo+1)))
+}
+
+// id draws an ID shaped like loan.NewID from the seeded source
+func (g *Generator) id() string {
+	return fmt.Sprintf("%016x%016x", g.rng.Uint64(), g.rng.Uint64())
+}
+
+func pick[T any](rng *rand.Rand, s []T) T {
+	return s[rng.IntN(len(s))]
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
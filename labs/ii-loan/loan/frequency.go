@@ -0,0 +1,92 @@
+package loan
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// Repayment frequencies: how often installments fall due. Weekly and
+// biweekly loans line repayments up with payroll.
+const (
+	FrequencyMonthly  = "monthly"
+	FrequencyBiweekly = "biweekly"
+	FrequencyWeekly   = "weekly"
+)
+
+// Frequencies lists every repayment frequency, the default first
+var Frequencies = []string{FrequencyMonthly, FrequencyBiweekly, FrequencyWeekly}
+
+// ValidFrequency reports whether f is a known repayment frequency
+func ValidFrequency(f string) bool {
+	return slices.Contains(Frequencies, f)
+}
+
+// PeriodsPerYear is how many installments fall due in a year at frequency
+// f, 12 for monthly or unknown frequencies
+func PeriodsPerYear(f string) int {
+	switch f {
+	case FrequencyWeekly:
+		return 52
+	case FrequencyBiweekly:
+		return 26
+	}
+	return 12
+}
+
+// InstallmentsIn is how many installments at frequency f repay a loan
+// over termMonths, rounded up so the term is never cut short
+func InstallmentsIn(f string, termMonths int) int {
+	if f == FrequencyMonthly || f == "" {
+		return termMonths
+	}
+	return int(math.Ceil(float64(termMonths*PeriodsPerYear(f)) / 12))
+}
+
+// monthsOf is the term in whole months, rounded up, of n installments at
+// frequency f
+func monthsOf(f string, n int) int {
+	return int(math.Ceil(float64(n*12) / float64(PeriodsPerYear(f))))
+}
+
+// dueAfter is the date n periods of frequency f after start. Weekly and
+// biweekly dates keep the weekday of start; monthly ones its day of month,
+// or the month's last day when it is shorter, so a loan started on the
+// 31st falls due on the 28th or 29th in February and never in March.
+func dueAfter(start time.Time, f string, n int) time.Time {
+	switch f {
+	case FrequencyWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case FrequencyBiweekly:
+		return start.AddDate(0, 0, 14*n)
+	}
+	target := time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, start.Location())
+	day := min(start.Day(), daysIn(target.Year(), target.Month()))
+	h, m, sec := start.Clock()
+	return time.Date(target.Year(), target.Month(), day, h, m, sec, start.Nanosecond(), start.Location())
+}
+
+// daysIn is the number of days in month of year
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// RepaymentFrequency is the loan's frequency, FrequencyMonthly when none
+// is set
+func (l *Loan) RepaymentFrequency() string {
+	if l.Frequency == "" {
+		return FrequencyMonthly
+	}
+	return l.Frequency
+}
+
+// Installments is how many installments repay the loan over its term
+func (l *Loan) Installments() int {
+	return InstallmentsIn(l.Frequency, l.TermMonths)
+}
+
+// RemainingMonths is the time left to repay in whole months, rounded up,
+// whatever the repayment frequency
+func (l *Loan) RemainingMonths() int {
+	return monthsOf(l.Frequency, l.RemainingInstallments())
+}
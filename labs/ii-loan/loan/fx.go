@@ -0,0 +1,43 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultCurrency is the ISO 4217 code of loans booked without a currency
+// and the default reporting currency
+const DefaultCurrency = "THB"
+
+// ErrNoFXRate is returned when no rate is known between two currencies
+var ErrNoFXRate = errors.New("no exchange rate")
+
+// FXRate converts amounts in From into To: to = from * Rate
+type FXRate struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Rate   float64   `json:"rate"`
+	AsOf   time.Time `json:"asOf"`
+	Source string    `json:"source,omitempty"`
+}
+
+// Convert returns amount expressed in r.To
+func (r FXRate) Convert(amount float64) float64 {
+	return round2(amount * r.Rate)
+}
+
+// FXRateProvider quotes exchange rates. Implementations call external
+// services and must honour ctx cancellation; unknown pairs are reported
+// with an error wrapping ErrNoFXRate.
+type FXRateProvider interface {
+	Rate(ctx context.Context, from, to string) (FXRate, error)
+}
+
+// CurrencyCode returns the loan's currency, DefaultCurrency when unset
+func (l *Loan) CurrencyCode() string {
+	if l.Currency == "" {
+		return DefaultCurrency
+	}
+	return l.Currency
+}
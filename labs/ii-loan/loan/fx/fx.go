@@ -0,0 +1,126 @@
+// Package fx provides loan.FXRateProvider implementations: a cache that
+// keeps quotes for a while so reports do not hit the rate source per loan,
+// and a static rate table for labs and tests.
+package fx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"loan"
+)
+
+// DefaultTTL is how long a cached rate is reused
+const DefaultTTL = time.Hour
+
+// Cache reuses rates of its provider per currency pair
+type Cache struct {
+	next loan.FXRateProvider
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu    sync.Mutex
+	rates map[string]cached
+}
+
+type cached struct {
+	rate    loan.FXRate
+	expires time.Time
+}
+
+// Option configures a Cache
+type Option func(*Cache)
+
+// WithTTL sets how long rates are reused instead of DefaultTTL
+func WithTTL(d time.Duration) Option {
+	return func(c *Cache) { c.ttl = d }
+}
+
+// NewCache wraps next with a rate cache
+func NewCache(next loan.FXRateProvider, opts ...Option) *Cache {
+	c := &Cache{next: next, ttl: DefaultTTL, now: time.Now, rates: make(map[string]cached)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Rate implements loan.FXRateProvider
+func (c *Cache) Rate(ctx context.Context, from, to string) (loan.FXRate, error) {
+	key := from + "/" + to
+	now := c.now()
+	c.mu.Lock()
+	hit, ok := c.rates[key]
+	c.mu.Unlock()
+	if ok && now.Before(hit.expires) {
+		return hit.rate, nil
+	}
+	r, err := c.next.Rate(ctx, from, to)
+	if err != nil {
+		return loan.FXRate{}, err
+	}
+	c.mu.Lock()
+	c.rates[key] = cached{rate: r, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return r, nil
+}
+
+// Static quotes fixed rates. Inverse pairs are derived, so a table of
+// rates into one currency converts both ways.
+type Static struct {
+	rates map[string]float64
+	asOf  time.Time
+}
+
+// NewStatic creates a table from rates keyed "FROM/TO", such as
+// {"USD/THB": 36.5}
+func NewStatic(asOf time.Time, rates map[string]float64) *Static {
+	return &Static{rates: rates, asOf: asOf.UTC()}
+}
+
+// ParseRates reads a table written as "USD/THB=36.5,EUR/THB=39.8"
+func ParseRates(s string) (map[string]float64, error) {
+	rates := map[string]float64{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		var from, to string
+		var rate float64
+		pair, value, ok := strings.Cut(part, "=")
+		if ok {
+			from, to, ok = strings.Cut(pair, "/")
+		}
+		if ok {
+			_, err := fmt.Sscanf(value, "%g", &rate)
+			ok = err == nil && rate > 0
+		}
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("fx: malformed rate %q, want FROM/TO=rate", part)
+		}
+		rates[strings.ToUpper(from)+"/"+strings.ToUpper(to)] = rate
+	}
+	return rates, nil
+}
+
+// Rate implements loan.FXRateProvider
+func (s *Static) Rate(ctx context.Context, from, to string) (loan.FXRate, error) {
+	if err := ctx.Err(); err != nil {
+		return loan.FXRate{}, err
+	}
+	r := loan.FXRate{From: from, To: to, AsOf: s.asOf, Source: "static"}
+	switch {
+	case from == to:
+		r.Rate = 1
+	case s.rates[from+"/"+to] > 0:
+		r.Rate = s.rates[from+"/"+to]
+	case s.rates[to+"/"+from] > 0:
+		r.Rate = 1 / s.rates[to+"/"+from]
+	default:
+		return loan.FXRate{}, fmt.Errorf("%w from %s to %s", loan.ErrNoFXRate, from, to)
+	}
+	return r, nil
+}
@@ -0,0 +1,71 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPaymentDeclined is returned by payment providers that refuse a
+// transaction; wrap it with the provider's reason
+var ErrPaymentDeclined = errors.New("payment declined")
+
+// ErrNoPaymentProvider is returned by flows that move money when the
+// service has no PaymentProvider
+var ErrNoPaymentProvider = errors.New("no payment provider configured")
+
+// Direction says which way money moves in a transaction
+type Direction string
+
+// Transaction directions
+const (
+	// Disbursement pays the loan amount out to the customer
+	Disbursement Direction = "disbursement"
+	// Collection takes a repayment from the customer
+	Collection Direction = "collection"
+)
+
+// TransactionStatus is the state of a gateway transaction
+type TransactionStatus string
+
+// Transaction states. An authorized transaction holds the funds until it
+// is captured; refunds are only possible once captured.
+const (
+	TransactionAuthorized TransactionStatus = "authorized"
+	TransactionCaptured   TransactionStatus = "captured"
+	TransactionRefunded   TransactionStatus = "refunded"
+	TransactionDeclined   TransactionStatus = "declined"
+)
+
+// PaymentOrder asks a provider to move money for a loan
+type PaymentOrder struct {
+	// Reference ties the transaction to the loan, normally its ID
+	Reference string
+	Direction Direction
+	Amount    float64
+	// Account is the customer's bank account, when the provider needs it
+	Account string
+}
+
+// Transaction is a provider's record of a payment order
+type Transaction struct {
+	ID        string            `json:"id"`
+	Reference string            `json:"reference"`
+	Direction Direction         `json:"direction"`
+	Amount    float64           `json:"amount"`
+	Refunded  float64           `json:"refunded,omitempty"`
+	Status    TransactionStatus `json:"status"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// PaymentProvider moves money through a payment gateway. Implementations
+// call external services and must honour ctx cancellation; declined
+// orders are reported with an error wrapping ErrPaymentDeclined.
+// Calls are not retried by the service since they are not idempotent.
+type PaymentProvider interface {
+	Authorize(ctx context.Context, order PaymentOrder) (Transaction, error)
+	Capture(ctx context.Context, id string) (Transaction, error)
+	// Refund returns amount of a captured transaction
+	Refund(ctx context.Context, id string, amount float64) (Transaction, error)
+	Status(ctx context.Context, id string) (Transaction, error)
+}
@@ -0,0 +1,133 @@
+// Package gateway holds loan.PaymentProvider implementations. Sandbox
+// settles everything in memory for development and labs; real gateways
+// are added next to it and swapped in with loan.WithPaymentProvider.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"loan"
+)
+
+// ErrTransactionNotFound is returned for unknown transaction IDs
+var ErrTransactionNotFound = errors.New("gateway: transaction not found")
+
+// ErrInvalidState is returned when an operation does not fit the
+// transaction's status, such as capturing twice
+var ErrInvalidState = errors.New("gateway: invalid transaction state")
+
+// Sandbox is an in-memory payment provider. Orders above its limit are
+// declined so failure paths can be exercised.
+type Sandbox struct {
+	mu    sync.Mutex
+	txs   map[string]loan.Transaction
+	limit float64
+	now   func() time.Time
+}
+
+// SandboxOption configures a Sandbox
+type SandboxOption func(*Sandbox)
+
+// WithLimit declines orders above amount
+func WithLimit(amount float64) SandboxOption {
+	return func(s *Sandbox) { s.limit = amount }
+}
+
+// NewSandbox creates a sandbox that accepts every order
+func NewSandbox(opts ...SandboxOption) *Sandbox {
+	s := &Sandbox{txs: make(map[string]loan.Transaction), limit: math.Inf(1), now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Authorize implements loan.PaymentProvider
+func (s *Sandbox) Authorize(ctx context.Context, order loan.PaymentOrder) (loan.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return loan.Transaction{}, err
+	}
+	if order.Amount <= 0 {
+		return loan.Transaction{}, fmt.Errorf("gateway: amount must be positive, got %.2f", order.Amount)
+	}
+	tx := loan.Transaction{
+		ID:        "sbx_" + loan.NewID(),
+		Reference: order.Reference,
+		Direction: order.Direction,
+		Amount:    order.Amount,
+		Status:    loan.TransactionAuthorized,
+		UpdatedAt: s.now().UTC(),
+	}
+	declined := order.Amount > s.limit
+	if declined {
+		tx.Status = loan.TransactionDeclined
+	}
+	s.mu.Lock()
+	s.txs[tx.ID] = tx
+	s.mu.Unlock()
+	if declined {
+		return tx, fmt.Errorf("%w: amount %.2f exceeds sandbox limit %.2f", loan.ErrPaymentDeclined, order.Amount, s.limit)
+	}
+	return tx, nil
+}
+
+// Capture implements loan.PaymentProvider
+func (s *Sandbox) Capture(ctx context.Context, id string) (loan.Transaction, error) {
+	return s.update(ctx, id, func(tx *loan.Transaction) error {
+		if tx.Status != loan.TransactionAuthorized {
+			return fmt.Errorf("%w: cannot capture %s transaction", ErrInvalidState, tx.Status)
+		}
+		tx.Status = loan.TransactionCaptured
+		return nil
+	})
+}
+
+// Refund implements loan.PaymentProvider. Partial refunds keep the
+// transaction captured until the whole amount has been returned.
+func (s *Sandbox) Refund(ctx context.Context, id string, amount float64) (loan.Transaction, error) {
+	return s.update(ctx, id, func(tx *loan.Transaction) error {
+		if tx.Status != loan.TransactionCaptured {
+			return fmt.Errorf("%w: cannot refund %s transaction", ErrInvalidState, tx.Status)
+		}
+		left := math.Round((tx.Amount-tx.Refunded)*100) / 100
+		if amount <= 0 || amount > left {
+			return fmt.Errorf("gateway: refund %.2f must be positive and at most %.2f", amount, left)
+		}
+		tx.Refunded = math.Round((tx.Refunded+amount)*100) / 100
+		if tx.Refunded >= tx.Amount {
+			tx.Status = loan.TransactionRefunded
+		}
+		return nil
+	})
+}
+
+// Status implements loan.PaymentProvider
+func (s *Sandbox) Status(ctx context.Context, id string) (loan.Transaction, error) {
+	return s.update(ctx, id, func(*loan.Transaction) error { return nil })
+}
+
+func (s *Sandbox) update(ctx context.Context, id string, fn func(*loan.Transaction) error) (loan.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return loan.Transaction{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, ok := s.txs[id]
+	if !ok {
+		return loan.Transaction{}, fmt.Errorf("%w: %s", ErrTransactionNotFound, id)
+	}
+	before := tx.Status
+	if err := fn(&tx); err != nil {
+		return loan.Transaction{}, err
+	}
+	if tx.Status != before || tx.Refunded != s.txs[id].Refunded {
+		tx.UpdatedAt = s.now().UTC()
+		s.txs[id] = tx
+	}
+	return tx, nil
+}
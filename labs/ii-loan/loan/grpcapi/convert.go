@@ -0,0 +1,76 @@
+package grpcapi
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"loan"
+	loanv1 "loan/gen/loan/v1"
+)
+
+var statusToProto = map[string]loanv1.LoanStatus{
+	loan.StatusPending:  loanv1.LoanStatus_LOAN_STATUS_PENDING,
+	loan.StatusApproved: loanv1.LoanStatus_LOAN_STATUS_APPROVED,
+	loan.StatusRejected: loanv1.LoanStatus_LOAN_STATUS_REJECTED,
+	loan.StatusDefault:  loanv1.LoanStatus_LOAN_STATUS_DEFAULT,
+}
+
+var statusFromProto = map[loanv1.LoanStatus]string{
+	loanv1.LoanStatus_LOAN_STATUS_PENDING:  loan.StatusPending,
+	loanv1.LoanStatus_LOAN_STATUS_APPROVED: loan.StatusApproved,
+	loanv1.LoanStatus_LOAN_STATUS_REJECTED: loan.StatusRejected,
+	loanv1.LoanStatus_LOAN_STATUS_DEFAULT:  loan.StatusDefault,
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func loanToProto(l *loan.Loan) *loanv1.Loan {
+	return &loanv1.Loan{
+		Id:              l.ID,
+		CustomerId:      l.CustomerID,
+		Amount:          l.Amount,
+		Status:          statusToProto[l.Status],
+		InterestRate:    l.InterestRate,
+		TermMonths:      int32(l.TermMonths),
+		CreatedAt:       timestamp(l.CreatedAt),
+		ApprovedAt:      timestamp(l.ApprovedAt),
+		Balance:         l.Balance,
+		AccruedInterest: l.AccruedInterest,
+		DaysPastDue:     int32(l.DaysPastDue),
+		Delinquency:     string(l.Delinquency),
+		RejectionReason: l.RejectionReason,
+		Schedule:        installmentsToProto(l.Schedule),
+	}
+}
+
+func installmentsToProto(schedule []loan.Installment) []*loanv1.Installment {
+	out := make([]*loanv1.Installment, 0, len(schedule))
+	for _, inst := range schedule {
+		out = append(out, &loanv1.Installment{
+			Number:    int32(inst.Number),
+			DueDate:   timestamp(inst.DueDate),
+			Principal: inst.Principal,
+			Interest:  inst.Interest,
+			Amount:    inst.Amount,
+			Paid:      inst.Paid,
+		})
+	}
+	return out
+}
+
+func paymentToProto(p loan.Payment) *loanv1.Payment {
+	return &loanv1.Payment{
+		Id:        p.ID,
+		LoanId:    p.LoanID,
+		Amount:    p.Amount,
+		Interest:  p.Interest,
+		Principal: p.Principal,
+		PaidAt:    timestamp(p.PaidAt),
+	}
+}
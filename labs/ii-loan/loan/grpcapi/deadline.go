@@ -0,0 +1,57 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	loanv1 "loan/gen/loan/v1"
+)
+
+// DefaultTimeout applies to calls that arrive without a deadline
+const DefaultTimeout = 10 * time.Second
+
+// DeadlineInterceptor keeps the caller's deadline when one was sent and
+// otherwise bounds the call with fallback, so no request can run forever.
+func DeadlineInterceptor(fallback time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := ctx.Deadline(); !ok && fallback > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, fallback)
+			defer cancel()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ClientDeadlineInterceptor gives outgoing calls a deadline when the
+// caller's context has none. Calls made with a context that already has a
+// deadline, such as one derived from an incoming request, forward it as is.
+func ClientDeadlineInterceptor(fallback time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok && fallback > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, fallback)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// Dial connects to a loan gRPC server without TLS, which is enough for the
+// labs, and returns the generated client with the tracing, logging and
+// deadline client interceptors installed. Close the returned connection
+// when done.
+func Dial(target string, opts ...grpc.DialOption) (loanv1.LoanServiceClient, *grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(ClientTracingInterceptor(), ClientLoggingInterceptor(), ClientDeadlineInterceptor(DefaultTimeout)),
+	}, opts...)
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return loanv1.NewLoanServiceClient(conn), conn, nil
+}
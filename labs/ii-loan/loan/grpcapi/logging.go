@@ -0,0 +1,67 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"loan/logging"
+)
+
+// Metadata keys used for correlation over gRPC
+const (
+	RequestIDMetadata = "x-request-id"
+	TenantMetadata    = "x-tenant-id"
+)
+
+func firstMetadata(md metadata.MD, key string) string {
+	if vs := md.Get(key); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// LoggingInterceptor is the gRPC counterpart of logging.Middleware: it
+// takes the request ID and tenant from the incoming metadata, generating an
+// ID when none is sent, and logs one line per call.
+func LoggingInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		id := firstMetadata(md, RequestIDMetadata)
+		if !logging.ValidID(id) {
+			id = logging.NewRequestID()
+		}
+		ctx = logging.WithRequestID(ctx, id)
+		if t := firstMetadata(md, TenantMetadata); logging.ValidID(t) {
+			ctx = logging.WithTenant(ctx, t)
+		}
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadata, id))
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logger.LogAttrs(ctx, slog.LevelInfo, "grpc request",
+			slog.String("method", info.FullMethod),
+			slog.String("code", status.Code(err).String()),
+			slog.Duration("duration", time.Since(start)),
+		)
+		return resp, err
+	}
+}
+
+// ClientLoggingInterceptor forwards the request ID and tenant of ctx to the
+// server being called
+func ClientLoggingInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if id := logging.RequestID(ctx); id != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, RequestIDMetadata, id)
+		}
+		if t := logging.Tenant(ctx); t != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, TenantMetadata, t)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,162 @@
+// Package grpcapi exposes the loan service over gRPC using the
+// definitions in proto/loan/v1.
+package grpcapi
+
+//go:generate protoc -I ../proto --go_out=../gen --go_opt=paths=source_relative --go-grpc_out=../gen --go-grpc_opt=paths=source_relative loan/v1/loan.proto
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"loan"
+	loanv1 "loan/gen/loan/v1"
+)
+
+// Server implements loanv1.LoanServiceServer on top of loan.LoanService.
+// Every call passes the incoming context straight to the service, so the
+// caller's deadline and cancellation reach the repository and integrations.
+type Server struct {
+	loanv1.UnimplementedLoanServiceServer
+	svc    *loan.LoanService
+	logger *slog.Logger
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithLogger sets the logger of the request logging interceptor
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) { s.logger = l }
+}
+
+// NewServer creates the gRPC adapter for svc
+func NewServer(svc *loan.LoanService, opts ...Option) *Server {
+	s := &Server{svc: svc, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register creates a grpc.Server with the default interceptors and registers s on it
+func (s *Server) Register(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{grpc.ChainUnaryInterceptor(
+		TracingInterceptor(),
+		LoggingInterceptor(s.logger),
+		DeadlineInterceptor(DefaultTimeout),
+	)}, opts...)
+	gs := grpc.NewServer(opts...)
+	loanv1.RegisterLoanServiceServer(gs, s)
+	return gs
+}
+
+// SubmitApplication implements loanv1.LoanServiceServer
+func (s *Server) SubmitApplication(ctx context.Context, req *loanv1.SubmitApplicationRequest) (*loanv1.Loan, error) {
+	app := req.GetApplication()
+	if app == nil {
+		return nil, status.Error(codes.InvalidArgument, "application is required")
+	}
+	l := &loan.Loan{
+		CustomerID:   strings.TrimSpace(app.GetCustomerId()),
+		Amount:       app.GetAmount(),
+		InterestRate: app.GetInterestRate(),
+		TermMonths:   int(app.GetTermMonths()),
+	}
+	if err := s.svc.ProcessLoanApplication(ctx, l); err != nil {
+		return nil, toStatus(err)
+	}
+	return loanToProto(l), nil
+}
+
+// GetLoan implements loanv1.LoanServiceServer
+func (s *Server) GetLoan(ctx context.Context, req *loanv1.GetLoanRequest) (*loanv1.Loan, error) {
+	l, err := s.svc.GetLoan(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return loanToProto(l), nil
+}
+
+// ListLoans implements loanv1.LoanServiceServer
+func (s *Server) ListLoans(ctx context.Context, req *loanv1.ListLoansRequest) (*loanv1.ListLoansResponse, error) {
+	filter := loan.Filter{CustomerID: req.GetCustomerId()}
+	for _, st := range req.GetStatuses() {
+		name, ok := statusFromProto[st]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unsupported status %s", st)
+		}
+		filter.Statuses = append(filter.Statuses, name)
+	}
+	loans, err := s.svc.ListLoans(ctx, filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &loanv1.ListLoansResponse{Loans: make([]*loanv1.Loan, 0, len(loans))}
+	for _, l := range loans {
+		resp.Loans = append(resp.Loans, loanToProto(l))
+	}
+	return resp, nil
+}
+
+// ApproveLoan implements loanv1.LoanServiceServer
+func (s *Server) ApproveLoan(ctx context.Context, req *loanv1.ApproveLoanRequest) (*loanv1.Loan, error) {
+	l, err := s.svc.ApproveLoan(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return loanToProto(l), nil
+}
+
+// RejectLoan implements loanv1.LoanServiceServer
+func (s *Server) RejectLoan(ctx context.Context, req *loanv1.RejectLoanRequest) (*loanv1.Loan, error) {
+	l, err := s.svc.RejectLoan(ctx, req.GetId(), req.GetReason())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return loanToProto(l), nil
+}
+
+// RecordPayment implements loanv1.LoanServiceServer
+func (s *Server) RecordPayment(ctx context.Context, req *loanv1.RecordPaymentRequest) (*loanv1.Payment, error) {
+	p, err := s.svc.RecordPayment(ctx, req.GetLoanId(), req.GetAmount())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return paymentToProto(p), nil
+}
+
+// GetSchedule implements loanv1.LoanServiceServer
+func (s *Server) GetSchedule(ctx context.Context, req *loanv1.GetScheduleRequest) (*loanv1.GetScheduleResponse, error) {
+	l, err := s.svc.GetLoan(ctx, req.GetLoanId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &loanv1.GetScheduleResponse{LoanId: l.ID, Installments: installmentsToProto(l.Schedule)}, nil
+}
+
+// toStatus maps service errors onto gRPC status codes
+func toStatus(err error) error {
+	var valErr *loan.ValidationError
+	switch {
+	case errors.As(err, &valErr):
+		return status.Error(codes.InvalidArgument, valErr.Error())
+	case errors.Is(err, loan.ErrLoanNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, loan.ErrInvalidTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, loan.ErrConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
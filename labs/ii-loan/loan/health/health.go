@@ -0,0 +1,164 @@
+// Package health serves liveness and readiness probes. Liveness only says
+// the process is up; readiness runs the registered dependency checks and
+// turns unready as soon as shutdown starts, so load balancers stop routing
+// new requests before the server stops accepting them.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout bounds all readiness checks of one probe
+const DefaultTimeout = 2 * time.Second
+
+// ErrShuttingDown is reported by readiness once draining started
+var ErrShuttingDown = errors.New("shutting down")
+
+// Checker verifies one dependency
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to Checker
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker
+func (f CheckerFunc) Check(ctx context.Context) error { return f(ctx) }
+
+// Pinger is implemented by stores and brokers that can test their
+// connection, such as *sql.DB
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks a Pinger
+func Ping(p Pinger) Checker {
+	return CheckerFunc(p.Ping)
+}
+
+// Health holds the readiness checks of a process
+type Health struct {
+	timeout  time.Duration
+	draining atomic.Bool
+
+	mu     sync.RWMutex
+	checks map[string]Checker
+}
+
+// Option configures Health
+type Option func(*Health)
+
+// WithTimeout bounds the checks of one readiness probe
+func WithTimeout(d time.Duration) Option {
+	return func(h *Health) { h.timeout = d }
+}
+
+// New creates a Health without checks
+func New(opts ...Option) *Health {
+	h := &Health{timeout: DefaultTimeout, checks: map[string]Checker{}}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Add registers a readiness check under name
+func (h *Health) Add(name string, c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = c
+}
+
+// Drain makes readiness fail from now on
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// CheckResult is the outcome of one check
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the body of both probes
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Ready runs every check concurrently and reports whether all passed
+func (h *Health) Ready(ctx context.Context) (Report, bool) {
+	if h.draining.Load() {
+		return Report{Status: ErrShuttingDown.Error()}, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, c Checker) {
+			defer wg.Done()
+			errs[i] = c.Check(ctx)
+		}(i, h.checks[name])
+	}
+	h.mu.RUnlock()
+	wg.Wait()
+
+	report := Report{Status: "ok", Checks: make(map[string]CheckResult, len(names))}
+	ok := true
+	for i, name := range names {
+		if errs[i] != nil {
+			ok = false
+			report.Checks[name] = CheckResult{Status: "fail", Error: errs[i].Error()}
+			continue
+		}
+		report.Checks[name] = CheckResult{Status: "ok"}
+	}
+	if !ok {
+		report.Status = "unavailable"
+	}
+	return report, ok
+}
+
+func write(w http.ResponseWriter, ok bool, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// Livez serves /healthz: the process is running and able to serve HTTP
+func (h *Health) Livez(w http.ResponseWriter, r *http.Request) {
+	write(w, true, Report{Status: "ok"})
+}
+
+// Readyz serves /readyz
+func (h *Health) Readyz(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.Ready(r.Context())
+	write(w, ok, report)
+}
+
+// Register mounts /healthz and /readyz on mux
+func (h *Health) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", h.Livez)
+	mux.HandleFunc("GET /readyz", h.Readyz)
+}
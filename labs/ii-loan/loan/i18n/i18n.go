@@ -0,0 +1,207 @@
+// Package i18n renders the text customers read — loan status names,
+// rejection reasons and notification messages — in their language.
+// Messages live in a Catalog, one flat JSON object of keys to text per
+// locale; keys missing from a locale fall back to the catalog's default
+// locale.
+//
+//	cat := i18n.Default()
+//	locale := cat.Negotiate(r.Header.Get("Accept-Language"))
+//	cat.Status(locale, loan.StatusApproved) // "อนุมัติแล้ว" for th
+//
+// Messages may hold {name} placeholders filled by Text.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLocale is the locale of the built-in catalog's fallback messages
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// Catalog holds the messages of every supported locale. It is read-only
+// once loaded and safe for concurrent use.
+type Catalog struct {
+	fallback string
+	messages map[string]map[string]string
+}
+
+// Load reads a catalog from the *.json files of fsys, each named after its
+// locale such as th.json. fallback must be one of them.
+func Load(fsys fs.FS, fallback string) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{fallback: normalize(fallback), messages: make(map[string]map[string]string)}
+	for _, name := range files {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(b, &messages); err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", name, err)
+		}
+		c.messages[normalize(strings.TrimSuffix(path.Base(name), ".json"))] = messages
+	}
+	if _, ok := c.messages[c.fallback]; !ok {
+		return nil, fmt.Errorf("i18n: no messages for fallback locale %q", fallback)
+	}
+	return c, nil
+}
+
+// Default returns the built-in catalog, English with a Thai translation
+func Default() *Catalog {
+	sub, err := fs.Sub(localeFS, "locales")
+	if err != nil {
+		panic(err)
+	}
+	c, err := Load(sub, DefaultLocale)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Locales returns the locales of the catalog, sorted
+func (c *Catalog) Locales() []string {
+	out := make([]string, 0, len(c.messages))
+	for l := range c.messages {
+		out = append(out, l)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Fallback returns the locale used when no other matches
+func (c *Catalog) Fallback() string {
+	return c.fallback
+}
+
+// Negotiate picks the locale for an Accept-Language header value: the
+// supported locale the client weighs highest, matching "th-TH" to "th"
+// when only the language is supported, or the fallback
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	best, bestQ := c.fallback, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+		if l, ok := c.match(tag); ok {
+			best, bestQ = l, q
+		}
+	}
+	return best
+}
+
+// match returns the supported locale for a language tag
+func (c *Catalog) match(tag string) (string, bool) {
+	tag = normalize(tag)
+	if tag == "*" {
+		return c.fallback, true
+	}
+	for ; tag != ""; tag, _, _ = cutLast(tag) {
+		if _, ok := c.messages[tag]; ok {
+			return tag, true
+		}
+	}
+	return "", false
+}
+
+func cutLast(tag string) (string, string, bool) {
+	i := strings.LastIndexByte(tag, '-')
+	if i < 0 {
+		return "", tag, false
+	}
+	return tag[:i], tag[i+1:], true
+}
+
+func normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+// Message returns the text of key in locale, falling back to the less
+// specific locale and then the catalog's fallback
+func (c *Catalog) Message(locale, key string) (string, bool) {
+	for l := normalize(locale); l != ""; l, _, _ = cutLast(l) {
+		if m, ok := c.messages[l][key]; ok {
+			return m, true
+		}
+	}
+	m, ok := c.messages[c.fallback][key]
+	return m, ok
+}
+
+// Text returns the message of key in locale with its {name} placeholders
+// replaced by args, or key itself when no locale has it
+func (c *Catalog) Text(locale, key string, args map[string]any) string {
+	m, ok := c.Message(locale, key)
+	if !ok {
+		return key
+	}
+	for name, v := range args {
+		m = strings.ReplaceAll(m, "{"+name+"}", fmt.Sprint(v))
+	}
+	return m
+}
+
+// Status returns the display name of a loan status
+func (c *Catalog) Status(locale, status string) string {
+	if m, ok := c.Message(locale, "status."+status); ok {
+		return m
+	}
+	return status
+}
+
+// Reason translates a rejection reason. Reasons are free text, so only
+// those in the catalog under "reason.<text>" are translated; the others
+// are returned as written.
+func (c *Catalog) Reason(locale, reason string) string {
+	if m, ok := c.Message(locale, "reason."+strings.ToLower(strings.TrimSpace(reason))); ok {
+		return m
+	}
+	return reason
+}
+
+// Date formats t as a long date with the locale's month names. The
+// "format.date" message places {day}, {month}, and {year} or, for the Thai
+// calendar, {buddhistYear}.
+func (c *Catalog) Date(locale string, t time.Time) string {
+	return c.Text(locale, "format.date", map[string]any{
+		"day": t.Day(), "month": c.Text(locale, "month."+strconv.Itoa(int(t.Month())), nil), "year": t.Year(),
+		"buddhistYear": t.Year() + 543,
+	})
+}
+
+type localeKey struct{}
+
+// WithLocale returns a context carrying the locale negotiated for a request
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// Locale returns the locale carried by ctx, empty if none
+func Locale(ctx context.Context) string {
+	l, _ := ctx.Value(localeKey{}).(string)
+	return l
+}
@@ -0,0 +1,233 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"loan/tracing"
+)
+
+// ErrNotInsured is returned for insurance claims on a loan without credit
+// insurance
+var ErrNotInsured = errors.New("loan is not insured")
+
+// Insured events: what credit insurance can pay a loan off for
+const (
+	InsuredDeath        = "death"
+	InsuredDisability   = "disability"
+	InsuredUnemployment = "unemployment"
+)
+
+// InsuredEvents lists every event credit insurance can cover
+var InsuredEvents = []string{InsuredDeath, InsuredDisability, InsuredUnemployment}
+
+// InsurancePlan is credit insurance a product offers with its loans. A
+// claim for an event it covers pays off the balance of the loan.
+type InsurancePlan struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// Rate is the yearly premium as a fraction of the amount borrowed
+	Rate float64 `json:"rate"`
+	// Covers lists the events claims can be made for
+	Covers []string `json:"covers"`
+}
+
+// Clone returns a deep copy of the plan
+func (p InsurancePlan) Clone() InsurancePlan {
+	p.Covers = slices.Clone(p.Covers)
+	return p
+}
+
+// Validate returns a ValidationError describing what is wrong with p
+func (p InsurancePlan) Validate() error {
+	switch {
+	case strings.TrimSpace(p.Code) == "":
+		return invalid("insurance.code", "plan code is required")
+	case p.Rate <= 0 || p.Rate >= 1:
+		return invalid("insurance.rate", "premium rate must be a fraction between 0 and 1")
+	case len(p.Covers) == 0:
+		return invalid("insurance.covers", "a plan must cover at least one event")
+	}
+	for _, e := range p.Covers {
+		if !slices.Contains(InsuredEvents, e) {
+			return invalid("insurance.covers", fmt.Sprintf("unknown insured event %q; events are %s", e, strings.Join(InsuredEvents, ", ")))
+		}
+	}
+	return nil
+}
+
+// PremiumFor is the premium the plan collects with each installment of l:
+// the yearly premium on the amount borrowed spread over a year's
+// installments
+func (p InsurancePlan) PremiumFor(l *Loan) float64 {
+	return round2(l.Amount * p.Rate / float64(PeriodsPerYear(l.Frequency)))
+}
+
+// Insurance is the credit insurance attached to a loan. Loans keep the
+// plan as it was priced when attached.
+type Insurance struct {
+	Plan InsurancePlan `json:"plan"`
+	// Premium is collected with each installment
+	Premium    float64   `json:"premium"`
+	AttachedAt time.Time `json:"attachedAt"`
+	// Claim is the claim that paid the loan off, if any
+	Claim *InsuranceClaim `json:"claim,omitempty"`
+}
+
+// Clone returns a deep copy of the insurance
+func (i *Insurance) Clone() *Insurance {
+	c := *i
+	c.Plan = i.Plan.Clone()
+	if i.Claim != nil {
+		claim := *i.Claim
+		c.Claim = &claim
+	}
+	return &c
+}
+
+// InsuranceClaim is a claim on a loan's insurance, settled by paying off
+// the loan
+type InsuranceClaim struct {
+	ID         string    `json:"id"`
+	LoanID     string    `json:"loanId"`
+	CustomerID string    `json:"customerId"`
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurredAt"`
+	ClaimedAt  time.Time `json:"claimedAt"`
+	// Amount is the balance paid off by PaymentID
+	Amount    float64 `json:"amount"`
+	PaymentID string  `json:"paymentId"`
+}
+
+// Customer returns the insured customer
+func (c InsuranceClaim) Customer() string { return c.CustomerID }
+
+// AttachInsurance insures a pending or approved loan under plan from the
+// time at, adding its premium to the installments falling due after at
+func (l *Loan) AttachInsurance(plan InsurancePlan, at time.Time) (*Insurance, error) {
+	if l.Status != StatusPending && !l.IsActive() {
+		return nil, fmt.Errorf("%w: cannot insure loan in status %q", ErrInvalidTransition, l.Status)
+	}
+	if l.Insurance != nil {
+		return nil, fmt.Errorf("%w: loan is already insured", ErrInvalidTransition)
+	}
+	l.Insurance = &Insurance{Plan: plan.Clone(), Premium: plan.PremiumFor(l), AttachedAt: at.UTC()}
+	l.spreadAddOns(at)
+	return l.Insurance, nil
+}
+
+// ClaimInsurance settles a claim for event, which occurred at occurredAt
+// no earlier than the day the loan was insured, by paying the balance of
+// the loan off at the time at. No prepayment penalty is charged and
+// nothing is deposited in escrow.
+func (l *Loan) ClaimInsurance(event string, occurredAt, at time.Time) (InsuranceClaim, Payment, error) {
+	if l.Insurance == nil {
+		return InsuranceClaim{}, Payment{}, ErrNotInsured
+	}
+	if !l.IsActive() {
+		return InsuranceClaim{}, Payment{}, fmt.Errorf("%w: loan in status %q has nothing to claim", ErrInvalidTransition, l.Status)
+	}
+	plan := l.Insurance.Plan
+	switch {
+	case !slices.Contains(plan.Covers, event):
+		return InsuranceClaim{}, Payment{}, invalid("event", fmt.Sprintf("plan %s does not cover %q, only %s", plan.Code, event, strings.Join(plan.Covers, ", ")))
+	case occurredAt.IsZero():
+		return InsuranceClaim{}, Payment{}, invalid("occurredAt", "when the event occurred is required")
+	case occurredAt.Before(l.Insurance.AttachedAt.Truncate(24 * time.Hour)):
+		return InsuranceClaim{}, Payment{}, invalid("occurredAt", "the event occurred before the loan was insured")
+	case occurredAt.After(at):
+		return InsuranceClaim{}, Payment{}, invalid("occurredAt", "the event cannot occur in the future")
+	}
+	p, err := l.applyPayment(l.Balance, at, false)
+	if err != nil {
+		return InsuranceClaim{}, Payment{}, err
+	}
+	c := InsuranceClaim{
+		ID: NewID(), LoanID: l.ID, CustomerID: l.CustomerID, Event: event,
+		OccurredAt: occurredAt.UTC(), ClaimedAt: at.UTC(), Amount: p.Amount, PaymentID: p.ID,
+	}
+	l.Insurance.Claim = &c
+	return c, p, nil
+}
+
+// product looks code up in the pricing in force or, when it has no
+// products, the catalog, returning ErrProductNotFound for unknown codes
+func (s *LoanService) product(ctx context.Context, code string) (Product, error) {
+	var p *Pricing
+	if s.pricing != nil {
+		p = s.pricing.Pricing()
+	}
+	switch {
+	case p != nil && len(p.Products) > 0:
+		if prod, ok := p.Products[code]; ok {
+			return prod, nil
+		}
+	case s.products != nil && code != "":
+		return s.products.Product(ctx, code)
+	}
+	return Product{}, ErrProductNotFound
+}
+
+// AttachInsurance insures a loan under the plan with code offered by its
+// product, priced from the product as it is now, and publishes
+// EventInsuranceAttached
+func (s *LoanService) AttachInsurance(ctx context.Context, id, plan string) (_ *Insurance, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.AttachInsurance", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	l, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	prod, err := s.product(ctx, l.Product)
+	if errors.Is(err, ErrProductNotFound) {
+		return nil, invalid("plan", "the loan's product offers no insurance")
+	}
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(prod.Insurance, func(p InsurancePlan) bool { return p.Code == plan })
+	if i < 0 {
+		return nil, invalid("plan", fmt.Sprintf("%s loans offer no insurance plan %q", prod.Code, plan))
+	}
+	ins, err := l.AttachInsurance(prod.Insurance[i], time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, l); err != nil {
+		s.log(l).ErrorContext(ctx, "updating insured loan", "error", err)
+		return nil, err
+	}
+	s.log(l).InfoContext(ctx, "insurance attached", "plan", plan, "premium", ins.Premium)
+	return ins, s.publish(ctx, l, NewEvent(EventInsuranceAttached, l.ID, ins))
+}
+
+// ClaimInsurance pays a loan off from its insurance for event, which
+// occurred at occurredAt, and publishes EventPaymentReceived and
+// EventInsuranceClaimed
+func (s *LoanService) ClaimInsurance(ctx context.Context, id, event string, occurredAt time.Time) (_ InsuranceClaim, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.ClaimInsurance", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	l, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return InsuranceClaim{}, err
+	}
+	claim, payment, err := l.ClaimInsurance(event, occurredAt, time.Now())
+	if err != nil {
+		return InsuranceClaim{}, err
+	}
+	if err := s.repo.Update(ctx, l); err != nil {
+		s.log(l).ErrorContext(ctx, "updating loan paid off by insurance", "error", err)
+		return InsuranceClaim{}, err
+	}
+	s.log(l).InfoContext(ctx, "insurance claim paid", "claim_id", claim.ID, "event", event, "amount", claim.Amount)
+	if err := s.publish(ctx, l, NewEvent(EventPaymentReceived, l.ID, payment)); err != nil {
+		return claim, err
+	}
+	return claim, s.publish(ctx, l, NewEvent(EventInsuranceClaimed, l.ID, claim))
+}
@@ -0,0 +1,207 @@
+package investor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"loan"
+)
+
+// Book funds loans and pays investors their part of repayments
+type Book struct {
+	store Store
+	loans loan.LoanRepository
+	now   func() time.Time
+	// mu serialises funding, so two commitments cannot both see room
+	// for the same part of a loan
+	mu sync.Mutex
+}
+
+// NewBook creates a book keeping shares in store and reading loans from
+// loans
+func NewBook(store Store, loans loan.LoanRepository) *Book {
+	return &Book{store: store, loans: loans, now: time.Now}
+}
+
+// Fund commits amount of a pending or approved loan's principal to
+// investorID and returns the investor's whole share of the loan
+func (b *Book) Fund(ctx context.Context, loanID, investorID string, amount float64) (Share, error) {
+	if amount <= 0 || math.IsNaN(amount) {
+		return Share{}, fmt.Errorf("investor: amount must be positive, got %v", amount)
+	}
+	if investorID == "" {
+		return Share{}, fmt.Errorf("investor: investor ID is required")
+	}
+	l, err := b.loans.FindByID(ctx, loanID)
+	if err != nil {
+		return Share{}, err
+	}
+	if l.Status != loan.StatusPending && l.Status != loan.StatusApproved {
+		return Share{}, fmt.Errorf("%w: loan %s is %s", ErrNotFundable, l.ID, l.Status)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	shares, err := b.store.Shares(ctx, loanID)
+	if err != nil {
+		return Share{}, err
+	}
+	var funded float64
+	for _, s := range shares {
+		funded += s.Amount
+	}
+	if left := round2(l.Amount - funded); amount > left {
+		return Share{}, fmt.Errorf("%w: %.2f of %.2f left to fund", ErrOverfunded, math.Max(left, 0), l.Amount)
+	}
+	amount = round2(amount)
+	return b.store.AddShare(ctx, Share{
+		LoanID: loanID, InvestorID: investorID, Amount: amount, Fraction: amount / l.Amount, FundedAt: b.now().UTC(),
+	})
+}
+
+// Shares returns the shares of a loan
+func (b *Book) Shares(ctx context.Context, loanID string) ([]Share, error) {
+	return b.store.Shares(ctx, loanID)
+}
+
+// Allocate splits p between the investors of its loan and stores their
+// parts. Principal and interest are split separately, to the cent, with
+// the cents rounding leaves going to the largest remainders.
+func (b *Book) Allocate(ctx context.Context, p loan.Payment) ([]Allocation, error) {
+	shares, err := b.store.Shares(ctx, p.LoanID)
+	if err != nil || len(shares) == 0 {
+		return nil, err
+	}
+	fractions := make([]float64, len(shares))
+	for i, s := range shares {
+		fractions[i] = s.Fraction
+	}
+	principal, interest := split(p.Principal, fractions), split(p.Interest, fractions)
+	out := make([]Allocation, 0, len(shares))
+	for i, s := range shares {
+		a := Allocation{
+			PaymentID: p.ID, LoanID: p.LoanID, InvestorID: s.InvestorID,
+			Principal: principal[i], Interest: interest[i], PaidAt: p.PaidAt.UTC(),
+		}
+		a.Amount = round2(a.Principal + a.Interest)
+		out = append(out, a)
+	}
+	return out, b.store.SaveAllocations(ctx, out)
+}
+
+// Publish implements loan.EventPublisher, allocating received payments
+func (b *Book) Publish(ctx context.Context, e loan.Event) error {
+	if e.Type != loan.EventPaymentReceived {
+		return nil
+	}
+	p, ok := e.Data.(loan.Payment)
+	if !ok {
+		return fmt.Errorf("investor: %s event carries %T, want loan.Payment", e.Type, e.Data)
+	}
+	_, err := b.Allocate(ctx, p)
+	return err
+}
+
+// split divides amount by fractions in whole cents. The investors' total
+// is their combined fraction of amount rounded to the cent, the rest being
+// the platform's; within it each gets the floor of their part and the
+// cents left over go to the largest remainders.
+func split(amount float64, fractions []float64) []float64 {
+	cents := math.Round(amount * 100)
+	var total float64
+	parts := make([]float64, len(fractions))
+	rest := make([]float64, len(fractions))
+	for i, f := range fractions {
+		total += f
+		exact := cents * f
+		parts[i] = math.Floor(exact)
+		rest[i] = exact - parts[i]
+	}
+	left := int(math.Round(cents * math.Min(total, 1)))
+	for _, p := range parts {
+		left -= int(p)
+	}
+	order := make([]int, len(fractions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return rest[order[a]] > rest[order[b]] })
+	for _, i := range order[:min(max(left, 0), len(order))] {
+		parts[i]++
+	}
+	out := make([]float64, len(parts))
+	for i, p := range parts {
+		out[i] = p / 100
+	}
+	return out
+}
+
+// Holding is an investor's share of one loan as of a statement
+type Holding struct {
+	Share
+	// Outstanding is the investor's part of the loan's balance
+	Outstanding float64 `json:"outstanding"`
+	Status      string  `json:"status"`
+}
+
+// Statement is what an investor held and received in one month
+type Statement struct {
+	InvestorID  string       `json:"investorId"`
+	Period      time.Time    `json:"period"`
+	Holdings    []Holding    `json:"holdings"`
+	Allocations []Allocation `json:"allocations"`
+	Invested    float64      `json:"invested"`
+	Outstanding float64      `json:"outstanding"`
+	Principal   float64      `json:"principal"`
+	Interest    float64      `json:"interest"`
+	Received    float64      `json:"received"`
+	GeneratedAt time.Time    `json:"generatedAt"`
+}
+
+// Statement builds the statement of investorID for the month of period,
+// with holdings valued at the loans' current balances
+func (b *Book) Statement(ctx context.Context, investorID string, period time.Time) (Statement, error) {
+	start := loan.MonthStart(period)
+	st := Statement{
+		InvestorID: investorID, Period: start, Holdings: []Holding{}, GeneratedAt: b.now().UTC(),
+	}
+	shares, err := b.store.Holdings(ctx, investorID)
+	if err != nil {
+		return Statement{}, err
+	}
+	for _, s := range shares {
+		l, err := b.loans.FindByID(ctx, s.LoanID)
+		if err != nil {
+			return Statement{}, err
+		}
+		h := Holding{Share: s, Outstanding: round2(math.Max(l.Balance, 0) * s.Fraction), Status: l.Status}
+		if l.Status == loan.StatusPending {
+			// nothing is lent until approval
+			h.Outstanding = 0
+		}
+		st.Holdings = append(st.Holdings, h)
+		st.Invested += s.Amount
+		st.Outstanding += h.Outstanding
+	}
+	if st.Allocations, err = b.store.Allocations(ctx, investorID, start, start.AddDate(0, 1, 0)); err != nil {
+		return Statement{}, err
+	}
+	if st.Allocations == nil {
+		st.Allocations = []Allocation{}
+	}
+	for _, a := range st.Allocations {
+		st.Principal += a.Principal
+		st.Interest += a.Interest
+	}
+	st.Invested, st.Outstanding = round2(st.Invested), round2(st.Outstanding)
+	st.Principal, st.Interest = round2(st.Principal), round2(st.Interest)
+	st.Received = round2(st.Principal + st.Interest)
+	return st, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,167 @@
+// Package investor tracks loans funded peer to peer. Investors commit part
+// of a loan's principal; each repayment is split between them in
+// proportion to their commitment, and the part of a loan nobody funded
+// stays with the platform. Per-investor statements list the holdings and
+// the money received in a month.
+//
+//	book := investor.NewBook(investor.NewMemory(), repo)
+//	book.Fund(ctx, "L-1", "INV-7", 2500)
+//	svc := loan.NewLoanService(repo, loan.WithEventPublisher(book))
+//
+// As an event publisher the book allocates every EventPaymentReceived.
+package investor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrOverfunded is returned when a commitment would take a loan's shares
+// past its principal
+var ErrOverfunded = errors.New("investor: loan is fully funded")
+
+// ErrNotFundable is returned for loans that are not pending or approved
+var ErrNotFundable = errors.New("investor: loan cannot be funded")
+
+// Share is an investor's commitment to one loan
+type Share struct {
+	LoanID     string  `json:"loanId"`
+	InvestorID string  `json:"investorId"`
+	Amount     float64 `json:"amount"`
+	// Fraction is Amount over the loan's principal, the part of each
+	// repayment the investor receives
+	Fraction float64   `json:"fraction"`
+	FundedAt time.Time `json:"fundedAt"`
+}
+
+// Allocation is an investor's part of one repayment
+type Allocation struct {
+	PaymentID  string    `json:"paymentId"`
+	LoanID     string    `json:"loanId"`
+	InvestorID string    `json:"investorId"`
+	Amount     float64   `json:"amount"`
+	Principal  float64   `json:"principal"`
+	Interest   float64   `json:"interest"`
+	PaidAt     time.Time `json:"paidAt"`
+}
+
+// Store keeps shares and allocations
+type Store interface {
+	// AddShare stores s, adding to the investor's earlier share of the
+	// same loan
+	AddShare(ctx context.Context, s Share) (Share, error)
+	// Shares returns the shares of a loan ordered by investor
+	Shares(ctx context.Context, loanID string) ([]Share, error)
+	// Holdings returns the shares of an investor ordered by loan
+	Holdings(ctx context.Context, investorID string) ([]Share, error)
+	// SaveAllocations stores the allocations of one payment, ignoring
+	// those already stored so a redelivered event is not paid twice
+	SaveAllocations(ctx context.Context, allocations []Allocation) error
+	// Allocations returns an investor's allocations paid in [from, to),
+	// oldest first
+	Allocations(ctx context.Context, investorID string, from, to time.Time) ([]Allocation, error)
+}
+
+// Memory is an in-process Store
+type Memory struct {
+	mu          sync.RWMutex
+	shares      map[string]map[string]Share
+	allocations []Allocation
+	allocated   map[string]bool
+}
+
+// NewMemory creates an empty store
+func NewMemory() *Memory {
+	return &Memory{shares: make(map[string]map[string]Share), allocated: make(map[string]bool)}
+}
+
+// AddShare implements Store
+func (m *Memory) AddShare(ctx context.Context, s Share) (Share, error) {
+	if err := ctx.Err(); err != nil {
+		return Share{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	byInvestor := m.shares[s.LoanID]
+	if byInvestor == nil {
+		byInvestor = make(map[string]Share)
+		m.shares[s.LoanID] = byInvestor
+	}
+	if prev, ok := byInvestor[s.InvestorID]; ok {
+		s.Amount += prev.Amount
+		s.Fraction += prev.Fraction
+		s.FundedAt = prev.FundedAt
+	}
+	byInvestor[s.InvestorID] = s
+	return s, nil
+}
+
+// Shares implements Store
+func (m *Memory) Shares(ctx context.Context, loanID string) ([]Share, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Share, 0, len(m.shares[loanID]))
+	for _, s := range m.shares[loanID] {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].InvestorID < out[j].InvestorID })
+	return out, nil
+}
+
+// Holdings implements Store
+func (m *Memory) Holdings(ctx context.Context, investorID string) ([]Share, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Share
+	for _, byInvestor := range m.shares {
+		if s, ok := byInvestor[investorID]; ok {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LoanID < out[j].LoanID })
+	return out, nil
+}
+
+// SaveAllocations implements Store
+func (m *Memory) SaveAllocations(ctx context.Context, allocations []Allocation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range allocations {
+		key := a.PaymentID + "/" + a.InvestorID
+		if m.allocated[key] {
+			continue
+		}
+		m.allocated[key] = true
+		m.allocations = append(m.allocations, a)
+	}
+	return nil
+}
+
+// Allocations implements Store
+func (m *Memory) Allocations(ctx context.Context, investorID string, from, to time.Time) ([]Allocation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Allocation
+	for _, a := range m.allocations {
+		if a.InvestorID == investorID && !a.PaidAt.Before(from) && a.PaidAt.Before(to) {
+			out = append(out, a)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].PaidAt.Before(out[j].PaidAt) })
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"loan"
+	"loan/scheduler"
+)
+
+// DirectDebitSpec runs direct debits in the morning UTC, after the
+// installment due reminders
+const DirectDebitSpec = "0 8 * * *"
+
+// Collector takes repayments through the payment gateway.
+// *loan.LoanService implements it.
+type Collector interface {
+	CollectPayment(ctx context.Context, loanID string, amount float64, account string) (loan.Payment, error)
+}
+
+// DirectDebitFailedPayload is the data of EventDirectDebitFailed
+type DirectDebitFailedPayload struct {
+	LoanID      string    `json:"loanId"`
+	CustomerID  string    `json:"customerId"`
+	MandateID   string    `json:"mandateId"`
+	Installment int       `json:"installment"`
+	DueDate     time.Time `json:"dueDate"`
+	Amount      float64   `json:"amount"`
+	Attempts    int       `json:"attempts"`
+	Error       string    `json:"error"`
+}
+
+// Customer returns the customer whose debit failed
+func (p DirectDebitFailedPayload) Customer() string { return p.CustomerID }
+
+// NewDirectDebitJob collects due installments of loans with an active
+// mandate. An installment is debited on its due date and, while that
+// fails, again on each of the mandate's retry days; when the last attempt
+// fails EventDirectDebitFailed is raised. Each run makes at most one
+// attempt per loan, oldest installment first, and records it on the
+// mandate so re-running a day does not debit twice.
+func NewDirectDebitJob(deps Deps) scheduler.Job {
+	return scheduler.NewJob("direct-debit", func(ctx context.Context, now time.Time) error {
+		mandates, err := deps.Mandates.ActiveMandates(ctx)
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, m := range mandates {
+			if err := debit(ctx, deps, m, now); err != nil {
+				errs = append(errs, fmt.Errorf("loan %s: %w", m.LoanID, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+func debit(ctx context.Context, deps Deps, m *loan.Mandate, now time.Time) error {
+	l, err := deps.Loans.FindByID(ctx, m.LoanID)
+	if err != nil {
+		return err
+	}
+	if !l.IsActive() {
+		return nil
+	}
+	for _, inst := range l.Schedule {
+		if inst.IsPaid() {
+			continue
+		}
+		attempt, on, ok := m.NextAttempt(inst)
+		if !ok || day(on).After(day(now)) {
+			continue
+		}
+		a := loan.DebitAttempt{
+			Installment: inst.Number, DueDate: inst.DueDate, Attempt: attempt,
+			Amount: math.Min(inst.Outstanding(), l.Balance), AttemptedAt: now.UTC(),
+		}
+		p, collectErr := deps.Collector.CollectPayment(ctx, l.ID, a.Amount, m.Account)
+		if collectErr != nil {
+			a.Status, a.Error = loan.DebitFailed, collectErr.Error()
+		} else {
+			a.Status, a.PaymentID = loan.DebitSucceeded, p.ID
+		}
+		m.Attempts = append(m.Attempts, a)
+		if err := deps.Mandates.SaveMandate(ctx, m); err != nil {
+			return err
+		}
+		if collectErr == nil || !m.Final(attempt) {
+			return nil
+		}
+		payload := DirectDebitFailedPayload{
+			LoanID: l.ID, CustomerID: l.CustomerID, MandateID: m.ID, Installment: inst.Number,
+			DueDate: inst.DueDate, Amount: a.Amount, Attempts: attempt, Error: a.Error,
+		}
+		return deps.Publisher.Publish(ctx, loan.NewEvent(loan.EventDirectDebitFailed, l.ID, payload))
+	}
+	return nil
+}
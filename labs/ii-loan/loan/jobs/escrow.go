@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"loan"
+	"loan/scheduler"
+)
+
+// EscrowSpec runs escrow disbursements in the morning UTC, after accrual
+const EscrowSpec = "0 5 * * *"
+
+// NewEscrowJob pays out the escrow items of active loans falling due by
+// the run, raising EventEscrowDisbursed for each payment and
+// EventEscrowShortfall for those the escrow balance could not cover, then
+// analyzes escrow a year after its last analysis, raising
+// EventEscrowAnalyzed. Items are paid once per due date however often the
+// job runs.
+func NewEscrowJob(deps Deps) scheduler.Job {
+	return scheduler.NewJob("escrow", func(ctx context.Context, now time.Time) error {
+		loans, err := activeLoans(ctx, deps.Loans)
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, l := range loans {
+			if l.Escrow == nil {
+				continue
+			}
+			if err := escrow(ctx, deps, l, now); err != nil {
+				errs = append(errs, fmt.Errorf("loan %s: %w", l.ID, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+func escrow(ctx context.Context, deps Deps, l *loan.Loan, now time.Time) error {
+	paid, short := l.DisburseEscrow(now)
+	var analysis *loan.EscrowAnalysis
+	if l.EscrowAnalysisDue(now) {
+		a := l.AnalyzeEscrow(now)
+		analysis = &a
+	}
+	if len(paid) == 0 && analysis == nil {
+		return nil
+	}
+	if err := deps.Loans.Update(ctx, l); err != nil {
+		return err
+	}
+	var errs []error
+	for _, t := range paid {
+		errs = append(errs, deps.Publisher.Publish(ctx, loan.NewEvent(loan.EventEscrowDisbursed, l.ID, t)))
+	}
+	for _, s := range short {
+		errs = append(errs, deps.Publisher.Publish(ctx, loan.NewEvent(loan.EventEscrowShortfall, l.ID, s)))
+	}
+	if analysis != nil {
+		errs = append(errs, deps.Publisher.Publish(ctx, loan.NewEvent(loan.EventEscrowAnalyzed, l.ID, *analysis)))
+	}
+	return errors.Join(errs...)
+}
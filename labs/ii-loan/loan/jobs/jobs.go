@@ -0,0 +1,264 @@
+// Package jobs contains the scheduled loan lifecycle tasks run by the
+// scheduler package.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"loan"
+	"loan/ledger"
+	"loan/scheduler"
+)
+
+// StatementStore persists generated monthly statements
+type StatementStore interface {
+	SaveStatement(ctx context.Context, st loan.Statement) error
+}
+
+// Deps are the collaborators shared by the lifecycle jobs
+type Deps struct {
+	Loans      loan.LoanRepository
+	Publisher  loan.EventPublisher
+	Statements StatementStore
+	Ledger     ledger.Ledger
+	// Mandates and Collector enable the direct debit job; it is not
+	// registered without them
+	Mandates  loan.MandateRepository
+	Collector Collector
+	// Aging enables the month-end aging snapshot job; it is not
+	// registered without it
+	Aging loan.AgingStore
+	// Transitions, with Aging, enables the roll-rate job
+	Transitions loan.TransitionStore
+	// ReminderLeadDays is how many days before the due date a payment
+	// due event is raised. Zero raises it on the due date only.
+	ReminderLeadDays int
+}
+
+// Default schedules, all in UTC
+const (
+	InstallmentDueSpec = "0 6 * * *"
+	DelinquencySpec    = "30 0 * * *"
+	StatementSpec      = "0 2 1 * *"
+	// AgingSnapshotSpec runs after the delinquency job has bucketed the
+	// last day of the month
+	AgingSnapshotSpec = "0 3 1 * *"
+	// RollRateSpec runs once the aging snapshot of the month is taken
+	RollRateSpec = "30 3 1 * *"
+)
+
+// Register adds the lifecycle jobs to s with their default schedules
+func Register(s *scheduler.Scheduler, deps Deps) error {
+	err := errors.Join(
+		s.Add(AccrualSpec, NewAccrualJob(deps.Loans, deps.Ledger)),
+		s.Add(InstallmentDueSpec, NewInstallmentDueJob(deps)),
+		s.Add(DelinquencySpec, NewDelinquencyJob(deps)),
+		s.Add(StatementSpec, NewStatementJob(deps)),
+		s.Add(EscrowSpec, NewEscrowJob(deps)),
+	)
+	if deps.Mandates != nil && deps.Collector != nil {
+		err = errors.Join(err, s.Add(DirectDebitSpec, NewDirectDebitJob(deps)))
+	}
+	if deps.Aging != nil {
+		err = errors.Join(err, s.Add(AgingSnapshotSpec, NewAgingSnapshotJob(deps)))
+		if deps.Transitions != nil {
+			err = errors.Join(err, s.Add(RollRateSpec, NewRollRateJob(deps)))
+		}
+	}
+	return err
+}
+
+func activeLoans(ctx context.Context, repo loan.LoanRepository) ([]*loan.Loan, error) {
+	loans, err := repo.List(ctx, loan.Filter{Statuses: []string{loan.StatusApproved}})
+	if err != nil {
+		return nil, err
+	}
+	active := loans[:0]
+	for _, l := range loans {
+		if l.IsActive() {
+			active = append(active, l)
+		}
+	}
+	return active, nil
+}
+
+// conflictRetries is how many times a job redoes its change of a loan
+// updated meanwhile, such as by a payment recorded while the job ran
+const conflictRetries = 3
+
+// updateLoan applies change to l and stores l if change reports it
+// changed it. A loan updated since it was read is read again and the
+// change redone on that, so a job never writes back a loan it has not
+// seen. It returns the loan stored, nil when change left it as it was.
+func updateLoan(ctx context.Context, repo loan.LoanRepository, l *loan.Loan, change func(*loan.Loan) (bool, error)) (*loan.Loan, error) {
+	for attempt := 0; ; attempt++ {
+		changed, err := change(l)
+		if !changed {
+			return nil, err
+		}
+		updateErr := repo.Update(ctx, l)
+		if updateErr == nil {
+			return l, err
+		}
+		if !errors.Is(updateErr, loan.ErrConflict) || attempt == conflictRetries {
+			return nil, errors.Join(err, updateErr)
+		}
+		if l, err = repo.FindByID(ctx, l.ID); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func sameDay(a, b time.Time) bool {
+	return day(a).Equal(day(b))
+}
+
+// InstallmentDuePayload is the data of EventInstallmentDue
+type InstallmentDuePayload struct {
+	LoanID      string           `json:"loanId"`
+	CustomerID  string           `json:"customerId"`
+	Installment loan.Installment `json:"installment"`
+}
+
+// Customer returns the customer owing the installment
+func (p InstallmentDuePayload) Customer() string { return p.CustomerID }
+
+// NewInstallmentDueJob raises EventInstallmentDue for unpaid installments
+// falling due ReminderLeadDays after the run date. Running once per day
+// raises each reminder exactly once.
+func NewInstallmentDueJob(deps Deps) scheduler.Job {
+	return scheduler.NewJob("installment-due", func(ctx context.Context, now time.Time) error {
+		loans, err := activeLoans(ctx, deps.Loans)
+		if err != nil {
+			return err
+		}
+		target := now.AddDate(0, 0, deps.ReminderLeadDays)
+		var errs []error
+		for _, l := range loans {
+			for _, inst := range l.Schedule {
+				if inst.IsPaid() || !sameDay(inst.DueDate, target) {
+					continue
+				}
+				payload := InstallmentDuePayload{LoanID: l.ID, CustomerID: l.CustomerID, Installment: inst}
+				errs = append(errs, deps.Publisher.Publish(ctx, loan.NewEvent(loan.EventInstallmentDue, l.ID, payload)))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// OverduePayload is the data of EventPaymentOverdue
+type OverduePayload struct {
+	LoanID      string      `json:"loanId"`
+	CustomerID  string      `json:"customerId"`
+	DaysPastDue int         `json:"daysPastDue"`
+	Previous    loan.Bucket `json:"previous"`
+	Bucket      loan.Bucket `json:"bucket"`
+	Balance     float64     `json:"balance"`
+}
+
+// Customer returns the customer whose loan is overdue
+func (p OverduePayload) Customer() string { return p.CustomerID }
+
+// NewDelinquencyJob recomputes days past due and the delinquency bucket of
+// every active loan, raising EventPaymentOverdue when a loan moves into a
+// worse bucket.
+func NewDelinquencyJob(deps Deps) scheduler.Job {
+	return scheduler.NewJob("delinquency-bucketing", func(ctx context.Context, now time.Time) error {
+		loans, err := activeLoans(ctx, deps.Loans)
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, l := range loans {
+			var (
+				dpd              int
+				bucket, previous loan.Bucket
+			)
+			l, err := updateLoan(ctx, deps.Loans, l, func(l *loan.Loan) (bool, error) {
+				dpd = l.CalculateDaysPastDue(now)
+				bucket = loan.BucketFor(dpd)
+				previous = l.Delinquency
+				if previous == "" {
+					previous = loan.BucketCurrent
+				}
+				if dpd == l.DaysPastDue && bucket == l.Delinquency {
+					return false, nil
+				}
+				l.DaysPastDue, l.Delinquency = dpd, bucket
+				return true, nil
+			})
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if l != nil && bucket.Worse(previous) {
+				payload := OverduePayload{
+					LoanID: l.ID, CustomerID: l.CustomerID, DaysPastDue: dpd,
+					Previous: previous, Bucket: bucket, Balance: l.Balance,
+				}
+				errs = append(errs, deps.Publisher.Publish(ctx, loan.NewEvent(loan.EventPaymentOverdue, l.ID, payload)))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// NewStatementJob generates last month's statement for every active loan.
+// It is meant to run early on the first day of each month.
+func NewStatementJob(deps Deps) scheduler.Job {
+	return scheduler.NewJob("statement-generation", func(ctx context.Context, now time.Time) error {
+		loans, err := activeLoans(ctx, deps.Loans)
+		if err != nil {
+			return err
+		}
+		period := loan.MonthStart(now).AddDate(0, -1, 0)
+		var errs []error
+		for _, l := range loans {
+			st := loan.BuildStatement(l, period)
+			if err := deps.Statements.SaveStatement(ctx, st); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			errs = append(errs, deps.Publisher.Publish(ctx, loan.NewEvent(loan.EventStatementGenerated, l.ID, st)))
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// NewAgingSnapshotJob records the delinquency bucket and balance of every
+// loan of the book as the aging snapshot of last month. It is meant to run
+// early on the first day of each month.
+func NewAgingSnapshotJob(deps Deps) scheduler.Job {
+	return scheduler.NewJob("aging-snapshot", func(ctx context.Context, now time.Time) error {
+		loans, err := deps.Loans.List(ctx, loan.Filter{Statuses: []string{loan.StatusApproved, loan.StatusDefault}})
+		if err != nil {
+			return err
+		}
+		return deps.Aging.SaveAging(ctx, loan.MonthStart(now).AddDate(0, -1, 0), loan.SnapshotAging(loans))
+	})
+}
+
+// NewRollRateJob records the bucket transitions of last month between the
+// aging snapshots of its end and of the month before. The first month
+// snapshots are taken has nothing to compare with and is skipped.
+func NewRollRateJob(deps Deps) scheduler.Job {
+	return scheduler.NewJob("roll-rate", func(ctx context.Context, now time.Time) error {
+		month := loan.MonthStart(now).AddDate(0, -1, 0)
+		current, ok, err := deps.Aging.Aging(ctx, month)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w for %s", loan.ErrNoAging, month.Format("2006-01"))
+		}
+		previous, ok, err := deps.Aging.Aging(ctx, month.AddDate(0, -1, 0))
+		if err != nil || !ok {
+			return err
+		}
+		return deps.Transitions.SaveTransitions(ctx, month, loan.BucketTransitions(previous, current))
+	})
+}
@@ -0,0 +1,94 @@
+// Package ledger records the financial postings made against loans.
+package ledger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"loan"
+)
+
+// EntryType classifies a ledger posting
+type EntryType string
+
+// Ledger entry types
+const (
+	EntryInterestAccrual EntryType = "interest_accrual"
+)
+
+// Entry is a single posting against a loan. Reference is unique per ledger
+// and makes posting idempotent.
+type Entry struct {
+	ID            string    `json:"id"`
+	LoanID        string    `json:"loanId"`
+	Type          EntryType `json:"type"`
+	Amount        float64   `json:"amount"`
+	EffectiveDate time.Time `json:"effectiveDate"`
+	PostedAt      time.Time `json:"postedAt"`
+	Reference     string    `json:"reference"`
+}
+
+// Ledger stores entries
+type Ledger interface {
+	// Post stores e unless an entry with the same Reference exists, and
+	// returns the stored entry together with whether it was newly created.
+	Post(ctx context.Context, e Entry) (Entry, bool, error)
+	Entries(ctx context.Context, loanID string) ([]Entry, error)
+}
+
+// ErrMissingReference is returned when an entry has no idempotency reference
+var ErrMissingReference = errors.New("ledger: entry reference is required")
+
+// MemoryLedger is an in-process Ledger
+type MemoryLedger struct {
+	mu      sync.RWMutex
+	entries []Entry
+	byRef   map[string]int
+}
+
+// NewMemoryLedger creates an empty ledger
+func NewMemoryLedger() *MemoryLedger {
+	return &MemoryLedger{byRef: make(map[string]int)}
+}
+
+// Post stores e idempotently by Reference
+func (m *MemoryLedger) Post(ctx context.Context, e Entry) (Entry, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return Entry{}, false, err
+	}
+	if e.Reference == "" {
+		return Entry{}, false, ErrMissingReference
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i, ok := m.byRef[e.Reference]; ok {
+		return m.entries[i], false, nil
+	}
+	if e.ID == "" {
+		e.ID = loan.NewID()
+	}
+	if e.PostedAt.IsZero() {
+		e.PostedAt = time.Now().UTC()
+	}
+	m.byRef[e.Reference] = len(m.entries)
+	m.entries = append(m.entries, e)
+	return e, true, nil
+}
+
+// Entries returns a loan's entries in posting order
+func (m *MemoryLedger) Entries(ctx context.Context, loanID string) ([]Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Entry
+	for _, e := range m.entries {
+		if e.LoanID == loanID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
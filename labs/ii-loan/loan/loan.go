@@ -1,7 +1,9 @@
 package loan
 
 import (
-	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -28,56 +30,185 @@ const (
 
 // Loan represents a financial loan agreement
 type Loan struct {
-	ID           string
-	Amount       float64
-	Status       string
-	InterestRate float64
-	CustomerID   string
-	CreatedAt    time.Time
+	ID           string    `json:"id"`
+	Amount       float64   `json:"amount"`
+	Status       string    `json:"status"`
+	InterestRate float64   `json:"interestRate"`
+	CustomerID   string    `json:"customerId"`
+	CreatedAt    time.Time `json:"createdAt"`
+	TermMonths   int       `json:"termMonths,omitempty"`
+	ApprovedAt   time.Time `json:"approvedAt"`
+	// Balance is the outstanding amount owed, including accrued interest
+	Balance         float64       `json:"balance"`
+	AccruedInterest float64       `json:"accruedInterest"`
+	AccruedThrough  time.Time     `json:"accruedThrough"`
+	Schedule        []Installment `json:"schedule,omitempty"`
+	DaysPastDue     int           `json:"daysPastDue"`
+	Delinquency     Bucket        `json:"delinquency,omitempty"`
+	RejectionReason string        `json:"rejectionReason,omitempty"`
+	Payments        []Payment     `json:"payments,omitempty"`
+	// CreditScore is the bureau score at application time, 0 when unchecked
+	CreditScore int `json:"creditScore,omitempty"`
+	// Product is the code of the loan product applied for, if any
+	Product string `json:"product,omitempty"`
+	// Purpose is one of Purposes, empty when not given
+	Purpose string `json:"purpose,omitempty"`
+	// Amortization is one of Amortizations, empty for an annuity; it comes
+	// from the product applied for
+	Amortization string `json:"amortization,omitempty"`
+	// Frequency is one of Frequencies, empty for monthly repayments
+	Frequency string `json:"frequency,omitempty"`
+	// Prepayment is the penalty for settling early, from the product
+	// applied for; nil charges none
+	Prepayment *PrepaymentPenalty `json:"prepayment,omitempty"`
+	// Escrow is the escrow sub-account of a secured loan, if any
+	Escrow *Escrow `json:"escrow,omitempty"`
+	// Insurance is the credit insurance attached to the loan, if any
+	Insurance *Insurance `json:"insurance,omitempty"`
+	// TermsChanges are the recalculations of the schedule, oldest first
+	TermsChanges []TermsChange `json:"termsChanges,omitempty"`
+	// Decision is the risk engine's advice at application time, if any
+	Decision *Decision `json:"decision,omitempty"`
+	// Screening is the applicant's watchlist screening, if any
+	Screening *Screening `json:"screening,omitempty"`
+	// Currency is the ISO 4217 code the loan is booked in, empty for
+	// DefaultCurrency
+	Currency string `json:"currency,omitempty"`
+	// DisbursedAt is when the amount was paid out, zero until then
+	DisbursedAt time.Time `json:"disbursedAt"`
+	// DisbursementRef is the payment provider's transaction ID
+	DisbursementRef string `json:"disbursementRef,omitempty"`
+	// Version counts the updates stored. A repository refuses an update
+	// of a loan read before the latest one with ErrConflict.
+	Version int `json:"-"`
 	// Technical Debt - Missing Fields:
-	// Duration     int      // Loan duration in months
-	// PaymentSchedule []Payment
 	// LastModified time.Time
 	// ApprovedBy   string
-	// Purpose      string
 }
 
 // Validate checks if the loan data is valid
 func (l *Loan) Validate() error {
 	if l.Amount <= 0 {
-		return errors.New("loan amount must be positive")
+		return invalid("amount", "loan amount must be positive")
 	}
 	if l.CustomerID == "" {
-		return errors.New("customer ID is required")
+		return invalid("customerId", "customer ID is required")
 	}
 	if l.InterestRate < 0 {
-		return errors.New("interest rate cannot be negative")
+		return invalid("interestRate", "interest rate cannot be negative")
+	}
+	if l.TermMonths < 0 {
+		return invalid("termMonths", "term cannot be negative")
+	}
+	if l.Purpose != "" && !ValidPurpose(l.Purpose) {
+		return invalid("purpose", fmt.Sprintf("unknown purpose %q; purposes are %s", l.Purpose, strings.Join(Purposes, ", ")))
+	}
+	if l.Amortization != "" && !ValidAmortization(l.Amortization) {
+		return invalid("amortization", fmt.Sprintf("unknown amortization %q; methods are %s", l.Amortization, strings.Join(Amortizations, ", ")))
+	}
+	if l.Frequency != "" && !ValidFrequency(l.Frequency) {
+		return invalid("frequency", fmt.Sprintf("unknown frequency %q; frequencies are %s", l.Frequency, strings.Join(Frequencies, ", ")))
 	}
 	return nil
 }
 
-// Approve changes the loan status to approved
-func (l *Loan) Approve() error {
+// IsActive reports whether the loan is approved and still has money owed
+func (l *Loan) IsActive() bool {
+	return l.Status == StatusApproved && l.Balance > 0
+}
+
+// Clone returns a deep copy so callers cannot alias stored state
+func (l *Loan) Clone() *Loan {
+	c := *l
+	c.Schedule = append([]Installment(nil), l.Schedule...)
+	c.Payments = append([]Payment(nil), l.Payments...)
+	c.TermsChanges = append([]TermsChange(nil), l.TermsChanges...)
+	if l.Decision != nil {
+		c.Decision = l.Decision.Clone()
+	}
+	if l.Screening != nil {
+		c.Screening = l.Screening.Clone()
+	}
+	if l.Prepayment != nil {
+		c.Prepayment = l.Prepayment.Clone()
+	}
+	if l.Escrow != nil {
+		c.Escrow = l.Escrow.Clone()
+	}
+	if l.Insurance != nil {
+		c.Insurance = l.Insurance.Clone()
+	}
+	return &c
+}
+
+// Approve changes the loan status to approved and builds its repayment
+// schedule with opts, amortized by the loan's method and with any escrow
+// payment collected with each installment
+func (l *Loan) Approve(opts ...ScheduleOption) error {
 	// Technical Debt - Code Debt:
-	// - No validation before approval
 	// - No audit trail
-	// - No check for valid state transitions
+	if l.Status != StatusPending {
+		return fmt.Errorf("%w: cannot approve loan in status %q", ErrInvalidTransition, l.Status)
+	}
 	if err := l.Validate(); err != nil {
 		return err
 	}
 	l.Status = StatusApproved
+	l.ApprovedAt = time.Now().UTC()
+	l.Balance = l.Amount
+	if l.TermMonths > 0 {
+		l.Schedule = BuildSchedule(l.Amount, l.AnnualRate(), l.Installments(), l.ApprovedAt, l.scheduleOptions(opts)...)
+		l.spreadAddOns(l.ApprovedAt)
+	}
 	return nil
 }
 
+// scheduleOptions adds the loan's amortization method and repayment
+// frequency to opts
+func (l *Loan) scheduleOptions(opts []ScheduleOption) []ScheduleOption {
+	return append(slices.Clip(opts), Amortized(l.Amortization), Every(l.Frequency))
+}
+
+// Disburse records that the approved amount was paid out under the
+// provider transaction ref
+func (l *Loan) Disburse(ref string, at time.Time) error {
+	if l.Status != StatusApproved {
+		return fmt.Errorf("%w: cannot disburse loan in status %q", ErrInvalidTransition, l.Status)
+	}
+	if !l.DisbursedAt.IsZero() {
+		return fmt.Errorf("%w: loan was already disbursed", ErrInvalidTransition)
+	}
+	l.DisbursedAt = at.UTC()
+	l.DisbursementRef = ref
+	return nil
+}
+
+// Reject declines a pending loan with the given reason
+func (l *Loan) Reject(reason string) error {
+	if l.Status != StatusPending {
+		return fmt.Errorf("%w: cannot reject loan in status %q", ErrInvalidTransition, l.Status)
+	}
+	if reason == "" {
+		return invalid("reason", "rejection reason is required")
+	}
+	l.Status = StatusRejected
+	l.RejectionReason = reason
+	return nil
+}
+
+// AnnualRate returns the contractual rate, falling back to the tiered default
+func (l *Loan) AnnualRate() float64 {
+	if l.InterestRate > 0 {
+		return l.InterestRate
+	}
+	return DefaultRateTable.Rate(l.Amount)
+}
+
 // CalculateInterest calculates the interest amount for the loan
 func (l *Loan) CalculateInterest() float64 {
 	// Technical Debt - Code Debt:
-	// - Hard-coded interest rates
 	// - No consideration of loan duration
 	// - Oversimplified calculation
 	// - No risk assessment
-	if l.Amount > 10000 {
-		return l.Amount * 0.15
-	}
-	return l.Amount * 0.12
+	return l.Amount * DefaultRateTable.Rate(l.Amount)
 }
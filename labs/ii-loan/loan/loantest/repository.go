@@ -0,0 +1,241 @@
+// Package loantest provides test doubles for code built on the loan
+// package. Repository is a working in-memory loan.LoanRepository that can
+// be told to fail, slow down and report the calls it received, so services
+// can be unit tested without hand-rolled mocks:
+//
+//	repo := loantest.NewRepository(existing)
+//	repo.FailNext(loantest.Update, errors.New("disk full"))
+//	svc := loan.NewLoanService(repo, loan.WithRetryPolicy(retry.Never))
+//	_, err := svc.ApproveLoan(ctx, existing.ID)
+//	// err wraps "disk full"; repo.Count(loantest.Update) == 1
+package loantest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"loan"
+	"loan/memory"
+)
+
+// Method names a repository method
+type Method string
+
+// Repository methods
+const (
+	Save     Method = "Save"
+	FindByID Method = "FindByID"
+	Update   Method = "Update"
+	List     Method = "List"
+	Ping     Method = "Ping"
+)
+
+// Call records one repository call
+type Call struct {
+	Method Method
+	// LoanID is the loan saved, updated or looked up; empty for List and Ping
+	LoanID string
+	// Loan is a copy of the loan passed to Save or Update
+	Loan   *loan.Loan
+	Filter loan.Filter
+	// Err is what the call returned
+	Err error
+	At  time.Time
+}
+
+// Rule decides whether a call fails; a nil error lets it through
+type Rule func(Call) error
+
+// Repository is a controllable fake loan.LoanRepository. Stored loans are
+// copied like in the memory repository. Injected failures are evaluated
+// before the call touches the store, so a failed Save stores nothing. It
+// is safe for concurrent use.
+type Repository struct {
+	store *memory.LoanRepository
+
+	mu      sync.Mutex
+	once    map[Method][]error
+	rules   map[Method][]Rule
+	latency map[Method]time.Duration
+	calls   []Call
+}
+
+var _ loan.LoanRepository = (*Repository)(nil)
+
+// NewRepository creates a fake holding copies of loans
+func NewRepository(loans ...*loan.Loan) *Repository {
+	r := &Repository{store: memory.NewLoanRepository()}
+	r.Reset()
+	for _, l := range loans {
+		if err := r.store.Save(context.Background(), l); err != nil {
+			panic("loantest: " + err.Error())
+		}
+	}
+	return r
+}
+
+// Reset clears injected failures, latency and recorded calls. Stored loans
+// are kept.
+func (r *Repository) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.once = map[Method][]error{}
+	r.rules = map[Method][]Rule{}
+	r.latency = map[Method]time.Duration{}
+	r.calls = nil
+}
+
+// FailNext makes the next call of m return err. Repeated calls queue up
+// failures for consecutive calls, e.g. to exercise retries.
+func (r *Repository) FailNext(m Method, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.once[m] = append(r.once[m], err)
+}
+
+// Fail makes every call of m return err until Reset
+func (r *Repository) Fail(m Method, err error) {
+	r.FailWhen(m, func(Call) error { return err })
+}
+
+// FailWhen consults rule on every call of m until Reset, e.g. to fail only
+// for one loan ID
+func (r *Repository) FailWhen(m Method, rule Rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules[m] = append(r.rules[m], rule)
+}
+
+// SetLatency delays every call of m by d. The delay ends early with the
+// context's error if the caller gives up first.
+func (r *Repository) SetLatency(m Method, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latency[m] = d
+}
+
+// Calls returns the recorded calls in order, optionally only those of the
+// given methods
+func (r *Repository) Calls(methods ...Method) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Call
+	for _, c := range r.calls {
+		if len(methods) == 0 || contains(methods, c.Method) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Count returns how many times m was called
+func (r *Repository) Count(m Method) int {
+	return len(r.Calls(m))
+}
+
+// Loans returns copies of everything stored, ignoring injected failures
+func (r *Repository) Loans() []*loan.Loan {
+	loans, _ := r.store.List(context.Background(), loan.Filter{})
+	return loans
+}
+
+func contains(ms []Method, m Method) bool {
+	for _, x := range ms {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+// begin waits out the latency of the call and returns an injected
+// failure, if any
+func (r *Repository) begin(ctx context.Context, c Call) error {
+	r.mu.Lock()
+	delay := r.latency[c.Method]
+	var err error
+	if queued := r.once[c.Method]; len(queued) > 0 {
+		err, r.once[c.Method] = queued[0], queued[1:]
+	}
+	rules := append([]Rule(nil), r.rules[c.Method]...)
+	r.mu.Unlock()
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := rule(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) record(c Call, err error) {
+	c.Err = err
+	c.At = time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, c)
+}
+
+func (r *Repository) do(ctx context.Context, c Call, fn func() error) (err error) {
+	defer func() { r.record(c, err) }()
+	if err := r.begin(ctx, c); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// Save stores a new loan
+func (r *Repository) Save(ctx context.Context, l *loan.Loan) error {
+	return r.do(ctx, Call{Method: Save, LoanID: l.ID, Loan: l.Clone()}, func() error {
+		return r.store.Save(ctx, l)
+	})
+}
+
+// FindByID returns a copy of the stored loan
+func (r *Repository) FindByID(ctx context.Context, id string) (out *loan.Loan, err error) {
+	err = r.do(ctx, Call{Method: FindByID, LoanID: id}, func() error {
+		out, err = r.store.FindByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Update replaces an existing loan
+func (r *Repository) Update(ctx context.Context, l *loan.Loan) error {
+	return r.do(ctx, Call{Method: Update, LoanID: l.ID, Loan: l.Clone()}, func() error {
+		return r.store.Update(ctx, l)
+	})
+}
+
+// List returns copies of the loans matching the filter
+func (r *Repository) List(ctx context.Context, filter loan.Filter) (out []*loan.Loan, err error) {
+	err = r.do(ctx, Call{Method: List, Filter: filter}, func() error {
+		out, err = r.store.List(ctx, filter)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Ping implements health.Pinger so readiness checks can be driven too
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.do(ctx, Call{Method: Ping}, func() error { return r.store.Ping(ctx) })
+}
@@ -0,0 +1,192 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+)
+
+// KeyAccount is the attribute key for bank account numbers
+const KeyAccount = "account"
+
+// DefaultAmountThreshold is the amount above which logged amounts are
+// redacted unless the handler is configured otherwise
+const DefaultAmountThreshold = 100000
+
+// Redacted replaces values that are hidden completely
+const Redacted = "[redacted]"
+
+// Attribute keys whose plain values are masked by the masking handler, so
+// call sites that log "amount", loan.Amount are covered too
+var (
+	customerKeys = keySet(KeyCustomer, "customerId", "customer")
+	accountKeys  = keySet(KeyAccount, "bank_account", "bankAccount", "account_number")
+	amountKeys   = keySet("amount", "balance", "principal", "outstanding")
+	errorKeys    = keySet("error", "err")
+)
+
+func keySet(keys ...string) map[string]bool {
+	m := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		m[k] = true
+	}
+	return m
+}
+
+// longNumber matches digit runs long enough to be account or card numbers
+var longNumber = regexp.MustCompile(`\d{8,}`)
+
+// MaskAccount hides all but the last four digits of an account number
+func MaskAccount(number string) string {
+	return MaskCustomerID(number)
+}
+
+// MaskText hides account-like numbers inside free text such as error
+// messages, keeping their last four digits
+func MaskText(s string) string {
+	return longNumber.ReplaceAllStringFunc(s, MaskAccount)
+}
+
+// customerID and accountNumber are logged masked by any handler; only a
+// masking handler in unmasked mode reveals them
+type (
+	customerID    string
+	accountNumber string
+	amount        float64
+)
+
+func (id customerID) LogValue() slog.Value   { return slog.StringValue(MaskCustomerID(string(id))) }
+func (n accountNumber) LogValue() slog.Value { return slog.StringValue(MaskAccount(string(n))) }
+func (a amount) LogValue() slog.Value        { return maskAmount(float64(a), DefaultAmountThreshold) }
+
+func maskAmount(v, threshold float64) slog.Value {
+	if v > threshold {
+		return slog.StringValue(Redacted)
+	}
+	return slog.Float64Value(v)
+}
+
+// Account is the attribute for a bank account number, masked
+func Account(number string) slog.Attr {
+	return slog.Any(KeyAccount, accountNumber(number))
+}
+
+// Amount is an attribute for a money amount, redacted above the threshold
+func Amount(key string, v float64) slog.Attr {
+	return slog.Any(key, amount(v))
+}
+
+// Option configures the logger built by New
+type Option func(*maskOptions)
+
+type maskOptions struct {
+	threshold float64
+	unmasked  bool
+}
+
+// WithAmountThreshold redacts amounts above v instead of
+// DefaultAmountThreshold
+func WithAmountThreshold(v float64) Option {
+	return func(o *maskOptions) { o.threshold = v }
+}
+
+// Unmasked logs customer IDs, account numbers and amounts in clear. It is
+// meant for local debugging and must not be used where logs are shipped.
+func Unmasked() Option {
+	return func(o *maskOptions) { o.unmasked = true }
+}
+
+// maskingHandler masks sensitive attributes before they reach the wrapped
+// handler, whether they were logged through Customer, Account and Amount
+// or as plain values under a well-known key
+type maskingHandler struct {
+	slog.Handler
+	opts maskOptions
+}
+
+// NewMaskingHandler wraps h so sensitive attributes are masked, or shown
+// in clear with Unmasked
+func NewMaskingHandler(h slog.Handler, opts ...Option) slog.Handler {
+	o := maskOptions{threshold: DefaultAmountThreshold}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return maskingHandler{Handler: h, opts: o}
+}
+
+func (h maskingHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.mask(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, out)
+}
+
+func (h maskingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = h.mask(a)
+	}
+	return maskingHandler{Handler: h.Handler.WithAttrs(masked), opts: h.opts}
+}
+
+func (h maskingHandler) WithGroup(name string) slog.Handler {
+	return maskingHandler{Handler: h.Handler.WithGroup(name), opts: h.opts}
+}
+
+func (h maskingHandler) mask(a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindLogValuer {
+		switch v := a.Value.Any().(type) {
+		case customerID:
+			return h.clear(a, v.LogValue(), slog.StringValue(string(v)))
+		case accountNumber:
+			return h.clear(a, v.LogValue(), slog.StringValue(string(v)))
+		case amount:
+			return h.clear(a, maskAmount(float64(v), h.opts.threshold), slog.Float64Value(float64(v)))
+		}
+	}
+	a.Value = a.Value.Resolve()
+	if h.opts.unmasked {
+		return a
+	}
+	switch {
+	case a.Value.Kind() == slog.KindGroup:
+		group := a.Value.Group()
+		masked := make([]slog.Attr, len(group))
+		for i, g := range group {
+			masked[i] = h.mask(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(masked...)}
+	case customerKeys[a.Key] && a.Value.Kind() == slog.KindString:
+		a.Value = slog.StringValue(MaskCustomerID(a.Value.String()))
+	case accountKeys[a.Key] && a.Value.Kind() == slog.KindString:
+		a.Value = slog.StringValue(MaskAccount(a.Value.String()))
+	case amountKeys[a.Key]:
+		switch a.Value.Kind() {
+		case slog.KindFloat64:
+			a.Value = maskAmount(a.Value.Float64(), h.opts.threshold)
+		case slog.KindInt64:
+			a.Value = maskAmount(float64(a.Value.Int64()), h.opts.threshold)
+		}
+	case errorKeys[a.Key] || isError(a.Value):
+		a.Value = slog.StringValue(MaskText(a.Value.String()))
+	}
+	return a
+}
+
+// clear picks the masked or the raw value of a sensitive attribute
+func (h maskingHandler) clear(a slog.Attr, masked, raw slog.Value) slog.Attr {
+	if h.opts.unmasked {
+		return slog.Attr{Key: a.Key, Value: raw}
+	}
+	return slog.Attr{Key: a.Key, Value: masked}
+}
+
+func isError(v slog.Value) bool {
+	if v.Kind() != slog.KindAny {
+		return false
+	}
+	_, ok := v.Any().(error)
+	return ok
+}
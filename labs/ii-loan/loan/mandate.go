@@ -0,0 +1,258 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"loan/tracing"
+)
+
+// ErrMandateNotFound is returned when a loan has no direct debit mandate
+var ErrMandateNotFound = errors.New("mandate not found")
+
+// ErrDirectDebitDisabled is returned when mandates are used on a service
+// without a mandate repository or payment provider
+var ErrDirectDebitDisabled = errors.New("direct debit is not configured")
+
+// DefaultRetryDays are the days after a failed due-date debit on which it
+// is retried before the debit is given up
+var DefaultRetryDays = []int{3, 7}
+
+// Mandate states
+const (
+	MandateActive    = "active"
+	MandateCancelled = "cancelled"
+)
+
+// Debit attempt outcomes
+const (
+	DebitSucceeded = "succeeded"
+	DebitFailed    = "failed"
+)
+
+// Mandate authorises collecting a loan's installments from the customer's
+// bank account on their due dates
+type Mandate struct {
+	ID      string `json:"id"`
+	LoanID  string `json:"loanId"`
+	Account string `json:"account"`
+	Status  string `json:"status"`
+	// RetryDays are the days after the due date on which a failed debit
+	// is tried again, in increasing order
+	RetryDays   []int          `json:"retryDays"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	CancelledAt time.Time      `json:"cancelledAt"`
+	Attempts    []DebitAttempt `json:"attempts,omitempty"`
+}
+
+// DebitAttempt is one try at collecting an installment
+type DebitAttempt struct {
+	Installment int       `json:"installment"`
+	DueDate     time.Time `json:"dueDate"`
+	// Attempt counts from 1 for the due-date debit
+	Attempt     int       `json:"attempt"`
+	Amount      float64   `json:"amount"`
+	Status      string    `json:"status"`
+	PaymentID   string    `json:"paymentId,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	AttemptedAt time.Time `json:"attemptedAt"`
+}
+
+// MandateRepository stores direct debit mandates, at most one per loan
+type MandateRepository interface {
+	SaveMandate(ctx context.Context, m *Mandate) error
+	FindMandate(ctx context.Context, loanID string) (*Mandate, error)
+	// ActiveMandates returns the mandates still collecting
+	ActiveMandates(ctx context.Context) ([]*Mandate, error)
+}
+
+// Clone returns a deep copy so callers cannot alias stored state
+func (m *Mandate) Clone() *Mandate {
+	c := *m
+	c.RetryDays = append([]int(nil), m.RetryDays...)
+	c.Attempts = append([]DebitAttempt(nil), m.Attempts...)
+	return &c
+}
+
+// ValidateRetryDays checks days are positive and strictly increasing, so
+// no two attempts fall on the same day
+func ValidateRetryDays(days []int) error {
+	prev := 0
+	for _, d := range days {
+		if d <= prev {
+			return invalid("retryDays", "retry days must be positive and increasing")
+		}
+		prev = d
+	}
+	return nil
+}
+
+// Offsets returns the days after the due date of every attempt, starting
+// with the due date itself
+func (m *Mandate) Offsets() []int {
+	return append([]int{0}, m.RetryDays...)
+}
+
+// attempts returns the attempts made for installment number n
+func (m *Mandate) attempts(n int) []DebitAttempt {
+	var out []DebitAttempt
+	for _, a := range m.Attempts {
+		if a.Installment == n {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// NextAttempt returns the number and date of the next debit of inst, or
+// ok false when it was collected or every attempt has failed
+func (m *Mandate) NextAttempt(inst Installment) (attempt int, on time.Time, ok bool) {
+	made := m.attempts(inst.Number)
+	for _, a := range made {
+		if a.Status == DebitSucceeded {
+			return 0, time.Time{}, false
+		}
+	}
+	offsets := m.Offsets()
+	if len(made) >= len(offsets) {
+		return 0, time.Time{}, false
+	}
+	return len(made) + 1, inst.DueDate.AddDate(0, 0, offsets[len(made)]), true
+}
+
+// Final reports whether attempt is the last one the mandate allows
+func (m *Mandate) Final(attempt int) bool {
+	return attempt >= len(m.Offsets())
+}
+
+// WithMandates enables direct debit mandates stored in repo
+func WithMandates(repo MandateRepository) Option {
+	return func(s *LoanService) {
+		s.mandates = repo
+	}
+}
+
+// CreateMandate sets up direct debit of an active loan's installments from
+// account, replacing any earlier mandate. Nil retryDays uses
+// DefaultRetryDays.
+func (s *LoanService) CreateMandate(ctx context.Context, loanID, account string, retryDays []int) (_ *Mandate, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.CreateMandate", attrLoanID.String(loanID))
+	defer tracing.End(span, &err)
+
+	if s.mandates == nil || s.payments == nil {
+		return nil, ErrDirectDebitDisabled
+	}
+	if account == "" {
+		return nil, invalid("account", "account is required")
+	}
+	if retryDays == nil {
+		retryDays = DefaultRetryDays
+	}
+	if err := ValidateRetryDays(retryDays); err != nil {
+		return nil, err
+	}
+	loan, err := s.repo.FindByID(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if !loan.IsActive() {
+		return nil, fmt.Errorf("%w: cannot debit loan in status %q", ErrInvalidTransition, loan.Status)
+	}
+	m := &Mandate{
+		ID: NewID(), LoanID: loan.ID, Account: account, Status: MandateActive,
+		RetryDays: append([]int(nil), retryDays...), CreatedAt: time.Now().UTC(),
+	}
+	if err := s.mandates.SaveMandate(ctx, m); err != nil {
+		return nil, err
+	}
+	s.log(loan).InfoContext(ctx, "direct debit mandate created", "mandate_id", m.ID)
+	return m, nil
+}
+
+// GetMandate returns the direct debit mandate of a loan
+func (s *LoanService) GetMandate(ctx context.Context, loanID string) (_ *Mandate, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.GetMandate", attrLoanID.String(loanID))
+	defer tracing.End(span, &err)
+
+	if s.mandates == nil {
+		return nil, ErrDirectDebitDisabled
+	}
+	return s.mandates.FindMandate(ctx, loanID)
+}
+
+// CancelMandate stops direct debit of a loan
+func (s *LoanService) CancelMandate(ctx context.Context, loanID string) (_ *Mandate, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.CancelMandate", attrLoanID.String(loanID))
+	defer tracing.End(span, &err)
+
+	if s.mandates == nil {
+		return nil, ErrDirectDebitDisabled
+	}
+	m, err := s.mandates.FindMandate(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status == MandateCancelled {
+		return m, nil
+	}
+	m.Status, m.CancelledAt = MandateCancelled, time.Now().UTC()
+	return m, s.mandates.SaveMandate(ctx, m)
+}
+
+// MandateEraser returns a CustomerEraser cancelling the mandates of the
+// customer's loans and erasing the bank account and debit errors they
+// hold. Mandates are kept per loan, so the loans are looked up in loans.
+func MandateEraser(mandates MandateRepository, loans LoanRepository) CustomerEraser {
+	return mandateEraser{mandates: mandates, loans: loans}
+}
+
+type mandateEraser struct {
+	mandates MandateRepository
+	loans    LoanRepository
+}
+
+func (e mandateEraser) EraseCustomer(ctx context.Context, customerID, pseudonym string) error {
+	// Loans moved to the pseudonym by an interrupted run are erased too
+	for _, id := range []string{customerID, pseudonym} {
+		loans, err := e.loans.List(ctx, Filter{CustomerID: id})
+		if err != nil {
+			return err
+		}
+		for _, l := range loans {
+			m, err := e.mandates.FindMandate(ctx, l.ID)
+			if errors.Is(err, ErrMandateNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if !m.erase(time.Now().UTC()) {
+				continue
+			}
+			if err := e.mandates.SaveMandate(ctx, m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// erase cancels m and clears its account and debit errors, reporting
+// whether anything changed
+func (m *Mandate) erase(at time.Time) bool {
+	changed := false
+	if m.Status != MandateCancelled {
+		m.Status, m.CancelledAt, changed = MandateCancelled, at, true
+	}
+	if m.Account != erased {
+		m.Account, changed = erased, true
+	}
+	for i := range m.Attempts {
+		if a := &m.Attempts[i]; a.Error != "" && a.Error != erased {
+			a.Error, changed = erased, true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"loan"
+)
+
+// AgingStore keeps month-end aging snapshots keyed by month
+type AgingStore struct {
+	mu        sync.RWMutex
+	snapshots map[string][]loan.AgingRecord
+}
+
+// NewAgingStore creates an empty aging store
+func NewAgingStore() *AgingStore {
+	return &AgingStore{snapshots: make(map[string][]loan.AgingRecord)}
+}
+
+// SaveAging implements loan.AgingStore
+func (s *AgingStore) SaveAging(ctx context.Context, month time.Time, records []loan.AgingRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	records = slices.Clone(records)
+	sort.Slice(records, func(i, j int) bool { return records[i].LoanID < records[j].LoanID })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[loan.MonthStart(month).Format("2006-01")] = records
+	return nil
+}
+
+// Aging implements loan.AgingStore
+func (s *AgingStore) Aging(ctx context.Context, month time.Time) ([]loan.AgingRecord, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	records, ok := s.snapshots[loan.MonthStart(month).Format("2006-01")]
+	return slices.Clone(records), ok, nil
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"loan"
+)
+
+// CustomerRepository stores customers in a map guarded by a mutex
+type CustomerRepository struct {
+	mu        sync.RWMutex
+	customers map[string]loan.Customer
+}
+
+// NewCustomerRepository creates an empty repository
+func NewCustomerRepository() *CustomerRepository {
+	return &CustomerRepository{customers: make(map[string]loan.Customer)}
+}
+
+// Save stores a new customer
+func (r *CustomerRepository) Save(ctx context.Context, c *loan.Customer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.customers[c.ID]; ok {
+		return fmt.Errorf("customer %s already exists", c.ID)
+	}
+	r.customers[c.ID] = *c
+	return nil
+}
+
+// FindByID returns a copy of the stored customer
+func (r *CustomerRepository) FindByID(ctx context.Context, id string) (*loan.Customer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.customers[id]
+	if !ok {
+		return nil, loan.ErrCustomerNotFound
+	}
+	return &c, nil
+}
+
+// Update replaces an existing customer
+func (r *CustomerRepository) Update(ctx context.Context, c *loan.Customer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.customers[c.ID]; !ok {
+		return loan.ErrCustomerNotFound
+	}
+	r.customers[c.ID] = *c
+	return nil
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"loan"
+)
+
+// MandateRepository keeps direct debit mandates keyed by loan
+type MandateRepository struct {
+	mu       sync.RWMutex
+	mandates map[string]*loan.Mandate
+}
+
+// NewMandateRepository creates an empty repository
+func NewMandateRepository() *MandateRepository {
+	return &MandateRepository{mandates: make(map[string]*loan.Mandate)}
+}
+
+// SaveMandate stores m, replacing the loan's earlier mandate
+func (r *MandateRepository) SaveMandate(ctx context.Context, m *loan.Mandate) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mandates[m.LoanID] = m.Clone()
+	return nil
+}
+
+// FindMandate returns a copy of the loan's mandate
+func (r *MandateRepository) FindMandate(ctx context.Context, loanID string) (*loan.Mandate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.mandates[loanID]
+	if !ok {
+		return nil, loan.ErrMandateNotFound
+	}
+	return m.Clone(), nil
+}
+
+// ActiveMandates returns copies of the active mandates, oldest first
+func (r *MandateRepository) ActiveMandates(ctx context.Context) ([]*loan.Mandate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []*loan.Mandate
+	for _, m := range r.mandates {
+		if m.Status == loan.MandateActive {
+			out = append(out, m.Clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
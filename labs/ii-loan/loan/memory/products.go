@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"loan"
+)
+
+// ProductRepository stores the product catalog in a map guarded by a mutex
+type ProductRepository struct {
+	mu       sync.RWMutex
+	products map[string]loan.Product
+}
+
+// NewProductRepository creates a repository holding products
+func NewProductRepository(products ...loan.Product) *ProductRepository {
+	r := &ProductRepository{products: make(map[string]loan.Product, len(products))}
+	for _, p := range products {
+		r.products[p.Code] = p.Clone()
+	}
+	return r
+}
+
+// Product implements loan.ProductCatalog
+func (r *ProductRepository) Product(ctx context.Context, code string) (loan.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return loan.Product{}, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.products[code]
+	if !ok {
+		return loan.Product{}, loan.ErrProductNotFound
+	}
+	return p.Clone(), nil
+}
+
+// Products returns the catalog ordered by code
+func (r *ProductRepository) Products(ctx context.Context) ([]loan.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]loan.Product, 0, len(r.products))
+	for _, p := range r.products {
+		out = append(out, p.Clone())
+	}
+	slices.SortFunc(out, func(a, b loan.Product) int { return strings.Compare(a.Code, b.Code) })
+	return out, nil
+}
+
+// SaveProduct adds p or replaces the product with its code
+func (r *ProductRepository) SaveProduct(ctx context.Context, p loan.Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.products[p.Code] = p.Clone()
+	return nil
+}
+
+// DeleteProduct removes a product
+func (r *ProductRepository) DeleteProduct(ctx context.Context, code string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.products[code]; !ok {
+		return loan.ErrProductNotFound
+	}
+	delete(r.products, code)
+	return nil
+}
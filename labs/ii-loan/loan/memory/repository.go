@@ -0,0 +1,117 @@
+// Package memory provides in-memory implementations of the loan repositories
+// for labs, demos and local development.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"loan"
+)
+
+// LoanRepository stores loans in a map guarded by a mutex.
+// Loans are copied on the way in and out so callers never share state.
+type LoanRepository struct {
+	mu    sync.RWMutex
+	loans map[string]*loan.Loan
+}
+
+// NewLoanRepository creates an empty repository
+func NewLoanRepository() *LoanRepository {
+	return &LoanRepository{loans: make(map[string]*loan.Loan)}
+}
+
+// Ping reports the repository as reachable; it is held in process
+func (r *LoanRepository) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Save stores a new loan
+func (r *LoanRepository) Save(ctx context.Context, l *loan.Loan) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.loans[l.ID]; ok {
+		return fmt.Errorf("loan %s already exists", l.ID)
+	}
+	r.loans[l.ID] = l.Clone()
+	return nil
+}
+
+// FindByID returns a copy of the stored loan
+func (r *LoanRepository) FindByID(ctx context.Context, id string) (*loan.Loan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	l, ok := r.loans[id]
+	if !ok {
+		return nil, loan.ErrLoanNotFound
+	}
+	return l.Clone(), nil
+}
+
+// Update replaces an existing loan unless it was updated since l was read
+func (r *LoanRepository) Update(ctx context.Context, l *loan.Loan) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.loans[l.ID]
+	if !ok {
+		return loan.ErrLoanNotFound
+	}
+	if stored.Version != l.Version {
+		return loan.ErrConflict
+	}
+	l.Version++
+	r.loans[l.ID] = l.Clone()
+	return nil
+}
+
+// List returns copies of all loans matching the filter ordered by creation time
+func (r *LoanRepository) List(ctx context.Context, filter loan.Filter) ([]*loan.Loan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []*loan.Loan
+	for _, l := range r.loans {
+		if filter.Match(l) {
+			out = append(out, l.Clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, nil
+}
+
+// GroupBy implements loan.Grouper
+func (r *LoanRepository) GroupBy(ctx context.Context, filter loan.Filter, d loan.Dimension) ([]loan.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var matched []*loan.Loan
+	for _, l := range r.loans {
+		if filter.Match(l) {
+			matched = append(matched, l)
+		}
+	}
+	return loan.GroupLoans(matched, d), nil
+}
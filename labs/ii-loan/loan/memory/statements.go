@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"loan"
+)
+
+// StatementStore keeps generated statements keyed by loan and month
+type StatementStore struct {
+	mu         sync.RWMutex
+	statements map[string]loan.Statement
+}
+
+// NewStatementStore creates an empty statement store
+func NewStatementStore() *StatementStore {
+	return &StatementStore{statements: make(map[string]loan.Statement)}
+}
+
+func statementKey(loanID string, period time.Time) string {
+	return loanID + "/" + loan.MonthStart(period).Format("2006-01")
+}
+
+// SaveStatement stores st, replacing any earlier statement for the same month
+func (s *StatementStore) SaveStatement(ctx context.Context, st loan.Statement) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statements[statementKey(st.LoanID, st.Period)] = st
+	return nil
+}
+
+// FindStatement returns the statement for a loan and month
+func (s *StatementStore) FindStatement(ctx context.Context, loanID string, period time.Time) (loan.Statement, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return loan.Statement{}, false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st, ok := s.statements[statementKey(loanID, period)]
+	return st, ok, nil
+}
+
+// EraseCustomer implements loan.CustomerEraser
+func (s *StatementStore) EraseCustomer(ctx context.Context, customerID, pseudonym string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, st := range s.statements {
+		if st.CustomerID == customerID {
+			st.CustomerID = pseudonym
+			s.statements[k] = st
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"loan"
+)
+
+// TransferRepository keeps loan transfers keyed by ID
+type TransferRepository struct {
+	mu        sync.RWMutex
+	transfers map[string]*loan.Transfer
+}
+
+// NewTransferRepository creates an empty repository
+func NewTransferRepository() *TransferRepository {
+	return &TransferRepository{transfers: make(map[string]*loan.Transfer)}
+}
+
+// SaveTransfer stores a copy of t, replacing an earlier version of it
+func (r *TransferRepository) SaveTransfer(ctx context.Context, t *loan.Transfer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transfers[t.ID] = t.Clone()
+	return nil
+}
+
+// FindTransfer returns a copy of a transfer
+func (r *TransferRepository) FindTransfer(ctx context.Context, id string) (*loan.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.transfers[id]
+	if !ok {
+		return nil, loan.ErrTransferNotFound
+	}
+	return t.Clone(), nil
+}
+
+// Transfers returns copies of a loan's transfers, oldest first
+func (r *TransferRepository) Transfers(ctx context.Context, loanID string) ([]*loan.Transfer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []*loan.Transfer
+	for _, t := range r.transfers {
+		if t.LoanID == loanID {
+			out = append(out, t.Clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RequestedAt.Before(out[j].RequestedAt) })
+	return out, nil
+}
+
+// EraseCustomer implements loan.CustomerEraser
+func (r *TransferRepository) EraseCustomer(ctx context.Context, customerID, pseudonym string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.transfers {
+		t.Pseudonymize(customerID, pseudonym)
+	}
+	return nil
+}
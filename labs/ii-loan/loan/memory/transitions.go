@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"loan"
+)
+
+// TransitionStore keeps the bucket transitions recorded for each month
+type TransitionStore struct {
+	mu     sync.RWMutex
+	months map[string][]loan.BucketTransition
+}
+
+// NewTransitionStore creates an empty transition store
+func NewTransitionStore() *TransitionStore {
+	return &TransitionStore{months: make(map[string][]loan.BucketTransition)}
+}
+
+// SaveTransitions implements loan.TransitionStore
+func (s *TransitionStore) SaveTransitions(ctx context.Context, month time.Time, transitions []loan.BucketTransition) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	transitions = slices.Clone(transitions)
+	sort.Slice(transitions, func(i, j int) bool { return transitions[i].LoanID < transitions[j].LoanID })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.months[loan.MonthStart(month).Format("2006-01")] = transitions
+	return nil
+}
+
+// Transitions implements loan.TransitionStore
+func (s *TransitionStore) Transitions(ctx context.Context, month time.Time) ([]loan.BucketTransition, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	transitions, ok := s.months[loan.MonthStart(month).Format("2006-01")]
+	return slices.Clone(transitions), ok, nil
+}
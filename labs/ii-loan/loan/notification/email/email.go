@@ -0,0 +1,214 @@
+// Package email is a notification channel that renders HTML emails from
+// templates and hands them to a Sender.
+package email
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"loan/i18n"
+	"loan/notification"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// kinds lists the notification kinds that have a template
+var kinds = []notification.Kind{
+	notification.KindLoanApproved,
+	notification.KindLoanRejected,
+	notification.KindPaymentDue,
+	notification.KindPaymentOverdue,
+}
+
+// funcs are the template functions, rendering dates, statuses and
+// rejection reasons in locale
+func funcs(cat *i18n.Catalog, locale string) template.FuncMap {
+	return template.FuncMap{
+		"money":   func(v float64) string { return fmt.Sprintf("%.2f", v) },
+		"percent": func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) },
+		"date":    func(t time.Time) string { return cat.Date(locale, t) },
+		"status":  func(s string) string { return cat.Status(locale, s) },
+		"reason":  func(s string) string { return cat.Reason(locale, s) },
+	}
+}
+
+// Message is a rendered email
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	HTML    string
+}
+
+// Sender transmits rendered emails
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Notifier is a notification.Notifier delivering templated emails in the
+// recipient's locale
+type Notifier struct {
+	from    string
+	sender  Sender
+	catalog *i18n.Catalog
+	// templates holds the templates of each locale by kind
+	templates map[string]map[notification.Kind]*template.Template
+}
+
+// Option configures a Notifier
+type Option func(*Notifier)
+
+// WithCatalog renders dates, statuses and reasons from cat instead of the
+// built-in i18n catalog
+func WithCatalog(cat *i18n.Catalog) Option {
+	return func(n *Notifier) { n.catalog = cat }
+}
+
+// NewNotifier parses the embedded templates and returns an email channel.
+// The English templates are in templates/; a translation lives in
+// templates/<locale>/, and recipients whose locale has none get English.
+func NewNotifier(from string, sender Sender, opts ...Option) (*Notifier, error) {
+	n := &Notifier{from: from, sender: sender, catalog: i18n.Default(), templates: make(map[string]map[notification.Kind]*template.Template)}
+	for _, opt := range opts {
+		opt(n)
+	}
+	dirs := map[string]string{i18n.DefaultLocale: "templates"}
+	entries, err := templateFS.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			dirs[e.Name()] = "templates/" + e.Name()
+		}
+	}
+	for locale, dir := range dirs {
+		n.templates[locale] = make(map[notification.Kind]*template.Template)
+		for _, kind := range kinds {
+			t, err := template.New(string(kind)).Funcs(funcs(n.catalog, locale)).ParseFS(templateFS,
+				dir+"/layout.html", dir+"/"+string(kind)+".html")
+			if err != nil {
+				return nil, fmt.Errorf("email: parse %s %s template: %w", locale, kind, err)
+			}
+			n.templates[locale][kind] = t
+		}
+	}
+	return n, nil
+}
+
+// Channel implements notification.Notifier
+func (n *Notifier) Channel() string { return "email" }
+
+// Notify renders and sends the notification
+func (n *Notifier) Notify(ctx context.Context, note notification.Notification) error {
+	if note.Recipient.Email == "" {
+		return fmt.Errorf("email: customer %s has no email address", note.Recipient.CustomerID)
+	}
+	msg, err := n.Render(note)
+	if err != nil {
+		return err
+	}
+	return n.sender.Send(ctx, msg)
+}
+
+// templateData is what templates see as "."
+type templateData struct {
+	Recipient notification.Contact
+	LoanID    string
+	Data      any
+}
+
+// Render produces the email for a notification without sending it
+func (n *Notifier) Render(note notification.Notification) (Message, error) {
+	byKind, ok := n.templates[n.catalog.Negotiate(note.Recipient.Locale)]
+	if !ok {
+		byKind = n.templates[i18n.DefaultLocale]
+	}
+	t, ok := byKind[note.Kind]
+	if !ok {
+		return Message{}, fmt.Errorf("email: no template for %s", note.Kind)
+	}
+	data := templateData{Recipient: note.Recipient, LoanID: note.LoanID, Data: note.Event.Data}
+
+	var subject, body bytes.Buffer
+	if err := t.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := t.ExecuteTemplate(&body, "layout", data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		From:    n.from,
+		To:      note.Recipient.Email,
+		Subject: html.UnescapeString(strings.TrimSpace(subject.String())),
+		HTML:    body.String(),
+	}, nil
+}
+
+// rfc822 formats msg as a MIME message
+func rfc822(msg Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+	b.WriteString(msg.HTML)
+	return b.Bytes()
+}
+
+// SandboxSender writes each email as an .eml file in Dir instead of sending
+// it, so lab participants can open the results in a mail client or browser.
+type SandboxSender struct {
+	Dir string
+	seq atomic.Int64
+}
+
+// Send writes msg to a new file in Dir
+func (s *SandboxSender) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%04d-%s.eml", time.Now().Format("20060102T150405"), s.seq.Add(1), sanitize(msg.To))
+	return os.WriteFile(filepath.Join(s.Dir, name), rfc822(msg), 0o644)
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// SMTPSender delivers emails through an SMTP relay
+type SMTPSender struct {
+	Addr string
+	Auth smtp.Auth
+}
+
+// Send relays msg via net/smtp. The context is only checked before sending
+// because net/smtp does not support cancellation.
+func (s SMTPSender) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, s.Auth, msg.From, []string{msg.To}, rfc822(msg))
+}
@@ -0,0 +1,198 @@
+// Package notification turns loan domain events into customer
+// notifications and fans them out to the configured channels.
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"loan"
+	"loan/logging"
+)
+
+// Kind identifies what a notification is about
+type Kind string
+
+// Notification kinds
+const (
+	KindLoanApproved   Kind = "loan_approved"
+	KindLoanRejected   Kind = "loan_rejected"
+	KindPaymentDue     Kind = "payment_due"
+	KindPaymentOverdue Kind = "payment_overdue"
+)
+
+// kindForEvent maps the domain events that notify customers to a kind
+var kindForEvent = map[loan.EventType]Kind{
+	loan.EventLoanApproved:   KindLoanApproved,
+	loan.EventLoanRejected:   KindLoanRejected,
+	loan.EventInstallmentDue: KindPaymentDue,
+	loan.EventPaymentOverdue: KindPaymentOverdue,
+}
+
+// Contact holds the details needed to reach a customer
+type Contact struct {
+	CustomerID string
+	Name       string
+	Email      string
+	Phone      string
+	// Locale is the language tag the customer reads, such as "th";
+	// empty for the default
+	Locale string
+}
+
+// Notification is a message to a single customer
+type Notification struct {
+	Kind      Kind
+	LoanID    string
+	Recipient Contact
+	// Event is the domain event that triggered the notification; its Data
+	// carries kind-specific details such as the installment due.
+	Event loan.Event
+}
+
+// Notifier delivers notifications over one channel (email, SMS, ...)
+type Notifier interface {
+	Channel() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// ContactResolver looks up how to reach a customer
+type ContactResolver interface {
+	Contact(ctx context.Context, customerID string) (Contact, error)
+}
+
+// StaticContacts is a ContactResolver backed by a map
+type StaticContacts map[string]Contact
+
+// Contact returns the stored contact or an error if unknown
+func (s StaticContacts) Contact(_ context.Context, customerID string) (Contact, error) {
+	c, ok := s[customerID]
+	if !ok {
+		return Contact{}, fmt.Errorf("notification: no contact for customer %s", customerID)
+	}
+	return c, nil
+}
+
+// CustomerContacts resolves contacts from the customers on file.
+// Anonymized customers cannot be reached.
+func CustomerContacts(customers loan.CustomerRepository) ContactResolver {
+	return customerContacts{customers}
+}
+
+type customerContacts struct {
+	customers loan.CustomerRepository
+}
+
+func (r customerContacts) Contact(ctx context.Context, customerID string) (Contact, error) {
+	c, err := r.customers.FindByID(ctx, customerID)
+	if err != nil {
+		return Contact{}, fmt.Errorf("notification: customer %s: %w", customerID, err)
+	}
+	if c.IsAnonymized() {
+		return Contact{}, fmt.Errorf("notification: customer %s was anonymized", customerID)
+	}
+	return Contact{CustomerID: c.ID, Name: c.Name, Email: c.Email, Phone: c.Phone}, nil
+}
+
+type route struct {
+	notifier Notifier
+	kinds    []Kind
+}
+
+// Dispatcher is a loan.EventPublisher that converts events into
+// notifications and sends them through every channel routed for the kind.
+type Dispatcher struct {
+	contacts ContactResolver
+	mu       sync.RWMutex
+	routes   []route
+}
+
+// NewDispatcher creates a dispatcher resolving recipients with contacts
+func NewDispatcher(contacts ContactResolver) *Dispatcher {
+	return &Dispatcher{contacts: contacts}
+}
+
+// Register adds a channel for the given kinds, or for every kind if none are given
+func (d *Dispatcher) Register(n Notifier, kinds ...Kind) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.routes = append(d.routes, route{notifier: n, kinds: kinds})
+}
+
+// Publish implements loan.EventPublisher. Events that do not map to a
+// notification kind are ignored.
+func (d *Dispatcher) Publish(ctx context.Context, event loan.Event) error {
+	kind, ok := kindForEvent[event.Type]
+	if !ok {
+		return nil
+	}
+	customerID, err := customerOf(event)
+	if err != nil {
+		return err
+	}
+	contact, err := d.contacts.Contact(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	return d.Dispatch(ctx, Notification{Kind: kind, LoanID: event.LoanID, Recipient: contact, Event: event})
+}
+
+// Dispatch sends n to every matching channel concurrently and joins the errors
+func (d *Dispatcher) Dispatch(ctx context.Context, n Notification) error {
+	d.mu.RLock()
+	var targets []Notifier
+	for _, r := range d.routes {
+		if len(r.kinds) == 0 || slices.Contains(r.kinds, n.Kind) {
+			targets = append(targets, r.notifier)
+		}
+	}
+	d.mu.RUnlock()
+
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t Notifier) {
+			defer wg.Done()
+			if err := t.Notify(ctx, n); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", t.Channel(), err)
+			}
+		}(i, t)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// customerOf extracts the customer ID carried by the supported events
+func customerOf(event loan.Event) (string, error) {
+	switch data := event.Data.(type) {
+	case *loan.Loan:
+		return data.CustomerID, nil
+	case interface{ Customer() string }:
+		return data.Customer(), nil
+	default:
+		return "", fmt.Errorf("notification: event %s carries no customer", event.Type)
+	}
+}
+
+// LogNotifier writes notifications to a logger, handy for labs
+type LogNotifier struct {
+	Logger *slog.Logger
+}
+
+// Channel implements Notifier
+func (LogNotifier) Channel() string { return "log" }
+
+// Notify implements Notifier
+func (l LogNotifier) Notify(ctx context.Context, n Notification) error {
+	logger := l.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.InfoContext(ctx, "notify", "kind", n.Kind, logging.Loan(n.LoanID), logging.Customer(n.Recipient.CustomerID))
+	return nil
+}
@@ -0,0 +1,110 @@
+// Package openbanking provides loan.AccountDataProvider implementations
+// for labs and development: a fake bank holding the transactions added to
+// it and generating a plausible history for anyone else.
+package openbanking
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"loan"
+)
+
+// Fake is an in-memory open banking provider. Customers without added
+// transactions get a generated history that only depends on their ID.
+type Fake struct {
+	mu  sync.Mutex
+	txs map[string][]loan.BankTransaction
+	now func() time.Time
+}
+
+// NewFake creates a provider with no added transactions
+func NewFake() *Fake {
+	return &Fake{txs: make(map[string][]loan.BankTransaction), now: time.Now}
+}
+
+// Add books transactions on the customer's accounts. Once a customer has
+// any, no history is generated for them.
+func (f *Fake) Add(customerID string, txs ...loan.BankTransaction) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.txs[customerID] = append(f.txs[customerID], txs...)
+}
+
+// Transactions implements loan.AccountDataProvider
+func (f *Fake) Transactions(ctx context.Context, customerID string, since time.Time) ([]loan.BankTransaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	txs, ok := f.txs[customerID]
+	f.mu.Unlock()
+	if !ok {
+		txs = Generate(customerID, since, f.now())
+	}
+	var out []loan.BankTransaction
+	for _, tx := range txs {
+		if !tx.BookedAt.Before(since) {
+			out = append(out, tx)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].BookedAt.Before(out[j].BookedAt) })
+	return out, nil
+}
+
+// Generate makes up the bank history of customerID between from and to: a
+// monthly salary, rent, card spending and, for some customers, a loan
+// repayment. Salaries and spending vary by customer, and some customers
+// are paid irregularly. A month's transactions are the same whatever the
+// range asked for.
+func Generate(customerID string, from, to time.Time) []loan.BankTransaction {
+	h := fnv.New64a()
+	h.Write([]byte(customerID))
+	seed := h.Sum64()
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+
+	salary := float64(15000 + rng.IntN(18)*5000)
+	rent := salary * (0.15 + rng.Float64()*0.2)
+	var debt float64
+	if rng.IntN(3) == 0 {
+		debt = salary * (0.1 + rng.Float64()*0.4)
+	}
+	irregular := rng.IntN(4) == 0
+	account := fmt.Sprintf("%010d", seed%1e10)
+
+	var txs []loan.BankTransaction
+	book := func(at time.Time, amount float64, desc, category string) {
+		if at.Before(from) || at.After(to) {
+			return
+		}
+		txs = append(txs, loan.BankTransaction{
+			ID: fmt.Sprintf("%s-%d", account, len(txs)+1), Account: account, BookedAt: at,
+			Amount: math.Round(amount*100) / 100, Description: desc, Category: category,
+		})
+	}
+	start := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for m := start; !m.After(to); m = m.AddDate(0, 1, 0) {
+		month := uint64(m.Year()*12 + int(m.Month()))
+		rng := rand.New(rand.NewPCG(seed, month))
+		pay := salary
+		if irregular {
+			pay = salary * rng.Float64() * 2
+		}
+		book(m.AddDate(0, 0, 24), pay, "SALARY", loan.CategorySalary)
+		book(m, -rent, "RENT", "")
+		if debt > 0 {
+			book(m.AddDate(0, 0, 4), -debt, "LOAN REPAYMENT", loan.CategoryDebt)
+		}
+		for i := 0; i < 4; i++ {
+			book(m.AddDate(0, 0, 2+i*7), -salary*(0.03+rng.Float64()*0.07), "CARD PURCHASE", "")
+		}
+		book(m.AddDate(0, 0, 26), -salary*0.1, "TO SAVINGS", loan.CategoryTransfer)
+	}
+	return txs
+}
@@ -0,0 +1,95 @@
+package loan
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Payment is a repayment received against a loan
+type Payment struct {
+	ID        string    `json:"id"`
+	LoanID    string    `json:"loanId"`
+	Amount    float64   `json:"amount"`
+	Interest  float64   `json:"interest"`
+	Principal float64   `json:"principal"`
+	PaidAt    time.Time `json:"paidAt"`
+	// Penalty is the prepayment penalty of an early settlement, part of
+	// Amount but neither interest nor principal
+	Penalty float64 `json:"penalty,omitempty"`
+	// Escrow is the part of Amount deposited in the loan's escrow
+	Escrow float64 `json:"escrow,omitempty"`
+	// Premium is the part of Amount paid for the loan's credit insurance
+	Premium float64 `json:"premium,omitempty"`
+}
+
+// ApplyPayment settles accrued interest first, then principal, and marks
+// installments paid oldest first. The escrow share of the installments it
+// pays is deposited in escrow, as is anything beyond the balance on loans
+// with escrow, and their insurance premium paid; the loan's part of an
+// installment is paid before either.
+func (l *Loan) ApplyPayment(amount float64, at time.Time) (Payment, error) {
+	return l.applyPayment(amount, at, true)
+}
+
+// applyPayment applies amount, collecting escrow and premiums when collect
+// is set
+func (l *Loan) applyPayment(amount float64, at time.Time, collect bool) (Payment, error) {
+	if !l.IsActive() {
+		return Payment{}, fmt.Errorf("%w: loan in status %q has nothing to repay", ErrInvalidTransition, l.Status)
+	}
+	amount = round2(amount)
+	if amount <= 0 {
+		return Payment{}, invalid("amount", "payment amount must be positive")
+	}
+	owed := round2(l.Balance)
+	if collect {
+		owed = round2(owed + l.addOnsDue())
+	}
+	if amount > owed {
+		return Payment{}, invalid("amount", fmt.Sprintf("payment %.2f exceeds outstanding balance %.2f", amount, owed))
+	}
+
+	var escrow, premium float64
+	remaining := amount
+	for i := range l.Schedule {
+		if remaining <= 0 {
+			break
+		}
+		inst := &l.Schedule[i]
+		applied := math.Min(inst.Outstanding(), remaining)
+		if collect {
+			e, p := inst.addOns(applied)
+			escrow, premium = escrow+e, premium+p
+		}
+		inst.Paid = round2(inst.Paid + applied)
+		remaining = round2(remaining - applied)
+	}
+	premium = round2(premium)
+	switch {
+	case collect && l.Escrow != nil:
+		escrow = round2(math.Max(escrow, amount-premium-l.Balance))
+	case collect:
+		premium = round2(math.Max(premium, amount-l.Balance))
+	}
+
+	repaid := round2(amount - escrow - premium)
+	interest := math.Min(repaid, l.AccruedInterest)
+	p := Payment{
+		ID:        NewID(),
+		LoanID:    l.ID,
+		Amount:    amount,
+		Interest:  round2(interest),
+		Principal: round2(repaid - interest),
+		PaidAt:    at.UTC(),
+		Escrow:    escrow,
+		Premium:   premium,
+	}
+	l.AccruedInterest = round2(l.AccruedInterest - p.Interest)
+	l.Balance = round2(l.Balance - repaid)
+	if escrow > 0 {
+		l.Escrow.record(EscrowTransaction{Type: EscrowDeposit, PaymentID: p.ID, Amount: escrow, At: at})
+	}
+	l.Payments = append(l.Payments, p)
+	return p, nil
+}
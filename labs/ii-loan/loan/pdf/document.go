@@ -0,0 +1,126 @@
+// Package pdf renders customer documents, such as monthly loan statements,
+// as PDF. Documents are laid out from text templates line by line with the
+// PDF standard fonts, so no font files or third party libraries are needed.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Font is one of the standard Type 1 fonts every PDF reader provides
+type Font int
+
+// Fonts used by documents
+const (
+	Regular Font = iota
+	Bold
+	Mono
+)
+
+var baseFonts = []string{"Helvetica", "Helvetica-Bold", "Courier"}
+
+// A4 page size and margins in points
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 50
+)
+
+// Document is a multi-page text document. Lines flow down the page and
+// onto a new one when the bottom margin is reached.
+type Document struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+// NewDocument creates a document with one empty page
+func NewDocument() *Document {
+	d := &Document{}
+	d.NewPage()
+	return d
+}
+
+// NewPage starts a new page
+func (d *Document) NewPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+// Line writes s on the next line in font at size points
+func (d *Document) Line(font Font, size float64, s string) {
+	leading := size * 1.4
+	if d.y-leading < margin {
+		d.NewPage()
+	}
+	d.y -= leading
+	page := d.pages[len(d.pages)-1]
+	fmt.Fprintf(page, "BT /F%d %g Tf %d %g Td (%s) Tj ET\n", font+1, size, margin, d.y, escape(s))
+}
+
+// Space leaves size points of vertical space
+func (d *Document) Space(size float64) {
+	d.y -= size
+}
+
+// escape quotes s for a PDF literal string. The standard fonts are
+// WinAnsi encoded; runes outside Latin-1 are replaced with '?'.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString("    ")
+		case r < 0x20 || r > 0xff:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+// WriteTo writes the document as a PDF file
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects: 1 catalog, 2 page tree, one per font, then a page and its
+	// content stream for each page.
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	firstPage := 3 + len(baseFonts)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	var fonts strings.Builder
+	for i, name := range baseFonts {
+		obj("<< /Type /Font /Subtype /Type1 /BaseFont /" + name + " /Encoding /WinAnsiEncoding >>")
+		fmt.Fprintf(&fonts, "/F%d %d 0 R ", i+1, 3+i)
+	}
+	for i, content := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << %s>> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, fonts.String(), firstPage+2*i+1))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	n, err := w.Write(b.Bytes())
+	return int64(n), err
+}
@@ -0,0 +1,60 @@
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"loan"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var funcs = template.FuncMap{
+	"money":   func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"percent": func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) },
+	"date":    func(t time.Time) string { return t.UTC().Format(time.DateOnly) },
+	"month":   func(t time.Time) string { return t.UTC().Format("January 2006") },
+}
+
+var statementTemplate = template.Must(template.New("statement.tmpl").Funcs(funcs).ParseFS(templateFS, "templates/statement.tmpl"))
+
+// Statement writes st as a PDF document
+func Statement(w io.Writer, st loan.Statement) error {
+	var text bytes.Buffer
+	if err := statementTemplate.Execute(&text, st); err != nil {
+		return fmt.Errorf("pdf: statement template: %w", err)
+	}
+	_, err := Layout(&text).WriteTo(w)
+	return err
+}
+
+// Layout lays out template output one line at a time: "# " starts a
+// title, "## " a section heading, a blank line leaves a gap and anything
+// else is set in a monospaced font so printf-aligned columns line up.
+func Layout(r io.Reader) *Document {
+	d := NewDocument()
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " ")
+		switch {
+		case strings.HasPrefix(line, "## "):
+			d.Space(8)
+			d.Line(Bold, 11, strings.TrimPrefix(line, "## "))
+		case strings.HasPrefix(line, "# "):
+			d.Line(Bold, 16, strings.TrimPrefix(line, "# "))
+			d.Space(6)
+		case line == "":
+			d.Space(6)
+		default:
+			d.Line(Mono, 9, line)
+		}
+	}
+	return d
+}
@@ -0,0 +1,149 @@
+// Package pool groups loans into pools for securitization. A pool has
+// eligibility criteria every member must meet when added; once frozen its
+// membership and the balances it was cut at are fixed, and the pool's
+// cashflows are reported from its members' schedules and repayments.
+//
+//	pools := pool.NewService(pool.NewMemory(), repo, pool.WithCollateral(values))
+//	p, _ := pools.Create(ctx, "2026-A", pool.Criteria{Grades: []string{"A", "B"}, MinSeasoningMonths: 6, MaxLTV: 0.8})
+//	pools.Fill(ctx, p.ID)
+//	pools.Freeze(ctx, p.ID)
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pool states
+const (
+	StatusOpen   = "open"
+	StatusFrozen = "frozen"
+)
+
+var (
+	// ErrNotFound is returned for unknown pools
+	ErrNotFound = errors.New("pool: not found")
+	// ErrFrozen is returned when changing the members of a frozen pool
+	ErrFrozen = errors.New("pool: pool is frozen")
+	// ErrAlreadyPooled is returned for loans that belong to another pool
+	ErrAlreadyPooled = errors.New("pool: loan is in another pool")
+	// ErrEmpty is returned when freezing a pool without members
+	ErrEmpty = errors.New("pool: pool has no loans")
+)
+
+// Criteria are the conditions a loan must meet to join a pool. Zero
+// fields do not restrict.
+type Criteria struct {
+	// Grades are the risk grades admitted
+	Grades []string `json:"grades,omitempty"`
+	// Products are the loan products admitted
+	Products []string `json:"products,omitempty"`
+	// MinSeasoningMonths is how many whole months a loan must have been
+	// on the book since approval
+	MinSeasoningMonths int `json:"minSeasoningMonths,omitempty"`
+	// MaxLTV is the highest loan-to-value admitted; loans without a
+	// collateral value are not eligible when it is set
+	MaxLTV float64 `json:"maxLtv,omitempty"`
+	// MaxDaysPastDue admits loans at most this late; zero admits only
+	// current loans
+	MaxDaysPastDue int `json:"maxDaysPastDue"`
+}
+
+// Member is a loan of a pool. Balance is the balance it joined at,
+// replaced by its balance at the freeze.
+type Member struct {
+	LoanID  string    `json:"loanId"`
+	Grade   string    `json:"grade"`
+	Product string    `json:"product,omitempty"`
+	Balance float64   `json:"balance"`
+	Rate    float64   `json:"rate"`
+	LTV     *float64  `json:"ltv,omitempty"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+// Pool is a group of loans cut for securitization
+type Pool struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Criteria  Criteria  `json:"criteria"`
+	Status    string    `json:"status"`
+	Members   []Member  `json:"members"`
+	CreatedAt time.Time `json:"createdAt"`
+	FrozenAt  time.Time `json:"frozenAt"`
+	// CutOffBalance is the members' balance at the freeze
+	CutOffBalance float64 `json:"cutOffBalance,omitempty"`
+}
+
+// Clone returns a deep copy so callers cannot alias stored state
+func (p *Pool) Clone() *Pool {
+	c := *p
+	c.Criteria.Grades = slices.Clone(p.Criteria.Grades)
+	c.Criteria.Products = slices.Clone(p.Criteria.Products)
+	c.Members = slices.Clone(p.Members)
+	return &c
+}
+
+// Store keeps pools
+type Store interface {
+	SavePool(ctx context.Context, p *Pool) error
+	// FindPool returns ErrNotFound for unknown pools
+	FindPool(ctx context.Context, id string) (*Pool, error)
+	// ListPools returns every pool ordered by creation
+	ListPools(ctx context.Context) ([]*Pool, error)
+}
+
+// Memory is an in-process Store
+type Memory struct {
+	mu    sync.RWMutex
+	pools map[string]*Pool
+}
+
+// NewMemory creates an empty store
+func NewMemory() *Memory {
+	return &Memory{pools: make(map[string]*Pool)}
+}
+
+// SavePool implements Store
+func (m *Memory) SavePool(ctx context.Context, p *Pool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pools[p.ID] = p.Clone()
+	return nil
+}
+
+// FindPool implements Store
+func (m *Memory) FindPool(ctx context.Context, id string) (*Pool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.pools[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return p.Clone(), nil
+}
+
+// ListPools implements Store
+func (m *Memory) ListPools(ctx context.Context) ([]*Pool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]*Pool, 0, len(m.pools))
+	for _, p := range m.pools {
+		out = append(out, p.Clone())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
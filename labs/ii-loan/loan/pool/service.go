@@ -0,0 +1,387 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"loan"
+)
+
+// CollateralValues returns the current value of the collateral securing
+// a loan; the second result is false for unsecured loans
+type CollateralValues interface {
+	CollateralValue(ctx context.Context, loanID string) (float64, bool, error)
+}
+
+// StaticCollateral holds collateral values keyed by loan ID
+type StaticCollateral map[string]float64
+
+// CollateralValue implements CollateralValues
+func (s StaticCollateral) CollateralValue(_ context.Context, loanID string) (float64, bool, error) {
+	v, ok := s[loanID]
+	return v, ok, nil
+}
+
+// LoadCollateral reads collateral values from a JSON object mapping loan
+// IDs to values
+func LoadCollateral(r io.Reader) (StaticCollateral, error) {
+	var s StaticCollateral
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("pool: collateral values: %w", err)
+	}
+	for id, v := range s {
+		if v <= 0 || math.IsNaN(v) {
+			return nil, fmt.Errorf("pool: collateral value of %s must be positive, got %v", id, v)
+		}
+	}
+	return s, nil
+}
+
+// IneligibleError lists why loans may not join a pool, keyed by loan ID
+type IneligibleError struct {
+	Reasons map[string]string
+}
+
+func (e *IneligibleError) Error() string {
+	ids := make([]string, 0, len(e.Reasons))
+	for id := range e.Reasons {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = id + ": " + e.Reasons[id]
+	}
+	return "pool: ineligible loans: " + strings.Join(parts, "; ")
+}
+
+// Service creates pools and manages their membership
+type Service struct {
+	store      Store
+	loans      loan.LoanRepository
+	collateral CollateralValues
+	now        func() time.Time
+	// mu serialises membership changes, so a loan cannot join two pools
+	mu sync.Mutex
+}
+
+// ServiceOption configures a Service
+type ServiceOption func(*Service)
+
+// WithCollateral values collateral for the LTV criterion. Without it no
+// loan has an LTV and pools with MaxLTV admit none.
+func WithCollateral(c CollateralValues) ServiceOption {
+	return func(s *Service) { s.collateral = c }
+}
+
+// NewService creates a pool service over store and the loans of repo
+func NewService(store Store, repo loan.LoanRepository, opts ...ServiceOption) *Service {
+	s := &Service{store: store, loans: repo, collateral: StaticCollateral(nil), now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create opens an empty pool
+func (s *Service) Create(ctx context.Context, name string, c Criteria) (*Pool, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("pool: name is required")
+	}
+	if c.MaxLTV < 0 || c.MinSeasoningMonths < 0 || c.MaxDaysPastDue < 0 {
+		return nil, fmt.Errorf("pool: criteria cannot be negative")
+	}
+	p := &Pool{
+		ID: loan.NewID(), Name: strings.TrimSpace(name), Criteria: c, Status: StatusOpen,
+		Members: []Member{}, CreatedAt: s.now().UTC(),
+	}
+	return p, s.store.SavePool(ctx, p)
+}
+
+// Get returns a pool
+func (s *Service) Get(ctx context.Context, id string) (*Pool, error) {
+	return s.store.FindPool(ctx, id)
+}
+
+// List returns every pool
+func (s *Service) List(ctx context.Context) ([]*Pool, error) {
+	return s.store.ListPools(ctx)
+}
+
+// member checks l against c and returns it as a member, or why it is not
+// eligible
+func (s *Service) member(ctx context.Context, c Criteria, l *loan.Loan, now time.Time) (Member, string, error) {
+	m := Member{
+		LoanID: l.ID, Grade: loan.RiskGrade(l.CreditScore), Product: l.Product,
+		Balance: l.Balance, Rate: l.AnnualRate(), AddedAt: now,
+	}
+	value, secured, err := s.collateral.CollateralValue(ctx, l.ID)
+	if err != nil {
+		return Member{}, "", err
+	}
+	if secured && value > 0 {
+		ltv := math.Round(l.Balance/value*10000) / 10000
+		m.LTV = &ltv
+	}
+	switch {
+	case !l.IsActive():
+		return m, fmt.Sprintf("loan is %s with balance %.2f, not active", l.Status, l.Balance), nil
+	case len(c.Grades) > 0 && !slices.Contains(c.Grades, m.Grade):
+		return m, fmt.Sprintf("grade %s not admitted", m.Grade), nil
+	case len(c.Products) > 0 && !slices.Contains(c.Products, l.Product):
+		return m, fmt.Sprintf("product %q not admitted", l.Product), nil
+	case l.DaysPastDue > c.MaxDaysPastDue:
+		return m, fmt.Sprintf("%d days past due, at most %d admitted", l.DaysPastDue, c.MaxDaysPastDue), nil
+	case seasoning(l, now) < c.MinSeasoningMonths:
+		return m, fmt.Sprintf("seasoned %d months, %d required", seasoning(l, now), c.MinSeasoningMonths), nil
+	case c.MaxLTV > 0 && m.LTV == nil:
+		return m, "no collateral value for the LTV criterion", nil
+	case c.MaxLTV > 0 && *m.LTV > c.MaxLTV:
+		return m, fmt.Sprintf("LTV %.4f above %.4f", *m.LTV, c.MaxLTV), nil
+	}
+	return m, "", nil
+}
+
+// seasoning is how many whole months l has been on the book at now
+func seasoning(l *loan.Loan, now time.Time) int {
+	if l.ApprovedAt.IsZero() {
+		return 0
+	}
+	a, b := l.ApprovedAt.UTC(), now.UTC()
+	months := (b.Year()-a.Year())*12 + int(b.Month()-a.Month())
+	if b.Day() < a.Day() {
+		months--
+	}
+	return max(months, 0)
+}
+
+// pooled returns the pool each loan in any other pool belongs to
+func (s *Service) pooled(ctx context.Context, except string) (map[string]string, error) {
+	pools, err := s.store.ListPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	in := map[string]string{}
+	for _, p := range pools {
+		if p.ID == except {
+			continue
+		}
+		for _, m := range p.Members {
+			in[m.LoanID] = p.ID
+		}
+	}
+	return in, nil
+}
+
+// Add puts the loans named into an open pool. Nothing is added unless
+// every loan is eligible and in no other pool.
+func (s *Service) Add(ctx context.Context, poolID string, loanIDs []string) (*Pool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.openPool(ctx, poolID)
+	if err != nil {
+		return nil, err
+	}
+	pooled, err := s.pooled(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	reasons := map[string]string{}
+	var added []Member
+	for _, id := range loanIDs {
+		if slices.ContainsFunc(p.Members, func(m Member) bool { return m.LoanID == id }) {
+			continue
+		}
+		if other, ok := pooled[id]; ok {
+			return nil, fmt.Errorf("%w: %s is in pool %s", ErrAlreadyPooled, id, other)
+		}
+		l, err := s.loans.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		m, reason, err := s.member(ctx, p.Criteria, l, now)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			reasons[id] = reason
+			continue
+		}
+		added = append(added, m)
+	}
+	if len(reasons) > 0 {
+		return nil, &IneligibleError{Reasons: reasons}
+	}
+	p.Members = append(p.Members, added...)
+	return p, s.store.SavePool(ctx, p)
+}
+
+// Fill adds every eligible active loan that is in no pool
+func (s *Service) Fill(ctx context.Context, poolID string) (*Pool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.openPool(ctx, poolID)
+	if err != nil {
+		return nil, err
+	}
+	pooled, err := s.pooled(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	loans, err := s.loans.List(ctx, loan.Filter{Statuses: []string{loan.StatusApproved}})
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	for _, l := range loans {
+		if _, ok := pooled[l.ID]; ok || slices.ContainsFunc(p.Members, func(m Member) bool { return m.LoanID == l.ID }) {
+			continue
+		}
+		m, reason, err := s.member(ctx, p.Criteria, l, now)
+		if err != nil {
+			return nil, err
+		}
+		if reason == "" {
+			p.Members = append(p.Members, m)
+		}
+	}
+	slices.SortFunc(p.Members, func(a, b Member) int { return strings.Compare(a.LoanID, b.LoanID) })
+	return p, s.store.SavePool(ctx, p)
+}
+
+// Freeze fixes the membership of a pool and records its members' balances
+// as the cut-off balance. Members that have stopped being active since
+// they were added are still frozen in; eligibility is checked on entry.
+func (s *Service) Freeze(ctx context.Context, poolID string) (*Pool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.openPool(ctx, poolID)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Members) == 0 {
+		return nil, ErrEmpty
+	}
+	var total float64
+	for i, m := range p.Members {
+		l, err := s.loans.FindByID(ctx, m.LoanID)
+		if err != nil {
+			return nil, err
+		}
+		p.Members[i].Balance = l.Balance
+		total += l.Balance
+	}
+	p.Status, p.FrozenAt, p.CutOffBalance = StatusFrozen, s.now().UTC(), round2(total)
+	return p, s.store.SavePool(ctx, p)
+}
+
+func (s *Service) openPool(ctx context.Context, id string) (*Pool, error) {
+	p, err := s.store.FindPool(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Status == StatusFrozen {
+		return nil, fmt.Errorf("%w: %s", ErrFrozen, id)
+	}
+	return p, nil
+}
+
+// MonthCashflow is the money a pool's members owed and paid in one month
+type MonthCashflow struct {
+	Month              time.Time `json:"month"`
+	ScheduledPrincipal float64   `json:"scheduledPrincipal"`
+	ScheduledInterest  float64   `json:"scheduledInterest"`
+	CollectedPrincipal float64   `json:"collectedPrincipal"`
+	CollectedInterest  float64   `json:"collectedInterest"`
+	// Shortfall is what was scheduled and not collected in a month that
+	// has ended, never negative
+	Shortfall float64 `json:"shortfall"`
+}
+
+// CashflowReport is the cashflow of a pool per month
+type CashflowReport struct {
+	PoolID string `json:"poolId"`
+	Loans  int    `json:"loans"`
+	// CutOffBalance is the balance at the freeze, or the members' current
+	// balance for open pools
+	CutOffBalance  float64 `json:"cutOffBalance"`
+	CurrentBalance float64 `json:"currentBalance"`
+	// Factor is CurrentBalance over CutOffBalance, the share of the pool
+	// still outstanding
+	Factor float64         `json:"factor"`
+	Months []MonthCashflow `json:"months"`
+}
+
+// Cashflows reports the scheduled and collected cashflows of a pool's
+// members for the months from the month of from through the month of to
+func (s *Service) Cashflows(ctx context.Context, poolID string, from, to time.Time) (CashflowReport, error) {
+	p, err := s.store.FindPool(ctx, poolID)
+	if err != nil {
+		return CashflowReport{}, err
+	}
+	from, to = loan.MonthStart(from), loan.MonthStart(to)
+	if to.Before(from) {
+		return CashflowReport{}, fmt.Errorf("pool: cashflow range ends before it starts")
+	}
+	r := CashflowReport{PoolID: p.ID, Loans: len(p.Members), Months: []MonthCashflow{}}
+	index := map[time.Time]int{}
+	for m := from; !m.After(to); m = m.AddDate(0, 1, 0) {
+		index[m] = len(r.Months)
+		r.Months = append(r.Months, MonthCashflow{Month: m})
+	}
+	for _, m := range p.Members {
+		l, err := s.loans.FindByID(ctx, m.LoanID)
+		if errors.Is(err, loan.ErrLoanNotFound) {
+			continue
+		}
+		if err != nil {
+			return CashflowReport{}, err
+		}
+		r.CurrentBalance += l.Balance
+		if p.Status != StatusFrozen {
+			r.CutOffBalance += l.Balance
+		}
+		for _, inst := range l.Schedule {
+			if i, ok := index[loan.MonthStart(inst.DueDate)]; ok {
+				r.Months[i].ScheduledPrincipal += inst.Principal
+				r.Months[i].ScheduledInterest += inst.Interest
+			}
+		}
+		for _, pay := range l.Payments {
+			if i, ok := index[loan.MonthStart(pay.PaidAt)]; ok {
+				r.Months[i].CollectedPrincipal += pay.Principal
+				r.Months[i].CollectedInterest += pay.Interest
+			}
+		}
+	}
+	if p.Status == StatusFrozen {
+		r.CutOffBalance = p.CutOffBalance
+	}
+	current := loan.MonthStart(s.now())
+	for i := range r.Months {
+		m := &r.Months[i]
+		m.ScheduledPrincipal, m.ScheduledInterest = round2(m.ScheduledPrincipal), round2(m.ScheduledInterest)
+		m.CollectedPrincipal, m.CollectedInterest = round2(m.CollectedPrincipal), round2(m.CollectedInterest)
+		if m.Month.Before(current) {
+			m.Shortfall = round2(math.Max(m.ScheduledPrincipal+m.ScheduledInterest-m.CollectedPrincipal-m.CollectedInterest, 0))
+		}
+	}
+	r.CutOffBalance, r.CurrentBalance = round2(r.CutOffBalance), round2(r.CurrentBalance)
+	if r.CutOffBalance > 0 {
+		r.Factor = math.Round(r.CurrentBalance/r.CutOffBalance*10000) / 10000
+	}
+	return r, nil
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,180 @@
+package loan
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"loan/tracing"
+)
+
+// Prepayment penalty types
+const (
+	// PenaltyNone charges nothing for settling early
+	PenaltyNone = "none"
+	// PenaltyPercent charges Rate of the principal still owed
+	PenaltyPercent = "percent"
+	// PenaltyDeclining charges the rate of Rates for the loan year the
+	// loan is settled in, and nothing after the last of them
+	PenaltyDeclining = "declining"
+)
+
+// PenaltyTypes lists every prepayment penalty type
+var PenaltyTypes = []string{PenaltyNone, PenaltyPercent, PenaltyDeclining}
+
+// PrepaymentPenalty is what a product charges for settling a loan before
+// it matures. Loans keep the penalty of their product at application, so
+// later changes to the product do not alter their contract.
+type PrepaymentPenalty struct {
+	Type string `json:"type"`
+	// Rate is the fraction of the principal owed charged by PenaltyPercent
+	Rate float64 `json:"rate,omitempty"`
+	// Rates are the fractions charged by PenaltyDeclining in the first,
+	// second and later years after approval
+	Rates []float64 `json:"rates,omitempty"`
+}
+
+// Clone returns a deep copy of the penalty
+func (p *PrepaymentPenalty) Clone() *PrepaymentPenalty {
+	c := *p
+	c.Rates = slices.Clone(p.Rates)
+	return &c
+}
+
+// Validate returns a ValidationError describing what is wrong with p
+func (p *PrepaymentPenalty) Validate() error {
+	fraction := func(r float64) bool { return r >= 0 && r < 1 }
+	switch p.Type {
+	case PenaltyNone:
+	case PenaltyPercent:
+		if !fraction(p.Rate) {
+			return invalid("prepayment.rate", "penalty rate must be a fraction between 0 and 1")
+		}
+	case PenaltyDeclining:
+		if len(p.Rates) == 0 {
+			return invalid("prepayment.rates", "a declining penalty needs a rate for at least its first year")
+		}
+		for i, r := range p.Rates {
+			if !fraction(r) {
+				return invalid("prepayment.rates", "penalty rates must be fractions between 0 and 1")
+			}
+			if i > 0 && r > p.Rates[i-1] {
+				return invalid("prepayment.rates", "penalty rates cannot rise from one year to the next")
+			}
+		}
+	default:
+		return invalid("prepayment.type", fmt.Sprintf("unknown penalty type %q; types are none, percent, declining", p.Type))
+	}
+	return nil
+}
+
+// RateAt is the fraction of the principal owed charged for settling a loan
+// approved at approvedAt at the time at
+func (p *PrepaymentPenalty) RateAt(approvedAt, at time.Time) float64 {
+	switch p.Type {
+	case PenaltyPercent:
+		return p.Rate
+	case PenaltyDeclining:
+		year := 0
+		for !approvedAt.AddDate(year+1, 0, 0).After(at) {
+			year++
+		}
+		if year < len(p.Rates) {
+			return p.Rates[year]
+		}
+	}
+	return 0
+}
+
+// PayoffQuote is what it takes to settle a loan in full
+type PayoffQuote struct {
+	LoanID string `json:"loanId"`
+	// Principal and Interest are the principal and accrued interest owed
+	Principal float64 `json:"principal"`
+	Interest  float64 `json:"interest"`
+	// PenaltyRate is the fraction of Principal charged as Penalty
+	PenaltyRate float64   `json:"penaltyRate"`
+	Penalty     float64   `json:"penalty"`
+	Total       float64   `json:"total"`
+	AsOf        time.Time `json:"asOf"`
+}
+
+// Payoff quotes the amount settling an active loan at the time at: the
+// balance, accrued interest included, plus any prepayment penalty on the
+// principal owed
+func (l *Loan) Payoff(at time.Time) (PayoffQuote, error) {
+	if !l.IsActive() {
+		return PayoffQuote{}, fmt.Errorf("%w: loan in status %q has nothing to settle", ErrInvalidTransition, l.Status)
+	}
+	q := PayoffQuote{
+		LoanID:    l.ID,
+		Principal: round2(l.Balance - l.AccruedInterest),
+		Interest:  round2(l.AccruedInterest),
+		AsOf:      at.UTC(),
+	}
+	if l.Prepayment != nil {
+		q.PenaltyRate = l.Prepayment.RateAt(l.ApprovedAt, at)
+	}
+	q.Penalty = round2(q.Principal * q.PenaltyRate)
+	q.Total = round2(q.Principal + q.Interest + q.Penalty)
+	return q, nil
+}
+
+// Settle repays an active loan in full at the time at. amount must be the
+// total of its payoff quote; the penalty part of it is recorded on the
+// payment but repays nothing. Nothing is deposited in escrow.
+func (l *Loan) Settle(amount float64, at time.Time) (Payment, PayoffQuote, error) {
+	q, err := l.Payoff(at)
+	if err != nil {
+		return Payment{}, PayoffQuote{}, err
+	}
+	if round2(amount) != q.Total {
+		return Payment{}, PayoffQuote{}, invalid("amount", fmt.Sprintf("settlement %.2f does not match the payoff amount %.2f", amount, q.Total))
+	}
+	p, err := l.applyPayment(round2(q.Principal+q.Interest), at, false)
+	if err != nil {
+		return Payment{}, PayoffQuote{}, err
+	}
+	p.Penalty, p.Amount = q.Penalty, q.Total
+	l.Payments[len(l.Payments)-1] = p
+	return p, q, nil
+}
+
+// PayoffQuote quotes the amount settling a loan today
+func (s *LoanService) PayoffQuote(ctx context.Context, id string) (_ PayoffQuote, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.PayoffQuote", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	l, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return PayoffQuote{}, err
+	}
+	return l.Payoff(time.Now())
+}
+
+// SettleLoan repays a loan in full ahead of its schedule for the amount of
+// today's payoff quote, prepayment penalty included, and publishes
+// EventPaymentReceived and EventLoanSettled
+func (s *LoanService) SettleLoan(ctx context.Context, id string, amount float64) (_ Payment, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.SettleLoan", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	l, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return Payment{}, err
+	}
+	payment, quote, err := l.Settle(amount, time.Now())
+	if err != nil {
+		return Payment{}, err
+	}
+	if err := s.repo.Update(ctx, l); err != nil {
+		s.log(l).ErrorContext(ctx, "updating settled loan", "error", err)
+		return Payment{}, err
+	}
+	s.log(l).InfoContext(ctx, "loan settled early", "payment_id", payment.ID, "amount", payment.Amount, "penalty", payment.Penalty)
+	if err := s.publish(ctx, l, NewEvent(EventPaymentReceived, l.ID, payment)); err != nil {
+		return payment, err
+	}
+	return payment, s.publish(ctx, l, NewEvent(EventLoanSettled, l.ID, quote))
+}
@@ -0,0 +1,200 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ErrProductNotFound is returned for product codes not in the catalog
+var ErrProductNotFound = errors.New("product not found")
+
+// Product is a loan product customers apply for by its Code
+type Product struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	MinAmount float64 `json:"minAmount,omitempty"`
+	MaxAmount float64 `json:"maxAmount,omitempty"`
+	// Rate is the annual rate of applications for the product that do not
+	// ask for one, 0 to use the rate table
+	Rate float64 `json:"rate,omitempty"`
+	// Terms lists the terms in months offered, any term when empty
+	Terms []int `json:"terms,omitempty"`
+	// Purposes lists the purposes the product lends for, any purpose and
+	// none when empty
+	Purposes []string `json:"purposes,omitempty"`
+	// PurposeRates replace Rate for applications with these purposes
+	PurposeRates map[string]float64 `json:"purposeRates,omitempty"`
+	// Amortization is how the product's loans repay, one of Amortizations,
+	// empty for an annuity
+	Amortization string `json:"amortization,omitempty"`
+	// Prepayment is the penalty for settling the product's loans early,
+	// none when nil
+	Prepayment *PrepaymentPenalty `json:"prepayment,omitempty"`
+	// Insurance lists the credit insurance plans borrowers can add to the
+	// product's loans
+	Insurance []InsurancePlan `json:"insurance,omitempty"`
+}
+
+// RateFor is the rate the product offers for purpose, 0 to use the rate
+// table
+func (p Product) RateFor(purpose string) float64 {
+	if r, ok := p.PurposeRates[purpose]; ok {
+		return r
+	}
+	return p.Rate
+}
+
+// Clone returns a deep copy of the product
+func (p Product) Clone() Product {
+	p.Terms = slices.Clone(p.Terms)
+	p.Purposes = slices.Clone(p.Purposes)
+	p.PurposeRates = maps.Clone(p.PurposeRates)
+	if p.Prepayment != nil {
+		p.Prepayment = p.Prepayment.Clone()
+	}
+	if p.Insurance != nil {
+		plans := make([]InsurancePlan, len(p.Insurance))
+		for i, plan := range p.Insurance {
+			plans[i] = plan.Clone()
+		}
+		p.Insurance = plans
+	}
+	return p
+}
+
+// Check returns a ValidationError if l is outside what p offers
+func (p Product) Check(l *Loan) error {
+	switch {
+	case p.MinAmount > 0 && l.Amount < p.MinAmount:
+		return invalid("amount", fmt.Sprintf("%s loans start at %.2f", p.Code, p.MinAmount))
+	case p.MaxAmount > 0 && l.Amount > p.MaxAmount:
+		return invalid("amount", fmt.Sprintf("%s loans go up to %.2f", p.Code, p.MaxAmount))
+	case len(p.Terms) > 0 && !slices.Contains(p.Terms, l.TermMonths):
+		return invalid("termMonths", fmt.Sprintf("%s loans are offered over %v months", p.Code, p.Terms))
+	case len(p.Purposes) > 0 && l.Purpose == "":
+		return invalid("purpose", fmt.Sprintf("%s loans need a purpose: %s", p.Code, strings.Join(p.Purposes, ", ")))
+	case len(p.Purposes) > 0 && !slices.Contains(p.Purposes, l.Purpose):
+		return invalid("purpose", fmt.Sprintf("%s loans are not offered for %s, only %s", p.Code, l.Purpose, strings.Join(p.Purposes, ", ")))
+	}
+	return nil
+}
+
+// Pricing is a consistent snapshot of the rate table and product catalog.
+// A snapshot is never modified once in use: changes replace it whole, so
+// an application priced against one sees all of it or none.
+type Pricing struct {
+	Rates RateTable
+	// Products are keyed by code; applications must name one of them when
+	// there are any
+	Products map[string]Product
+}
+
+// NewPricing builds a snapshot of rates and products
+func NewPricing(rates RateTable, products []Product) *Pricing {
+	p := &Pricing{Rates: slices.Clone(rates), Products: make(map[string]Product, len(products))}
+	for _, prod := range products {
+		p.Products[prod.Code] = prod.Clone()
+	}
+	return p
+}
+
+// price checks l against prod, the product it applies for if known, and
+// fixes its rate if it does not ask for one: the product's rate first,
+// then the rate table. The loan repays as the product amortizes and keeps
+// its prepayment penalty.
+func (p *Pricing) price(l *Loan, prod *Product) error {
+	var rate float64
+	if prod != nil {
+		if err := prod.Check(l); err != nil {
+			return err
+		}
+		rate = prod.RateFor(l.Purpose)
+		l.Amortization = prod.Amortization
+		if prod.Prepayment != nil {
+			l.Prepayment = prod.Prepayment.Clone()
+		}
+	}
+	if rate == 0 && p != nil && len(p.Rates) > 0 {
+		rate = p.Rates.Rate(l.Amount)
+	}
+	if l.InterestRate == 0 {
+		l.InterestRate = rate
+	}
+	return nil
+}
+
+// PricingSource provides the pricing in force. Reloadable sources swap in
+// a new snapshot atomically; the service reads one per application.
+type PricingSource interface {
+	Pricing() *Pricing
+}
+
+// StaticPricing is a PricingSource that never changes
+type StaticPricing struct{ P *Pricing }
+
+// Pricing implements PricingSource
+func (s StaticPricing) Pricing() *Pricing { return s.P }
+
+// WithPricing checks applications against the products of src and fixes
+// the rate of those that do not ask for one from them
+func WithPricing(src PricingSource) Option {
+	return func(s *LoanService) { s.pricing = src }
+}
+
+// ProductCatalog looks products up by code, returning ErrProductNotFound
+// for unknown codes
+type ProductCatalog interface {
+	Product(ctx context.Context, code string) (Product, error)
+}
+
+// ProductRepository is a catalog maintained by administrators
+type ProductRepository interface {
+	ProductCatalog
+	// Products returns the catalog ordered by code
+	Products(ctx context.Context) ([]Product, error)
+	// SaveProduct adds p or replaces the product with its code
+	SaveProduct(ctx context.Context, p Product) error
+	// DeleteProduct removes a product, or returns ErrProductNotFound
+	DeleteProduct(ctx context.Context, code string) error
+}
+
+// WithProductCatalog checks applications naming a product against c and
+// prices them from it, when the pricing in force has no products of its
+// own. Applications naming no product are not checked.
+func WithProductCatalog(c ProductCatalog) Option {
+	return func(s *LoanService) { s.products = c }
+}
+
+// price applies the pricing in force and the product l applies for to l,
+// reading one pricing snapshot so a reload cannot split the application
+func (s *LoanService) price(ctx context.Context, l *Loan) error {
+	var p *Pricing
+	if s.pricing != nil {
+		p = s.pricing.Pricing()
+	}
+	switch {
+	case p != nil && len(p.Products) > 0:
+		prod, ok := p.Products[l.Product]
+		if !ok {
+			if l.Product == "" {
+				return invalid("product", "product is required")
+			}
+			return invalid("product", fmt.Sprintf("unknown product %q", l.Product))
+		}
+		return p.price(l, &prod)
+	case s.products != nil && l.Product != "":
+		prod, err := s.products.Product(ctx, l.Product)
+		if errors.Is(err, ErrProductNotFound) {
+			return invalid("product", fmt.Sprintf("unknown product %q", l.Product))
+		}
+		if err != nil {
+			return err
+		}
+		return p.price(l, &prod)
+	}
+	return p.price(l, nil)
+}
@@ -0,0 +1,27 @@
+package loan
+
+import "slices"
+
+// Loan purposes: what the borrower says the money is for
+const (
+	PurposeHomeImprovement   = "home_improvement"
+	PurposeDebtConsolidation = "debt_consolidation"
+	PurposeAuto              = "auto"
+	PurposeEducation         = "education"
+	PurposeMedical           = "medical"
+	PurposeBusiness          = "business"
+	PurposeWedding           = "wedding"
+	PurposeTravel            = "travel"
+	PurposeOther             = "other"
+)
+
+// Purposes lists every loan purpose in display order
+var Purposes = []string{
+	PurposeHomeImprovement, PurposeDebtConsolidation, PurposeAuto, PurposeEducation,
+	PurposeMedical, PurposeBusiness, PurposeWedding, PurposeTravel, PurposeOther,
+}
+
+// ValidPurpose reports whether p is a known purpose
+func ValidPurpose(p string) bool {
+	return slices.Contains(Purposes, p)
+}
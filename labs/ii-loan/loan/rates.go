@@ -0,0 +1,82 @@
+package loan
+
+import "fmt"
+
+// RateTier is the default annual rate of amounts above Above
+type RateTier struct {
+	Above float64 `json:"above"`
+	Rate  float64 `json:"rate"`
+}
+
+// RateTable holds the default rates of applications that do not ask for
+// one, by amount: an amount gets the rate of the highest tier it is
+// above, and amounts above no tier the rate of the lowest.
+type RateTable []RateTier
+
+// DefaultRateTable is the tiering Loan.AnnualRate falls back to
+var DefaultRateTable = RateTable{{Above: 0, Rate: 0.12}, {Above: 10000, Rate: 0.15}}
+
+// Rate returns the default rate of amount, 0 for an empty table
+func (t RateTable) Rate(amount float64) float64 {
+	best, lowest := -1, -1
+	for i, tier := range t {
+		if lowest < 0 || tier.Above < t[lowest].Above {
+			lowest = i
+		}
+		if amount > tier.Above && (best < 0 || tier.Above > t[best].Above) {
+			best = i
+		}
+	}
+	switch {
+	case best >= 0:
+		return t[best].Rate
+	case lowest >= 0:
+		return t[lowest].Rate
+	}
+	return 0
+}
+
+// Limits bound what applications may ask for. Zero fields do not limit.
+type Limits struct {
+	MinAmount     float64 `json:"minAmount,omitempty"`
+	MaxAmount     float64 `json:"maxAmount,omitempty"`
+	MinTermMonths int     `json:"minTermMonths,omitempty"`
+	MaxTermMonths int     `json:"maxTermMonths,omitempty"`
+	// MaxRate caps the annual rate an application may ask for
+	MaxRate float64 `json:"maxRate,omitempty"`
+	// MaxExposure caps what one customer may owe, applications pending
+	// included. It is not checked on submission: the risk engine sees it
+	// in RiskInputs.Exposure and declines applications that would exceed
+	// it.
+	MaxExposure float64 `json:"maxExposure,omitempty"`
+}
+
+// Check returns a ValidationError for the first limit l breaks
+func (lim Limits) Check(l *Loan) error {
+	switch {
+	case lim.MinAmount > 0 && l.Amount < lim.MinAmount:
+		return invalid("amount", fmt.Sprintf("loan amount must be at least %.2f", lim.MinAmount))
+	case lim.MaxAmount > 0 && l.Amount > lim.MaxAmount:
+		return invalid("amount", fmt.Sprintf("loan amount must be at most %.2f", lim.MaxAmount))
+	case lim.MinTermMonths > 0 && l.TermMonths < lim.MinTermMonths:
+		return invalid("termMonths", fmt.Sprintf("term must be at least %d months", lim.MinTermMonths))
+	case lim.MaxTermMonths > 0 && l.TermMonths > lim.MaxTermMonths:
+		return invalid("termMonths", fmt.Sprintf("term must be at most %d months", lim.MaxTermMonths))
+	case lim.MaxRate > 0 && l.InterestRate > lim.MaxRate:
+		return invalid("interestRate", fmt.Sprintf("interest rate must be at most %g", lim.MaxRate))
+	}
+	return nil
+}
+
+// WithRateTable fixes the rate of applications that do not ask for one
+// from t when they are submitted, instead of leaving them to
+// DefaultRateTable for the life of the loan. It is WithPricing with rates
+// only.
+func WithRateTable(t RateTable) Option {
+	return WithPricing(StaticPricing{NewPricing(t, nil)})
+}
+
+// WithLimits rejects applications outside lim
+func WithLimits(lim Limits) Option {
+	return func(s *LoanService) { s.limits = lim }
+}
@@ -0,0 +1,124 @@
+package regreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Field is one fixed-width column of a record
+type Field struct {
+	// Name is the record value the field holds, see the Field* constants
+	Name  string `json:"name"`
+	Width int    `json:"width"`
+	// Numeric fields are right aligned and zero padded; others are left
+	// aligned and space padded
+	Numeric bool `json:"numeric,omitempty"`
+	// Decimals is the number of implied decimal places of an amount, e.g.
+	// 1234.5 with 2 decimals is written 123450
+	Decimals int `json:"decimals,omitempty"`
+}
+
+// Record values a layout can place
+const (
+	FieldRecordType  = "record_type"
+	FieldInstitution = "institution"
+	FieldPeriod      = "period"
+	FieldCreated     = "created"
+	FieldSection     = "section"
+	FieldProduct     = "product"
+	FieldBucket      = "bucket"
+	FieldCount       = "count"
+	FieldAmount      = "amount"
+	FieldRecords     = "records"
+	FieldTotalAmount = "total_amount"
+)
+
+// Code dimensions, the keys of Layout.Codes
+const (
+	CodeRecord  = "record"
+	CodeSection = "section"
+	CodeProduct = "product"
+	CodeBucket  = "bucket"
+)
+
+// Layout describes the file a regulator expects: the fields of the
+// header, detail and trailer records and the codes internal values are
+// reported under. A dimension without a code table is written as is; one
+// with a table must map every value that occurs.
+type Layout struct {
+	Institution string                       `json:"institution"`
+	Header      []Field                      `json:"header"`
+	Detail      []Field                      `json:"detail"`
+	Trailer     []Field                      `json:"trailer"`
+	Codes       map[string]map[string]string `json:"codes"`
+	// LineEnding separates records, "\r\n" when empty
+	LineEnding string `json:"lineEnding,omitempty"`
+}
+
+// DefaultLayout is a 60 character record layout with two-digit product
+// and bucket codes
+func DefaultLayout(institution string) Layout {
+	return Layout{
+		Institution: institution,
+		Header: []Field{
+			{Name: FieldRecordType, Width: 1},
+			{Name: FieldInstitution, Width: 10},
+			{Name: FieldPeriod, Width: 6, Numeric: true},
+			{Name: FieldCreated, Width: 8, Numeric: true},
+			{Name: "filler", Width: 35},
+		},
+		Detail: []Field{
+			{Name: FieldRecordType, Width: 1},
+			{Name: FieldSection, Width: 3},
+			{Name: FieldProduct, Width: 2},
+			{Name: FieldBucket, Width: 2},
+			{Name: FieldCount, Width: 9, Numeric: true},
+			{Name: FieldAmount, Width: 17, Numeric: true, Decimals: 2},
+			{Name: "filler", Width: 26},
+		},
+		Trailer: []Field{
+			{Name: FieldRecordType, Width: 1},
+			{Name: FieldRecords, Width: 9, Numeric: true},
+			{Name: FieldTotalAmount, Width: 19, Numeric: true, Decimals: 2},
+			{Name: "filler", Width: 31},
+		},
+		Codes: map[string]map[string]string{
+			CodeRecord:  {"header": "H", "detail": "D", "trailer": "T"},
+			CodeSection: {Originations: "ORG", Arrears: "ARR"},
+			CodeProduct: {"": "00", "PL": "01", "AUTO": "02", "HOME": "03", "MICRO": "04", "EDU": "05"},
+			CodeBucket:  {"": "00", "1-30": "01", "31-60": "02", "61-90": "03", "90+": "04"},
+		},
+	}
+}
+
+// LoadLayout decodes a JSON layout
+func LoadLayout(r io.Reader) (Layout, error) {
+	var l Layout
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&l); err != nil {
+		return Layout{}, fmt.Errorf("regreport: layout: %w", err)
+	}
+	return l, nil
+}
+
+func (l Layout) lineEnding() string {
+	if l.LineEnding == "" {
+		return "\r\n"
+	}
+	return l.LineEnding
+}
+
+// code maps value through the code table of dimension
+func (l Layout) code(dimension, value string) (string, error) {
+	table, ok := l.Codes[dimension]
+	if !ok {
+		return value, nil
+	}
+	c, ok := table[value]
+	if !ok {
+		return "", fmt.Errorf("no %s code for %q", dimension, value)
+	}
+	return c, nil
+}
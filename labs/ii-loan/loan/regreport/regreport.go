@@ -0,0 +1,256 @@
+// Package regreport produces the periodic regulatory return on lending: a
+// fixed-width file of loans originated in the period and loans in arrears
+// at its end. The record layout and reporting codes differ between
+// regulators, so they come from a Layout rather than being built in, and
+// every record is checked against it before anything is written.
+package regreport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"loan"
+)
+
+// Report sections
+const (
+	Originations = "originations"
+	Arrears      = "arrears"
+)
+
+// Line is one detail figure of the report
+type Line struct {
+	Section string
+	Product string
+	// Bucket is the delinquency bucket of arrears lines, empty otherwise
+	Bucket string
+	Count  int
+	// Amount is the principal lent for originations and the outstanding
+	// balance for arrears
+	Amount float64
+}
+
+// Report holds the figures of one monthly period
+type Report struct {
+	Period  time.Time
+	Created time.Time
+	Lines   []Line
+}
+
+// Build computes the report for the month containing period: loans
+// approved during the month by product, and loans past due at the end of
+// the month by product and delinquency bucket. Days past due are derived
+// from the repayment schedules as they stand now.
+func Build(ctx context.Context, repo loan.LoanRepository, period time.Time) (Report, error) {
+	start := loan.MonthStart(period)
+	end := start.AddDate(0, 1, 0)
+	type key struct{ section, product, bucket string }
+	lines := map[key]*Line{}
+	add := func(k key, amount float64) {
+		l := lines[k]
+		if l == nil {
+			l = &Line{Section: k.section, Product: k.product, Bucket: k.bucket}
+			lines[k] = l
+		}
+		l.Count++
+		l.Amount += amount
+	}
+
+	filter := loan.Filter{Statuses: []string{loan.StatusApproved, loan.StatusDefault}}
+	err := loan.EachLoan(ctx, repo, filter, func(l *loan.Loan) error {
+		if l.ApprovedAt.IsZero() || !l.ApprovedAt.Before(end) {
+			return nil
+		}
+		if !l.ApprovedAt.Before(start) {
+			add(key{Originations, l.Product, ""}, l.Amount)
+		}
+		if dpd := l.CalculateDaysPastDue(end); dpd > 0 && l.Balance > 0 {
+			add(key{Arrears, l.Product, string(loan.BucketFor(dpd))}, l.Balance)
+		}
+		return nil
+	})
+	if err != nil {
+		return Report{}, err
+	}
+
+	r := Report{Period: start, Created: time.Now().UTC(), Lines: make([]Line, 0, len(lines))}
+	for _, l := range lines {
+		l.Amount = math.Round(l.Amount*100) / 100
+		r.Lines = append(r.Lines, *l)
+	}
+	sort.Slice(r.Lines, func(i, j int) bool {
+		a, b := r.Lines[i], r.Lines[j]
+		if a.Section != b.Section {
+			return a.Section > b.Section // originations before arrears
+		}
+		if a.Product != b.Product {
+			return a.Product < b.Product
+		}
+		return loan.Bucket(b.Bucket).Worse(loan.Bucket(a.Bucket))
+	})
+	return r, nil
+}
+
+// Problem is one reason a report cannot be emitted in a layout
+type Problem struct {
+	// Record is the 1-based record number, 0 for problems of the layout
+	Record  int
+	Field   string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Record == 0 {
+		return fmt.Sprintf("layout: %s", p.Message)
+	}
+	return fmt.Sprintf("record %d field %s: %s", p.Record, p.Field, p.Message)
+}
+
+// ValidationError lists every problem found by the validation pass
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.String()
+	}
+	return "regreport: validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Validate renders r in layout l without writing it and reports every
+// problem found, as a *ValidationError
+func (l Layout) Validate(r Report) error {
+	_, err := l.render(r)
+	return err
+}
+
+// Write validates r and, only when it is valid, writes the file
+func (l Layout) Write(w io.Writer, r Report) error {
+	records, err := l.render(r)
+	if err != nil {
+		return err
+	}
+	eol := l.lineEnding()
+	for _, rec := range records {
+		if _, err := io.WriteString(w, rec+eol); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func width(fields []Field) int {
+	n := 0
+	for _, f := range fields {
+		n += f.Width
+	}
+	return n
+}
+
+// render formats the header, detail and trailer records, collecting
+// problems instead of stopping at the first
+func (l Layout) render(r Report) ([]string, error) {
+	var problems []Problem
+	if len(l.Header) == 0 || len(l.Detail) == 0 || len(l.Trailer) == 0 {
+		problems = append(problems, Problem{Message: "header, detail and trailer records need at least one field"})
+	}
+	if hw, dw, tw := width(l.Header), width(l.Detail), width(l.Trailer); hw != dw || dw != tw {
+		problems = append(problems, Problem{Message: fmt.Sprintf("record lengths differ: header %d, detail %d, trailer %d", hw, dw, tw)})
+	}
+
+	var records []string
+	emit := func(kind string, fields []Field, values map[string]any) {
+		n := len(records) + 1
+		recordType, err := l.code(CodeRecord, kind)
+		if err != nil {
+			problems = append(problems, Problem{Record: n, Field: FieldRecordType, Message: err.Error()})
+		}
+		values[FieldRecordType] = recordType
+		var b strings.Builder
+		for _, f := range fields {
+			s, err := f.format(values)
+			if err != nil {
+				problems = append(problems, Problem{Record: n, Field: f.Name, Message: err.Error()})
+				s = strings.Repeat("?", f.Width)
+			}
+			b.WriteString(s)
+		}
+		records = append(records, b.String())
+	}
+
+	emit("header", l.Header, map[string]any{
+		FieldInstitution: l.Institution,
+		FieldPeriod:      r.Period.UTC().Format("200601"),
+		FieldCreated:     r.Created.UTC().Format("20060102"),
+	})
+	var total float64
+	for _, line := range r.Lines {
+		values := map[string]any{FieldCount: line.Count, FieldAmount: line.Amount}
+		for dim, v := range map[string]string{CodeSection: line.Section, CodeProduct: line.Product, CodeBucket: line.Bucket} {
+			c, err := l.code(dim, v)
+			if err != nil {
+				problems = append(problems, Problem{Record: len(records) + 1, Field: dim, Message: err.Error()})
+			}
+			values[dim] = c
+		}
+		emit("detail", l.Detail, values)
+		total += line.Amount
+	}
+	emit("trailer", l.Trailer, map[string]any{
+		FieldRecords:     len(records) + 1,
+		FieldTotalAmount: math.Round(total*100) / 100,
+	})
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return records, nil
+}
+
+// format renders the field's value from values at exactly its width
+func (f Field) format(values map[string]any) (string, error) {
+	if f.Width <= 0 {
+		return "", fmt.Errorf("width must be positive")
+	}
+	v, ok := values[f.Name]
+	if !ok {
+		if f.Name != "filler" {
+			return "", fmt.Errorf("not available in this record")
+		}
+		v = ""
+	}
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case int:
+		s = strconv.Itoa(v)
+	case float64:
+		if !f.Numeric {
+			return "", fmt.Errorf("amount needs a numeric field")
+		}
+		s = strconv.FormatInt(int64(math.Round(v*math.Pow10(f.Decimals))), 10)
+	}
+	if f.Numeric {
+		if s == "" || strings.Trim(s, "0123456789") != "" {
+			return "", fmt.Errorf("value %q is not a non-negative number", s)
+		}
+	}
+	if n := utf8.RuneCountInString(s); n > f.Width {
+		return "", fmt.Errorf("value %q is longer than %d characters", s, f.Width)
+	} else if f.Numeric {
+		s = strings.Repeat("0", f.Width-n) + s
+	} else {
+		s += strings.Repeat(" ", f.Width-n)
+	}
+	return s, nil
+}
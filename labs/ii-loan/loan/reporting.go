@@ -0,0 +1,379 @@
+package loan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"loan/tracing"
+)
+
+// Dimension is a loan attribute portfolio figures are grouped by
+type Dimension string
+
+// Reporting dimensions
+const (
+	ByStatus    Dimension = "status"
+	ByProduct   Dimension = "product"
+	ByRiskGrade Dimension = "riskGrade"
+	// ByOriginationMonth groups approved loans by the month, YYYY-MM, they
+	// were approved in; loans never approved are left out
+	ByOriginationMonth Dimension = "originationMonth"
+	// ByPerformance splits loans into PerformingKey and NonPerformingKey
+	ByPerformance Dimension = "performance"
+	// ByCurrency groups loans by the currency they are booked in
+	ByCurrency Dimension = "currency"
+)
+
+// Keys of the ByPerformance groups
+const (
+	PerformingKey    = "performing"
+	NonPerformingKey = "non-performing"
+)
+
+// NonPerformingDays is the days past due after which a loan is
+// non-performing even before it is written off as defaulted
+const NonPerformingDays = 90
+
+// Group is the aggregate of the loans sharing one key of a dimension
+type Group struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+	// Principal is the sum of the amounts lent
+	Principal float64 `json:"principal"`
+	// Outstanding is the sum of the balances still owed
+	Outstanding float64 `json:"outstanding"`
+}
+
+// Grouper is implemented by repositories that can aggregate loans in the
+// store. Groups are returned ordered by key. ReportingService falls back to
+// listing and grouping in memory for repositories without it.
+type Grouper interface {
+	GroupBy(ctx context.Context, filter Filter, d Dimension) ([]Group, error)
+}
+
+// RiskGrade maps a credit score to a grade from A (best) to E; loans
+// without a score are "unrated"
+func RiskGrade(score int) string {
+	switch {
+	case score <= 0:
+		return "unrated"
+	case score >= 750:
+		return "A"
+	case score >= 700:
+		return "B"
+	case score >= 650:
+		return "C"
+	case score >= 600:
+		return "D"
+	default:
+		return "E"
+	}
+}
+
+// IsNonPerforming reports whether the loan has defaulted or is more than
+// NonPerformingDays past due
+func (l *Loan) IsNonPerforming() bool {
+	return l.Status == StatusDefault || l.DaysPastDue > NonPerformingDays
+}
+
+// GroupKey returns the loan's key in dimension d. The second result is
+// false when the loan does not belong to any group, as for unapproved
+// loans by origination month.
+func (l *Loan) GroupKey(d Dimension) (string, bool) {
+	switch d {
+	case ByStatus:
+		return l.Status, true
+	case ByProduct:
+		return l.Product, true
+	case ByRiskGrade:
+		return RiskGrade(l.CreditScore), true
+	case ByOriginationMonth:
+		if l.ApprovedAt.IsZero() {
+			return "", false
+		}
+		return l.ApprovedAt.UTC().Format("2006-01"), true
+	case ByPerformance:
+		if l.IsNonPerforming() {
+			return NonPerformingKey, true
+		}
+		return PerformingKey, true
+	case ByCurrency:
+		return l.CurrencyCode(), true
+	}
+	return "", false
+}
+
+// ValidDimension reports whether d is one of the reporting dimensions
+func ValidDimension(d Dimension) bool {
+	switch d {
+	case ByStatus, ByProduct, ByRiskGrade, ByOriginationMonth, ByPerformance, ByCurrency:
+		return true
+	}
+	return false
+}
+
+// GroupLoans aggregates loans in memory, ordered by key
+func GroupLoans(loans []*Loan, d Dimension) []Group {
+	byKey := map[string]*Group{}
+	for _, l := range loans {
+		key, ok := l.GroupKey(d)
+		if !ok {
+			continue
+		}
+		g := byKey[key]
+		if g == nil {
+			g = &Group{Key: key}
+			byKey[key] = g
+		}
+		g.Count++
+		g.Principal += l.Amount
+		g.Outstanding += l.Balance
+	}
+	out := make([]Group, 0, len(byKey))
+	for _, g := range byKey {
+		g.Principal, g.Outstanding = round2(g.Principal), round2(g.Outstanding)
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// bookStatuses are the loans that were lent out and may still be owed
+var bookStatuses = []string{StatusApproved, StatusDefault}
+
+// PortfolioReport summarises the loan book
+type PortfolioReport struct {
+	GeneratedAt          time.Time `json:"generatedAt"`
+	OutstandingByStatus  []Group   `json:"outstandingByStatus"`
+	OutstandingByProduct []Group   `json:"outstandingByProduct"`
+	OutstandingByGrade   []Group   `json:"outstandingByRiskGrade"`
+	Originations         []Group   `json:"originations"`
+	AverageTicketSize    float64   `json:"averageTicketSize"`
+	// NPLRatio is the non-performing share of the outstanding balance
+	NPLRatio float64 `json:"nplRatio"`
+	// Exposure is the outstanding balance in the base currency. The
+	// figures above add up amounts as booked and are only meaningful for
+	// a single-currency book.
+	Exposure ExposureReport `json:"exposure"`
+	// LossAllowance is the expected credit loss provisioned for the book
+	LossAllowance float64 `json:"lossAllowance"`
+}
+
+// CurrencyExposure is the outstanding balance booked in one currency and
+// its value in the base currency
+type CurrencyExposure struct {
+	Currency    string  `json:"currency"`
+	Count       int     `json:"count"`
+	Outstanding float64 `json:"outstanding"`
+	Rate        float64 `json:"rate"`
+	// OutstandingBase is Outstanding converted at Rate
+	OutstandingBase float64 `json:"outstandingBase"`
+}
+
+// ExposureReport expresses the book's outstanding balance in one base
+// currency. Rates holds the snapshot of every rate used, so the figures
+// can be reproduced after rates have moved.
+type ExposureReport struct {
+	Base       string             `json:"base"`
+	ByCurrency []CurrencyExposure `json:"byCurrency"`
+	Total      float64            `json:"total"`
+	Rates      []FXRate           `json:"rates"`
+}
+
+// ReportingService answers aggregate questions about the loan book. It
+// reads through the repository's Grouper when there is one.
+type ReportingService struct {
+	repo         LoanRepository
+	now          func() time.Time
+	fx           FXRateProvider
+	base         string
+	provisioning ProvisioningModel
+	aging        AgingStore
+	transitions  TransitionStore
+}
+
+// ReportingOption configures a ReportingService
+type ReportingOption func(*ReportingService)
+
+// WithFXRates converts exposure into base currency with rates from p.
+// Without it the base is DefaultCurrency and loans booked in any other
+// currency make exposure reports fail with ErrNoFXRate.
+func WithFXRates(p FXRateProvider, base string) ReportingOption {
+	return func(s *ReportingService) { s.fx, s.base = p, base }
+}
+
+// NewReportingService creates a reporting service over repo
+func NewReportingService(repo LoanRepository, opts ...ReportingOption) *ReportingService {
+	s := &ReportingService{repo: repo, now: time.Now, base: DefaultCurrency, provisioning: DefaultProvisioningModel}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Group aggregates the loans matching filter by d
+func (s *ReportingService) Group(ctx context.Context, filter Filter, d Dimension) (_ []Group, err error) {
+	ctx, span := tracing.Start(ctx, "ReportingService.Group", attribute.String("report.dimension", string(d)))
+	defer tracing.End(span, &err)
+	if !ValidDimension(d) {
+		return nil, invalid("dimension", fmt.Sprintf("unknown dimension %q", d))
+	}
+	if g, ok := s.repo.(Grouper); ok {
+		return g.GroupBy(ctx, filter, d)
+	}
+	loans, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return GroupLoans(loans, d), nil
+}
+
+// OutstandingBy returns the outstanding balance of the book (approved and
+// defaulted loans) grouped by d
+func (s *ReportingService) OutstandingBy(ctx context.Context, d Dimension) ([]Group, error) {
+	return s.Group(ctx, Filter{Statuses: bookStatuses}, d)
+}
+
+// Originations returns the loans approved per month from the month of from
+// through the month of to; zero bounds are open
+func (s *ReportingService) Originations(ctx context.Context, from, to time.Time) ([]Group, error) {
+	groups, err := s.Group(ctx, Filter{Statuses: bookStatuses}, ByOriginationMonth)
+	if err != nil {
+		return nil, err
+	}
+	out := groups[:0]
+	for _, g := range groups {
+		if !from.IsZero() && g.Key < from.UTC().Format("2006-01") {
+			continue
+		}
+		if !to.IsZero() && g.Key > to.UTC().Format("2006-01") {
+			continue
+		}
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// AverageTicketSize returns the mean principal of the loans approved
+func (s *ReportingService) AverageTicketSize(ctx context.Context) (float64, error) {
+	groups, err := s.OutstandingBy(ctx, ByStatus)
+	if err != nil {
+		return 0, err
+	}
+	return averageTicket(groups), nil
+}
+
+// NPLRatio returns the non-performing share of the outstanding balance, 0
+// for an empty book
+func (s *ReportingService) NPLRatio(ctx context.Context) (float64, error) {
+	groups, err := s.OutstandingBy(ctx, ByPerformance)
+	if err != nil {
+		return 0, err
+	}
+	return nplRatio(groups), nil
+}
+
+// Portfolio computes every figure of the portfolio report
+func (s *ReportingService) Portfolio(ctx context.Context) (_ PortfolioReport, err error) {
+	ctx, span := tracing.Start(ctx, "ReportingService.Portfolio")
+	defer tracing.End(span, &err)
+	r := PortfolioReport{GeneratedAt: s.now().UTC()}
+	if r.OutstandingByStatus, err = s.OutstandingBy(ctx, ByStatus); err != nil {
+		return PortfolioReport{}, err
+	}
+	if r.OutstandingByProduct, err = s.OutstandingBy(ctx, ByProduct); err != nil {
+		return PortfolioReport{}, err
+	}
+	if r.OutstandingByGrade, err = s.OutstandingBy(ctx, ByRiskGrade); err != nil {
+		return PortfolioReport{}, err
+	}
+	if r.Originations, err = s.Originations(ctx, time.Time{}, time.Time{}); err != nil {
+		return PortfolioReport{}, err
+	}
+	performance, err := s.OutstandingBy(ctx, ByPerformance)
+	if err != nil {
+		return PortfolioReport{}, err
+	}
+	r.AverageTicketSize = averageTicket(r.OutstandingByStatus)
+	r.NPLRatio = nplRatio(performance)
+	if r.Exposure, err = s.Exposure(ctx); err != nil {
+		return PortfolioReport{}, err
+	}
+	provision, err := s.Provisioning(ctx)
+	if err != nil {
+		return PortfolioReport{}, err
+	}
+	r.LossAllowance = provision.TotalECL
+	return r, nil
+}
+
+// Exposure returns the outstanding balance of the book per currency and in
+// total, converted into the base currency
+func (s *ReportingService) Exposure(ctx context.Context) (_ ExposureReport, err error) {
+	ctx, span := tracing.Start(ctx, "ReportingService.Exposure", attribute.String("report.base_currency", s.base))
+	defer tracing.End(span, &err)
+	groups, err := s.OutstandingBy(ctx, ByCurrency)
+	if err != nil {
+		return ExposureReport{}, err
+	}
+	r := ExposureReport{Base: s.base, ByCurrency: []CurrencyExposure{}, Rates: []FXRate{}}
+	for _, g := range groups {
+		rate, err := s.rate(ctx, g.Key)
+		if err != nil {
+			return ExposureReport{}, err
+		}
+		if g.Key != s.base {
+			r.Rates = append(r.Rates, rate)
+		}
+		e := CurrencyExposure{
+			Currency: g.Key, Count: g.Count, Outstanding: g.Outstanding,
+			Rate: rate.Rate, OutstandingBase: rate.Convert(g.Outstanding),
+		}
+		r.ByCurrency = append(r.ByCurrency, e)
+		r.Total += e.OutstandingBase
+	}
+	r.Total = round2(r.Total)
+	return r, nil
+}
+
+// rate returns the rate from currency into the base currency
+func (s *ReportingService) rate(ctx context.Context, currency string) (FXRate, error) {
+	if currency == s.base {
+		return FXRate{From: currency, To: s.base, Rate: 1, AsOf: s.now().UTC()}, nil
+	}
+	if s.fx == nil {
+		return FXRate{}, fmt.Errorf("%w from %s to %s: no rate provider configured", ErrNoFXRate, currency, s.base)
+	}
+	return s.fx.Rate(ctx, currency, s.base)
+}
+
+func averageTicket(groups []Group) float64 {
+	var n int
+	var principal float64
+	for _, g := range groups {
+		n += g.Count
+		principal += g.Principal
+	}
+	if n == 0 {
+		return 0
+	}
+	return round2(principal / float64(n))
+}
+
+func nplRatio(groups []Group) float64 {
+	var npl, total float64
+	for _, g := range groups {
+		total += g.Outstanding
+		if g.Key == NonPerformingKey {
+			npl += g.Outstanding
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return npl / total
+}
@@ -0,0 +1,114 @@
+// Package retry re-runs operations that failed for transient reasons, with
+// exponential backoff and jitter, within the caller's context.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// ErrTransient marks failures worth retrying. Wrap it, or return an error
+// with a Temporary() bool or Timeout() bool method reporting true.
+var ErrTransient = errors.New("transient failure")
+
+// Policy controls how often and how fast operations are retried
+type Policy struct {
+	// MaxAttempts counts the first call; 1 disables retries
+	MaxAttempts int
+	// InitialDelay is the wait before the second attempt
+	InitialDelay time.Duration
+	// MaxDelay caps the wait between attempts
+	MaxDelay time.Duration
+	// Multiplier grows the delay after each attempt (default 2)
+	Multiplier float64
+	// Jitter is the fraction of each delay that is randomised, 0 to 1.
+	// Full jitter (1) spreads retries of many clients the most.
+	Jitter float64
+	// Retryable decides which errors are retried; nil uses IsTransient
+	Retryable func(error) bool
+}
+
+// DefaultPolicy suits calls to repositories and providers on a request path
+var DefaultPolicy = Policy{
+	MaxAttempts:  3,
+	InitialDelay: 50 * time.Millisecond,
+	MaxDelay:     time.Second,
+	Multiplier:   2,
+	Jitter:       0.5,
+}
+
+// Never performs a single attempt
+var Never = Policy{MaxAttempts: 1}
+
+// Delay returns the wait after the given failed attempt (1-based),
+// including jitter
+func (p Policy) Delay(attempt int) time.Duration {
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 2
+	}
+	d := float64(p.InitialDelay) * math.Pow(mult, float64(attempt-1))
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	if j := min(max(p.Jitter, 0), 1); j > 0 {
+		d = d*(1-j) + d*j*rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+func (p Policy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsTransient(err)
+}
+
+// IsTransient reports whether err is marked with ErrTransient or reports
+// itself as temporary or a timeout, as network errors do
+func IsTransient(err error) bool {
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var temp interface{ Temporary() bool }
+	return errors.As(err, &temp) && temp.Temporary()
+}
+
+// Do calls fn until it succeeds, fails with an error the policy does not
+// retry, runs out of attempts, or ctx is done. It returns the last error
+// from fn, or the context error if ctx ended while waiting.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	attempts := max(p.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= attempts || !p.retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		timer := time.NewTimer(p.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// Value is Do for functions returning a value
+func Value[T any](ctx context.Context, p Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	var out T
+	err := Do(ctx, p, func(ctx context.Context) error {
+		var err error
+		out, err = fn(ctx)
+		return err
+	})
+	return out, err
+}
@@ -0,0 +1,30 @@
+package loan
+
+import (
+	"context"
+
+	"loan/retry"
+)
+
+// retryingRepository retries transient repository failures, such as a
+// dropped database connection, under the service retry policy
+type retryingRepository struct {
+	next   LoanRepository
+	policy retry.Policy
+}
+
+func (r retryingRepository) Save(ctx context.Context, loan *Loan) error {
+	return retry.Do(ctx, r.policy, func(ctx context.Context) error { return r.next.Save(ctx, loan) })
+}
+
+func (r retryingRepository) FindByID(ctx context.Context, id string) (*Loan, error) {
+	return retry.Value(ctx, r.policy, func(ctx context.Context) (*Loan, error) { return r.next.FindByID(ctx, id) })
+}
+
+func (r retryingRepository) Update(ctx context.Context, loan *Loan) error {
+	return retry.Do(ctx, r.policy, func(ctx context.Context) error { return r.next.Update(ctx, loan) })
+}
+
+func (r retryingRepository) List(ctx context.Context, filter Filter) ([]*Loan, error) {
+	return retry.Value(ctx, r.policy, func(ctx context.Context) ([]*Loan, error) { return r.next.List(ctx, filter) })
+}
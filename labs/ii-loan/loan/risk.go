@@ -0,0 +1,120 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"time"
+
+	"loan/tracing"
+)
+
+// Decision outcomes
+const (
+	OutcomeApprove = "approve"
+	OutcomeRefer   = "refer"
+	OutcomeDecline = "decline"
+)
+
+// RiskInputs are what the risk engine knows about an application
+type RiskInputs struct {
+	Loan *Loan
+	// Report is empty when no credit bureau is configured
+	Report CreditReport
+	Bureau BureauSummary
+	// Affordability is nil when no account data provider is configured
+	Affordability *Affordability
+	// Exposure is what the applicant owes and has applied for besides this
+	// application
+	Exposure Exposure
+}
+
+// Decision is the risk engine's recommendation on an application. Loan
+// officers still approve or reject; the decision is kept on the loan as
+// the record of what the engine advised.
+type Decision struct {
+	Outcome string   `json:"outcome"`
+	Grade   string   `json:"grade"`
+	Reasons []string `json:"reasons,omitempty"`
+	// Model and ModelVersion name the rules or scoring model that decided
+	Model        string `json:"model"`
+	ModelVersion string `json:"modelVersion,omitempty"`
+	// PD is the model's probability of default, 0 for rules without one
+	PD          float64              `json:"probabilityOfDefault,omitempty"`
+	Explanation *DecisionExplanation `json:"explanation,omitempty"`
+	// Experiment is set when the decision was made in an experiment arm
+	Experiment *ExperimentAssignment `json:"experiment,omitempty"`
+	DecidedAt  time.Time             `json:"decidedAt"`
+}
+
+// Clone returns a deep copy of the decision
+func (d *Decision) Clone() *Decision {
+	c := *d
+	c.Reasons = append([]string(nil), d.Reasons...)
+	if d.Explanation != nil {
+		c.Explanation = &DecisionExplanation{
+			Factors: append([]DecisionFactor(nil), d.Explanation.Factors...),
+			Inputs:  maps.Clone(d.Explanation.Inputs),
+		}
+	}
+	if d.Experiment != nil {
+		e := *d.Experiment
+		c.Experiment = &e
+	}
+	return &c
+}
+
+// Decision factor sources
+const (
+	FactorPolicy = "policy"
+	FactorModel  = "model"
+)
+
+// DecisionExplanation records why the engine decided as it did: every
+// rule that fired, whether or not it set the outcome, the model's main
+// factors and the inputs the model saw
+type DecisionExplanation struct {
+	Factors []DecisionFactor   `json:"factors"`
+	Inputs  map[string]float64 `json:"inputs,omitempty"`
+}
+
+// DecisionFactor is one rule that fired or one model factor. Rules compare
+// Value with Threshold using Operator, as in "score 480 < 500 threshold".
+type DecisionFactor struct {
+	Source string `json:"source"`
+	Rule   string `json:"rule"`
+	// Effect is the outcome the rule calls for; empty for model factors,
+	// which only inform
+	Effect    string  `json:"effect,omitempty"`
+	Value     float64 `json:"value"`
+	Operator  string  `json:"operator,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+	// Contribution is a model factor's share of the model's log-odds
+	Contribution float64 `json:"contribution,omitempty"`
+	Message      string  `json:"message"`
+}
+
+// ErrNoDecision is returned for applications the risk engine has not
+// assessed
+var ErrNoDecision = errors.New("application has no decision")
+
+// GetDecision returns the risk engine's decision on an application with
+// its explanation
+func (s *LoanService) GetDecision(ctx context.Context, id string) (_ *Decision, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.GetDecision", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	loan, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if loan.Decision == nil {
+		return nil, ErrNoDecision
+	}
+	return loan.Decision, nil
+}
+
+// RiskEngine assesses applications
+type RiskEngine interface {
+	Assess(ctx context.Context, in RiskInputs) (Decision, error)
+}
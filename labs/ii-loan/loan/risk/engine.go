@@ -0,0 +1,200 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"loan"
+)
+
+// Default PD cut-offs of an Engine
+const (
+	DefaultApproveBelow = 0.05
+	DefaultDeclineFrom  = 0.2
+)
+
+// Engine scores applications with the model configured for their product
+// and decides by the PD: approve below ApproveBelow, decline from
+// DeclineFrom and refer in between. The underwriting rules still apply,
+// so a rule can decline or refer what the model would approve.
+type Engine struct {
+	models       map[string]Scorer
+	fallback     Scorer
+	approveBelow float64
+	declineFrom  float64
+	now          func() time.Time
+}
+
+// EngineOption configures an Engine
+type EngineOption func(*Engine)
+
+// WithModel scores applications for product with s
+func WithModel(product string, s Scorer) EngineOption {
+	return func(e *Engine) { e.models[product] = s }
+}
+
+// WithCutoffs sets the PD below which applications are approved and from
+// which they are declined
+func WithCutoffs(approveBelow, declineFrom float64) EngineOption {
+	return func(e *Engine) { e.approveBelow, e.declineFrom = approveBelow, declineFrom }
+}
+
+// NewEngine creates an engine scoring products without a model of their
+// own with fallback
+func NewEngine(fallback Scorer, opts ...EngineOption) *Engine {
+	e := &Engine{
+		models: map[string]Scorer{}, fallback: fallback,
+		approveBelow: DefaultApproveBelow, declineFrom: DefaultDeclineFrom, now: time.Now,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Assess implements loan.RiskEngine
+func (e *Engine) Assess(ctx context.Context, in loan.RiskInputs) (loan.Decision, error) {
+	scorer := e.fallback
+	if in.Loan != nil {
+		if s, ok := e.models[in.Loan.Product]; ok {
+			scorer = s
+		}
+	}
+	features := FeaturesOf(in)
+	s, err := scorer.Score(ctx, features)
+	if err != nil {
+		return loan.Decision{}, err
+	}
+	factors := policy(in)
+	switch {
+	case s.PD >= e.declineFrom:
+		factors = append(factors, rule("pd_decline", loan.OutcomeDecline, s.PD, ">=", e.declineFrom,
+			"probability of default %.1f%% >= %.1f%% threshold", s.PD*100, e.declineFrom*100))
+	case s.PD >= e.approveBelow:
+		factors = append(factors, rule("pd_refer", loan.OutcomeRefer, s.PD, ">=", e.approveBelow,
+			"probability of default %.1f%% >= %.1f%% threshold", s.PD*100, e.approveBelow*100))
+	}
+	d := loan.Decision{
+		Grade: loan.RiskGrade(in.Report.Score), Model: s.Model, ModelVersion: s.Version, PD: s.PD,
+		Explanation: &loan.DecisionExplanation{Inputs: features}, DecidedAt: e.now().UTC(),
+	}
+	decide(&d, append(factors, s.Factors...))
+	return d, nil
+}
+
+// Model types of Config
+const (
+	ModelScorecard  = "scorecard"
+	ModelGradeTable = "grade-table"
+	ModelRemote     = "remote"
+)
+
+// Config selects the scoring model of each product:
+//
+//	{
+//	  "default": "scorecard",
+//	  "products": {"MICRO": "grades"},
+//	  "models": {
+//	    "scorecard": {"type": "scorecard", "version": "2026.1", "intercept": -2.5, "weights": {"bureau_score": -1}},
+//	    "grades": {"type": "grade-table", "version": "1", "pd": {"A": 0.01, "B": 0.03}},
+//	    "ml": {"type": "remote", "version": "3", "url": "http://models/pd", "timeout": "2s"}
+//	  }
+//	}
+type Config struct {
+	// Default names the model of products not listed in Products
+	Default  string                 `json:"default"`
+	Products map[string]string      `json:"products,omitempty"`
+	Models   map[string]ModelConfig `json:"models"`
+	// ApproveBelow and DeclineFrom are the PD cut-offs, zero for the
+	// defaults
+	ApproveBelow float64 `json:"approveBelow,omitempty"`
+	DeclineFrom  float64 `json:"declineFrom,omitempty"`
+}
+
+// ModelConfig describes one model; which fields apply depends on Type
+type ModelConfig struct {
+	Type      string             `json:"type"`
+	Version   string             `json:"version"`
+	Intercept float64            `json:"intercept,omitempty"`
+	Weights   map[string]float64 `json:"weights,omitempty"`
+	PD        map[string]float64 `json:"pd,omitempty"`
+	URL       string             `json:"url,omitempty"`
+	Timeout   string             `json:"timeout,omitempty"`
+}
+
+// LoadConfig decodes a JSON model configuration
+func LoadConfig(r io.Reader) (Config, error) {
+	var c Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return Config{}, fmt.Errorf("risk: config: %w", err)
+	}
+	return c, nil
+}
+
+// NewEngineFromConfig builds the models of c and an engine using them
+func NewEngineFromConfig(c Config) (*Engine, error) {
+	scorers := make(map[string]Scorer, len(c.Models))
+	for name, m := range c.Models {
+		s, err := m.scorer(name)
+		if err != nil {
+			return nil, fmt.Errorf("risk: model %q: %w", name, err)
+		}
+		scorers[name] = s
+	}
+	fallback, ok := scorers[c.Default]
+	if !ok {
+		return nil, fmt.Errorf("risk: default model %q is not configured", c.Default)
+	}
+	var opts []EngineOption
+	for product, name := range c.Products {
+		s, ok := scorers[name]
+		if !ok {
+			return nil, fmt.Errorf("risk: product %s uses unknown model %q", product, name)
+		}
+		opts = append(opts, WithModel(product, s))
+	}
+	if c.ApproveBelow != 0 || c.DeclineFrom != 0 {
+		if c.ApproveBelow <= 0 || c.DeclineFrom < c.ApproveBelow || c.DeclineFrom > 1 {
+			return nil, fmt.Errorf("risk: cut-offs must satisfy 0 < approveBelow <= declineFrom <= 1")
+		}
+		opts = append(opts, WithCutoffs(c.ApproveBelow, c.DeclineFrom))
+	}
+	return NewEngine(fallback, opts...), nil
+}
+
+func (m ModelConfig) scorer(name string) (Scorer, error) {
+	if m.Version == "" {
+		return nil, fmt.Errorf("version is required")
+	}
+	switch m.Type {
+	case ModelScorecard:
+		if len(m.Weights) == 0 {
+			return nil, fmt.Errorf("scorecard has no weights")
+		}
+		return &Scorecard{Name: name, Version: m.Version, Intercept: m.Intercept, Weights: m.Weights}, nil
+	case ModelGradeTable:
+		if len(m.PD) == 0 {
+			return nil, fmt.Errorf("grade table has no pd")
+		}
+		return &GradeTable{Name: name, Version: m.Version, PD: m.PD}, nil
+	case ModelRemote:
+		if m.URL == "" {
+			return nil, fmt.Errorf("remote model has no url")
+		}
+		var timeout time.Duration
+		if m.Timeout != "" {
+			d, err := time.ParseDuration(m.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("timeout: %w", err)
+			}
+			timeout = d
+		}
+		return NewRemote(name, m.Version, m.URL, timeout), nil
+	}
+	return nil, fmt.Errorf("unknown model type %q", m.Type)
+}
@@ -0,0 +1,97 @@
+package risk
+
+import (
+	"context"
+	"hash/fnv"
+
+	"loan"
+)
+
+// Experiment arms
+const (
+	ArmControl   = "control"
+	ArmTreatment = "treatment"
+)
+
+// Experiment runs two engines side by side, deciding each application
+// with the arm its customer is assigned to and recording the assignment
+// on the decision. Customers are assigned by a hash of the experiment
+// name and their ID, so they stay in one arm across applications and
+// restarts, and separate experiments split customers independently.
+type Experiment struct {
+	name      string
+	control   loan.RiskEngine
+	treatment loan.RiskEngine
+	// share is the treatment's part of the customers in basis points
+	share  uint32
+	shadow bool
+}
+
+// ExperimentOption configures an Experiment
+type ExperimentOption func(*Experiment)
+
+// WithTreatmentShare sends the fraction share of customers, between 0 and
+// 1, to the treatment instead of half
+func WithTreatmentShare(share float64) ExperimentOption {
+	return func(e *Experiment) { e.share = uint32(min(max(share, 0), 1) * 10000) }
+}
+
+// WithShadow also runs the arm not assigned and records what it would
+// have decided, comparing both policies on the same applications at the
+// cost of a second assessment each
+func WithShadow() ExperimentOption {
+	return func(e *Experiment) { e.shadow = true }
+}
+
+// NewExperiment creates the experiment name between the current control
+// engine and a treatment
+func NewExperiment(name string, control, treatment loan.RiskEngine, opts ...ExperimentOption) *Experiment {
+	e := &Experiment{name: name, control: control, treatment: treatment, share: 5000}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Name returns the experiment name recorded on decisions
+func (e *Experiment) Name() string {
+	return e.name
+}
+
+// Arm returns the arm customerID is assigned to
+func (e *Experiment) Arm(customerID string) string {
+	h := fnv.New32a()
+	h.Write([]byte(e.name))
+	h.Write([]byte{0})
+	h.Write([]byte(customerID))
+	if h.Sum32()%10000 < e.share {
+		return ArmTreatment
+	}
+	return ArmControl
+}
+
+// Assess implements loan.RiskEngine
+func (e *Experiment) Assess(ctx context.Context, in loan.RiskInputs) (loan.Decision, error) {
+	var customerID string
+	if in.Loan != nil {
+		customerID = in.Loan.CustomerID
+	}
+	arm := e.Arm(customerID)
+	engine, other := e.control, e.treatment
+	if arm == ArmTreatment {
+		engine, other = other, engine
+	}
+	d, err := engine.Assess(ctx, in)
+	if err != nil {
+		return loan.Decision{}, err
+	}
+	d.Experiment = &loan.ExperimentAssignment{Name: e.name, Arm: arm}
+	if e.shadow {
+		// the assigned arm has decided; a failing shadow only loses the
+		// comparison
+		if s, err := other.Assess(ctx, in); err == nil {
+			d.Experiment.ShadowOutcome = s.Outcome
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,92 @@
+package risk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"loan"
+	"loan/retry"
+	"loan/tracing"
+)
+
+// Remote scores with a model served over HTTP. It POSTs
+//
+//	{"features": {"bureau_score": 0.5, ...}}
+//
+// and expects
+//
+//	{"pd": 0.042, "version": "2026-09-30", "reasons": ["utilization"]}
+//
+// The version is optional; without it decisions record the configured one.
+// Reasons are reported as model factors of the decision.
+type Remote struct {
+	Name    string
+	Version string
+	URL     string
+	client  *http.Client
+}
+
+// NewRemote creates a scorer for the model endpoint url. A zero timeout
+// uses 5 seconds.
+func NewRemote(name, version, url string, timeout time.Duration) *Remote {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &Remote{
+		Name: name, Version: version, URL: url,
+		client: &http.Client{Timeout: timeout, Transport: tracing.Transport(nil)},
+	}
+}
+
+type remoteRequest struct {
+	Features Features `json:"features"`
+}
+
+type remoteResponse struct {
+	PD      *float64 `json:"pd"`
+	Version string   `json:"version"`
+	Reasons []string `json:"reasons"`
+}
+
+// Score implements Scorer
+func (r *Remote) Score(ctx context.Context, f Features) (Score, error) {
+	body, err := json.Marshal(remoteRequest{Features: f})
+	if err != nil {
+		return Score{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(body))
+	if err != nil {
+		return Score{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return Score{}, fmt.Errorf("model %s: %w", r.Name, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= http.StatusInternalServerError:
+		return Score{}, fmt.Errorf("model %s: status %s: %w", r.Name, resp.Status, retry.ErrTransient)
+	case resp.StatusCode != http.StatusOK:
+		return Score{}, fmt.Errorf("model %s: unexpected status %s", r.Name, resp.Status)
+	}
+	var out remoteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Score{}, fmt.Errorf("model %s: decoding score: %w", r.Name, err)
+	}
+	if out.PD == nil || *out.PD < 0 || *out.PD > 1 {
+		return Score{}, fmt.Errorf("model %s: response has no pd between 0 and 1", r.Name)
+	}
+	s := Score{PD: *out.PD, Model: r.Name, Version: r.Version}
+	for _, reason := range out.Reasons {
+		s.Factors = append(s.Factors, loan.DecisionFactor{Source: loan.FactorModel, Rule: reason, Message: reason})
+	}
+	if out.Version != "" {
+		s.Version = out.Version
+	}
+	return s, nil
+}
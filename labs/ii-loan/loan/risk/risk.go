@@ -0,0 +1,155 @@
+// Package risk implements loan.RiskEngine with underwriting rules over
+// the bureau score, the credit file, the customer's exposure and, when
+// available, affordability, and with scoring models chosen per product.
+package risk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"loan"
+)
+
+// RulesModel and RulesVersion name the rules on the decisions they make
+const (
+	RulesModel   = "rules"
+	RulesVersion = "1"
+)
+
+// Rule thresholds
+const (
+	// DeclineScore is the score below which applications are declined
+	DeclineScore = 500
+	// DeclineDaysPastDue declines customers with a recent delinquency this
+	// late
+	DeclineDaysPastDue = 90
+	// ReferInquiries refers customers shopping for credit with this many
+	// recent inquiries
+	ReferInquiries = 4
+	// MaxDebtService declines applicants whose debt repayments, the new
+	// installment included, would take more of their income
+	MaxDebtService = 0.5
+	// MinIncomeStability refers applicants with a less regular income
+	MinIncomeStability = 0.6
+)
+
+// Rules decides with fixed underwriting rules. Declines win over
+// referrals; applications no rule objects to are approved.
+type Rules struct {
+	now func() time.Time
+}
+
+// NewRules creates the rules engine
+func NewRules() *Rules {
+	return &Rules{now: time.Now}
+}
+
+// Assess implements loan.RiskEngine
+func (r *Rules) Assess(ctx context.Context, in loan.RiskInputs) (loan.Decision, error) {
+	if err := ctx.Err(); err != nil {
+		return loan.Decision{}, err
+	}
+	d := loan.Decision{Grade: loan.RiskGrade(in.Report.Score), Model: RulesModel, ModelVersion: RulesVersion, DecidedAt: r.now().UTC()}
+	decide(&d, policy(in))
+	return d, nil
+}
+
+// rule returns a policy factor for value failing threshold
+func rule(name, effect string, value float64, op string, threshold float64, format string, args ...any) loan.DecisionFactor {
+	return loan.DecisionFactor{
+		Source: loan.FactorPolicy, Rule: name, Effect: effect,
+		Value: value, Operator: op, Threshold: threshold, Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// policy returns the underwriting rules that fire on an application
+func policy(in loan.RiskInputs) []loan.DecisionFactor {
+	var fired []loan.DecisionFactor
+	add := func(f loan.DecisionFactor) { fired = append(fired, f) }
+	decline, refer := loan.OutcomeDecline, loan.OutcomeRefer
+
+	score, b := in.Report.Score, in.Bureau
+	switch {
+	case score <= 0:
+		add(rule("no_score", refer, 0, "", 0, "no credit score"))
+	case score < DeclineScore:
+		add(rule("min_score", decline, float64(score), "<", DeclineScore, "score %d < %d threshold", score, DeclineScore))
+	}
+	if b.WorstDaysPastDue >= DeclineDaysPastDue {
+		add(rule("max_days_past_due", decline, float64(b.WorstDaysPastDue), ">=", DeclineDaysPastDue,
+			"%d days past due >= %d threshold in the last %d months", b.WorstDaysPastDue, DeclineDaysPastDue, loan.DelinquencyWindowMonths))
+	} else if b.Delinquencies > 0 {
+		add(rule("delinquencies", refer, float64(b.Delinquencies), ">", 0,
+			"%d late payments > 0 in the last %d months", b.Delinquencies, loan.DelinquencyWindowMonths))
+	}
+	if in.Report.Bureau != "" && b.OpenTradeLines == 0 {
+		add(rule("thin_file", refer, 0, "=", 0, "thin file: 0 open trade lines"))
+	}
+	if b.RecentInquiries >= ReferInquiries {
+		add(rule("max_inquiries", refer, float64(b.RecentInquiries), ">=", ReferInquiries,
+			"%d credit inquiries >= %d threshold in the last %d months", b.RecentInquiries, ReferInquiries, loan.InquiryWindowMonths))
+	}
+	if e := in.Exposure; e.Limit > 0 && in.Loan != nil {
+		if total := e.Total() + in.Loan.Amount; total > e.Limit {
+			add(rule("max_exposure", decline, total, ">", e.Limit,
+				"exposure %.2f with this application > %.2f limit", total, e.Limit))
+		}
+	}
+	if a := in.Affordability; a != nil {
+		installment := monthlyInstallment(in.Loan)
+		if a.MonthlyIncome <= 0 {
+			add(rule("no_income", refer, 0, "", 0, "no income in the last %d months of bank history", a.Months))
+			return fired
+		}
+		if ds := (a.MonthlyDebtPayments + installment) / a.MonthlyIncome; ds > MaxDebtService {
+			add(rule("max_debt_service", decline, round4(ds), ">", MaxDebtService,
+				"debt service %.0f%% > %.0f%% threshold of income", ds*100, MaxDebtService*100))
+		}
+		if a.IncomeStability < MinIncomeStability {
+			add(rule("min_income_stability", refer, a.IncomeStability, "<", MinIncomeStability,
+				"income stability %.2f < %.2f threshold", a.IncomeStability, MinIncomeStability))
+		}
+		if a.DisposableIncome < installment {
+			add(rule("disposable_income", refer, a.DisposableIncome, "<", installment,
+				"disposable income %.2f < installment %.2f", a.DisposableIncome, installment))
+		}
+	}
+	return fired
+}
+
+// decide sets the outcome called for by the worst factor, with the
+// messages of the factors calling for it as reasons, and the explanation
+func decide(d *loan.Decision, factors []loan.DecisionFactor) {
+	d.Outcome, d.Reasons = loan.OutcomeApprove, nil
+	for _, outcome := range []string{loan.OutcomeDecline, loan.OutcomeRefer} {
+		for _, f := range factors {
+			if f.Effect == outcome {
+				d.Outcome, d.Reasons = outcome, append(d.Reasons, f.Message)
+			}
+		}
+		if d.Outcome != loan.OutcomeApprove {
+			break
+		}
+	}
+	if d.Explanation == nil {
+		d.Explanation = &loan.DecisionExplanation{}
+	}
+	d.Explanation.Factors = append([]loan.DecisionFactor{}, factors...)
+}
+
+// monthlyInstallment is the first installment of the loan applied for,
+// its largest under either amortization, as paid over a month at the
+// loan's repayment frequency
+func monthlyInstallment(l *loan.Loan) float64 {
+	if l == nil {
+		return 0
+	}
+	f := l.RepaymentFrequency()
+	s := loan.BuildSchedule(l.Amount, l.AnnualRate(), l.Installments(), time.Now(), loan.Amortized(l.Amortization), loan.Every(f))
+	if len(s) == 0 {
+		return 0
+	}
+	return math.Round(s[0].Amount*float64(loan.PeriodsPerYear(f))/12*100) / 100
+}
@@ -0,0 +1,173 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"loan"
+)
+
+// Feature names. Features are scaled to be of order one so scorecard
+// weights stay readable; a feature is missing when its input is unknown.
+const (
+	// FeatureScore is the bureau score less 650, in hundreds
+	FeatureScore = "bureau_score"
+	// FeatureOpenTradeLines counts the open trade lines
+	FeatureOpenTradeLines = "open_trade_lines"
+	// FeatureUtilization is the balance over the limit of open trade lines
+	FeatureUtilization = "utilization"
+	// FeatureDelinquencies counts recent late payments
+	FeatureDelinquencies = "delinquencies"
+	// FeatureWorstDaysPastDue is the worst recent days past due, in months
+	FeatureWorstDaysPastDue = "worst_days_past_due"
+	// FeatureRecentInquiries counts recent credit inquiries
+	FeatureRecentInquiries = "recent_inquiries"
+	// FeatureDebtService is debt repayments with the new installment over
+	// income
+	FeatureDebtService = "debt_service"
+	// FeatureIncomeStability is the affordability income stability
+	FeatureIncomeStability = "income_stability"
+	// FeatureTermYears is the term applied for, in years
+	FeatureTermYears = "term_years"
+)
+
+// Features are the model inputs derived from an application
+type Features map[string]float64
+
+// FeaturesOf derives the model inputs of an application
+func FeaturesOf(in loan.RiskInputs) Features {
+	f := Features{}
+	if in.Report.Score > 0 {
+		f[FeatureScore] = float64(in.Report.Score-650) / 100
+	}
+	if in.Report.Bureau != "" {
+		b := in.Bureau
+		f[FeatureOpenTradeLines] = float64(b.OpenTradeLines)
+		if b.TotalLimit > 0 {
+			f[FeatureUtilization] = b.TotalBalance / b.TotalLimit
+		}
+		f[FeatureDelinquencies] = float64(b.Delinquencies)
+		f[FeatureWorstDaysPastDue] = float64(b.WorstDaysPastDue) / 30
+		f[FeatureRecentInquiries] = float64(b.RecentInquiries)
+	}
+	if a := in.Affordability; a != nil && a.MonthlyIncome > 0 {
+		f[FeatureDebtService] = (a.MonthlyDebtPayments + monthlyInstallment(in.Loan)) / a.MonthlyIncome
+		f[FeatureIncomeStability] = a.IncomeStability
+	}
+	if in.Loan != nil {
+		f[FeatureTermYears] = float64(in.Loan.TermMonths) / 12
+	}
+	return f
+}
+
+// Score is a model's estimate for an application
+type Score struct {
+	// PD is the probability of default
+	PD      float64
+	Model   string
+	Version string
+	// Factors are the model's main factors, worst first
+	Factors []loan.DecisionFactor
+}
+
+// Scorer is a scoring model
+type Scorer interface {
+	Score(ctx context.Context, f Features) (Score, error)
+}
+
+// Scorecard is a logistic regression model: the PD is the logistic
+// function of the intercept plus the weighted features
+type Scorecard struct {
+	Name      string
+	Version   string
+	Intercept float64
+	Weights   map[string]float64
+}
+
+// DefaultScorecard is the scorecard used for products without a model
+var DefaultScorecard = &Scorecard{
+	Name: "scorecard", Version: "2026.1", Intercept: -2.5,
+	Weights: map[string]float64{
+		FeatureScore:            -1.0,
+		FeatureOpenTradeLines:   -0.1,
+		FeatureUtilization:      1.2,
+		FeatureDelinquencies:    0.35,
+		FeatureWorstDaysPastDue: 0.3,
+		FeatureRecentInquiries:  0.2,
+		FeatureDebtService:      2.0,
+		FeatureIncomeStability:  -1.0,
+		FeatureTermYears:        0.1,
+	},
+}
+
+// maxFactors is how many factors a scorecard reports
+const maxFactors = 3
+
+// Score implements Scorer
+func (s *Scorecard) Score(ctx context.Context, f Features) (Score, error) {
+	if err := ctx.Err(); err != nil {
+		return Score{}, err
+	}
+	logit := s.Intercept
+	var adverse []loan.DecisionFactor
+	for name, w := range s.Weights {
+		x, ok := f[name]
+		if !ok {
+			continue
+		}
+		logit += w * x
+		if w*x > 0 {
+			adverse = append(adverse, loan.DecisionFactor{
+				Source: loan.FactorModel, Rule: name, Value: round4(x), Contribution: round4(w * x),
+				Message: fmt.Sprintf("%s %.2f raised the log-odds by %.2f", name, x, w*x),
+			})
+		}
+	}
+	sort.Slice(adverse, func(i, j int) bool {
+		if adverse[i].Contribution != adverse[j].Contribution {
+			return adverse[i].Contribution > adverse[j].Contribution
+		}
+		return adverse[i].Rule < adverse[j].Rule
+	})
+	if len(adverse) > maxFactors {
+		adverse = adverse[:maxFactors]
+	}
+	return Score{PD: round4(1 / (1 + math.Exp(-logit))), Model: s.Name, Version: s.Version, Factors: adverse}, nil
+}
+
+// GradeTable is the simplest model: a fixed PD per bureau risk grade
+type GradeTable struct {
+	Name    string
+	Version string
+	PD      map[string]float64
+}
+
+// DefaultGradeTable holds long-run default rates per grade
+var DefaultGradeTable = &GradeTable{
+	Name: "grade-table", Version: "1",
+	PD: map[string]float64{"A": 0.01, "B": 0.025, "C": 0.05, "D": 0.1, "E": 0.25, "unrated": 0.08},
+}
+
+// Score implements Scorer
+func (g *GradeTable) Score(ctx context.Context, f Features) (Score, error) {
+	if err := ctx.Err(); err != nil {
+		return Score{}, err
+	}
+	grade := "unrated"
+	if x, ok := f[FeatureScore]; ok {
+		grade = loan.RiskGrade(int(math.Round(x*100 + 650)))
+	}
+	pd, ok := g.PD[grade]
+	if !ok {
+		return Score{}, fmt.Errorf("model %s: no pd for grade %s", g.Name, grade)
+	}
+	return Score{PD: pd, Model: g.Name, Version: g.Version, Factors: []loan.DecisionFactor{{
+		Source: loan.FactorModel, Rule: "grade", Value: pd, Message: fmt.Sprintf("grade %s has a %.1f%% default rate", grade, pd*100),
+	}}}, nil
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,35 @@
+package risk
+
+import (
+	"context"
+
+	"loan"
+	"loan/featureflag"
+)
+
+// FlagScoringModels moves tenants from the underwriting rules alone to the
+// scoring models
+const FlagScoringModels = "risk.scoring-models"
+
+// Toggle decides with one of two engines depending on a feature flag, so
+// a new engine can be turned on tenant by tenant and off again without a
+// redeploy
+type Toggle struct {
+	flags   featureflag.Flags
+	flag    string
+	on, off loan.RiskEngine
+}
+
+// NewToggle creates an engine using on while flag is enabled for the
+// application's tenant and off otherwise
+func NewToggle(flags featureflag.Flags, flag string, on, off loan.RiskEngine) *Toggle {
+	return &Toggle{flags: flags, flag: flag, on: on, off: off}
+}
+
+// Assess implements loan.RiskEngine
+func (t *Toggle) Assess(ctx context.Context, in loan.RiskInputs) (loan.Decision, error) {
+	if t.flags.Enabled(ctx, t.flag) {
+		return t.on.Assess(ctx, in)
+	}
+	return t.off.Assess(ctx, in)
+}
@@ -0,0 +1,176 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"loan/tracing"
+)
+
+// BucketClosed is where loans paid off or gone from the book during the
+// month roll to
+const BucketClosed Bucket = "closed"
+
+// ErrNoRollRates is returned for months no bucket transitions were
+// recorded for
+var ErrNoRollRates = errors.New("no bucket transitions")
+
+// BucketTransition is the move of one loan between the delinquency bucket
+// it closed the previous month in and the one it closed the month in
+type BucketTransition struct {
+	LoanID  string `json:"loanId"`
+	Product string `json:"product"`
+	From    Bucket `json:"from"`
+	To      Bucket `json:"to"`
+	// Outstanding is the balance at the end of the previous month, the
+	// amount that rolled
+	Outstanding float64 `json:"outstanding"`
+}
+
+// TransitionStore keeps the bucket transitions recorded for each month
+type TransitionStore interface {
+	// SaveTransitions replaces the transitions of the month of month
+	SaveTransitions(ctx context.Context, month time.Time, transitions []BucketTransition) error
+	// Transitions returns the transitions of the month of month, ordered by
+	// loan ID. The second result is false when none were recorded.
+	Transitions(ctx context.Context, month time.Time) ([]BucketTransition, bool, error)
+}
+
+// BucketTransitions pairs the loans owing a balance in the previous
+// month's aging snapshot with their bucket in the current one. Loans
+// missing from current or with nothing left to pay roll to BucketClosed;
+// loans new in current have no transition.
+func BucketTransitions(previous, current []AgingRecord) []BucketTransition {
+	now := make(map[string]AgingRecord, len(current))
+	for _, r := range current {
+		now[r.LoanID] = r
+	}
+	out := make([]BucketTransition, 0, len(previous))
+	for _, p := range previous {
+		if p.Outstanding <= 0 {
+			continue
+		}
+		t := BucketTransition{LoanID: p.LoanID, Product: p.Product, From: p.Bucket, To: BucketClosed, Outstanding: p.Outstanding}
+		if c, ok := now[p.LoanID]; ok && c.Outstanding > 0 {
+			t.To = c.Bucket
+		}
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LoanID < out[j].LoanID })
+	return out
+}
+
+// RollCell is the part of a bucket that moved to one destination
+type RollCell struct {
+	To          Bucket  `json:"to"`
+	Count       int     `json:"count"`
+	Outstanding float64 `json:"outstanding"`
+	// Rate is Outstanding over the balance the bucket started with
+	Rate float64 `json:"rate"`
+}
+
+// RollRow is where the loans of one bucket at the start of the month were
+// at its end
+type RollRow struct {
+	From        Bucket     `json:"from"`
+	Count       int        `json:"count"`
+	Outstanding float64    `json:"outstanding"`
+	To          []RollCell `json:"to"`
+	// RollForward is the share of the balance that moved to a worse bucket,
+	// the roll rate usually quoted as, e.g., 30→60
+	RollForward float64 `json:"rollForward"`
+	// CureRate is the share of the balance that moved to a better bucket
+	CureRate float64 `json:"cureRate"`
+}
+
+// RollRateReport is the roll-rate matrix of a month: for each bucket the
+// loans closed the previous month in, the share of their balance in each
+// bucket at the end of Month
+type RollRateReport struct {
+	Month time.Time `json:"month"`
+	// Rows are ordered as in AgingBuckets; buckets nothing started in are
+	// left out
+	Rows []RollRow `json:"rows"`
+}
+
+// WithTransitionStore reads roll rates from the transitions in store
+func WithTransitionStore(store TransitionStore) ReportingOption {
+	return func(s *ReportingService) { s.transitions = store }
+}
+
+// RollRates computes the roll-rate matrix from the bucket transitions
+// recorded for the month of month
+func (s *ReportingService) RollRates(ctx context.Context, month time.Time) (_ RollRateReport, err error) {
+	month = MonthStart(month)
+	ctx, span := tracing.Start(ctx, "ReportingService.RollRates", attribute.String("report.month", month.Format("2006-01")))
+	defer tracing.End(span, &err)
+	if s.transitions == nil {
+		return RollRateReport{}, fmt.Errorf("%w: no transition store configured", ErrNoRollRates)
+	}
+	transitions, ok, err := s.transitions.Transitions(ctx, month)
+	if err != nil {
+		return RollRateReport{}, err
+	}
+	if !ok {
+		return RollRateReport{}, fmt.Errorf("%w for %s", ErrNoRollRates, month.Format("2006-01"))
+	}
+	return RollRateReport{Month: month, Rows: rollRows(transitions)}, nil
+}
+
+// rollRows builds the matrix rows of transitions
+func rollRows(transitions []BucketTransition) []RollRow {
+	rows := map[Bucket]*RollRow{}
+	cells := map[Bucket]map[Bucket]*RollCell{}
+	for _, t := range transitions {
+		r := rows[t.From]
+		if r == nil {
+			r = &RollRow{From: t.From}
+			rows[t.From] = r
+			cells[t.From] = map[Bucket]*RollCell{}
+		}
+		c := cells[t.From][t.To]
+		if c == nil {
+			c = &RollCell{To: t.To}
+			cells[t.From][t.To] = c
+		}
+		r.Count++
+		r.Outstanding += t.Outstanding
+		c.Count++
+		c.Outstanding += t.Outstanding
+	}
+	out := make([]RollRow, 0, len(rows))
+	for from, r := range rows {
+		var forward, cured float64
+		for to, c := range cells[from] {
+			c.Outstanding = round2(c.Outstanding)
+			c.Rate = ratio(c.Outstanding, r.Outstanding)
+			r.To = append(r.To, *c)
+			switch {
+			case to == BucketClosed:
+			case to.Worse(from):
+				forward += c.Outstanding
+			case from.Worse(to):
+				cured += c.Outstanding
+			}
+		}
+		sort.Slice(r.To, func(i, j int) bool { return rollRank(r.To[i].To) < rollRank(r.To[j].To) })
+		r.RollForward, r.CureRate = ratio(forward, r.Outstanding), ratio(cured, r.Outstanding)
+		r.Outstanding = round2(r.Outstanding)
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].From.rank() < out[j].From.rank() })
+	return out
+}
+
+// rollRank orders BucketClosed after every delinquency bucket
+func rollRank(b Bucket) int {
+	if b == BucketClosed {
+		return len(AgingBuckets)
+	}
+	return b.rank()
+}
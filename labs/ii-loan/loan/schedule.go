@@ -0,0 +1,270 @@
+package loan
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"loan/calendar"
+)
+
+// Installment is one scheduled repayment of a loan
+type Installment struct {
+	Number    int       `json:"number"`
+	DueDate   time.Time `json:"dueDate"`
+	Principal float64   `json:"principal"`
+	Interest  float64   `json:"interest"`
+	Amount    float64   `json:"amount"`
+	Paid      float64   `json:"paid"`
+	// Escrow is the part of Amount collected for the loan's escrow
+	Escrow float64 `json:"escrow,omitempty"`
+	// Premium is the part of Amount paid for the loan's credit insurance
+	Premium float64 `json:"premium,omitempty"`
+}
+
+// Outstanding returns the part of the installment that is still unpaid
+func (i Installment) Outstanding() float64 {
+	return math.Max(round2(i.Amount-i.Paid), 0)
+}
+
+// addOns splits applied, paid towards the installment on top of what is
+// already paid, into its escrow and premium shares. The loan's part of an
+// installment is paid first, then escrow, then the premium.
+func (i Installment) addOns(applied float64) (escrow, premium float64) {
+	from, to := i.Paid, i.Paid+applied
+	share := func(lo, hi float64) float64 {
+		return math.Max(math.Min(to, hi)-math.Max(from, lo), 0)
+	}
+	loanPart := i.Amount - i.Escrow - i.Premium
+	return share(loanPart, loanPart+i.Escrow), share(loanPart+i.Escrow, i.Amount)
+}
+
+// IsPaid reports whether the installment has been settled in full
+func (i Installment) IsPaid() bool {
+	return i.Outstanding() == 0
+}
+
+// Amortization methods: how installments repay principal
+const (
+	// AmortizationAnnuity repays in equal installments, mostly interest at
+	// first and mostly principal towards the end
+	AmortizationAnnuity = "annuity"
+	// AmortizationStraightLine repays equal principal each month with the
+	// interest on what is left, so installments shrink and less interest is
+	// paid overall
+	AmortizationStraightLine = "straight_line"
+)
+
+// Amortizations lists every amortization method, the default first
+var Amortizations = []string{AmortizationAnnuity, AmortizationStraightLine}
+
+// ValidAmortization reports whether m is a known amortization method
+func ValidAmortization(m string) bool {
+	return slices.Contains(Amortizations, m)
+}
+
+// ScheduleOption adjusts how BuildSchedule lays out installments
+type ScheduleOption func(*scheduleConfig)
+
+type scheduleConfig struct {
+	calendar   *calendar.Calendar
+	convention calendar.Convention
+	method     string
+	frequency  string
+	skipped    int
+}
+
+// DueDatesOn moves due dates that are not business days of cal by conv.
+// Due dates still step a period at a time from the start, so an adjusted
+// date never shifts the ones after it.
+func DueDatesOn(cal *calendar.Calendar, conv calendar.Convention) ScheduleOption {
+	return func(c *scheduleConfig) { c.calendar, c.convention = cal, conv }
+}
+
+// Amortized lays installments out by method, one of Amortizations; empty
+// keeps the annuity
+func Amortized(method string) ScheduleOption {
+	return func(c *scheduleConfig) { c.method = method }
+}
+
+// Every lays installments out at frequency, one of Frequencies; empty
+// keeps them monthly
+func Every(frequency string) ScheduleOption {
+	return func(c *scheduleConfig) { c.frequency = frequency }
+}
+
+// skipping counts due dates as if n installments came before the first, so
+// a recalculated schedule keeps the due days of the one it replaces
+func skipping(n int) ScheduleOption {
+	return func(c *scheduleConfig) { c.skipped = n }
+}
+
+// BuildSchedule generates a schedule of installments monthly, or as often
+// as Every says, the first one period after start. It is an annuity unless
+// Amortized says otherwise, charging each period its share of annualRate.
+// Rounding is settled on the last installment, which repays whatever
+// principal is left.
+func BuildSchedule(principal, annualRate float64, installments int, start time.Time, opts ...ScheduleOption) []Installment {
+	if installments <= 0 {
+		return nil
+	}
+	var cfg scheduleConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	r := annualRate / float64(PeriodsPerYear(cfg.frequency))
+	payment := principal / float64(installments)
+	if r > 0 {
+		payment = principal * r / (1 - math.Pow(1+r, -float64(installments)))
+	}
+	payment = round2(payment)
+	straight := round2(principal / float64(installments))
+
+	schedule := make([]Installment, 0, installments)
+	remaining := principal
+	for n := 1; n <= installments; n++ {
+		interest := round2(remaining * r)
+		p := round2(payment - interest)
+		if cfg.method == AmortizationStraightLine {
+			p = straight
+		}
+		if n == installments {
+			p = round2(remaining)
+		}
+		remaining = round2(remaining - p)
+		due := dueAfter(start, cfg.frequency, cfg.skipped+n)
+		if cfg.calendar != nil {
+			due = cfg.calendar.Adjust(due, cfg.convention)
+		}
+		schedule = append(schedule, Installment{
+			Number:    n,
+			DueDate:   due,
+			Principal: p,
+			Interest:  interest,
+			Amount:    round2(p + interest),
+		})
+	}
+	return schedule
+}
+
+// AmortizationMethod is the loan's amortization, AmortizationAnnuity when
+// none is set
+func (l *Loan) AmortizationMethod() string {
+	if l.Amortization == "" {
+		return AmortizationAnnuity
+	}
+	return l.Amortization
+}
+
+// TotalInterest is the interest charged over installments
+func TotalInterest(installments []Installment) float64 {
+	var total float64
+	for _, inst := range installments {
+		total += inst.Interest
+	}
+	return round2(total)
+}
+
+// Bucket is a delinquency bucket based on days past due
+type Bucket string
+
+// Delinquency buckets
+const (
+	BucketCurrent Bucket = "current"
+	Bucket1To30   Bucket = "1-30"
+	Bucket31To60  Bucket = "31-60"
+	Bucket61To90  Bucket = "61-90"
+	Bucket90Plus  Bucket = "90+"
+)
+
+// BucketFor maps days past due to a delinquency bucket
+func BucketFor(dpd int) Bucket {
+	switch {
+	case dpd <= 0:
+		return BucketCurrent
+	case dpd <= 30:
+		return Bucket1To30
+	case dpd <= 60:
+		return Bucket31To60
+	case dpd <= 90:
+		return Bucket61To90
+	default:
+		return Bucket90Plus
+	}
+}
+
+// Worse reports whether b is a more severe bucket than other
+func (b Bucket) Worse(other Bucket) bool {
+	return b.rank() > other.rank()
+}
+
+func (b Bucket) rank() int {
+	switch b {
+	case Bucket1To30:
+		return 1
+	case Bucket31To60:
+		return 2
+	case Bucket61To90:
+		return 3
+	case Bucket90Plus:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// OldestUnpaid returns the earliest installment that is due and not paid
+func (l *Loan) OldestUnpaid(now time.Time) (Installment, bool) {
+	for _, inst := range l.Schedule {
+		if !inst.IsPaid() && !inst.DueDate.After(now) {
+			return inst, true
+		}
+	}
+	return Installment{}, false
+}
+
+// CalculateDaysPastDue returns how many days the oldest unpaid installment is overdue
+func (l *Loan) CalculateDaysPastDue(now time.Time) int {
+	inst, ok := l.OldestUnpaid(now)
+	if !ok {
+		return 0
+	}
+	return int(now.Sub(inst.DueDate).Hours() / 24)
+}
+
+// spreadAddOns sets the escrow payment and insurance premium as the shares
+// of the unpaid installments falling due after at
+func (l *Loan) spreadAddOns(at time.Time) {
+	if l.Escrow == nil && l.Insurance == nil {
+		return
+	}
+	var escrow, premium float64
+	if l.Escrow != nil {
+		escrow = l.Escrow.Payment
+	}
+	if l.Insurance != nil && l.Insurance.Claim == nil {
+		premium = l.Insurance.Premium
+	}
+	for i := range l.Schedule {
+		inst := &l.Schedule[i]
+		if inst.Paid > 0 || !inst.DueDate.After(at) {
+			continue
+		}
+		inst.Escrow, inst.Premium = escrow, premium
+		inst.Amount = round2(inst.Principal + inst.Interest + escrow + premium)
+	}
+}
+
+// addOnsDue is the escrow and premium still to be collected with the
+// schedule
+func (l *Loan) addOnsDue() float64 {
+	var due float64
+	for _, inst := range l.Schedule {
+		due += math.Min(inst.Escrow+inst.Premium, inst.Outstanding())
+	}
+	return round2(due)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
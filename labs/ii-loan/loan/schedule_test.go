@@ -0,0 +1,96 @@
+package loan
+
+import (
+	"testing"
+	"time"
+)
+
+// scheduleCases are loans both amortizations are laid out for
+var scheduleCases = []struct {
+	name         string
+	principal    float64
+	annualRate   float64
+	installments int
+}{
+	{"small short", 1_000, 0.12, 6},
+	{"typical", 50_000, 0.18, 24},
+	{"large long", 1_234_567.89, 0.075, 360},
+	{"rounding", 10_000, 0.0999, 7},
+}
+
+var scheduleStart = time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)
+
+func TestStraightLineChargesLessInterestThanAnnuity(t *testing.T) {
+	for _, c := range scheduleCases {
+		t.Run(c.name, func(t *testing.T) {
+			annuity := TotalInterest(BuildSchedule(c.principal, c.annualRate, c.installments, scheduleStart))
+			straight := TotalInterest(BuildSchedule(c.principal, c.annualRate, c.installments, scheduleStart, Amortized(AmortizationStraightLine)))
+			if straight >= annuity {
+				t.Errorf("straight-line interest %.2f, want less than the annuity's %.2f", straight, annuity)
+			}
+		})
+	}
+}
+
+func TestSchedulesRepayPrincipal(t *testing.T) {
+	for _, c := range scheduleCases {
+		for _, method := range Amortizations {
+			t.Run(c.name+"/"+method, func(t *testing.T) {
+				schedule := BuildSchedule(c.principal, c.annualRate, c.installments, scheduleStart, Amortized(method))
+				if len(schedule) != c.installments {
+					t.Fatalf("%d installments, want %d", len(schedule), c.installments)
+				}
+				var repaid float64
+				remaining := c.principal
+				for _, inst := range schedule {
+					repaid = round2(repaid + inst.Principal)
+					remaining = round2(remaining - inst.Principal)
+					if got := round2(inst.Principal + inst.Interest); inst.Amount != got {
+						t.Errorf("installment %d: amount %.2f, want principal and interest %.2f", inst.Number, inst.Amount, got)
+					}
+				}
+				if repaid != round2(c.principal) {
+					t.Errorf("principal repaid %.2f, want %.2f", repaid, c.principal)
+				}
+				if remaining != 0 {
+					t.Errorf("balance after the last installment %.2f, want 0", remaining)
+				}
+			})
+		}
+	}
+}
+
+func TestMonthlyDueDatesKeepMonthEnd(t *testing.T) {
+	for _, day := range []int{29, 30, 31} {
+		t.Run(time.Date(2024, time.December, day, 0, 0, 0, 0, time.UTC).Format("Jan 2"), func(t *testing.T) {
+			start := time.Date(2024, time.December, day, 0, 0, 0, 0, time.UTC)
+			schedule := BuildSchedule(12_000, 0.12, 12, start)
+			for _, inst := range schedule {
+				month := time.Month(int(start.Month())+inst.Number-1)%12 + 1
+				want := min(day, daysIn(2025, month))
+				if inst.DueDate.Month() != month || inst.DueDate.Day() != want {
+					t.Errorf("installment %d due %s, want %s %d", inst.Number, inst.DueDate.Format(time.DateOnly), month, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRecalculatedScheduleKeepsDueDays(t *testing.T) {
+	approved := time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)
+	l := &Loan{
+		Status:       StatusApproved,
+		InterestRate: 0.12,
+		ApprovedAt:   approved,
+		Schedule:     BuildSchedule(12_000, 0.12, 12, approved),
+	}
+	// after February's installment, clamped to the 28th, falls due
+	if _, err := l.Recalculate(TermsChangeRequest{InterestRate: 0.09, Reason: "rate reset", ChangedBy: "ops"}, l.Schedule[1].DueDate); err != nil {
+		t.Fatal(err)
+	}
+	for _, inst := range l.Schedule {
+		if want := dueAfter(approved, FrequencyMonthly, inst.Number); !inst.DueDate.Equal(want) {
+			t.Errorf("installment %d due %s, want %s", inst.Number, inst.DueDate.Format(time.DateOnly), want.Format(time.DateOnly))
+		}
+	}
+}
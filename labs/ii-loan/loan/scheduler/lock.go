@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Locker grants exclusive, expiring leases on a key. Instances sharing a
+// Locker backed by a common store (database, Redis, ...) will not run the
+// same job occurrence twice.
+type Locker interface {
+	// TryLock acquires key for ttl. It returns ok=false without error when
+	// another holder owns the lease. The release func gives it up early.
+	TryLock(ctx context.Context, key string, ttl time.Duration) (release func(), ok bool, err error)
+}
+
+// MemoryLocker is a Locker for a single process
+type MemoryLocker struct {
+	mu     sync.Mutex
+	leases map[string]time.Time
+	now    func() time.Time
+}
+
+// NewMemoryLocker creates an in-process locker
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{leases: make(map[string]time.Time), now: time.Now}
+}
+
+// TryLock acquires the lease unless an unexpired one exists
+func (m *MemoryLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if exp, held := m.leases[key]; held && now.Before(exp) {
+		return nil, false, nil
+	}
+	exp := now.Add(ttl)
+	m.leases[key] = exp
+	release := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.leases[key].Equal(exp) {
+			delete(m.leases, key)
+		}
+	}
+	return release, true, nil
+}
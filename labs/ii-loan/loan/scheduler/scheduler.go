@@ -0,0 +1,182 @@
+// Package scheduler runs recurring loan lifecycle jobs on cron-like
+// schedules, using a Locker so several service instances can share the
+// work without running the same occurrence twice.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job is a unit of scheduled work. now is the scheduled occurrence time,
+// which lets jobs compute business dates deterministically.
+type Job interface {
+	Name() string
+	Run(ctx context.Context, now time.Time) error
+}
+
+type funcJob struct {
+	name string
+	fn   func(ctx context.Context, now time.Time) error
+}
+
+func (j funcJob) Name() string                                 { return j.name }
+func (j funcJob) Run(ctx context.Context, now time.Time) error { return j.fn(ctx, now) }
+
+// NewJob adapts a function to the Job interface
+func NewJob(name string, fn func(ctx context.Context, now time.Time) error) Job {
+	return funcJob{name: name, fn: fn}
+}
+
+// ErrUnknownJob is returned by RunNow for unregistered job names
+var ErrUnknownJob = errors.New("scheduler: unknown job")
+
+type entry struct {
+	job      Job
+	schedule Schedule
+}
+
+// Scheduler triggers registered jobs when their schedules fire
+type Scheduler struct {
+	locker  Locker
+	lockTTL time.Duration
+	now     func() time.Time
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	order   []string
+}
+
+// Option configures a Scheduler
+type Option func(*Scheduler)
+
+// WithLockTTL sets how long an occurrence lease is held (default one hour).
+// It should exceed both the longest job run and clock skew between instances.
+func WithLockTTL(d time.Duration) Option {
+	return func(s *Scheduler) { s.lockTTL = d }
+}
+
+// WithLogger sets the logger used to report job failures
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Scheduler) { s.logger = l }
+}
+
+// New creates a scheduler coordinating through locker
+func New(locker Locker, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		locker:  locker,
+		lockTTL: time.Hour,
+		now:     time.Now,
+		logger:  slog.Default(),
+		entries: make(map[string]*entry),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add registers job to run on the given cron spec
+func (s *Scheduler) Add(spec string, job Job) error {
+	sched, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	return s.AddSchedule(sched, job)
+}
+
+// AddSchedule registers job with an already built schedule
+func (s *Scheduler) AddSchedule(sched Schedule, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, dup := s.entries[job.Name()]; dup {
+		return fmt.Errorf("scheduler: job %q already registered", job.Name())
+	}
+	s.entries[job.Name()] = &entry{job: job, schedule: sched}
+	s.order = append(s.order, job.Name())
+	return nil
+}
+
+// Jobs returns registered job names in registration order
+func (s *Scheduler) Jobs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.order...)
+}
+
+// Run drives all registered jobs until ctx is cancelled, then waits for
+// in-flight runs to finish. Jobs registered after Run starts are ignored.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	entries := make([]*entry, 0, len(s.order))
+	for _, name := range s.order {
+		entries = append(entries, s.entries[name])
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, e := range entries {
+		wg.Add(1)
+		go func(e *entry) {
+			defer wg.Done()
+			s.loop(ctx, e)
+		}(e)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	for {
+		next := e.schedule.Next(s.now())
+		if next.IsZero() {
+			s.logger.WarnContext(ctx, "scheduler: job has no future occurrences", "job", e.job.Name())
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		key := fmt.Sprintf("%s@%s", e.job.Name(), next.UTC().Format(time.RFC3339))
+		if err := s.runLocked(ctx, e.job, key, next, false); err != nil {
+			s.logger.ErrorContext(ctx, "scheduler: job failed", "job", e.job.Name(), "occurrence", next, "error", err)
+		}
+	}
+}
+
+// RunNow executes a job immediately, outside its schedule, still honouring
+// the lock so concurrent manual triggers do not overlap.
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	return s.runLocked(ctx, e.job, name+"@manual", s.now(), true)
+}
+
+// runLocked runs job under the lease key. Scheduled occurrences keep their
+// lease until it expires so a slower instance cannot pick the same slot up
+// after the first one finished.
+func (s *Scheduler) runLocked(ctx context.Context, job Job, key string, at time.Time, release bool) error {
+	unlock, ok, err := s.locker.TryLock(ctx, key, s.lockTTL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	if release {
+		defer unlock()
+	}
+	return job.Run(ctx, at)
+}
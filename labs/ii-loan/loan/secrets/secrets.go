@@ -0,0 +1,140 @@
+// Package secrets hands out credentials such as database passwords,
+// encryption keys and provider API keys from one place, whatever holds
+// them: environment variables, files mounted by the orchestrator, or a
+// Vault-compatible secret store. Secrets are named by slash-separated
+// paths, "db/password" or "bureau/api-key", and settings can refer to them
+// inline:
+//
+//	dsn, err := secrets.Expand(ctx, provider, "postgres://loan:${secret:db/password}@db/loan")
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrNotFound is returned for secrets the provider does not hold
+var ErrNotFound = errors.New("secrets: not found")
+
+// Provider looks secrets up by name
+type Provider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// validName restricts names to what every provider can map safely
+var validName = regexp.MustCompile(`^[A-Za-z0-9_.-]+(/[A-Za-z0-9_.-]+)*$`)
+
+// checkName rejects names that are empty, absolute or climb directories
+func checkName(name string) error {
+	if !validName.MatchString(name) || strings.Contains("/"+name+"/", "/../") || strings.Contains("/"+name+"/", "/./") {
+		return fmt.Errorf("secrets: invalid name %q", name)
+	}
+	return nil
+}
+
+// DefaultEnvPrefix starts the variables Env reads
+const DefaultEnvPrefix = "LOAN_SECRET_"
+
+// Env reads secrets from environment variables named by the prefix and the
+// secret's name in upper case with '/', '-' and '.' as '_':
+// "db/password" is LOAN_SECRET_DB_PASSWORD. It suits development; the
+// environment leaks into child processes and crash reports.
+type Env struct {
+	Prefix string
+	// Lookup reads a variable; nil is os.LookupEnv
+	Lookup func(string) (string, bool)
+}
+
+// NewEnv creates a provider reading variables with DefaultEnvPrefix
+func NewEnv() *Env {
+	return &Env{Prefix: DefaultEnvPrefix}
+}
+
+// Secret implements Provider
+func (e *Env) Secret(_ context.Context, name string) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	lookup := e.Lookup
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	v, ok := lookup(e.Prefix + strings.ToUpper(strings.NewReplacer("/", "_", "-", "_", ".", "_").Replace(name)))
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return v, nil
+}
+
+// Dir reads each secret from the file of its name under a directory, the
+// way Docker and Kubernetes mount them: "db/password" is
+// /run/secrets/db/password. A trailing newline is dropped.
+type Dir string
+
+// Secret implements Provider
+func (d Dir) Secret(_ context.Context, name string) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(filepath.Join(string(d), filepath.FromSlash(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("secrets: %w", err)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r"), nil
+}
+
+// Chain looks secrets up in each provider in turn, returning the first
+// found. Errors other than ErrNotFound stop the search.
+func Chain(providers ...Provider) Provider {
+	return chain(providers)
+}
+
+type chain []Provider
+
+func (c chain) Secret(ctx context.Context, name string) (string, error) {
+	for _, p := range c {
+		v, err := p.Secret(ctx, name)
+		if !errors.Is(err, ErrNotFound) {
+			return v, err
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// reference matches ${secret:name} in Expand
+var reference = regexp.MustCompile(`\$\{secret:([^}]*)\}`)
+
+// Expand replaces each ${secret:name} in s with the secret, failing on the
+// first that cannot be read
+func Expand(ctx context.Context, p Provider, s string) (string, error) {
+	var err error
+	out := reference.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		var v string
+		v, err = p.Secret(ctx, reference.FindStringSubmatch(ref)[1])
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// Optional returns the secret name, or "" if p does not hold it
+func Optional(ctx context.Context, p Provider, name string) (string, error) {
+	v, err := p.Secret(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	return v, err
+}
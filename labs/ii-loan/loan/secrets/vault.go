@@ -0,0 +1,142 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"loan/tracing"
+)
+
+// DefaultVaultTTL is how long a Vault provider keeps secrets it read
+const DefaultVaultTTL = 5 * time.Minute
+
+// Vault reads secrets from the KV version 2 engine of a Vault-compatible
+// server, GET {addr}/v1/{mount}/data/{path}. The last segment of a name is
+// the field of the secret at the path before it: "db/password" is the
+// password field of the secret db. A name without a slash is the value
+// field of its secret. Secrets are cached, so rotations show within the
+// TTL.
+type Vault struct {
+	addr   string
+	token  string
+	mount  string
+	ttl    time.Duration
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]vaultEntry
+}
+
+type vaultEntry struct {
+	fields  map[string]any
+	expires time.Time
+}
+
+// VaultOption configures a Vault provider
+type VaultOption func(*Vault)
+
+// WithMount sets the mount path of the KV engine instead of "secret"
+func WithMount(mount string) VaultOption {
+	return func(v *Vault) { v.mount = strings.Trim(mount, "/") }
+}
+
+// WithTTL sets how long secrets are cached instead of DefaultVaultTTL; 0
+// reads them on every lookup
+func WithTTL(ttl time.Duration) VaultOption {
+	return func(v *Vault) { v.ttl = ttl }
+}
+
+// WithHTTPClient overrides the HTTP client
+func WithHTTPClient(c *http.Client) VaultOption {
+	return func(v *Vault) { v.client = c }
+}
+
+// NewVault creates a provider for the server at addr, authenticating with
+// token
+func NewVault(addr, token string, opts ...VaultOption) *Vault {
+	v := &Vault{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		mount:  "secret",
+		ttl:    DefaultVaultTTL,
+		client: &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)},
+		cache:  make(map[string]vaultEntry),
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Secret implements Provider
+func (v *Vault) Secret(ctx context.Context, name string) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	p, field := path.Split(name)
+	p = strings.TrimSuffix(p, "/")
+	if p == "" {
+		p, field = name, "value"
+	}
+	fields, err := v.read(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	switch s := fields[field].(type) {
+	case string:
+		return s, nil
+	case nil:
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	default:
+		return "", fmt.Errorf("secrets: vault field %s of %s is not a string", field, p)
+	}
+}
+
+// read returns the fields of the secret at p, from the cache while fresh
+func (v *Vault) read(ctx context.Context, p string) (map[string]any, error) {
+	v.mu.Lock()
+	e, ok := v.cache[p]
+	v.mu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.fields, nil
+	}
+
+	u := v.addr + "/v1/" + v.mount + "/data/" + (&url.URL{Path: p}).EscapedPath()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: vault: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, p)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("secrets: vault: reading %s: unexpected status %s", p, resp.Status)
+	}
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("secrets: vault: decoding %s: %w", p, err)
+	}
+	if v.ttl > 0 {
+		v.mu.Lock()
+		v.cache[p] = vaultEntry{fields: body.Data.Data, expires: time.Now().Add(v.ttl)}
+		v.mu.Unlock()
+	}
+	return body.Data.Data, nil
+}
@@ -2,6 +2,17 @@ package loan
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"loan/calendar"
+	"loan/logging"
+	"loan/retry"
+	"loan/tracing"
 )
 
 // Technical Debt - Architectural Debt:
@@ -12,37 +23,534 @@ import (
 // - Missing proper error handling
 // - No context usage for timeouts and cancellation
 
+// ErrLoanNotFound is returned by repositories when no loan has the given ID
+var ErrLoanNotFound = errors.New("loan not found")
+
+// ErrConflict is returned by repositories updating a loan that was updated
+// since it was read; read it again and redo the change
+var ErrConflict = errors.New("loan was changed concurrently")
+
+// Filter narrows the loans returned by LoanRepository.List.
+// Zero-value fields do not filter.
+type Filter struct {
+	Statuses   []string
+	CustomerID string
+	// After keeps the loans listed after a position, to page through a
+	// listing without skipping or repeating loans stored meanwhile
+	After Position
+	// Limit caps how many loans List returns, the first in listing order
+	Limit int
+	// Conditions must all hold
+	Conditions []Condition
+}
+
+// Position is a loan's place in the listing order: by creation time, then
+// by ID
+type Position struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// PositionOf returns the position of l
+func PositionOf(l *Loan) Position {
+	return Position{CreatedAt: l.CreatedAt, ID: l.ID}
+}
+
+// IsZero reports whether p is the start of the listing
+func (p Position) IsZero() bool { return p.CreatedAt.IsZero() && p.ID == "" }
+
+// Precedes reports whether p comes before l in the listing order
+func (p Position) Precedes(l *Loan) bool {
+	if l.CreatedAt.Equal(p.CreatedAt) {
+		return l.ID > p.ID
+	}
+	return l.CreatedAt.After(p.CreatedAt)
+}
+
+// Match reports whether the loan satisfies the filter; Limit is left to
+// the repository
+func (f Filter) Match(l *Loan) bool {
+	if f.CustomerID != "" && l.CustomerID != f.CustomerID {
+		return false
+	}
+	if !f.After.IsZero() && !f.After.Precedes(l) {
+		return false
+	}
+	for _, c := range f.Conditions {
+		if !c.Match(l) {
+			return false
+		}
+	}
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, s := range f.Statuses {
+		if l.Status == s {
+			return true
+		}
+	}
+	return false
+}
+
 // LoanRepository interface for data persistence
 type LoanRepository interface {
 	Save(ctx context.Context, loan *Loan) error
 	FindByID(ctx context.Context, id string) (*Loan, error)
 	Update(ctx context.Context, loan *Loan) error
+	List(ctx context.Context, filter Filter) ([]*Loan, error)
 }
 
-// LoanService handles loan business logic
+// LoanService handles loan business logic. Every method runs in its own
+// span, as do the repository, credit bureau and publisher calls it makes.
 type LoanService struct {
-	repo LoanRepository
+	repo        LoanRepository
+	publisher   EventPublisher
+	bureau      CreditBureau
+	retry       retry.Policy
+	logger      *slog.Logger
+	payments    PaymentProvider
+	accounts    BankVerification
+	mandates    MandateRepository
+	risk        RiskEngine
+	accountData AccountDataProvider
+	transfers   TransferRepository
+	kyc         KYCChecker
+	schedule    []ScheduleOption
+	pricing     PricingSource
+	products    ProductCatalog
+	limits      Limits
+	watchlist   WatchlistScreener
+	customers   CustomerRepository
+}
+
+// Option configures optional LoanService dependencies
+type Option func(*LoanService)
+
+// WithEventPublisher sets the publisher that receives domain events
+func WithEventPublisher(p EventPublisher) Option {
+	return func(s *LoanService) {
+		s.publisher = p
+	}
+}
+
+// WithCreditBureau makes applications fetch a credit report before they
+// are stored
+func WithCreditBureau(b CreditBureau) Option {
+	return func(s *LoanService) {
+		s.bureau = b
+	}
+}
+
+// WithRiskEngine makes applications get the engine's decision before they
+// are stored
+func WithRiskEngine(e RiskEngine) Option {
+	return func(s *LoanService) {
+		s.risk = e
+	}
+}
+
+// WithRetryPolicy sets how transient repository and credit bureau
+// failures are retried (default retry.DefaultPolicy; retry.Never disables)
+func WithRetryPolicy(p retry.Policy) Option {
+	return func(s *LoanService) {
+		s.retry = p
+	}
+}
+
+// WithPaymentProvider sets the gateway used to disburse loans and collect
+// repayments
+func WithPaymentProvider(p PaymentProvider) Option {
+	return func(s *LoanService) {
+		s.payments = p
+	}
+}
+
+// WithBankVerification makes disbursements verify the payout account
+// first
+func WithBankVerification(v BankVerification) Option {
+	return func(s *LoanService) {
+		s.accounts = v
+	}
+}
+
+// WithBusinessCalendar makes approved loans' installments fall due on
+// business days of cal, moving the others by conv
+func WithBusinessCalendar(cal *calendar.Calendar, conv calendar.Convention) Option {
+	return func(s *LoanService) {
+		s.schedule = []ScheduleOption{DueDatesOn(cal, conv)}
+	}
+}
+
+// WithLogger sets the logger. Wrap its handler with logging.NewHandler to
+// get the request ID and tenant of each call on its lines.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *LoanService) {
+		s.logger = l
+	}
 }
 
 // NewLoanService creates a new loan service
-func NewLoanService(repo LoanRepository) *LoanService {
-	return &LoanService{
-		repo: repo,
+func NewLoanService(repo LoanRepository, opts ...Option) *LoanService {
+	s := &LoanService{
+		publisher: nopPublisher{},
+		retry:     retry.DefaultPolicy,
+		logger:    slog.Default(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.repo = tracedRepository{retryingRepository{repo, s.retry}}
+	return s
 }
 
 // ProcessLoanApplication handles the loan application process
-func (s *LoanService) ProcessLoanApplication(ctx context.Context, loan *Loan) error {
+func (s *LoanService) ProcessLoanApplication(ctx context.Context, loan *Loan) (err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.ProcessLoanApplication")
+	defer tracing.End(span, &err)
+
 	if err := loan.Validate(); err != nil {
 		return err
 	}
+	if err := s.limits.Check(loan); err != nil {
+		return err
+	}
+	if err := s.price(ctx, loan); err != nil {
+		return err
+	}
 
 	// Technical Debt - Missing Features:
-	// - Credit score check
-	// - Risk assessment
 	// - Fraud detection
 	// - Compliance checks
 	// - Automated approval rules
 
-	return s.repo.Save(ctx, loan)
-} 
\ No newline at end of file
+	if loan.ID == "" {
+		loan.ID = NewID()
+	}
+	span.SetAttributes(attrLoanID.String(loan.ID))
+	if loan.Status == "" {
+		loan.Status = StatusPending
+	}
+	if loan.CreatedAt.IsZero() {
+		loan.CreatedAt = time.Now().UTC()
+	}
+	if s.watchlist != nil {
+		if err := s.screen(ctx, loan); err != nil {
+			s.log(loan).ErrorContext(ctx, "screening applicant", "error", err)
+			return err
+		}
+	}
+	var report CreditReport
+	if s.bureau != nil {
+		report, err = s.creditReport(ctx, loan.CustomerID)
+		if err != nil {
+			s.log(loan).ErrorContext(ctx, "fetching credit report", "error", err)
+			return err
+		}
+		loan.CreditScore = report.Score
+	}
+	if s.risk != nil {
+		if err := s.assess(ctx, loan, report); err != nil {
+			s.log(loan).ErrorContext(ctx, "assessing application", "error", err)
+			return err
+		}
+	}
+	if err := s.repo.Save(ctx, loan); err != nil {
+		s.log(loan).ErrorContext(ctx, "saving loan application", "error", err)
+		return err
+	}
+	s.log(loan).InfoContext(ctx, "loan application submitted", "amount", loan.Amount, "term_months", loan.TermMonths)
+	return s.publish(ctx, loan, NewEvent(EventApplicationSubmitted, loan.ID, loan))
+}
+
+func (s *LoanService) creditReport(ctx context.Context, customerID string) (_ CreditReport, err error) {
+	ctx, span := tracing.Start(ctx, "CreditBureau.CreditReport")
+	defer tracing.End(span, &err)
+	report, err := retry.Value(ctx, s.retry, func(ctx context.Context) (CreditReport, error) {
+		return s.bureau.CreditReport(ctx, customerID)
+	})
+	if err != nil {
+		return CreditReport{}, fmt.Errorf("credit check: %w", err)
+	}
+	span.SetAttributes(attribute.String("credit.bureau", report.Bureau))
+	return report, nil
+}
+
+func (s *LoanService) assess(ctx context.Context, loan *Loan, report CreditReport) (err error) {
+	ctx, span := tracing.Start(ctx, "RiskEngine.Assess", attrLoanID.String(loan.ID))
+	defer tracing.End(span, &err)
+	now := time.Now()
+	in := RiskInputs{Loan: loan, Report: report, Bureau: report.Summary(now)}
+	if in.Exposure, err = s.CustomerExposure(ctx, loan.CustomerID); err != nil {
+		return fmt.Errorf("exposure: %w", err)
+	}
+	if s.accountData != nil {
+		if in.Affordability, err = s.affordability(ctx, loan.CustomerID, now); err != nil {
+			return err
+		}
+	}
+	d, err := retry.Value(ctx, s.retry, func(ctx context.Context) (Decision, error) {
+		return s.risk.Assess(ctx, in)
+	})
+	if err != nil {
+		return fmt.Errorf("risk assessment: %w", err)
+	}
+	span.SetAttributes(attribute.String("risk.outcome", d.Outcome), attribute.String("risk.model", d.Model))
+	loan.Decision = &d
+	return nil
+}
+
+// log returns the service logger annotated with the loan being worked on
+func (s *LoanService) log(l *Loan) *slog.Logger {
+	return s.logger.With(logging.Loan(l.ID), logging.Customer(l.CustomerID))
+}
+
+// publish sends e and logs when delivery fails; the state change it
+// describes has already been stored
+func (s *LoanService) publish(ctx context.Context, l *Loan, e Event) (err error) {
+	ctx, span := tracing.Start(ctx, "EventPublisher.Publish", attrLoanID.String(e.LoanID), attrEventType.String(string(e.Type)))
+	defer tracing.End(span, &err)
+	if err := s.publisher.Publish(ctx, e); err != nil {
+		s.log(l).ErrorContext(ctx, "publishing event", "event_type", e.Type, "error", err)
+		return err
+	}
+	return nil
+}
+
+// ApproveLoan approves a stored loan and publishes EventLoanApproved.
+// Applications with uncleared watchlist matches return ErrScreeningHold.
+func (s *LoanService) ApproveLoan(ctx context.Context, id string) (_ *Loan, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.ApproveLoan", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	loan, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.update(ctx, loan, func(l *Loan) error {
+		if l.Screening != nil && l.Screening.Status == ScreeningMatch {
+			return ErrScreeningHold
+		}
+		return l.Approve(s.schedule...)
+	})
+	if err != nil {
+		s.log(loan).ErrorContext(ctx, "updating approved loan", "error", err)
+		return nil, err
+	}
+	loan = stored
+	s.log(loan).InfoContext(ctx, "loan approved")
+	return loan, s.publish(ctx, loan, NewEvent(EventLoanApproved, loan.ID, loan))
+}
+
+// RejectLoan declines a stored loan and publishes EventLoanRejected
+func (s *LoanService) RejectLoan(ctx context.Context, id, reason string) (_ *Loan, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.RejectLoan", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	loan, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.update(ctx, loan, func(l *Loan) error { return l.Reject(reason) })
+	if err != nil {
+		s.log(loan).ErrorContext(ctx, "updating rejected loan", "error", err)
+		return nil, err
+	}
+	loan = stored
+	s.log(loan).InfoContext(ctx, "loan rejected", "reason", reason)
+	return loan, s.publish(ctx, loan, NewEvent(EventLoanRejected, loan.ID, loan))
+}
+
+// GetLoan returns a stored loan
+func (s *LoanService) GetLoan(ctx context.Context, id string) (_ *Loan, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.GetLoan", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+	return s.repo.FindByID(ctx, id)
+}
+
+// ListLoans returns the loans matching filter
+func (s *LoanService) ListLoans(ctx context.Context, filter Filter) (_ []*Loan, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.ListLoans")
+	defer tracing.End(span, &err)
+	return s.repo.List(ctx, filter)
+}
+
+// RecordPayment applies a repayment to a loan and publishes EventPaymentReceived
+func (s *LoanService) RecordPayment(ctx context.Context, id string, amount float64) (_ Payment, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.RecordPayment", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	loan, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return Payment{}, err
+	}
+	var payment Payment
+	stored, err := s.update(ctx, loan, func(l *Loan) (err error) {
+		payment, err = l.ApplyPayment(amount, time.Now())
+		return err
+	})
+	if err != nil {
+		s.log(loan).ErrorContext(ctx, "updating loan after payment", "error", err)
+		return Payment{}, err
+	}
+	loan = stored
+	s.log(loan).InfoContext(ctx, "payment recorded", "payment_id", payment.ID, "amount", payment.Amount, "balance", loan.Balance)
+	return payment, s.publish(ctx, loan, NewEvent(EventPaymentReceived, loan.ID, payment))
+}
+
+// Disburse pays the amount of an approved loan out to the payee through
+// the payment provider and publishes EventLoanDisbursed. With bank
+// verification configured the payee's account is verified first. If the
+// loan cannot be stored afterwards the payout is refunded, so money never
+// leaves without the loan recording it.
+func (s *LoanService) Disburse(ctx context.Context, id string, payee Payee) (_ *Loan, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.Disburse", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	if s.payments == nil {
+		return nil, ErrNoPaymentProvider
+	}
+	loan, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// check the transition before any money moves
+	if err := loan.Clone().Disburse("", time.Now()); err != nil {
+		return nil, err
+	}
+	if s.accounts != nil {
+		if err := s.verifyAccount(ctx, payee); err != nil {
+			s.log(loan).WarnContext(ctx, "payout account not verified", "error", err)
+			return nil, err
+		}
+	}
+	tx, err := s.transfer(ctx, PaymentOrder{Reference: loan.ID, Direction: Disbursement, Amount: loan.Amount, Account: payee.Account})
+	if err != nil {
+		s.log(loan).ErrorContext(ctx, "disbursing loan", "error", err)
+		return nil, err
+	}
+	stored, err := s.update(ctx, loan, func(l *Loan) error { return l.Disburse(tx.ID, time.Now()) })
+	if err != nil {
+		s.log(loan).ErrorContext(ctx, "updating disbursed loan", "transaction_id", tx.ID, "error", err)
+		return nil, errors.Join(err, s.reverse(ctx, loan, tx))
+	}
+	loan = stored
+	s.log(loan).InfoContext(ctx, "loan disbursed", "transaction_id", tx.ID, "amount", loan.Amount)
+	return loan, s.publish(ctx, loan, NewEvent(EventLoanDisbursed, loan.ID, tx))
+}
+
+func (s *LoanService) verifyAccount(ctx context.Context, payee Payee) (err error) {
+	ctx, span := tracing.Start(ctx, "BankVerification.VerifyAccount")
+	defer tracing.End(span, &err)
+	v, err := s.accounts.VerifyAccount(ctx, payee)
+	switch {
+	case err != nil:
+		return fmt.Errorf("verifying bank account: %w", err)
+	case !v.Exists:
+		return fmt.Errorf("%w: account does not exist", ErrAccountNotVerified)
+	case !v.Verified():
+		return fmt.Errorf("%w: holder name match is %s", ErrAccountNotVerified, v.Match)
+	}
+	span.SetAttributes(attribute.String("bank.verification.match", string(v.Match)))
+	return nil
+}
+
+// CollectPayment takes a repayment from the customer's account through the
+// payment provider and applies it like RecordPayment. A collection the
+// loan cannot record is refunded.
+func (s *LoanService) CollectPayment(ctx context.Context, id string, amount float64, account string) (_ Payment, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.CollectPayment", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	if s.payments == nil {
+		return Payment{}, ErrNoPaymentProvider
+	}
+	loan, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return Payment{}, err
+	}
+	// check the payment applies before any money moves
+	if _, err := loan.Clone().ApplyPayment(amount, time.Now()); err != nil {
+		return Payment{}, err
+	}
+	tx, err := s.transfer(ctx, PaymentOrder{Reference: loan.ID, Direction: Collection, Amount: round2(amount), Account: account})
+	if err != nil {
+		s.log(loan).ErrorContext(ctx, "collecting payment", "error", err)
+		return Payment{}, err
+	}
+	var payment Payment
+	stored, err := s.update(ctx, loan, func(l *Loan) (err error) {
+		payment, err = l.ApplyPayment(amount, time.Now())
+		return err
+	})
+	if err != nil {
+		s.log(loan).ErrorContext(ctx, "updating loan after collection", "transaction_id", tx.ID, "error", err)
+		return Payment{}, errors.Join(err, s.reverse(ctx, loan, tx))
+	}
+	loan = stored
+	s.log(loan).InfoContext(ctx, "payment collected", "payment_id", payment.ID, "transaction_id", tx.ID, "amount", payment.Amount, "balance", loan.Balance)
+	return payment, s.publish(ctx, loan, NewEvent(EventPaymentReceived, loan.ID, payment))
+}
+
+// transfer authorizes and captures order
+func (s *LoanService) transfer(ctx context.Context, order PaymentOrder) (_ Transaction, err error) {
+	ctx, span := tracing.Start(ctx, "PaymentProvider.Transfer", attrLoanID.String(order.Reference))
+	defer tracing.End(span, &err)
+	tx, err := s.payments.Authorize(ctx, order)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("authorizing %s: %w", order.Direction, err)
+	}
+	captured, err := s.payments.Capture(ctx, tx.ID)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("capturing %s %s: %w", order.Direction, tx.ID, err)
+	}
+	return captured, nil
+}
+
+// conflictRetries is how many times update redoes a change of a loan
+// updated meanwhile
+const conflictRetries = 3
+
+// update applies change to l and stores it. A loan updated since it was
+// read, such as by the accrual job, is read again and the change redone on
+// that, so no change fails on a conflict it could have survived and money
+// that moved is recorded rather than refunded. It returns the loan stored.
+func (s *LoanService) update(ctx context.Context, l *Loan, change func(*Loan) error) (*Loan, error) {
+	for attempt := 0; ; attempt++ {
+		if err := change(l); err != nil {
+			return nil, err
+		}
+		err := s.repo.Update(ctx, l)
+		if err == nil {
+			return l, nil
+		}
+		if !errors.Is(err, ErrConflict) || attempt == conflictRetries {
+			return nil, err
+		}
+		if l, err = s.repo.FindByID(ctx, l.ID); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// reverse refunds a captured transaction whose effect could not be stored.
+// It runs even when ctx is cancelled since the money has already moved.
+func (s *LoanService) reverse(ctx context.Context, l *Loan, tx Transaction) error {
+	if _, err := s.payments.Refund(context.WithoutCancel(ctx), tx.ID, tx.Amount); err != nil {
+		s.log(l).ErrorContext(ctx, "refunding unrecorded transaction", "transaction_id", tx.ID, "error", err)
+		return fmt.Errorf("refunding %s: %w", tx.ID, err)
+	}
+	return nil
+}
+
+// Statement builds the statement of a stored loan for the month containing
+// period
+func (s *LoanService) Statement(ctx context.Context, id string, period time.Time) (_ Statement, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.Statement", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	loan, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return Statement{}, err
+	}
+	return BuildStatement(loan, period), nil
+}
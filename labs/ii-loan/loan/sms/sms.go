@@ -0,0 +1,186 @@
+// Package sms abstracts SMS providers used for payment reminders and
+// one-time passwords.
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"loan"
+	"loan/i18n"
+	"loan/notification"
+	"loan/tracing"
+)
+
+// SMSSender sends a text message and returns the provider message ID
+type SMSSender interface {
+	SendSMS(ctx context.Context, to, body string) (string, error)
+}
+
+// Message is an SMS recorded by FakeSender
+type Message struct {
+	ID     string
+	To     string
+	Body   string
+	SentAt time.Time
+}
+
+// FakeSender records messages in memory instead of sending them.
+// Set Err to make every send fail.
+type FakeSender struct {
+	Err error
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+// SendSMS records the message
+func (f *FakeSender) SendSMS(ctx context.Context, to, body string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if f.Err != nil {
+		return "", f.Err
+	}
+	msg := Message{ID: loan.NewID(), To: to, Body: body, SentAt: time.Now().UTC()}
+	f.mu.Lock()
+	f.sent = append(f.sent, msg)
+	f.mu.Unlock()
+	return msg.ID, nil
+}
+
+// Sent returns all recorded messages
+func (f *FakeSender) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.sent...)
+}
+
+// Last returns the most recent message sent to a number
+func (f *FakeSender) Last(to string) (Message, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.sent) - 1; i >= 0; i-- {
+		if f.sent[i].To == to {
+			return f.sent[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// DefaultTwilioURL is the public Twilio REST API endpoint
+const DefaultTwilioURL = "https://api.twilio.com"
+
+// TwilioSender talks to the Twilio Messages API or any compatible service
+type TwilioSender struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+	// BaseURL defaults to DefaultTwilioURL; point it at a compatible
+	// provider or a local stub for the labs.
+	BaseURL string
+	Client  *http.Client
+}
+
+type twilioResponse struct {
+	SID          string `json:"sid"`
+	Status       string `json:"status"`
+	Code         int    `json:"code"`
+	Message      string `json:"message"`
+	ErrorCode    *int   `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// SendSMS posts the message to /2010-04-01/Accounts/{sid}/Messages.json
+func (t *TwilioSender) SendSMS(ctx context.Context, to, body string) (string, error) {
+	base := t.BaseURL
+	if base == "" {
+		base = DefaultTwilioURL
+	}
+	client := t.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)}
+	}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", strings.TrimRight(base, "/"), url.PathEscape(t.AccountSID))
+	form := url.Values{"To": {to}, "From": {t.From}, "Body": {body}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out twilioResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("sms: decode provider response (%s): %w", resp.Status, err)
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("sms: provider error %d: %s", out.Code, out.Message)
+	}
+	if out.ErrorCode != nil {
+		return "", fmt.Errorf("sms: provider error %d: %s", *out.ErrorCode, out.ErrorMessage)
+	}
+	return out.SID, nil
+}
+
+// Notifier is a notification channel sending short payment reminders in
+// the recipient's locale
+type Notifier struct {
+	sender  SMSSender
+	catalog *i18n.Catalog
+}
+
+// Option configures a Notifier
+type Option func(*Notifier)
+
+// WithCatalog takes the reminders from cat, under the keys
+// "sms.payment_due" and "sms.payment_overdue", instead of the built-in
+// i18n catalog
+func WithCatalog(cat *i18n.Catalog) Option {
+	return func(n *Notifier) { n.catalog = cat }
+}
+
+// NewNotifier creates an SMS notification channel
+func NewNotifier(sender SMSSender, opts ...Option) *Notifier {
+	n := &Notifier{sender: sender, catalog: i18n.Default()}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// Channel implements notification.Notifier
+func (n *Notifier) Channel() string { return "sms" }
+
+// Notify sends payment due and overdue reminders; other kinds are skipped
+func (n *Notifier) Notify(ctx context.Context, note notification.Notification) error {
+	if note.Recipient.Phone == "" {
+		return fmt.Errorf("sms: customer %s has no phone number", note.Recipient.CustomerID)
+	}
+	if note.Kind != notification.KindPaymentDue && note.Kind != notification.KindPaymentOverdue {
+		return nil
+	}
+	locale := n.catalog.Negotiate(note.Recipient.Locale)
+	body := n.catalog.Text(locale, "sms."+string(note.Kind), map[string]any{"loan": shortID(note.LoanID)})
+	_, err := n.sender.SendSMS(ctx, note.Recipient.Phone, body)
+	return err
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,79 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"loan"
+)
+
+// AgingStore keeps month-end aging snapshots in the aging_snapshots
+// table, one row per month, with a row per loan in aging_records
+type AgingStore struct {
+	db *DB
+}
+
+// NewAgingStore creates an aging store on a migrated database
+func NewAgingStore(db *DB) *AgingStore {
+	return &AgingStore{db: db}
+}
+
+// SaveAging implements loan.AgingStore, replacing the month's records in
+// one transaction
+func (s *AgingStore) SaveAging(ctx context.Context, month time.Time, records []loan.AgingRecord) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	key := period(month)
+	if _, err := tx.ExecContext(ctx, s.db.Dialect.rebind("DELETE FROM aging_records WHERE month = ?"), key); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, s.db.Dialect.rebind(`INSERT INTO aging_snapshots (month, taken_at) VALUES (?, ?)
+ON CONFLICT (month) DO UPDATE SET taken_at = excluded.taken_at`), key, time.Now().UTC()); err != nil {
+		return err
+	}
+	insert, err := tx.PrepareContext(ctx, s.db.Dialect.rebind(
+		"INSERT INTO aging_records (month, loan_id, product, bucket, days_past_due, outstanding) VALUES ("+placeholders(6)+")"))
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	for _, r := range records {
+		if _, err := insert.ExecContext(ctx, key, r.LoanID, r.Product, string(r.Bucket), r.DaysPastDue, r.Outstanding); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Aging implements loan.AgingStore
+func (s *AgingStore) Aging(ctx context.Context, month time.Time) ([]loan.AgingRecord, bool, error) {
+	key := period(month)
+	var found int
+	err := s.db.queryRow(ctx, "SELECT 1 FROM aging_snapshots WHERE month = ?", key).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	rows, err := s.db.query(ctx,
+		"SELECT loan_id, product, bucket, days_past_due, outstanding FROM aging_records WHERE month = ? ORDER BY loan_id", key)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	out := []loan.AgingRecord{}
+	for rows.Next() {
+		var r loan.AgingRecord
+		if err := rows.Scan(&r.LoanID, &r.Product, &r.Bucket, &r.DaysPastDue, &r.Outstanding); err != nil {
+			return nil, false, err
+		}
+		out = append(out, r)
+	}
+	return out, true, rows.Err()
+}
@@ -0,0 +1,69 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"loan"
+)
+
+// CustomerRepository stores customers in the customers table
+type CustomerRepository struct {
+	db *DB
+}
+
+// NewCustomerRepository creates a repository on a migrated database
+func NewCustomerRepository(db *DB) *CustomerRepository {
+	return &CustomerRepository{db: db}
+}
+
+const customerColumns = "id, name, email, phone, national_id, bank_account, address, date_of_birth, created_at, anonymized_at, pseudonym"
+
+func customerArgs(c *loan.Customer) []any {
+	return []any{c.ID, c.Name, c.Email, c.Phone, c.NationalID, c.BankAccount, c.Address, nullTime(c.DateOfBirth), c.CreatedAt.UTC(), nullTime(c.AnonymizedAt), c.Pseudonym}
+}
+
+// Save stores a new customer
+func (r *CustomerRepository) Save(ctx context.Context, c *loan.Customer) error {
+	_, err := r.db.exec(ctx, "INSERT INTO customers ("+customerColumns+") VALUES ("+placeholders(11)+")", customerArgs(c)...)
+	return err
+}
+
+// FindByID returns the stored customer
+func (r *CustomerRepository) FindByID(ctx context.Context, id string) (*loan.Customer, error) {
+	var (
+		c                  loan.Customer
+		born, anonymizedAt sql.NullTime
+	)
+	err := r.db.queryRow(ctx, "SELECT "+customerColumns+" FROM customers WHERE id = ?", id).
+		Scan(&c.ID, &c.Name, &c.Email, &c.Phone, &c.NationalID, &c.BankAccount, &c.Address, &born, &c.CreatedAt, &anonymizedAt, &c.Pseudonym)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, loan.ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.CreatedAt = c.CreatedAt.UTC()
+	c.DateOfBirth = born.Time
+	c.AnonymizedAt = anonymizedAt.Time
+	return &c, nil
+}
+
+// Update replaces an existing customer
+func (r *CustomerRepository) Update(ctx context.Context, c *loan.Customer) error {
+	args := customerArgs(c)
+	res, err := r.db.exec(ctx, `UPDATE customers SET name = ?, email = ?, phone = ?, national_id = ?, bank_account = ?,
+address = ?, date_of_birth = ?, created_at = ?, anonymized_at = ?, pseudonym = ? WHERE id = ?`, append(args[1:], c.ID)...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return loan.ErrCustomerNotFound
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+// Package drivers registers the database/sql drivers for the dialects
+// sqlstore supports: "sqlite" (pure Go, no cgo) and "pgx" for PostgreSQL.
+// Commands import it for its side effects.
+package drivers
+
+import (
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
@@ -0,0 +1,61 @@
+package sqlstore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// LeaseLocker is a scheduler.Locker keeping leases in the scheduler_leases
+// table, so instances sharing the database run each job occurrence once
+type LeaseLocker struct {
+	db  *DB
+	now func() time.Time
+}
+
+// NewLeaseLocker creates a locker on a migrated database
+func NewLeaseLocker(db *DB) *LeaseLocker {
+	return &LeaseLocker{db: db, now: time.Now}
+}
+
+// TryLock implements scheduler.Locker. The lease is taken in one
+// statement, inserting it or replacing an expired one, so of instances
+// racing for a key exactly one gets it. Expired leases of other keys,
+// such as past occurrences of a job, are removed on the way.
+func (l *LeaseLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	holder, err := leaseHolder()
+	if err != nil {
+		return nil, false, err
+	}
+	now := l.now()
+	if _, err := l.db.exec(ctx, "DELETE FROM scheduler_leases WHERE expires_at <= ?", now.UnixMilli()); err != nil {
+		return nil, false, err
+	}
+	res, err := l.db.exec(ctx, `INSERT INTO scheduler_leases (lease_key, holder, expires_at) VALUES (?, ?, ?)
+ON CONFLICT (lease_key) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+WHERE scheduler_leases.expires_at <= ?`, key, holder, now.Add(ttl).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return nil, false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return nil, false, err
+	}
+	release := func() {
+		// the lease lapses at its expiry if this fails, or the job's
+		// context is already cancelled
+		l.db.exec(context.WithoutCancel(ctx), "DELETE FROM scheduler_leases WHERE lease_key = ? AND holder = ?", key, holder)
+	}
+	return release, true, nil
+}
+
+// leaseHolder identifies one acquisition of a lease, so releasing it never
+// removes a lease taken since by another instance
+func leaseHolder() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,438 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"loan"
+)
+
+// LoanRepository stores loans in the loans table. The schedule and
+// payments are kept as JSON documents on the loan row; they are always
+// read and written together with it.
+type LoanRepository struct {
+	db *DB
+}
+
+// NewLoanRepository creates a repository on a migrated database
+func NewLoanRepository(db *DB) *LoanRepository {
+	return &LoanRepository{db: db}
+}
+
+// Ping implements health.Pinger
+func (r *LoanRepository) Ping(ctx context.Context) error {
+	return r.db.Ping(ctx)
+}
+
+// loanColumnNames are in the order of loanArgs and scanLoan; id comes
+// first and version last
+var loanColumnNames = []string{
+	"id", "customer_id", "status", "amount", "interest_rate", "term_months", "created_at", "approved_at",
+	"balance", "accrued_interest", "accrued_through", "days_past_due", "delinquency", "rejection_reason", "credit_score",
+	"product", "schedule", "payments", "disbursed_at", "disbursement_ref", "currency", "decision",
+	"screening", "purpose", "terms_changes",
+	"amortization", "frequency", "prepayment", "escrow", "insurance",
+	"version",
+}
+
+var loanColumns = strings.Join(loanColumnNames, ", ")
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+func loanArgs(l *loan.Loan) ([]any, error) {
+	schedule, err := json.Marshal(orEmpty(l.Schedule))
+	if err != nil {
+		return nil, err
+	}
+	payments, err := json.Marshal(orEmpty(l.Payments))
+	if err != nil {
+		return nil, err
+	}
+	changes, err := json.Marshal(orEmpty(l.TermsChanges))
+	if err != nil {
+		return nil, err
+	}
+	var decision, screening, prepayment, escrow, insurance sql.NullString
+	if l.Decision != nil {
+		if decision, err = nullJSON(l.Decision); err != nil {
+			return nil, err
+		}
+	}
+	if l.Screening != nil {
+		if screening, err = nullJSON(l.Screening); err != nil {
+			return nil, err
+		}
+	}
+	if l.Prepayment != nil {
+		if prepayment, err = nullJSON(l.Prepayment); err != nil {
+			return nil, err
+		}
+	}
+	if l.Escrow != nil {
+		if escrow, err = nullJSON(l.Escrow); err != nil {
+			return nil, err
+		}
+	}
+	if l.Insurance != nil {
+		if insurance, err = nullJSON(l.Insurance); err != nil {
+			return nil, err
+		}
+	}
+	return []any{
+		l.ID, l.CustomerID, l.Status, l.Amount, l.InterestRate, l.TermMonths, l.CreatedAt.UTC(), nullTime(l.ApprovedAt),
+		l.Balance, l.AccruedInterest, nullTime(l.AccruedThrough), l.DaysPastDue, string(l.Delinquency), l.RejectionReason, l.CreditScore,
+		l.Product, string(schedule), string(payments), nullTime(l.DisbursedAt), l.DisbursementRef, l.Currency, decision,
+		screening, l.Purpose, string(changes),
+		l.Amortization, l.Frequency, prepayment, escrow, insurance,
+		l.Version,
+	}, nil
+}
+
+// nullJSON encodes v for a nullable JSON column
+func nullJSON(v any) (sql.NullString, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func orEmpty[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLoan(row scanner) (*loan.Loan, error) {
+	var (
+		l                  loan.Loan
+		approved, through  sql.NullTime
+		disbursed          sql.NullTime
+		delinquency        string
+		schedule, payments []byte
+		decision           []byte
+		screening, changes []byte
+		prepayment, escrow []byte
+		insurance          []byte
+	)
+	err := row.Scan(&l.ID, &l.CustomerID, &l.Status, &l.Amount, &l.InterestRate, &l.TermMonths, &l.CreatedAt, &approved,
+		&l.Balance, &l.AccruedInterest, &through, &l.DaysPastDue, &delinquency, &l.RejectionReason, &l.CreditScore,
+		&l.Product, &schedule, &payments, &disbursed, &l.DisbursementRef, &l.Currency, &decision,
+		&screening, &l.Purpose, &changes,
+		&l.Amortization, &l.Frequency, &prepayment, &escrow, &insurance,
+		&l.Version)
+	if err != nil {
+		return nil, err
+	}
+	l.CreatedAt = l.CreatedAt.UTC()
+	l.ApprovedAt = approved.Time
+	l.AccruedThrough = through.Time
+	l.DisbursedAt = disbursed.Time
+	l.Delinquency = loan.Bucket(delinquency)
+	if err := json.Unmarshal(schedule, &l.Schedule); err != nil {
+		return nil, fmt.Errorf("sqlstore: loan %s schedule: %w", l.ID, err)
+	}
+	if err := json.Unmarshal(payments, &l.Payments); err != nil {
+		return nil, fmt.Errorf("sqlstore: loan %s payments: %w", l.ID, err)
+	}
+	if len(decision) > 0 {
+		if err := json.Unmarshal(decision, &l.Decision); err != nil {
+			return nil, fmt.Errorf("sqlstore: loan %s decision: %w", l.ID, err)
+		}
+	}
+	if len(screening) > 0 {
+		if err := json.Unmarshal(screening, &l.Screening); err != nil {
+			return nil, fmt.Errorf("sqlstore: loan %s screening: %w", l.ID, err)
+		}
+	}
+	if len(prepayment) > 0 {
+		if err := json.Unmarshal(prepayment, &l.Prepayment); err != nil {
+			return nil, fmt.Errorf("sqlstore: loan %s prepayment: %w", l.ID, err)
+		}
+	}
+	if len(escrow) > 0 {
+		if err := json.Unmarshal(escrow, &l.Escrow); err != nil {
+			return nil, fmt.Errorf("sqlstore: loan %s escrow: %w", l.ID, err)
+		}
+	}
+	if len(insurance) > 0 {
+		if err := json.Unmarshal(insurance, &l.Insurance); err != nil {
+			return nil, fmt.Errorf("sqlstore: loan %s insurance: %w", l.ID, err)
+		}
+	}
+	if len(l.Schedule) == 0 {
+		l.Schedule = nil
+	}
+	if err := json.Unmarshal(changes, &l.TermsChanges); err != nil {
+		return nil, fmt.Errorf("sqlstore: loan %s terms changes: %w", l.ID, err)
+	}
+	if len(l.Payments) == 0 {
+		l.Payments = nil
+	}
+	if len(l.TermsChanges) == 0 {
+		l.TermsChanges = nil
+	}
+	return &l, nil
+}
+
+// Save stores a new loan
+func (r *LoanRepository) Save(ctx context.Context, l *loan.Loan) error {
+	args, err := loanArgs(l)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.exec(ctx, "INSERT INTO loans ("+loanColumns+") VALUES ("+placeholders(len(args))+")", args...)
+	return err
+}
+
+// FindByID returns the stored loan
+func (r *LoanRepository) FindByID(ctx context.Context, id string) (*loan.Loan, error) {
+	l, err := scanLoan(r.db.queryRow(ctx, "SELECT "+loanColumns+" FROM loans WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, loan.ErrLoanNotFound
+	}
+	return l, err
+}
+
+// Update replaces an existing loan unless it was updated since l was read
+func (r *LoanRepository) Update(ctx context.Context, l *loan.Loan) error {
+	args, err := loanArgs(l)
+	if err != nil {
+		return err
+	}
+	args[len(args)-1] = l.Version + 1
+	sets := make([]string, 0, len(loanColumnNames)-1)
+	for _, c := range loanColumnNames[1:] {
+		sets = append(sets, c+" = ?")
+	}
+	res, err := r.db.exec(ctx, "UPDATE loans SET "+strings.Join(sets, ", ")+" WHERE id = ? AND version = ?", append(args[1:], l.ID, l.Version)...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		var found int
+		err := r.db.queryRow(ctx, "SELECT 1 FROM loans WHERE id = ?", l.ID).Scan(&found)
+		if errors.Is(err, sql.ErrNoRows) {
+			return loan.ErrLoanNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return loan.ErrConflict
+	}
+	l.Version++
+	return nil
+}
+
+// filterClause returns the WHERE clause, if any, selecting the loans that
+// match filter, with its arguments. Extra conditions are ANDed on.
+func filterClause(filter loan.Filter, extra ...string) (string, []any) {
+	where := extra
+	var args []any
+	if filter.CustomerID != "" {
+		where = append(where, "customer_id = ?")
+		args = append(args, filter.CustomerID)
+	}
+	if len(filter.Statuses) > 0 {
+		where = append(where, "status IN ("+placeholders(len(filter.Statuses))+")")
+		for _, s := range filter.Statuses {
+			args = append(args, s)
+		}
+	}
+	if !filter.After.IsZero() {
+		where = append(where, "(created_at > ? OR (created_at = ? AND id > ?))")
+		after := filter.After.CreatedAt.UTC()
+		args = append(args, after, after, filter.After.ID)
+	}
+	for _, c := range filter.Conditions {
+		clause, cargs := conditionClause(c)
+		where = append(where, clause)
+		args = append(args, cargs...)
+	}
+	if len(where) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(where, " AND "), args
+}
+
+// conditionColumns maps the fields of loan.Condition to columns
+var conditionColumns = map[string]string{
+	loan.FieldStatus:     "status",
+	loan.FieldCustomerID: "customer_id",
+	loan.FieldProduct:    "product",
+	loan.FieldPurpose:    "purpose",
+	loan.FieldAmount:     "amount",
+	loan.FieldTermMonths: "term_months",
+	loan.FieldCreatedAt:  "created_at",
+}
+
+// conditionClause renders c as SQL; invalid conditions match nothing, as
+// they do in memory
+func conditionClause(c loan.Condition) (string, []any) {
+	column, ok := conditionColumns[c.Field]
+	if !ok || c.Validate() != nil {
+		return "1 = 0", nil
+	}
+	switch v := c.Value.(type) {
+	case []string:
+		args := make([]any, len(v))
+		for i, s := range v {
+			args[i] = s
+		}
+		if len(v) == 0 {
+			return "1 = 0", nil
+		}
+		return column + " IN (" + placeholders(len(v)) + ")", args
+	case time.Time:
+		return column + " " + string(c.Op) + " ?", []any{v.UTC()}
+	}
+	return column + " " + string(c.Op) + " ?", []any{c.Value}
+}
+
+// List returns the loans matching the filter ordered by creation time
+func (r *LoanRepository) List(ctx context.Context, filter loan.Filter) ([]*loan.Loan, error) {
+	var out []*loan.Loan
+	err := r.Each(ctx, filter, func(l *loan.Loan) error {
+		out = append(out, l)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Each implements loan.Streamer, scanning one row at a time
+func (r *LoanRepository) Each(ctx context.Context, filter loan.Filter, fn func(*loan.Loan) error) error {
+	where, args := filterClause(filter)
+	query := "SELECT " + loanColumns + " FROM loans" + where + " ORDER BY created_at, id"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		l, err := scanLoan(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StatementStore stores monthly statements as JSON documents
+type StatementStore struct {
+	db *DB
+}
+
+// NewStatementStore creates a statement store on a migrated database
+func NewStatementStore(db *DB) *StatementStore {
+	return &StatementStore{db: db}
+}
+
+func period(t time.Time) string {
+	return loan.MonthStart(t).Format("2006-01")
+}
+
+// SaveStatement stores st, replacing any earlier statement for the same month
+func (s *StatementStore) SaveStatement(ctx context.Context, st loan.Statement) error {
+	body, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.exec(ctx, `INSERT INTO statements (loan_id, period, generated_at, body) VALUES (?, ?, ?, ?)
+ON CONFLICT (loan_id, period) DO UPDATE SET generated_at = excluded.generated_at, body = excluded.body`,
+		st.LoanID, period(st.Period), st.GeneratedAt.UTC(), string(body))
+	return err
+}
+
+// FindStatement returns the statement for a loan and month
+func (s *StatementStore) FindStatement(ctx context.Context, loanID string, at time.Time) (loan.Statement, bool, error) {
+	var body []byte
+	err := s.db.queryRow(ctx, "SELECT body FROM statements WHERE loan_id = ? AND period = ?", loanID, period(at)).Scan(&body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return loan.Statement{}, false, nil
+	}
+	if err != nil {
+		return loan.Statement{}, false, err
+	}
+	var st loan.Statement
+	if err := json.Unmarshal(body, &st); err != nil {
+		return loan.Statement{}, false, err
+	}
+	return st, true, nil
+}
+
+// EraseCustomer implements loan.CustomerEraser by rewriting the statements
+// of the customer's loans under the pseudonym
+func (s *StatementStore) EraseCustomer(ctx context.Context, customerID, pseudonym string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, s.db.Dialect.rebind(
+		"SELECT s.loan_id, s.period, s.body FROM statements s JOIN loans l ON l.id = s.loan_id WHERE l.customer_id = ?"), customerID)
+	if err != nil {
+		return err
+	}
+	type row struct {
+		loanID, period string
+		body           []byte
+	}
+	var found []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.loanID, &r.period, &r.body); err != nil {
+			rows.Close()
+			return err
+		}
+		found = append(found, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, r := range found {
+		var st loan.Statement
+		if err := json.Unmarshal(r.body, &st); err != nil {
+			return err
+		}
+		st.CustomerID = pseudonym
+		body, err := json.Marshal(st)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, s.db.Dialect.rebind("UPDATE statements SET body = ? WHERE loan_id = ? AND period = ?"),
+			string(body), r.loanID, r.period); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,91 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"loan"
+)
+
+// MandateRepository stores direct debit mandates in the mandates table,
+// one row per loan with the attempts kept as a JSON document
+type MandateRepository struct {
+	db *DB
+}
+
+// NewMandateRepository creates a repository on a migrated database
+func NewMandateRepository(db *DB) *MandateRepository {
+	return &MandateRepository{db: db}
+}
+
+const mandateColumns = "loan_id, id, account, status, retry_days, created_at, cancelled_at, attempts"
+
+// SaveMandate stores m, replacing the loan's earlier mandate
+func (r *MandateRepository) SaveMandate(ctx context.Context, m *loan.Mandate) error {
+	retryDays, err := json.Marshal(orEmpty(m.RetryDays))
+	if err != nil {
+		return err
+	}
+	attempts, err := json.Marshal(orEmpty(m.Attempts))
+	if err != nil {
+		return err
+	}
+	_, err = r.db.exec(ctx, `INSERT INTO mandates (`+mandateColumns+`) VALUES (`+placeholders(8)+`)
+ON CONFLICT (loan_id) DO UPDATE SET id = excluded.id, account = excluded.account, status = excluded.status,
+retry_days = excluded.retry_days, created_at = excluded.created_at, cancelled_at = excluded.cancelled_at, attempts = excluded.attempts`,
+		m.LoanID, m.ID, m.Account, m.Status, string(retryDays), m.CreatedAt.UTC(), nullTime(m.CancelledAt), string(attempts))
+	return err
+}
+
+func scanMandate(row scanner) (*loan.Mandate, error) {
+	var (
+		m                   loan.Mandate
+		cancelled           sql.NullTime
+		retryDays, attempts []byte
+	)
+	if err := row.Scan(&m.LoanID, &m.ID, &m.Account, &m.Status, &retryDays, &m.CreatedAt, &cancelled, &attempts); err != nil {
+		return nil, err
+	}
+	m.CreatedAt = m.CreatedAt.UTC()
+	m.CancelledAt = cancelled.Time
+	if err := json.Unmarshal(retryDays, &m.RetryDays); err != nil {
+		return nil, fmt.Errorf("sqlstore: mandate %s retry days: %w", m.ID, err)
+	}
+	if err := json.Unmarshal(attempts, &m.Attempts); err != nil {
+		return nil, fmt.Errorf("sqlstore: mandate %s attempts: %w", m.ID, err)
+	}
+	if len(m.Attempts) == 0 {
+		m.Attempts = nil
+	}
+	return &m, nil
+}
+
+// FindMandate returns the loan's mandate
+func (r *MandateRepository) FindMandate(ctx context.Context, loanID string) (*loan.Mandate, error) {
+	m, err := scanMandate(r.db.queryRow(ctx, "SELECT "+mandateColumns+" FROM mandates WHERE loan_id = ?", loanID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, loan.ErrMandateNotFound
+	}
+	return m, err
+}
+
+// ActiveMandates returns the active mandates, oldest first
+func (r *MandateRepository) ActiveMandates(ctx context.Context) ([]*loan.Mandate, error) {
+	rows, err := r.db.query(ctx, "SELECT "+mandateColumns+" FROM mandates WHERE status = ? ORDER BY created_at, loan_id", loan.MandateActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*loan.Mandate
+	for rows.Next() {
+		m, err := scanMandate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,7 @@
+CREATE TABLE statements (
+    loan_id      TEXT NOT NULL REFERENCES loans (id),
+    period       TEXT NOT NULL,
+    generated_at TIMESTAMPTZ NOT NULL,
+    body         JSONB NOT NULL,
+    PRIMARY KEY (loan_id, period)
+);
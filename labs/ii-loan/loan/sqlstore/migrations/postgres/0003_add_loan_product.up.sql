@@ -0,0 +1,3 @@
+ALTER TABLE loans ADD COLUMN product TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX loans_product ON loans (product);
@@ -0,0 +1,11 @@
+CREATE TABLE customers (
+    id            TEXT PRIMARY KEY,
+    name          TEXT NOT NULL,
+    email         TEXT NOT NULL DEFAULT '',
+    phone         TEXT NOT NULL DEFAULT '',
+    national_id   TEXT NOT NULL DEFAULT '',
+    address       TEXT NOT NULL DEFAULT '',
+    date_of_birth TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL,
+    anonymized_at TIMESTAMPTZ
+);
@@ -0,0 +1 @@
+ALTER TABLE customers ADD COLUMN bank_account TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE loans DROP COLUMN disbursement_ref;
+ALTER TABLE loans DROP COLUMN disbursed_at;
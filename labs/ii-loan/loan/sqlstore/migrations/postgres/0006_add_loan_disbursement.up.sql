@@ -0,0 +1,2 @@
+ALTER TABLE loans ADD COLUMN disbursed_at TIMESTAMPTZ;
+ALTER TABLE loans ADD COLUMN disbursement_ref TEXT NOT NULL DEFAULT '';
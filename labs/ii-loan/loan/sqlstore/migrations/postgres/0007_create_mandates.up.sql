@@ -0,0 +1,12 @@
+CREATE TABLE mandates (
+    loan_id      TEXT PRIMARY KEY REFERENCES loans (id),
+    id           TEXT NOT NULL,
+    account      TEXT NOT NULL,
+    status       TEXT NOT NULL,
+    retry_days   JSONB NOT NULL DEFAULT '[]',
+    created_at   TIMESTAMPTZ NOT NULL,
+    cancelled_at TIMESTAMPTZ,
+    attempts     JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX mandates_status ON mandates (status);
@@ -0,0 +1 @@
+ALTER TABLE loans DROP COLUMN decision;
@@ -0,0 +1,14 @@
+CREATE TABLE aging_snapshots (
+    month    TEXT PRIMARY KEY,
+    taken_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE aging_records (
+    month         TEXT NOT NULL REFERENCES aging_snapshots (month),
+    loan_id       TEXT NOT NULL,
+    product       TEXT NOT NULL DEFAULT '',
+    bucket        TEXT NOT NULL,
+    days_past_due INTEGER NOT NULL DEFAULT 0,
+    outstanding   DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (month, loan_id)
+);
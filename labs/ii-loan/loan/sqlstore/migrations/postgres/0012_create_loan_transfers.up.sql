@@ -0,0 +1,19 @@
+CREATE TABLE loan_transfers (
+    id               TEXT PRIMARY KEY,
+    loan_id          TEXT NOT NULL REFERENCES loans (id),
+    from_customer_id TEXT NOT NULL,
+    to_customer_id   TEXT NOT NULL,
+    reason           TEXT NOT NULL,
+    status           TEXT NOT NULL,
+    balance          DOUBLE PRECISION NOT NULL,
+    requested_by     TEXT NOT NULL,
+    requested_at     TIMESTAMPTZ NOT NULL,
+    kyc              JSONB NOT NULL,
+    credit_score     INTEGER NOT NULL DEFAULT 0,
+    decision         JSONB,
+    decided_by       TEXT NOT NULL DEFAULT '',
+    decided_at       TIMESTAMPTZ,
+    rejection_reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX loan_transfers_loan ON loan_transfers (loan_id, requested_at);
@@ -0,0 +1,8 @@
+CREATE TABLE products (
+    code       TEXT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    min_amount DOUBLE PRECISION NOT NULL DEFAULT 0,
+    max_amount DOUBLE PRECISION NOT NULL DEFAULT 0,
+    rate       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    terms      JSONB NOT NULL DEFAULT '[]'
+);
@@ -0,0 +1,4 @@
+ALTER TABLE products DROP COLUMN purpose_rates;
+ALTER TABLE products DROP COLUMN purposes;
+
+ALTER TABLE loans DROP COLUMN purpose;
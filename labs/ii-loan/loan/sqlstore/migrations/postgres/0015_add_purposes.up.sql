@@ -0,0 +1,4 @@
+ALTER TABLE loans ADD COLUMN purpose TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE products ADD COLUMN purposes JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE products ADD COLUMN purpose_rates JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+ALTER TABLE products DROP COLUMN amortization;
+
+ALTER TABLE loans DROP COLUMN amortization;
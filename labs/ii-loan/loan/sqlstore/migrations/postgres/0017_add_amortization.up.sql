@@ -0,0 +1,3 @@
+ALTER TABLE loans ADD COLUMN amortization TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE products ADD COLUMN amortization TEXT NOT NULL DEFAULT '';
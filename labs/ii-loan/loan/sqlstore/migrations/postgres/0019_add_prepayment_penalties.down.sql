@@ -0,0 +1,3 @@
+ALTER TABLE products DROP COLUMN prepayment;
+
+ALTER TABLE loans DROP COLUMN prepayment;
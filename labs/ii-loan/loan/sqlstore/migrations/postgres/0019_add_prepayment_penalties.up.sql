@@ -0,0 +1,3 @@
+ALTER TABLE loans ADD COLUMN prepayment JSONB;
+
+ALTER TABLE products ADD COLUMN prepayment JSONB;
@@ -0,0 +1,3 @@
+ALTER TABLE loans ADD COLUMN insurance JSONB;
+
+ALTER TABLE products ADD COLUMN insurance JSONB;
@@ -0,0 +1,22 @@
+CREATE TABLE loans (
+    id               TEXT PRIMARY KEY,
+    customer_id      TEXT NOT NULL,
+    status           TEXT NOT NULL,
+    amount           REAL NOT NULL,
+    interest_rate    REAL NOT NULL DEFAULT 0,
+    term_months      INTEGER NOT NULL DEFAULT 0,
+    created_at       TIMESTAMP NOT NULL,
+    approved_at      TIMESTAMP,
+    balance          REAL NOT NULL DEFAULT 0,
+    accrued_interest REAL NOT NULL DEFAULT 0,
+    accrued_through  TIMESTAMP,
+    days_past_due    INTEGER NOT NULL DEFAULT 0,
+    delinquency      TEXT NOT NULL DEFAULT '',
+    rejection_reason TEXT NOT NULL DEFAULT '',
+    credit_score     INTEGER NOT NULL DEFAULT 0,
+    schedule         TEXT NOT NULL DEFAULT '[]',
+    payments         TEXT NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX loans_customer_id ON loans (customer_id);
+CREATE INDEX loans_status_created_at ON loans (status, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX loans_product;
+
+ALTER TABLE loans DROP COLUMN product;
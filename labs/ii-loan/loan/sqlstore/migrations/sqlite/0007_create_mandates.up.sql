@@ -0,0 +1,12 @@
+CREATE TABLE mandates (
+    loan_id      TEXT PRIMARY KEY REFERENCES loans (id),
+    id           TEXT NOT NULL,
+    account      TEXT NOT NULL,
+    status       TEXT NOT NULL,
+    retry_days   TEXT NOT NULL DEFAULT '[]',
+    created_at   TIMESTAMP NOT NULL,
+    cancelled_at TIMESTAMP,
+    attempts     TEXT NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX mandates_status ON mandates (status);
@@ -0,0 +1 @@
+ALTER TABLE loans ADD COLUMN currency TEXT NOT NULL DEFAULT '';
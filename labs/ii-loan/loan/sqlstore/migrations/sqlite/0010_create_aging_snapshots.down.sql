@@ -0,0 +1,2 @@
+DROP TABLE aging_records;
+DROP TABLE aging_snapshots;
@@ -0,0 +1,2 @@
+DROP TABLE bucket_transitions;
+DROP TABLE transition_months;
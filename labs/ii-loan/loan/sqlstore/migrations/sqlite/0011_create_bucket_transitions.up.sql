@@ -0,0 +1,14 @@
+CREATE TABLE transition_months (
+    month       TEXT PRIMARY KEY,
+    recorded_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE bucket_transitions (
+    month       TEXT NOT NULL REFERENCES transition_months (month),
+    loan_id     TEXT NOT NULL,
+    product     TEXT NOT NULL DEFAULT '',
+    from_bucket TEXT NOT NULL,
+    to_bucket   TEXT NOT NULL,
+    outstanding REAL NOT NULL DEFAULT 0,
+    PRIMARY KEY (month, loan_id)
+);
@@ -0,0 +1,19 @@
+CREATE TABLE loan_transfers (
+    id               TEXT PRIMARY KEY,
+    loan_id          TEXT NOT NULL REFERENCES loans (id),
+    from_customer_id TEXT NOT NULL,
+    to_customer_id   TEXT NOT NULL,
+    reason           TEXT NOT NULL,
+    status           TEXT NOT NULL,
+    balance          REAL NOT NULL,
+    requested_by     TEXT NOT NULL,
+    requested_at     TIMESTAMP NOT NULL,
+    kyc              TEXT NOT NULL,
+    credit_score     INTEGER NOT NULL DEFAULT 0,
+    decision         TEXT,
+    decided_by       TEXT NOT NULL DEFAULT '',
+    decided_at       TIMESTAMP,
+    rejection_reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX loan_transfers_loan ON loan_transfers (loan_id, requested_at);
@@ -0,0 +1,8 @@
+CREATE TABLE products (
+    code       TEXT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    min_amount REAL NOT NULL DEFAULT 0,
+    max_amount REAL NOT NULL DEFAULT 0,
+    rate       REAL NOT NULL DEFAULT 0,
+    terms      TEXT NOT NULL DEFAULT '[]'
+);
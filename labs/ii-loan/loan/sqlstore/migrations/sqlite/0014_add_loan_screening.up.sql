@@ -0,0 +1 @@
+ALTER TABLE loans ADD COLUMN screening TEXT;
@@ -0,0 +1 @@
+ALTER TABLE loans ADD COLUMN terms_changes TEXT NOT NULL DEFAULT '[]';
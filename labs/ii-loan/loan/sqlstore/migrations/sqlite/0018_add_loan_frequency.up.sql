@@ -0,0 +1 @@
+ALTER TABLE loans ADD COLUMN frequency TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE loans ADD COLUMN prepayment TEXT;
+
+ALTER TABLE products ADD COLUMN prepayment TEXT;
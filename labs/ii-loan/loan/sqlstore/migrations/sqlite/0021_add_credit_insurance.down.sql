@@ -0,0 +1,3 @@
+ALTER TABLE products DROP COLUMN insurance;
+
+ALTER TABLE loans DROP COLUMN insurance;
@@ -0,0 +1,3 @@
+ALTER TABLE loans ADD COLUMN insurance TEXT;
+
+ALTER TABLE products ADD COLUMN insurance TEXT;
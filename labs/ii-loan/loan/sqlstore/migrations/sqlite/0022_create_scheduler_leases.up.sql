@@ -0,0 +1,5 @@
+CREATE TABLE scheduler_leases (
+    lease_key  TEXT PRIMARY KEY,
+    holder     TEXT NOT NULL,
+    expires_at BIGINT NOT NULL -- unix milliseconds
+);
@@ -0,0 +1 @@
+ALTER TABLE customers ADD COLUMN pseudonym TEXT NOT NULL DEFAULT '';
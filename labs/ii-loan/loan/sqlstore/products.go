@@ -0,0 +1,144 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"loan"
+)
+
+// ProductRepository stores the product catalog in the products table, with
+// the terms and purposes offered kept as JSON arrays and the purpose rates
+// as a JSON object
+type ProductRepository struct {
+	db *DB
+}
+
+// NewProductRepository creates a repository on a migrated database
+func NewProductRepository(db *DB) *ProductRepository {
+	return &ProductRepository{db: db}
+}
+
+const productColumns = "code, name, min_amount, max_amount, rate, terms, purposes, purpose_rates, amortization, prepayment, insurance"
+
+func scanProduct(row scanner) (loan.Product, error) {
+	var (
+		p                       loan.Product
+		terms, purposes, priced []byte
+		prepayment, insurance   []byte
+	)
+	if err := row.Scan(&p.Code, &p.Name, &p.MinAmount, &p.MaxAmount, &p.Rate, &terms, &purposes, &priced, &p.Amortization, &prepayment, &insurance); err != nil {
+		return loan.Product{}, err
+	}
+	if err := json.Unmarshal(terms, &p.Terms); err != nil {
+		return loan.Product{}, fmt.Errorf("sqlstore: product %s terms: %w", p.Code, err)
+	}
+	if err := json.Unmarshal(purposes, &p.Purposes); err != nil {
+		return loan.Product{}, fmt.Errorf("sqlstore: product %s purposes: %w", p.Code, err)
+	}
+	if err := json.Unmarshal(priced, &p.PurposeRates); err != nil {
+		return loan.Product{}, fmt.Errorf("sqlstore: product %s purpose rates: %w", p.Code, err)
+	}
+	if len(prepayment) > 0 {
+		if err := json.Unmarshal(prepayment, &p.Prepayment); err != nil {
+			return loan.Product{}, fmt.Errorf("sqlstore: product %s prepayment: %w", p.Code, err)
+		}
+	}
+	if len(insurance) > 0 {
+		if err := json.Unmarshal(insurance, &p.Insurance); err != nil {
+			return loan.Product{}, fmt.Errorf("sqlstore: product %s insurance: %w", p.Code, err)
+		}
+	}
+	if len(p.Terms) == 0 {
+		p.Terms = nil
+	}
+	if len(p.Purposes) == 0 {
+		p.Purposes = nil
+	}
+	if len(p.PurposeRates) == 0 {
+		p.PurposeRates = nil
+	}
+	return p, nil
+}
+
+// Product implements loan.ProductCatalog
+func (r *ProductRepository) Product(ctx context.Context, code string) (loan.Product, error) {
+	p, err := scanProduct(r.db.queryRow(ctx, "SELECT "+productColumns+" FROM products WHERE code = ?", code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return loan.Product{}, loan.ErrProductNotFound
+	}
+	return p, err
+}
+
+// Products returns the catalog ordered by code
+func (r *ProductRepository) Products(ctx context.Context) ([]loan.Product, error) {
+	rows, err := r.db.query(ctx, "SELECT "+productColumns+" FROM products ORDER BY code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []loan.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// SaveProduct adds p or replaces the product with its code
+func (r *ProductRepository) SaveProduct(ctx context.Context, p loan.Product) error {
+	terms, err := json.Marshal(orEmpty(p.Terms))
+	if err != nil {
+		return err
+	}
+	purposes, err := json.Marshal(orEmpty(p.Purposes))
+	if err != nil {
+		return err
+	}
+	priced := []byte("{}")
+	if len(p.PurposeRates) > 0 {
+		if priced, err = json.Marshal(p.PurposeRates); err != nil {
+			return err
+		}
+	}
+	var prepayment, insurance sql.NullString
+	if p.Prepayment != nil {
+		if prepayment, err = nullJSON(p.Prepayment); err != nil {
+			return err
+		}
+	}
+	if len(p.Insurance) > 0 {
+		if insurance, err = nullJSON(p.Insurance); err != nil {
+			return err
+		}
+	}
+	_, err = r.db.exec(ctx, `INSERT INTO products (`+productColumns+`) VALUES (`+placeholders(11)+`)
+ON CONFLICT (code) DO UPDATE SET name = excluded.name, min_amount = excluded.min_amount,
+max_amount = excluded.max_amount, rate = excluded.rate, terms = excluded.terms,
+purposes = excluded.purposes, purpose_rates = excluded.purpose_rates, amortization = excluded.amortization,
+prepayment = excluded.prepayment, insurance = excluded.insurance`,
+		p.Code, p.Name, p.MinAmount, p.MaxAmount, p.Rate, string(terms), string(purposes), string(priced), p.Amortization, prepayment, insurance)
+	return err
+}
+
+// DeleteProduct removes a product
+func (r *ProductRepository) DeleteProduct(ctx context.Context, code string) error {
+	res, err := r.db.exec(ctx, "DELETE FROM products WHERE code = ?", code)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return loan.ErrProductNotFound
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"loan"
+)
+
+var riskGradeExpr = `CASE
+	WHEN credit_score <= 0 THEN 'unrated'
+	WHEN credit_score >= 750 THEN 'A'
+	WHEN credit_score >= 700 THEN 'B'
+	WHEN credit_score >= 650 THEN 'C'
+	WHEN credit_score >= 600 THEN 'D'
+	ELSE 'E' END`
+
+var performanceExpr = fmt.Sprintf(`CASE WHEN status = '%s' OR days_past_due > %d THEN '%s' ELSE '%s' END`,
+	loan.StatusDefault, loan.NonPerformingDays, loan.NonPerformingKey, loan.PerformingKey)
+
+var currencyExpr = fmt.Sprintf(`CASE WHEN currency = '' THEN '%s' ELSE currency END`, loan.DefaultCurrency)
+
+// groupExpr returns the SQL computing a dimension's key, matching
+// loan.Loan.GroupKey, and any condition a loan needs to have a key
+func (d Dialect) groupExpr(dim loan.Dimension) (expr string, cond []string, err error) {
+	switch dim {
+	case loan.ByStatus:
+		return "status", nil, nil
+	case loan.ByProduct:
+		return "product", nil, nil
+	case loan.ByRiskGrade:
+		return riskGradeExpr, nil, nil
+	case loan.ByPerformance:
+		return performanceExpr, nil, nil
+	case loan.ByCurrency:
+		return currencyExpr, nil, nil
+	case loan.ByOriginationMonth:
+		cond := []string{"approved_at IS NOT NULL"}
+		if d == Postgres {
+			return "to_char(approved_at AT TIME ZONE 'UTC', 'YYYY-MM')", cond, nil
+		}
+		// SQLite keeps timestamps as RFC 3339 text in UTC
+		return "substr(approved_at, 1, 7)", cond, nil
+	}
+	return "", nil, fmt.Errorf("sqlstore: unknown dimension %q", dim)
+}
+
+// GroupBy implements loan.Grouper with a GROUP BY query
+func (r *LoanRepository) GroupBy(ctx context.Context, filter loan.Filter, d loan.Dimension) ([]loan.Group, error) {
+	expr, cond, err := r.db.Dialect.groupExpr(d)
+	if err != nil {
+		return nil, err
+	}
+	where, args := filterClause(filter, cond...)
+	rows, err := r.db.query(ctx, "SELECT "+expr+" AS k, COUNT(*), COALESCE(SUM(amount), 0), COALESCE(SUM(balance), 0) FROM loans"+
+		where+" GROUP BY k ORDER BY k", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []loan.Group{}
+	for rows.Next() {
+		var g loan.Group
+		if err := rows.Scan(&g.Key, &g.Count, &g.Principal, &g.Outstanding); err != nil {
+			return nil, err
+		}
+		g.Principal, g.Outstanding = round2(g.Principal), round2(g.Outstanding)
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
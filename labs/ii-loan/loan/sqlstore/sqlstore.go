@@ -0,0 +1,103 @@
+// Package sqlstore implements the loan repositories on database/sql for
+// SQLite and PostgreSQL, together with the versioned schema migrations
+// they need. Programs register the drivers by importing loan/sqlstore/drivers.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect covers the SQL differences between the supported databases
+type Dialect struct {
+	Name string
+	// numbered placeholders ($1) rather than ?
+	numbered bool
+}
+
+// Supported dialects
+var (
+	SQLite   = Dialect{Name: "sqlite"}
+	Postgres = Dialect{Name: "postgres", numbered: true}
+)
+
+// DialectFor returns the dialect of a database/sql driver name
+func DialectFor(driver string) (Dialect, error) {
+	switch driver {
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	case "pgx", "postgres":
+		return Postgres, nil
+	}
+	return Dialect{}, fmt.Errorf("sqlstore: unsupported driver %q", driver)
+}
+
+// rebind rewrites ? placeholders for dialects that number them
+func (d Dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// DB is an open database with its dialect
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Open opens a database through a registered driver. SQLite connections
+// get foreign keys enabled and are limited to one writer at a time.
+func Open(ctx context.Context, driver, dsn string) (*DB, error) {
+	d, err := DialectFor(driver)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if d == SQLite {
+		db.SetMaxOpenConns(1)
+		if _, err := db.ExecContext(ctx, "PRAGMA foreign_keys = ON"); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{DB: db, Dialect: d}, nil
+}
+
+// Ping implements health.Pinger
+func (db *DB) Ping(ctx context.Context) error {
+	return db.PingContext(ctx)
+}
+
+func (db *DB) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(ctx, db.Dialect.rebind(query), args...)
+}
+
+func (db *DB) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(ctx, db.Dialect.rebind(query), args...)
+}
+
+func (db *DB) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.QueryRowContext(ctx, db.Dialect.rebind(query), args...)
+}
@@ -0,0 +1,138 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"loan"
+)
+
+// TransferRepository stores loan transfers in the loan_transfers table,
+// with the KYC result and risk decision kept as JSON documents
+type TransferRepository struct {
+	db *DB
+}
+
+// NewTransferRepository creates a repository on a migrated database
+func NewTransferRepository(db *DB) *TransferRepository {
+	return &TransferRepository{db: db}
+}
+
+const transferColumns = "id, loan_id, from_customer_id, to_customer_id, reason, status, balance, requested_by, requested_at, " +
+	"kyc, credit_score, decision, decided_by, decided_at, rejection_reason"
+
+// SaveTransfer stores t, replacing an earlier version of it
+func (r *TransferRepository) SaveTransfer(ctx context.Context, t *loan.Transfer) error {
+	kyc, err := json.Marshal(t.KYC)
+	if err != nil {
+		return err
+	}
+	var decision sql.NullString
+	if t.Decision != nil {
+		b, err := json.Marshal(t.Decision)
+		if err != nil {
+			return err
+		}
+		decision = sql.NullString{String: string(b), Valid: true}
+	}
+	_, err = r.db.exec(ctx, `INSERT INTO loan_transfers (`+transferColumns+`) VALUES (`+placeholders(15)+`)
+ON CONFLICT (id) DO UPDATE SET status = excluded.status, decided_by = excluded.decided_by,
+decided_at = excluded.decided_at, rejection_reason = excluded.rejection_reason`,
+		t.ID, t.LoanID, t.FromCustomerID, t.ToCustomerID, t.Reason, t.Status, t.Balance, t.RequestedBy, t.RequestedAt.UTC(),
+		string(kyc), t.CreditScore, decision, t.DecidedBy, nullTime(t.DecidedAt), t.RejectionReason)
+	return err
+}
+
+func scanTransfer(row scanner) (*loan.Transfer, error) {
+	var (
+		t             loan.Transfer
+		decided       sql.NullTime
+		kyc, decision []byte
+	)
+	if err := row.Scan(&t.ID, &t.LoanID, &t.FromCustomerID, &t.ToCustomerID, &t.Reason, &t.Status, &t.Balance,
+		&t.RequestedBy, &t.RequestedAt, &kyc, &t.CreditScore, &decision, &t.DecidedBy, &decided, &t.RejectionReason); err != nil {
+		return nil, err
+	}
+	t.RequestedAt = t.RequestedAt.UTC()
+	t.DecidedAt = decided.Time
+	if err := json.Unmarshal(kyc, &t.KYC); err != nil {
+		return nil, fmt.Errorf("sqlstore: transfer %s kyc: %w", t.ID, err)
+	}
+	if len(decision) > 0 {
+		if err := json.Unmarshal(decision, &t.Decision); err != nil {
+			return nil, fmt.Errorf("sqlstore: transfer %s decision: %w", t.ID, err)
+		}
+	}
+	return &t, nil
+}
+
+// FindTransfer returns a transfer by ID
+func (r *TransferRepository) FindTransfer(ctx context.Context, id string) (*loan.Transfer, error) {
+	t, err := scanTransfer(r.db.queryRow(ctx, "SELECT "+transferColumns+" FROM loan_transfers WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, loan.ErrTransferNotFound
+	}
+	return t, err
+}
+
+// Transfers returns the transfers of a loan, oldest first
+func (r *TransferRepository) Transfers(ctx context.Context, loanID string) ([]*loan.Transfer, error) {
+	rows, err := r.db.query(ctx, "SELECT "+transferColumns+" FROM loan_transfers WHERE loan_id = ? ORDER BY requested_at, id", loanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*loan.Transfer
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// EraseCustomer implements loan.CustomerEraser by rewriting the transfers
+// to or from the customer under the pseudonym
+func (r *TransferRepository) EraseCustomer(ctx context.Context, customerID, pseudonym string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, r.db.Dialect.rebind(
+		"SELECT "+transferColumns+" FROM loan_transfers WHERE from_customer_id = ? OR to_customer_id = ?"), customerID, customerID)
+	if err != nil {
+		return err
+	}
+	var found []*loan.Transfer
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		found = append(found, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, t := range found {
+		t.Pseudonymize(customerID, pseudonym)
+		kyc, err := json.Marshal(t.KYC)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, r.db.Dialect.rebind(`UPDATE loan_transfers SET from_customer_id = ?, to_customer_id = ?,
+reason = ?, kyc = ?, rejection_reason = ? WHERE id = ?`),
+			t.FromCustomerID, t.ToCustomerID, t.Reason, string(kyc), t.RejectionReason, t.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
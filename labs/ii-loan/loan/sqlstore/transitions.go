@@ -0,0 +1,80 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"loan"
+)
+
+// TransitionStore keeps the bucket transitions of each month in the
+// bucket_transitions table, with a transition_months row marking the
+// month as recorded
+type TransitionStore struct {
+	db *DB
+}
+
+// NewTransitionStore creates a transition store on a migrated database
+func NewTransitionStore(db *DB) *TransitionStore {
+	return &TransitionStore{db: db}
+}
+
+// SaveTransitions implements loan.TransitionStore, replacing the month's
+// transitions in one transaction
+func (s *TransitionStore) SaveTransitions(ctx context.Context, month time.Time, transitions []loan.BucketTransition) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	key := period(month)
+	if _, err := tx.ExecContext(ctx, s.db.Dialect.rebind("DELETE FROM bucket_transitions WHERE month = ?"), key); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, s.db.Dialect.rebind(`INSERT INTO transition_months (month, recorded_at) VALUES (?, ?)
+ON CONFLICT (month) DO UPDATE SET recorded_at = excluded.recorded_at`), key, time.Now().UTC()); err != nil {
+		return err
+	}
+	insert, err := tx.PrepareContext(ctx, s.db.Dialect.rebind(
+		"INSERT INTO bucket_transitions (month, loan_id, product, from_bucket, to_bucket, outstanding) VALUES ("+placeholders(6)+")"))
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	for _, t := range transitions {
+		if _, err := insert.ExecContext(ctx, key, t.LoanID, t.Product, string(t.From), string(t.To), t.Outstanding); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Transitions implements loan.TransitionStore
+func (s *TransitionStore) Transitions(ctx context.Context, month time.Time) ([]loan.BucketTransition, bool, error) {
+	key := period(month)
+	var found int
+	err := s.db.queryRow(ctx, "SELECT 1 FROM transition_months WHERE month = ?", key).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	rows, err := s.db.query(ctx,
+		"SELECT loan_id, product, from_bucket, to_bucket, outstanding FROM bucket_transitions WHERE month = ? ORDER BY loan_id", key)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	out := []loan.BucketTransition{}
+	for rows.Next() {
+		var t loan.BucketTransition
+		if err := rows.Scan(&t.LoanID, &t.Product, &t.From, &t.To, &t.Outstanding); err != nil {
+			return nil, false, err
+		}
+		out = append(out, t)
+	}
+	return out, true, rows.Err()
+}
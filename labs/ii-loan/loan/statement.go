@@ -0,0 +1,63 @@
+package loan
+
+import "time"
+
+// upcomingInstallments is how many unpaid installments after the period a
+// statement lists
+const upcomingInstallments = 3
+
+// Statement summarises a loan's position for one calendar month
+type Statement struct {
+	LoanID          string        `json:"loanId"`
+	CustomerID      string        `json:"customerId"`
+	Product         string        `json:"product,omitempty"`
+	Period          time.Time     `json:"period"`
+	Balance         float64       `json:"balance"`
+	AccruedInterest float64       `json:"accruedInterest"`
+	InterestRate    float64       `json:"interestRate"`
+	DaysPastDue     int           `json:"daysPastDue"`
+	Installments    []Installment `json:"installments"`
+	// Payments are the repayments received during the period
+	Payments []Payment `json:"payments,omitempty"`
+	// Upcoming are the next unpaid installments falling due after the period
+	Upcoming    []Installment `json:"upcoming,omitempty"`
+	GeneratedAt time.Time     `json:"generatedAt"`
+}
+
+// MonthStart truncates t to midnight UTC on the first of its month
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// BuildStatement creates the statement for the month containing period
+func BuildStatement(l *Loan, period time.Time) Statement {
+	start := MonthStart(period)
+	end := start.AddDate(0, 1, 0)
+	st := Statement{
+		LoanID:          l.ID,
+		CustomerID:      l.CustomerID,
+		Product:         l.Product,
+		Period:          start,
+		Balance:         l.Balance,
+		AccruedInterest: l.AccruedInterest,
+		InterestRate:    l.AnnualRate(),
+		DaysPastDue:     l.DaysPastDue,
+		GeneratedAt:     time.Now().UTC(),
+	}
+	for _, inst := range l.Schedule {
+		switch {
+		case inst.DueDate.Before(start):
+		case inst.DueDate.Before(end):
+			st.Installments = append(st.Installments, inst)
+		case !inst.IsPaid() && len(st.Upcoming) < upcomingInstallments:
+			st.Upcoming = append(st.Upcoming, inst)
+		}
+	}
+	for _, p := range l.Payments {
+		if !p.PaidAt.Before(start) && p.PaidAt.Before(end) {
+			st.Payments = append(st.Payments, p)
+		}
+	}
+	return st
+}
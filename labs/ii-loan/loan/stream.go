@@ -0,0 +1,33 @@
+package loan
+
+import "context"
+
+// Streamer is implemented by repositories that can hand out the loans
+// matching a filter one at a time, in creation order, instead of building
+// the whole list. Iteration stops at the first error fn returns. fn must
+// not call back into the repository, which may hold its only connection.
+type Streamer interface {
+	Each(ctx context.Context, filter Filter, fn func(*Loan) error) error
+}
+
+// EachLoan calls fn for every loan matching filter in creation order. It
+// streams through the repository's Streamer when there is one and falls
+// back to List otherwise.
+func EachLoan(ctx context.Context, repo LoanRepository, filter Filter, fn func(*Loan) error) error {
+	if s, ok := repo.(Streamer); ok {
+		return s.Each(ctx, filter, fn)
+	}
+	loans, err := repo.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for _, l := range loans {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
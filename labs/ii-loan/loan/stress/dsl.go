@@ -0,0 +1,156 @@
+package stress
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The scenario language is line based. A scenario starts with its name
+// and lists the shocks applied together; blank lines and text after # are
+// ignored.
+//
+//	scenario "Rate shock"
+//	rate +2%          # every loan's annual rate rises by 2 percentage points
+//
+//	scenario "Recession"
+//	pd +50%           # every probability of default rises by half
+//	pd E +100%        # grade E's doubles, on top of the shock above
+//	lgd AUTO +20%     # losses on AUTO loans rise by a fifth
+//	rate +1.5%
+//
+// pd shocks take a risk grade and lgd shocks a product; without one they
+// apply to all. Shocks of the same kind compound.
+
+// Shock kinds
+const (
+	// Rate adds percentage points to the annual interest rate
+	Rate = "rate"
+	// PD scales probabilities of default by a percentage
+	PD = "pd"
+	// LGD scales losses given default by a percentage
+	LGD = "lgd"
+)
+
+// Shock is one line of a scenario
+type Shock struct {
+	Kind string `json:"kind"`
+	// Target is the risk grade of a pd shock or the product of an lgd
+	// shock; empty applies to all
+	Target string `json:"target,omitempty"`
+	// Percent is the signed change, +2 for "+2%"
+	Percent float64 `json:"percent"`
+}
+
+func (s Shock) String() string {
+	target := ""
+	if s.Target != "" {
+		target = " " + s.Target
+	}
+	return fmt.Sprintf("%s%s %+g%%", s.Kind, target, s.Percent)
+}
+
+// Scenario is a named set of shocks applied together
+type Scenario struct {
+	Name   string  `json:"name"`
+	Shocks []Shock `json:"shocks"`
+}
+
+// ParseError reports a malformed scenario line
+type ParseError struct {
+	Line    int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("stress: line %d: %s", e.Line, e.Message)
+}
+
+// Parse reads the scenarios of a script. Every error it returns for a
+// malformed script is a *ParseError.
+func Parse(r io.Reader) ([]Scenario, error) {
+	var (
+		out []Scenario
+		// starts holds the line each scenario starts on
+		starts []int
+		line   int
+	)
+	fail := func(format string, args ...any) error {
+		return &ParseError{Line: line, Message: fmt.Sprintf(format, args...)}
+	}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line++
+		text, _, _ := strings.Cut(sc.Text(), "#")
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if name, ok := strings.CutPrefix(text, "scenario "); ok {
+			name, err := strconv.Unquote(strings.TrimSpace(name))
+			if err != nil || name == "" {
+				return nil, fail("scenario name must be a non-empty quoted string")
+			}
+			out = append(out, Scenario{Name: name})
+			starts = append(starts, line)
+			continue
+		}
+		if len(out) == 0 {
+			return nil, fail("shock before the first scenario")
+		}
+		shock, err := parseShock(strings.Fields(text))
+		if err != nil {
+			return nil, fail("%v", err)
+		}
+		s := &out[len(out)-1]
+		s.Shocks = append(s.Shocks, shock)
+	}
+	if err := sc.Err(); errors.Is(err, bufio.ErrTooLong) {
+		return nil, &ParseError{Line: line + 1, Message: "line too long"}
+	} else if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fail("no scenario")
+	}
+	for i, s := range out {
+		if len(s.Shocks) == 0 {
+			return nil, &ParseError{Line: starts[i], Message: fmt.Sprintf("scenario %q has no shocks", s.Name)}
+		}
+	}
+	return out, nil
+}
+
+func parseShock(f []string) (Shock, error) {
+	if len(f) == 0 {
+		return Shock{}, fmt.Errorf("empty shock")
+	}
+	s := Shock{Kind: f[0]}
+	switch {
+	case s.Kind != Rate && s.Kind != PD && s.Kind != LGD:
+		return Shock{}, fmt.Errorf("unknown shock %q, want rate, pd or lgd", s.Kind)
+	case s.Kind == Rate && len(f) != 2:
+		return Shock{}, fmt.Errorf("rate shock takes a change only, as in rate +2%%")
+	case len(f) == 3:
+		s.Target = f[1]
+	case len(f) != 2:
+		return Shock{}, fmt.Errorf("%s shock takes an optional target and a change, as in %s +10%%", s.Kind, s.Kind)
+	}
+	change := f[len(f)-1]
+	num, ok := strings.CutSuffix(change, "%")
+	if !ok || num == "" || (num[0] != '+' && num[0] != '-') {
+		return Shock{}, fmt.Errorf("change %q must be a signed percentage, as in +10%%", change)
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return Shock{}, fmt.Errorf("change %q is not a number", change)
+	}
+	if s.Kind != Rate && v < -100 {
+		return Shock{}, fmt.Errorf("%s cannot fall by more than 100%%", s.Kind)
+	}
+	s.Percent = v
+	return s, nil
+}
@@ -0,0 +1,157 @@
+// Package stress runs what-if scenarios on the loan book. A scenario
+// shocks interest rates, probabilities of default and losses given
+// default, written in a small language (see Parse), and is scored by the
+// book's figures before and after the shocks.
+//
+//	scenarios, err := stress.Parse(strings.NewReader(`
+//	scenario "Recession"
+//	pd +50%
+//	rate +2%`))
+//	results := stress.Run(loans, loan.DefaultProvisioningModel, scenarios)
+package stress
+
+import (
+	"maps"
+	"math"
+
+	"loan"
+)
+
+// Metrics are the figures of the book a scenario is judged by
+type Metrics struct {
+	Loans    int     `json:"loans"`
+	Exposure float64 `json:"exposure"`
+	// LossAllowance is the expected credit loss of the book
+	LossAllowance float64 `json:"lossAllowance"`
+	// Coverage is LossAllowance over Exposure
+	Coverage float64 `json:"coverage"`
+	// InterestIncome is a year's interest on the outstanding balances
+	InterestIncome float64 `json:"interestIncome"`
+	// MonthlyPayments is what borrowers pay each month to repay their
+	// balances over the installments left, at their rates
+	MonthlyPayments float64 `json:"monthlyPayments"`
+}
+
+// Result compares the book before and after a scenario. Change is After
+// less Before.
+type Result struct {
+	Scenario Scenario `json:"scenario"`
+	Before   Metrics  `json:"before"`
+	After    Metrics  `json:"after"`
+	Change   Metrics  `json:"change"`
+}
+
+// Run applies each scenario to the approved and defaulted loans among
+// loans, on top of the provisioning model
+func Run(loans []*loan.Loan, model loan.ProvisioningModel, scenarios []Scenario) []Result {
+	var book []*loan.Loan
+	for _, l := range loans {
+		if l.Status == loan.StatusApproved || l.Status == loan.StatusDefault {
+			book = append(book, l)
+		}
+	}
+	before := measure(book, model, 0)
+	out := make([]Result, 0, len(scenarios))
+	for _, s := range scenarios {
+		shocked, rate := apply(model, s)
+		after := measure(book, shocked, rate)
+		out = append(out, Result{Scenario: s, Before: before, After: after, Change: diff(after, before)})
+	}
+	return out
+}
+
+// apply returns the model shocked by s and the rate change in points
+func apply(m loan.ProvisioningModel, s Scenario) (loan.ProvisioningModel, float64) {
+	out := loan.ProvisioningModel{
+		PD: maps.Clone(m.PD), BucketPD: maps.Clone(m.BucketPD), LGD: maps.Clone(m.LGD), DefaultLGD: m.DefaultLGD,
+	}
+	if out.LGD == nil {
+		out.LGD = map[string]float64{}
+	}
+	var rate float64
+	for _, sh := range s.Shocks {
+		factor := 1 + sh.Percent/100
+		switch sh.Kind {
+		case Rate:
+			rate += sh.Percent / 100
+		case PD:
+			for grade, pd := range out.PD {
+				if sh.Target == "" || sh.Target == grade {
+					out.PD[grade] = math.Min(pd*factor, 1)
+				}
+			}
+			// delinquency is a grade-independent signal, shocked with the
+			// book as a whole
+			if sh.Target == "" {
+				for b, pd := range out.BucketPD {
+					out.BucketPD[b] = math.Min(pd*factor, 1)
+				}
+			}
+		case LGD:
+			if sh.Target != "" {
+				lgd, ok := out.LGD[sh.Target]
+				if !ok {
+					lgd = out.DefaultLGD
+				}
+				out.LGD[sh.Target] = math.Min(lgd*factor, 1)
+				continue
+			}
+			for product, lgd := range out.LGD {
+				out.LGD[product] = math.Min(lgd*factor, 1)
+			}
+			out.DefaultLGD = math.Min(out.DefaultLGD*factor, 1)
+		}
+	}
+	return out, rate
+}
+
+// measure computes the metrics of book under model with rates moved by
+// rate
+func measure(book []*loan.Loan, model loan.ProvisioningModel, rate float64) Metrics {
+	var m Metrics
+	for _, l := range book {
+		balance := math.Max(l.Balance, 0)
+		r := math.Max(l.AnnualRate()+rate, 0)
+		m.Loans++
+		m.Exposure += balance
+		m.LossAllowance += model.ECL(l).ECL
+		m.InterestIncome += balance * r
+		m.MonthlyPayments += payment(balance, r, max(l.RemainingMonths(), 1))
+	}
+	m.Exposure, m.LossAllowance = round2(m.Exposure), round2(m.LossAllowance)
+	m.InterestIncome, m.MonthlyPayments = round2(m.InterestIncome), round2(m.MonthlyPayments)
+	m.Coverage = ratio(m.LossAllowance, m.Exposure)
+	return m
+}
+
+// payment is the level monthly payment repaying balance over n months at
+// the annual rate
+func payment(balance, annualRate float64, n int) float64 {
+	r := annualRate / 12
+	if r == 0 {
+		return balance / float64(n)
+	}
+	return balance * r / (1 - math.Pow(1+r, -float64(n)))
+}
+
+func diff(a, b Metrics) Metrics {
+	return Metrics{
+		Loans:           a.Loans - b.Loans,
+		Exposure:        round2(a.Exposure - b.Exposure),
+		LossAllowance:   round2(a.LossAllowance - b.LossAllowance),
+		Coverage:        math.Round((a.Coverage-b.Coverage)*10000) / 10000,
+		InterestIncome:  round2(a.InterestIncome - b.InterestIncome),
+		MonthlyPayments: round2(a.MonthlyPayments - b.MonthlyPayments),
+	}
+}
+
+func ratio(n, d float64) float64 {
+	if d == 0 {
+		return 0
+	}
+	return math.Round(n/d*10000) / 10000
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,146 @@
+package loan
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"loan/tracing"
+)
+
+// Terms are the pricing and shape of what is left of a loan's schedule
+type Terms struct {
+	InterestRate float64 `json:"interestRate"`
+	// Installments counts the installments due after the change
+	Installments int `json:"installments"`
+	// Installment is the amount of the first of them
+	Installment float64   `json:"installment"`
+	Principal   float64   `json:"principal"`
+	Maturity    time.Time `json:"maturity"`
+}
+
+// TermsChange records a recalculation of a loan's schedule: what its
+// remaining terms were before and after, and why
+type TermsChange struct {
+	ID string `json:"id"`
+	// FromInstallment is the number of the first installment regenerated
+	FromInstallment int       `json:"fromInstallment"`
+	Old             Terms     `json:"old"`
+	New             Terms     `json:"new"`
+	Reason          string    `json:"reason"`
+	ChangedBy       string    `json:"changedBy"`
+	ChangedAt       time.Time `json:"changedAt"`
+}
+
+// TermsChangeRequest asks for a loan to be repriced or restructured
+type TermsChangeRequest struct {
+	// InterestRate is the new annual rate, 0 to keep the current one
+	InterestRate float64
+	// Installments is the new number of installments after the change,
+	// 0 to keep the current number
+	Installments int
+	Reason       string
+	ChangedBy    string
+}
+
+// Recalculate regenerates the installments of an approved loan falling
+// due after at: the principal still owed on them is amortized by the
+// loan's method at the new rate over the new number of installments, due
+// on the same dates at the loan's repayment frequency. Installments due
+// by at or already paid are kept, arrears included; partial payments on
+// regenerated ones count as principal repaid. Regenerated installments
+// collect the escrow payment, if any. The change is appended to
+// TermsChanges.
+func (l *Loan) Recalculate(req TermsChangeRequest, at time.Time, opts ...ScheduleOption) (TermsChange, error) {
+	if l.Status != StatusApproved {
+		return TermsChange{}, fmt.Errorf("%w: cannot change the terms of loan in status %q", ErrInvalidTransition, l.Status)
+	}
+	switch {
+	case req.InterestRate < 0 || req.InterestRate >= 1:
+		return TermsChange{}, invalid("interestRate", "interest rate must be a fraction between 0 and 1")
+	case req.Installments < 0:
+		return TermsChange{}, invalid("installments", "installments cannot be negative")
+	case strings.TrimSpace(req.Reason) == "":
+		return TermsChange{}, invalid("reason", "the reason for the change is required")
+	case strings.TrimSpace(req.ChangedBy) == "":
+		return TermsChange{}, invalid("changedBy", "who changed the terms is required")
+	}
+	kept := 0
+	for kept < len(l.Schedule) && (l.Schedule[kept].IsPaid() || !l.Schedule[kept].DueDate.After(at)) {
+		kept++
+	}
+	remaining := l.Schedule[kept:]
+	if len(remaining) == 0 {
+		return TermsChange{}, fmt.Errorf("%w: loan has no installments left to recalculate", ErrInvalidTransition)
+	}
+	var principal float64
+	for _, inst := range remaining {
+		principal += inst.Principal - inst.Paid
+	}
+	principal = round2(principal)
+
+	rate, n := l.AnnualRate(), len(remaining)
+	if req.InterestRate > 0 {
+		rate = req.InterestRate
+	}
+	if req.Installments > 0 {
+		n = req.Installments
+	}
+	// due dates step from approval past the kept ones, so they stay put even
+	// where a short month clamped an earlier one
+	regenerated := BuildSchedule(principal, rate, n, l.ApprovedAt, append(l.scheduleOptions(opts), skipping(kept))...)
+	for i := range regenerated {
+		regenerated[i].Number = kept + i + 1
+	}
+	change := TermsChange{
+		ID:              NewID(),
+		FromInstallment: kept + 1,
+		Old:             termsOf(l.AnnualRate(), principal, remaining),
+		New:             termsOf(rate, principal, regenerated),
+		Reason:          req.Reason,
+		ChangedBy:       req.ChangedBy,
+		ChangedAt:       at.UTC(),
+	}
+	l.Schedule = append(l.Schedule[:kept:kept], regenerated...)
+	l.spreadAddOns(at)
+	l.InterestRate, l.TermMonths = rate, monthsOf(l.Frequency, len(l.Schedule))
+	l.TermsChanges = append(l.TermsChanges, change)
+	return change, nil
+}
+
+func termsOf(rate, principal float64, installments []Installment) Terms {
+	t := Terms{InterestRate: rate, Installments: len(installments), Principal: principal}
+	if len(installments) > 0 {
+		t.Installment = installments[0].Amount
+		t.Maturity = installments[len(installments)-1].DueDate
+	}
+	return t
+}
+
+// ChangeTerms recalculates a loan's remaining schedule under new terms,
+// as for a rate reset or a restructure, and publishes EventTermsChanged
+// with the old and new terms
+func (s *LoanService) ChangeTerms(ctx context.Context, id string, req TermsChangeRequest) (_ TermsChange, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.ChangeTerms", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	l, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return TermsChange{}, err
+	}
+	var change TermsChange
+	stored, err := s.update(ctx, l, func(l *Loan) (err error) {
+		change, err = l.Recalculate(req, time.Now(), s.schedule...)
+		return err
+	})
+	if err != nil {
+		s.log(l).ErrorContext(ctx, "updating loan with new terms", "error", err)
+		return TermsChange{}, err
+	}
+	l = stored
+	s.log(l).InfoContext(ctx, "loan terms changed", "changed_by", change.ChangedBy,
+		"old_rate", change.Old.InterestRate, "new_rate", change.New.InterestRate,
+		"old_installments", change.Old.Installments, "new_installments", change.New.Installments)
+	return change, s.publish(ctx, l, NewEvent(EventTermsChanged, l.ID, change))
+}
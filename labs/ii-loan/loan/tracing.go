@@ -0,0 +1,44 @@
+package loan
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"loan/tracing"
+)
+
+// Span attribute keys used by the service
+const (
+	attrLoanID    = attribute.Key("loan.id")
+	attrEventType = attribute.Key("loan.event.type")
+)
+
+// tracedRepository wraps a LoanRepository with a span per call
+type tracedRepository struct {
+	next LoanRepository
+}
+
+func (r tracedRepository) Save(ctx context.Context, loan *Loan) (err error) {
+	ctx, span := tracing.Start(ctx, "LoanRepository.Save", attrLoanID.String(loan.ID))
+	defer tracing.End(span, &err)
+	return r.next.Save(ctx, loan)
+}
+
+func (r tracedRepository) FindByID(ctx context.Context, id string) (_ *Loan, err error) {
+	ctx, span := tracing.Start(ctx, "LoanRepository.FindByID", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+	return r.next.FindByID(ctx, id)
+}
+
+func (r tracedRepository) Update(ctx context.Context, loan *Loan) (err error) {
+	ctx, span := tracing.Start(ctx, "LoanRepository.Update", attrLoanID.String(loan.ID))
+	defer tracing.End(span, &err)
+	return r.next.Update(ctx, loan)
+}
+
+func (r tracedRepository) List(ctx context.Context, filter Filter) (_ []*Loan, err error) {
+	ctx, span := tracing.Start(ctx, "LoanRepository.List")
+	defer tracing.End(span, &err)
+	return r.next.List(ctx, filter)
+}
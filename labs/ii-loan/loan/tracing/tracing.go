@@ -0,0 +1,87 @@
+// Package tracing sets up OpenTelemetry for the loan service and carries
+// trace context across HTTP and gRPC boundaries. Instrumented code obtains
+// its tracer from the global provider, so tracing costs nothing until a
+// program installs one with Setup.
+package tracing
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName identifies spans created by this module
+const InstrumentationName = "loan"
+
+// Tracer returns the module tracer from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Start begins a span with the module tracer
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if any, and ends it. It is meant to be deferred
+// with a pointer to the function's named error result.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// Setup installs a tracer provider exporting through exp and the W3C trace
+// context and baggage propagators. The returned function flushes and stops
+// the provider.
+func Setup(service string, exp sdktrace.SpanExporter) func(context.Context) error {
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown
+}
+
+// LogExporter writes finished spans to a logger, enough to follow a flow
+// in the labs without running a collector
+type LogExporter struct {
+	Logger *slog.Logger
+}
+
+// ExportSpans implements sdktrace.SpanExporter
+func (e LogExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	for _, s := range spans {
+		attrs := []slog.Attr{
+			slog.String("trace_id", s.SpanContext().TraceID().String()),
+			slog.String("span_id", s.SpanContext().SpanID().String()),
+			slog.String("kind", s.SpanKind().String()),
+			slog.Duration("duration", s.EndTime().Sub(s.StartTime())),
+		}
+		if s.Parent().IsValid() {
+			attrs = append(attrs, slog.String("parent_id", s.Parent().SpanID().String()))
+		}
+		if s.Status().Code == codes.Error {
+			attrs = append(attrs, slog.String("error", s.Status().Description))
+		}
+		for _, kv := range s.Attributes() {
+			attrs = append(attrs, slog.String(string(kv.Key), kv.Value.Emit()))
+		}
+		e.Logger.LogAttrs(ctx, slog.LevelInfo, "span "+s.Name(), attrs...)
+	}
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter
+func (LogExporter) Shutdown(context.Context) error { return nil }
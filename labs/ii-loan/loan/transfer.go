@@ -0,0 +1,367 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"loan/tracing"
+)
+
+// ErrTransferNotFound is returned by repositories when no transfer has the
+// given ID
+var ErrTransferNotFound = errors.New("transfer not found")
+
+// ErrTransfersDisabled is returned when transfers are used on a service
+// without a transfer repository and KYC checker
+var ErrTransfersDisabled = errors.New("loan transfers are not configured")
+
+// ErrSameApprover is returned when the person who requested a transfer
+// tries to decide it
+var ErrSameApprover = errors.New("transfer must be decided by someone other than its requester")
+
+// Transfer states
+const (
+	TransferPending  = "pending"
+	TransferApproved = "approved"
+	TransferRejected = "rejected"
+)
+
+// KYCResult is the outcome of know-your-customer checks on a customer
+type KYCResult struct {
+	CustomerID string `json:"customerId"`
+	Passed     bool   `json:"passed"`
+	// Reasons explain why the checks failed
+	Reasons   []string  `json:"reasons,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// KYCChecker verifies who a customer is before they take on a loan
+type KYCChecker interface {
+	CheckCustomer(ctx context.Context, customerID string) (KYCResult, error)
+}
+
+// CustomerKYC passes customers on file who have not been anonymized and
+// whose name, national ID and date of birth are held
+func CustomerKYC(customers CustomerRepository) KYCChecker {
+	return customerKYC{customers}
+}
+
+type customerKYC struct {
+	customers CustomerRepository
+}
+
+func (k customerKYC) CheckCustomer(ctx context.Context, customerID string) (KYCResult, error) {
+	r := KYCResult{CustomerID: customerID, CheckedAt: time.Now().UTC()}
+	c, err := k.customers.FindByID(ctx, customerID)
+	if errors.Is(err, ErrCustomerNotFound) {
+		r.Reasons = []string{"customer is not on file"}
+		return r, nil
+	}
+	if err != nil {
+		return KYCResult{}, err
+	}
+	switch {
+	case c.IsAnonymized():
+		r.Reasons = append(r.Reasons, "customer was anonymized")
+	default:
+		if strings.TrimSpace(c.Name) == "" {
+			r.Reasons = append(r.Reasons, "name is missing")
+		}
+		if strings.TrimSpace(c.NationalID) == "" {
+			r.Reasons = append(r.Reasons, "national ID is missing")
+		}
+		if c.DateOfBirth.IsZero() {
+			r.Reasons = append(r.Reasons, "date of birth is missing")
+		}
+	}
+	r.Passed = len(r.Reasons) == 0
+	return r, nil
+}
+
+// Transfer moves a loan's obligation from one customer to another, as in
+// a business acquisition. It is requested, checked against the new
+// obligor and decided by a second person; FromCustomerID keeps the link
+// to the prior obligor once the loan has moved.
+type Transfer struct {
+	ID             string `json:"id"`
+	LoanID         string `json:"loanId"`
+	FromCustomerID string `json:"fromCustomerId"`
+	ToCustomerID   string `json:"toCustomerId"`
+	Reason         string `json:"reason"`
+	Status         string `json:"status"`
+	// Balance is the balance the new obligor takes on
+	Balance     float64   `json:"balance"`
+	RequestedBy string    `json:"requestedBy"`
+	RequestedAt time.Time `json:"requestedAt"`
+	KYC         KYCResult `json:"kyc"`
+	// CreditScore is the new obligor's bureau score, 0 when unchecked
+	CreditScore int `json:"creditScore,omitempty"`
+	// Decision is the risk engine's advice on the new obligor, if any
+	Decision        *Decision `json:"decision,omitempty"`
+	DecidedBy       string    `json:"decidedBy,omitempty"`
+	DecidedAt       time.Time `json:"decidedAt"`
+	RejectionReason string    `json:"rejectionReason,omitempty"`
+}
+
+// Clone returns a deep copy so callers cannot alias stored state
+func (t *Transfer) Clone() *Transfer {
+	c := *t
+	c.KYC.Reasons = slices.Clone(t.KYC.Reasons)
+	if t.Decision != nil {
+		c.Decision = t.Decision.Clone()
+	}
+	return &c
+}
+
+// Pseudonymize replaces customerID with pseudonym wherever t refers to
+// it, erasing the free text of a transfer that did, and reports whether t
+// changed. Transfer stores implementing CustomerEraser apply it.
+func (t *Transfer) Pseudonymize(customerID, pseudonym string) bool {
+	if t.FromCustomerID != customerID && t.ToCustomerID != customerID && t.KYC.CustomerID != customerID {
+		return false
+	}
+	for _, id := range []*string{&t.FromCustomerID, &t.ToCustomerID, &t.KYC.CustomerID} {
+		if *id == customerID {
+			*id = pseudonym
+		}
+	}
+	for _, text := range []*string{&t.Reason, &t.RejectionReason} {
+		if *text != "" {
+			*text = erased
+		}
+	}
+	return true
+}
+
+// TransferRepository stores loan transfers
+type TransferRepository interface {
+	SaveTransfer(ctx context.Context, t *Transfer) error
+	FindTransfer(ctx context.Context, id string) (*Transfer, error)
+	// Transfers returns the transfers of a loan, oldest first
+	Transfers(ctx context.Context, loanID string) ([]*Transfer, error)
+}
+
+// TransferRequest asks for a loan to be moved to another customer
+type TransferRequest struct {
+	LoanID       string
+	ToCustomerID string
+	Reason       string
+	RequestedBy  string
+}
+
+// WithTransfers enables loan transfers stored in repo, with new obligors
+// checked by kyc
+func WithTransfers(repo TransferRepository, kyc KYCChecker) Option {
+	return func(s *LoanService) {
+		s.transfers, s.kyc = repo, kyc
+	}
+}
+
+// RequestTransfer opens the transfer of an active loan to another
+// customer. The new obligor goes through KYC and, when configured, the
+// credit check and risk engine an application would; a transfer failing
+// KYC is stored already rejected. A loan has at most one pending transfer.
+func (s *LoanService) RequestTransfer(ctx context.Context, req TransferRequest) (_ *Transfer, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.RequestTransfer", attrLoanID.String(req.LoanID))
+	defer tracing.End(span, &err)
+
+	if s.transfers == nil || s.kyc == nil {
+		return nil, ErrTransfersDisabled
+	}
+	switch {
+	case req.ToCustomerID == "":
+		return nil, invalid("toCustomerId", "new customer ID is required")
+	case strings.TrimSpace(req.Reason) == "":
+		return nil, invalid("reason", "transfer reason is required")
+	case strings.TrimSpace(req.RequestedBy) == "":
+		return nil, invalid("requestedBy", "requester is required")
+	}
+	l, err := s.repo.FindByID(ctx, req.LoanID)
+	if err != nil {
+		return nil, err
+	}
+	if !l.IsActive() {
+		return nil, fmt.Errorf("%w: cannot transfer loan in status %q", ErrInvalidTransition, l.Status)
+	}
+	if req.ToCustomerID == l.CustomerID {
+		return nil, invalid("toCustomerId", "loan already belongs to this customer")
+	}
+	earlier, err := s.transfers.Transfers(ctx, l.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range earlier {
+		if t.Status == TransferPending {
+			return nil, fmt.Errorf("%w: transfer %s is already pending", ErrInvalidTransition, t.ID)
+		}
+	}
+	t := &Transfer{
+		ID: NewID(), LoanID: l.ID, FromCustomerID: l.CustomerID, ToCustomerID: req.ToCustomerID,
+		Reason: strings.TrimSpace(req.Reason), Status: TransferPending, Balance: l.Balance,
+		RequestedBy: strings.TrimSpace(req.RequestedBy), RequestedAt: time.Now().UTC(),
+	}
+	if err := s.checkObligor(ctx, l, t); err != nil {
+		s.log(l).ErrorContext(ctx, "checking new obligor", "transfer_id", t.ID, "error", err)
+		return nil, err
+	}
+	if !t.KYC.Passed {
+		t.Status, t.DecidedAt = TransferRejected, t.RequestedAt
+		t.RejectionReason = "KYC failed: " + strings.Join(t.KYC.Reasons, "; ")
+	}
+	if err := s.transfers.SaveTransfer(ctx, t); err != nil {
+		return nil, err
+	}
+	s.log(l).InfoContext(ctx, "loan transfer requested", "transfer_id", t.ID, "status", t.Status)
+	return t, nil
+}
+
+// checkObligor runs KYC and, when configured, the credit check and risk
+// engine on the customer taking the loan over. The risk engine sees
+// the loan as if the customer applied for its balance over the
+// installments left.
+func (s *LoanService) checkObligor(ctx context.Context, l *Loan, t *Transfer) error {
+	var err error
+	if t.KYC, err = s.kyc.CheckCustomer(ctx, t.ToCustomerID); err != nil {
+		return fmt.Errorf("kyc: %w", err)
+	}
+	if !t.KYC.Passed {
+		return nil
+	}
+	var report CreditReport
+	if s.bureau != nil {
+		if report, err = s.creditReport(ctx, t.ToCustomerID); err != nil {
+			return err
+		}
+		t.CreditScore = report.Score
+	}
+	if s.risk != nil {
+		as := l.Clone()
+		as.CustomerID, as.Amount, as.CreditScore = t.ToCustomerID, l.Balance, t.CreditScore
+		as.TermMonths = max(l.RemainingMonths(), 1)
+		if err := s.assess(ctx, as, report); err != nil {
+			return err
+		}
+		t.Decision = as.Decision
+	}
+	return nil
+}
+
+// ApproveTransfer moves the loan to the new obligor, cancels any direct
+// debit mandate on the prior obligor's account and publishes
+// EventLoanTransferred. The approver must not be the requester, and a
+// transfer the risk engine declined cannot be approved.
+func (s *LoanService) ApproveTransfer(ctx context.Context, id, approvedBy string) (_ *Transfer, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.ApproveTransfer")
+	defer tracing.End(span, &err)
+
+	t, err := s.pendingTransfer(ctx, id, approvedBy)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attrLoanID.String(t.LoanID))
+	if t.Decision != nil && t.Decision.Outcome == OutcomeDecline {
+		return nil, fmt.Errorf("%w: the new obligor was declined: %s", ErrInvalidTransition, strings.Join(t.Decision.Reasons, "; "))
+	}
+	l, err := s.repo.FindByID(ctx, t.LoanID)
+	if err != nil {
+		return nil, err
+	}
+	if !l.IsActive() || l.CustomerID != t.FromCustomerID {
+		return nil, fmt.Errorf("%w: loan changed since the transfer was requested", ErrInvalidTransition)
+	}
+	l.CustomerID = t.ToCustomerID
+	if t.CreditScore > 0 {
+		l.CreditScore = t.CreditScore
+	}
+	if err := s.repo.Update(ctx, l); err != nil {
+		s.log(l).ErrorContext(ctx, "updating transferred loan", "error", err)
+		return nil, err
+	}
+	t.Status, t.DecidedBy, t.DecidedAt = TransferApproved, strings.TrimSpace(approvedBy), time.Now().UTC()
+	if err := s.transfers.SaveTransfer(ctx, t); err != nil {
+		return nil, err
+	}
+	if s.mandates != nil {
+		m, err := s.mandates.FindMandate(ctx, l.ID)
+		switch {
+		case errors.Is(err, ErrMandateNotFound):
+		case err != nil:
+			return nil, err
+		case m.Status == MandateActive:
+			if _, err := s.CancelMandate(ctx, l.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	s.log(l).InfoContext(ctx, "loan transferred", "transfer_id", t.ID)
+	return t, s.publish(ctx, l, NewEvent(EventLoanTransferred, l.ID, t))
+}
+
+// RejectTransfer declines a pending transfer, leaving the loan with its
+// obligor
+func (s *LoanService) RejectTransfer(ctx context.Context, id, decidedBy, reason string) (_ *Transfer, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.RejectTransfer")
+	defer tracing.End(span, &err)
+
+	if strings.TrimSpace(reason) == "" {
+		return nil, invalid("reason", "rejection reason is required")
+	}
+	t, err := s.pendingTransfer(ctx, id, decidedBy)
+	if err != nil {
+		return nil, err
+	}
+	t.Status, t.DecidedBy, t.DecidedAt = TransferRejected, strings.TrimSpace(decidedBy), time.Now().UTC()
+	t.RejectionReason = strings.TrimSpace(reason)
+	return t, s.transfers.SaveTransfer(ctx, t)
+}
+
+// pendingTransfer returns the pending transfer id for decidedBy to decide
+func (s *LoanService) pendingTransfer(ctx context.Context, id, decidedBy string) (*Transfer, error) {
+	if s.transfers == nil {
+		return nil, ErrTransfersDisabled
+	}
+	if strings.TrimSpace(decidedBy) == "" {
+		return nil, invalid("decidedBy", "approver is required")
+	}
+	t, err := s.transfers.FindTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != TransferPending {
+		return nil, fmt.Errorf("%w: transfer is %s", ErrInvalidTransition, t.Status)
+	}
+	if strings.TrimSpace(decidedBy) == t.RequestedBy {
+		return nil, ErrSameApprover
+	}
+	return t, nil
+}
+
+// GetTransfer returns a transfer
+func (s *LoanService) GetTransfer(ctx context.Context, id string) (_ *Transfer, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.GetTransfer")
+	defer tracing.End(span, &err)
+
+	if s.transfers == nil {
+		return nil, ErrTransfersDisabled
+	}
+	return s.transfers.FindTransfer(ctx, id)
+}
+
+// Transfers returns the transfers of a loan, oldest first: the audit trail
+// of who owed it before its current obligor
+func (s *LoanService) Transfers(ctx context.Context, loanID string) (_ []*Transfer, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.Transfers", attrLoanID.String(loanID))
+	defer tracing.End(span, &err)
+
+	if s.transfers == nil {
+		return nil, ErrTransfersDisabled
+	}
+	if _, err := s.repo.FindByID(ctx, loanID); err != nil {
+		return nil, err
+	}
+	return s.transfers.Transfers(ctx, loanID)
+}
@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SGR escape sequences for styling text
+const (
+	reset   = "\x1b[0m"
+	bold    = "\x1b[1m"
+	faint   = "\x1b[2m"
+	reverse = "\x1b[7m"
+	red     = "\x1b[31m"
+	green   = "\x1b[32m"
+	yellow  = "\x1b[33m"
+)
+
+// Bold renders s in bold
+func Bold(s string) string { return bold + s + reset }
+
+// Faint renders s dimmed
+func Faint(s string) string { return faint + s + reset }
+
+// Reverse renders s with the colours swapped, as for a selected line
+func Reverse(s string) string { return reverse + s + reset }
+
+// Red renders s in red
+func Red(s string) string { return red + s + reset }
+
+// Green renders s in green
+func Green(s string) string { return green + s + reset }
+
+// Yellow renders s in yellow
+func Yellow(s string) string { return yellow + s + reset }
+
+// Width is the number of columns s takes, not counting escape sequences.
+// Every rune is taken to be one column wide.
+func Width(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if end := escapeEnd(s, i); end > i {
+			i = end
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// Truncate cuts s to width columns, keeping its escape sequences so the
+// styles it sets are still reset; width 0 leaves s whole
+func Truncate(s string, width int) string {
+	if width <= 0 || Width(s) <= width {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(s); {
+		if end := escapeEnd(s, i); end > i {
+			b.WriteString(s[i:end])
+			i = end
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		if n < width {
+			b.WriteString(s[i : i+size])
+			n++
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// Pad fills s with spaces to width columns
+func Pad(s string, width int) string {
+	if n := Width(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// escapeEnd returns the end of the CSI escape sequence starting at i, or i
+// when there is none
+func escapeEnd(s string, i int) int {
+	if !strings.HasPrefix(s[i:], "\x1b[") {
+		return i
+	}
+	for j := i + 2; j < len(s); j++ {
+		if c := s[j]; c >= 0x40 && c <= 0x7e {
+			return j + 1
+		}
+	}
+	return i
+}
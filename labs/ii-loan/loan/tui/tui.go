@@ -0,0 +1,274 @@
+// Package tui runs full-screen terminal interfaces in the Elm architecture
+// popularised by bubbletea: a Model folds each message (a key press, a
+// resize, the result of a command) into a new model, and its View is
+// redrawn after every update. Commands run in their own goroutines, so a
+// slow query never blocks the keyboard.
+//
+//	final, err := tui.NewProgram(model).Run()
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ErrNotTerminal is returned by Run when the input is not a terminal
+var ErrNotTerminal = errors.New("tui: input is not a terminal")
+
+// Msg is anything a model is updated with
+type Msg any
+
+// Cmd is I/O run off the update loop; its message, if not nil, is sent to
+// the model when it returns
+type Cmd func() Msg
+
+// Model is the state of an interface
+type Model interface {
+	// Init returns the command to run at start, or nil
+	Init() Cmd
+	// Update returns the model after msg and the command to run next
+	Update(msg Msg) (Model, Cmd)
+	// View renders the model, one screen line per line
+	View() string
+}
+
+// KeyMsg is a key press. Key is the character typed, or a name such as
+// "up", "down", "left", "right", "enter", "esc", "tab", "backspace",
+// "pgup", "pgdown", "home", "end" or "ctrl+c".
+type KeyMsg struct {
+	Key string
+}
+
+// WindowSizeMsg is sent at start and whenever the terminal is resized
+type WindowSizeMsg struct {
+	Width, Height int
+}
+
+type quitMsg struct{}
+
+// Quit is a Cmd ending the program after the current update
+func Quit() Msg { return quitMsg{} }
+
+// Tick returns a Cmd sending fn's message after d
+func Tick(d time.Duration, fn func(time.Time) Msg) Cmd {
+	return func() Msg { return fn(<-time.After(d)) }
+}
+
+// Batch returns a Cmd running every cmd at once
+func Batch(cmds ...Cmd) Cmd {
+	return func() Msg { return batchMsg(cmds) }
+}
+
+type batchMsg []Cmd
+
+// Program drives a Model on a terminal
+type Program struct {
+	model Model
+	in    *os.File
+	out   io.Writer
+	msgs  chan Msg
+	width int
+	// height of the terminal, 0 until known
+	height int
+}
+
+// Option configures a Program
+type Option func(*Program)
+
+// WithInput reads keys from f instead of stdin
+func WithInput(f *os.File) Option {
+	return func(p *Program) { p.in = f }
+}
+
+// WithOutput draws on w instead of stdout
+func WithOutput(w io.Writer) Option {
+	return func(p *Program) { p.out = w }
+}
+
+// NewProgram creates a program for m
+func NewProgram(m Model, opts ...Option) *Program {
+	p := &Program{model: m, in: os.Stdin, out: os.Stdout, msgs: make(chan Msg)}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// resizePoll is how often the terminal size is checked; polling works on
+// every platform, unlike SIGWINCH
+const resizePoll = 250 * time.Millisecond
+
+// Run puts the terminal in raw mode on the alternate screen and runs the
+// model until it quits or ctrl+c is pressed, returning the final model.
+// The terminal is restored before Run returns.
+func (p *Program) Run() (Model, error) {
+	fd := int(p.in.Fd())
+	if !term.IsTerminal(fd) {
+		return p.model, ErrNotTerminal
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return p.model, fmt.Errorf("tui: %w", err)
+	}
+	defer term.Restore(fd, state)
+	fmt.Fprint(p.out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(p.out, "\x1b[?25h\x1b[?1049l")
+
+	readErr := make(chan error, 1)
+	// the reader is left blocked on the terminal when Run returns; it
+	// ends with the process
+	go func() { readErr <- p.readKeys() }()
+	p.resize()
+	p.exec(p.model.Init())
+	p.render()
+
+	poll := time.NewTicker(resizePoll)
+	defer poll.Stop()
+	for {
+		select {
+		case err := <-readErr:
+			return p.model, fmt.Errorf("tui: reading keys: %w", err)
+		case <-poll.C:
+			if p.resize() {
+				p.render()
+			}
+		case msg := <-p.msgs:
+			switch msg := msg.(type) {
+			case quitMsg:
+				return p.model, nil
+			case batchMsg:
+				for _, cmd := range msg {
+					p.exec(cmd)
+				}
+				continue
+			case KeyMsg:
+				if msg.Key == "ctrl+c" {
+					return p.model, nil
+				}
+			}
+			p.update(msg)
+			p.render()
+		}
+	}
+}
+
+func (p *Program) update(msg Msg) {
+	var cmd Cmd
+	p.model, cmd = p.model.Update(msg)
+	p.exec(cmd)
+}
+
+func (p *Program) exec(cmd Cmd) {
+	if cmd == nil {
+		return
+	}
+	go func() {
+		if msg := cmd(); msg != nil {
+			p.msgs <- msg
+		}
+	}()
+}
+
+// resize updates the model when the terminal size changed and reports
+// whether it did
+func (p *Program) resize() bool {
+	w, h, err := term.GetSize(int(p.in.Fd()))
+	if err != nil || (w == p.width && h == p.height) {
+		return false
+	}
+	p.width, p.height = w, h
+	p.update(WindowSizeMsg{Width: w, Height: h})
+	return true
+}
+
+// render draws the view from the top left, cutting lines at the width
+// and the view at the height of the terminal
+func (p *Program) render() {
+	lines := strings.Split(strings.TrimRight(p.model.View(), "\n"), "\n")
+	if p.height > 0 && len(lines) > p.height {
+		lines = lines[:p.height]
+	}
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(Truncate(line, p.width))
+		b.WriteString("\x1b[K")
+	}
+	b.WriteString("\x1b[J")
+	io.WriteString(p.out, b.String())
+}
+
+// keys names the escape sequences of the keys handled
+var keys = map[string]string{
+	"\x1b[A": "up", "\x1b[B": "down", "\x1b[C": "right", "\x1b[D": "left",
+	"\x1bOA": "up", "\x1bOB": "down", "\x1bOC": "right", "\x1bOD": "left",
+	"\x1b[5~": "pgup", "\x1b[6~": "pgdown", "\x1b[H": "home", "\x1b[F": "end",
+	"\x1b[1~": "home", "\x1b[4~": "end",
+}
+
+// readKeys sends a KeyMsg for every key read until reading fails
+func (p *Program) readKeys() error {
+	buf := make([]byte, 64)
+	for {
+		n, err := p.in.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			p.msgs <- KeyMsg{Key: k}
+		}
+	}
+}
+
+// parseKeys splits one read from the terminal into key names
+func parseKeys(b []byte) []string {
+	var out []string
+	for len(b) > 0 {
+		if b[0] == 0x1b {
+			if len(b) == 1 {
+				return append(out, "esc")
+			}
+			matched := false
+			for seq, name := range keys {
+				if strings.HasPrefix(string(b), seq) {
+					out, b, matched = append(out, name), b[len(seq):], true
+					break
+				}
+			}
+			if !matched {
+				// an unknown sequence: drop the rest of the read
+				return out
+			}
+			continue
+		}
+		switch b[0] {
+		case 0x03:
+			out = append(out, "ctrl+c")
+		case '\r', '\n':
+			out = append(out, "enter")
+		case '\t':
+			out = append(out, "tab")
+		case 0x7f, 0x08:
+			out = append(out, "backspace")
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r >= ' ' {
+				out = append(out, string(r))
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrAccountNotVerified is returned when a payout account fails bank
+// verification
+var ErrAccountNotVerified = errors.New("bank account not verified")
+
+// NameMatch grades how well the account holder's name matches the
+// customer's
+type NameMatch string
+
+// Name match grades
+const (
+	NameExact   NameMatch = "exact"
+	NamePartial NameMatch = "partial"
+	NameNone    NameMatch = "none"
+)
+
+// Payee is where a disbursement is paid
+type Payee struct {
+	Account string
+	// Name is the customer's name, compared with the account holder's
+	Name string
+}
+
+// AccountVerification is a bank's answer about an account
+type AccountVerification struct {
+	Account    string    `json:"account"`
+	Exists     bool      `json:"exists"`
+	HolderName string    `json:"holderName,omitempty"`
+	Match      NameMatch `json:"match"`
+	// Method is how the bank checked, such as "penny_drop"
+	Method     string    `json:"method"`
+	VerifiedAt time.Time `json:"verifiedAt"`
+}
+
+// Verified reports whether money may be sent to the account: it exists
+// and its holder's name matches at least partially
+func (v AccountVerification) Verified() bool {
+	return v.Exists && (v.Match == NameExact || v.Match == NamePartial)
+}
+
+// BankVerification confirms that an account exists and belongs to the
+// payee, typically with a penny drop and an account-name lookup.
+// Implementations call external services and must honour ctx
+// cancellation.
+type BankVerification interface {
+	VerifyAccount(ctx context.Context, payee Payee) (AccountVerification, error)
+}
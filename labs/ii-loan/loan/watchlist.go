@@ -0,0 +1,139 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"loan/tracing"
+)
+
+// ErrScreeningHold is returned when approving an application whose
+// applicant matched a watchlist and has not been cleared
+var ErrScreeningHold = errors.New("watchlist matches await clearance")
+
+// ErrNotScreened is returned when clearing an application that has no
+// matches to clear
+var ErrNotScreened = errors.New("application has no watchlist matches")
+
+// Screening statuses
+const (
+	ScreeningClear   = "clear"
+	ScreeningMatch   = "match"
+	ScreeningCleared = "cleared"
+)
+
+// WatchlistEntry is a person or organisation on a sanctions list or an
+// internal blacklist
+type WatchlistEntry struct {
+	ID string `json:"id"`
+	// List names the list the entry comes from, as "OFAC-SDN" or "internal"
+	List    string   `json:"list"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	// DateOfBirth narrows matches when both it and the customer's are known
+	DateOfBirth time.Time `json:"dateOfBirth,omitempty"`
+}
+
+// WatchlistMatch is an entry a customer's name resembles
+type WatchlistMatch struct {
+	EntryID string `json:"entryId"`
+	List    string `json:"list"`
+	// Name is the name or alias of the entry that matched
+	Name string `json:"name"`
+	// Score is how alike the names are, 1 for the same name
+	Score float64 `json:"score"`
+}
+
+// WatchlistScreener looks customers up on watchlists. Names are matched
+// fuzzily, so matches are only possible hits for a person to review.
+type WatchlistScreener interface {
+	Screen(ctx context.Context, c *Customer) ([]WatchlistMatch, error)
+}
+
+// Screening is the outcome of screening an applicant at intake. An
+// application with matches cannot be approved until they are cleared as
+// false positives; true hits are rejected.
+type Screening struct {
+	Status     string           `json:"status"`
+	Matches    []WatchlistMatch `json:"matches,omitempty"`
+	ScreenedAt time.Time        `json:"screenedAt"`
+	ClearedBy  string           `json:"clearedBy,omitempty"`
+	ClearedAt  time.Time        `json:"clearedAt,omitempty"`
+	Note       string           `json:"note,omitempty"`
+}
+
+// Clone returns a deep copy of the screening
+func (s *Screening) Clone() *Screening {
+	c := *s
+	c.Matches = slices.Clone(s.Matches)
+	return &c
+}
+
+// WithWatchlist screens applicants on w at intake, looking their names up
+// in customers. Applicants not on file are refused.
+func WithWatchlist(w WatchlistScreener, customers CustomerRepository) Option {
+	return func(s *LoanService) {
+		s.watchlist, s.customers = w, customers
+	}
+}
+
+// screen records the watchlist screening of the applicant of l
+func (s *LoanService) screen(ctx context.Context, l *Loan) (err error) {
+	ctx, span := tracing.Start(ctx, "WatchlistScreener.Screen", attrLoanID.String(l.ID))
+	defer tracing.End(span, &err)
+	c, err := s.customers.FindByID(ctx, l.CustomerID)
+	if errors.Is(err, ErrCustomerNotFound) {
+		return invalid("customerId", "customer is not on file, so cannot be screened")
+	}
+	if err != nil {
+		return err
+	}
+	matches, err := s.watchlist.Screen(ctx, c)
+	if err != nil {
+		return fmt.Errorf("watchlist screening: %w", err)
+	}
+	l.Screening = &Screening{Status: ScreeningClear, Matches: matches, ScreenedAt: time.Now().UTC()}
+	if len(matches) > 0 {
+		l.Screening.Status = ScreeningMatch
+	}
+	return nil
+}
+
+// ClearScreening records that a person reviewed the watchlist matches of
+// an application and found them false positives, so it can be approved
+func (s *LoanService) ClearScreening(ctx context.Context, id, clearedBy, note string) (_ *Loan, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.ClearScreening", attrLoanID.String(id))
+	defer tracing.End(span, &err)
+
+	if strings.TrimSpace(clearedBy) == "" {
+		return nil, invalid("clearedBy", "who cleared the matches is required")
+	}
+	if strings.TrimSpace(note) == "" {
+		return nil, invalid("note", "why the matches are false positives is required")
+	}
+	l, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.update(ctx, l, func(l *Loan) error {
+		if l.Screening == nil || l.Screening.Status != ScreeningMatch {
+			return ErrNotScreened
+		}
+		if l.Status != StatusPending {
+			return fmt.Errorf("%w: cannot clear screening of loan in status %q", ErrInvalidTransition, l.Status)
+		}
+		l.Screening.Status, l.Screening.ClearedBy, l.Screening.ClearedAt, l.Screening.Note = ScreeningCleared, clearedBy, time.Now().UTC(), note
+		return nil
+	})
+	if err != nil {
+		s.log(l).ErrorContext(ctx, "updating cleared screening", "error", err)
+		return nil, err
+	}
+	l = stored
+	s.log(l).InfoContext(ctx, "watchlist matches cleared", "cleared_by", clearedBy, "matches", len(l.Screening.Matches))
+	return l, nil
+}
@@ -0,0 +1,218 @@
+// Package watchlist screens customers against sanctions lists and
+// blacklists held in memory. Names are compared word by word with
+// Jaro-Winkler similarity after folding case and punctuation, so word
+// order, middle names, transliteration variants and typos still match:
+// screening would rather ask a person about a near miss than let a listed
+// name through.
+package watchlist
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"loan"
+)
+
+// DefaultThreshold is the score from which names match
+const DefaultThreshold = 0.88
+
+// List is a loan.WatchlistScreener over entries held in memory. It is
+// safe for concurrent use, including Replace while screening.
+type List struct {
+	threshold float64
+
+	mu      sync.RWMutex
+	entries []indexed
+}
+
+// indexed is an entry with its name and aliases split into words
+type indexed struct {
+	entry loan.WatchlistEntry
+	names []name
+}
+
+type name struct {
+	text  string
+	words []string
+}
+
+// Option configures a List
+type Option func(*List)
+
+// WithThreshold sets the score, between 0 and 1, from which names match
+// instead of DefaultThreshold
+func WithThreshold(t float64) Option {
+	return func(l *List) { l.threshold = t }
+}
+
+// New creates a list of entries
+func New(entries []loan.WatchlistEntry, opts ...Option) *List {
+	l := &List{threshold: DefaultThreshold}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.Replace(entries)
+	return l
+}
+
+// Load decodes a JSON array of entries
+func Load(r io.Reader) ([]loan.WatchlistEntry, error) {
+	var entries []loan.WatchlistEntry
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&entries); err != nil {
+		return nil, fmt.Errorf("watchlist: %w", err)
+	}
+	for i, e := range entries {
+		if e.ID == "" || strings.TrimSpace(e.Name) == "" {
+			return nil, fmt.Errorf("watchlist: entry %d needs an id and a name", i)
+		}
+	}
+	return entries, nil
+}
+
+// LoadFile reads a JSON array of entries from path
+func LoadFile(path string) ([]loan.WatchlistEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("watchlist: %w", err)
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Replace swaps the entries screened against, as when a list is updated
+func (l *List) Replace(entries []loan.WatchlistEntry) {
+	idx := make([]indexed, 0, len(entries))
+	for _, e := range entries {
+		in := indexed{entry: e}
+		for _, n := range append([]string{e.Name}, e.Aliases...) {
+			if words := split(n); len(words) > 0 {
+				in.names = append(in.names, name{text: n, words: words})
+			}
+		}
+		idx = append(idx, in)
+	}
+	l.mu.Lock()
+	l.entries = idx
+	l.mu.Unlock()
+}
+
+// Screen implements loan.WatchlistScreener, returning the entries whose
+// name or an alias matches the customer's best first. Entries with a date
+// of birth other than the customer's do not match.
+func (l *List) Screen(ctx context.Context, c *loan.Customer) ([]loan.WatchlistMatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	words := split(c.Name)
+	if len(words) == 0 {
+		return nil, nil
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var matches []loan.WatchlistMatch
+	for _, in := range l.entries {
+		e := in.entry
+		if !e.DateOfBirth.IsZero() && !c.DateOfBirth.IsZero() && !sameDay(e.DateOfBirth, c.DateOfBirth) {
+			continue
+		}
+		best := loan.WatchlistMatch{}
+		for _, n := range in.names {
+			if s := score(words, n.words); s > best.Score {
+				best = loan.WatchlistMatch{EntryID: e.ID, List: e.List, Name: n.text, Score: s}
+			}
+		}
+		if best.Score >= l.threshold {
+			best.Score = math.Round(best.Score*100) / 100
+			matches = append(matches, best)
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b loan.WatchlistMatch) int { return cmp.Compare(b.Score, a.Score) })
+	return matches, nil
+}
+
+// split folds a name to lower case words, dropping punctuation
+func split(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r)
+	})
+}
+
+// score compares two names word by word: each word of the shorter name is
+// paired with its most similar word of the other, in any order, and the
+// mean similarity is discounted a little for the longer name's unpaired
+// words
+func score(a, b []string) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var sum float64
+	for _, wa := range a {
+		var best float64
+		for _, wb := range b {
+			best = max(best, jaroWinkler(wa, wb))
+		}
+		sum += best
+	}
+	return sum / float64(len(a)) * (0.8 + 0.2*float64(len(a))/float64(len(b)))
+}
+
+// jaroWinkler is the Jaro-Winkler similarity of a and b, 1 when equal
+func jaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+	window := max(max(len(ra), len(rb))/2-1, 0)
+	matchedA, matchedB := make([]bool, len(ra)), make([]bool, len(rb))
+	var m int
+	for i := range ra {
+		for j := max(0, i-window); j < min(len(rb), i+window+1); j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				m++
+				break
+			}
+		}
+	}
+	if m == 0 {
+		return 0
+	}
+	var transpositions, j int
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+	mf := float64(m)
+	jaro := (mf/float64(len(ra)) + mf/float64(len(rb)) + (mf-float64(transpositions)/2)/mf) / 3
+	var prefix int
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.UTC().Date()
+	by, bm, bd := b.UTC().Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,417 @@
+// Package webhook delivers loan domain events to registered HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"loan"
+	"loan/cloudevents"
+	"loan/tracing"
+)
+
+// Headers set on every delivery
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderEventID   = "X-Webhook-Event-Id"
+)
+
+// ErrSubscriptionNotFound is returned for unknown subscription IDs
+var ErrSubscriptionNotFound = errors.New("webhook: subscription not found")
+
+// ErrInvalidEndpoint is returned by Register for URLs that are not
+// absolute http or https URLs
+var ErrInvalidEndpoint = errors.New("webhook: invalid endpoint URL")
+
+// ErrSecretRequired is returned by Register without a signing secret
+var ErrSecretRequired = errors.New("webhook: secret is required")
+
+// errQueueFull is the dead-letter error of deliveries the queue had no
+// room for
+var errQueueFull = errors.New("webhook: delivery queue full")
+
+// Delivery queue defaults
+const (
+	DefaultQueueSize    = 1024
+	DefaultWorkers      = 4
+	DefaultDrainTimeout = 10 * time.Second
+)
+
+// Subscription registers an endpoint for one or more event types.
+// An empty EventTypes list receives every event.
+type Subscription struct {
+	ID         string           `json:"id"`
+	URL        string           `json:"url"`
+	Secret     string           `json:"-"`
+	EventTypes []loan.EventType `json:"eventTypes,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt"`
+}
+
+func (s Subscription) matches(t loan.EventType) bool {
+	return len(s.EventTypes) == 0 || slices.Contains(s.EventTypes, t)
+}
+
+// DeadLetter records a delivery that failed after all retries
+type DeadLetter struct {
+	ID             string         `json:"id"`
+	SubscriptionID string         `json:"subscriptionId"`
+	EventID        string         `json:"eventId"`
+	EventType      loan.EventType `json:"eventType"`
+	Attempts       int            `json:"attempts"`
+	LastError      string         `json:"lastError"`
+	FailedAt       time.Time      `json:"failedAt"`
+	message        cloudevents.Message
+}
+
+// delivery is an event waiting in the queue for one subscription
+type delivery struct {
+	sub   Subscription
+	event loan.Event
+	msg   cloudevents.Message
+}
+
+// RetryPolicy controls redelivery of failed webhook calls
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+// DefaultRetryPolicy retries five times starting at one second
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	Multiplier:     2,
+}
+
+// Backoff returns the wait before the given retry (1-based)
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		d *= p.Multiplier
+		if p.MaxBackoff > 0 && d >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+	return time.Duration(d)
+}
+
+// WebhookService manages subscriptions and delivers events to them.
+// It implements loan.EventPublisher: Publish queues the deliveries and
+// the workers started by Run make them, so publishers never wait on a
+// subscriber's endpoint or its retries.
+type WebhookService struct {
+	source  string
+	client  *http.Client
+	policy  RetryPolicy
+	now     func() time.Time
+	queue   chan delivery
+	workers int
+	drain   time.Duration
+
+	mu          sync.RWMutex
+	subs        map[string]Subscription
+	deadLetters []DeadLetter
+}
+
+// Option configures a WebhookService
+type Option func(*WebhookService)
+
+// WithHTTPClient overrides the client used for deliveries
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *WebhookService) { s.client = c }
+}
+
+// WithRetryPolicy overrides DefaultRetryPolicy
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(s *WebhookService) { s.policy = p }
+}
+
+// WithQueue sets how many deliveries may wait for a worker, and how many
+// workers Run starts, instead of DefaultQueueSize and DefaultWorkers
+func WithQueue(size, workers int) Option {
+	return func(s *WebhookService) {
+		s.queue = make(chan delivery, max(size, 1))
+		s.workers = max(workers, 1)
+	}
+}
+
+// WithDrainTimeout bounds how long Run keeps delivering what is still
+// queued once its context is cancelled, instead of DefaultDrainTimeout
+func WithDrainTimeout(d time.Duration) Option {
+	return func(s *WebhookService) { s.drain = d }
+}
+
+// NewWebhookService creates a service emitting events with the given CloudEvents source
+func NewWebhookService(source string, opts ...Option) *WebhookService {
+	s := &WebhookService{
+		source:  source,
+		client:  &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)},
+		policy:  DefaultRetryPolicy,
+		now:     time.Now,
+		queue:   make(chan delivery, DefaultQueueSize),
+		workers: DefaultWorkers,
+		drain:   DefaultDrainTimeout,
+		subs:    make(map[string]Subscription),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register adds an endpoint for the given event types
+func (s *WebhookService) Register(endpoint, secret string, types ...loan.EventType) (Subscription, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, fmt.Errorf("%w %q", ErrInvalidEndpoint, endpoint)
+	}
+	if secret == "" {
+		return Subscription{}, ErrSecretRequired
+	}
+	sub := Subscription{
+		ID:         loan.NewID(),
+		URL:        endpoint,
+		Secret:     secret,
+		EventTypes: slices.Clone(types),
+		CreatedAt:  s.now().UTC(),
+	}
+	s.mu.Lock()
+	s.subs[sub.ID] = sub
+	s.mu.Unlock()
+	return sub, nil
+}
+
+// Unregister removes a subscription
+func (s *WebhookService) Unregister(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[id]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	delete(s.subs, id)
+	return nil
+}
+
+// Subscriptions lists all registered subscriptions
+func (s *WebhookService) Subscriptions() []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subs := make([]Subscription, 0, len(s.subs))
+	for _, sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// DeadLetters returns deliveries that exhausted their retries
+func (s *WebhookService) DeadLetters() []DeadLetter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.deadLetters)
+}
+
+// Publish queues the event for every matching subscription and returns
+// without waiting for the deliveries. Deliveries that fail, or that the
+// queue has no room for, end up in the dead-letter list rather than
+// failing the caller; only encoding errors are returned.
+func (s *WebhookService) Publish(_ context.Context, event loan.Event) error {
+	env, err := cloudevents.New(s.source, event)
+	if err != nil {
+		return err
+	}
+	msg, err := cloudevents.Encode(env, cloudevents.ModeStructured)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	var targets []Subscription
+	for _, sub := range s.subs {
+		if sub.matches(event.Type) {
+			targets = append(targets, sub)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, sub := range targets {
+		select {
+		case s.queue <- delivery{sub: sub, event: event, msg: msg}:
+		default:
+			s.deadLetter(sub, event, msg, 0, errQueueFull)
+		}
+	}
+	return nil
+}
+
+// Run makes the queued deliveries with the configured number of workers
+// until ctx is cancelled. It then drains the queue for up to the drain
+// timeout, so deliveries queued before the cancellation still go out, and
+// waits for the workers to stop. Deliveries the drain cuts short or never
+// reaches are dead-lettered.
+func (s *WebhookService) Run(ctx context.Context) error {
+	drainCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(s.drain, cancel)
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					s.drainQueue(drainCtx)
+					return
+				case d := <-s.queue:
+					s.deliverWithRetry(drainCtx, d.sub, d.event, d.msg)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// drainQueue makes the deliveries left in the queue until it is empty
+func (s *WebhookService) drainQueue(ctx context.Context) {
+	for {
+		select {
+		case d := <-s.queue:
+			s.deliverWithRetry(ctx, d.sub, d.event, d.msg)
+		default:
+			return
+		}
+	}
+}
+
+// Redeliver retries a dead letter once and removes it on success
+func (s *WebhookService) Redeliver(ctx context.Context, deadLetterID string) error {
+	s.mu.Lock()
+	idx := slices.IndexFunc(s.deadLetters, func(d DeadLetter) bool { return d.ID == deadLetterID })
+	if idx < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("webhook: dead letter %s not found", deadLetterID)
+	}
+	dl := s.deadLetters[idx]
+	sub, ok := s.subs[dl.SubscriptionID]
+	s.mu.Unlock()
+	if !ok {
+		return ErrSubscriptionNotFound
+	}
+
+	if err := s.deliver(ctx, sub, dl.EventID, dl.message); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.deadLetters = slices.DeleteFunc(s.deadLetters, func(d DeadLetter) bool { return d.ID == deadLetterID })
+	s.mu.Unlock()
+	return nil
+}
+
+// deliverWithRetry makes a delivery, retrying it by the policy until ctx
+// is done, and dead-letters it with the attempts actually made if none
+// succeeds
+func (s *WebhookService) deliverWithRetry(ctx context.Context, sub Subscription, event loan.Event, msg cloudevents.Message) {
+	attempts := max(s.policy.MaxAttempts, 1)
+	made := 0
+	var err error
+retry:
+	for made < attempts {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		made++
+		if err = s.deliver(ctx, sub, event.ID, msg); err == nil {
+			return
+		}
+		if made == attempts {
+			break
+		}
+		timer := time.NewTimer(s.policy.Backoff(made))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+			break retry
+		case <-timer.C:
+		}
+	}
+
+	s.deadLetter(sub, event, msg, made, err)
+}
+
+func (s *WebhookService) deadLetter(sub Subscription, event loan.Event, msg cloudevents.Message, attempts int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters = append(s.deadLetters, DeadLetter{
+		ID:             loan.NewID(),
+		SubscriptionID: sub.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		Attempts:       attempts,
+		LastError:      err.Error(),
+		FailedAt:       s.now().UTC(),
+		message:        msg,
+	})
+}
+
+func (s *WebhookService) deliver(ctx context.Context, sub Subscription, eventID string, msg cloudevents.Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(msg.Body))
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(s.now().Unix(), 10)
+	req.Header.Set("Content-Type", msg.ContentType)
+	for k, v := range msg.Header {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set(HeaderEventID, eventID)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(sub.Secret, ts, msg.Body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: %s responded %s", sub.URL, resp.Status)
+	}
+	return nil
+}
+
+// Sign computes the hex HMAC-SHA256 of "timestamp.body" with secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery signature header value on the receiving side
+func Verify(secret, timestamp, signature string, body []byte) bool {
+	expected := "sha256=" + Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}